@@ -0,0 +1,196 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"golang.org/x/exp/slices"
+)
+
+// allowedCallers is the set of callers allowed to call a component method.
+type allowedCallers struct {
+	components map[string]bool // allowed calling components, by full name
+	external   bool            // are callers outside the application allowed?
+}
+
+// newAllowedCallers returns the allowed callers of the methods of the
+// provided component, as listed under runtime.AllowedCallersKey in the
+// component's config section, keyed by method name. Methods that any caller
+// may call have no entry. The events delivered to a subscriber component are
+// admitted as calls to its eventMethod, made by the publishing component. It
+// returns an error if the list names a method that the component doesn't
+// have.
+func newAllowedCallers(info *codegen.Registration, sections map[string]string) (map[string]*allowedCallers, error) {
+	lists, err := runtime.ParseAllowedCallers(info.Name, sections)
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, nil
+	}
+	parse := func(callers []string) *allowedCallers {
+		a := &allowedCallers{components: map[string]bool{}}
+		for _, caller := range callers {
+			if caller == runtime.ExternalCaller {
+				a.external = true
+			} else {
+				a.components[caller] = true
+			}
+		}
+		return a
+	}
+	methods := methodNames(info)
+	for method := range lists {
+		if !slices.Contains(methods, method) && method != runtime.AllMethods {
+			return nil, fmt.Errorf("section %q: %s: unknown method %q", info.Name, runtime.AllowedCallersKey, method)
+		}
+	}
+	result := map[string]*allowedCallers{}
+	for _, method := range methods {
+		if callers, ok := lists[method]; ok {
+			result[method] = parse(callers)
+		} else if callers, ok := lists[runtime.AllMethods]; ok {
+			result[method] = parse(callers)
+		}
+	}
+	return result, nil
+}
+
+// checkAllowedCallers returns an error if the allowed callers of component c
+// name a caller that is not a component of the application, i.e., not in
+// components, or if they can't be enforced: on local calls, if c's generated
+// code has no interceptors, and on remote calls, if the callers are not
+// verified, i.e., if the application runs in multiple processes without mTLS.
+func checkAllowedCallers(c *component, components map[string]*component, verified bool) error {
+	if len(c.allowedCallers) == 0 {
+		return nil
+	}
+	if !verified {
+		return fmt.Errorf("section %q: %s requires a deployment that verifies the callers of remote calls with mTLS", c.info.Name, runtime.AllowedCallersKey)
+	}
+	if c.info.InterceptFn == nil {
+		return fmt.Errorf("section %q: %s requires generated code that supports interceptors; re-run 'weaver generate'", c.info.Name, runtime.AllowedCallersKey)
+	}
+	for method, allowed := range c.allowedCallers {
+		for caller := range allowed.components {
+			if _, ok := components[caller]; !ok {
+				return fmt.Errorf("section %q: %s: method %s: unknown caller %q; callers outside of the application can only be allowed as %q", c.info.Name, runtime.AllowedCallersKey, method, caller, runtime.ExternalCaller)
+			}
+		}
+	}
+	return nil
+}
+
+// authorize admits a call to the provided method of component c, made by
+// caller, if caller is among the method's allowed callers. If external is
+// true, the caller is outside of the application, and the call is admitted
+// if the method allows external callers, whatever the caller's name. It
+// returns an error wrapping ErrPermissionDenied if the call is rejected.
+func (c *component) authorize(caller string, external bool, method string, remote bool) error {
+	allowed, ok := c.allowedCallers[method]
+	if !ok {
+		return nil
+	}
+	if external && allowed.external || !external && allowed.components[caller] {
+		return nil
+	}
+	if external {
+		// Don't label metrics with names reported by other applications.
+		caller = runtime.ExternalCaller
+	}
+	return c.denied(caller, method, remote, fmt.Sprintf("caller %q is not in %s", caller, runtime.AllowedCallersKey))
+}
+
+// authorizeRemote admits a remote call to the provided method of component c
+// (see authorize). caller is the calling component, as reported by the calling
+// process, and peer is the verified identity of that process (see
+// runtime.PeerIdentity), or empty if the weavelet doesn't use mTLS.
+//
+// The reported caller is only trusted if peer is the colocation group of this
+// deployment that hosts it. A peer of another deployment is a process of
+// another application, so the call is external. Without mTLS, callers can't be
+// verified, so the call is rejected; checkAllowedCallers keeps such weavelets
+// from starting in the first place.
+func (c *component) authorizeRemote(caller, peer, method string) error {
+	if _, ok := c.allowedCallers[method]; !ok {
+		return nil
+	}
+	switch {
+	case peer == "":
+		return c.denied(caller, method, true, "the caller can't be verified without mTLS")
+	case !c.wlet.isInternal(peer):
+		return c.authorize(caller, true, method, true)
+	case c.wlet.hosts(peer, caller):
+		return c.authorize(caller, false, method, true)
+	default:
+		return c.denied(caller, method, true, fmt.Sprintf("caller %q is not hosted by peer %q", caller, peer))
+	}
+}
+
+// denied records that a call to the provided method of component c, made by
+// caller, was rejected for the provided reason, and returns an error wrapping
+// ErrPermissionDenied.
+func (c *component) denied(caller, method string, remote bool, reason string) error {
+	labels := codegen.MethodLabels{Caller: caller, Component: c.info.Name, Method: method, Remote: remote}
+	codegen.MethodPermissionDenied.Get(labels).Inc()
+	return fmt.Errorf("%s.%s: %s: %w", c.info.Name, method, reason, ErrPermissionDenied)
+}
+
+// withAllowedCallers wraps handle, a local handle to component c used by
+// requester, so that calls to methods with allowed callers are admitted only
+// if requester is one of them. Remote calls are admitted by the weavelet that
+// executes them; see weavelet.beginRemoteCall. Note that checkAllowedCallers
+// rejects components with allowed callers whose generated code has no
+// InterceptFn.
+func withAllowedCallers(c *component, handle any, requester string) any {
+	if len(c.allowedCallers) == 0 {
+		return handle
+	}
+	authorize := func(ctx context.Context, call codegen.Call, next codegen.Handler) ([]any, error) {
+		if err := c.authorize(requester, false, call.Method, false); err != nil {
+			return nil, err
+		}
+		return next(ctx, call.Args)
+	}
+	return c.info.InterceptFn(handle, authorize, codegen.Call{Caller: requester, Component: c.info.Name})
+}
+
+// groupName returns the name of the colocation group that hosts the provided
+// component, i.e., the first component listed in the component's colocate
+// entry, or the component itself if it isn't colocated with others.
+func (w *weavelet) groupName(component string) string {
+	if group, ok := w.groups[component]; ok {
+		return group
+	}
+	return component
+}
+
+// isInternal returns whether the weavelet with verified identity peer belongs
+// to the same deployment as w. See runtime.PeerIdentity.
+func (w *weavelet) isInternal(peer string) bool {
+	deploymentId, _, err := runtime.ParsePeerIdentity(peer)
+	return err == nil && deploymentId == w.info.DeploymentId
+}
+
+// hosts returns whether the weavelet with verified identity peer hosts the
+// provided component, i.e., whether it is a weavelet of w's deployment that
+// hosts the component's colocation group.
+func (w *weavelet) hosts(peer, component string) bool {
+	return peer == runtime.PeerIdentity(w.info.DeploymentId, w.groupName(component))
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+)
+
+type aclCatalog interface {
+	Get(ctx context.Context, name string) (string, error)
+	Put(ctx context.Context, name, value string) error
+	Delete(ctx context.Context, name string) error
+}
+
+func TestAuthorize(t *testing.T) {
+	const (
+		admin    = "pkg/Admin"
+		frontend = "pkg/Frontend"
+	)
+	var (
+		adminPeer    = runtime.PeerIdentity("d1", admin)
+		frontendPeer = runtime.PeerIdentity("d1", frontend)
+		selfPeer     = runtime.PeerIdentity("d1", t.Name())
+		otherPeer    = runtime.PeerIdentity("d2", admin) // a process of another deployment
+	)
+	info := &codegen.Registration{Name: t.Name(), Iface: reflection.Type[aclCatalog]()}
+	section := `allowed_callers = {Delete = ["pkg/Admin"], "*" = ["pkg/Frontend", "pkg/Admin", "external"]}`
+	allowed, err := newAllowedCallers(info, map[string]string{info.Name: section})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &weavelet{
+		componentsByName: map[string]*component{admin: nil, frontend: nil, info.Name: nil},
+		info:             &protos.EnvelopeInfo{DeploymentId: "d1"},
+	}
+	c := &component{wlet: w, info: info, allowedCallers: allowed}
+
+	for _, test := range []struct {
+		caller, peer, method string
+		want                 bool
+	}{
+		{admin, adminPeer, "Delete", true},
+		{frontend, frontendPeer, "Delete", false},
+		{admin, frontendPeer, "Delete", false}, // frontend doesn't host admin
+		{frontend, frontendPeer, "Get", true},
+		{admin, adminPeer, "Put", true},
+		{admin, otherPeer, "Delete", false}, // names reported by other deployments aren't trusted
+		{admin, otherPeer, "Get", true},     // "external" matches other deployments
+		{admin, "", "Get", false},           // without mTLS, callers can't be verified
+		{info.Name, selfPeer, "Get", false}, // "external" doesn't match the application's own components
+	} {
+		err := c.authorizeRemote(test.caller, test.peer, test.method)
+		if test.want && err != nil {
+			t.Errorf("authorizeRemote(%q, %q, %q): %v", test.caller, test.peer, test.method, err)
+		}
+		if !test.want && !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("authorizeRemote(%q, %q, %q): got %v, want %v", test.caller, test.peer, test.method, err, ErrPermissionDenied)
+		}
+	}
+
+	var denied float64
+	for _, m := range metrics.Snapshot() {
+		if m.Name == "serviceweaver_method_permission_denied_count" && m.Labels["component"] == t.Name() {
+			denied += m.Value
+		}
+	}
+	if denied != 5 {
+		t.Errorf("permission denied count: got %v, want 5", denied)
+	}
+}
+
+func TestAuthorizeColocated(t *testing.T) {
+	const (
+		admin    = "pkg/Admin"
+		frontend = "pkg/Frontend"
+	)
+	info := &codegen.Registration{Name: t.Name(), Iface: reflection.Type[aclCatalog]()}
+	section := `allowed_callers = {Delete = ["pkg/Admin"]}`
+	allowed, err := newAllowedCallers(info, map[string]string{info.Name: section})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Admin is colocated with Frontend, in the group named after Frontend.
+	w := &weavelet{
+		componentsByName: map[string]*component{admin: nil, frontend: nil, info.Name: nil},
+		groups:           map[string]string{frontend: frontend, admin: frontend},
+		info:             &protos.EnvelopeInfo{DeploymentId: "d1"},
+	}
+	c := &component{wlet: w, info: info, allowedCallers: allowed}
+	if err := c.authorizeRemote(admin, runtime.PeerIdentity("d1", frontend), "Delete"); err != nil {
+		t.Errorf("authorizeRemote from the group hosting admin: %v", err)
+	}
+	if err := c.authorizeRemote(admin, runtime.PeerIdentity("d1", info.Name), "Delete"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("authorizeRemote from another group: got %v, want %v", err, ErrPermissionDenied)
+	}
+}
+
+func TestCheckAllowedCallers(t *testing.T) {
+	info := &codegen.Registration{
+		Name:        "pkg/Catalog",
+		Iface:       reflection.Type[aclCatalog](),
+		InterceptFn: func(handle any, _ codegen.Interceptor, _ codegen.Call) any { return handle },
+	}
+	components := map[string]*component{"pkg/Admin": nil, info.Name: nil}
+	for _, test := range []struct {
+		name       string
+		section    string
+		noFn       bool   // clear info.InterceptFn?
+		unverified bool   // multiprocess deployment without mTLS?
+		want       string // expected error, if any
+	}{
+		{"Valid", `allowed_callers = {Delete = ["pkg/Admin", "external"]}`, false, false, ""},
+		{"UnknownCaller", `allowed_callers = {Delete = ["other/app/Importer"]}`, false, false, `unknown caller "other/app/Importer"`},
+		{"NoInterceptFn", `allowed_callers = {Delete = ["pkg/Admin"]}`, true, false, "re-run 'weaver generate'"},
+		{"Unverified", `allowed_callers = {Delete = ["pkg/Admin"]}`, false, true, "verifies the callers of remote calls with mTLS"},
+		{"UnverifiedExternal", `allowed_callers = {Delete = ["external"]}`, false, true, "verifies the callers of remote calls with mTLS"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			info := *info
+			if test.noFn {
+				info.InterceptFn = nil
+			}
+			allowed, err := newAllowedCallers(&info, map[string]string{info.Name: test.section})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkAllowedCallers(&component{info: &info, allowedCallers: allowed}, components, !test.unverified)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("checkAllowedCallers: got %v, want error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeDefaultAllow(t *testing.T) {
+	info := &codegen.Registration{Name: t.Name(), Iface: reflection.Type[aclCatalog]()}
+	section := `allowed_callers = {Delete = []}`
+	allowed, err := newAllowedCallers(info, map[string]string{info.Name: section})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &component{wlet: &weavelet{}, info: info, allowedCallers: allowed}
+	if err := c.authorize("pkg/Anyone", false, "Get", false); err != nil {
+		t.Errorf("authorize(Get): %v", err)
+	}
+	if err := c.authorize("pkg/Anyone", false, "Delete", false); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("authorize(Delete): got %v, want %v", err, ErrPermissionDenied)
+	}
+}
+
+func TestNewAllowedCallersUnknownMethod(t *testing.T) {
+	info := &codegen.Registration{Name: "pkg/Catalog", Iface: reflection.Type[aclCatalog]()}
+	section := `allowed_callers = {Drop = ["pkg/Admin"]}`
+	_, err := newAllowedCallers(info, map[string]string{info.Name: section})
+	if err == nil || !strings.Contains(err.Error(), `unknown method "Drop"`) {
+		t.Fatalf("newAllowedCallers: got %v, want unknown method error", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/metrics"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// affinityCache remembers the replica that served the calls for every shard
+// key of a routed component, so that later calls for the key can be sent to
+// the same replica. See runtime.Affinity.
+//
+// The cache holds at most size keys, evicting the least recently used key
+// when full, and forgets a key that hasn't been used for ttl.
+type affinityCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time // the current time; replaced in tests
+
+	hits   *metrics.Counter // calls sent to their remembered replica
+	misses *metrics.Counter // calls without an available remembered replica
+
+	mu      sync.Mutex
+	entries map[uint64]*list.Element // values are *affinityEntry
+	lru     *list.List               // most recently used entry first
+}
+
+// affinityEntry is an entry in an affinityCache.
+type affinityEntry struct {
+	key     uint64
+	addr    string    // address of the replica
+	expires time.Time // when the entry expires
+}
+
+// newAffinityCache returns a new affinityCache for the provided component.
+func newAffinityCache(component string, affinity *runtime.Affinity) *affinityCache {
+	labels := codegen.ComponentLabels{Component: component}
+	return &affinityCache{
+		ttl:     affinity.TTL,
+		size:    affinity.Size,
+		now:     time.Now,
+		hits:    codegen.RoutingAffinityHits.Get(labels),
+		misses:  codegen.RoutingAffinityMisses.Get(labels),
+		entries: map[uint64]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// get returns the address of the replica remembered for the provided key,
+// if any, and extends the key's lifetime.
+func (a *affinityCache) get(key uint64) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	elem, ok := a.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*affinityEntry)
+	now := a.now()
+	if now.After(entry.expires) {
+		a.lru.Remove(elem)
+		delete(a.entries, key)
+		return "", false
+	}
+	entry.expires = now.Add(a.ttl)
+	a.lru.MoveToFront(elem)
+	return entry.addr, true
+}
+
+// put remembers that the provided key is served by the replica with the
+// provided address.
+func (a *affinityCache) put(key uint64, addr string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	expires := a.now().Add(a.ttl)
+	if elem, ok := a.entries[key]; ok {
+		entry := elem.Value.(*affinityEntry)
+		entry.addr = addr
+		entry.expires = expires
+		a.lru.MoveToFront(elem)
+		return
+	}
+	a.entries[key] = a.lru.PushFront(&affinityEntry{key: key, addr: addr, expires: expires})
+	for a.lru.Len() > a.size {
+		oldest := a.lru.Back()
+		a.lru.Remove(oldest)
+		delete(a.entries, oldest.Value.(*affinityEntry).key)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestAffinityCache(t *testing.T, ttl time.Duration, size int) (*affinityCache, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	a := newAffinityCache(t.Name(), &runtime.Affinity{TTL: ttl, Size: size})
+	a.now = clock.now
+	return a, clock
+}
+
+func TestAffinityCacheTTL(t *testing.T) {
+	a, clock := newTestAffinityCache(t, time.Minute, 10)
+	a.put(1, "tcp://a")
+	clock.t = clock.t.Add(50 * time.Second)
+	if got, ok := a.get(1); !ok || got != "tcp://a" {
+		t.Fatalf("get(1): got %q, %t; want %q, true", got, ok, "tcp://a")
+	}
+
+	// The get above extended the lifetime of the key.
+	clock.t = clock.t.Add(50 * time.Second)
+	if _, ok := a.get(1); !ok {
+		t.Fatal("get(1): key expired early")
+	}
+	clock.t = clock.t.Add(2 * time.Minute)
+	if got, ok := a.get(1); ok {
+		t.Fatalf("get(1): got %q for expired key", got)
+	}
+}
+
+func TestAffinityCacheSize(t *testing.T) {
+	a, _ := newTestAffinityCache(t, time.Minute, 2)
+	a.put(1, "tcp://a")
+	a.put(2, "tcp://b")
+	a.get(1) // 2 is now the least recently used key
+	a.put(3, "tcp://c")
+	for key, want := range map[uint64]bool{1: true, 2: false, 3: true} {
+		if _, ok := a.get(key); ok != want {
+			t.Errorf("get(%d): got %t, want %t", key, ok, want)
+		}
+	}
+}
+
+// TestRoutingBalancerAffinity tests that a routingBalancer with session
+// affinity keeps sending a key to the same replica after the assignment
+// changes, until the replica goes away.
+func TestRoutingBalancerAffinity(t *testing.T) {
+	a, b := call.TCP("a"), call.TCP("b")
+	assign := func(rb *routingBalancer, replica string) {
+		rb.update(&protos.Assignment{
+			Slices: []*protos.Assignment_Slice{{Start: 0, Replicas: []string{replica}}},
+		})
+	}
+	pick := func(rb *routingBalancer) call.Endpoint {
+		t.Helper()
+		got, err := rb.Pick(call.CallOptions{ShardKey: 42})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	rb := newRoutingBalancer(nil, call.RoundRobin())
+	rb.affinity, _ = newTestAffinityCache(t, time.Minute, 10)
+	rb.Update([]call.Endpoint{a, b})
+	assign(rb, "tcp://a")
+	if got := pick(rb); got != a {
+		t.Fatalf("first pick: got %v, want %v", got, a)
+	}
+
+	// The assignment moves the key to b, but the key sticks to a.
+	assign(rb, "tcp://b")
+	if got := pick(rb); got != a {
+		t.Fatalf("pick after reassignment: got %v, want %v", got, a)
+	}
+
+	// Once a is gone, the key follows the assignment.
+	rb.Update([]call.Endpoint{b})
+	if got := pick(rb); got != b {
+		t.Fatalf("pick after a is gone: got %v, want %v", got, b)
+	}
+	for name, want := range map[string]float64{
+		"serviceweaver_routing_affinity_hit_count":  1,
+		"serviceweaver_routing_affinity_miss_count": 2,
+	} {
+		var got float64
+		for _, m := range metrics.Snapshot() {
+			if m.Name == name && m.Labels["component"] == t.Name() {
+				got = m.Value
+			}
+		}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+
+	// Without affinity, the key follows the assignment right away.
+	rb = newRoutingBalancer(nil, call.RoundRobin())
+	rb.Update([]call.Endpoint{a, b})
+	assign(rb, "tcp://a")
+	pick(rb)
+	assign(rb, "tcp://b")
+	if got := pick(rb); got != b {
+		t.Fatalf("pick without affinity: got %v, want %v", got, b)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// ReplicaInfo contains information about a replica of a remote component.
+type ReplicaInfo struct {
+	// Address is the network address of the replica.
+	Address string
+
+	// Pending is the number of method calls that the current process has
+	// sent to the replica and that have not yet completed.
+	Pending int
+}
+
+// CallInfo contains information about a method call to a remote component.
+type CallInfo struct {
+	Component string // full name of the called component
+	Method    string // name of the called method
+}
+
+// A Balancer picks the replica of a remote component to which a method call is
+// sent. By default, a component's method calls are sent to its replicas in
+// round-robin order. To use a different Balancer for a component, embed
+// [weaver.WithBalancer] in the component implementation. For example:
+//
+//	type cache struct {
+//	    weaver.Implements[Cache]
+//	    weaver.WithBalancer[weaver.LeastLoaded]
+//	}
+//
+// Every process that calls a component has its own instance of the
+// component's Balancer, so a Balancer only knows about the calls made by the
+// process it lives in. Calls to Pick on a Balancer instance are never
+// concurrent.
+//
+// Balancers are not used for the methods of routed components (see
+// [weaver.WithRouter]), unless a routed call cannot be routed, e.g., because
+// the routing assignment is not yet known.
+type Balancer interface {
+	// Pick returns the index of the replica in replicas to which the provided
+	// call should be sent. replicas is never empty. Pick must not modify or
+	// retain replicas.
+	Pick(replicas []ReplicaInfo, call CallInfo) int
+}
+
+// WithBalancer[B] is a type that can be embedded inside a component
+// implementation struct to indicate that calls to the component should be
+// balanced across its replicas using a Balancer of type *B. See
+// [weaver.Balancer] for details.
+//
+// Every process creates a new instance of B, starting from its zero value.
+// *B must implement [weaver.Balancer]; if it doesn't, the application fails to
+// start.
+type WithBalancer[B any] struct{}
+
+// newBalancer returns a new instance of *B. The unusual name of the method
+// makes it unlikely to be shadowed by a field of the component implementation
+// that embeds WithBalancer.
+//
+//nolint:unused
+func (WithBalancer[B]) newBalancer() (Balancer, error) {
+	var b B
+	if balancer, ok := any(&b).(Balancer); ok {
+		return balancer, nil
+	}
+	return nil, fmt.Errorf("*%v does not implement weaver.Balancer", reflection.Type[B]())
+}
+
+// newBalancer returns a new instance of the Balancer of the provided
+// component, or nil if the component does not embed weaver.WithBalancer.
+func newBalancer(info *codegen.Registration) (Balancer, error) {
+	impl := reflect.New(info.Impl).Interface()
+	b, ok := impl.(interface{ newBalancer() (Balancer, error) })
+	if !ok {
+		return nil, nil
+	}
+	return b.newBalancer()
+}
+
+// BalancedRef[T, B] is a [weaver.Ref] whose calls are balanced across the
+// replicas of component T using a Balancer of type *B, rather than the
+// Balancer of T (see [weaver.WithBalancer]). It lets different callers of the
+// same component use different policies. For example:
+//
+//	type api struct {
+//	    weaver.Implements[API]
+//	    backend weaver.BalancedRef[Backend, weaver.LeastLoaded]
+//	}
+//
+//	type batch struct {
+//	    weaver.Implements[Batch]
+//	    backend weaver.BalancedRef[Backend, weaver.RoundRobin]
+//	}
+//
+// Every BalancedRef field has its own instance of B, starting from its zero
+// value. *B must implement [weaver.Balancer]; if it doesn't, the component
+// holding the field fails to initialize. The pending call counts passed to
+// the Balancer include the calls made through every handle to T in the
+// process, not only those made through this BalancedRef, since they all share
+// the same connections to T's replicas.
+//
+// As with WithBalancer, the Balancer is not used if T is local or routed.
+type BalancedRef[T, B any] struct {
+	Ref[T]
+}
+
+// newRefBalancer returns a new instance of *B.
+//
+//nolint:unused
+func (BalancedRef[T, B]) newRefBalancer() (Balancer, error) {
+	var b B
+	if balancer, ok := any(&b).(Balancer); ok {
+		return balancer, nil
+	}
+	return nil, fmt.Errorf("*%v does not implement weaver.Balancer", reflection.Type[B]())
+}
+
+// RoundRobin is a Balancer that picks replicas in round-robin order.
+type RoundRobin struct {
+	next int
+}
+
+var _ Balancer = &RoundRobin{}
+
+// Pick implements the Balancer interface.
+func (r *RoundRobin) Pick(replicas []ReplicaInfo, _ CallInfo) int {
+	i := r.next % len(replicas)
+	r.next = i + 1
+	return i
+}
+
+// Random is a Balancer that picks replicas uniformly at random.
+type Random struct{}
+
+var _ Balancer = &Random{}
+
+// Pick implements the Balancer interface.
+func (*Random) Pick(replicas []ReplicaInfo, _ CallInfo) int {
+	return rand.Intn(len(replicas))
+}
+
+// LeastLoaded is a Balancer that picks the replica with the fewest pending
+// calls. Ties are broken randomly.
+type LeastLoaded struct{}
+
+var _ Balancer = &LeastLoaded{}
+
+// Pick implements the Balancer interface.
+func (*LeastLoaded) Pick(replicas []ReplicaInfo, _ CallInfo) int {
+	// Scan the replicas starting at a random offset, so that ties are not
+	// always broken in favor of the same replica.
+	n := len(replicas)
+	start := rand.Intn(n)
+	best := start
+	for i := 1; i < n; i++ {
+		j := (start + i) % n
+		if replicas[j].Pending < replicas[best].Pending {
+			best = j
+		}
+	}
+	return best
+}
+
+// callBalancer adapts a Balancer to the call.LoadAwareBalancer interface.
+type callBalancer struct {
+	component string
+	balancer  Balancer
+	endpoints []call.Endpoint
+	replicas  []ReplicaInfo
+}
+
+var _ call.LoadAwareBalancer = &callBalancer{}
+
+// Update implements the call.Balancer interface.
+func (cb *callBalancer) Update(endpoints []call.Endpoint) {
+	// A per-call balancer is updated on every call, usually with the same
+	// endpoints. Avoid rebuilding the replicas if the endpoints haven't
+	// changed.
+	if len(endpoints) == len(cb.endpoints) && (len(endpoints) == 0 || &endpoints[0] == &cb.endpoints[0]) {
+		return
+	}
+	cb.endpoints = endpoints
+	cb.replicas = make([]ReplicaInfo, len(endpoints))
+	for i, endpoint := range endpoints {
+		cb.replicas[i].Address = endpoint.Address()
+	}
+}
+
+// Pick implements the call.Balancer interface.
+func (cb *callBalancer) Pick(opts call.CallOptions) (call.Endpoint, error) {
+	return cb.PickWithLoad(opts, nil)
+}
+
+// PickWithLoad implements the call.LoadAwareBalancer interface.
+func (cb *callBalancer) PickWithLoad(opts call.CallOptions, pending []int) (call.Endpoint, error) {
+	if len(cb.endpoints) == 0 {
+		return nil, fmt.Errorf("%w: no endpoints available", call.Unreachable)
+	}
+	for i := range cb.replicas {
+		cb.replicas[i].Pending = 0
+		if i < len(pending) {
+			cb.replicas[i].Pending = pending[i]
+		}
+	}
+	i := cb.balancer.Pick(cb.replicas, CallInfo{Component: cb.component, Method: opts.Method})
+	if i < 0 || i >= len(cb.endpoints) {
+		return nil, fmt.Errorf("balancer %T for component %q picked replica %d, but there are only %d replicas", cb.balancer, cb.component, i, len(cb.endpoints))
+	}
+	return cb.endpoints[i], nil
+}
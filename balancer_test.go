@@ -0,0 +1,222 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+func replicas(pending ...int) []ReplicaInfo {
+	rs := make([]ReplicaInfo, len(pending))
+	for i, p := range pending {
+		rs[i] = ReplicaInfo{Address: fmt.Sprintf("tcp://replica%d:1", i), Pending: p}
+	}
+	return rs
+}
+
+func TestRoundRobin(t *testing.T) {
+	var b RoundRobin
+	rs := replicas(0, 0, 0)
+	for i := 0; i < 7; i++ {
+		if got, want := b.Pick(rs, CallInfo{}), i%3; got != want {
+			t.Errorf("Pick #%d: got %d, want %d", i, got, want)
+		}
+	}
+
+	// Shrink the set of replicas.
+	if got := b.Pick(rs[:1], CallInfo{}); got != 0 {
+		t.Errorf("Pick: got %d, want 0", got)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	var b Random
+	rs := replicas(0, 0, 0)
+	counts := make([]int, len(rs))
+	for i := 0; i < 3000; i++ {
+		counts[b.Pick(rs, CallInfo{})]++
+	}
+	for i, count := range counts {
+		if count == 0 {
+			t.Errorf("replica %d never picked", i)
+		}
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	var b LeastLoaded
+	for _, test := range []struct {
+		pending []int
+		want    []int // acceptable picks
+	}{
+		{[]int{0}, []int{0}},
+		{[]int{3, 1, 2}, []int{1}},
+		{[]int{5, 5, 0}, []int{2}},
+		{[]int{1, 0, 0, 1}, []int{1, 2}},
+	} {
+		picked := map[int]bool{}
+		for i := 0; i < 100; i++ {
+			picked[b.Pick(replicas(test.pending...), CallInfo{})] = true
+		}
+		want := map[int]bool{}
+		for _, w := range test.want {
+			want[w] = true
+		}
+		if !reflect.DeepEqual(picked, want) {
+			t.Errorf("Pick(%v): got %v, want %v", test.pending, picked, want)
+		}
+	}
+}
+
+// endpoint is a call.Endpoint for testing.
+type endpoint string
+
+func (e endpoint) Dial(context.Context) (net.Conn, error) { return nil, fmt.Errorf("unimplemented") }
+func (e endpoint) Address() string                        { return string(e) }
+
+// recordingBalancer is a Balancer that records its arguments.
+type recordingBalancer struct {
+	pick     int
+	replicas []ReplicaInfo
+	call     CallInfo
+}
+
+func (r *recordingBalancer) Pick(replicas []ReplicaInfo, call CallInfo) int {
+	r.replicas = append([]ReplicaInfo(nil), replicas...)
+	r.call = call
+	return r.pick
+}
+
+func TestCallBalancer(t *testing.T) {
+	rb := &recordingBalancer{pick: 1}
+	cb := &callBalancer{component: "pkg/Foo", balancer: rb}
+	if _, err := cb.Pick(call.CallOptions{}); err == nil {
+		t.Fatal("Pick with no endpoints: unexpected success")
+	}
+
+	cb.Update([]call.Endpoint{endpoint("a"), endpoint("b")})
+	got, err := cb.PickWithLoad(call.CallOptions{Method: "Bar"}, []int{3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != endpoint("b") {
+		t.Errorf("PickWithLoad: got %v, want %v", got, endpoint("b"))
+	}
+	if want := []ReplicaInfo{{"a", 3}, {"b", 4}}; !reflect.DeepEqual(rb.replicas, want) {
+		t.Errorf("replicas: got %v, want %v", rb.replicas, want)
+	}
+	if want := (CallInfo{Component: "pkg/Foo", Method: "Bar"}); rb.call != want {
+		t.Errorf("call: got %v, want %v", rb.call, want)
+	}
+
+	// Out of range picks are rejected.
+	rb.pick = 2
+	if _, err := cb.Pick(call.CallOptions{}); err == nil {
+		t.Error("out of range Pick: unexpected success")
+	}
+}
+
+type balancedImpl struct {
+	WithBalancer[LeastLoaded]
+}
+
+type unbalancedImpl struct{}
+
+type badBalancer struct{}
+
+type badBalancedImpl struct {
+	WithBalancer[badBalancer]
+}
+
+func TestNewBalancer(t *testing.T) {
+	for _, test := range []struct {
+		impl    reflect.Type
+		want    Balancer
+		wantErr string
+	}{
+		{reflect.TypeOf(balancedImpl{}), &LeastLoaded{}, ""},
+		{reflect.TypeOf(unbalancedImpl{}), nil, ""},
+		{reflect.TypeOf(badBalancedImpl{}), nil, "does not implement weaver.Balancer"},
+	} {
+		t.Run(test.impl.Name(), func(t *testing.T) {
+			got, err := newBalancer(&codegen.Registration{Impl: test.impl})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("newBalancer: got error %v, want error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("newBalancer: got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// benchmarkSkewedLoad simulates calls to replicas of different speeds and
+// reports the average number of calls queued ahead of a call on the replica
+// it is sent to, which approximates the queueing delay experienced by the
+// call. Every simulated tick, three calls are sent to replicas picked by the
+// balancer, and every replica completes one of its pending calls with a
+// probability equal to its speed. The last replica is slower than the others.
+func benchmarkSkewedLoad(b *testing.B, balancer Balancer) {
+	speeds := []float64{1, 1, 1, 0.8}
+	const arrivals = 3
+	rs := replicas(make([]int, len(speeds))...)
+	r := rand.New(rand.NewSource(0))
+	var queued int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		picked := balancer.Pick(rs, CallInfo{})
+		queued += rs[picked].Pending
+		rs[picked].Pending++
+		if i%arrivals != arrivals-1 {
+			continue
+		}
+		for j, speed := range speeds {
+			if rs[j].Pending > 0 && r.Float64() < speed {
+				rs[j].Pending--
+			}
+		}
+	}
+	b.ReportMetric(float64(queued)/float64(b.N), "queued/op")
+}
+
+func BenchmarkSkewedLoad(b *testing.B) {
+	for _, test := range []struct {
+		name     string
+		balancer Balancer
+	}{
+		{"RoundRobin", &RoundRobin{}},
+		{"Random", &Random{}},
+		{"LeastLoaded", &LeastLoaded{}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			benchmarkSkewedLoad(b, test.balancer)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+)
+
+// replica is a handle to a single replica of a remote component.
+type replica struct {
+	addr  string // address of the replica
+	value any    // client stub whose calls are all sent to the replica
+}
+
+// Broadcast calls f once for every replica of the component that ref refers
+// to, passing it a handle whose method calls are all served by that replica.
+// The calls to f run concurrently, and Broadcast returns when they have all
+// finished. For example, to invalidate a key in the in-memory cache of every
+// replica of a Cache component:
+//
+//	err := weaver.Broadcast(ctx, c.cache, func(cache Cache) error {
+//	    return cache.Invalidate(ctx, key)
+//	})
+//
+// Broadcast calls f on the replicas that exist when Broadcast is called;
+// replicas that start afterwards are not called. If f returns an error for
+// any replica, Broadcast returns a *BroadcastError that reports the error of
+// every failed replica. A replica that is unreachable fails with an error
+// that embeds RemoteCallError. Broadcast doesn't bound the duration of the
+// calls to f, so pass f a ctx with a deadline to avoid waiting on a replica
+// that is slow to respond.
+//
+// If the component is hosted in the caller's process, e.g., because it is
+// colocated with the caller or because the application runs in a single
+// process, Broadcast calls f once, with the handle returned by ref.Get(). In
+// that case, other replicas of the component, if any, are not called.
+func Broadcast[T any](ctx context.Context, ref Ref[T], f func(T) error) error {
+	if ref.replicas == nil {
+		return f(ref.Get())
+	}
+	replicas, err := ref.replicas(ctx)
+	if err != nil {
+		return err
+	}
+	if len(replicas) == 0 {
+		return fmt.Errorf("broadcast to %q: %w: no replicas available", ref.ID(), ErrUnavailable)
+	}
+
+	var mu sync.Mutex
+	errs := map[string]error{}
+	var wg sync.WaitGroup
+	for _, r := range replicas {
+		r := r
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(r.value.(T)); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				errs[r.addr] = err
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return &BroadcastError{Replicas: len(replicas), Errors: errs}
+	}
+	return nil
+}
+
+// A BroadcastError is returned by Broadcast when f fails for one or more of
+// the replicas it was broadcast to. errors.Is and errors.As inspect the error
+// of every failed replica.
+type BroadcastError struct {
+	Replicas int              // number of replicas broadcast to
+	Errors   map[string]error // errors, keyed by replica address
+}
+
+// Error implements the error interface.
+func (e *BroadcastError) Error() string {
+	addrs := make([]string, 0, len(e.Errors))
+	for addr := range e.Errors {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	var b strings.Builder
+	fmt.Fprintf(&b, "broadcast failed on %d of %d replicas", len(e.Errors), e.Replicas)
+	for _, addr := range addrs {
+		fmt.Fprintf(&b, "; %s: %v", addr, e.Errors[addr])
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the failed replicas.
+func (e *BroadcastError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// pinnedBalancer is a call.Balancer that always picks the endpoint with a
+// given address. It fails with call.Unreachable once the endpoint is gone,
+// e.g., because the replica it belongs to has failed.
+type pinnedBalancer struct {
+	addr     string
+	endpoint call.Endpoint // nil if the endpoint is gone
+}
+
+var _ call.Balancer = &pinnedBalancer{}
+
+// Update implements the call.Balancer interface.
+func (pb *pinnedBalancer) Update(endpoints []call.Endpoint) {
+	pb.endpoint = nil
+	for _, endpoint := range endpoints {
+		if endpoint.Address() == pb.addr {
+			pb.endpoint = endpoint
+			return
+		}
+	}
+}
+
+// Pick implements the call.Balancer interface.
+func (pb *pinnedBalancer) Pick(call.CallOptions) (call.Endpoint, error) {
+	if pb.endpoint == nil {
+		return nil, fmt.Errorf("%w: replica %s is gone", call.Unreachable, pb.addr)
+	}
+	return pb.endpoint, nil
+}
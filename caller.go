@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// Caller identifies the caller of a component method. See CallerIdentity.
+type Caller struct {
+	// Component is the full name of the calling component, e.g.,
+	// "github.com/ServiceWeaver/weaver/Main".
+	Component string
+
+	// Identity is the identity of the calling process, as presented in its
+	// mTLS certificate and verified by the deployer. It is empty if the
+	// caller is local, or if the deployer doesn't use mTLS.
+	Identity string
+
+	// Local is true iff the caller runs in the same process as the callee.
+	Local bool
+}
+
+// CallerIdentity returns the identity of the caller of the component method
+// that received ctx. It returns false if ctx was not passed to a component
+// method by Service Weaver (e.g., it was derived from context.Background()).
+//
+// CallerIdentity can be used to restrict which components are allowed to
+// call a method:
+//
+//	func (p *payments) Charge(ctx context.Context, ...) error {
+//	    caller, ok := weaver.CallerIdentity(ctx)
+//	    if !ok || caller.Component != "example.com/shop/Frontend" {
+//	        return fmt.Errorf("permission denied")
+//	    }
+//	    ...
+//	}
+//
+// Note that a component's name is reported by the weavelet hosting it, whereas
+// Identity is verified using mTLS. A weavelet with a verified identity may
+// host multiple components.
+func CallerIdentity(ctx context.Context) (Caller, bool) {
+	info, ok := codegen.CallerInfoFromContext(ctx)
+	if !ok {
+		return Caller{}, false
+	}
+	return Caller{
+		Component: info.Component,
+		Identity:  info.Identity,
+		Local:     info.Local,
+	}, true
+}
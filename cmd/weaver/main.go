@@ -77,8 +77,18 @@ func main() {
 		generateFlags.Usage = func() {
 			fmt.Fprintln(os.Stderr, generate.Usage)
 		}
+		mocks := generateFlags.Bool("mocks", false, "Generate mocks of component interfaces")
+		allowRefCycles := generateFlags.Bool("allow_ref_cycles", false, "Report cycles of weaver.Ref fields as warnings rather than errors")
+		schema := generateFlags.String("schema", "", `Generate a schema of the components' services ("openapi" or "proto")`)
+		schemaDir := generateFlags.String("schema_dir", "", "Directory to write service schemas to")
 		generateFlags.Parse(flag.Args()[1:]) //nolint:errcheck // does os.Exit on error
-		if err := generate.Generate(".", flag.Args()[1:], generate.Options{}); err != nil {
+		opt := generate.Options{
+			Mocks:          *mocks,
+			AllowRefCycles: *allowRefCycles,
+			Schema:         *schema,
+			SchemaDir:      *schemaDir,
+		}
+		if err := generate.Generate(".", generateFlags.Args(), opt); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
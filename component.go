@@ -15,11 +15,19 @@
 package weaver
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/ServiceWeaver/weaver/internal/reflection"
 	"github.com/ServiceWeaver/weaver/internal/register"
+	"github.com/ServiceWeaver/weaver/metrics"
+	"github.com/ServiceWeaver/weaver/runtime"
 	"github.com/ServiceWeaver/weaver/runtime/codegen"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slog"
@@ -44,6 +52,45 @@ type Instance interface {
 	// Logger returns a logger that associates its log entries with this component.
 	Logger() *slog.Logger
 
+	// Tracer returns the tracer that Service Weaver uses to create the spans
+	// of this component's method calls. Use it to create spans for
+	// sub-operations of a method, passing the context the method received as
+	// the parent, so that the spans become children of the method's span:
+	//
+	//	func (f *foo) Bar(ctx context.Context) error {
+	//	    ctx, span := f.Tracer().Start(ctx, "parse")
+	//	    defer span.End()
+	//	    ...
+	//	}
+	//
+	// Unlike the global OpenTelemetry tracer, spans created with the returned
+	// tracer are exported by the deployer, along with the method spans.
+	Tracer() trace.Tracer
+
+	// Counter, Gauge, and Histogram return metrics with the provided name that
+	// are labeled with the name of this component, under the label
+	// "component". Calls with the same name return the same metric, so these
+	// methods can be called whenever a metric is needed, e.g.:
+	//
+	//	func (f *foo) Init(context.Context) error {
+	//	    f.hits = f.Counter("foo_cache_hits", "Number of cache hits")
+	//	    return nil
+	//	}
+	//
+	// Different components that use the same metric name share the same
+	// metric with different labels; the help text (and bounds, for histograms)
+	// provided by the first caller is used. Like the constructors in the
+	// metrics package, these methods panic if name is already used by another
+	// metric, including a component-scoped metric of a different type.
+	Counter(name, help string) *metrics.Counter
+	Gauge(name, help string) *metrics.Gauge
+	Histogram(name, help string, bounds []float64) *metrics.Histogram
+
+	// Runtime returns information about this component and the deployment
+	// and weavelet hosting it, e.g., to enrich logs or to namespace cache
+	// keys by deployment.
+	Runtime() RuntimeInfo
+
 	// rep is for internal use.
 	rep() *component
 }
@@ -70,14 +117,82 @@ type component struct {
 	info      *codegen.Registration // read-only, once initialized
 	clientTLS *tls.Config           // read-only, once initialized
 
+	// Timeouts of the component's methods, keyed by method name.
+	methodTimeouts map[string]time.Duration // read-only, once initialized
+
+	// If positive, remote calls to the component compress their arguments
+	// and results of at least this many bytes.
+	compressMinBytes int // read-only, once initialized
+
+	// The Hash method of the component's router, or nil. See routerHash.
+	routerHash func(key any) (uint64, bool) // read-only, once initialized
+
+	// Rate limiters of the component's methods, keyed by method name. They
+	// are shared by all the calls this replica of the component executes.
+	rateLimiters map[string]*rateLimiter // read-only, once initialized
+
+	// Allowed callers of the component's methods, keyed by method name.
+	// Methods without an entry may be called by any caller.
+	allowedCallers map[string]*allowedCallers // read-only, once initialized
+
+	// Session affinity settings of calls to the component, or nil if
+	// affinity is disabled. Only set for routed components.
+	affinity *runtime.Affinity // read-only, once initialized
+
+	// The Priority method of the component's prioritizer, or nil. See
+	// callPrioritizer.
+	prioritize func(context.Context) int // read-only, once initialized
+
+	// Schedules the remote calls that this replica of the component executes,
+	// or nil if their number is not limited.
+	scheduler *scheduler // read-only, once initialized
+
+	// Does the component implementation embed Singleton?
+	singleton bool // read-only, once initialized
+
+	// Is the component created when the weavelet starts hosting it, rather
+	// than when it is first used? See EagerKey.
+	eager bool // read-only, once initialized
+
+	// Does a panic in a remote call to the component crash the process? See
+	// CrashOnPanicKey.
+	crashOnPanic bool // read-only, once initialized
+
+	// Can other applications call the component? See ExternalRef.
+	exported bool // read-only, once initialized
+
+	// Admits the remote calls executed by this replica of the component,
+	// unless it is quiesced. See Quiesce.
+	quiesce quiesceGate
+
+	// The Secret fields of the component implementation struct.
+	secrets []secretField // read-only, once initialized
+
+	// The values of the secrets, if they were looked up when the weavelet
+	// started, or nil if they are looked up when the component is
+	// constructed.
+	secretValues []Secret // read-only, once initialized
+
+	// The type of the events the component subscribes to, or nil. See
+	// Subscriber.
+	subscribes reflect.Type // read-only, once initialized
+
+	// Configures the queues of the events published to the component by
+	// this weavelet. See Subscriber.
+	eventQueue runtime.EventQueue // read-only, once initialized
+
 	registerInit sync.Once // used to register the component
 	registerErr  error     // non-nil if registration fails
 
-	implInit sync.Once      // used to initialize impl, logger
-	implErr  error          // non-nil if impl creation fails
+	implMu   sync.Mutex     // guards the initialization of impl, logger
+	implDone bool           // true once impl has been created
+	implErr  error          // non-nil if the last attempt to create impl failed
 	impl     *componentImpl // only ever non-nil if this component is local
-	logger   *slog.Logger   // read-only after implInit.Do()
-	tracer   trace.Tracer   // read-only after implInit.Do()
+	logger   *slog.Logger   // read-only once implDone
+	tracer   trace.Tracer   // read-only once implDone
+
+	// logger, published for LoggerFromContext, which can't wait on implMu.
+	ctxLogger atomic.Pointer[slog.Logger]
 
 	// TODO(mwhittaker): We have one client for every component. Every client
 	// independently maintains network connections to every weavelet hosting
@@ -89,9 +204,7 @@ type component struct {
 	clientInit sync.Once // used to initialize client
 	client     *client   // only evern non-nil if this component is remote or routed
 
-	stubInit sync.Once // used to initialize stub
-	stubErr  error     // non-nil if stub creation fails
-	stub     *stub     // only ever non-nil if this component is remote or routed
+	stub lazyStub // only ever initialized if this component is remote or routed
 
 	local register.WriteOnce[bool] // routed locally?
 	load  *loadCollector           // non-nil for routed components
@@ -102,6 +215,29 @@ var _ Instance = &componentImpl{}
 // Main is interface implemented by an application's main component.
 type Main interface{}
 
+// Initializable is implemented by a component implementation, including the
+// implementation of Main, that needs to run startup logic. The Service Weaver
+// runtime calls Init exactly once, after the implementation's config,
+// weaver.Ref, and weaver.Listener fields are filled, and before any of its
+// methods are invoked. If Init returns an error, the component fails to start,
+// and so does every component and application that depends on it.
+type Initializable interface {
+	Init(context.Context) error
+}
+
+// Finalizable is implemented by a component implementation that needs to
+// release resources (e.g., flush buffers or close database connections) when
+// the application exits gracefully, i.e., when weaver.Run returns. Shutdown is
+// called at most once, and only for components that were initialized
+// successfully in the exiting process. A component is shut down before the
+// components it depends on. The provided ctx has a deadline; implementations
+// should return promptly when it expires.
+//
+// Shutdown is not called when a process is killed or crashes.
+type Finalizable interface {
+	Shutdown(context.Context) error
+}
+
 // Implements[T] is a type that can be embedded inside a component implementation
 // struct to indicate that the struct implements a component of type T. E.g.,
 // consider a Cache component.
@@ -150,11 +286,60 @@ var _ Unrouted = (*componentImpl)(nil)
 // fill such a field with a handle to the corresponding component.
 type Ref[T any] struct {
 	value T
+	id    string
+
+	// replicas returns handles to every replica of the component, for use by
+	// Broadcast. It is nil if the component is local.
+	replicas func(context.Context) ([]replica, error)
+
+	// keyed returns a handle to the component whose calls are routed by the
+	// provided key, for use by ForKey.
+	keyed func(key any) any
 }
 
 // Get returns a handle to the component of type T.
 func (r Ref[T]) Get() T { return r.value }
 
+// ForKey returns a handle to the component of type T whose method calls are
+// all routed by the provided key. Calls made through handles with equal keys
+// tend to be served by the same replica, which lets a caller address a
+// component per tenant, per user, etc., without writing a router. Every call
+// made through the handle also carries the key, formatted with fmt.Sprint, in
+// its metadata under RoutingKeyMetadataKey (see Metadata).
+//
+// The key can be any value that a router method may return (see WithRouter):
+// an integer, a float, a string, or a struct of these. ForKey panics on other
+// keys.
+//
+// If T is routed, the key replaces the routing key computed by T's router for
+// calls made through the handle. Since keys are hashed the same way, by the
+// router's Hash method if it has one, a call through ForKey(k) is routed like
+// a call for which T's router returns k. If T
+// is not routed, calls with the same key are sent to the same replica as long
+// as the set of replicas doesn't change, and T's Balancer is not used. If T is
+// local, all calls are served by the local instance.
+func (r Ref[T]) ForKey(key any) T {
+	if r.keyed == nil {
+		// The Ref hasn't been filled by Service Weaver, or is a fake.
+		return r.value
+	}
+	return r.keyed(key).(T)
+}
+
+// ID returns a stable identifier of the component that r refers to, suitable
+// for use as a map key. Two Refs have the same ID if and only if they refer to
+// the same component, even if they are held by different components or in
+// different processes. The ID is the full name of the component, e.g.,
+// "github.com/example/cache/Cache". The ID of a Ref that Service Weaver hasn't
+// filled is the empty string.
+//
+// A Ref refers to a component, not to a particular replica of it: the ID is
+// the same whether the component is local or remote, and successive method
+// calls through the same Ref may be served by different replicas. Compare Refs
+// using their IDs rather than with ==, which also compares the handles
+// returned by Get, and these differ between the components holding them.
+func (r Ref[T]) ID() string { return r.id }
+
 // isRef is an internal interface that is only implemented by Ref[T] and is
 // used by the implementation to check that a value is of type Ref[T].
 func (r Ref[T]) isRef() {}
@@ -171,13 +356,22 @@ func (r Ref[T]) isRef() {}
 //	}
 //
 // By default, all listeners listen on address ":0". This behavior can be
-// modified by passing options for individual listeners in the application
-// config. For example, to specify local addresses for the above two listeners,
-// the user can add the following lines to the application config file:
+// modified by passing options for individual listeners in the deployer's
+// section of the application config. For example, to specify local addresses
+// for the above two listeners when running with "weaver single", the user can
+// add the following lines to the application config file:
 //
-//	[listeners]
-//	myListener      = {local_address = "localhost:9000"}
-//	myOtherListener = {local_address = "localhost:9001"}
+//	[single]
+//	listeners.myListener      = {address = "localhost:9000"}
+//	listeners.myOtherListener = {address = "localhost:9001"}
+//
+// A listener can also listen on a Unix domain socket, which is convenient for
+// talking to co-located sidecars. The socket file is created with the
+// permissions given by the optional mode parameter, and it is removed when the
+// application shuts down:
+//
+//	[single]
+//	listeners.myListener = {address = "unix:///tmp/myapp.sock?mode=0660"}
 //
 // Listeners are identified by their field names in the component implementation
 // structs (e.g., myListener and myOtherListener).
@@ -195,14 +389,50 @@ func (r Ref[T]) isRef() {}
 // declare a Listener field "foo" in two different component implementation
 // structs, unless one is renamed using the `weaver:"name"` struct tag.
 //
+// A listener can terminate TLS by adding the tls option to its struct tag,
+// e.g., `weaver:"name,tls"` or `weaver:",tls"` to keep the field name. The
+// certificate and private key of such a listener are read from the files
+// specified in the [tls] section of the application config:
+//
+//	[tls]
+//	myListener = {cert_file = "cert.pem", key_file = "key.pem"}
+//
+// The certificate is reloaded from these files when the process receives a
+// SIGHUP. New connections use the reloaded certificate, while established
+// connections are left intact.
+//
+// When clients reach a listener through an address that the process can't
+// discover by itself, e.g., the DNS name and port of an external load
+// balancer, that address can be set in the [listeners] section of the
+// application config:
+//
+//	[listeners]
+//	myListener = {advertise = "api.example.com:443"}
+//
+// The advertised address must have the form <host>:<port>, and it is returned
+// by String and ProxyAddr in place of the listener's proxy or bind address.
+//
 // HTTP servers constructed using this listener are expected to perform
 // health checks on the reserved HealthzURL path. (Note that this
-// URL path is configured to never receive any user traffic.)
+// URL path is configured to never receive any user traffic.) The
+// [Listener.Serve] method takes care of this automatically.
 //
 // [1] https://en.wikipedia.org/wiki/Domain_name
 type Listener struct {
-	net.Listener        // underlying listener
-	proxyAddr    string // address of proxy that forwards to the listener
+	net.Listener               // underlying listener
+	proxyAddr    string        // address of proxy that forwards to the listener
+	tls          bool          // does the listener terminate TLS?
+	sockOpts     SocketOptions // options applied to the listener's socket
+
+	// The following fields are used by Serve and ServeGRPC. They may be nil.
+	ctx       context.Context             // canceled when the weavelet shuts down
+	servers   *activeServers              // servers running on the listener
+	logger    *slog.Logger                // logger of the owning component
+	health    func(context.Context) error // health check of the owning component
+	draining  func() bool                 // is the weavelet shutting down?
+	component string                      // name of the owning component
+	tracer    trace.Tracer                // tracer of the owning component
+	name      string                      // name of the listener
 }
 
 // isListener is an internal interface that is only implemented by Listener and
@@ -210,26 +440,75 @@ type Listener struct {
 func (l Listener) isListener() {}
 
 // String returns the address clients should dial to connect to the
-// listener; this will be the proxy address if available, otherwise
-// the <host>:<port> for this listener.
+// listener; this will be the advertised address if configured, otherwise the
+// proxy address if available, otherwise the <host>:<port> for this listener.
+// IPv6 hosts are enclosed in square brackets, as in "[::1]:80". If the
+// listener terminates TLS, the address is prefixed with "https://".
+//
+// If the listener listens on a Unix domain socket, String returns
+// "unix://<path>", and the listener has no proxy, unless it has an advertised
+// address.
 func (l Listener) String() string {
-	if l.proxyAddr != "" {
-		return l.proxyAddr
+	addr := l.proxyAddr
+	if addr == "" {
+		addr = listenerAddress(l.Listener)
+	}
+	if l.tls && !strings.HasPrefix(addr, unixScheme) {
+		return "https://" + addr
 	}
-	return l.Addr().String()
+	return addr
 }
 
 // ProxyAddr returns the dialable address of the proxy that forwards traffic to
-// this listener, or returns the empty string if there is no such proxy.
+// this listener, or returns the empty string if there is no such proxy. If the
+// listener has an advertised address (see Listener), ProxyAddr returns it. The
+// address has the form <host>:<port>, as accepted by net.Dial, with IPv6
+// hosts enclosed in square brackets.
 func (l *Listener) ProxyAddr() string {
 	return l.proxyAddr
 }
 
+// SocketOptions returns the options applied to the listener's socket, set with
+// the reuseport option of its weaver struct tag and in the [listeners] section
+// of the config file, including the defaults of the options that aren't set.
+// It is meant for debugging. If the listener listens on a Unix domain socket,
+// SocketOptions returns the zero SocketOptions.
+func (l *Listener) SocketOptions() SocketOptions {
+	return l.sockOpts
+}
+
+// normalizeProxyAddr returns the provided proxy address in the <host>:<port>
+// form accepted by net.Dial. Deployers may report the address of a proxy with
+// an IPv6 host without square brackets, e.g., "2001:db8::1:80", which
+// normalizeProxyAddr turns into "[2001:db8::1]:80" by treating everything
+// after the last colon as the port. Other addresses are returned unchanged.
+func normalizeProxyAddr(addr string) string {
+	if addr == "" || strings.HasPrefix(addr, "[") {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return addr
+	}
+	host, port := addr[:i], addr[i+1:]
+	if net.ParseIP(host) == nil {
+		return addr
+	}
+	return net.JoinHostPort(host, port)
+}
+
 func (c *componentImpl) rep() *component { return c.component }
 
 // Logger returns a logger that associates its log entries with this component.
 func (c *componentImpl) Logger() *slog.Logger { return c.component.logger }
 
+// Tracer returns the tracer used to create the spans of this component's
+// method calls.
+func (c *componentImpl) Tracer() trace.Tracer { return c.component.tracer }
+
 // WithRouter[T] is a type that can be embedded inside a component implementation
 // struct to indicate that calls to a method M on the component must be routed according
 // to the the value returned by T.M().
@@ -278,6 +557,63 @@ func (c *componentImpl) Logger() *slog.Logger { return c.component.logger }
 //	func (cacheRouter) Get(_ context.Context, key string) string { return key }
 //	func (cacheRouter) Put(_ context.Context, key, value string) int { return 42 }
 //
+// # Catch-All Routing
+//
+// If the routing key is computed the same way for many methods, the router
+// can instead implement a single Route method with the following signature,
+// where K is the routing key type:
+//
+//	func (cacheRouter) Route(ctx context.Context, method string, args ...any) K
+//
+// Route is invoked for every component method that doesn't have a dedicated
+// router method, with the method name and the method arguments (excluding the
+// context). For example:
+//
+//	func (cacheRouter) Route(_ context.Context, method string, args ...any) string {
+//	    return args[0].(string) // every Cache method takes the key first
+//	}
+//
+// Dedicated router methods take precedence over Route, so a router can
+// implement Route along with router methods for the methods that need to be
+// routed differently. Route must return the same routing key type as the
+// other router methods.
+//
+// # Context Routing
+//
+// Sometimes the routing key isn't a method argument at all, but is carried by
+// the context, e.g., a tenant id propagated with every call. The router can
+// then implement [ContextRouter], extracting the routing key from the context
+// of every component method that doesn't have a dedicated router method:
+//
+//	func (cacheRouter) RouteFromContext(ctx context.Context) K
+//
+// For example, with a tenant id propagated by a [ContextKey]:
+//
+//	func (cacheRouter) RouteFromContext(ctx context.Context) string {
+//	    tenant, _ := tenantKey.Value(ctx)
+//	    return tenant
+//	}
+//
+// As with Route, dedicated router methods take precedence over
+// RouteFromContext, and RouteFromContext must return the same routing key
+// type as the other router methods. A router can't implement both Route and
+// RouteFromContext.
+//
+// # Custom Hashing
+//
+// Routing keys are hashed to pick a replica. By default, every field of a
+// key contributes equally to its hash, which spreads keys evenly when they are
+// equally popular. When they aren't, e.g., when keys are (tenant, shard) pairs
+// and some tenants are much bigger than others, the router can implement a
+// Hash method, where K is the routing key type, to hash keys itself:
+//
+//	func (cacheRouter) Hash(key K) uint64
+//
+// Keys with close hashes tend to be served by the same replica, so a Hash
+// method can, e.g., spread the shards of a big tenant over the whole hash
+// space, while keeping the shards of small tenants together. Hash is also used
+// for the keys passed to Ref.ForKey.
+//
 // # Semantics
 //
 // NOTE that routing is done on a best-effort basis. Service Weaver will try to route
@@ -289,6 +625,16 @@ type WithRouter[T any] struct{}
 //nolint:unused
 func (WithRouter[T]) routedBy(T) {}
 
+// routerType returns T. See routerHash.
+func (WithRouter[T]) routerType() reflect.Type { return reflection.Type[T]() }
+
+// ContextRouter[K] is the interface implemented by a router that extracts
+// routing keys of type K from the context of a call, rather than from the
+// call's arguments. See [WithRouter] for details.
+type ContextRouter[K any] interface {
+	RouteFromContext(ctx context.Context) K
+}
+
 // RoutedBy[T] is the interface implemented by a struct that embeds
 // weaver.RoutedBy[T].
 type RoutedBy[T any] interface {
@@ -327,6 +673,24 @@ type if_youre_seeing_this_you_probably_forgot_to_run_weaver_generate struct{}
 //	    f func()   // functions are not serializable
 //	    c chan int // chans are not serializable
 //	}
+//
+// A field with a `weaver:"-"` struct tag is not serialized, and is left with
+// its zero value when the struct is deserialized. This is handy for fields
+// that are derived from other fields or that cache state, and such fields
+// don't need to be serializable. A field with a `weaver:"name"` struct tag is
+// serialized under the provided name, rather than its Go name, wherever
+// field names are serialized, i.e., in the versioned encoding and in the
+// service schemas generated with "weaver generate -schema". The field can
+// then be renamed in Go without breaking compatibility.
+//
+//	type Session struct {
+//	    weaver.AutoMarshal
+//	    UserID string `weaver:"user"`
+//	    scores []int
+//	    total  int `weaver:"-"` // sum of scores
+//	}
+//
+// The fields of a routing key (see WithRouter) can't be excluded.
 type AutoMarshal struct{}
 
 // TODO(mwhittaker): The following methods have AutoMarshal implement
@@ -368,6 +732,21 @@ func (AutoMarshal) WeaverUnmarshal(*codegen.Decoder) {}
 //	["example.com/mypkg/Cache"]
 //	Size = 1000
 //
+// ## Base Config
+//
+// Settings shared by many components can be specified once, in the [base]
+// section. A field of a component's config is set from the component's own
+// section if that section sets it, otherwise from the [base] section if that
+// section sets it, and otherwise it keeps its zero value. Settings in [base]
+// that a component's config doesn't have are ignored for that component.
+//
+//	[base]
+//	Size = 100
+//	Region = "us-east1"
+//
+//	["example.com/mypkg/Cache"]
+//	Size = 1000  # overrides [base]; Region is inherited
+//
 // ## Field Names
 //
 // You can use `toml` struct tags to specify the name that should be used for a
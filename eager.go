@@ -0,0 +1,70 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/exp/slices"
+)
+
+// startEager creates the eager components among the provided components,
+// which the weavelet hosts, with every component created after the components
+// it depends on. It returns the error of the first component that can't be
+// created. See runtime.EagerKey.
+func (w *weavelet) startEager(components []*component) error {
+	var eager []*component
+	for _, c := range components {
+		if c.eager {
+			eager = append(eager, c)
+		}
+	}
+	if len(eager) == 0 {
+		return nil
+	}
+	sort.Slice(eager, func(i, j int) bool { return eager[i].info.Name < eager[j].info.Name })
+	eager = dependencyOrder(eager)
+
+	w.eagerMu.Lock()
+	for _, c := range eager {
+		if !slices.Contains(w.eager, c) {
+			w.eager = append(w.eager, c)
+		}
+	}
+	w.eagerMu.Unlock()
+
+	for _, c := range eager {
+		w.env.SystemLogger().Debug("Creating eager component", "component", c.info.Name)
+		if _, err := w.getImpl(w.ctx, c); err != nil {
+			return fmt.Errorf("eager component %s: %w", c.info.Name, err)
+		}
+	}
+	return nil
+}
+
+// waitEager waits until the eager components hosted by the weavelet have been
+// created.
+func (w *weavelet) waitEager() error {
+	w.eagerMu.Lock()
+	eager := w.eager
+	w.eagerMu.Unlock()
+	for _, c := range eager {
+		if _, err := w.getImpl(w.ctx, c); err != nil {
+			return fmt.Errorf("eager component %s: %w", c.info.Name, err)
+		}
+	}
+	return nil
+}
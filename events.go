@@ -0,0 +1,579 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/cond"
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// eventMethod is the name of the pseudo-method that delivers events to a
+// subscriber component. Deliveries are authorized, rate limited, scheduled,
+// and measured like calls to a component method of that name, and the handler
+// of the deliveries made by other weavelets is registered under it, alongside
+// the handlers of the component's methods (see addEventHandler). The name is
+// not a Go identifier, so it can't clash with the name of a method.
+const eventMethod = "weaver.Handle"
+
+// Bounds of the backoff between two attempts to deliver an event.
+const (
+	eventRetryMinBackoff = 100 * time.Millisecond
+	eventRetryMaxBackoff = 10 * time.Second
+)
+
+// Publisher[E] is a field that can be placed inside a component
+// implementation struct to publish events of type E to every component that
+// subscribes to them (see Subscriber). E must be a struct type that embeds
+// weaver.AutoMarshal, so that "weaver generate" generates the code to
+// serialize it. For example:
+//
+//	type UserCreated struct {
+//	    weaver.AutoMarshal
+//	    ID   string
+//	    Name string
+//	}
+//
+//	type users struct {
+//	    weaver.Implements[Users]
+//	    created weaver.Publisher[UserCreated]
+//	}
+//
+//	func (u *users) Create(ctx context.Context, name string) error {
+//	    ...
+//	    return u.created.Publish(ctx, UserCreated{ID: id, Name: name})
+//	}
+//
+// Service Weaver automatically fills Publisher fields.
+type Publisher[E any] struct {
+	// publish queues an encoded event for delivery to every subscriber. It
+	// is nil if the Publisher hasn't been filled by Service Weaver.
+	publish func(ctx context.Context, data []byte) error
+}
+
+// Publish queues event for delivery to every component that subscribes to
+// events of type E, and returns without waiting for the deliveries. Every
+// subscriber component handles the event once, on one of its replicas, or
+// more than once if a delivery fails and is retried.
+//
+// Every process that publishes events has a bounded queue for every
+// subscriber, whose size and overflow policy are set by the event_queue
+// setting in the subscriber's config section (see Subscriber). If a queue is
+// full, Publish either drops the oldest event in it or waits for room,
+// returning ctx.Err() if ctx is done first. Events that are still queued when
+// the publishing process exits are lost.
+//
+// Publish returns nil if no component subscribes to events of type E.
+func (p Publisher[E]) Publish(ctx context.Context, event E) error {
+	if p.publish == nil {
+		return fmt.Errorf("weaver.Publisher[%v] was not filled by Service Weaver", reflection.Type[E]())
+	}
+	m, ok := any(&event).(codegen.AutoMarshal)
+	if !ok {
+		return fmt.Errorf("event type %v is not serializable; embed weaver.AutoMarshal in it and run weaver generate", reflection.Type[E]())
+	}
+	enc := codegen.NewEncoder()
+	m.WeaverMarshal(enc)
+	return p.publish(ctx, enc.Data())
+}
+
+// publishedType returns E. It also marks Publisher, so that fillPublishers
+// can find Publisher fields.
+func (Publisher[E]) publishedType() reflect.Type { return reflection.Type[E]() }
+
+// Subscriber[E] is a type that can be embedded inside a component
+// implementation struct to subscribe the component to the events of type E
+// published with a Publisher[E]. The implementation must also implement
+// EventHandler[E], whose Handle method is called for every event. For
+// example:
+//
+//	type welcomer struct {
+//	    weaver.Implements[Welcomer]
+//	    weaver.Subscriber[users.UserCreated]
+//	}
+//
+//	func (w *welcomer) Handle(ctx context.Context, e users.UserCreated) error {
+//	    return w.sendWelcomeMail(ctx, e.ID)
+//	}
+//
+// Events are delivered asynchronously and in publication order, one at a
+// time per publishing process. Every event is delivered at least once while
+// the deployment runs: if Handle returns an error, or if the subscriber can't
+// be reached, the delivery is retried with backoff, and no later event of the
+// same publishing process is delivered until it succeeds. Handle should
+// therefore be idempotent. Panics in Handle are treated like panics in a
+// method call: a delivery from another process that panics fails with an
+// error wrapping ErrPanic, and is retried, unless the subscriber sets
+// crash_on_panic, whereas a panic in a delivery within the publishing process
+// propagates and crashes it.
+//
+// The queue that buffers the events that a process publishes to the
+// subscriber can be configured in the subscriber's config section. size is
+// the maximum number of queued events, 1000 by default, and overflow is what
+// Publish does when the queue is full: "block" (the default) waits for room,
+// and "drop_oldest" drops the oldest queued event.
+//
+//	["example.com/mail/Welcomer"]
+//	event_queue = {size = 10000, overflow = "drop_oldest"}
+//
+// A delivery is admitted like a call to a method named "weaver.Handle" of the
+// subscriber, made by the publishing component, so it is subject to the
+// allowed_callers and rate_limits entries for "weaver.Handle" in the
+// subscriber's config section. Such entries don't apply to a Handle method of
+// the subscriber's interface, if any, nor the other way around.
+//
+// A component can subscribe to a single event type. The runtime records the
+// following metrics, labeled with the event type, the publishing component,
+// and the subscriber component:
+//
+//   - serviceweaver_event_published_count: Number of events queued.
+//   - serviceweaver_event_delivered_count: Number of events handled.
+//   - serviceweaver_event_dropped_count: Number of events dropped because
+//     the queue was full.
+//   - serviceweaver_event_delivery_error_count: Number of failed deliveries,
+//     which are retried.
+//
+// Events are not delivered to components that are replaced by fakes in
+// weavertest.
+type Subscriber[E any] struct{}
+
+// EventHandler[E] is the interface implemented by a component that
+// subscribes to the events of type E. See Subscriber.
+type EventHandler[E any] interface {
+	Handle(ctx context.Context, event E) error
+}
+
+// subscription returns E and EventHandler[E]. See subscribedEvent.
+func (Subscriber[E]) subscription() (event, handler reflect.Type) {
+	return reflection.Type[E](), reflection.Type[EventHandler[E]]()
+}
+
+// handleEncodedEvent decodes an event of type E and passes it to the Handle
+// method of impl, the component implementation that embeds the Subscriber.
+func (Subscriber[E]) handleEncodedEvent(ctx context.Context, impl any, data []byte) error {
+	var event E
+	m, ok := any(&event).(codegen.AutoMarshal)
+	if !ok {
+		return fmt.Errorf("event type %v is not serializable", reflection.Type[E]())
+	}
+	if err := decodeEvent(data, m); err != nil {
+		return fmt.Errorf("decode event %v: %w", reflection.Type[E](), err)
+	}
+	h, ok := impl.(EventHandler[E])
+	if !ok {
+		return fmt.Errorf("%T does not implement weaver.EventHandler[%v]", impl, reflection.Type[E]())
+	}
+	return h.Handle(ctx, event)
+}
+
+// decodeEvent decodes data into m.
+func decodeEvent(data []byte, m codegen.AutoMarshal) (err error) {
+	defer func() { err = codegen.CatchPanics(recover()) }()
+	m.WeaverUnmarshal(codegen.NewDecoder(data))
+	return nil
+}
+
+// subscriber is implemented by component implementations that embed
+// Subscriber.
+type subscriber interface {
+	subscription() (event, handler reflect.Type)
+	handleEncodedEvent(ctx context.Context, impl any, data []byte) error
+}
+
+// subscribedEvent returns the type of the events that the provided component
+// implementation type subscribes to, or nil if it doesn't embed Subscriber.
+// It returns an error if the implementation is not a valid subscriber.
+func subscribedEvent(impl reflect.Type) (reflect.Type, error) {
+	s, ok := reflect.New(impl).Interface().(subscriber)
+	if !ok {
+		return nil, nil
+	}
+	event, handler := s.subscription()
+	if !reflect.PointerTo(impl).Implements(handler) {
+		return nil, fmt.Errorf("%v embeds weaver.Subscriber[%v] but has no method Handle(context.Context, %v) error", impl, event, event)
+	}
+	if err := checkEventType(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// methodNames returns the names of the methods of the provided component
+// whose calls can be configured, e.g., with allowed callers or rate limits:
+// the methods of the component's interface and, if the component is a
+// subscriber, eventMethod.
+func methodNames(info *codegen.Registration) []string {
+	var names []string
+	for i := 0; i < info.Iface.NumMethod(); i++ {
+		names = append(names, info.Iface.Method(i).Name)
+	}
+	if info.Impl == nil {
+		return names
+	}
+	if _, ok := reflect.New(info.Impl).Interface().(subscriber); ok {
+		names = append(names, eventMethod)
+	}
+	return names
+}
+
+// checkEventType returns an error if events of the provided type can't be
+// published.
+func checkEventType(event reflect.Type) error {
+	if !reflect.PointerTo(event).Implements(reflection.Type[codegen.AutoMarshal]()) {
+		return fmt.Errorf("event type %v is not serializable; embed weaver.AutoMarshal in it and run weaver generate", event)
+	}
+	return nil
+}
+
+// eventName returns the name of the provided event type used in metrics,
+// e.g., "example.com/users.UserCreated".
+func eventName(event reflect.Type) string {
+	if event.Name() == "" || event.PkgPath() == "" {
+		return event.String()
+	}
+	return event.PkgPath() + "." + event.Name()
+}
+
+// fillPublishers initializes the Publisher[E] fields in a component
+// implementation struct. impl should be a pointer to the implementation
+// struct, and get should return the publish function of a Publisher[E], when
+// passed the reflect.Type for E.
+func fillPublishers(impl any, get func(reflect.Type) (func(context.Context, []byte) error, error)) error {
+	p := reflect.ValueOf(impl)
+	if p.Kind() != reflect.Pointer {
+		return fmt.Errorf("not a pointer")
+	}
+	s := p.Elem()
+	if s.Kind() != reflect.Struct {
+		return fmt.Errorf("not a struct pointer")
+	}
+	isPublisher := reflection.Type[interface{ publishedType() reflect.Type }]()
+	for i, n := 0, s.NumField(); i < n; i++ {
+		f := s.Field(i)
+		if !f.Type().Implements(isPublisher) {
+			continue
+		}
+		// Note that f may be an unexported field, so we call publishedType
+		// on a zero value of its type rather than on f itself.
+		event := reflect.Zero(f.Type()).Interface().(interface{ publishedType() reflect.Type }).publishedType()
+		publish, err := get(event)
+		if err != nil {
+			return fmt.Errorf("setting field %v.%s: %w", s.Type(), s.Type().Field(i).Name, err)
+		}
+		setPossiblyUnexported(f.Field(0), reflect.ValueOf(publish))
+	}
+	return nil
+}
+
+// getPublisher returns the publish function of a Publisher[E] field of the
+// publisher component, where E is the provided event type.
+func (w *weavelet) getPublisher(publisher string, event reflect.Type) (func(context.Context, []byte) error, error) {
+	if err := checkEventType(event); err != nil {
+		return nil, err
+	}
+	var subscribers []*component
+	for _, c := range w.componentsByName {
+		if _, faked := w.overrides[c.info.Iface]; !faked && c.subscribes == event {
+			subscribers = append(subscribers, c)
+		}
+	}
+	name := eventName(event)
+	return func(ctx context.Context, data []byte) error {
+		for _, sub := range subscribers {
+			e := queuedEvent{
+				labels: codegen.EventLabels{Event: name, Publisher: publisher, Subscriber: sub.info.Name},
+				data:   data,
+			}
+			if err := w.getEventQueue(sub).push(ctx, e); err != nil {
+				return fmt.Errorf("publish %s to %q: %w", name, sub.info.Name, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// getEventQueue returns the queue of the events published by this weavelet
+// to the provided subscriber component, creating it if necessary.
+func (w *weavelet) getEventQueue(sub *component) *eventQueue {
+	w.eventQueuesMu.Lock()
+	defer w.eventQueuesMu.Unlock()
+	if q, ok := w.eventQueues[sub]; ok {
+		return q
+	}
+	q := newEventQueue(sub.eventQueue, func(ctx context.Context, e queuedEvent) error {
+		return w.deliverEvent(ctx, sub, e)
+	}, w.env.SystemLogger().Error)
+	if w.eventQueues == nil {
+		w.eventQueues = map[*component]*eventQueue{}
+	}
+	w.eventQueues[sub] = q
+	go q.run(w.ctx)
+	return q
+}
+
+// deliverEvent delivers an event to the provided subscriber component.
+func (w *weavelet) deliverEvent(ctx context.Context, sub *component, e queuedEvent) error {
+	// Activate the subscriber, as a call through a Ref would.
+	if _, _, err := w.getInstance(ctx, sub, e.labels.Publisher, nil); err != nil {
+		return err
+	}
+	if sub.local.Read() {
+		return w.handleEventLocally(ctx, sub, e.labels.Publisher, e.data)
+	}
+	stub, err := w.getStub(ctx, sub)
+	if err != nil {
+		return err
+	}
+	opts := call.CallOptions{Balancer: stub.balancer, Caller: e.labels.Publisher, Method: eventMethod}
+	_, err = stub.conn.Call(ctx, call.MakeMethodKey(sub.info.Name, eventMethod), e.data, opts)
+	return err
+}
+
+// publishes returns whether the provided component implementation type has a
+// Publisher[E] field, where E is the provided event type.
+func publishes(impl reflect.Type, event reflect.Type) bool {
+	if impl.Kind() != reflect.Struct {
+		return false
+	}
+	isPublisher := reflection.Type[interface{ publishedType() reflect.Type }]()
+	for i, n := 0, impl.NumField(); i < n; i++ {
+		f := impl.Field(i).Type
+		if f.Implements(isPublisher) && reflect.Zero(f).Interface().(interface{ publishedType() reflect.Type }).publishedType() == event {
+			return true
+		}
+	}
+	return false
+}
+
+// mayPublishTo returns whether the weavelet with verified identity peer may
+// deliver events to the provided component, i.e., whether the component is a
+// subscriber and peer hosts a publisher of the events it subscribes to. Without mTLS (peer is empty), any weavelet may
+// deliver events to any subscriber, as it may call any component.
+func (w *weavelet) mayPublishTo(peer string, sub *component) bool {
+	if sub.subscribes == nil {
+		return false
+	}
+	if peer == "" {
+		return true
+	}
+	for name, c := range w.componentsByName {
+		if w.hosts(peer, name) && publishes(c.info.Impl, sub.subscribes) {
+			return true
+		}
+	}
+	return false
+}
+
+// addEventHandler registers the handler that delivers the events published
+// by the weavelet with verified identity peer to the subscriber component
+// sub, if the weavelet may publish to it (see mayPublishTo). Deliveries go
+// through the same steps as remote calls to the subscriber's eventMethod.
+func (w *weavelet) addEventHandler(handlers *call.HandlerMap, sub *component, peer string) {
+	if !w.mayPublishTo(peer, sub) {
+		return
+	}
+	dm := &dispatchMetrics{component: sub.info.Name, method: eventMethod}
+	handlers.Set(sub.info.Name, eventMethod, func(ctx context.Context, data []byte) (res []byte, err error) {
+		ctx, impl, done, err := w.beginRemoteCall(ctx, sub, eventMethod, peer, dm)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		defer w.recoverPanic(sub, eventMethod, &err)
+		return nil, handleEncodedEvent(ctx, sub, impl, data)
+	})
+}
+
+// handleEventLocally passes an event published by the provided publisher
+// component to the local instance of the provided subscriber component,
+// admitting it as a local call to the subscriber's eventMethod would be. Like
+// in a local call, a panic in the subscriber propagates to the caller.
+func (w *weavelet) handleEventLocally(ctx context.Context, sub *component, publisher string, data []byte) error {
+	if err := sub.authorize(publisher, false, eventMethod, false); err != nil {
+		return err
+	}
+	if err := sub.rateLimit(ctx, publisher, eventMethod, false); err != nil {
+		return err
+	}
+	impl, err := w.getImpl(w.ctx, sub)
+	if err != nil {
+		return err
+	}
+	ctx = withWeavelet(ctx, w)
+	ctx = codegen.WithCallerInfo(ctx, codegen.CallerInfo{
+		Component: publisher,
+		Callee:    sub.info.Name,
+	})
+	return handleEncodedEvent(ctx, sub, impl, data)
+}
+
+// handleEncodedEvent passes an encoded event to the Handle method of impl,
+// the implementation of the provided subscriber component.
+func handleEncodedEvent(ctx context.Context, sub *component, impl *componentImpl, data []byte) error {
+	s, ok := impl.impl.(subscriber)
+	if !ok {
+		return fmt.Errorf("component %q is not a subscriber", sub.info.Name)
+	}
+	return s.handleEncodedEvent(ctx, impl.impl, data)
+}
+
+// flushEvents waits, until ctx is done, for the events published by this
+// weavelet to be delivered.
+func (w *weavelet) flushEvents(ctx context.Context) {
+	w.eventQueuesMu.Lock()
+	queues := make([]*eventQueue, 0, len(w.eventQueues))
+	for _, q := range w.eventQueues {
+		queues = append(queues, q)
+	}
+	w.eventQueuesMu.Unlock()
+	for _, q := range queues {
+		if n := q.flush(ctx); n > 0 {
+			w.env.SystemLogger().Error("Events were not delivered before shutdown", "events", n, "err", ctx.Err())
+		}
+	}
+}
+
+// queuedEvent is an encoded event waiting to be delivered to a subscriber.
+type queuedEvent struct {
+	labels codegen.EventLabels
+	data   []byte
+}
+
+// eventQueue buffers the events published by a weavelet to a subscriber
+// component, and delivers them one at a time, in order.
+type eventQueue struct {
+	config   runtime.EventQueue
+	deliver  func(context.Context, queuedEvent) error
+	logError func(msg string, args ...any)
+
+	mu         sync.Mutex
+	changed    *cond.Cond    // broadcast when events are pushed or delivered
+	events     []queuedEvent // queued events, oldest first
+	delivering bool          // is an event being delivered?
+}
+
+// newEventQueue returns a new queue with the provided configuration, which
+// uses deliver to deliver its events and logError to log failed deliveries.
+func newEventQueue(config runtime.EventQueue, deliver func(context.Context, queuedEvent) error, logError func(string, ...any)) *eventQueue {
+	q := &eventQueue{config: config, deliver: deliver, logError: logError}
+	q.changed = cond.NewCond(&q.mu)
+	return q
+}
+
+// push queues an event. If the queue is full, push either drops the oldest
+// queued event or waits for room, per the queue's overflow policy.
+func (q *eventQueue) push(ctx context.Context, e queuedEvent) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.events) >= q.config.Size {
+		if q.config.Overflow == runtime.EventOverflowDropOldest {
+			codegen.EventsDropped.Get(q.events[0].labels).Inc()
+			q.events[0] = queuedEvent{}
+			q.events = q.events[1:]
+			break
+		}
+		if err := q.changed.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	q.events = append(q.events, e)
+	codegen.EventsPublished.Get(e.labels).Inc()
+	q.changed.Broadcast()
+	return nil
+}
+
+// run delivers the queued events until ctx is done.
+func (q *eventQueue) run(ctx context.Context) {
+	for {
+		q.mu.Lock()
+		for len(q.events) == 0 {
+			if err := q.changed.Wait(ctx); err != nil {
+				q.mu.Unlock()
+				return
+			}
+		}
+		e := q.events[0]
+		q.events[0] = queuedEvent{}
+		q.events = q.events[1:]
+		q.delivering = true
+		q.changed.Broadcast()
+		q.mu.Unlock()
+
+		delivered := q.deliverWithRetries(ctx, e)
+
+		q.mu.Lock()
+		q.delivering = false
+		q.changed.Broadcast()
+		q.mu.Unlock()
+		if !delivered {
+			return
+		}
+	}
+}
+
+// deliverWithRetries delivers an event, retrying with backoff until the
+// delivery succeeds or ctx is done. It returns whether the event was
+// delivered.
+func (q *eventQueue) deliverWithRetries(ctx context.Context, e queuedEvent) bool {
+	backoff := eventRetryMinBackoff
+	for {
+		err := q.deliver(ctx, e)
+		if err == nil {
+			codegen.EventsDelivered.Get(e.labels).Inc()
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		codegen.EventDeliveryErrors.Get(e.labels).Inc()
+		q.logError("Delivering event failed; will retry", "err", err, "event", e.labels.Event, "subscriber", e.labels.Subscriber, "backoff", backoff)
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		case <-t.C:
+		}
+		if backoff *= 2; backoff > eventRetryMaxBackoff {
+			backoff = eventRetryMaxBackoff
+		}
+	}
+}
+
+// flush waits, until ctx is done, for the queue to be empty and for no event
+// to be being delivered. It returns the number of events left undelivered.
+func (q *eventQueue) flush(ctx context.Context) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.events) > 0 || q.delivering {
+		if err := q.changed.Wait(ctx); err != nil {
+			n := len(q.events)
+			if q.delivering {
+				n++
+			}
+			return n
+		}
+	}
+	return 0
+}
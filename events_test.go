@@ -0,0 +1,265 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+)
+
+// testEvent is an event with handwritten serialization methods.
+type testEvent struct {
+	N int
+}
+
+func (e *testEvent) WeaverMarshal(enc *codegen.Encoder)   { enc.Int(e.N) }
+func (e *testEvent) WeaverUnmarshal(dec *codegen.Decoder) { e.N = dec.Int() }
+
+type testSubscriber struct {
+	Subscriber[testEvent]
+	got []int
+}
+
+func (s *testSubscriber) Handle(_ context.Context, e testEvent) error {
+	s.got = append(s.got, e.N)
+	return nil
+}
+
+func TestPublishAndHandle(t *testing.T) {
+	var x struct {
+		created Publisher[testEvent]
+	}
+	var published [][]byte
+	err := fillPublishers(&x, func(event reflect.Type) (func(context.Context, []byte) error, error) {
+		if event != reflection.Type[testEvent]() {
+			t.Fatalf("fillPublishers: got event type %v, want testEvent", event)
+		}
+		return func(_ context.Context, data []byte) error {
+			published = append(published, data)
+			return nil
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := x.created.Publish(context.Background(), testEvent{N: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sub := &testSubscriber{}
+	for _, data := range published {
+		if err := sub.handleEncodedEvent(context.Background(), sub, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(sub.got, want) {
+		t.Errorf("handled events: got %v, want %v", sub.got, want)
+	}
+}
+
+type testPublisher struct {
+	created Publisher[testEvent]
+}
+
+func TestMayPublishTo(t *testing.T) {
+	info := func(name string, impl reflect.Type) *codegen.Registration {
+		return &codegen.Registration{Name: name, Iface: reflection.Type[interface{}](), Impl: impl}
+	}
+	sub := &component{info: info("pkg/Sub", reflection.Type[testSubscriber]()), subscribes: reflection.Type[testEvent]()}
+	w := &weavelet{
+		componentsByName: map[string]*component{
+			"pkg/Sub":       sub,
+			"pkg/Publisher": {info: info("pkg/Publisher", reflection.Type[testPublisher]())},
+			"pkg/Group":     {info: info("pkg/Group", reflection.Type[struct{}]())},
+			"pkg/Other":     {info: info("pkg/Other", reflection.Type[struct{}]())},
+		},
+		groups: map[string]string{"pkg/Publisher": "pkg/Group", "pkg/Group": "pkg/Group"},
+		info:   &protos.EnvelopeInfo{DeploymentId: "d1"},
+	}
+	for _, test := range []struct {
+		peer string
+		want bool
+	}{
+		{"", true},             // no mTLS
+		{"d1/pkg/Group", true}, // hosts pkg/Publisher
+		{"d1/pkg/Publisher", false},
+		{"d1/pkg/Other", false},
+		{"d1/pkg/Sub", false},
+		{"d2/pkg/Group", false}, // another deployment
+	} {
+		if got := w.mayPublishTo(test.peer, sub); got != test.want {
+			t.Errorf("mayPublishTo(%q): got %v, want %v", test.peer, got, test.want)
+		}
+	}
+	if w.mayPublishTo("d1/pkg/Group", w.componentsByName["pkg/Other"]) {
+		t.Errorf("mayPublishTo(%q) a component that isn't a subscriber: got true, want false", "d1/pkg/Group")
+	}
+}
+
+func TestAllowedPublishers(t *testing.T) {
+	info := &codegen.Registration{Name: t.Name(), Iface: reflection.Type[interface{}](), Impl: reflection.Type[testSubscriber]()}
+	allowed, err := newAllowedCallers(info, map[string]string{info.Name: `allowed_callers = {"weaver.Handle" = ["pkg/Publisher"]}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &component{info: info, allowedCallers: allowed}
+	if err := c.authorize("pkg/Publisher", false, eventMethod, true); err != nil {
+		t.Errorf("authorize(pkg/Publisher): %v", err)
+	}
+	if err := c.authorize("pkg/Other", false, eventMethod, true); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("authorize(pkg/Other): got %v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestPublishUnfilled(t *testing.T) {
+	var p Publisher[testEvent]
+	if err := p.Publish(context.Background(), testEvent{}); err == nil {
+		t.Fatal("Publish on an unfilled Publisher: unexpected success")
+	}
+}
+
+type noHandler struct {
+	Subscriber[testEvent]
+}
+
+type notSerializable struct{ N int }
+
+type notSerializableSubscriber struct {
+	Subscriber[notSerializable]
+}
+
+func (notSerializableSubscriber) Handle(context.Context, notSerializable) error { return nil }
+
+func TestSubscribedEvent(t *testing.T) {
+	got, err := subscribedEvent(reflection.Type[testSubscriber]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := reflection.Type[testEvent](); got != want {
+		t.Errorf("subscribedEvent(testSubscriber): got %v, want %v", got, want)
+	}
+	if got, err := subscribedEvent(reflection.Type[struct{}]()); got != nil || err != nil {
+		t.Errorf("subscribedEvent(struct{}): got (%v, %v), want (nil, nil)", got, err)
+	}
+
+	for _, test := range []struct {
+		impl reflect.Type
+		want string
+	}{
+		{reflection.Type[noHandler](), "no method Handle"},
+		{reflection.Type[notSerializableSubscriber](), "not serializable"},
+	} {
+		if _, err := subscribedEvent(test.impl); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("subscribedEvent(%v): got %v, want error containing %q", test.impl, err, test.want)
+		}
+	}
+}
+
+func testQueuedEvent(test string, n int) queuedEvent {
+	return queuedEvent{
+		labels: codegen.EventLabels{Event: "testEvent", Publisher: test, Subscriber: "sub"},
+		data:   []byte{byte(n)},
+	}
+}
+
+func TestEventQueueDropsOldest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := runtime.EventQueue{Size: 2, Overflow: runtime.EventOverflowDropOldest}
+	var got []byte
+	q := newEventQueue(cfg, func(_ context.Context, e queuedEvent) error {
+		got = append(got, e.data...)
+		return nil
+	}, t.Logf)
+	for i := 1; i <= 4; i++ {
+		if err := q.push(ctx, testQueuedEvent(t.Name(), i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The queue holds two events, so 1 and 2 were dropped.
+	go q.run(ctx)
+	if n := q.flush(ctx); n != 0 {
+		t.Fatalf("flush: %d events left", n)
+	}
+	if want := []byte{3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered events: got %v, want %v", got, want)
+	}
+}
+
+func TestEventQueueBlocks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := runtime.EventQueue{Size: 1, Overflow: runtime.EventOverflowBlock}
+	q := newEventQueue(cfg, func(context.Context, queuedEvent) error { return nil }, t.Logf)
+	if err := q.push(ctx, testQueuedEvent(t.Name(), 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue is full and isn't drained, so push blocks until its context
+	// is done.
+	pushCtx, pushCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer pushCancel()
+	if err := q.push(pushCtx, testQueuedEvent(t.Name(), 2)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("push to a full queue: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Once the queue is drained, push succeeds.
+	go q.run(ctx)
+	if err := q.push(ctx, testQueuedEvent(t.Name(), 3)); err != nil {
+		t.Fatal(err)
+	}
+	if n := q.flush(ctx); n != 0 {
+		t.Fatalf("flush: %d events left", n)
+	}
+}
+
+func TestEventQueueRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := runtime.EventQueue{Size: 10, Overflow: runtime.EventOverflowBlock}
+	attempts := 0
+	q := newEventQueue(cfg, func(context.Context, queuedEvent) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("subscriber unavailable")
+		}
+		return nil
+	}, t.Logf)
+	if err := q.push(ctx, testQueuedEvent(t.Name(), 1)); err != nil {
+		t.Fatal(err)
+	}
+	go q.run(ctx)
+	if n := q.flush(ctx); n != 0 {
+		t.Fatalf("flush: %d events left", n)
+	}
+	if attempts != 2 {
+		t.Errorf("delivery attempts: got %d, want 2", attempts)
+	}
+}
@@ -15,10 +15,10 @@ import (
 	"time"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -41,13 +41,16 @@ func init() {
 		Iface: reflect.TypeOf((*ImageScaler)(nil)).Elem(),
 		Impl:  reflect.TypeOf(scaler{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return imageScaler_local_stub{impl: impl.(ImageScaler), tracer: tracer, scaleMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", Method: "Scale", Remote: false})}
+			return imageScaler_intercept(imageScaler_local_stub{impl: impl.(ImageScaler), caller: caller, tracer: tracer, scaleMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", Method: "Scale", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return imageScaler_client_stub{stub: stub, scaleMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", Method: "Scale", Remote: true})}
+			return imageScaler_intercept(imageScaler_client_stub{stub: stub, scaleMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", Method: "Scale", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return imageScaler_server_stub{impl: impl.(ImageScaler), addLoad: addLoad}
+			return imageScaler_server_stub{impl: imageScaler_intercept(impl.(ImageScaler), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return imageScaler_intercept(next.(ImageScaler), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -56,13 +59,16 @@ func init() {
 		Iface: reflect.TypeOf((*LocalCache)(nil)).Elem(),
 		Impl:  reflect.TypeOf(localCache{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return localCache_local_stub{impl: impl.(LocalCache), tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Get", Remote: false}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Put", Remote: false})}
+			return localCache_intercept(localCache_local_stub{impl: impl.(LocalCache), caller: caller, tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Get", Remote: false}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Put", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return localCache_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Get", Remote: true}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Put", Remote: true})}
+			return localCache_intercept(localCache_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Get", Remote: true}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Method: "Put", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return localCache_server_stub{impl: impl.(LocalCache), addLoad: addLoad}
+			return localCache_server_stub{impl: localCache_intercept(impl.(LocalCache), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return localCache_intercept(next.(LocalCache), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -72,11 +78,11 @@ func init() {
 		Impl:      reflect.TypeOf(server{}),
 		Listeners: []string{"chat"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return main_local_stub{impl: impl.(weaver.Main), tracer: tracer}
+			return main_local_stub{impl: impl.(weaver.Main), caller: caller, tracer: tracer}
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any { return main_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad}
+			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		},
 		RefData: "⟦7e1a0aa0:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/chat/SQLStore⟧\n⟦ae108c0d:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/chat/ImageScaler⟧\n⟦c86a1d44:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/chat/LocalCache⟧\n⟦7b9a3b0b:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/Main→chat⟧\n",
 	})
@@ -85,13 +91,16 @@ func init() {
 		Iface: reflect.TypeOf((*SQLStore)(nil)).Elem(),
 		Impl:  reflect.TypeOf(sqlStore{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return sQLStore_local_stub{impl: impl.(SQLStore), tracer: tracer, createPostMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreatePost", Remote: false}), createThreadMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreateThread", Remote: false}), getFeedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetFeed", Remote: false}), getImageMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetImage", Remote: false})}
+			return sQLStore_intercept(sQLStore_local_stub{impl: impl.(SQLStore), caller: caller, tracer: tracer, createPostMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreatePost", Remote: false}), createThreadMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreateThread", Remote: false}), getFeedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetFeed", Remote: false}), getImageMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetImage", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return sQLStore_client_stub{stub: stub, createPostMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreatePost", Remote: true}), createThreadMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreateThread", Remote: true}), getFeedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetFeed", Remote: true}), getImageMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetImage", Remote: true})}
+			return sQLStore_intercept(sQLStore_client_stub{stub: stub, createPostMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreatePost", Remote: true}), createThreadMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "CreateThread", Remote: true}), getFeedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetFeed", Remote: true}), getImageMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Method: "GetImage", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return sQLStore_server_stub{impl: impl.(SQLStore), addLoad: addLoad}
+			return sQLStore_server_stub{impl: sQLStore_intercept(impl.(SQLStore), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return sQLStore_intercept(next.(SQLStore), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -113,6 +122,7 @@ var _ weaver.Unrouted = (*sqlStore)(nil)
 
 type imageScaler_local_stub struct {
 	impl         ImageScaler
+	caller       string
 	tracer       trace.Tracer
 	scaleMetrics *codegen.MethodMetrics
 }
@@ -137,11 +147,13 @@ func (s imageScaler_local_stub) Scale(ctx context.Context, a0 []byte, a1 int, a2
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler")
 	return s.impl.Scale(ctx, a0, a1, a2)
 }
 
 type localCache_local_stub struct {
 	impl       LocalCache
+	caller     string
 	tracer     trace.Tracer
 	getMetrics *codegen.MethodMetrics
 	putMetrics *codegen.MethodMetrics
@@ -167,6 +179,7 @@ func (s localCache_local_stub) Get(ctx context.Context, a0 string) (r0 string, e
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/LocalCache")
 	return s.impl.Get(ctx, a0)
 }
 
@@ -187,11 +200,13 @@ func (s localCache_local_stub) Put(ctx context.Context, a0 string, a1 string) (e
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/LocalCache")
 	return s.impl.Put(ctx, a0, a1)
 }
 
 type main_local_stub struct {
 	impl   weaver.Main
+	caller string
 	tracer trace.Tracer
 }
 
@@ -200,6 +215,7 @@ var _ weaver.Main = (*main_local_stub)(nil)
 
 type sQLStore_local_stub struct {
 	impl                SQLStore
+	caller              string
 	tracer              trace.Tracer
 	createPostMetrics   *codegen.MethodMetrics
 	createThreadMetrics *codegen.MethodMetrics
@@ -227,6 +243,7 @@ func (s sQLStore_local_stub) CreatePost(ctx context.Context, a0 string, a1 time.
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore")
 	return s.impl.CreatePost(ctx, a0, a1, a2, a3)
 }
 
@@ -247,6 +264,7 @@ func (s sQLStore_local_stub) CreateThread(ctx context.Context, a0 string, a1 tim
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore")
 	return s.impl.CreateThread(ctx, a0, a1, a2, a3, a4)
 }
 
@@ -267,6 +285,7 @@ func (s sQLStore_local_stub) GetFeed(ctx context.Context, a0 string) (r0 []Threa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore")
 	return s.impl.GetFeed(ctx, a0)
 }
 
@@ -287,6 +306,7 @@ func (s sQLStore_local_stub) GetImage(ctx context.Context, a0 string, a1 ImageID
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore")
 	return s.impl.GetImage(ctx, a0, a1)
 }
 
@@ -303,8 +323,11 @@ var _ ImageScaler = (*imageScaler_client_stub)(nil)
 func (s imageScaler_client_stub) Scale(ctx context.Context, a0 []byte, a1 int, a2 int) (r0 []byte, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.scaleMetrics.Begin()
-	defer func() { s.scaleMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.scaleMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -317,6 +340,7 @@ func (s imageScaler_client_stub) Scale(ctx context.Context, a0 []byte, a1 int, a
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -331,7 +355,7 @@ func (s imageScaler_client_stub) Scale(ctx context.Context, a0 []byte, a1 int, a
 
 	// Preallocate a buffer of the right size.
 	size := 0
-	size += (4 + (len(a0) * 1))
+	size += codegen.BytesSize(a0)
 	size += 8
 	size += 8
 	enc := codegen.NewEncoder()
@@ -344,11 +368,12 @@ func (s imageScaler_client_stub) Scale(ctx context.Context, a0 []byte, a1 int, a
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -372,8 +397,11 @@ var _ LocalCache = (*localCache_client_stub)(nil)
 func (s localCache_client_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getMetrics.Begin()
-	defer func() { s.getMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -386,6 +414,7 @@ func (s localCache_client_stub) Get(ctx context.Context, a0 string) (r0 string,
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -409,11 +438,12 @@ func (s localCache_client_stub) Get(ctx context.Context, a0 string) (r0 string,
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -428,8 +458,11 @@ func (s localCache_client_stub) Get(ctx context.Context, a0 string) (r0 string,
 func (s localCache_client_stub) Put(ctx context.Context, a0 string, a1 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.putMetrics.Begin()
-	defer func() { s.putMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.putMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -442,6 +475,7 @@ func (s localCache_client_stub) Put(ctx context.Context, a0 string, a1 string) (
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -467,11 +501,12 @@ func (s localCache_client_stub) Put(ctx context.Context, a0 string, a1 string) (
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -503,8 +538,11 @@ var _ SQLStore = (*sQLStore_client_stub)(nil)
 func (s sQLStore_client_stub) CreatePost(ctx context.Context, a0 string, a1 time.Time, a2 ThreadID, a3 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.createPostMetrics.Begin()
-	defer func() { s.createPostMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.createPostMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -517,6 +555,7 @@ func (s sQLStore_client_stub) CreatePost(ctx context.Context, a0 string, a1 time
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -538,11 +577,12 @@ func (s sQLStore_client_stub) CreatePost(ctx context.Context, a0 string, a1 time
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -556,8 +596,11 @@ func (s sQLStore_client_stub) CreatePost(ctx context.Context, a0 string, a1 time
 func (s sQLStore_client_stub) CreateThread(ctx context.Context, a0 string, a1 time.Time, a2 []string, a3 string, a4 []byte) (r0 ThreadID, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.createThreadMetrics.Begin()
-	defer func() { s.createThreadMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.createThreadMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -570,6 +613,7 @@ func (s sQLStore_client_stub) CreateThread(ctx context.Context, a0 string, a1 ti
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -592,11 +636,12 @@ func (s sQLStore_client_stub) CreateThread(ctx context.Context, a0 string, a1 ti
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -611,8 +656,11 @@ func (s sQLStore_client_stub) CreateThread(ctx context.Context, a0 string, a1 ti
 func (s sQLStore_client_stub) GetFeed(ctx context.Context, a0 string) (r0 []Thread, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getFeedMetrics.Begin()
-	defer func() { s.getFeedMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getFeedMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -625,6 +673,7 @@ func (s sQLStore_client_stub) GetFeed(ctx context.Context, a0 string) (r0 []Thre
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -648,11 +697,12 @@ func (s sQLStore_client_stub) GetFeed(ctx context.Context, a0 string) (r0 []Thre
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 2, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -667,8 +717,11 @@ func (s sQLStore_client_stub) GetFeed(ctx context.Context, a0 string) (r0 []Thre
 func (s sQLStore_client_stub) GetImage(ctx context.Context, a0 string, a1 ImageID) (r0 []byte, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getImageMetrics.Begin()
-	defer func() { s.getImageMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getImageMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -681,6 +734,7 @@ func (s sQLStore_client_stub) GetImage(ctx context.Context, a0 string, a1 ImageI
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -706,11 +760,12 @@ func (s sQLStore_client_stub) GetImage(ctx context.Context, a0 string, a1 ImageI
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 3, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 3, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -725,8 +780,9 @@ func (s sQLStore_client_stub) GetImage(ctx context.Context, a0 string, a1 ImageI
 // Server stub implementations.
 
 type imageScaler_server_stub struct {
-	impl    ImageScaler
-	addLoad func(key uint64, load float64)
+	impl       ImageScaler
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that imageScaler_server_stub implements the codegen.Server interface.
@@ -742,6 +798,11 @@ func (s imageScaler_server_stub) GetStubFn(method string) func(ctx context.Conte
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s imageScaler_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s imageScaler_server_stub) scale(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -759,10 +820,14 @@ func (s imageScaler_server_stub) scale(ctx context.Context, args []byte) (res []
 	var a2 int
 	a2 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Scale(ctx, a0, a1, a2)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []byte
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/ImageScaler", "Scale", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Scale(ctx, a0, a1, a2)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -772,8 +837,9 @@ func (s imageScaler_server_stub) scale(ctx context.Context, args []byte) (res []
 }
 
 type localCache_server_stub struct {
-	impl    LocalCache
-	addLoad func(key uint64, load float64)
+	impl       LocalCache
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that localCache_server_stub implements the codegen.Server interface.
@@ -791,6 +857,11 @@ func (s localCache_server_stub) GetStubFn(method string) func(ctx context.Contex
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s localCache_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s localCache_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -804,10 +875,14 @@ func (s localCache_server_stub) get(ctx context.Context, args []byte) (res []byt
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Get(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -831,10 +906,12 @@ func (s localCache_server_stub) put(ctx context.Context, args []byte) (res []byt
 	var a1 string
 	a1 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Put(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/LocalCache", "Put", func(ctx context.Context) error {
+		return s.impl.Put(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -843,8 +920,9 @@ func (s localCache_server_stub) put(ctx context.Context, args []byte) (res []byt
 }
 
 type main_server_stub struct {
-	impl    weaver.Main
-	addLoad func(key uint64, load float64)
+	impl       weaver.Main
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that main_server_stub implements the codegen.Server interface.
@@ -858,9 +936,15 @@ func (s main_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s main_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 type sQLStore_server_stub struct {
-	impl    SQLStore
-	addLoad func(key uint64, load float64)
+	impl       SQLStore
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that sQLStore_server_stub implements the codegen.Server interface.
@@ -882,6 +966,11 @@ func (s sQLStore_server_stub) GetStubFn(method string) func(ctx context.Context,
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s sQLStore_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s sQLStore_server_stub) createPost(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -901,10 +990,12 @@ func (s sQLStore_server_stub) createPost(ctx context.Context, args []byte) (res
 	var a3 string
 	a3 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.CreatePost(ctx, a0, a1, a2, a3)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", "CreatePost", func(ctx context.Context) error {
+		return s.impl.CreatePost(ctx, a0, a1, a2, a3)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -933,10 +1024,14 @@ func (s sQLStore_server_stub) createThread(ctx context.Context, args []byte) (re
 	var a4 []byte
 	a4 = serviceweaver_dec_slice_byte_87461245(dec)
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.CreateThread(ctx, a0, a1, a2, a3, a4)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 ThreadID
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", "CreateThread", func(ctx context.Context) (err error) {
+		r0, err = s.impl.CreateThread(ctx, a0, a1, a2, a3, a4)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -958,10 +1053,14 @@ func (s sQLStore_server_stub) getFeed(ctx context.Context, args []byte) (res []b
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetFeed(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []Thread
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", "GetFeed", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetFeed(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -985,10 +1084,14 @@ func (s sQLStore_server_stub) getImage(ctx context.Context, args []byte) (res []
 	var a1 ImageID
 	*(*int64)(&a1) = dec.Int64()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetImage(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []byte
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/chat/SQLStore", "GetImage", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetImage(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -997,6 +1100,116 @@ func (s sQLStore_server_stub) getImage(ctx context.Context, args []byte) (res []
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type imageScaler_intercept_stub struct {
+	next        ImageScaler
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that imageScaler_intercept_stub implements the ImageScaler interface.
+var _ ImageScaler = (*imageScaler_intercept_stub)(nil)
+
+// imageScaler_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func imageScaler_intercept(next ImageScaler, interceptor codegen.Interceptor, call codegen.Call) ImageScaler {
+	if interceptor == nil {
+		return next
+	}
+	return imageScaler_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s imageScaler_intercept_stub) Scale(ctx context.Context, a0 []byte, a1 int, a2 int) (r0 []byte, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Scale", []any{a0, a1, a2}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Scale(ctx, codegen.Arg[[]byte](args, 0), codegen.Arg[int](args, 1), codegen.Arg[int](args, 2))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]byte](results, 0), err
+}
+
+type localCache_intercept_stub struct {
+	next        LocalCache
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that localCache_intercept_stub implements the LocalCache interface.
+var _ LocalCache = (*localCache_intercept_stub)(nil)
+
+// localCache_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func localCache_intercept(next LocalCache, interceptor codegen.Interceptor, call codegen.Call) LocalCache {
+	if interceptor == nil {
+		return next
+	}
+	return localCache_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s localCache_intercept_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s localCache_intercept_stub) Put(ctx context.Context, a0 string, a1 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Put", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Put(ctx, codegen.Arg[string](args, 0), codegen.Arg[string](args, 1))
+	})
+	return err
+}
+
+type sQLStore_intercept_stub struct {
+	next        SQLStore
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that sQLStore_intercept_stub implements the SQLStore interface.
+var _ SQLStore = (*sQLStore_intercept_stub)(nil)
+
+// sQLStore_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func sQLStore_intercept(next SQLStore, interceptor codegen.Interceptor, call codegen.Call) SQLStore {
+	if interceptor == nil {
+		return next
+	}
+	return sQLStore_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s sQLStore_intercept_stub) CreatePost(ctx context.Context, a0 string, a1 time.Time, a2 ThreadID, a3 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "CreatePost", []any{a0, a1, a2, a3}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.CreatePost(ctx, codegen.Arg[string](args, 0), codegen.Arg[time.Time](args, 1), codegen.Arg[ThreadID](args, 2), codegen.Arg[string](args, 3))
+	})
+	return err
+}
+
+func (s sQLStore_intercept_stub) CreateThread(ctx context.Context, a0 string, a1 time.Time, a2 []string, a3 string, a4 []byte) (r0 ThreadID, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "CreateThread", []any{a0, a1, a2, a3, a4}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.CreateThread(ctx, codegen.Arg[string](args, 0), codegen.Arg[time.Time](args, 1), codegen.Arg[[]string](args, 2), codegen.Arg[string](args, 3), codegen.Arg[[]byte](args, 4))
+		return []any{r0}, err
+	})
+	return codegen.Result[ThreadID](results, 0), err
+}
+
+func (s sQLStore_intercept_stub) GetFeed(ctx context.Context, a0 string) (r0 []Thread, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetFeed", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetFeed(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]Thread](results, 0), err
+}
+
+func (s sQLStore_intercept_stub) GetImage(ctx context.Context, a0 string, a1 ImageID) (r0 []byte, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetImage", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetImage(ctx, codegen.Arg[string](args, 0), codegen.Arg[ImageID](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]byte](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*Post)(nil)
@@ -1086,26 +1299,11 @@ func serviceweaver_dec_slice_Post_29a9ee83(dec *codegen.Decoder) []Post {
 // Encoding/decoding implementations.
 
 func serviceweaver_enc_slice_byte_87461245(enc *codegen.Encoder, arg []byte) {
-	if arg == nil {
-		enc.Len(-1)
-		return
-	}
-	enc.Len(len(arg))
-	for i := 0; i < len(arg); i++ {
-		enc.Byte(arg[i])
-	}
+	enc.Bytes(arg)
 }
 
 func serviceweaver_dec_slice_byte_87461245(dec *codegen.Decoder) []byte {
-	n := dec.Len()
-	if n == -1 {
-		return nil
-	}
-	res := make([]byte, n)
-	for i := 0; i < n; i++ {
-		res[i] = dec.Byte()
-	}
-	return res
+	return dec.Bytes()
 }
 
 func serviceweaver_enc_slice_string_4af10117(enc *codegen.Encoder, arg []string) {
@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -39,13 +39,16 @@ func init() {
 		Iface: reflect.TypeOf((*Even)(nil)).Elem(),
 		Impl:  reflect.TypeOf(even{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return even_local_stub{impl: impl.(Even), tracer: tracer, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even", Method: "Do", Remote: false})}
+			return even_intercept(even_local_stub{impl: impl.(Even), caller: caller, tracer: tracer, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even", Method: "Do", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return even_client_stub{stub: stub, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even", Method: "Do", Remote: true})}
+			return even_intercept(even_client_stub{stub: stub, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even", Method: "Do", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return even_server_stub{impl: impl.(Even), addLoad: addLoad}
+			return even_server_stub{impl: even_intercept(impl.(Even), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/collatz/Even", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return even_intercept(next.(Even), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -55,11 +58,11 @@ func init() {
 		Impl:      reflect.TypeOf(server{}),
 		Listeners: []string{"collatz"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return main_local_stub{impl: impl.(weaver.Main), tracer: tracer}
+			return main_local_stub{impl: impl.(weaver.Main), caller: caller, tracer: tracer}
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any { return main_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad}
+			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		},
 		RefData: "⟦f95ad2dd:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/collatz/Odd⟧\n⟦987c175b:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/collatz/Even⟧\n⟦f3b62957:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/Main→collatz⟧\n",
 	})
@@ -68,13 +71,16 @@ func init() {
 		Iface: reflect.TypeOf((*Odd)(nil)).Elem(),
 		Impl:  reflect.TypeOf(odd{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return odd_local_stub{impl: impl.(Odd), tracer: tracer, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd", Method: "Do", Remote: false})}
+			return odd_intercept(odd_local_stub{impl: impl.(Odd), caller: caller, tracer: tracer, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd", Method: "Do", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return odd_client_stub{stub: stub, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd", Method: "Do", Remote: true})}
+			return odd_intercept(odd_client_stub{stub: stub, doMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd", Method: "Do", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return odd_server_stub{impl: impl.(Odd), addLoad: addLoad}
+			return odd_server_stub{impl: odd_intercept(impl.(Odd), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/collatz/Odd", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return odd_intercept(next.(Odd), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -94,6 +100,7 @@ var _ weaver.Unrouted = (*odd)(nil)
 
 type even_local_stub struct {
 	impl      Even
+	caller    string
 	tracer    trace.Tracer
 	doMetrics *codegen.MethodMetrics
 }
@@ -118,11 +125,13 @@ func (s even_local_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/collatz/Even")
 	return s.impl.Do(ctx, a0)
 }
 
 type main_local_stub struct {
 	impl   weaver.Main
+	caller string
 	tracer trace.Tracer
 }
 
@@ -131,6 +140,7 @@ var _ weaver.Main = (*main_local_stub)(nil)
 
 type odd_local_stub struct {
 	impl      Odd
+	caller    string
 	tracer    trace.Tracer
 	doMetrics *codegen.MethodMetrics
 }
@@ -155,6 +165,7 @@ func (s odd_local_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/collatz/Odd")
 	return s.impl.Do(ctx, a0)
 }
 
@@ -171,8 +182,11 @@ var _ Even = (*even_client_stub)(nil)
 func (s even_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.doMetrics.Begin()
-	defer func() { s.doMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.doMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -185,6 +199,7 @@ func (s even_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -208,11 +223,12 @@ func (s even_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -242,8 +258,11 @@ var _ Odd = (*odd_client_stub)(nil)
 func (s odd_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.doMetrics.Begin()
-	defer func() { s.doMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.doMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -256,6 +275,7 @@ func (s odd_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -279,11 +299,12 @@ func (s odd_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -298,8 +319,9 @@ func (s odd_client_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
 // Server stub implementations.
 
 type even_server_stub struct {
-	impl    Even
-	addLoad func(key uint64, load float64)
+	impl       Even
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that even_server_stub implements the codegen.Server interface.
@@ -315,6 +337,11 @@ func (s even_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s even_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s even_server_stub) do(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -328,10 +355,14 @@ func (s even_server_stub) do(ctx context.Context, args []byte) (res []byte, err
 	var a0 int
 	a0 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Do(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/collatz/Even", "Do", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Do(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -341,8 +372,9 @@ func (s even_server_stub) do(ctx context.Context, args []byte) (res []byte, err
 }
 
 type main_server_stub struct {
-	impl    weaver.Main
-	addLoad func(key uint64, load float64)
+	impl       weaver.Main
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that main_server_stub implements the codegen.Server interface.
@@ -356,9 +388,15 @@ func (s main_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s main_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 type odd_server_stub struct {
-	impl    Odd
-	addLoad func(key uint64, load float64)
+	impl       Odd
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that odd_server_stub implements the codegen.Server interface.
@@ -374,6 +412,11 @@ func (s odd_server_stub) GetStubFn(method string) func(ctx context.Context, args
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s odd_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s odd_server_stub) do(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -387,10 +430,14 @@ func (s odd_server_stub) do(ctx context.Context, args []byte) (res []byte, err e
 	var a0 int
 	a0 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Do(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/collatz/Odd", "Do", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Do(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -398,3 +445,57 @@ func (s odd_server_stub) do(ctx context.Context, args []byte) (res []byte, err e
 	enc.Error(appErr)
 	return enc.Data(), nil
 }
+
+// Intercept stub implementations.
+
+type even_intercept_stub struct {
+	next        Even
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that even_intercept_stub implements the Even interface.
+var _ Even = (*even_intercept_stub)(nil)
+
+// even_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func even_intercept(next Even, interceptor codegen.Interceptor, call codegen.Call) Even {
+	if interceptor == nil {
+		return next
+	}
+	return even_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s even_intercept_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Do", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Do(ctx, codegen.Arg[int](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[int](results, 0), err
+}
+
+type odd_intercept_stub struct {
+	next        Odd
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that odd_intercept_stub implements the Odd interface.
+var _ Odd = (*odd_intercept_stub)(nil)
+
+// odd_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func odd_intercept(next Odd, interceptor codegen.Interceptor, call codegen.Call) Odd {
+	if interceptor == nil {
+		return next
+	}
+	return odd_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s odd_intercept_stub) Do(ctx context.Context, a0 int) (r0 int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Do", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Do(ctx, codegen.Arg[int](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[int](results, 0), err
+}
@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Impl:   reflect.TypeOf(factorer{}),
 		Routed: true,
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return factorer_local_stub{impl: impl.(Factorer), tracer: tracer, factorsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer", Method: "Factors", Remote: false})}
+			return factorer_intercept(factorer_local_stub{impl: impl.(Factorer), caller: caller, tracer: tracer, factorsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer", Method: "Factors", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return factorer_client_stub{stub: stub, factorsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer", Method: "Factors", Remote: true})}
+			return factorer_intercept(factorer_client_stub{stub: stub, factorsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer", Method: "Factors", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return factorer_server_stub{impl: impl.(Factorer), addLoad: addLoad}
+			return factorer_server_stub{impl: factorer_intercept(impl.(Factorer), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/factors/Factorer", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return factorer_intercept(next.(Factorer), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -56,11 +59,11 @@ func init() {
 		Impl:      reflect.TypeOf(server{}),
 		Listeners: []string{"factors"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return main_local_stub{impl: impl.(weaver.Main), tracer: tracer}
+			return main_local_stub{impl: impl.(weaver.Main), caller: caller, tracer: tracer}
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any { return main_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad}
+			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		},
 		RefData: "⟦4724da9b:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/factors/Factorer⟧\n⟦68699208:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/Main→factors⟧\n",
 	})
@@ -81,6 +84,7 @@ var _ func(_ context.Context, x int) int = (&router{}).Factors // routed
 
 type factorer_local_stub struct {
 	impl           Factorer
+	caller         string
 	tracer         trace.Tracer
 	factorsMetrics *codegen.MethodMetrics
 }
@@ -105,11 +109,13 @@ func (s factorer_local_stub) Factors(ctx context.Context, a0 int) (r0 []int, err
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/factors/Factorer")
 	return s.impl.Factors(ctx, a0)
 }
 
 type main_local_stub struct {
 	impl   weaver.Main
+	caller string
 	tracer trace.Tracer
 }
 
@@ -129,8 +135,11 @@ var _ Factorer = (*factorer_client_stub)(nil)
 func (s factorer_client_stub) Factors(ctx context.Context, a0 int) (r0 []int, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.factorsMetrics.Begin()
-	defer func() { s.factorsMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.factorsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -143,6 +152,7 @@ func (s factorer_client_stub) Factors(ctx context.Context, a0 int) (r0 []int, er
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -169,11 +179,12 @@ func (s factorer_client_stub) Factors(ctx context.Context, a0 int) (r0 []int, er
 	shardKey := _hashFactorer(r.Factors(ctx, a0))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -195,8 +206,9 @@ var _ weaver.Main = (*main_client_stub)(nil)
 // Server stub implementations.
 
 type factorer_server_stub struct {
-	impl    Factorer
-	addLoad func(key uint64, load float64)
+	impl       Factorer
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that factorer_server_stub implements the codegen.Server interface.
@@ -212,6 +224,11 @@ func (s factorer_server_stub) GetStubFn(method string) func(ctx context.Context,
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s factorer_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s factorer_server_stub) factors(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -225,12 +242,18 @@ func (s factorer_server_stub) factors(ctx context.Context, args []byte) (res []b
 	var a0 int
 	a0 = dec.Int()
 	var r router
-	s.addLoad(_hashFactorer(r.Factors(ctx, a0)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Factors(ctx, a0)
+	loadKey := _hashFactorer(r.Factors(ctx, a0))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/factors/Factorer", "Factors", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Factors(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -240,8 +263,9 @@ func (s factorer_server_stub) factors(ctx context.Context, args []byte) (res []b
 }
 
 type main_server_stub struct {
-	impl    weaver.Main
-	addLoad func(key uint64, load float64)
+	impl       weaver.Main
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that main_server_stub implements the codegen.Server interface.
@@ -255,6 +279,39 @@ func (s main_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s main_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+// Intercept stub implementations.
+
+type factorer_intercept_stub struct {
+	next        Factorer
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that factorer_intercept_stub implements the Factorer interface.
+var _ Factorer = (*factorer_intercept_stub)(nil)
+
+// factorer_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func factorer_intercept(next Factorer, interceptor codegen.Interceptor, call codegen.Call) Factorer {
+	if interceptor == nil {
+		return next
+	}
+	return factorer_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s factorer_intercept_stub) Factors(ctx context.Context, a0 int) (r0 []int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Factors", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Factors(ctx, codegen.Arg[int](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]int](results, 0), err
+}
+
 // Router methods.
 
 // _hashFactorer returns a 64 bit hash of the provided value.
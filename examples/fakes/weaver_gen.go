@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -39,13 +39,16 @@ func init() {
 		Iface: reflect.TypeOf((*Clock)(nil)).Elem(),
 		Impl:  reflect.TypeOf(clock{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return clock_local_stub{impl: impl.(Clock), tracer: tracer, unixMicroMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock", Method: "UnixMicro", Remote: false})}
+			return clock_intercept(clock_local_stub{impl: impl.(Clock), caller: caller, tracer: tracer, unixMicroMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock", Method: "UnixMicro", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return clock_client_stub{stub: stub, unixMicroMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock", Method: "UnixMicro", Remote: true})}
+			return clock_intercept(clock_client_stub{stub: stub, unixMicroMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock", Method: "UnixMicro", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return clock_server_stub{impl: impl.(Clock), addLoad: addLoad}
+			return clock_server_stub{impl: clock_intercept(impl.(Clock), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/fakes/Clock", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return clock_intercept(next.(Clock), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -61,6 +64,7 @@ var _ weaver.Unrouted = (*clock)(nil)
 
 type clock_local_stub struct {
 	impl             Clock
+	caller           string
 	tracer           trace.Tracer
 	unixMicroMetrics *codegen.MethodMetrics
 }
@@ -85,6 +89,7 @@ func (s clock_local_stub) UnixMicro(ctx context.Context) (r0 int64, err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/fakes/Clock")
 	return s.impl.UnixMicro(ctx)
 }
 
@@ -101,8 +106,11 @@ var _ Clock = (*clock_client_stub)(nil)
 func (s clock_client_stub) UnixMicro(ctx context.Context) (r0 int64, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.unixMicroMetrics.Begin()
-	defer func() { s.unixMicroMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.unixMicroMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -115,6 +123,7 @@ func (s clock_client_stub) UnixMicro(ctx context.Context) (r0 int64, err error)
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -134,6 +143,7 @@ func (s clock_client_stub) UnixMicro(ctx context.Context) (r0 int64, err error)
 	results, err = s.stub.Run(ctx, 0, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -148,8 +158,9 @@ func (s clock_client_stub) UnixMicro(ctx context.Context) (r0 int64, err error)
 // Server stub implementations.
 
 type clock_server_stub struct {
-	impl    Clock
-	addLoad func(key uint64, load float64)
+	impl       Clock
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that clock_server_stub implements the codegen.Server interface.
@@ -165,6 +176,11 @@ func (s clock_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s clock_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s clock_server_stub) unixMicro(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -173,10 +189,14 @@ func (s clock_server_stub) unixMicro(ctx context.Context, args []byte) (res []by
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.UnixMicro(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int64
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/fakes/Clock", "UnixMicro", func(ctx context.Context) (err error) {
+		r0, err = s.impl.UnixMicro(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -184,3 +204,31 @@ func (s clock_server_stub) unixMicro(ctx context.Context, args []byte) (res []by
 	enc.Error(appErr)
 	return enc.Data(), nil
 }
+
+// Intercept stub implementations.
+
+type clock_intercept_stub struct {
+	next        Clock
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that clock_intercept_stub implements the Clock interface.
+var _ Clock = (*clock_intercept_stub)(nil)
+
+// clock_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func clock_intercept(next Clock, interceptor codegen.Interceptor, call codegen.Call) Clock {
+	if interceptor == nil {
+		return next
+	}
+	return clock_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s clock_intercept_stub) UnixMicro(ctx context.Context) (r0 int64, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "UnixMicro", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.UnixMicro(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[int64](results, 0), err
+}
@@ -11,10 +11,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -37,11 +37,11 @@ func init() {
 		Iface: reflect.TypeOf((*weaver.Main)(nil)).Elem(),
 		Impl:  reflect.TypeOf(app{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return main_local_stub{impl: impl.(weaver.Main), tracer: tracer}
+			return main_local_stub{impl: impl.(weaver.Main), caller: caller, tracer: tracer}
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any { return main_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad}
+			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		},
 		RefData: "",
 	})
@@ -57,6 +57,7 @@ var _ weaver.Unrouted = (*app)(nil)
 
 type main_local_stub struct {
 	impl   weaver.Main
+	caller string
 	tracer trace.Tracer
 }
 
@@ -75,8 +76,9 @@ var _ weaver.Main = (*main_client_stub)(nil)
 // Server stub implementations.
 
 type main_server_stub struct {
-	impl    weaver.Main
-	addLoad func(key uint64, load float64)
+	impl       weaver.Main
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that main_server_stub implements the codegen.Server interface.
@@ -89,3 +91,8 @@ func (s main_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 		return nil
 	}
 }
+
+// Use implements the codegen.Server interface.
+func (s main_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
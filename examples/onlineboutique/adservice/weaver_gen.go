@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*T)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return t_local_stub{impl: impl.(T), tracer: tracer, getAdsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", Method: "GetAds", Remote: false})}
+			return t_intercept(t_local_stub{impl: impl.(T), caller: caller, tracer: tracer, getAdsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", Method: "GetAds", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return t_client_stub{stub: stub, getAdsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", Method: "GetAds", Remote: true})}
+			return t_intercept(t_client_stub{stub: stub, getAdsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", Method: "GetAds", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return t_server_stub{impl: impl.(T), addLoad: addLoad}
+			return t_server_stub{impl: t_intercept(impl.(T), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return t_intercept(next.(T), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -62,6 +65,7 @@ var _ weaver.Unrouted = (*impl)(nil)
 
 type t_local_stub struct {
 	impl          T
+	caller        string
 	tracer        trace.Tracer
 	getAdsMetrics *codegen.MethodMetrics
 }
@@ -86,6 +90,7 @@ func (s t_local_stub) GetAds(ctx context.Context, a0 []string) (r0 []Ad, err err
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T")
 	return s.impl.GetAds(ctx, a0)
 }
 
@@ -102,8 +107,11 @@ var _ T = (*t_client_stub)(nil)
 func (s t_client_stub) GetAds(ctx context.Context, a0 []string) (r0 []Ad, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getAdsMetrics.Begin()
-	defer func() { s.getAdsMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getAdsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -116,6 +124,7 @@ func (s t_client_stub) GetAds(ctx context.Context, a0 []string) (r0 []Ad, err er
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -134,11 +143,12 @@ func (s t_client_stub) GetAds(ctx context.Context, a0 []string) (r0 []Ad, err er
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -153,8 +163,9 @@ func (s t_client_stub) GetAds(ctx context.Context, a0 []string) (r0 []Ad, err er
 // Server stub implementations.
 
 type t_server_stub struct {
-	impl    T
-	addLoad func(key uint64, load float64)
+	impl       T
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that t_server_stub implements the codegen.Server interface.
@@ -170,6 +181,11 @@ func (s t_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s t_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s t_server_stub) getAds(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -183,10 +199,14 @@ func (s t_server_stub) getAds(ctx context.Context, args []byte) (res []byte, err
 	var a0 []string
 	a0 = serviceweaver_dec_slice_string_4af10117(dec)
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetAds(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []Ad
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/adservice/T", "GetAds", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetAds(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -195,6 +215,34 @@ func (s t_server_stub) getAds(ctx context.Context, args []byte) (res []byte, err
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type t_intercept_stub struct {
+	next        T
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that t_intercept_stub implements the T interface.
+var _ T = (*t_intercept_stub)(nil)
+
+// t_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func t_intercept(next T, interceptor codegen.Interceptor, call codegen.Call) T {
+	if interceptor == nil {
+		return next
+	}
+	return t_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s t_intercept_stub) GetAds(ctx context.Context, a0 []string) (r0 []Ad, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetAds", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetAds(ctx, codegen.Arg[[]string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]Ad](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*Ad)(nil)
@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*T)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return t_local_stub{impl: impl.(T), tracer: tracer, addItemMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "AddItem", Remote: false}), emptyCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "EmptyCart", Remote: false}), getCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "GetCart", Remote: false})}
+			return t_intercept(t_local_stub{impl: impl.(T), caller: caller, tracer: tracer, addItemMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "AddItem", Remote: false}), emptyCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "EmptyCart", Remote: false}), getCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "GetCart", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return t_client_stub{stub: stub, addItemMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "AddItem", Remote: true}), emptyCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "EmptyCart", Remote: true}), getCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "GetCart", Remote: true})}
+			return t_intercept(t_client_stub{stub: stub, addItemMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "AddItem", Remote: true}), emptyCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "EmptyCart", Remote: true}), getCartMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Method: "GetCart", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return t_server_stub{impl: impl.(T), addLoad: addLoad}
+			return t_server_stub{impl: t_intercept(impl.(T), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return t_intercept(next.(T), interceptor, call)
 		},
 		RefData: "⟦e78910e9:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache⟧\n",
 	})
@@ -56,13 +59,16 @@ func init() {
 		Impl:   reflect.TypeOf(cartCacheImpl{}),
 		Routed: true,
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return cartCache_local_stub{impl: impl.(cartCache), tracer: tracer, addMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Add", Remote: false}), getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Get", Remote: false}), removeMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Remove", Remote: false})}
+			return cartCache_intercept(cartCache_local_stub{impl: impl.(cartCache), caller: caller, tracer: tracer, addMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Add", Remote: false}), getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Get", Remote: false}), removeMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Remove", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return cartCache_client_stub{stub: stub, addMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Add", Remote: true}), getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Get", Remote: true}), removeMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Remove", Remote: true})}
+			return cartCache_intercept(cartCache_client_stub{stub: stub, addMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Add", Remote: true}), getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Get", Remote: true}), removeMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Method: "Remove", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return cartCache_server_stub{impl: impl.(cartCache), addLoad: addLoad}
+			return cartCache_server_stub{impl: cartCache_intercept(impl.(cartCache), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return cartCache_intercept(next.(cartCache), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -85,6 +91,7 @@ var _ func(_ context.Context, key string) string = (&cartCacheRouter{}).Remove
 
 type t_local_stub struct {
 	impl             T
+	caller           string
 	tracer           trace.Tracer
 	addItemMetrics   *codegen.MethodMetrics
 	emptyCartMetrics *codegen.MethodMetrics
@@ -111,6 +118,7 @@ func (s t_local_stub) AddItem(ctx context.Context, a0 string, a1 CartItem) (err
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T")
 	return s.impl.AddItem(ctx, a0, a1)
 }
 
@@ -131,6 +139,7 @@ func (s t_local_stub) EmptyCart(ctx context.Context, a0 string) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T")
 	return s.impl.EmptyCart(ctx, a0)
 }
 
@@ -151,11 +160,13 @@ func (s t_local_stub) GetCart(ctx context.Context, a0 string) (r0 []CartItem, er
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T")
 	return s.impl.GetCart(ctx, a0)
 }
 
 type cartCache_local_stub struct {
 	impl          cartCache
+	caller        string
 	tracer        trace.Tracer
 	addMetrics    *codegen.MethodMetrics
 	getMetrics    *codegen.MethodMetrics
@@ -182,6 +193,7 @@ func (s cartCache_local_stub) Add(ctx context.Context, a0 string, a1 []CartItem)
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache")
 	return s.impl.Add(ctx, a0, a1)
 }
 
@@ -202,6 +214,7 @@ func (s cartCache_local_stub) Get(ctx context.Context, a0 string) (r0 []CartItem
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache")
 	return s.impl.Get(ctx, a0)
 }
 
@@ -222,6 +235,7 @@ func (s cartCache_local_stub) Remove(ctx context.Context, a0 string) (r0 bool, e
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache")
 	return s.impl.Remove(ctx, a0)
 }
 
@@ -240,8 +254,11 @@ var _ T = (*t_client_stub)(nil)
 func (s t_client_stub) AddItem(ctx context.Context, a0 string, a1 CartItem) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.addItemMetrics.Begin()
-	defer func() { s.addItemMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.addItemMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -254,6 +271,7 @@ func (s t_client_stub) AddItem(ctx context.Context, a0 string, a1 CartItem) (err
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -279,11 +297,12 @@ func (s t_client_stub) AddItem(ctx context.Context, a0 string, a1 CartItem) (err
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -297,8 +316,11 @@ func (s t_client_stub) AddItem(ctx context.Context, a0 string, a1 CartItem) (err
 func (s t_client_stub) EmptyCart(ctx context.Context, a0 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.emptyCartMetrics.Begin()
-	defer func() { s.emptyCartMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.emptyCartMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -311,6 +333,7 @@ func (s t_client_stub) EmptyCart(ctx context.Context, a0 string) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -334,11 +357,12 @@ func (s t_client_stub) EmptyCart(ctx context.Context, a0 string) (err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -352,8 +376,11 @@ func (s t_client_stub) EmptyCart(ctx context.Context, a0 string) (err error) {
 func (s t_client_stub) GetCart(ctx context.Context, a0 string) (r0 []CartItem, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getCartMetrics.Begin()
-	defer func() { s.getCartMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getCartMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -366,6 +393,7 @@ func (s t_client_stub) GetCart(ctx context.Context, a0 string) (r0 []CartItem, e
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -389,11 +417,12 @@ func (s t_client_stub) GetCart(ctx context.Context, a0 string) (r0 []CartItem, e
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 2, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -418,8 +447,11 @@ var _ cartCache = (*cartCache_client_stub)(nil)
 func (s cartCache_client_stub) Add(ctx context.Context, a0 string, a1 []CartItem) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.addMetrics.Begin()
-	defer func() { s.addMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.addMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -432,6 +464,7 @@ func (s cartCache_client_stub) Add(ctx context.Context, a0 string, a1 []CartItem
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -454,11 +487,12 @@ func (s cartCache_client_stub) Add(ctx context.Context, a0 string, a1 []CartItem
 	shardKey := _hashCartCache(r.Add(ctx, a0, a1))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -472,8 +506,11 @@ func (s cartCache_client_stub) Add(ctx context.Context, a0 string, a1 []CartItem
 func (s cartCache_client_stub) Get(ctx context.Context, a0 string) (r0 []CartItem, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getMetrics.Begin()
-	defer func() { s.getMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -486,6 +523,7 @@ func (s cartCache_client_stub) Get(ctx context.Context, a0 string) (r0 []CartIte
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -512,11 +550,12 @@ func (s cartCache_client_stub) Get(ctx context.Context, a0 string) (r0 []CartIte
 	shardKey := _hashCartCache(r.Get(ctx, a0))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -531,8 +570,11 @@ func (s cartCache_client_stub) Get(ctx context.Context, a0 string) (r0 []CartIte
 func (s cartCache_client_stub) Remove(ctx context.Context, a0 string) (r0 bool, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.removeMetrics.Begin()
-	defer func() { s.removeMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.removeMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -545,6 +587,7 @@ func (s cartCache_client_stub) Remove(ctx context.Context, a0 string) (r0 bool,
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -571,11 +614,12 @@ func (s cartCache_client_stub) Remove(ctx context.Context, a0 string) (r0 bool,
 	shardKey := _hashCartCache(r.Remove(ctx, a0))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 2, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -590,8 +634,9 @@ func (s cartCache_client_stub) Remove(ctx context.Context, a0 string) (r0 bool,
 // Server stub implementations.
 
 type t_server_stub struct {
-	impl    T
-	addLoad func(key uint64, load float64)
+	impl       T
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that t_server_stub implements the codegen.Server interface.
@@ -611,6 +656,11 @@ func (s t_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s t_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s t_server_stub) addItem(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -626,10 +676,12 @@ func (s t_server_stub) addItem(ctx context.Context, args []byte) (res []byte, er
 	var a1 CartItem
 	(&a1).WeaverUnmarshal(dec)
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.AddItem(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", "AddItem", func(ctx context.Context) error {
+		return s.impl.AddItem(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -650,10 +702,12 @@ func (s t_server_stub) emptyCart(ctx context.Context, args []byte) (res []byte,
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.EmptyCart(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", "EmptyCart", func(ctx context.Context) error {
+		return s.impl.EmptyCart(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -674,10 +728,14 @@ func (s t_server_stub) getCart(ctx context.Context, args []byte) (res []byte, er
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetCart(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []CartItem
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T", "GetCart", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetCart(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -687,8 +745,9 @@ func (s t_server_stub) getCart(ctx context.Context, args []byte) (res []byte, er
 }
 
 type cartCache_server_stub struct {
-	impl    cartCache
-	addLoad func(key uint64, load float64)
+	impl       cartCache
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that cartCache_server_stub implements the codegen.Server interface.
@@ -708,6 +767,11 @@ func (s cartCache_server_stub) GetStubFn(method string) func(ctx context.Context
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s cartCache_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s cartCache_server_stub) add(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -723,12 +787,16 @@ func (s cartCache_server_stub) add(ctx context.Context, args []byte) (res []byte
 	var a1 []CartItem
 	a1 = serviceweaver_dec_slice_CartItem_7a7ff11c(dec)
 	var r cartCacheRouter
-	s.addLoad(_hashCartCache(r.Add(ctx, a0, a1)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Add(ctx, a0, a1)
+	loadKey := _hashCartCache(r.Add(ctx, a0, a1))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", "Add", func(ctx context.Context) error {
+		return s.impl.Add(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -749,12 +817,18 @@ func (s cartCache_server_stub) get(ctx context.Context, args []byte) (res []byte
 	var a0 string
 	a0 = dec.String()
 	var r cartCacheRouter
-	s.addLoad(_hashCartCache(r.Get(ctx, a0)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Get(ctx, a0)
+	loadKey := _hashCartCache(r.Get(ctx, a0))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []CartItem
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -776,12 +850,18 @@ func (s cartCache_server_stub) remove(ctx context.Context, args []byte) (res []b
 	var a0 string
 	a0 = dec.String()
 	var r cartCacheRouter
-	s.addLoad(_hashCartCache(r.Remove(ctx, a0)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Remove(ctx, a0)
+	loadKey := _hashCartCache(r.Remove(ctx, a0))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 bool
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/cartCache", "Remove", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Remove(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -790,6 +870,89 @@ func (s cartCache_server_stub) remove(ctx context.Context, args []byte) (res []b
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type t_intercept_stub struct {
+	next        T
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that t_intercept_stub implements the T interface.
+var _ T = (*t_intercept_stub)(nil)
+
+// t_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func t_intercept(next T, interceptor codegen.Interceptor, call codegen.Call) T {
+	if interceptor == nil {
+		return next
+	}
+	return t_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s t_intercept_stub) AddItem(ctx context.Context, a0 string, a1 CartItem) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "AddItem", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.AddItem(ctx, codegen.Arg[string](args, 0), codegen.Arg[CartItem](args, 1))
+	})
+	return err
+}
+
+func (s t_intercept_stub) EmptyCart(ctx context.Context, a0 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "EmptyCart", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.EmptyCart(ctx, codegen.Arg[string](args, 0))
+	})
+	return err
+}
+
+func (s t_intercept_stub) GetCart(ctx context.Context, a0 string) (r0 []CartItem, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetCart", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetCart(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]CartItem](results, 0), err
+}
+
+type cartCache_intercept_stub struct {
+	next        cartCache
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that cartCache_intercept_stub implements the cartCache interface.
+var _ cartCache = (*cartCache_intercept_stub)(nil)
+
+// cartCache_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func cartCache_intercept(next cartCache, interceptor codegen.Interceptor, call codegen.Call) cartCache {
+	if interceptor == nil {
+		return next
+	}
+	return cartCache_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s cartCache_intercept_stub) Add(ctx context.Context, a0 string, a1 []CartItem) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Add", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Add(ctx, codegen.Arg[string](args, 0), codegen.Arg[[]CartItem](args, 1))
+	})
+	return err
+}
+
+func (s cartCache_intercept_stub) Get(ctx context.Context, a0 string) (r0 []CartItem, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]CartItem](results, 0), err
+}
+
+func (s cartCache_intercept_stub) Remove(ctx context.Context, a0 string) (r0 bool, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Remove", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Remove(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[bool](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*CartItem)(nil)
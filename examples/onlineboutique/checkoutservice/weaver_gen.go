@@ -17,10 +17,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -43,13 +43,16 @@ func init() {
 		Iface: reflect.TypeOf((*T)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return t_local_stub{impl: impl.(T), tracer: tracer, placeOrderMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", Method: "PlaceOrder", Remote: false})}
+			return t_intercept(t_local_stub{impl: impl.(T), caller: caller, tracer: tracer, placeOrderMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", Method: "PlaceOrder", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return t_client_stub{stub: stub, placeOrderMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", Method: "PlaceOrder", Remote: true})}
+			return t_intercept(t_client_stub{stub: stub, placeOrderMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", Method: "PlaceOrder", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return t_server_stub{impl: impl.(T), addLoad: addLoad}
+			return t_server_stub{impl: t_intercept(impl.(T), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return t_intercept(next.(T), interceptor, call)
 		},
 		RefData: "⟦4c9a54a7:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T⟧\n⟦74479326:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/cartservice/T⟧\n⟦7395fba7:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T⟧\n⟦ae088216:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/shippingservice/T⟧\n⟦43860cf2:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T⟧\n⟦54f6b59f:wEaVeReDgE:github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T→github.com/ServiceWeaver/weaver/examples/onlineboutique/paymentservice/T⟧\n",
 	})
@@ -65,6 +68,7 @@ var _ weaver.Unrouted = (*impl)(nil)
 
 type t_local_stub struct {
 	impl              T
+	caller            string
 	tracer            trace.Tracer
 	placeOrderMetrics *codegen.MethodMetrics
 }
@@ -89,6 +93,7 @@ func (s t_local_stub) PlaceOrder(ctx context.Context, a0 PlaceOrderRequest) (r0
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T")
 	return s.impl.PlaceOrder(ctx, a0)
 }
 
@@ -105,8 +110,11 @@ var _ T = (*t_client_stub)(nil)
 func (s t_client_stub) PlaceOrder(ctx context.Context, a0 PlaceOrderRequest) (r0 types.Order, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.placeOrderMetrics.Begin()
-	defer func() { s.placeOrderMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.placeOrderMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -119,6 +127,7 @@ func (s t_client_stub) PlaceOrder(ctx context.Context, a0 PlaceOrderRequest) (r0
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -137,11 +146,12 @@ func (s t_client_stub) PlaceOrder(ctx context.Context, a0 PlaceOrderRequest) (r0
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -156,8 +166,9 @@ func (s t_client_stub) PlaceOrder(ctx context.Context, a0 PlaceOrderRequest) (r0
 // Server stub implementations.
 
 type t_server_stub struct {
-	impl    T
-	addLoad func(key uint64, load float64)
+	impl       T
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that t_server_stub implements the codegen.Server interface.
@@ -173,6 +184,11 @@ func (s t_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s t_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s t_server_stub) placeOrder(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -186,10 +202,14 @@ func (s t_server_stub) placeOrder(ctx context.Context, args []byte) (res []byte,
 	var a0 PlaceOrderRequest
 	(&a0).WeaverUnmarshal(dec)
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PlaceOrder(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 types.Order
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/checkoutservice/T", "PlaceOrder", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PlaceOrder(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -198,6 +218,34 @@ func (s t_server_stub) placeOrder(ctx context.Context, args []byte) (res []byte,
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type t_intercept_stub struct {
+	next        T
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that t_intercept_stub implements the T interface.
+var _ T = (*t_intercept_stub)(nil)
+
+// t_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func t_intercept(next T, interceptor codegen.Interceptor, call codegen.Call) T {
+	if interceptor == nil {
+		return next
+	}
+	return t_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s t_intercept_stub) PlaceOrder(ctx context.Context, a0 PlaceOrderRequest) (r0 types.Order, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PlaceOrder", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PlaceOrder(ctx, codegen.Arg[PlaceOrderRequest](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[types.Order](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*PlaceOrderRequest)(nil)
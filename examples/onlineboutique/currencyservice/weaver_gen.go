@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*T)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return t_local_stub{impl: impl.(T), tracer: tracer, convertMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "Convert", Remote: false}), getSupportedCurrenciesMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "GetSupportedCurrencies", Remote: false})}
+			return t_intercept(t_local_stub{impl: impl.(T), caller: caller, tracer: tracer, convertMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "Convert", Remote: false}), getSupportedCurrenciesMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "GetSupportedCurrencies", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return t_client_stub{stub: stub, convertMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "Convert", Remote: true}), getSupportedCurrenciesMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "GetSupportedCurrencies", Remote: true})}
+			return t_intercept(t_client_stub{stub: stub, convertMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "Convert", Remote: true}), getSupportedCurrenciesMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Method: "GetSupportedCurrencies", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return t_server_stub{impl: impl.(T), addLoad: addLoad}
+			return t_server_stub{impl: t_intercept(impl.(T), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return t_intercept(next.(T), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -62,6 +65,7 @@ var _ weaver.Unrouted = (*impl)(nil)
 
 type t_local_stub struct {
 	impl                          T
+	caller                        string
 	tracer                        trace.Tracer
 	convertMetrics                *codegen.MethodMetrics
 	getSupportedCurrenciesMetrics *codegen.MethodMetrics
@@ -87,6 +91,7 @@ func (s t_local_stub) Convert(ctx context.Context, a0 money.T, a1 string) (r0 mo
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T")
 	return s.impl.Convert(ctx, a0, a1)
 }
 
@@ -107,6 +112,7 @@ func (s t_local_stub) GetSupportedCurrencies(ctx context.Context) (r0 []string,
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T")
 	return s.impl.GetSupportedCurrencies(ctx)
 }
 
@@ -124,8 +130,11 @@ var _ T = (*t_client_stub)(nil)
 func (s t_client_stub) Convert(ctx context.Context, a0 money.T, a1 string) (r0 money.T, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.convertMetrics.Begin()
-	defer func() { s.convertMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.convertMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -138,6 +147,7 @@ func (s t_client_stub) Convert(ctx context.Context, a0 money.T, a1 string) (r0 m
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -157,11 +167,12 @@ func (s t_client_stub) Convert(ctx context.Context, a0 money.T, a1 string) (r0 m
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -176,8 +187,11 @@ func (s t_client_stub) Convert(ctx context.Context, a0 money.T, a1 string) (r0 m
 func (s t_client_stub) GetSupportedCurrencies(ctx context.Context) (r0 []string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getSupportedCurrenciesMetrics.Begin()
-	defer func() { s.getSupportedCurrenciesMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getSupportedCurrenciesMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -190,6 +204,7 @@ func (s t_client_stub) GetSupportedCurrencies(ctx context.Context) (r0 []string,
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -209,6 +224,7 @@ func (s t_client_stub) GetSupportedCurrencies(ctx context.Context) (r0 []string,
 	results, err = s.stub.Run(ctx, 1, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -223,8 +239,9 @@ func (s t_client_stub) GetSupportedCurrencies(ctx context.Context) (r0 []string,
 // Server stub implementations.
 
 type t_server_stub struct {
-	impl    T
-	addLoad func(key uint64, load float64)
+	impl       T
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that t_server_stub implements the codegen.Server interface.
@@ -242,6 +259,11 @@ func (s t_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s t_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s t_server_stub) convert(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -257,10 +279,14 @@ func (s t_server_stub) convert(ctx context.Context, args []byte) (res []byte, er
 	var a1 string
 	a1 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Convert(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 money.T
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", "Convert", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Convert(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -277,10 +303,14 @@ func (s t_server_stub) getSupportedCurrencies(ctx context.Context, args []byte)
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetSupportedCurrencies(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/currencyservice/T", "GetSupportedCurrencies", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetSupportedCurrencies(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -289,6 +319,42 @@ func (s t_server_stub) getSupportedCurrencies(ctx context.Context, args []byte)
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type t_intercept_stub struct {
+	next        T
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that t_intercept_stub implements the T interface.
+var _ T = (*t_intercept_stub)(nil)
+
+// t_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func t_intercept(next T, interceptor codegen.Interceptor, call codegen.Call) T {
+	if interceptor == nil {
+		return next
+	}
+	return t_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s t_intercept_stub) Convert(ctx context.Context, a0 money.T, a1 string) (r0 money.T, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Convert", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Convert(ctx, codegen.Arg[money.T](args, 0), codegen.Arg[string](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[money.T](results, 0), err
+}
+
+func (s t_intercept_stub) GetSupportedCurrencies(ctx context.Context) (r0 []string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetSupportedCurrencies", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetSupportedCurrencies(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[[]string](results, 0), err
+}
+
 // Encoding/decoding implementations.
 
 func serviceweaver_enc_slice_string_4af10117(enc *codegen.Encoder, arg []string) {
@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*T)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return t_local_stub{impl: impl.(T), tracer: tracer, sendOrderConfirmationMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", Method: "SendOrderConfirmation", Remote: false})}
+			return t_intercept(t_local_stub{impl: impl.(T), caller: caller, tracer: tracer, sendOrderConfirmationMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", Method: "SendOrderConfirmation", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return t_client_stub{stub: stub, sendOrderConfirmationMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", Method: "SendOrderConfirmation", Remote: true})}
+			return t_intercept(t_client_stub{stub: stub, sendOrderConfirmationMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", Method: "SendOrderConfirmation", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return t_server_stub{impl: impl.(T), addLoad: addLoad}
+			return t_server_stub{impl: t_intercept(impl.(T), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return t_intercept(next.(T), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -62,6 +65,7 @@ var _ weaver.Unrouted = (*impl)(nil)
 
 type t_local_stub struct {
 	impl                         T
+	caller                       string
 	tracer                       trace.Tracer
 	sendOrderConfirmationMetrics *codegen.MethodMetrics
 }
@@ -86,6 +90,7 @@ func (s t_local_stub) SendOrderConfirmation(ctx context.Context, a0 string, a1 t
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T")
 	return s.impl.SendOrderConfirmation(ctx, a0, a1)
 }
 
@@ -102,8 +107,11 @@ var _ T = (*t_client_stub)(nil)
 func (s t_client_stub) SendOrderConfirmation(ctx context.Context, a0 string, a1 types.Order) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.sendOrderConfirmationMetrics.Begin()
-	defer func() { s.sendOrderConfirmationMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.sendOrderConfirmationMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -116,6 +124,7 @@ func (s t_client_stub) SendOrderConfirmation(ctx context.Context, a0 string, a1
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -135,11 +144,12 @@ func (s t_client_stub) SendOrderConfirmation(ctx context.Context, a0 string, a1
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -153,8 +163,9 @@ func (s t_client_stub) SendOrderConfirmation(ctx context.Context, a0 string, a1
 // Server stub implementations.
 
 type t_server_stub struct {
-	impl    T
-	addLoad func(key uint64, load float64)
+	impl       T
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that t_server_stub implements the codegen.Server interface.
@@ -170,6 +181,11 @@ func (s t_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s t_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s t_server_stub) sendOrderConfirmation(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -185,13 +201,42 @@ func (s t_server_stub) sendOrderConfirmation(ctx context.Context, args []byte) (
 	var a1 types.Order
 	(&a1).WeaverUnmarshal(dec)
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.SendOrderConfirmation(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/emailservice/T", "SendOrderConfirmation", func(ctx context.Context) error {
+		return s.impl.SendOrderConfirmation(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
 	enc.Error(appErr)
 	return enc.Data(), nil
 }
+
+// Intercept stub implementations.
+
+type t_intercept_stub struct {
+	next        T
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that t_intercept_stub implements the T interface.
+var _ T = (*t_intercept_stub)(nil)
+
+// t_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func t_intercept(next T, interceptor codegen.Interceptor, call codegen.Call) T {
+	if interceptor == nil {
+		return next
+	}
+	return t_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s t_intercept_stub) SendOrderConfirmation(ctx context.Context, a0 string, a1 types.Order) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "SendOrderConfirmation", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.SendOrderConfirmation(ctx, codegen.Arg[string](args, 0), codegen.Arg[types.Order](args, 1))
+	})
+	return err
+}
@@ -15,10 +15,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -41,13 +41,16 @@ func init() {
 		Iface: reflect.TypeOf((*T)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return t_local_stub{impl: impl.(T), tracer: tracer, getProductMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "GetProduct", Remote: false}), listProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "ListProducts", Remote: false}), searchProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "SearchProducts", Remote: false})}
+			return t_intercept(t_local_stub{impl: impl.(T), caller: caller, tracer: tracer, getProductMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "GetProduct", Remote: false}), listProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "ListProducts", Remote: false}), searchProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "SearchProducts", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return t_client_stub{stub: stub, getProductMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "GetProduct", Remote: true}), listProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "ListProducts", Remote: true}), searchProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "SearchProducts", Remote: true})}
+			return t_intercept(t_client_stub{stub: stub, getProductMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "GetProduct", Remote: true}), listProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "ListProducts", Remote: true}), searchProductsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Method: "SearchProducts", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return t_server_stub{impl: impl.(T), addLoad: addLoad}
+			return t_server_stub{impl: t_intercept(impl.(T), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return t_intercept(next.(T), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -63,6 +66,7 @@ var _ weaver.Unrouted = (*impl)(nil)
 
 type t_local_stub struct {
 	impl                  T
+	caller                string
 	tracer                trace.Tracer
 	getProductMetrics     *codegen.MethodMetrics
 	listProductsMetrics   *codegen.MethodMetrics
@@ -89,6 +93,7 @@ func (s t_local_stub) GetProduct(ctx context.Context, a0 string) (r0 Product, er
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T")
 	return s.impl.GetProduct(ctx, a0)
 }
 
@@ -109,6 +114,7 @@ func (s t_local_stub) ListProducts(ctx context.Context) (r0 []Product, err error
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T")
 	return s.impl.ListProducts(ctx)
 }
 
@@ -129,6 +135,7 @@ func (s t_local_stub) SearchProducts(ctx context.Context, a0 string) (r0 []Produ
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T")
 	return s.impl.SearchProducts(ctx, a0)
 }
 
@@ -147,8 +154,11 @@ var _ T = (*t_client_stub)(nil)
 func (s t_client_stub) GetProduct(ctx context.Context, a0 string) (r0 Product, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getProductMetrics.Begin()
-	defer func() { s.getProductMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getProductMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -161,6 +171,7 @@ func (s t_client_stub) GetProduct(ctx context.Context, a0 string) (r0 Product, e
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -184,11 +195,12 @@ func (s t_client_stub) GetProduct(ctx context.Context, a0 string) (r0 Product, e
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -203,8 +215,11 @@ func (s t_client_stub) GetProduct(ctx context.Context, a0 string) (r0 Product, e
 func (s t_client_stub) ListProducts(ctx context.Context) (r0 []Product, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.listProductsMetrics.Begin()
-	defer func() { s.listProductsMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.listProductsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -217,6 +232,7 @@ func (s t_client_stub) ListProducts(ctx context.Context) (r0 []Product, err erro
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -236,6 +252,7 @@ func (s t_client_stub) ListProducts(ctx context.Context) (r0 []Product, err erro
 	results, err = s.stub.Run(ctx, 1, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -250,8 +267,11 @@ func (s t_client_stub) ListProducts(ctx context.Context) (r0 []Product, err erro
 func (s t_client_stub) SearchProducts(ctx context.Context, a0 string) (r0 []Product, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.searchProductsMetrics.Begin()
-	defer func() { s.searchProductsMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.searchProductsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -264,6 +284,7 @@ func (s t_client_stub) SearchProducts(ctx context.Context, a0 string) (r0 []Prod
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -287,11 +308,12 @@ func (s t_client_stub) SearchProducts(ctx context.Context, a0 string) (r0 []Prod
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 2, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -306,8 +328,9 @@ func (s t_client_stub) SearchProducts(ctx context.Context, a0 string) (r0 []Prod
 // Server stub implementations.
 
 type t_server_stub struct {
-	impl    T
-	addLoad func(key uint64, load float64)
+	impl       T
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that t_server_stub implements the codegen.Server interface.
@@ -327,6 +350,11 @@ func (s t_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s t_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s t_server_stub) getProduct(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -340,10 +368,14 @@ func (s t_server_stub) getProduct(ctx context.Context, args []byte) (res []byte,
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetProduct(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 Product
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", "GetProduct", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetProduct(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -360,10 +392,14 @@ func (s t_server_stub) listProducts(ctx context.Context, args []byte) (res []byt
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.ListProducts(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []Product
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", "ListProducts", func(ctx context.Context) (err error) {
+		r0, err = s.impl.ListProducts(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -385,10 +421,14 @@ func (s t_server_stub) searchProducts(ctx context.Context, args []byte) (res []b
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.SearchProducts(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []Product
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/onlineboutique/productcatalogservice/T", "SearchProducts", func(ctx context.Context) (err error) {
+		r0, err = s.impl.SearchProducts(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -397,6 +437,50 @@ func (s t_server_stub) searchProducts(ctx context.Context, args []byte) (res []b
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type t_intercept_stub struct {
+	next        T
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that t_intercept_stub implements the T interface.
+var _ T = (*t_intercept_stub)(nil)
+
+// t_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func t_intercept(next T, interceptor codegen.Interceptor, call codegen.Call) T {
+	if interceptor == nil {
+		return next
+	}
+	return t_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s t_intercept_stub) GetProduct(ctx context.Context, a0 string) (r0 Product, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetProduct", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetProduct(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[Product](results, 0), err
+}
+
+func (s t_intercept_stub) ListProducts(ctx context.Context) (r0 []Product, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "ListProducts", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.ListProducts(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[[]Product](results, 0), err
+}
+
+func (s t_intercept_stub) SearchProducts(ctx context.Context, a0 string) (r0 []Product, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "SearchProducts", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.SearchProducts(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]Product](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*Product)(nil)
@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,11 +40,11 @@ func init() {
 		Impl:      reflect.TypeOf(server{}),
 		Listeners: []string{"reverser"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return main_local_stub{impl: impl.(weaver.Main), tracer: tracer}
+			return main_local_stub{impl: impl.(weaver.Main), caller: caller, tracer: tracer}
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any { return main_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad}
+			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		},
 		RefData: "⟦b78b74f4:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/examples/reverser/Reverser⟧\n⟦7c420fb8:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/Main→reverser⟧\n",
 	})
@@ -53,13 +53,16 @@ func init() {
 		Iface: reflect.TypeOf((*Reverser)(nil)).Elem(),
 		Impl:  reflect.TypeOf(reverser{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return reverser_local_stub{impl: impl.(Reverser), tracer: tracer, reverseMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", Method: "Reverse", Remote: false})}
+			return reverser_intercept(reverser_local_stub{impl: impl.(Reverser), caller: caller, tracer: tracer, reverseMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", Method: "Reverse", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return reverser_client_stub{stub: stub, reverseMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", Method: "Reverse", Remote: true})}
+			return reverser_intercept(reverser_client_stub{stub: stub, reverseMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", Method: "Reverse", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return reverser_server_stub{impl: impl.(Reverser), addLoad: addLoad}
+			return reverser_server_stub{impl: reverser_intercept(impl.(Reverser), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return reverser_intercept(next.(Reverser), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -77,6 +80,7 @@ var _ weaver.Unrouted = (*reverser)(nil)
 
 type main_local_stub struct {
 	impl   weaver.Main
+	caller string
 	tracer trace.Tracer
 }
 
@@ -85,6 +89,7 @@ var _ weaver.Main = (*main_local_stub)(nil)
 
 type reverser_local_stub struct {
 	impl           Reverser
+	caller         string
 	tracer         trace.Tracer
 	reverseMetrics *codegen.MethodMetrics
 }
@@ -109,6 +114,7 @@ func (s reverser_local_stub) Reverse(ctx context.Context, a0 string) (r0 string,
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/examples/reverser/Reverser")
 	return s.impl.Reverse(ctx, a0)
 }
 
@@ -132,8 +138,11 @@ var _ Reverser = (*reverser_client_stub)(nil)
 func (s reverser_client_stub) Reverse(ctx context.Context, a0 string) (r0 string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.reverseMetrics.Begin()
-	defer func() { s.reverseMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.reverseMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -146,6 +155,7 @@ func (s reverser_client_stub) Reverse(ctx context.Context, a0 string) (r0 string
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -169,11 +179,12 @@ func (s reverser_client_stub) Reverse(ctx context.Context, a0 string) (r0 string
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -188,8 +199,9 @@ func (s reverser_client_stub) Reverse(ctx context.Context, a0 string) (r0 string
 // Server stub implementations.
 
 type main_server_stub struct {
-	impl    weaver.Main
-	addLoad func(key uint64, load float64)
+	impl       weaver.Main
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that main_server_stub implements the codegen.Server interface.
@@ -203,9 +215,15 @@ func (s main_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s main_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 type reverser_server_stub struct {
-	impl    Reverser
-	addLoad func(key uint64, load float64)
+	impl       Reverser
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that reverser_server_stub implements the codegen.Server interface.
@@ -221,6 +239,11 @@ func (s reverser_server_stub) GetStubFn(method string) func(ctx context.Context,
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s reverser_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s reverser_server_stub) reverse(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -234,10 +257,14 @@ func (s reverser_server_stub) reverse(ctx context.Context, args []byte) (res []b
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Reverse(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/examples/reverser/Reverser", "Reverse", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Reverse(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -245,3 +272,31 @@ func (s reverser_server_stub) reverse(ctx context.Context, args []byte) (res []b
 	enc.Error(appErr)
 	return enc.Data(), nil
 }
+
+// Intercept stub implementations.
+
+type reverser_intercept_stub struct {
+	next        Reverser
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that reverser_intercept_stub implements the Reverser interface.
+var _ Reverser = (*reverser_intercept_stub)(nil)
+
+// reverser_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func reverser_intercept(next Reverser, interceptor codegen.Interceptor, call codegen.Call) Reverser {
+	if interceptor == nil {
+		return next
+	}
+	return reverser_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s reverser_intercept_stub) Reverse(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Reverse", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Reverse(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
@@ -0,0 +1,503 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/exports"
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+const (
+	// Key and short key of the app config section that holds the addresses
+	// of the components of other applications referenced by ExternalRef
+	// fields, keyed by full component name. For example:
+	//
+	//	[externals]
+	//	"github.com/example/inventory/Catalog" = {addresses = ["tcp://10.0.0.7:9000"]}
+	externalsKey      = "github.com/ServiceWeaver/weaver/externals"
+	shortExternalsKey = "externals"
+)
+
+// describeMethodKey is the key of the method that returns the signatures of
+// the methods of an exported component. See externalCheck.
+var describeMethodKey = call.MakeMethodKey("", "describe")
+
+// exportsRefreshInterval is how often the replicas of a component of another
+// application are looked up among the exports published by the deployer.
+const exportsRefreshInterval = 5 * time.Second
+
+// ExternalRef[T] is a field that can be placed inside a component
+// implementation struct to call component T of another Service Weaver
+// application. T is the component's interface, imported from the package that
+// declares it, e.g.:
+//
+//	type store struct {
+//	    weaver.Implements[Store]
+//	    catalog weaver.ExternalRef[inventory.Catalog]
+//	}
+//
+// The other application must export the component by setting exported in the
+// component's config section:
+//
+//	["github.com/example/inventory/Catalog"]
+//	exported = true
+//
+// Deployers that support it, like the multiprocess deployer, publish the
+// addresses of the replicas of the exported components of the applications
+// they run, and the processes of the other applications they run find them
+// there. Otherwise, the calling application must list the addresses in the
+// [externals] section of its config, which also takes precedence over the
+// published addresses:
+//
+//	[externals]
+//	"github.com/example/inventory/Catalog" = {addresses = ["tcp://10.0.0.7:9000"]}
+//
+// Calls made through an ExternalRef use the same wire protocol, encoding,
+// and metrics as calls to the application's own components, and are sent to
+// the component's replicas in round-robin order. T is never hosted by the
+// calling application on behalf of an ExternalRef.
+//
+// Calls between applications use mutual TLS if the calling application does.
+// Both applications must then use mTLS, and their deployers must trust the
+// same certificate authority: the multiprocess deployer uses one authority
+// for all the applications it runs on a machine. A process of another
+// application may only call the exported components, and its calls are made
+// by the "external" caller as far as allowed_callers are concerned. Without
+// mTLS, neither side is authenticated.
+//
+// When the calling process connects to T, it checks that the signatures of
+// T's methods in both binaries match, including the structure of their
+// argument and result types. If they don't, the mismatch is logged, and every
+// call fails with an error that wraps [ErrIncompatibleVersion] and lists the
+// mismatched methods. The exported component may have methods that the caller
+// doesn't know about.
+type ExternalRef[T any] struct {
+	value T
+}
+
+// Get returns a handle to the component of the other application.
+func (r ExternalRef[T]) Get() T { return r.value }
+
+// isExternalRef is an internal method that is only implemented by
+// ExternalRef and is used by the implementation to check that a value is of
+// type ExternalRef.
+func (r ExternalRef[T]) isExternalRef() {}
+
+// externalConfig holds the options of a component of another application.
+type externalConfig struct {
+	// Addresses of the component's replicas, e.g., "tcp://10.0.0.7:9000".
+	Addresses []string `toml:"addresses"`
+}
+
+// externalConfigs holds the options of the components of other applications,
+// keyed by full component name.
+type externalConfigs map[string]externalConfig
+
+// Validate implements the interface consulted by runtime.ParseConfigSection.
+func (c externalConfigs) Validate() error {
+	for component, cfg := range c {
+		if len(cfg.Addresses) == 0 {
+			return fmt.Errorf("external component %q: no addresses", component)
+		}
+		for _, addr := range cfg.Addresses {
+			if _, err := call.ParseNetEndpoint(addr); err != nil {
+				return fmt.Errorf("external component %q: %w", component, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fillExternalRefs initializes the ExternalRef[T] fields in a component
+// implementation struct. impl should be a pointer to the implementation
+// struct, and get should return the handle to the component with interface
+// type T, when passed the reflect.Type for T.
+func fillExternalRefs(impl any, get func(reflect.Type) (any, error)) error {
+	p := reflect.ValueOf(impl)
+	if p.Kind() != reflect.Pointer {
+		return fmt.Errorf("not a pointer")
+	}
+	s := p.Elem()
+	if s.Kind() != reflect.Struct {
+		return fmt.Errorf("not a struct pointer")
+	}
+	isExternalRef := reflection.Type[interface{ isExternalRef() }]()
+	for i, n := 0, s.NumField(); i < n; i++ {
+		ref := s.Field(i)
+		if !ref.Type().Implements(isExternalRef) {
+			continue
+		}
+		valueField := ref.Field(0)
+		value, err := get(valueField.Type())
+		if err != nil {
+			return fmt.Errorf("setting field %v.%s: %w", s.Type(), s.Type().Field(i).Name, err)
+		}
+		setPossiblyUnexported(valueField, reflect.ValueOf(value))
+	}
+	return nil
+}
+
+// getExternal returns a handle, for use by the requester component, to the
+// component of another application with the provided interface type.
+func (w *weavelet) getExternal(requester *component, t reflect.Type) (any, error) {
+	c, err := w.getComponentByType(t)
+	if err != nil {
+		return nil, err
+	}
+	w.externalsMu.Lock()
+	defer w.externalsMu.Unlock()
+	check, ok := w.externals[c]
+	if !ok {
+		resolver, err := w.externalResolver(c)
+		if err != nil {
+			return nil, fmt.Errorf("external component %q: %w", c.info.Name, err)
+		}
+		opts := w.transport.clientOpts
+		opts.Balancer = call.RoundRobin()
+		conn, err := call.Connect(w.ctx, resolver, opts)
+		if err != nil {
+			return nil, fmt.Errorf("external component %q: %w", c.info.Name, err)
+		}
+		check = &externalCheck{component: c.info.Name, conn: conn, local: methodSignatures(c.info.Iface)}
+		if w.externals == nil {
+			w.externals = map[*component]*externalCheck{}
+		}
+		w.externals[c] = check
+
+		// Check the component right away, so that a mismatch is reported
+		// when the caller starts, rather than at its first call. If the other
+		// application can't be reached yet, the first call checks again.
+		go func() {
+			if err := check.verify(w.ctx); errors.Is(err, ErrIncompatibleVersion) {
+				requester.logger.Error("Incompatible external component", "err", err)
+			}
+		}()
+	}
+
+	n := c.info.Iface.NumMethod()
+	methods := make([]call.MethodKey, n)
+	names := make([]string, n)
+	for i := 0; i < n; i++ {
+		names[i] = c.info.Iface.Method(i).Name
+		methods[i] = call.MakeMethodKey(c.info.Name, names[i])
+	}
+	s := &externalStub{
+		stub: &stub{
+			component: c.info.Name,
+			conn:      check.conn,
+			methods:   methods,
+			names:     names,
+			tracer:    w.tracer,
+			caller:    requester.info.Name,
+
+			compressMinBytes: c.compressMinBytes,
+		},
+		check: check,
+	}
+	return c.info.ClientStubFn(s, requester.info.Name), nil
+}
+
+// externalResolver returns the resolver of the replicas of the provided
+// component of another application: the addresses listed in the [externals]
+// config section, if any, or else the addresses published by the deployer.
+func (w *weavelet) externalResolver(c *component) (call.Resolver, error) {
+	if cfg, ok := w.externalCfg[c.info.Name]; ok {
+		endpoints, err := parseEndpoints(dialAddresses(c, cfg.Addresses), c.clientTLS)
+		if err != nil {
+			return nil, err
+		}
+		return call.NewConstantResolver(endpoints...), nil
+	}
+	if w.exportsDir == "" {
+		return nil, fmt.Errorf("no addresses; list them in the [%s] config section", shortExternalsKey)
+	}
+	rr := newRoutingResolver()
+	r := exportsResolver{dir: w.exportsDir, mtls: w.info.Mtls}
+	go watchResolver(w.ctx, w.env.SystemLogger(), c, r, rr, exportsRefreshInterval)
+	return rr, nil
+}
+
+// exportsResolver is a Resolver that finds the replicas of the components of
+// other applications among the exports published by the deployer in dir. See
+// package exports.
+type exportsResolver struct {
+	dir  string // directory of the published exports
+	mtls bool   // does the calling process use mTLS?
+}
+
+var _ Resolver = exportsResolver{}
+
+// Resolve implements the Resolver interface. If no deployment exports the
+// component yet, it returns no addresses.
+func (r exportsResolver) Resolve(component string) ([]Address, error) {
+	e, ok, err := exports.Find(r.dir, component)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if e.Mtls != r.mtls {
+		return nil, fmt.Errorf("deployment %s of application %q exports %q with mtls = %t, but the calling deployment has mtls = %t", e.DeploymentId, e.App, component, e.Mtls, r.mtls)
+	}
+	addrs := make([]Address, len(e.Components[component]))
+	for i, addr := range e.Components[component] {
+		addrs[i] = Address(addr)
+	}
+	return addrs, nil
+}
+
+// Changed implements the Resolver interface. Published exports are polled.
+func (r exportsResolver) Changed() <-chan struct{} { return nil }
+
+// describe handles a call to describeMethodKey. The argument is the full
+// name of a component, and the result holds the signatures of its methods,
+// if the component is exported.
+func (w *weavelet) describe(_ context.Context, args []byte) ([]byte, error) {
+	name := string(args)
+	c, ok := w.componentsByName[name]
+	if !ok {
+		return nil, fmt.Errorf("component %q is not part of application %q", name, w.info.App)
+	}
+	if !c.exported {
+		return nil, fmt.Errorf("component %q of application %q is not exported; set exported = true in its config section", name, w.info.App)
+	}
+	sigs := methodSignatures(c.info.Iface)
+	enc := codegen.NewEncoder()
+	enc.Len(len(sigs))
+	for _, method := range codegen.SortedKeys(sigs) {
+		enc.String(method)
+		enc.String(sigs[method])
+	}
+	return enc.Data(), nil
+}
+
+// externalCheck checks, once, that the methods of a component of another
+// application match those expected by the calling binary.
+type externalCheck struct {
+	component string            // full name of the component
+	conn      call.Connection   // connection to the component's replicas
+	local     map[string]string // signatures of the methods, by method name
+
+	mu      sync.Mutex    // guards the following fields
+	done    bool          // has the check completed?
+	err     error         // if done, the outcome of the check
+	running chan struct{} // if non-nil, closed when the running attempt ends
+}
+
+// verify performs the check, if it hasn't completed yet, and returns its
+// outcome. Concurrent callers wait for a single attempt, without holding
+// e.mu. If the other application can't be reached, the check is retried on
+// the next call to verify.
+func (e *externalCheck) verify(ctx context.Context) error {
+	e.mu.Lock()
+	for e.running != nil {
+		running := e.running
+		e.mu.Unlock()
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return fmt.Errorf("external component %q: %w", e.component, ctx.Err())
+		}
+		e.mu.Lock()
+	}
+	if e.done {
+		defer e.mu.Unlock()
+		return e.err
+	}
+	running := make(chan struct{})
+	e.running = running
+	e.mu.Unlock()
+
+	done, err := e.check(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.done, e.err = done, err
+	e.running = nil
+	close(running)
+	return err
+}
+
+// check calls the exporting application to compare the signatures of the
+// component's methods. It returns whether the outcome is final, i.e., whether
+// the other application was reached, and the outcome.
+func (e *externalCheck) check(ctx context.Context) (bool, error) {
+	reply, err := e.conn.Call(ctx, describeMethodKey, []byte(e.component), call.CallOptions{})
+	if err != nil {
+		return false, fmt.Errorf("external component %q: %w", e.component, err)
+	}
+	remote, err := decodeSignatures(reply)
+	if err != nil {
+		return false, fmt.Errorf("external component %q: %w", e.component, err)
+	}
+	return true, compareSignatures(e.component, e.local, remote)
+}
+
+// decodeSignatures decodes the result of a call to describeMethodKey.
+func decodeSignatures(data []byte) (sigs map[string]string, err error) {
+	defer func() { err = codegen.CatchPanics(recover()) }()
+	dec := codegen.NewDecoder(data)
+	n := dec.Len()
+	sigs = make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		method := dec.String()
+		sigs[method] = dec.String()
+	}
+	return sigs, nil
+}
+
+// compareSignatures returns an error that lists the methods whose signatures
+// differ between the caller (local) and the callee (remote), if any.
+func compareSignatures(component string, local, remote map[string]string) error {
+	var diffs []string
+	for _, method := range codegen.SortedKeys(local) {
+		want := local[method]
+		got, ok := remote[method]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("method %s: missing in the exporting application", method))
+		case got != want:
+			diffs = append(diffs, fmt.Sprintf("method %s: caller has %s, exporting application has %s", method, want, got))
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("external component %q: %w:\n  %s", component, ErrIncompatibleVersion, strings.Join(diffs, "\n  "))
+}
+
+// externalStub is a stub that checks that the component of another
+// application is compatible with the calling binary before calling it.
+type externalStub struct {
+	*stub
+	check *externalCheck
+}
+
+var _ codegen.Stub = &externalStub{}
+
+// Run implements the codegen.Stub interface.
+func (s *externalStub) Run(ctx context.Context, method int, args []byte, shardKey uint64) ([]byte, error) {
+	if err := s.check.verify(ctx); err != nil {
+		return nil, err
+	}
+	return s.stub.Run(ctx, method, args, shardKey)
+}
+
+// RunBuffers implements the codegen.Stub interface.
+func (s *externalStub) RunBuffers(ctx context.Context, method int, args [][]byte, shardKey uint64) ([]byte, error) {
+	if err := s.check.verify(ctx); err != nil {
+		return nil, err
+	}
+	return s.stub.RunBuffers(ctx, method, args, shardKey)
+}
+
+// RunStream implements the codegen.Stub interface.
+func (s *externalStub) RunStream(ctx context.Context, method int, args []byte, shardKey uint64) (codegen.StreamReader, error) {
+	if err := s.check.verify(ctx); err != nil {
+		return nil, err
+	}
+	return s.stub.RunStream(ctx, method, args, shardKey)
+}
+
+// methodSignatures returns the signatures of the methods of the provided
+// component interface, keyed by method name. A signature spells out the
+// structure of the named types it mentions, so that two binaries agree on a
+// signature only if they encode its arguments and results the same way.
+func methodSignatures(iface reflect.Type) map[string]string {
+	sigs := make(map[string]string, iface.NumMethod())
+	for i := 0; i < iface.NumMethod(); i++ {
+		m := iface.Method(i)
+		var b strings.Builder
+		describeType(&b, m.Type, map[reflect.Type]bool{})
+		sigs[m.Name] = b.String()
+	}
+	return sigs
+}
+
+// describeType writes a description of t to b. seen holds the named types
+// whose structure was already described, which are only named thereafter.
+func describeType(b *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	if t.Name() != "" {
+		if t.PkgPath() != "" {
+			b.WriteString(t.PkgPath())
+			b.WriteString(".")
+		}
+		b.WriteString(t.Name())
+		if t.PkgPath() == "" || seen[t] || t.Kind() == reflect.Interface {
+			return
+		}
+		seen[t] = true
+		b.WriteString("=")
+		// Describe the underlying type below.
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		b.WriteString("*")
+		describeType(b, t.Elem(), seen)
+	case reflect.Slice:
+		b.WriteString("[]")
+		describeType(b, t.Elem(), seen)
+	case reflect.Array:
+		fmt.Fprintf(b, "[%d]", t.Len())
+		describeType(b, t.Elem(), seen)
+	case reflect.Map:
+		b.WriteString("map[")
+		describeType(b, t.Key(), seen)
+		b.WriteString("]")
+		describeType(b, t.Elem(), seen)
+	case reflect.Struct:
+		b.WriteString("struct{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			b.WriteString(f.Name)
+			b.WriteString(" ")
+			describeType(b, f.Type, seen)
+			if f.Tag != "" {
+				fmt.Fprintf(b, " %q", f.Tag)
+			}
+		}
+		b.WriteString("}")
+	case reflect.Func:
+		b.WriteString("func(")
+		for i := 0; i < t.NumIn(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			describeType(b, t.In(i), seen)
+		}
+		b.WriteString(") (")
+		for i := 0; i < t.NumOut(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			describeType(b, t.Out(i), seen)
+		}
+		b.WriteString(")")
+	default:
+		b.WriteString(t.Kind().String())
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/exports"
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+	"github.com/google/go-cmp/cmp"
+)
+
+type externalItem struct {
+	Name  string
+	Price int
+	Next  *externalItem
+}
+
+// externalCatalogV1 and externalCatalogV2 are two versions of the same
+// component interface. V2 changed the arguments of Remove and added Count.
+type externalCatalogV1 interface {
+	Get(ctx context.Context, name string) (externalItem, error)
+	Remove(ctx context.Context, name string) error
+}
+
+type externalCatalogV2 interface {
+	Get(ctx context.Context, name string) (externalItem, error)
+	Remove(ctx context.Context, names []string) error
+	Count(ctx context.Context) (int, error)
+}
+
+func TestCompareSignatures(t *testing.T) {
+	v1 := methodSignatures(reflection.Type[externalCatalogV1]())
+	v2 := methodSignatures(reflection.Type[externalCatalogV2]())
+
+	if diff := cmp.Diff(v1["Get"], v2["Get"]); diff != "" {
+		t.Errorf("Get signatures differ (-v1 +v2):\n%s", diff)
+	}
+	if err := compareSignatures("pkg/Catalog", v1, v1); err != nil {
+		t.Errorf("compareSignatures(v1, v1): %v", err)
+	}
+
+	err := compareSignatures("pkg/Catalog", v1, v2)
+	if !errors.Is(err, ErrIncompatibleVersion) {
+		t.Fatalf("compareSignatures(v1, v2): got %v, want %v", err, ErrIncompatibleVersion)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "method Remove") || strings.Contains(msg, "method Get") || strings.Contains(msg, "Count") {
+		t.Errorf("compareSignatures(v1, v2): unexpected error %q", msg)
+	}
+
+	// The caller may not use a method that the exporting application lacks.
+	err = compareSignatures("pkg/Catalog", v2, v1)
+	if err == nil || !strings.Contains(err.Error(), "method Count: missing") {
+		t.Errorf("compareSignatures(v2, v1): unexpected error %v", err)
+	}
+}
+
+func TestDescribeType(t *testing.T) {
+	// Two structurally different types with the same name must not have the
+	// same description.
+	type item struct{ Name string }
+	v1 := reflect.TypeOf(item{})
+	{
+		type item struct{ Name []byte }
+		v2 := reflect.TypeOf(item{})
+		var b1, b2 strings.Builder
+		describeType(&b1, v1, map[reflect.Type]bool{})
+		describeType(&b2, v2, map[reflect.Type]bool{})
+		if b1.String() == b2.String() {
+			t.Errorf("describeType: %v and %v both described as %q", v1, v2, b1.String())
+		}
+	}
+
+	// Recursive types are described once.
+	var b strings.Builder
+	describeType(&b, reflect.TypeOf(externalItem{}), map[reflect.Type]bool{})
+	const want = "github.com/ServiceWeaver/weaver.externalItem=struct{Name string; Price int; Next *github.com/ServiceWeaver/weaver.externalItem}"
+	if got := b.String(); got != want {
+		t.Errorf("describeType: got %q, want %q", got, want)
+	}
+}
+
+func TestExternalConfigsValidate(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		cfg     externalConfigs
+		wantErr string
+	}{
+		{"ok", externalConfigs{"pkg/A": {Addresses: []string{"tcp://localhost:9000"}}}, ""},
+		{"no addresses", externalConfigs{"pkg/A": {}}, "no addresses"},
+		{"bad address", externalConfigs{"pkg/A": {Addresses: []string{"localhost:9000"}}}, "pkg/A"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cfg.Validate()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Validate: got %v, want error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	iface := reflection.Type[externalCatalogV2]()
+	w := &weavelet{
+		info: &protos.EnvelopeInfo{App: "inventory"},
+		componentsByName: map[string]*component{
+			"pkg/Catalog": {info: &codegen.Registration{Name: "pkg/Catalog", Iface: iface}, exported: true},
+			"pkg/Private": {info: &codegen.Registration{Name: "pkg/Private", Iface: iface}},
+		},
+	}
+	ctx := context.Background()
+
+	reply, err := w.describe(ctx, []byte("pkg/Catalog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeSignatures(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(methodSignatures(iface), got); diff != "" {
+		t.Errorf("describe (-want +got):\n%s", diff)
+	}
+
+	if _, err := w.describe(ctx, []byte("pkg/Private")); err == nil || !strings.Contains(err.Error(), "not exported") {
+		t.Errorf("describe(pkg/Private): unexpected error %v", err)
+	}
+	if _, err := w.describe(ctx, []byte("pkg/Missing")); err == nil {
+		t.Error("describe(pkg/Missing): unexpected success")
+	}
+	if _, err := decodeSignatures([]byte{1}); err == nil {
+		t.Error("decodeSignatures of a truncated reply: unexpected success")
+	}
+}
+
+type externalCacheImpl struct {
+	Implements[testEnvCache]
+	store ExternalRef[testEnvStore]
+}
+
+func (c *externalCacheImpl) Get(ctx context.Context, key string) (string, error) {
+	return c.store.Get().Get(ctx, key)
+}
+
+func TestComponentTestEnvExternalRef(t *testing.T) {
+	var events []string
+	var env ComponentTestEnv
+	env.Register(&externalCacheImpl{})
+	env.Register(&testEnvStoreImpl{events: &events})
+	ctx := env.Start(t)
+
+	cache, err := Get[testEnvCache](ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cache.Get(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "value of k"; got != want {
+		t.Errorf("Get: got %q, want %q", got, want)
+	}
+}
+
+// describeConn is a call.Connection to an exporting application that answers
+// calls to describeMethodKey once release is closed.
+type describeConn struct {
+	call.Connection
+	sigs    map[string]string
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (c *describeConn) Call(ctx context.Context, key call.MethodKey, _ []byte, _ call.CallOptions) ([]byte, error) {
+	if key != describeMethodKey {
+		return nil, fmt.Errorf("unexpected method key %v", key)
+	}
+	c.calls.Add(1)
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	enc := codegen.NewEncoder()
+	enc.Len(len(c.sigs))
+	for _, method := range codegen.SortedKeys(c.sigs) {
+		enc.String(method)
+		enc.String(c.sigs[method])
+	}
+	return enc.Data(), nil
+}
+
+func TestExternalCheckConcurrent(t *testing.T) {
+	v1 := methodSignatures(reflection.Type[externalCatalogV1]())
+	v2 := methodSignatures(reflection.Type[externalCatalogV2]())
+	conn := &describeConn{sigs: v2, release: make(chan struct{})}
+	check := &externalCheck{component: "pkg/Catalog", conn: conn, local: v1}
+
+	// Start a check that blocks in the call to the exporting application.
+	ctx := context.Background()
+	first := make(chan error, 1)
+	go func() { first <- check.verify(ctx) }()
+	for conn.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A caller whose context is done doesn't wait for the running check.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := check.verify(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("verify with a canceled context: got %v, want %v", err, context.Canceled)
+	}
+
+	// Other callers share the outcome of the running check.
+	second := make(chan error, 1)
+	go func() { second <- check.verify(ctx) }()
+	close(conn.release)
+	for _, c := range []chan error{first, second} {
+		if err := <-c; !errors.Is(err, ErrIncompatibleVersion) {
+			t.Errorf("verify: got %v, want %v", err, ErrIncompatibleVersion)
+		}
+	}
+	if err := check.verify(ctx); !errors.Is(err, ErrIncompatibleVersion) {
+		t.Errorf("verify after the check: got %v, want %v", err, ErrIncompatibleVersion)
+	}
+	if got := conn.calls.Load(); got != 1 {
+		t.Errorf("calls to the exporting application: got %d, want 1", got)
+	}
+}
+
+func TestExportsResolver(t *testing.T) {
+	dir := t.TempDir()
+	r := exportsResolver{dir: dir}
+	if addrs, err := r.Resolve("pkg/Catalog"); err != nil || len(addrs) != 0 {
+		t.Fatalf("Resolve before publishing: got %v, %v, want no addresses", addrs, err)
+	}
+
+	e := exports.Export{
+		DeploymentId: "d1",
+		App:          "inventory",
+		Started:      time.Now(),
+		Components:   map[string][]string{"pkg/Catalog": {"tcp://localhost:1", "tcp://localhost:2"}},
+	}
+	if err := exports.Publish(dir, e); err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := r.Resolve("pkg/Catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]Address{"tcp://localhost:1", "tcp://localhost:2"}, addrs); diff != "" {
+		t.Errorf("Resolve (-want +got):\n%s", diff)
+	}
+
+	// A deployment with mTLS can't call a deployment without it.
+	r.mtls = true
+	if _, err := r.Resolve("pkg/Catalog"); err == nil || !strings.Contains(err.Error(), "mtls") {
+		t.Errorf("Resolve with mTLS: got %v, want an error about mtls", err)
+	}
+}
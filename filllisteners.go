@@ -17,17 +17,17 @@ package weaver
 import (
 	"fmt"
 	"go/token"
-	"net"
 	"reflect"
+	"strings"
 
 	"github.com/ServiceWeaver/weaver/internal/reflection"
 )
 
 // fillListeners initializes Listener fields in a component implementation struct.
 //   - impl should be a pointer to the implementation struct
-//   - get should be a function that returns the required Listener values,
-//     namely the network listener and the proxy address.
-func fillListeners(impl any, get func(field string) (net.Listener, string, error)) error {
+//   - get should be a function that returns the Listener value for the
+//     listener with the provided name and the options of its field.
+func fillListeners(impl any, get func(name string, opts listenerOptions) (Listener, error)) error {
 	p := reflect.ValueOf(impl)
 	if p.Kind() != reflect.Pointer {
 		return fmt.Errorf("not a pointer")
@@ -36,42 +36,59 @@ func fillListeners(impl any, get func(field string) (net.Listener, string, error
 	if s.Kind() != reflect.Struct {
 		return fmt.Errorf("not a struct pointer")
 	}
-	isListener := reflection.Type[interface{ isListener() }]()
+	listenerType := reflection.Type[Listener]()
 	for i, n := 0, s.NumField(); i < n; i++ {
 		// Handle field with type weaver.Listener.
 		ref := s.Field(i)
-		if !ref.Type().Implements(isListener) {
-			continue
-		}
-
-		// Sanity check that field type structure matches weaver.Listener.
-		if ref.Kind() != reflect.Struct {
-			continue
-		}
-		if ref.NumField() != 2 {
-			continue
-		}
-		if ref.Type().Field(0).Name != "Listener" {
-			continue
-		}
-		if ref.Type().Field(1).Name != "proxyAddr" {
+		if ref.Type() != listenerType {
 			continue
 		}
 
 		// Listener name is a field name, unless a tag is present.
 		lisName := s.Type().Field(i).Name
-		if tag := s.Type().Field(i).Tag.Get("weaver"); tag != "" {
-			if !token.IsIdentifier(tag) {
-				return fmt.Errorf("listener tag %s is not a valid Go identifier", tag)
-			}
-			lisName = tag
+		name, opts, err := parseListenerTag(s.Type().Field(i).Tag.Get("weaver"))
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			lisName = name
 		}
-		listener, proxyAddr, err := get(lisName)
+		listener, err := get(lisName, opts)
 		if err != nil {
 			return fmt.Errorf("setting field %v.%s: %w", s.Type(), s.Type().Field(i).Name, err)
 		}
-		setPossiblyUnexported(ref.Field(0), reflect.ValueOf(listener))
-		setPossiblyUnexported(ref.Field(1), reflect.ValueOf(proxyAddr))
+		setPossiblyUnexported(ref, reflect.ValueOf(listener))
 	}
 	return nil
 }
+
+// listenerOptions are the options of a Listener field, set in its weaver
+// struct tag.
+type listenerOptions struct {
+	tls       bool // terminate TLS? see the "tls" option
+	reusePort bool // set SO_REUSEPORT? see the "reuseport" option
+}
+
+// parseListenerTag parses the weaver struct tag of a Listener field. The tag
+// has the form "[name][,option]...", where every option is either "tls" or
+// "reuseport". It returns the (possibly empty) listener name and the options.
+func parseListenerTag(tag string) (string, listenerOptions, error) {
+	name, rest, _ := strings.Cut(tag, ",")
+	if name != "" && !token.IsIdentifier(name) {
+		return "", listenerOptions{}, fmt.Errorf("listener tag %s is not a valid Go identifier", name)
+	}
+	var opts listenerOptions
+	if rest != "" {
+		for _, opt := range strings.Split(rest, ",") {
+			switch opt {
+			case "tls":
+				opts.tls = true
+			case "reuseport":
+				opts.reusePort = true
+			default:
+				return "", listenerOptions{}, fmt.Errorf("listener tag %q has unknown option %q", tag, opt)
+			}
+		}
+	}
+	return name, opts, nil
+}
@@ -23,13 +23,16 @@ import (
 
 type testListener struct {
 	net.Listener
+	reusePort bool
 }
 
-func getListener(lis string) (net.Listener, string, error) {
-	if lis != "A" && lis != "b" && lis != "cname" && lis != "DName" {
-		return nil, "", fmt.Errorf("unexpected listener %q", lis)
+func getListener(lis string, opts listenerOptions) (Listener, error) {
+	switch lis {
+	case "A", "b", "cname", "DName", "E", "fname", "G":
+	default:
+		return Listener{}, fmt.Errorf("unexpected listener %q", lis)
 	}
-	return &testListener{}, lis, nil
+	return Listener{Listener: &testListener{reusePort: opts.reusePort}, proxyAddr: lis, tls: opts.tls}, nil
 }
 
 func TestFillListeners(t *testing.T) {
@@ -38,6 +41,9 @@ func TestFillListeners(t *testing.T) {
 		b Listener
 		C Listener `weaver:"cname"`
 		d Listener `weaver:"DName"`
+		E Listener `weaver:",tls"`
+		f Listener `weaver:"fname,tls"`
+		G Listener `weaver:",tls,reuseport"`
 	}
 	if err := fillListeners(&x, getListener); err != nil {
 		t.Fatal(err)
@@ -54,6 +60,45 @@ func TestFillListeners(t *testing.T) {
 	if x.d.proxyAddr != "DName" {
 		t.Errorf(`expecting x.d.proxyAddr to be "Dname", got %q`, x.d.proxyAddr)
 	}
+	if x.A.tls || x.b.tls || x.C.tls || x.d.tls {
+		t.Errorf("unexpected TLS listener")
+	}
+	if x.E.proxyAddr != "E" || !x.E.tls {
+		t.Errorf(`expecting x.E to be TLS listener "E", got %q (tls=%t)`, x.E.proxyAddr, x.E.tls)
+	}
+	if x.f.proxyAddr != "fname" || !x.f.tls {
+		t.Errorf(`expecting x.f to be TLS listener "fname", got %q (tls=%t)`, x.f.proxyAddr, x.f.tls)
+	}
+	if x.G.proxyAddr != "G" || !x.G.tls || !x.G.Listener.(*testListener).reusePort {
+		t.Errorf(`expecting x.G to be TLS reuseport listener "G", got %q (tls=%t)`, x.G.proxyAddr, x.G.tls)
+	}
+	for _, l := range []Listener{x.A, x.b, x.C, x.d, x.E, x.f} {
+		if l.Listener.(*testListener).reusePort {
+			t.Errorf("unexpected reuseport listener %q", l.proxyAddr)
+		}
+	}
+}
+
+func TestFillListenersBadTag(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		impl   any
+		expect string
+	}{
+		{"not-identifier", &struct {
+			A Listener `weaver:"a-b"`
+		}{}, "not a valid Go identifier"},
+		{"unknown-option", &struct {
+			A Listener `weaver:"a,gzip"`
+		}{}, "unknown option"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := fillListeners(test.impl, getListener)
+			if err == nil || !strings.Contains(err.Error(), test.expect) {
+				t.Fatalf("unexpected error %v; expecting %s", err, test.expect)
+			}
+		})
+	}
 }
 
 func TestFillListenerErrors(t *testing.T) {
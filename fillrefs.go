@@ -15,17 +15,28 @@
 package weaver
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 
 	"github.com/ServiceWeaver/weaver/internal/reflection"
 )
 
-// fillRefs initializes Ref[T] fields in a component implement struct.
+// refTarget holds the values of the fields of a Ref[T].
+type refTarget struct {
+	value    any                                      // handle to the component
+	id       string                                   // full component name
+	replicas func(context.Context) ([]replica, error) // see Ref.replicas
+	keyed    func(key any) any                        // see Ref.keyed
+}
+
+// fillRefs initializes Ref[T] and BalancedRef[T, B] fields in a component
+// implement struct.
 //   - impl should be a pointer to the implementation struct
-//   - get should be a function that returns the component of interface type
-//     T when passed the reflect.Type for T.
-func fillRefs(impl any, get func(reflect.Type) (any, error)) error {
+//   - get should be a function that returns the refTarget for the component
+//     of interface type T, when passed the reflect.Type for T and the
+//     Balancer of a BalancedRef, or nil for a Ref.
+func fillRefs(impl any, get func(reflect.Type, Balancer) (refTarget, error)) error {
 	p := reflect.ValueOf(impl)
 	if p.Kind() != reflect.Pointer {
 		return fmt.Errorf("not a pointer")
@@ -41,22 +52,37 @@ func fillRefs(impl any, get func(reflect.Type) (any, error)) error {
 		if !ref.Type().Implements(isRef) {
 			continue
 		}
+
+		// A weaver.BalancedRef[T, B] embeds a weaver.Ref[T], which we fill.
+		var balancer Balancer
+		// Note that ref may be an unexported field, so we call newRefBalancer
+		// on a zero value of its type rather than on ref itself.
+		if b, ok := reflect.Zero(ref.Type()).Interface().(interface{ newRefBalancer() (Balancer, error) }); ok {
+			var err error
+			if balancer, err = b.newRefBalancer(); err != nil {
+				return fmt.Errorf("setting field %v.%s: %w", s.Type(), s.Type().Field(i).Name, err)
+			}
+			ref = ref.Field(0)
+		}
 		// Sanity check that field type structure matches weaver.Ref[T].
 		if ref.Kind() != reflect.Struct {
 			continue // XXX Panic?
 		}
-		if ref.NumField() != 1 {
+		if ref.NumField() != 4 {
 			continue // XXX Panic?
 		}
-		if ref.Type().Field(0).Name != "value" {
+		if ref.Type().Field(0).Name != "value" || ref.Type().Field(1).Name != "id" || ref.Type().Field(2).Name != "replicas" || ref.Type().Field(3).Name != "keyed" {
 			continue // XXX Panic?
 		}
 		valueField := ref.Field(0)
-		component, err := get(valueField.Type())
+		target, err := get(valueField.Type(), balancer)
 		if err != nil {
 			return fmt.Errorf("setting field %v.%s: %w", s.Type(), s.Type().Field(i).Name, err)
 		}
-		setPossiblyUnexported(valueField, reflect.ValueOf(component))
+		setPossiblyUnexported(valueField, reflect.ValueOf(target.value))
+		setPossiblyUnexported(ref.Field(1), reflect.ValueOf(target.id))
+		setPossiblyUnexported(ref.Field(2), reflect.ValueOf(target.replicas))
+		setPossiblyUnexported(ref.Field(3), reflect.ValueOf(target.keyed))
 	}
 	return nil
 }
@@ -27,14 +27,14 @@ type impl struct {
 	C Ref[bool]
 }
 
-func getValue(t reflect.Type) (any, error) {
+func getValue(t reflect.Type, _ Balancer) (refTarget, error) {
 	if t == reflect.TypeOf(int(0)) {
-		return 42, nil
+		return refTarget{value: 42, id: "int"}, nil
 	}
 	if t == reflect.TypeOf("") {
-		return "hello", nil
+		return refTarget{value: "hello", id: "string"}, nil
 	}
-	return nil, fmt.Errorf("unsupported type %v", t)
+	return refTarget{}, fmt.Errorf("unsupported type %v", t)
 }
 
 func TestFillRefs(t *testing.T) {
@@ -51,12 +51,49 @@ func TestFillRefs(t *testing.T) {
 	if x.b.value != "hello" {
 		t.Errorf("expecting x.b to be `hello`, got %s", x.b.value)
 	}
+	if x.a.ID() != "int" || x.b.ID() != "string" {
+		t.Errorf("expecting IDs int and string, got %s and %s", x.a.ID(), x.b.ID())
+	}
+}
+
+func TestFillBalancedRefs(t *testing.T) {
+	var x struct {
+		a Ref[int]
+		b BalancedRef[int, LeastLoaded]
+		c BalancedRef[int, RoundRobin]
+	}
+	var balancers []Balancer
+	get := func(t reflect.Type, b Balancer) (refTarget, error) {
+		balancers = append(balancers, b)
+		return getValue(t, b)
+	}
+	if err := fillRefs(&x, get); err != nil {
+		t.Fatal(err)
+	}
+	if x.b.Get() != 42 || x.b.ID() != "int" {
+		t.Errorf("x.b: got (%d, %q), want (42, int)", x.b.Get(), x.b.ID())
+	}
+	if len(balancers) != 3 {
+		t.Fatalf("got %d refs, want 3", len(balancers))
+	}
+	if balancers[0] != nil {
+		t.Errorf("Ref balancer: got %T, want nil", balancers[0])
+	}
+	if _, ok := balancers[1].(*LeastLoaded); !ok {
+		t.Errorf("BalancedRef[int, LeastLoaded] balancer: got %T", balancers[1])
+	}
+	if _, ok := balancers[2].(*RoundRobin); !ok {
+		t.Errorf("BalancedRef[int, RoundRobin] balancer: got %T", balancers[2])
+	}
 }
 
 func TestFillRefsErrors(t *testing.T) {
 	type badref struct {
 		Ref[bool]
 	}
+	type badbalancer struct {
+		r BalancedRef[int, int]
+	}
 	type testCase struct {
 		name   string
 		impl   any    // impl argument to pass to fillRefs
@@ -66,6 +103,7 @@ func TestFillRefsErrors(t *testing.T) {
 		{"not-pointer", impl{}, "not a pointer"},
 		{"not-struct-pointer", new(int), "not a struct pointer"},
 		{"unsupported-type", &badref{}, "unsupported"},
+		{"not-balancer", &badbalancer{}, "does not implement weaver.Balancer"},
 	} {
 		t.Run(c.name, func(t *testing.T) {
 			err := fillRefs(c.impl, getValue)
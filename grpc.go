@@ -0,0 +1,240 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcHealthPrefix is the prefix of the full method names of the standard
+// gRPC health service. Calls to the health service are not instrumented.
+var grpcHealthPrefix = "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+
+// ServeGRPC serves gRPC requests received on the listener. It creates a
+// gRPC server with the provided options, passes it to register so that the
+// caller can register its services, and serves the listener until the server
+// fails or the weavelet hosting the component shuts down.
+//
+// The server exports the standard gRPC health service
+// (grpc.health.v1.Health). Health checks follow the same rules as those
+// served by Serve: they are delegated to the HealthCheck method of the owning
+// component implementation, if any, and fail once the weavelet starts
+// shutting down. The health service answers Check calls for the empty service
+// name and for every registered service; Watch is not supported.
+//
+// Every call is instrumented like a remote component method call: the
+// method's metrics (see [Metrics]) are recorded under the owning component
+// and the call's full gRPC method name, and the call runs in a span that
+// continues the W3C trace context sent by the client, if any.
+//
+// When the weavelet shuts down, the server stops accepting new connections
+// and waits a bounded amount of time for in-flight calls to complete, after
+// which any remaining calls are canceled and ServeGRPC returns nil.
+//
+//	func (s *server) Init(ctx context.Context) error {
+//	    go s.lis.ServeGRPC(func(srv *grpc.Server) {
+//	        pb.RegisterGreeterServer(srv, s)
+//	    })
+//	    return nil
+//	}
+//
+// [Metrics]: https://serviceweaver.dev/docs.html#metrics-auto-generated-metrics
+func (l *Listener) ServeGRPC(register func(*grpc.Server), opts ...grpc.ServerOption) error {
+	// Our interceptors run before any interceptors provided by the caller.
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(l.unaryInterceptor),
+		grpc.ChainStreamInterceptor(l.streamInterceptor),
+	}, opts...)
+	server := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(server, &grpcHealth{l: l, server: server})
+	register(server)
+
+	if l.logger != nil {
+		l.logger.Debug("Serving gRPC", "address", l.String())
+	}
+	defer l.servers.start()()
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(l.Listener) }()
+
+	var done <-chan struct{}
+	if l.ctx != nil {
+		done = l.ctx.Done()
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-done:
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(serveShutdownTimeout):
+			// Cancel the calls that are still running.
+			server.Stop()
+			<-stopped
+		}
+		return nil
+	}
+}
+
+// unaryInterceptor instruments a unary gRPC call.
+func (l *Listener) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if strings.HasPrefix(info.FullMethod, grpcHealthPrefix) {
+		return handler(ctx, req)
+	}
+	ctx, end := l.beginGRPC(ctx, info.FullMethod)
+	reply, err := handler(ctx, req)
+	end(err, protoSize(req), protoSize(reply))
+	return reply, err
+}
+
+// streamInterceptor instruments a streaming gRPC call. The number of bytes
+// sent and received on a stream is not recorded.
+func (l *Listener) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if strings.HasPrefix(info.FullMethod, grpcHealthPrefix) {
+		return handler(srv, ss)
+	}
+	ctx, end := l.beginGRPC(ss.Context(), info.FullMethod)
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	end(err, 0, 0)
+	return err
+}
+
+// beginGRPC starts recording metrics and a trace span for a call to the
+// provided gRPC method. It returns the context in which the call should run,
+// and a function that must be called once the call finishes.
+func (l *Listener) beginGRPC(ctx context.Context, method string) (context.Context, func(err error, requestBytes, replyBytes int)) {
+	metrics := codegen.MethodMetricsFor(codegen.MethodLabels{
+		Component: l.component,
+		Method:    method,
+		Remote:    true,
+	})
+	h := metrics.Begin()
+
+	var span trace.Span
+	if l.tracer != nil {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = propagation.TraceContext{}.Extract(ctx, metadataCarrier(md))
+		}
+		ctx, span = l.tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+	}
+
+	return ctx, func(err error, requestBytes, replyBytes int) {
+		if span != nil {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+		metrics.End(h, err != nil, requestBytes, replyBytes)
+	}
+}
+
+// protoSize returns the encoded size of msg, or 0 if msg is not a protocol
+// buffer.
+func protoSize(msg any) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+// serverStream is a grpc.ServerStream with a replaced context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements the grpc.ServerStream interface.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+// Get implements the propagation.TextMapCarrier interface.
+func (c metadataCarrier) Get(key string) string {
+	if vs := metadata.MD(c).Get(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Set implements the propagation.TextMapCarrier interface.
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys implements the propagation.TextMapCarrier interface.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// grpcHealth implements the standard gRPC health service on top of the health
+// of a listener.
+type grpcHealth struct {
+	healthpb.UnimplementedHealthServer
+	l      *Listener
+	server *grpc.Server
+}
+
+// Check implements the healthpb.HealthServer interface.
+func (h *grpcHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.Service != "" {
+		if _, ok := h.server.GetServiceInfo()[req.Service]; !ok {
+			return nil, status.Errorf(grpccodes.NotFound, "unknown service %q", req.Service)
+		}
+	}
+	serving := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}
+	notServing := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}
+	if h.l.draining != nil && h.l.draining() {
+		// Tell load balancers to stop sending requests to this replica.
+		return notServing, nil
+	}
+	if h.l.health == nil {
+		return serving, nil
+	}
+	if err := h.l.health(ctx); err != nil {
+		if h.l.logger != nil {
+			h.l.logger.Error("Health check failed", "address", h.l.String(), "err", err)
+		}
+		return notServing, nil
+	}
+	return serving, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testService is a gRPC test service that records the span context of the
+// last call it received.
+type testService struct {
+	testpb.UnimplementedTestServiceServer
+	span trace.SpanContext
+}
+
+func (s *testService) EmptyCall(ctx context.Context, _ *testpb.Empty) (*testpb.Empty, error) {
+	s.span = trace.SpanContextFromContext(ctx)
+	return &testpb.Empty{}, nil
+}
+
+// serveGRPC serves lis using ServeGRPC, and returns a client connected to it
+// and a channel that receives the result of ServeGRPC.
+func serveGRPC(t *testing.T, lis *Listener, svc *testService) (*grpc.ClientConn, <-chan error) {
+	t.Helper()
+	served := make(chan error, 1)
+	go func() {
+		served <- lis.ServeGRPC(func(s *grpc.Server) {
+			testpb.RegisterTestServiceServer(s, svc)
+		})
+	}()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, served
+}
+
+func TestListenerServeGRPCHealth(t *testing.T) {
+	draining := func() bool { return true }
+	for _, test := range []struct {
+		name     string
+		health   func(context.Context) error
+		draining func() bool
+		want     healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{"NoHealthCheck", nil, nil, healthpb.HealthCheckResponse_SERVING},
+		{"Healthy", func(context.Context) error { return nil }, nil, healthpb.HealthCheckResponse_SERVING},
+		{"Unhealthy", func(context.Context) error { return fmt.Errorf("sick") }, nil, healthpb.HealthCheckResponse_NOT_SERVING},
+		{"Draining", func(context.Context) error { return nil }, draining, healthpb.HealthCheckResponse_NOT_SERVING},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			lis := &Listener{Listener: l, ctx: ctx, health: test.health, draining: test.draining}
+			conn, served := serveGRPC(t, lis, &testService{})
+
+			client := healthpb.NewHealthClient(conn)
+			for _, service := range []string{"", "grpc.testing.TestService"} {
+				resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+				if err != nil {
+					t.Fatalf("Check(%q): %v", service, err)
+				}
+				if got := resp.Status; got != test.want {
+					t.Errorf("Check(%q): got %v, want %v", service, got, test.want)
+				}
+			}
+			_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+			if got, want := status.Code(err), codes.NotFound; got != want {
+				t.Errorf("Check(unknown): got code %v, want %v", got, want)
+			}
+
+			// Canceling the weavelet context should stop the server.
+			cancel()
+			if err := <-served; err != nil {
+				t.Fatalf("ServeGRPC: %v", err)
+			}
+		})
+	}
+}
+
+func TestListenerServeGRPCInstrumentation(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	lis := &Listener{Listener: l, ctx: ctx, component: "TestListenerServeGRPCInstrumentation", tracer: tracer}
+	svc := &testService{}
+	conn, served := serveGRPC(t, lis, svc)
+
+	// Send a trace context along with the call.
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	callCtx := metadata.AppendToOutgoingContext(ctx, "traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := testpb.NewTestServiceClient(conn).EmptyCall(callCtx, &testpb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.span.TraceID().String(); got != traceID {
+		t.Errorf("trace id: got %s, want %s", got, traceID)
+	}
+
+	counts := map[string]float64{}
+	for _, snap := range metrics.Snapshot() {
+		if snap.Labels["component"] == lis.component && snap.Labels["method"] == "/grpc.testing.TestService/EmptyCall" {
+			counts[snap.Name] = snap.Value
+		}
+	}
+	if got, want := counts[codegen.MethodCounts.Name()], 1.0; got != want {
+		t.Errorf("call count: got %v, want %v", got, want)
+	}
+	if got, want := counts[codegen.MethodErrors.Name()], 0.0; got != want {
+		t.Errorf("error count: got %v, want %v", got, want)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("ServeGRPC: %v", err)
+	}
+}
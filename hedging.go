@@ -0,0 +1,127 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// hedgingKey is the context key under which WithHedging stores a hedging
+// policy.
+type hedgingKey struct{}
+
+// hedging is a hedging policy. See WithHedging.
+type hedging struct {
+	threshold time.Duration // delay before sending every extra request
+	maxExtra  int           // maximum number of extra requests per call
+}
+
+// WithHedging returns a context that hedges the remote component method calls
+// made with it. If a call hasn't returned after the provided threshold, a
+// duplicate request is sent, typically to a different replica, and the first
+// successful reply wins. The requests that lose are cancelled. Up to
+// maxExtraRequests duplicates are sent per call, one every threshold.
+//
+// Hedging trades extra load for lower tail latency. A good threshold is a high
+// percentile, e.g., the 95th, of the method's recent latency (see the
+// serviceweaver_method_latency_micros metric). Since a hedged method may run
+// more than once, only hedge calls to methods that are safe to retry.
+//
+// For example:
+//
+//	ctx = weaver.WithHedging(ctx, 50*time.Millisecond, 1)
+//	reply, err := search.Query(ctx, q) // resent if no reply after 50ms
+//
+// Hedging applies to every remote call made with the returned context,
+// including calls made by a component that receives it in the same process,
+// but it is not propagated to other processes. Local calls are not hedged.
+// Calls to a routed component, or made through Ref.ForKey, are hedged to the
+// same replica, since the routing key picks the replica. Every extra request
+// increments the serviceweaver_method_hedged_count metric. If
+// maxExtraRequests is not positive, the returned context disables hedging.
+func WithHedging(ctx context.Context, threshold time.Duration, maxExtraRequests int) context.Context {
+	if threshold < 0 {
+		threshold = 0
+	}
+	if maxExtraRequests < 0 {
+		maxExtraRequests = 0
+	}
+	return context.WithValue(ctx, hedgingKey{}, hedging{threshold, maxExtraRequests})
+}
+
+// hedgingFromContext returns the hedging policy carried by ctx, if any.
+func hedgingFromContext(ctx context.Context) (hedging, bool) {
+	h, ok := ctx.Value(hedgingKey{}).(hedging)
+	return h, ok && h.maxExtra > 0
+}
+
+// runHedged calls the provided method, hedging the call according to h.
+func (s *stub) runHedged(ctx context.Context, h hedging, method int, args [][]byte, opts call.CallOptions) ([]byte, error) {
+	// Cancel the requests that are still running when the call returns.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Large arguments may reference the caller's memory rather than a copy
+	// (see codegen.Encoder.Bytes). The caller may reuse that memory once the
+	// call returns, while the requests that lost are still being sent, so
+	// send a copy instead.
+	args = [][]byte{bytes.Join(args, nil)}
+
+	type result struct {
+		reply []byte
+		err   error
+	}
+	results := make(chan result, h.maxExtra+1)
+	send := func() {
+		go func() {
+			reply, err := s.call(ctx, method, args, opts)
+			results <- result{reply, err}
+		}()
+	}
+
+	send()
+	inflight, extra := 1, 0
+	timer := time.NewTimer(h.threshold)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-results:
+			inflight--
+			if r.err == nil || inflight == 0 {
+				// Hedging doesn't retry failed calls. If no other request is
+				// in flight, the call fails.
+				return r.reply, r.err
+			}
+		case <-timer.C:
+			send()
+			inflight++
+			extra++
+			codegen.MethodHedges.Get(codegen.MethodLabels{
+				Caller:    s.caller,
+				Component: s.component,
+				Method:    s.names[method],
+				Remote:    true,
+			}).Inc()
+			if extra < h.maxExtra {
+				timer.Reset(h.threshold)
+			}
+		}
+	}
+}
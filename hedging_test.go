@@ -0,0 +1,161 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+)
+
+// slowFirstClient is a call.Connection whose first call blocks until it is
+// cancelled, and whose later calls reply with their sequence number.
+type slowFirstClient struct {
+	mu        sync.Mutex
+	calls     int
+	cancelled chan struct{} // closed when the first call is cancelled
+}
+
+var _ call.Connection = &slowFirstClient{}
+
+func (c *slowFirstClient) Call(ctx context.Context, _ call.MethodKey, _ []byte, _ call.CallOptions) ([]byte, error) {
+	c.mu.Lock()
+	c.calls++
+	n := c.calls
+	c.mu.Unlock()
+	if n == 1 {
+		<-ctx.Done()
+		close(c.cancelled)
+		return nil, ctx.Err()
+	}
+	return []byte(fmt.Sprint(n)), nil
+}
+
+func (c *slowFirstClient) CallBuffers(ctx context.Context, h call.MethodKey, args [][]byte, opts call.CallOptions) ([]byte, error) {
+	return c.Call(ctx, h, nil, opts)
+}
+
+func (c *slowFirstClient) CallStream(context.Context, call.MethodKey, []byte, call.CallOptions) (*call.ClientStream, error) {
+	return nil, fmt.Errorf("streaming calls not supported")
+}
+
+func (c *slowFirstClient) Close() {}
+
+func TestHedging(t *testing.T) {
+	conn := &slowFirstClient{cancelled: make(chan struct{})}
+	s := stub{
+		component: "TestHedging",
+		conn:      conn,
+		methods:   []call.MethodKey{call.MakeMethodKey("", "test")},
+		names:     []string{"test"},
+	}
+	before := hedgedCount("TestHedging")
+	ctx := WithHedging(context.Background(), time.Millisecond, 1)
+	reply, err := s.Run(ctx, 0, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(reply), "2"; got != want {
+		t.Errorf("reply: got %q, want %q", got, want)
+	}
+	if got, want := hedgedCount("TestHedging")-before, 1.0; got != want {
+		t.Errorf("hedged requests: got %v, want %v", got, want)
+	}
+
+	// The losing request is cancelled.
+	select {
+	case <-conn.cancelled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("first request not cancelled")
+	}
+}
+
+// argsClient is a call.Connection that records the arguments of its calls.
+type argsClient struct {
+	slowFirstClient
+	args chan [][]byte
+}
+
+func (c *argsClient) CallBuffers(ctx context.Context, h call.MethodKey, args [][]byte, opts call.CallOptions) ([]byte, error) {
+	c.args <- args
+	return c.slowFirstClient.CallBuffers(ctx, h, args, opts)
+}
+
+func TestHedgingCopiesArgs(t *testing.T) {
+	conn := &argsClient{
+		slowFirstClient: slowFirstClient{cancelled: make(chan struct{})},
+		args:            make(chan [][]byte, 2),
+	}
+	s := stub{
+		component: "TestHedgingCopiesArgs",
+		conn:      conn,
+		methods:   []call.MethodKey{call.MakeMethodKey("", "test")},
+		names:     []string{"test"},
+	}
+	ctx := WithHedging(context.Background(), time.Millisecond, 1)
+	header, borrowed := []byte("header"), []byte("borrowed")
+	if _, err := s.RunBuffers(ctx, 0, [][]byte{header, borrowed}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The requests don't reference the caller's memory, which the caller may
+	// reuse while the losing request is still being sent.
+	for i := 0; i < 2; i++ {
+		args := <-conn.args
+		if len(args) != 1 || string(args[0]) != "headerborrowed" {
+			t.Fatalf("request %d: got args %q, want [\"headerborrowed\"]", i, args)
+		}
+		borrowed[0] = 'B'
+		if string(args[0]) != "headerborrowed" {
+			t.Fatalf("request %d: args reference the caller's memory", i)
+		}
+		borrowed[0] = 'b'
+	}
+}
+
+func TestHedgingDisabled(t *testing.T) {
+	conn := &slowFirstClient{cancelled: make(chan struct{})}
+	s := stub{
+		conn:    conn,
+		methods: []call.MethodKey{call.MakeMethodKey("", "test")},
+		names:   []string{"test"},
+	}
+
+	// With no extra requests allowed, the call isn't hedged and waits for the
+	// first request.
+	ctx := WithHedging(context.Background(), time.Millisecond, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Run(ctx, 0, nil, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// hedgedCount returns the number of hedged requests sent to the provided
+// component.
+func hedgedCount(component string) float64 {
+	for _, m := range metrics.Snapshot() {
+		if m.Name == "serviceweaver_method_hedged_count" && m.Labels["component"] == component {
+			return m.Value
+		}
+	}
+	return 0
+}
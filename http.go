@@ -15,10 +15,16 @@
 package weaver
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/ServiceWeaver/weaver/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TODO(mwhittaker): Measure the size of HTTP requests.
@@ -28,6 +34,7 @@ import (
 type httpLabels struct {
 	Label string // user-provided instrumentation label
 	Host  string // URL host
+	Class string // status code class (e.g., "2xx")
 }
 
 type httpErrorLabels struct {
@@ -62,44 +69,81 @@ var (
 	)
 )
 
+// httpTracerName is the instrumentation name of the tracer used by
+// InstrumentHandler.
+const httpTracerName = "github.com/ServiceWeaver/weaver/http"
+
 // InstrumentHandler instruments the provided HTTP handler to maintain the
 // following metrics about HTTP request execution. Every metric is labelled
-// with the supplied label.
+// with the supplied label, the request's host, and, except for the error
+// count, the class of the reply's status code (e.g., "2xx" or "5xx").
 //
 //   - serviceweaver_http_request_count: Total number of requests.
-//   - serviceweaver_http_error_count: Total number of 4XX and 5XX replies.
+//   - serviceweaver_http_error_count: Total number of 4XX and 5XX replies,
+//     labelled with the status code.
 //   - serviceweaver_http_request_latency_micros: Execution latency in microseconds.
+//   - serviceweaver_http_request_bytes_received: Approximate request size.
+//   - serviceweaver_http_request_bytes_returned: Approximate reply size.
+//
+// Every request also runs in a server span named after the label. The span
+// continues the trace of the client if the request carries a W3C traceparent
+// header, and starts a new trace otherwise.
+//
+// InstrumentHandler wraps any http.Handler, so it composes with any router.
+// See also [Serve], which instruments a handler and serves it on a Listener.
 func InstrumentHandler(label string, handler http.Handler) http.Handler {
+	tracer := otel.Tracer(httpTracerName)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, label,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethodKey.String(r.Method), semconv.HTTPTargetKey.String(r.URL.RequestURI())))
+		defer span.End()
+
+		writer := responseWriterInstrumenter{w: w}
+		handler.ServeHTTP(&writer, r.WithContext(ctx))
+		code := writer.statusCode
+		if code == 0 {
+			// Nothing was written, so net/http replies with a 200.
+			code = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(code))
+		if code >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(code))
+		}
+
 		// TODO(spetrovic): It is possible for the user to override r.Host
 		// and therefore get an incorrect host label attached here. Consider
 		// a more robust solution for fetching the hostname (e.g., get the
 		// listener attached to the HTTP server and return its associated
 		// hostname).
-		labels := httpLabels{Label: label, Host: r.Host}
-
+		labels := httpLabels{Label: label, Host: r.Host, Class: statusClass(code)}
 		httpRequestCounts.Get(labels).Add(1)
-		defer func() {
-			httpRequestLatencyMicros.Get(labels).Put(
-				float64(time.Since(start).Microseconds()))
-		}()
+		httpRequestLatencyMicros.Get(labels).Put(float64(time.Since(start).Microseconds()))
 		if size, ok := requestSize(r); ok {
 			httpRequestBytesReceived.Get(labels).Put(float64(size))
 		}
-		writer := responseWriterInstrumenter{w: w}
-		handler.ServeHTTP(&writer, r)
-		if writer.statusCode >= 400 && writer.statusCode < 600 {
+		if code >= 400 && code < 600 {
 			httpRequestErrors.Get(httpErrorLabels{
 				Label: label,
 				Host:  r.Host,
-				Code:  writer.statusCode,
+				Code:  code,
 			}).Add(1)
 		}
 		httpRequestBytesReturned.Get(labels).Put(float64(writer.responseSize(r)))
 	})
 }
 
+// statusClass returns the class of an HTTP status code, e.g., "4xx" for 404.
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "other"
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
+
 // InstrumentHandlerFunc is identical to [InstrumentHandler] but takes a
 // function instead of an http.Handler.
 func InstrumentHandlerFunc(label string, f func(http.ResponseWriter, *http.Request)) http.Handler {
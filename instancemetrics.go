@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"sync"
+
+	"github.com/ServiceWeaver/weaver/metrics"
+)
+
+// componentLabels are the labels of the metrics returned by Instance.Counter,
+// Instance.Gauge, and Instance.Histogram.
+type componentLabels struct {
+	Component string // full component name
+}
+
+// instanceMetrics stores the metric maps backing component-scoped metrics,
+// keyed by metric name. A metric map is registered the first time any
+// component asks for a metric with its name.
+var instanceMetrics = struct {
+	mu         sync.Mutex
+	counters   map[string]*metrics.CounterMap[componentLabels]
+	gauges     map[string]*metrics.GaugeMap[componentLabels]
+	histograms map[string]*metrics.HistogramMap[componentLabels]
+}{
+	counters:   map[string]*metrics.CounterMap[componentLabels]{},
+	gauges:     map[string]*metrics.GaugeMap[componentLabels]{},
+	histograms: map[string]*metrics.HistogramMap[componentLabels]{},
+}
+
+// Counter implements the Instance interface.
+func (c *componentImpl) Counter(name, help string) *metrics.Counter {
+	instanceMetrics.mu.Lock()
+	defer instanceMetrics.mu.Unlock()
+	m, ok := instanceMetrics.counters[name]
+	if !ok {
+		m = metrics.NewCounterMap[componentLabels](name, help)
+		instanceMetrics.counters[name] = m
+	}
+	return m.Get(componentLabels{c.component.info.Name})
+}
+
+// Gauge implements the Instance interface.
+func (c *componentImpl) Gauge(name, help string) *metrics.Gauge {
+	instanceMetrics.mu.Lock()
+	defer instanceMetrics.mu.Unlock()
+	m, ok := instanceMetrics.gauges[name]
+	if !ok {
+		m = metrics.NewGaugeMap[componentLabels](name, help)
+		instanceMetrics.gauges[name] = m
+	}
+	return m.Get(componentLabels{c.component.info.Name})
+}
+
+// Histogram implements the Instance interface.
+func (c *componentImpl) Histogram(name, help string, bounds []float64) *metrics.Histogram {
+	instanceMetrics.mu.Lock()
+	defer instanceMetrics.mu.Unlock()
+	m, ok := instanceMetrics.histograms[name]
+	if !ok {
+		m = metrics.NewHistogramMap[componentLabels](name, help, bounds)
+		instanceMetrics.histograms[name] = m
+	}
+	return m.Get(componentLabels{c.component.info.Name})
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+)
+
+func TestInstanceMetrics(t *testing.T) {
+	newImpl := func(name string) *componentImpl {
+		return &componentImpl{component: &component{info: &codegen.Registration{Name: name}}}
+	}
+	a, b := newImpl("pkg/A"), newImpl("pkg/B")
+
+	// Repeated calls with the same name return the same metric.
+	a.Counter("test_instance_counter", "").Add(1)
+	a.Counter("test_instance_counter", "").Add(2)
+	b.Counter("test_instance_counter", "").Add(10)
+	a.Gauge("test_instance_gauge", "").Set(5)
+	a.Histogram("test_instance_histogram", "", []float64{1, 10}).Put(3)
+	a.Histogram("test_instance_histogram", "", []float64{1, 10}).Put(4)
+
+	type key struct{ name, component string }
+	want := map[key]float64{
+		{"test_instance_counter", "pkg/A"}:   3,
+		{"test_instance_counter", "pkg/B"}:   10,
+		{"test_instance_gauge", "pkg/A"}:     5,
+		{"test_instance_histogram", "pkg/A"}: 7,
+	}
+	got := map[key]float64{}
+	for _, m := range metrics.Snapshot() {
+		k := key{m.Name, m.Labels["component"]}
+		if _, ok := want[k]; ok {
+			got[k] = m.Value
+		}
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("metric %q with component %q: got %v, want %v", k.name, k.component, got[k], v)
+		}
+	}
+}
+
+func TestInstanceMetricsTypeMismatch(t *testing.T) {
+	c := &componentImpl{component: &component{info: &codegen.Registration{Name: "pkg/A"}}}
+	c.Counter("test_instance_mismatch", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("Gauge with the name of an existing Counter: unexpected success")
+		}
+	}()
+	c.Gauge("test_instance_mismatch", "")
+}
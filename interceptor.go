@@ -0,0 +1,96 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import "github.com/ServiceWeaver/weaver/runtime/codegen"
+
+// Call describes a component method call intercepted by an [Interceptor].
+type Call = codegen.Call
+
+// A Handler executes a component method call. It receives the method
+// arguments, excluding the context, and returns the method results, excluding
+// the final error.
+type Handler = codegen.Handler
+
+// An Interceptor intercepts component method calls. It is invoked with the
+// call's context, a description of the call, and a next handler that continues
+// the call. An interceptor can
+//
+//   - add values to the context before calling next, e.g., an auth token;
+//   - inspect or replace the arguments passed to next;
+//   - inspect or replace the results returned by next, e.g., to log them; or
+//   - return results of its own without calling next, e.g., to inject a fake
+//     response in a test.
+//
+// Arguments and results are passed as []any, in the order declared by the
+// method, excluding the leading context and the trailing error. Values passed
+// to next and results returned by an interceptor must have the types declared
+// by the method. A nil result is replaced with the zero value of its type.
+//
+// For example, the following interceptor logs every call and its latency:
+//
+//	func logCalls(ctx context.Context, call weaver.Call, next weaver.Handler) ([]any, error) {
+//	    start := time.Now()
+//	    results, err := next(ctx, call.Args)
+//	    log.Printf("%s.%s: %v (%v)", call.Component, call.Method, err, time.Since(start))
+//	    return results, err
+//	}
+//
+// # Ordering
+//
+// Client interceptors, registered with [InterceptClient], run in the calling
+// process. Server interceptors, registered with [InterceptServer] or returned
+// by a component's [Interceptable] implementation, run in the process hosting
+// the called component. For a local call, both run, client interceptors first.
+// Within each group, interceptors run in the order they were registered, with
+// the first being the outermost. Interceptors returned by a component's
+// Interceptors method run after the ones registered with InterceptServer.
+//
+// Client interceptors wrap the stub that records the call's metrics and
+// traces, so a call answered by an interceptor without calling next doesn't
+// produce any metrics or traces. Server interceptors wrap the component
+// implementation, inside its [WithObserver] observer, if any.
+type Interceptor = codegen.Interceptor
+
+// Interceptable is implemented by component implementations that intercept
+// the method calls they receive. Interceptors is called once per stub, when
+// the stub is created, which happens after the component is initialized. See
+// [Interceptor] for details.
+//
+//	type cache struct {
+//	    weaver.Implements[Cache]
+//	}
+//
+//	func (c *cache) Interceptors() []weaver.Interceptor {
+//	    return []weaver.Interceptor{c.authorize}
+//	}
+type Interceptable interface {
+	Interceptors() []Interceptor
+}
+
+// InterceptClient registers an interceptor that is invoked on every component
+// method call made by the current process. It must be called before
+// [Run], e.g., in main or in an init function. See [Interceptor] for details.
+func InterceptClient(i Interceptor) {
+	codegen.AddClientInterceptor(i)
+}
+
+// InterceptServer registers an interceptor that is invoked on every component
+// method call received by a component hosted in the current process. It must
+// be called before [Run], e.g., in main or in an init function. See
+// [Interceptor] for details.
+func InterceptServer(i Interceptor) {
+	codegen.AddServerInterceptor(i)
+}
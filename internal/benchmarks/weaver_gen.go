@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping1)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping1{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping1_local_stub{impl: impl.(Ping1), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingS", Remote: false})}
+			return ping1_intercept(ping1_local_stub{impl: impl.(Ping1), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping1_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingS", Remote: true})}
+			return ping1_intercept(ping1_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping1_server_stub{impl: impl.(Ping1), addLoad: addLoad}
+			return ping1_server_stub{impl: ping1_intercept(impl.(Ping1), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping1_intercept(next.(Ping1), interceptor, call)
 		},
 		RefData: "⟦544443c5:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2⟧\n",
 	})
@@ -55,13 +58,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping10)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping10{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping10_local_stub{impl: impl.(Ping10), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingS", Remote: false})}
+			return ping10_intercept(ping10_local_stub{impl: impl.(Ping10), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping10_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingS", Remote: true})}
+			return ping10_intercept(ping10_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping10_server_stub{impl: impl.(Ping10), addLoad: addLoad}
+			return ping10_server_stub{impl: ping10_intercept(impl.(Ping10), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping10_intercept(next.(Ping10), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -70,13 +76,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping2)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping2{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping2_local_stub{impl: impl.(Ping2), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingS", Remote: false})}
+			return ping2_intercept(ping2_local_stub{impl: impl.(Ping2), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping2_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingS", Remote: true})}
+			return ping2_intercept(ping2_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping2_server_stub{impl: impl.(Ping2), addLoad: addLoad}
+			return ping2_server_stub{impl: ping2_intercept(impl.(Ping2), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping2_intercept(next.(Ping2), interceptor, call)
 		},
 		RefData: "⟦b42b173c:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3⟧\n",
 	})
@@ -85,13 +94,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping3)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping3{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping3_local_stub{impl: impl.(Ping3), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingS", Remote: false})}
+			return ping3_intercept(ping3_local_stub{impl: impl.(Ping3), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping3_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingS", Remote: true})}
+			return ping3_intercept(ping3_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping3_server_stub{impl: impl.(Ping3), addLoad: addLoad}
+			return ping3_server_stub{impl: ping3_intercept(impl.(Ping3), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping3_intercept(next.(Ping3), interceptor, call)
 		},
 		RefData: "⟦8c498b47:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4⟧\n",
 	})
@@ -100,13 +112,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping4)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping4{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping4_local_stub{impl: impl.(Ping4), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingS", Remote: false})}
+			return ping4_intercept(ping4_local_stub{impl: impl.(Ping4), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping4_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingS", Remote: true})}
+			return ping4_intercept(ping4_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping4_server_stub{impl: impl.(Ping4), addLoad: addLoad}
+			return ping4_server_stub{impl: ping4_intercept(impl.(Ping4), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping4_intercept(next.(Ping4), interceptor, call)
 		},
 		RefData: "⟦90669915:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5⟧\n",
 	})
@@ -115,13 +130,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping5)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping5{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping5_local_stub{impl: impl.(Ping5), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingS", Remote: false})}
+			return ping5_intercept(ping5_local_stub{impl: impl.(Ping5), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping5_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingS", Remote: true})}
+			return ping5_intercept(ping5_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping5_server_stub{impl: impl.(Ping5), addLoad: addLoad}
+			return ping5_server_stub{impl: ping5_intercept(impl.(Ping5), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping5_intercept(next.(Ping5), interceptor, call)
 		},
 		RefData: "⟦a38d1914:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6⟧\n",
 	})
@@ -130,13 +148,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping6)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping6{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping6_local_stub{impl: impl.(Ping6), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingS", Remote: false})}
+			return ping6_intercept(ping6_local_stub{impl: impl.(Ping6), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping6_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingS", Remote: true})}
+			return ping6_intercept(ping6_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping6_server_stub{impl: impl.(Ping6), addLoad: addLoad}
+			return ping6_server_stub{impl: ping6_intercept(impl.(Ping6), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping6_intercept(next.(Ping6), interceptor, call)
 		},
 		RefData: "⟦ebf8b6d3:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7⟧\n",
 	})
@@ -145,13 +166,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping7)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping7{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping7_local_stub{impl: impl.(Ping7), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingS", Remote: false})}
+			return ping7_intercept(ping7_local_stub{impl: impl.(Ping7), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping7_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingS", Remote: true})}
+			return ping7_intercept(ping7_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping7_server_stub{impl: impl.(Ping7), addLoad: addLoad}
+			return ping7_server_stub{impl: ping7_intercept(impl.(Ping7), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping7_intercept(next.(Ping7), interceptor, call)
 		},
 		RefData: "⟦88d68418:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8⟧\n",
 	})
@@ -160,13 +184,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping8)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping8{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping8_local_stub{impl: impl.(Ping8), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingS", Remote: false})}
+			return ping8_intercept(ping8_local_stub{impl: impl.(Ping8), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping8_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingS", Remote: true})}
+			return ping8_intercept(ping8_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping8_server_stub{impl: impl.(Ping8), addLoad: addLoad}
+			return ping8_server_stub{impl: ping8_intercept(impl.(Ping8), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping8_intercept(next.(Ping8), interceptor, call)
 		},
 		RefData: "⟦ed98271d:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9⟧\n",
 	})
@@ -175,13 +202,16 @@ func init() {
 		Iface: reflect.TypeOf((*Ping9)(nil)).Elem(),
 		Impl:  reflect.TypeOf(ping9{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return ping9_local_stub{impl: impl.(Ping9), tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingS", Remote: false})}
+			return ping9_intercept(ping9_local_stub{impl: impl.(Ping9), caller: caller, tracer: tracer, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingC", Remote: false}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingS", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return ping9_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingS", Remote: true})}
+			return ping9_intercept(ping9_client_stub{stub: stub, pingCMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingC", Remote: true}), pingSMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Method: "PingS", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return ping9_server_stub{impl: impl.(Ping9), addLoad: addLoad}
+			return ping9_server_stub{impl: ping9_intercept(impl.(Ping9), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return ping9_intercept(next.(Ping9), interceptor, call)
 		},
 		RefData: "⟦5ceb96a7:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9→github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10⟧\n",
 	})
@@ -215,6 +245,7 @@ var _ weaver.Unrouted = (*ping9)(nil)
 
 type ping1_local_stub struct {
 	impl         Ping1
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -240,6 +271,7 @@ func (s ping1_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -260,11 +292,13 @@ func (s ping1_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping10_local_stub struct {
 	impl         Ping10
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -290,6 +324,7 @@ func (s ping10_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -310,11 +345,13 @@ func (s ping10_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping2_local_stub struct {
 	impl         Ping2
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -340,6 +377,7 @@ func (s ping2_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -360,11 +398,13 @@ func (s ping2_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping3_local_stub struct {
 	impl         Ping3
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -390,6 +430,7 @@ func (s ping3_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -410,11 +451,13 @@ func (s ping3_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping4_local_stub struct {
 	impl         Ping4
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -440,6 +483,7 @@ func (s ping4_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -460,11 +504,13 @@ func (s ping4_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping5_local_stub struct {
 	impl         Ping5
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -490,6 +536,7 @@ func (s ping5_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -510,11 +557,13 @@ func (s ping5_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping6_local_stub struct {
 	impl         Ping6
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -540,6 +589,7 @@ func (s ping6_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -560,11 +610,13 @@ func (s ping6_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping7_local_stub struct {
 	impl         Ping7
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -590,6 +642,7 @@ func (s ping7_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -610,11 +663,13 @@ func (s ping7_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping8_local_stub struct {
 	impl         Ping8
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -640,6 +695,7 @@ func (s ping8_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -660,11 +716,13 @@ func (s ping8_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
 type ping9_local_stub struct {
 	impl         Ping9
+	caller       string
 	tracer       trace.Tracer
 	pingCMetrics *codegen.MethodMetrics
 	pingSMetrics *codegen.MethodMetrics
@@ -690,6 +748,7 @@ func (s ping9_local_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9")
 	return s.impl.PingC(ctx, a0, a1)
 }
 
@@ -710,6 +769,7 @@ func (s ping9_local_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 pa
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9")
 	return s.impl.PingS(ctx, a0, a1)
 }
 
@@ -727,8 +787,11 @@ var _ Ping1 = (*ping1_client_stub)(nil)
 func (s ping1_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -741,6 +804,7 @@ func (s ping1_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -766,11 +830,12 @@ func (s ping1_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -785,8 +850,11 @@ func (s ping1_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping1_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -799,6 +867,7 @@ func (s ping1_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -818,11 +887,12 @@ func (s ping1_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -846,8 +916,11 @@ var _ Ping10 = (*ping10_client_stub)(nil)
 func (s ping10_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -860,6 +933,7 @@ func (s ping10_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -885,11 +959,12 @@ func (s ping10_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -904,8 +979,11 @@ func (s ping10_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0
 func (s ping10_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -918,6 +996,7 @@ func (s ping10_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -937,11 +1016,12 @@ func (s ping10_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -965,8 +1045,11 @@ var _ Ping2 = (*ping2_client_stub)(nil)
 func (s ping2_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -979,6 +1062,7 @@ func (s ping2_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1004,11 +1088,12 @@ func (s ping2_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1023,8 +1108,11 @@ func (s ping2_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping2_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1037,6 +1125,7 @@ func (s ping2_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1056,11 +1145,12 @@ func (s ping2_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1084,8 +1174,11 @@ var _ Ping3 = (*ping3_client_stub)(nil)
 func (s ping3_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1098,6 +1191,7 @@ func (s ping3_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1123,11 +1217,12 @@ func (s ping3_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1142,8 +1237,11 @@ func (s ping3_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping3_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1156,6 +1254,7 @@ func (s ping3_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1175,11 +1274,12 @@ func (s ping3_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1203,8 +1303,11 @@ var _ Ping4 = (*ping4_client_stub)(nil)
 func (s ping4_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1217,6 +1320,7 @@ func (s ping4_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1242,11 +1346,12 @@ func (s ping4_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1261,8 +1366,11 @@ func (s ping4_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping4_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1275,6 +1383,7 @@ func (s ping4_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1294,11 +1403,12 @@ func (s ping4_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1322,8 +1432,11 @@ var _ Ping5 = (*ping5_client_stub)(nil)
 func (s ping5_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1336,6 +1449,7 @@ func (s ping5_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1361,11 +1475,12 @@ func (s ping5_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1380,8 +1495,11 @@ func (s ping5_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping5_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1394,6 +1512,7 @@ func (s ping5_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1413,11 +1532,12 @@ func (s ping5_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1441,8 +1561,11 @@ var _ Ping6 = (*ping6_client_stub)(nil)
 func (s ping6_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1455,6 +1578,7 @@ func (s ping6_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1480,11 +1604,12 @@ func (s ping6_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1499,8 +1624,11 @@ func (s ping6_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping6_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1513,6 +1641,7 @@ func (s ping6_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1532,11 +1661,12 @@ func (s ping6_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1560,8 +1690,11 @@ var _ Ping7 = (*ping7_client_stub)(nil)
 func (s ping7_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1574,6 +1707,7 @@ func (s ping7_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1599,11 +1733,12 @@ func (s ping7_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1618,8 +1753,11 @@ func (s ping7_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping7_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1632,6 +1770,7 @@ func (s ping7_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1651,11 +1790,12 @@ func (s ping7_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1679,8 +1819,11 @@ var _ Ping8 = (*ping8_client_stub)(nil)
 func (s ping8_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1693,6 +1836,7 @@ func (s ping8_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1718,11 +1862,12 @@ func (s ping8_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1737,8 +1882,11 @@ func (s ping8_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping8_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1751,6 +1899,7 @@ func (s ping8_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1770,11 +1919,12 @@ func (s ping8_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1798,8 +1948,11 @@ var _ Ping9 = (*ping9_client_stub)(nil)
 func (s ping9_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingCMetrics.Begin()
-	defer func() { s.pingCMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingCMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1812,6 +1965,7 @@ func (s ping9_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1837,11 +1991,12 @@ func (s ping9_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1856,8 +2011,11 @@ func (s ping9_client_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 p
 func (s ping9_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingSMetrics.Begin()
-	defer func() { s.pingSMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingSMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -1870,6 +2028,7 @@ func (s ping9_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -1889,11 +2048,12 @@ func (s ping9_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -1908,8 +2068,9 @@ func (s ping9_client_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 p
 // Server stub implementations.
 
 type ping1_server_stub struct {
-	impl    Ping1
-	addLoad func(key uint64, load float64)
+	impl       Ping1
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping1_server_stub implements the codegen.Server interface.
@@ -1927,6 +2088,11 @@ func (s ping1_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping1_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping1_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -1942,10 +2108,14 @@ func (s ping1_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -1969,10 +2139,14 @@ func (s ping1_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping1", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -1982,8 +2156,9 @@ func (s ping1_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping10_server_stub struct {
-	impl    Ping10
-	addLoad func(key uint64, load float64)
+	impl       Ping10
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping10_server_stub implements the codegen.Server interface.
@@ -2001,6 +2176,11 @@ func (s ping10_server_stub) GetStubFn(method string) func(ctx context.Context, a
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping10_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping10_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2016,10 +2196,14 @@ func (s ping10_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2043,10 +2227,14 @@ func (s ping10_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping10", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2056,8 +2244,9 @@ func (s ping10_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping2_server_stub struct {
-	impl    Ping2
-	addLoad func(key uint64, load float64)
+	impl       Ping2
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping2_server_stub implements the codegen.Server interface.
@@ -2075,6 +2264,11 @@ func (s ping2_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping2_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping2_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2090,10 +2284,14 @@ func (s ping2_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2117,10 +2315,14 @@ func (s ping2_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping2", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2130,8 +2332,9 @@ func (s ping2_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping3_server_stub struct {
-	impl    Ping3
-	addLoad func(key uint64, load float64)
+	impl       Ping3
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping3_server_stub implements the codegen.Server interface.
@@ -2149,6 +2352,11 @@ func (s ping3_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping3_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping3_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2164,10 +2372,14 @@ func (s ping3_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2191,10 +2403,14 @@ func (s ping3_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping3", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2204,8 +2420,9 @@ func (s ping3_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping4_server_stub struct {
-	impl    Ping4
-	addLoad func(key uint64, load float64)
+	impl       Ping4
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping4_server_stub implements the codegen.Server interface.
@@ -2223,6 +2440,11 @@ func (s ping4_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping4_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping4_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2238,10 +2460,14 @@ func (s ping4_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2265,10 +2491,14 @@ func (s ping4_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping4", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2278,8 +2508,9 @@ func (s ping4_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping5_server_stub struct {
-	impl    Ping5
-	addLoad func(key uint64, load float64)
+	impl       Ping5
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping5_server_stub implements the codegen.Server interface.
@@ -2297,6 +2528,11 @@ func (s ping5_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping5_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping5_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2312,10 +2548,14 @@ func (s ping5_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2339,10 +2579,14 @@ func (s ping5_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping5", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2352,8 +2596,9 @@ func (s ping5_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping6_server_stub struct {
-	impl    Ping6
-	addLoad func(key uint64, load float64)
+	impl       Ping6
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping6_server_stub implements the codegen.Server interface.
@@ -2371,6 +2616,11 @@ func (s ping6_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping6_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping6_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2386,10 +2636,14 @@ func (s ping6_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2413,10 +2667,14 @@ func (s ping6_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping6", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2426,8 +2684,9 @@ func (s ping6_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping7_server_stub struct {
-	impl    Ping7
-	addLoad func(key uint64, load float64)
+	impl       Ping7
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping7_server_stub implements the codegen.Server interface.
@@ -2445,6 +2704,11 @@ func (s ping7_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping7_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping7_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2460,10 +2724,14 @@ func (s ping7_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2487,10 +2755,14 @@ func (s ping7_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping7", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2500,8 +2772,9 @@ func (s ping7_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping8_server_stub struct {
-	impl    Ping8
-	addLoad func(key uint64, load float64)
+	impl       Ping8
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping8_server_stub implements the codegen.Server interface.
@@ -2519,6 +2792,11 @@ func (s ping8_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping8_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping8_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2534,10 +2812,14 @@ func (s ping8_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2561,10 +2843,14 @@ func (s ping8_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping8", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2574,8 +2860,9 @@ func (s ping8_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 }
 
 type ping9_server_stub struct {
-	impl    Ping9
-	addLoad func(key uint64, load float64)
+	impl       Ping9
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that ping9_server_stub implements the codegen.Server interface.
@@ -2593,6 +2880,11 @@ func (s ping9_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s ping9_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s ping9_server_stub) pingC(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -2608,10 +2900,14 @@ func (s ping9_server_stub) pingC(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingC(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadC
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", "PingC", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingC(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2635,10 +2931,14 @@ func (s ping9_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	var a1 int
 	a1 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.PingS(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 payloadS
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/benchmarks/Ping9", "PingS", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingS(ctx, a0, a1)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -2647,6 +2947,348 @@ func (s ping9_server_stub) pingS(ctx context.Context, args []byte) (res []byte,
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type ping1_intercept_stub struct {
+	next        Ping1
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping1_intercept_stub implements the Ping1 interface.
+var _ Ping1 = (*ping1_intercept_stub)(nil)
+
+// ping1_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping1_intercept(next Ping1, interceptor codegen.Interceptor, call codegen.Call) Ping1 {
+	if interceptor == nil {
+		return next
+	}
+	return ping1_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping1_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping1_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping10_intercept_stub struct {
+	next        Ping10
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping10_intercept_stub implements the Ping10 interface.
+var _ Ping10 = (*ping10_intercept_stub)(nil)
+
+// ping10_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping10_intercept(next Ping10, interceptor codegen.Interceptor, call codegen.Call) Ping10 {
+	if interceptor == nil {
+		return next
+	}
+	return ping10_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping10_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping10_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping2_intercept_stub struct {
+	next        Ping2
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping2_intercept_stub implements the Ping2 interface.
+var _ Ping2 = (*ping2_intercept_stub)(nil)
+
+// ping2_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping2_intercept(next Ping2, interceptor codegen.Interceptor, call codegen.Call) Ping2 {
+	if interceptor == nil {
+		return next
+	}
+	return ping2_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping2_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping2_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping3_intercept_stub struct {
+	next        Ping3
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping3_intercept_stub implements the Ping3 interface.
+var _ Ping3 = (*ping3_intercept_stub)(nil)
+
+// ping3_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping3_intercept(next Ping3, interceptor codegen.Interceptor, call codegen.Call) Ping3 {
+	if interceptor == nil {
+		return next
+	}
+	return ping3_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping3_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping3_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping4_intercept_stub struct {
+	next        Ping4
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping4_intercept_stub implements the Ping4 interface.
+var _ Ping4 = (*ping4_intercept_stub)(nil)
+
+// ping4_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping4_intercept(next Ping4, interceptor codegen.Interceptor, call codegen.Call) Ping4 {
+	if interceptor == nil {
+		return next
+	}
+	return ping4_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping4_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping4_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping5_intercept_stub struct {
+	next        Ping5
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping5_intercept_stub implements the Ping5 interface.
+var _ Ping5 = (*ping5_intercept_stub)(nil)
+
+// ping5_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping5_intercept(next Ping5, interceptor codegen.Interceptor, call codegen.Call) Ping5 {
+	if interceptor == nil {
+		return next
+	}
+	return ping5_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping5_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping5_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping6_intercept_stub struct {
+	next        Ping6
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping6_intercept_stub implements the Ping6 interface.
+var _ Ping6 = (*ping6_intercept_stub)(nil)
+
+// ping6_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping6_intercept(next Ping6, interceptor codegen.Interceptor, call codegen.Call) Ping6 {
+	if interceptor == nil {
+		return next
+	}
+	return ping6_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping6_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping6_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping7_intercept_stub struct {
+	next        Ping7
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping7_intercept_stub implements the Ping7 interface.
+var _ Ping7 = (*ping7_intercept_stub)(nil)
+
+// ping7_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping7_intercept(next Ping7, interceptor codegen.Interceptor, call codegen.Call) Ping7 {
+	if interceptor == nil {
+		return next
+	}
+	return ping7_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping7_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping7_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping8_intercept_stub struct {
+	next        Ping8
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping8_intercept_stub implements the Ping8 interface.
+var _ Ping8 = (*ping8_intercept_stub)(nil)
+
+// ping8_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping8_intercept(next Ping8, interceptor codegen.Interceptor, call codegen.Call) Ping8 {
+	if interceptor == nil {
+		return next
+	}
+	return ping8_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping8_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping8_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
+type ping9_intercept_stub struct {
+	next        Ping9
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that ping9_intercept_stub implements the Ping9 interface.
+var _ Ping9 = (*ping9_intercept_stub)(nil)
+
+// ping9_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func ping9_intercept(next Ping9, interceptor codegen.Interceptor, call codegen.Call) Ping9 {
+	if interceptor == nil {
+		return next
+	}
+	return ping9_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s ping9_intercept_stub) PingC(ctx context.Context, a0 payloadC, a1 int) (r0 payloadC, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingC", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingC(ctx, codegen.Arg[payloadC](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadC](results, 0), err
+}
+
+func (s ping9_intercept_stub) PingS(ctx context.Context, a0 payloadS, a1 int) (r0 payloadS, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingS", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingS(ctx, codegen.Arg[payloadS](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[payloadS](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*X1)(nil)
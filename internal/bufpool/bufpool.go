@@ -0,0 +1,96 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufpool implements a pool of byte slices, shared by the encoders of
+// generated code and the RPC transport.
+//
+// Slices are pooled by size class: class i holds slices with a capacity of at
+// least minSize << i and less than twice that. When the pool of a request's
+// class is empty, Get allocates a slice of exactly the requested capacity, so
+// that callers that never return their slices don't pay for rounding. Slices
+// larger than maxSize are allocated on demand and dropped when returned, so
+// that a single large message doesn't pin a large slice in the pool.
+package bufpool
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	minSize    = 64
+	maxSize    = 64 << 10
+	numClasses = 11 // minSize << (numClasses-1) == maxSize
+)
+
+var (
+	pools [numClasses]sync.Pool // *[]byte
+
+	// holders holds the *[]byte values that are put in pools, so that
+	// returning a slice to its pool doesn't allocate.
+	holders = sync.Pool{New: func() any { return new([]byte) }}
+)
+
+// Get returns an empty byte slice with a capacity of at least n. The slice
+// may be returned to the pool with Put once it is no longer in use, but it
+// doesn't have to be.
+func Get(n int) []byte {
+	c := getClass(n)
+	if c < 0 {
+		return make([]byte, 0, n)
+	}
+	if h, ok := pools[c].Get().(*[]byte); ok {
+		b := *h
+		*h = nil
+		holders.Put(h)
+		return b[:0]
+	}
+	return make([]byte, 0, n)
+}
+
+// Put returns a byte slice to the pool. The caller must own b, and b must not
+// be used after the call. Slices whose capacity is too small or too large to
+// be pooled are dropped.
+func Put(b []byte) {
+	c := putClass(cap(b))
+	if c < 0 {
+		return
+	}
+	h := holders.Get().(*[]byte)
+	*h = b[:0]
+	pools[c].Put(h)
+}
+
+// getClass returns the class of the smallest pooled slices that can hold n
+// bytes, i.e., the smallest i such that n <= minSize << i, or -1 if n is
+// larger than maxSize.
+func getClass(n int) int {
+	if n <= minSize {
+		return 0
+	}
+	if n > maxSize {
+		return -1
+	}
+	return bits.Len(uint(n-1) / minSize)
+}
+
+// putClass returns the class of slices with capacity n, i.e., the largest i
+// such that minSize << i <= n, or -1 if n is smaller than minSize or larger
+// than maxSize.
+func putClass(n int) int {
+	if n < minSize || n > maxSize {
+		return -1
+	}
+	return bits.Len(uint(n/minSize)) - 1
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpool
+
+import "testing"
+
+func TestClasses(t *testing.T) {
+	for _, test := range []struct {
+		n, get, put int
+	}{
+		{0, 0, -1},
+		{1, 0, -1},
+		{63, 0, -1},
+		{64, 0, 0},
+		{65, 1, 0},
+		{128, 1, 1},
+		{129, 2, 1},
+		{4 << 10, 6, 6},
+		{5000, 7, 6},
+		{maxSize, numClasses - 1, numClasses - 1},
+		{maxSize + 1, -1, -1},
+	} {
+		if got := getClass(test.n); got != test.get {
+			t.Errorf("getClass(%d): got %d, want %d", test.n, got, test.get)
+		}
+		if got := putClass(test.n); got != test.put {
+			t.Errorf("putClass(%d): got %d, want %d", test.n, got, test.put)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 1000, maxSize, maxSize + 1, 1 << 20} {
+		b := Get(n)
+		if len(b) != 0 || cap(b) < n {
+			t.Errorf("Get(%d): got length %d, capacity %d", n, len(b), cap(b))
+		}
+		Put(b)
+	}
+}
+
+func TestGetMissAllocatesExactly(t *testing.T) {
+	// Class 8 is not used by any other test, so its pool is empty, and Get
+	// must allocate exactly the requested capacity rather than the size of
+	// the class.
+	const n = 10000
+	if c := getClass(n); c != 8 {
+		t.Fatalf("getClass(%d): got %d, want 8", n, c)
+	}
+	if b := Get(n); cap(b) != n {
+		t.Errorf("Get(%d): got capacity %d, want %d", n, cap(b), n)
+	}
+}
+
+func TestPut(t *testing.T) {
+	// Return slices of every size, including ones that aren't pooled, or
+	// whose capacity isn't the size of a class. Every slice returned by Get
+	// must still be large enough.
+	for _, n := range []int{1, 100, 200, 1000, 5000, maxSize - 1, maxSize, 1 << 20} {
+		Put(make([]byte, n))
+	}
+	for _, n := range []int{65, 129, 300, 1000, 4096, 5000, maxSize} {
+		for i := 0; i < 10; i++ {
+			b := Get(n)
+			if cap(b) < n {
+				t.Fatalf("Get(%d): got capacity %d", n, cap(b))
+			}
+			Put(b)
+		}
+	}
+
+	// A slice that is too large to be pooled is dropped, rather than being
+	// returned to the largest pool.
+	Put(make([]byte, 1<<20))
+	for i := 0; i < 10; i++ {
+		if b := Get(maxSize); cap(b) != maxSize {
+			t.Fatalf("Get(%d): got capacity %d", maxSize, cap(b))
+		}
+	}
+}
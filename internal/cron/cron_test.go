@@ -0,0 +1,79 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// 2023-06-15 is a Thursday.
+	start := time.Date(2023, 6, 15, 10, 30, 45, 0, time.UTC)
+	for _, test := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2023, 6, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, 6, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 * * * *", time.Date(2023, 6, 15, 11, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2023, 6, 15, 13, 0, 0, 0, time.UTC)},
+		{"5,10 0 * * *", time.Date(2023, 6, 16, 0, 5, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon", time.Date(2023, 6, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2023, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * jan *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * 6", time.Date(2023, 6, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, 6, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2023, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2023, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			s, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(start); !got.Equal(test.want) {
+				t.Errorf("Next(%v): got %v, want %v", start, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+		"@often",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): unexpected success", expr)
+		}
+	}
+}
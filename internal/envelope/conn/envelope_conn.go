@@ -346,6 +346,22 @@ func (e *EnvelopeConn) UpdateRoutingInfoRPC(routing *protos.RoutingInfo) error {
 	return nil
 }
 
+// UpdateLeaderRPC tells the weavelet whether it is the leader among the
+// replicas of its colocation group.
+func (e *EnvelopeConn) UpdateLeaderRPC(leader bool) error {
+	req := &protos.EnvelopeMsg{
+		UpdateLeaderRequest: &protos.UpdateLeaderRequest{Leader: leader},
+	}
+	reply, err := e.rpc(req)
+	if err != nil {
+		return err
+	}
+	if reply.UpdateLeaderReply == nil {
+		return fmt.Errorf("nil UpdateLeaderReply received from weavelet")
+	}
+	return nil
+}
+
 func (e *EnvelopeConn) rpc(request *protos.EnvelopeMsg) (*protos.WeaveletMsg, error) {
 	response, err := e.conn.doBlockingRPC(request)
 	if err != nil {
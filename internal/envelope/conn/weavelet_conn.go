@@ -45,6 +45,10 @@ type WeaveletHandler interface {
 
 	// UpdateRoutingInfo updates a component's routing information.
 	UpdateRoutingInfo(*protos.UpdateRoutingInfoRequest) (*protos.UpdateRoutingInfoReply, error)
+
+	// UpdateLeader updates whether the weavelet is the leader among the
+	// replicas of its colocation group.
+	UpdateLeader(*protos.UpdateLeaderRequest) (*protos.UpdateLeaderReply, error)
 }
 
 // WeaveletConn is the weavelet side of the connection between a weavelet and
@@ -211,6 +215,13 @@ func (w *WeaveletConn) handleMessage(msg *protos.EnvelopeMsg) error {
 			Error:                  errstring(err),
 			UpdateRoutingInfoReply: reply,
 		})
+	case msg.UpdateLeaderRequest != nil:
+		reply, err := w.handler.UpdateLeader(msg.UpdateLeaderRequest)
+		return w.conn.send(&protos.WeaveletMsg{
+			Id:                -msg.Id,
+			Error:             errstring(err),
+			UpdateLeaderReply: reply,
+		})
 	default:
 		err := fmt.Errorf("weavelet_conn: unexpected message %+v", msg)
 		w.conn.cleanup(err)
@@ -0,0 +1,136 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exports publishes the addresses of the exported components of
+// running deployments, so that the processes of other applications can call
+// them through a weaver.ExternalRef.
+package exports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/files"
+)
+
+// DirKey is the environment variable that holds the directory in which a
+// deployer publishes the exports of its deployments. A deployer that
+// publishes exports sets it in the environment of the weavelets it starts,
+// which look up the components of other applications in the directory.
+const DirKey = "SERVICEWEAVER_EXPORTS_DIR"
+
+// An Export lists the exported components of a deployment and the addresses
+// of their replicas.
+type Export struct {
+	DeploymentId string              // deployment id
+	App          string              // app name
+	Started      time.Time           // when the deployment started
+	Mtls         bool                // do the replicas require mTLS?
+	Components   map[string][]string // replica addresses, by component
+}
+
+// filename returns the name of the file that holds the export of the
+// provided deployment in dir.
+func filename(dir, deploymentId string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.export.json", deploymentId))
+}
+
+// Publish publishes the provided export in dir, replacing the previous export
+// of the same deployment, if any.
+func Publish(dir string, e Export) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("exports: make dir %q: %w", dir, err)
+	}
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("exports: encode %v: %w", e, err)
+	}
+	file := filename(dir, e.DeploymentId)
+	w := files.NewWriter(file)
+	defer w.Cleanup()
+	if _, err := w.Write(bytes); err != nil {
+		return fmt.Errorf("exports: write %q: %w", file, err)
+	}
+	return w.Close()
+}
+
+// Unpublish removes the export of the provided deployment from dir, if any.
+func Unpublish(dir, deploymentId string) error {
+	file := filename(dir, deploymentId)
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("exports: remove %q: %w", file, err)
+	}
+	return nil
+}
+
+// List returns the exports published in dir.
+func List(dir string) ([]Export, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("exports: read dir %q: %w", dir, err)
+	}
+
+	var exports []Export
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".export.json") {
+			// Ignore temporary files, among others.
+			continue
+		}
+		file := filepath.Join(dir, entry.Name())
+		bytes, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			// The export was unpublished after the directory was read.
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("exports: read file %q: %w", file, err)
+		}
+		var e Export
+		if err := json.Unmarshal(bytes, &e); err != nil {
+			return nil, fmt.Errorf("exports: decode file %q: %w", file, err)
+		}
+		exports = append(exports, e)
+	}
+	return exports, nil
+}
+
+// Find returns the export of the most recently started deployment, among
+// those published in dir, that exports the provided component. It returns
+// false if no deployment exports the component.
+//
+// A deployment that terminates abruptly may leave its export behind. Its
+// replacement is started later, so its export takes precedence.
+func Find(dir, component string) (Export, bool, error) {
+	exports, err := List(dir)
+	if err != nil {
+		return Export{}, false, err
+	}
+	var found Export
+	var ok bool
+	for _, e := range exports {
+		if _, exported := e.Components[component]; !exported {
+			continue
+		}
+		if !ok || e.Started.After(found.Started) {
+			found, ok = e, true
+		}
+	}
+	return found, ok, nil
+}
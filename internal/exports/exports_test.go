@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exports
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPublish(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "exports")
+	now := time.Now().UTC()
+	old := Export{
+		DeploymentId: "0",
+		App:          "inventory",
+		Started:      now.Add(-time.Minute),
+		Components:   map[string][]string{"pkg/Catalog": {"tcp://localhost:1"}},
+	}
+	cur := Export{
+		DeploymentId: "1",
+		App:          "inventory",
+		Started:      now,
+		Mtls:         true,
+		Components:   map[string][]string{"pkg/Catalog": {"tcp://localhost:2", "tcp://localhost:3"}},
+	}
+	for _, e := range []Export{old, cur} {
+		if err := Publish(dir, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The most recently started deployment exporting a component wins.
+	got, ok, err := Find(dir, "pkg/Catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Find: not found")
+	}
+	if diff := cmp.Diff(cur, got); diff != "" {
+		t.Errorf("Find (-want +got):\n%s", diff)
+	}
+	if _, ok, err := Find(dir, "pkg/Private"); err != nil || ok {
+		t.Errorf("Find(pkg/Private): got %v, %v, want not found", ok, err)
+	}
+
+	// Republishing replaces the export.
+	cur.Components["pkg/Catalog"] = []string{"tcp://localhost:4"}
+	if err := Publish(dir, cur); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := Find(dir, "pkg/Catalog"); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(cur, got); diff != "" {
+		t.Errorf("Find after republishing (-want +got):\n%s", diff)
+	}
+
+	// Once unpublished, the older export is found.
+	if err := Unpublish(dir, cur.DeploymentId); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := Find(dir, "pkg/Catalog"); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(old, got); diff != "" {
+		t.Errorf("Find after unpublishing (-want +got):\n%s", diff)
+	}
+}
+
+func TestListMissingDir(t *testing.T) {
+	exports, err := List(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(exports) != 0 {
+		t.Fatalf("List: got %v, %v, want no exports", exports, err)
+	}
+}
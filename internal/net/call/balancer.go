@@ -22,9 +22,8 @@ import (
 // A Balancer picks the endpoint to which which an RPC client performs a call. A
 // Balancer should only be used by a single goroutine.
 //
-// TODO(mwhittaker): Right now, balancers have no load information about
-// endpoints. In the short term, we can at least add information about the
-// number of pending requests for every endpoint.
+// A Balancer has no load information about endpoints, unless it also
+// implements LoadAwareBalancer.
 //
 // TODO(mwhittaker): Right now, we pass a balancer the set of all endpoints. We
 // instead probably want to pass it only the endpoints for which we have a
@@ -45,6 +44,19 @@ type Balancer interface {
 	Pick(CallOptions) (Endpoint, error)
 }
 
+// A LoadAwareBalancer is a Balancer that takes into account the number of
+// calls pending on every endpoint. When a Connection uses a LoadAwareBalancer,
+// it calls PickWithLoad instead of Pick.
+type LoadAwareBalancer interface {
+	Balancer
+
+	// PickWithLoad is like Pick, but it is also passed the number of calls
+	// that the client has pending on every endpoint. pending[i] is the number
+	// of pending calls on the i-th endpoint passed to the most recent call of
+	// Update.
+	PickWithLoad(opts CallOptions, pending []int) (Endpoint, error)
+}
+
 // balancerFuncImpl is the imeplementation of the "functional" balancer
 // returned by BalancerFunc.
 type balancerFuncImpl struct {
@@ -65,10 +65,13 @@ package call
 // the resolver later returns a new set of endpoints that includes a draining
 // connection that hasn't closed itself, the connection is transitioned out of
 // the draining phase and is once again allowed to process new RPCs.
+//
+// A server may also drain itself before shutting down. See drain.go.
 
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -78,6 +81,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ServiceWeaver/weaver/internal/bufpool"
+	"github.com/ServiceWeaver/weaver/metrics"
 	"github.com/ServiceWeaver/weaver/runtime/logging"
 	"github.com/ServiceWeaver/weaver/runtime/retry"
 	"go.opentelemetry.io/otel/codes"
@@ -94,6 +99,26 @@ const (
 	maxReconnectTries = 3
 )
 
+var (
+	// The following metrics track client connection churn. A high rate of
+	// connections being opened and closed for being idle suggests that
+	// ClientOptions.IdleTimeout is too short.
+	connectionsOpened = metrics.NewCounter(
+		"serviceweaver_client_connections_opened",
+		"Number of connections dialed by Service Weaver RPC clients",
+	)
+	connectionsClosed = metrics.NewCounterMap[closeLabels](
+		"serviceweaver_client_connections_closed",
+		"Number of Service Weaver RPC client connections closed, by reason",
+	)
+)
+
+// closeLabels are the labels of the serviceweaver_client_connections_closed
+// metric.
+type closeLabels struct {
+	Reason string // "idle", "drained", "error", or "closed"
+}
+
 // TODO:
 // - Preserve error types (maybe via registration and Gob?)
 // - Load balancer
@@ -109,6 +134,14 @@ type Connection interface {
 	// Call makes an RPC over a Connection.
 	Call(context.Context, MethodKey, []byte, CallOptions) ([]byte, error)
 
+	// CallBuffers is like Call, but the argument is the concatenation of the
+	// provided buffers. The buffers are written to the network as they are,
+	// without being copied into a single buffer first.
+	CallBuffers(context.Context, MethodKey, [][]byte, CallOptions) ([]byte, error)
+
+	// CallStream makes a streaming RPC over a Connection. See ClientStream.
+	CallStream(context.Context, MethodKey, []byte, CallOptions) (*ClientStream, error)
+
 	// Close closes a connection. Pending invocations of Call are cancelled and
 	// return an error. All future invocations of Call fail and return an error
 	// immediately. Close can be called more than once.
@@ -131,10 +164,13 @@ type reconnectingConnection struct {
 	// mu guards the following fields and some of the fields in the
 	// clientConnections inside connections and draining.
 	mu          sync.Mutex
-	endpoints   []Endpoint
+	resolved    []Endpoint                   // endpoints returned by the resolver
+	endpoints   []Endpoint                   // resolved endpoints, minus the draining ones
 	connections map[string]*clientConnection // keys are endpoint addresses
 	draining    map[string]*clientConnection // keys are endpoint addresses
+	drained     map[string]bool              // addresses of draining servers
 	closed      bool
+	pending     []int // scratch space for LoadAwareBalancers
 
 	resolver       Resolver
 	cancelResolver func()         // cancels the watchResolver goroutine
@@ -153,8 +189,15 @@ type clientConnection struct {
 	ended          bool             // has this clientConnection ended?
 	loggedShutdown bool             // Have we logged a shutdown error?
 	version        version          // Version number to use for connection
+	negotiated     chan struct{}    // Closed when version is received from the server
 	calls          map[uint64]*call // In-progress calls
 	lastID         uint64           // Last assigned request ID for a call
+	onDrain        func()           // Called when the server announces it is draining
+
+	// If positive, the connection is closed after being idle for this long.
+	idleTimeout time.Duration
+	idleTimer   *time.Timer // Fires idleTimeout after the connection went idle
+	idleSince   time.Time   // When the connection last became idle
 }
 
 // call holds the state for an active call at the client.
@@ -166,6 +209,11 @@ type call struct {
 	// synchronized via doneSignal, i.e., it is never concurrent.
 	err      error
 	response []byte
+	wireSize int // size of the response as read from the network
+
+	// Chunks of data streamed by the server before the response. Nil for
+	// calls that are not streaming.
+	chunks chan []byte
 
 	// Is the call done?
 	// This field is accessed across goroutines using atomics.
@@ -180,9 +228,10 @@ type serverConnection struct {
 	cbuf        *bufio.Reader // Buffered reader wrapped around c
 	wlock       sync.Mutex    // Guards writes to c
 	mu          sync.Mutex
-	closed      bool              // has c been closed?
-	version     version           // Version number to use for connection
-	cancelFuncs map[uint64]func() // Cancellation functions for in-progress calls
+	closed      bool                     // has c been closed?
+	version     version                  // Version number to use for connection
+	cancelFuncs map[uint64]func()        // Cancellation functions for in-progress calls
+	credits     map[uint64]chan struct{} // Flow control credits for in-progress streaming calls
 }
 
 // serverState tracks all live server-side connections so we can clean things up when canceled.
@@ -249,10 +298,17 @@ func (ss *serverState) serveConnection(ctx context.Context, conn net.Conn, hmap
 		cbuf:        bufio.NewReader(conn),
 		version:     initialVersion, // Updated when we hear from client
 		cancelFuncs: map[uint64]func(){},
+		credits:     map[uint64]chan struct{}{},
 	}
 	ss.register(c)
+	if ss.opts.Drainer.add(c) {
+		c.announceDrain()
+	}
 
-	go c.readRequests(ctx, hmap, func() { ss.unregister(c) })
+	go c.readRequests(ctx, hmap, func() {
+		ss.unregister(c)
+		ss.opts.Drainer.remove(c)
+	})
 }
 
 func (ss *serverState) stop() {
@@ -287,6 +343,7 @@ func Connect(ctx context.Context, resolver Resolver, opts ClientOptions) (Connec
 		endpoints:      []Endpoint{},
 		connections:    map[string]*clientConnection{},
 		draining:       map[string]*clientConnection{},
+		drained:        map[string]bool{},
 		resolver:       resolver,
 		cancelResolver: func() {},
 	}
@@ -332,10 +389,10 @@ func (rc *reconnectingConnection) Close() {
 		}
 		rc.closed = true
 		for _, conn := range rc.connections {
-			conn.endCalls(fmt.Errorf("%w: %s", CommunicationError, "connection closed"))
+			conn.endCalls(fmt.Errorf("%w: %s", CommunicationError, "connection closed"), "closed")
 		}
 		for _, conn := range rc.draining {
-			conn.endCalls(fmt.Errorf("%w: %s", CommunicationError, "connection closed"))
+			conn.endCalls(fmt.Errorf("%w: %s", CommunicationError, "connection closed"), "closed")
 		}
 	}
 	closeWithLock()
@@ -350,29 +407,85 @@ func (rc *reconnectingConnection) Close() {
 
 // Call makes an RPC over connection c.
 func (rc *reconnectingConnection) Call(ctx context.Context, h MethodKey, arg []byte, opts CallOptions) ([]byte, error) {
-	var hdr [msgHeaderSize]byte
-	copy(hdr[0:], h[:])
-	deadline, haveDeadline := ctx.Deadline()
-	if haveDeadline {
+	return rc.CallBuffers(ctx, h, [][]byte{arg}, opts)
+}
+
+// CallBuffers makes an RPC over connection c.
+func (rc *reconnectingConnection) CallBuffers(ctx context.Context, h MethodKey, arg [][]byte, opts CallOptions) ([]byte, error) {
+	for {
+		result, err := rc.callOnce(ctx, h, arg, opts)
+		if err != errDraining {
+			return result, err
+		}
+		// The call was rejected, without running, by a draining server. The
+		// server's endpoint is no longer picked, so retry the call on another
+		// endpoint. The retries end, at the latest, when no endpoint is left.
+	}
+}
+
+// callOnce makes an RPC over connection c, without retrying it.
+func (rc *reconnectingConnection) callOnce(ctx context.Context, h MethodKey, arg [][]byte, opts CallOptions) ([]byte, error) {
+	rpc := &call{}
+	rpc.doneSignal = make(chan struct{})
+	conn, err := rc.sendRequest(ctx, h, arg, opts, rpc)
+	if err != nil {
+		return nil, err
+	}
+
+	// result returns the results of the call, once it is done.
+	result := func() ([]byte, error) {
+		if opts.WireSizes != nil && rpc.err == nil {
+			opts.WireSizes.Reply = rpc.wireSize
+		}
+		return rpc.response, rpc.err
+	}
+
+	if rc.opts.OptimisticSpinDuration > 0 {
+		// Optimistically spin, waiting for the results.
+		for start := time.Now(); time.Since(start) < rc.opts.OptimisticSpinDuration; {
+			if atomic.LoadUint32(&rpc.done) > 0 {
+				return result()
+			}
+		}
+	}
+
+	if cdone := ctx.Done(); cdone != nil {
+		select {
+		case <-rpc.doneSignal:
+			// Regular return
+		case <-cdone:
+			// Canceled or deadline expired.
+			conn.cancelCall(ctx, rpc, rc.opts.WriteFlattenLimit)
+			return nil, ctx.Err()
+		}
+	} else {
+		<-rpc.doneSignal
+	}
+	return result()
+}
+
+// sendRequest registers rpc as a new in-progress call and sends the request
+// for it. The argument is the concatenation of the buffers in arg. It returns
+// the connection the request was sent on.
+func (rc *reconnectingConnection) sendRequest(ctx context.Context, h MethodKey, arg [][]byte, opts CallOptions, rpc *call) (*clientConnection, error) {
+	md := Metadata(ctx)
+	if n := metadataSize(md); n > MaxMetadataSize {
+		return nil, fmt.Errorf("call metadata size %d exceeds limit of %d bytes", n, MaxMetadataSize)
+	}
+	var micros int64
+	if deadline, haveDeadline := ctx.Deadline(); haveDeadline {
 		// Send the deadline in the header. We use the relative time instead
 		// of absolute in case there is significant clock skew. This does mean
 		// that we will not count transmission delay against the deadline.
-		micros := time.Until(deadline).Microseconds()
+		micros = time.Until(deadline).Microseconds()
 		if micros <= 0 {
 			// Fail immediately without attempting to send a zero or negative
 			// deadline to the server which will be misinterpreted.
 			<-ctx.Done()
 			return nil, ctx.Err()
 		}
-		binary.LittleEndian.PutUint64(hdr[16:], uint64(micros))
 	}
 
-	// Send trace information in the header.
-	writeTraceContext(ctx, hdr[24:])
-
-	rpc := &call{}
-	rpc.doneSignal = make(chan struct{})
-
 	// TODO: Arrange to obey deadline in any reconnection done inside startCall.
 	//
 	// TODO(mwhittaker): Right now, every RPC call is tried on a single server
@@ -385,42 +498,79 @@ func (rc *reconnectingConnection) Call(ctx context.Context, h MethodKey, arg []b
 		return nil, err
 	}
 
-	if err := writeMessage(conn.c, &conn.wlock, requestMessage, rpc.id, hdr[:], arg, rc.opts.WriteFlattenLimit); err != nil {
-		conn.shutdown("client send request", err)
+	// The format of the request depends on the version negotiated with the
+	// server, which is only known once the server responds to the version
+	// message sent when the connection was established.
+	v, err := conn.awaitVersion(ctx, rpc)
+	if err != nil {
 		conn.endCall(rpc)
-		return nil, fmt.Errorf("%w: %s", CommunicationError, err)
+		return nil, err
+	}
+	priorityLen, callerLen, metadataLen := 0, 0, 0
+	if v >= priorityVersion {
+		priorityLen = priorityHeaderLen
+	}
+	if v >= callerVersion {
+		callerLen = callerHeaderLen(opts.Caller)
+	}
+	if v >= metadataVersion {
+		metadataLen = metadataHeaderLen(md)
 	}
 
-	if rc.opts.OptimisticSpinDuration > 0 {
-		// Optimistically spin, waiting for the results.
-		for start := time.Now(); time.Since(start) < rc.opts.OptimisticSpinDuration; {
-			if atomic.LoadUint32(&rpc.done) > 0 {
-				return rpc.response, rpc.err
-			}
-		}
+	// The header is preceded by room for a compression header, in case the
+	// request is compressed below. The header is only used by the write of
+	// this request, so it can be pooled: every attempt at a call, like a
+	// hedged request, gets its own.
+	n := compressionHeaderSize + msgHeaderSize + priorityLen + callerLen + metadataLen
+	prefixed := bufpool.Get(n)[:n]
+	defer bufpool.Put(prefixed)
+	for i := range prefixed {
+		prefixed[i] = 0 // fields like the deadline are optional
 	}
+	hdr := prefixed[compressionHeaderSize:]
+	copy(hdr[0:], h[:])
+	binary.LittleEndian.PutUint64(hdr[16:], uint64(micros))
 
-	if cdone := ctx.Done(); cdone != nil {
-		select {
-		case <-rpc.doneSignal:
-			// Regular return
-		case <-cdone:
-			// Canceled or deadline expired.
-			conn.endCall(rpc)
+	// Send trace information in the header.
+	writeTraceContext(ctx, hdr[24:])
 
-			if !haveDeadline || time.Now().Before(deadline) {
-				// Early cancellation. Tell server about it.
-				if err := writeMessage(conn.c, &conn.wlock, cancelMessage, rpc.id, nil, nil, rc.opts.WriteFlattenLimit); err != nil {
-					conn.shutdown("client send cancel", err)
-				}
-			}
+	// Send the priority, caller name, and metadata in the header, if the
+	// server supports them.
+	b := hdr[msgHeaderSize:]
+	if v >= priorityVersion {
+		writePriority(ctx, b)
+		b = b[priorityLen:]
+	}
+	if v >= callerVersion {
+		writeCaller(opts.Caller, b)
+		b = b[callerLen:]
+	}
+	if v >= metadataVersion {
+		writeMetadata(md, b)
+	}
 
-			return nil, ctx.Err()
+	// Compress the argument, if the server supports it.
+	mt := requestMessage
+	if opts.CompressMinBytes > 0 && v >= compressionVersion {
+		mt = compressedRequestMessage
+		cmp := requestCompression{replyMinSize: opts.CompressMinBytes}
+		if buffersLen(arg) >= opts.CompressMinBytes {
+			cmp.compressed = true
+			arg = [][]byte{compress(flatten(arg))}
 		}
-	} else {
-		<-rpc.doneSignal
+		writeCompressionHeader(cmp, prefixed)
+		hdr = prefixed
+	}
+	if opts.WireSizes != nil {
+		opts.WireSizes.Request = buffersLen(arg)
+	}
+
+	if err := writeMessageBuffers(conn.c, &conn.wlock, mt, rpc.id, hdr, arg, rc.opts.WriteFlattenLimit); err != nil {
+		conn.shutdown("client send request", err)
+		conn.endCall(rpc)
+		return nil, fmt.Errorf("%w: %s", CommunicationError, err)
 	}
-	return rpc.response, rpc.err
+	return conn, nil
 }
 
 // watchResolver watches for updates to the set of endpoints. When a new set of
@@ -463,6 +613,45 @@ func (rc *reconnectingConnection) updateEndpoints(endpoints []Endpoint) error {
 		return fmt.Errorf("updateEndpoints on closed Connection")
 	}
 
+	// Forget the draining servers that are no longer resolved.
+	resolved := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		resolved[endpoint.Address()] = true
+	}
+	for addr := range rc.drained {
+		if !resolved[addr] {
+			delete(rc.drained, addr)
+		}
+	}
+	rc.resolved = endpoints
+	rc.setEndpoints()
+	return nil
+}
+
+// serverDraining records that the server at the provided address announced
+// that it is draining. Calls are no longer sent to it.
+// REQUIRES: rc.mu is not held.
+func (rc *reconnectingConnection) serverDraining(addr string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.closed || rc.drained[addr] {
+		return
+	}
+	rc.drained[addr] = true
+	rc.setEndpoints()
+}
+
+// setEndpoints sets the endpoints that calls are sent to to the resolved
+// endpoints of the servers that are not draining.
+// REQUIRES: rc.mu is held.
+func (rc *reconnectingConnection) setEndpoints() {
+	endpoints := make([]Endpoint, 0, len(rc.resolved))
+	for _, endpoint := range rc.resolved {
+		if !rc.drained[endpoint.Address()] {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
 	// Remove fully drained connections since they have been closed already and
 	// cannot be reused.
 	rc.removeDrainedConnections()
@@ -500,8 +689,6 @@ func (rc *reconnectingConnection) updateEndpoints(endpoints []Endpoint) error {
 	rc.removeDrainedConnections()
 
 	// TODO(mwhittaker): Close draining connections after a delay?
-
-	return nil
 }
 
 // removeDrainedConnections closes and removes any fully drained connections
@@ -546,7 +733,7 @@ func (rc *reconnectingConnection) startCall(ctx context.Context, rpc *call, opts
 	// operations.
 	var connectErr error
 	for i := 0; i < maxReconnectTries; i++ {
-		endpoint, err := balancer.Pick(opts)
+		endpoint, err := rc.pick(balancer, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -570,6 +757,26 @@ func (rc *reconnectingConnection) startCall(ctx context.Context, rpc *call, opts
 	return nil, connectErr
 }
 
+// pick picks an endpoint using the provided balancer. If the balancer is a
+// LoadAwareBalancer, it is passed the number of calls pending on every
+// endpoint.
+// REQUIRES: rc.mu is held.
+func (rc *reconnectingConnection) pick(balancer Balancer, opts CallOptions) (Endpoint, error) {
+	lb, ok := balancer.(LoadAwareBalancer)
+	if !ok {
+		return balancer.Pick(opts)
+	}
+	rc.pending = rc.pending[:0]
+	for _, endpoint := range rc.endpoints {
+		n := 0
+		if conn, ok := rc.connections[endpoint.Address()]; ok && !conn.ended {
+			n = len(conn.calls)
+		}
+		rc.pending = append(rc.pending, n)
+	}
+	return lb.PickWithLoad(opts, rc.pending)
+}
+
 // reconnect establishes (or re-establishes) the network connection to the server.
 // REQUIRES: rc.mu is held.
 func (rc *reconnectingConnection) reconnect(ctx context.Context, endpoint Endpoint) (*clientConnection, error) {
@@ -577,28 +784,91 @@ func (rc *reconnectingConnection) reconnect(ctx context.Context, endpoint Endpoi
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", CommunicationError, err)
 	}
+	if err := setKeepAlive(nc, rc.opts.KeepAlive); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("%w: set keep-alive: %s", CommunicationError, err)
+	}
 	conn := &clientConnection{
-		logger:   rc.opts.Logger,
-		endpoint: endpoint,
-		c:        nc,
-		cbuf:     bufio.NewReader(nc),
-		mu:       &rc.mu,
-		version:  initialVersion, // Updated when we hear from server
-		calls:    map[uint64]*call{},
-		lastID:   0,
+		logger:      rc.opts.Logger,
+		endpoint:    endpoint,
+		c:           nc,
+		cbuf:        bufio.NewReader(nc),
+		mu:          &rc.mu,
+		version:     initialVersion, // Updated when we hear from server
+		negotiated:  make(chan struct{}),
+		calls:       map[uint64]*call{},
+		lastID:      0,
+		idleTimeout: rc.opts.IdleTimeout,
+		onDrain:     func() { rc.serverDraining(endpoint.Address()) },
 	}
 	if err := writeVersion(conn.c, &conn.wlock); err != nil {
+		nc.Close()
 		return nil, fmt.Errorf("%w: client send version: %s", CommunicationError, err)
 	}
+	connectionsOpened.Add(1)
 	go conn.readResponses()
 	return conn, nil
 }
 
+// setKeepAlive configures TCP keep-alive probes on nc, as described by
+// ClientOptions.KeepAlive. Connections that are not TCP connections are left
+// untouched.
+func setKeepAlive(nc net.Conn, period time.Duration) error {
+	if period == 0 {
+		return nil
+	}
+	if tc, ok := nc.(*tls.Conn); ok {
+		nc = tc.NetConn()
+	}
+	tcp, ok := nc.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if period < 0 {
+		return tcp.SetKeepAlive(false)
+	}
+	if err := tcp.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tcp.SetKeepAlivePeriod(period)
+}
+
+// awaitVersion waits until the server's version message is received, and
+// returns the protocol version negotiated with the server. It returns an
+// error if ctx is done, or if the provided call, which must have been started
+// on c, ends first, e.g., because the connection broke.
+func (c *clientConnection) awaitVersion(ctx context.Context, rpc *call) (version, error) {
+	select {
+	case <-c.negotiated:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.version, nil
+	case <-rpc.doneSignal:
+		return 0, rpc.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
 func (c *clientConnection) endCall(rpc *call) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.calls, rpc.id)
 	c.endIfDrained()
+	c.startIdleTimer()
+}
+
+// cancelCall ends the provided call, which was issued with the provided
+// context, and tells the server about it if the call was canceled before its
+// deadline.
+func (c *clientConnection) cancelCall(ctx context.Context, rpc *call, flattenLimit int) {
+	c.endCall(rpc)
+	if deadline, haveDeadline := ctx.Deadline(); !haveDeadline || time.Now().Before(deadline) {
+		// Early cancellation. Tell server about it.
+		if err := writeMessage(c.c, &c.wlock, cancelMessage, rpc.id, nil, nil, flattenLimit); err != nil {
+			c.shutdown("client send cancel", err)
+		}
+	}
 }
 
 func (c *clientConnection) findAndEndCall(id uint64) *call {
@@ -608,6 +878,7 @@ func (c *clientConnection) findAndEndCall(id uint64) *call {
 	if rpc != nil {
 		delete(c.calls, id)
 		c.endIfDrained()
+		c.startIdleTimer()
 	}
 	return rpc
 }
@@ -623,10 +894,41 @@ func (c *clientConnection) endIfDrained() {
 	// reconnectingConnection may modify a child clientConnection, but a
 	// clientConnection never modifies its parent reconnectingConnection.
 	if c.draining && len(c.calls) == 0 {
-		c.endCalls(fmt.Errorf("connection drained"))
+		c.endCalls(fmt.Errorf("connection drained"), "drained")
 	}
 }
 
+// startIdleTimer arranges for c to be closed after idleTimeout if c has no
+// in-progress calls. A connection that is closed this way is re-dialed by the
+// next call to its endpoint.
+//
+// REQUIRES: c.mu is held.
+func (c *clientConnection) startIdleTimer() {
+	if c.idleTimeout <= 0 || c.ended || len(c.calls) > 0 {
+		return
+	}
+	c.idleSince = time.Now()
+	if c.idleTimer == nil {
+		c.idleTimer = time.AfterFunc(c.idleTimeout, c.endIfIdle)
+	} else {
+		c.idleTimer.Reset(c.idleTimeout)
+	}
+}
+
+// endIfIdle closes c if it has been idle for at least idleTimeout.
+//
+// REQUIRES: c.mu is not held.
+func (c *clientConnection) endIfIdle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The timer may fire concurrently with a call that ends and re-arms it,
+	// so we double check that c has actually been idle long enough.
+	if c.ended || len(c.calls) > 0 || time.Since(c.idleSince) < c.idleTimeout {
+		return
+	}
+	c.endCalls(fmt.Errorf("connection idle"), "idle")
+}
+
 // shutdown processes an error detected while operating on a connection.
 // It closes the network connection and cancels all requests in progress on the connection.
 // REQUIRES: c.mu is not held.
@@ -639,14 +941,22 @@ func (c *clientConnection) shutdown(details string, err error) {
 	}
 
 	// Cancel all in-progress calls.
-	c.endCalls(fmt.Errorf("%w: %s: %s", CommunicationError, details, err))
+	c.endCalls(fmt.Errorf("%w: %s: %s", CommunicationError, details, err), "error")
 }
 
-// endCalls closes the network connection and ends any in-progress calls.
+// endCalls closes the network connection and ends any in-progress calls. The
+// provided reason is recorded in the serviceweaver_client_connections_closed
+// metric.
 // REQUIRES: c.mu is held.
-func (c *clientConnection) endCalls(err error) {
+func (c *clientConnection) endCalls(err error, reason string) {
 	c.c.Close()
+	if !c.ended {
+		connectionsClosed.Get(closeLabels{Reason: reason}).Add(1)
+	}
 	c.ended = true
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
 	for id, active := range c.calls {
 		active.err = err
 		atomic.StoreUint32(&active.done, 1)
@@ -674,22 +984,65 @@ func (c *clientConnection) readResponses() {
 			c.mu.Lock()
 			c.version = v
 			c.mu.Unlock()
-		case responseMessage, responseError:
+			select {
+			case <-c.negotiated:
+				// The server sent more than one version message.
+			default:
+				close(c.negotiated)
+			}
+		case responseMessage, compressedResponseMessage, responseError:
 			rpc := c.findAndEndCall(id)
 			if rpc == nil {
 				continue // May have been canceled
 			}
-			if mt == responseError {
+			switch mt {
+			case responseError:
 				if err, ok := decodeError(msg); ok {
 					rpc.err = err
 				} else {
 					rpc.err = fmt.Errorf("%w: could not decode error", CommunicationError)
 				}
-			} else {
+			case compressedResponseMessage:
+				rpc.wireSize = len(msg)
+				if rpc.response, err = decompress(msg); err != nil {
+					rpc.err = fmt.Errorf("%w: %s", CommunicationError, err)
+				}
+			default:
+				rpc.wireSize = len(msg)
 				rpc.response = msg
 			}
 			atomic.StoreUint32(&rpc.done, 1)
 			close(rpc.doneSignal)
+		case streamMessage:
+			c.mu.Lock()
+			rpc := c.calls[id]
+			c.mu.Unlock()
+			if rpc == nil {
+				continue // May have been canceled
+			}
+			if rpc.chunks == nil {
+				c.shutdown("client read", fmt.Errorf("unexpected stream message for a non-streaming call"))
+				return
+			}
+			select {
+			case rpc.chunks <- msg:
+			default:
+				// The server ignored flow control.
+				c.shutdown("client read", fmt.Errorf("stream window of %d chunks exceeded", streamWindow))
+				return
+			}
+		case drainMessage:
+			c.onDrain()
+			if id == 0 {
+				continue // An announcement, not a response.
+			}
+			rpc := c.findAndEndCall(id)
+			if rpc == nil {
+				continue // May have been canceled
+			}
+			rpc.err = errDraining
+			atomic.StoreUint32(&rpc.done, 1)
+			close(rpc.doneSignal)
 		default:
 			c.shutdown("client read", fmt.Errorf("invalid response %d", mt))
 			return
@@ -725,24 +1078,40 @@ func (c *serverConnection) readRequests(ctx context.Context, hmap *HandlerMap, o
 				onDone()
 				return
 			}
-		case requestMessage:
-			if c.opts.InlineHandlerDuration > 0 {
+		case requestMessage, compressedRequestMessage:
+			if !c.opts.Drainer.startCall() {
+				c.rejectCall(id)
+				continue
+			}
+			received := time.Now()
+			var cmp requestCompression
+			if mt == compressedRequestMessage {
+				if cmp, msg, err = readCompressionHeader(msg); err != nil {
+					c.opts.Drainer.endCall()
+					c.shutdown("server read", err)
+					onDone()
+					return
+				}
+			}
+			if c.opts.InlineHandlerDuration > 0 && !hmap.isStream(msg) {
 				// Run the handler inline. If it doesn't return in the specified
 				// time period, launch another goroutine to read incoming requests.
 				t := time.AfterFunc(c.opts.InlineHandlerDuration, func() {
 					c.readRequests(ctx, hmap, onDone)
 				})
-				c.runHandler(hmap, id, msg)
+				c.runHandler(hmap, id, msg, received, cmp)
 				if !t.Stop() {
 					// Another goroutine is reading incoming requests: bail out.
 					return
 				}
 			} else {
 				// Run the handler in a separate goroutine.
-				go c.runHandler(hmap, id, msg)
+				go c.runHandler(hmap, id, msg, received, cmp)
 			}
 		case cancelMessage:
 			c.endRequest(id)
+		case streamAckMessage:
+			c.addCredit(id)
 		default:
 			c.shutdown("server read", fmt.Errorf("invalid request type %d", mt))
 			onDone()
@@ -753,9 +1122,26 @@ func (c *serverConnection) readRequests(ctx context.Context, hmap *HandlerMap, o
 	onDone()
 }
 
+// receivedKey is the context key under which the server stores the time at
+// which it received the request being handled.
+type receivedKey struct{}
+
+// Received returns the time at which the server read the call being handled
+// from the network, or the zero time if unknown. The difference between
+// Received and the time a Handler starts running is the time the call spent
+// queued on the server. ctx must be the context passed to a Handler.
+func Received(ctx context.Context) time.Time {
+	received, _ := ctx.Value(receivedKey{}).(time.Time)
+	return received
+}
+
 // runHandler runs an application specified RPC handler at the server side.
 // The result (or error) from the handler is sent back to the client over c.
-func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte) {
+// received is the time at which the request was read from the network, and
+// cmp describes the compression of the request and its reply.
+func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte, received time.Time, cmp requestCompression) {
+	defer c.opts.Drainer.endCall()
+
 	// Extract request header from front of payload.
 	if len(msg) < msgHeaderSize {
 		c.shutdown("server handler", fmt.Errorf("missing request header"))
@@ -776,18 +1162,20 @@ func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte) {
 
 	// Extract trace context and create a new child span to trace the method
 	// call on the server.
-	ctx := context.Background()
+	ctx := context.WithValue(context.Background(), receivedKey{}, received)
 	span := trace.SpanFromContext(ctx) // noop span
 	if sc := readTraceContext(msg[24:]); sc.IsValid() {
 		ctx, span = c.opts.Tracer.Start(trace.ContextWithSpanContext(ctx, sc), methodName, trace.WithSpanKind(trace.SpanKindServer))
 		defer span.End()
 	}
 
-	// Add deadline information from the header to the context.
+	// Add deadline information from the header to the context. The caller's
+	// remaining time is counted from when the request was received, so that
+	// the time the call waits on the server counts against it.
 	micros := binary.LittleEndian.Uint64(msg[16:])
 	var cancelFunc func()
 	if micros != 0 {
-		deadline := time.Now().Add(time.Microsecond * time.Duration(micros))
+		deadline := received.Add(time.Microsecond * time.Duration(micros))
 		ctx, cancelFunc = context.WithDeadline(ctx, deadline)
 	} else {
 		ctx, cancelFunc = context.WithCancel(ctx)
@@ -798,14 +1186,57 @@ func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte) {
 		}
 	}()
 
-	// Call the handler passing it the payload.
+	// Extract the priority, caller name, and metadata, if the client sends
+	// them. The priority is added to the context, so that it is propagated
+	// along with the calls made by the handler.
+	v := c.negotiated()
 	payload := msg[msgHeaderSize:]
+	if v >= priorityVersion {
+		if len(payload) < priorityHeaderLen {
+			c.shutdown("server handler", fmt.Errorf("missing priority header"))
+			return
+		}
+		if priority, ok := readPriority(payload); ok {
+			ctx = WithPriority(ctx, priority)
+		}
+		payload = payload[priorityHeaderLen:]
+	}
 	var err error
+	if v >= callerVersion {
+		var caller string
+		if caller, payload, err = readCaller(payload); err != nil {
+			c.shutdown("server handler", err)
+			return
+		}
+		if caller != "" {
+			ctx = context.WithValue(ctx, callerKey{}, caller)
+		}
+	}
+	if v >= metadataVersion {
+		var md map[string]string
+		if md, payload, err = readMetadata(payload); err != nil {
+			c.shutdown("server handler", err)
+			return
+		}
+		if md != nil {
+			ctx = WithMetadata(ctx, md)
+		}
+	}
+
+	// Decompress the arguments.
+	if cmp.compressed {
+		if payload, err = decompress(payload); err != nil {
+			c.shutdown("server handler", err)
+			return
+		}
+	}
+
+	// Call the handler passing it the payload, unless the caller's deadline
+	// expired while the call was waiting to run.
 	var result []byte
-	fn, ok := hmap.handlers[hkey]
-	if !ok {
-		err = fmt.Errorf("internal error: unknown function")
-	} else {
+	if ctx.Err() != nil {
+		err = fmt.Errorf("%s: deadline expired before the call started: %w", methodName, ctx.Err())
+	} else if fn, ok := hmap.handlers[hkey]; ok {
 		if err := c.startRequest(id, cancelFunc); err != nil {
 			logError(c.opts.Logger, "handle "+hmap.names[hkey], err)
 			return
@@ -813,6 +1244,35 @@ func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte) {
 		cancelFunc = nil // endRequest() or cancellation will deal with it
 		defer c.endRequest(id)
 		result, err = fn(ctx, payload)
+		if release := c.opts.ReleaseResult; release != nil && err == nil && result != nil {
+			// Deferred calls run last-in first-out, so the result is
+			// released after it is written below.
+			owned := result
+			defer release(owned)
+		}
+	} else if fn, ok := hmap.streams[hkey]; ok {
+		if err := c.startRequest(id, cancelFunc); err != nil {
+			logError(c.opts.Logger, "handle "+hmap.names[hkey], err)
+			return
+		}
+		cancelFunc = nil // endRequest() or cancellation will deal with it
+		defer c.endRequest(id)
+		credits := c.startStream(id)
+		send := func(chunk []byte) error {
+			select {
+			case <-credits:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if err := writeMessage(c.c, &c.wlock, streamMessage, id, nil, chunk, c.opts.WriteFlattenLimit); err != nil {
+				c.shutdown("server stream "+hmap.names[hkey], err)
+				return err
+			}
+			return nil
+		}
+		result, err = fn(ctx, payload, send)
+	} else {
+		err = fmt.Errorf("internal error: unknown function")
 	}
 
 	mt := responseMessage
@@ -821,6 +1281,9 @@ func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte) {
 		result = encodeError(err)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
+	} else if cmp.replyMinSize > 0 && len(result) >= cmp.replyMinSize {
+		mt = compressedResponseMessage
+		result = compress(result)
 	}
 
 	if err := writeMessage(c.c, &c.wlock, mt, id, nil, result, c.opts.WriteFlattenLimit); err != nil {
@@ -828,6 +1291,13 @@ func (c *serverConnection) runHandler(hmap *HandlerMap, id uint64, msg []byte) {
 	}
 }
 
+// negotiated returns the protocol version negotiated with the client.
+func (c *serverConnection) negotiated() version {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
 func (c *serverConnection) startRequest(id uint64, cancelFunc func()) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -838,9 +1308,38 @@ func (c *serverConnection) startRequest(id uint64, cancelFunc func()) error {
 	return nil
 }
 
+// startStream registers the flow control credits of a new streaming call and
+// returns them. The server may send a chunk of streamed data for every credit
+// it receives from the channel. Credits are replenished as the client
+// acknowledges chunks.
+func (c *serverConnection) startStream(id uint64) chan struct{} {
+	credits := make(chan struct{}, streamWindow)
+	for i := 0; i < streamWindow; i++ {
+		credits <- struct{}{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credits[id] = credits
+	return credits
+}
+
+// addCredit adds a flow control credit to the provided streaming call.
+func (c *serverConnection) addCredit(id uint64) {
+	c.mu.Lock()
+	credits := c.credits[id]
+	c.mu.Unlock()
+	select {
+	case credits <- struct{}{}:
+	default:
+		// Either the call has finished, in which case credits is nil, or the
+		// client acknowledged more chunks than were sent. Ignore the ack.
+	}
+}
+
 func (c *serverConnection) endRequest(id uint64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	delete(c.credits, id)
 	if cancelFunc, ok := c.cancelFuncs[id]; ok {
 		delete(c.cancelFuncs, id)
 		cancelFunc()
@@ -23,9 +23,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"net"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -321,6 +323,18 @@ func (p *pipeEndpoint) Address() string {
 	return fmt.Sprintf("pipe://%s", p.name)
 }
 
+// countingEndpoint is an endpoint that counts the number of times it is
+// dialed.
+type countingEndpoint struct {
+	call.Endpoint
+	dials atomic.Int32
+}
+
+func (c *countingEndpoint) Dial(ctx context.Context) (net.Conn, error) {
+	c.dials.Add(1)
+	return c.Endpoint.Dial(ctx)
+}
+
 // connEndpoint is an endpoint that always returns the provided net.Conn.
 type connEndpoint struct {
 	name string
@@ -598,6 +612,108 @@ func TestTracePropagation(t *testing.T) {
 	}
 }
 
+// TestCallerPropagation tests that the caller name is propagated across an
+// RPC.
+func TestCallerPropagation(t *testing.T) {
+	h := &call.HandlerMap{}
+	h.Set("", "who", func(ctx context.Context, _ []byte) ([]byte, error) {
+		return []byte(call.Caller(ctx)), nil
+	})
+	ep := pipeEndpoint{t: t, handlers: h}
+	opts := call.ClientOptions{Logger: logger(t)}
+	client, err := call.Connect(context.Background(), call.NewConstantResolver(&ep), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, caller := range []string{"", "caller", strings.Repeat("x", 1000)} {
+		got, err := client.Call(context.Background(), whoKey, nil /*args*/, call.CallOptions{Caller: caller})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != caller {
+			t.Errorf("Caller: got %q, want %q", got, caller)
+		}
+	}
+}
+
+// TestPriorityPropagation tests that call priorities are propagated across an
+// RPC.
+func TestPriorityPropagation(t *testing.T) {
+	h := &call.HandlerMap{}
+	h.Set("", "who", func(ctx context.Context, _ []byte) ([]byte, error) {
+		priority, ok := call.Priority(ctx)
+		if !ok {
+			return []byte("none"), nil
+		}
+		return []byte(strconv.Itoa(priority)), nil
+	})
+	ep := pipeEndpoint{t: t, handlers: h}
+	opts := call.ClientOptions{Logger: logger(t)}
+	client, err := call.Connect(context.Background(), call.NewConstantResolver(&ep), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		ctx  context.Context
+		want string
+	}{
+		{context.Background(), "none"},
+		{call.WithPriority(context.Background(), 0), "0"},
+		{call.WithPriority(context.Background(), 7), "7"},
+		{call.WithPriority(context.Background(), -3), "-3"},
+		{call.WithPriority(context.Background(), math.MaxInt), strconv.Itoa(math.MaxInt)},
+	} {
+		got, err := client.Call(test.ctx, whoKey, nil /*args*/, call.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("Priority: got %s, want %s", got, test.want)
+		}
+	}
+}
+
+// TestMetadataPropagation tests that metadata is propagated across an RPC.
+func TestMetadataPropagation(t *testing.T) {
+	h := &call.HandlerMap{}
+	h.Set("", "who", func(ctx context.Context, _ []byte) ([]byte, error) {
+		return json.Marshal(call.Metadata(ctx))
+	})
+	ep := pipeEndpoint{t: t, handlers: h}
+	opts := call.ClientOptions{Logger: logger(t)}
+	client, err := call.Connect(context.Background(), call.NewConstantResolver(&ep), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, md := range []map[string]string{
+		nil,
+		{"a": "1"},
+		{"a": "1", "b": "", "": "3"},
+	} {
+		ctx := call.WithMetadata(context.Background(), md)
+		result, err := client.Call(ctx, whoKey, nil /*args*/, call.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]string
+		if err := json.Unmarshal(result, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, md) {
+			t.Errorf("Metadata: got %v, want %v", got, md)
+		}
+	}
+
+	// Metadata that is too large should be rejected.
+	big := map[string]string{"big": strings.Repeat("x", call.MaxMetadataSize)}
+	ctx := call.WithMetadata(context.Background(), big)
+	if _, err := client.Call(ctx, whoKey, nil /*args*/, call.CallOptions{}); err == nil {
+		t.Error("unexpected success for oversized metadata")
+	}
+}
+
 // TestMultipleEndpoints tests that RPC calls succeed when the resolver returns
 // a constant set of multiple endpoints.
 func TestMultipleEndpoints(t *testing.T) {
@@ -750,6 +866,69 @@ func TestCallOptionsBalancer(t *testing.T) {
 	}
 }
 
+// loadRecordingBalancer is a LoadAwareBalancer that always picks the first
+// endpoint and records the pending counts it was last passed.
+type loadRecordingBalancer struct {
+	endpoints []call.Endpoint
+
+	mu      sync.Mutex
+	pending []int
+}
+
+func (b *loadRecordingBalancer) Update(endpoints []call.Endpoint) {
+	b.endpoints = endpoints
+}
+
+func (b *loadRecordingBalancer) Pick(call.CallOptions) (call.Endpoint, error) {
+	return nil, fmt.Errorf("Pick called on a LoadAwareBalancer")
+}
+
+func (b *loadRecordingBalancer) PickWithLoad(_ call.CallOptions, pending []int) (call.Endpoint, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending[:0], pending...)
+	return b.endpoints[0], nil
+}
+
+func (b *loadRecordingBalancer) lastPending() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int(nil), b.pending...)
+}
+
+// TestLoadAwareBalancer tests that a LoadAwareBalancer is passed the number of
+// calls pending on every endpoint.
+func TestLoadAwareBalancer(t *testing.T) {
+	ctx := context.Background()
+	b := &loadRecordingBalancer{}
+	resolver := call.NewConstantResolver(server(t, "1"), server(t, "2"))
+	opts := call.ClientOptions{Balancer: b, Logger: logger(t)}
+	client, err := call.Connect(ctx, resolver, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := client.Call(ctx, whoKey, []byte{}, call.CallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.lastPending(), []int{0, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending: got %v, want %v", got, want)
+	}
+
+	// Start a long-running call on the first endpoint. Subsequent calls should
+	// see it as pending.
+	sleepCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go client.Call(sleepCtx, sleepKey, []byte(testTimeout.String()), call.CallOptions{}) //nolint:errcheck // canceled below
+	waitUntil(t, func() bool {
+		if _, err := client.Call(ctx, whoKey, []byte{}, call.CallOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return reflect.DeepEqual(b.lastPending(), []int{1, 0})
+	})
+}
+
 // TestNoEndpointsConstant tests that it is an error to call Connect with a
 // constant resolver that returns no endpoints.
 func TestNoEndpointsConstant(t *testing.T) {
@@ -1247,6 +1426,54 @@ func TestReconnect(t *testing.T) {
 	}
 }
 
+// TestIdleTimeout tests that idle connections are closed and transparently
+// re-dialed, and that connections with in-progress calls are kept open.
+func TestIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const idle = 50 * time.Millisecond
+	endpoint := &countingEndpoint{Endpoint: server(t, "1")}
+	copts := call.ClientOptions{Logger: logger(t), IdleTimeout: idle}
+	client, err := call.Connect(ctx, call.NewConstantResolver(endpoint), copts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	echo := func() {
+		t.Helper()
+		if _, err := client.Call(ctx, echoKey, []byte("hello"), call.CallOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	checkDials := func(want int32) {
+		t.Helper()
+		if got := endpoint.dials.Load(); got != want {
+			t.Fatalf("dials: got %d, want %d", got, want)
+		}
+	}
+
+	// Back-to-back calls share a connection.
+	echo()
+	echo()
+	checkDials(1)
+
+	// A call that lasts longer than the idle timeout doesn't get its
+	// connection closed.
+	arg := []byte((4 * idle).String())
+	if _, err := client.Call(ctx, sleepKey, arg, call.CallOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkDials(1)
+
+	// After the connection has been idle for a while, the next call dials a
+	// new connection.
+	time.Sleep(4 * idle)
+	echo()
+	checkDials(2)
+}
+
 func TestPartialFailure(t *testing.T) {
 	for name, maker := range resolverMakers {
 		t.Run(name, func(t *testing.T) {
@@ -1386,6 +1613,7 @@ func BenchmarkCall(b *testing.B) {
 			ctx := context.Background()
 			for _, msgSize := range []int{1, 65536, 1048576} {
 				b.Run(fmt.Sprintf("%s/%s/Msg-%s", resolverName, protocol, sizeString(msgSize)), func(b *testing.B) {
+					b.ReportAllocs()
 					msg := make([]byte, msgSize)
 					for i := range msg {
 						msg[i] = 'x'
@@ -1618,3 +1846,278 @@ func sizeString(s int) string {
 func logger(t testing.TB) *slog.Logger {
 	return logging.NewTestSlogger(t, testing.Verbose())
 }
+
+// drainableEndpoint is a pipe-based endpoint, like pipeEndpoint, whose
+// servers are drained by a Drainer.
+type drainableEndpoint struct {
+	name     string
+	handlers *call.HandlerMap
+	drainer  *call.Drainer
+	t        testing.TB
+}
+
+func (d *drainableEndpoint) Dial(context.Context) (net.Conn, error) {
+	client, server := pipe(d.t)
+	opts := call.ServerOptions{Logger: logger(d.t), Drainer: d.drainer}
+	call.ServeOn(context.Background(), server, d.handlers, opts)
+	return client, nil
+}
+
+func (d *drainableEndpoint) Address() string {
+	return fmt.Sprintf("pipe://%s", d.name)
+}
+
+// TestServerDrain tests that a draining server finishes its running calls,
+// and that the calls sent to it afterwards are retried on other servers.
+func TestServerDrain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Construct two servers with a blocking handler.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blockKey := call.MakeMethodKey("", "block")
+	endpoint := func(name string) *drainableEndpoint {
+		h := handlersFor(name)
+		h.Set("", "block", func(context.Context, []byte) ([]byte, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		return &drainableEndpoint{name: name, handlers: h, drainer: &call.Drainer{}, t: t}
+	}
+	server0, server1 := endpoint("0"), endpoint("1")
+
+	opts := call.ClientOptions{Balancer: call.RoundRobin(), Logger: logger(t)}
+	client, err := call.Connect(ctx, call.NewConstantResolver(server0, server1), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Start a call on server 0 and drain it while the call is running.
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := client.Call(ctx, blockKey, nil, call.CallOptions{})
+		blocked <- err
+	}()
+	<-started
+	short, cancelShort := context.WithTimeout(ctx, shortDelay)
+	defer cancelShort()
+	if err := server0.drainer.Drain(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain with a running call: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !server0.drainer.Draining() {
+		t.Fatal("server 0 not draining")
+	}
+
+	// New calls are served by server 1.
+	for i := 0; i < 4; i++ {
+		result, err := client.Call(ctx, whoKey, nil, call.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(result), "1"; got != want {
+			t.Fatalf("call %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	// The running call finishes, and so does the drain.
+	drained := make(chan error, 1)
+	go func() { drained <- server0.drainer.Drain(ctx) }()
+	close(release)
+	if err := <-blocked; err != nil {
+		t.Fatalf("running call: %v", err)
+	}
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	// Once every server is draining, calls fail without running.
+	if err := server1.drainer.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Call(ctx, whoKey, nil, call.CallOptions{}); !errors.Is(err, call.Unreachable) {
+		t.Fatalf("call to drained servers: got %v, want %v", err, call.Unreachable)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	client, err := call.Connect(ctx, call.NewConstantResolver(server(t, "0")), call.ClientOptions{Logger: logger(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Make a first call, so that the protocol version is negotiated before
+	// the calls below.
+	if _, err := client.Call(ctx, echoKey, nil, call.CallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		size       int
+		compressed bool
+	}{
+		{"Small", 100, false},
+		{"Large", 64 << 10, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			arg := []byte(strings.Repeat("a", test.size))
+			var sizes call.WireSizes
+			opts := call.CallOptions{CompressMinBytes: 1024, WireSizes: &sizes}
+			result, err := client.Call(ctx, echoKey, arg, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(result, arg) {
+				t.Fatalf("echo: got %d bytes, want %d", len(result), len(arg))
+			}
+			if got := sizes.Request < test.size; got != test.compressed {
+				t.Errorf("request: %d bytes sent for a %d byte argument", sizes.Request, test.size)
+			}
+			if got := sizes.Reply < test.size; got != test.compressed {
+				t.Errorf("reply: %d bytes received for a %d byte result", sizes.Reply, test.size)
+			}
+		})
+	}
+}
+
+func TestCallBuffers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	client, err := call.Connect(ctx, call.NewConstantResolver(server(t, "0")), call.ClientOptions{Logger: logger(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	large := []byte(strings.Repeat("a", 1<<20))
+	for _, test := range []struct {
+		name string
+		args [][]byte
+		opts call.CallOptions
+	}{
+		{"Empty", nil, call.CallOptions{}},
+		{"Small", [][]byte{[]byte("a"), []byte("b")}, call.CallOptions{}},
+		{"Large", [][]byte{[]byte("hdr"), large, []byte("trailer")}, call.CallOptions{}},
+		{"Compressed", [][]byte{[]byte("hdr"), large}, call.CallOptions{CompressMinBytes: 1024}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := client.CallBuffers(ctx, echoKey, test.args, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := bytes.Join(test.args, nil); !bytes.Equal(result, want) {
+				t.Fatalf("echo: got %d bytes, want %d", len(result), len(want))
+			}
+		})
+	}
+}
+
+func BenchmarkCallBuffers(b *testing.B) {
+	ctx := context.Background()
+	opts := call.ServerOptions{Logger: logger(b)}
+	endpoints := startServers(ctx, opts)
+	client := getClientConn(b, "tcp", endpoints["tcp"], resolverMakers["Constant"])
+
+	// A small header followed by a 4MB payload, as produced by an Encoder
+	// for a method with a large []byte argument. Flat copies the arguments
+	// into a single buffer before the call, like Call does.
+	hdr := []byte("header")
+	payload := make([]byte, 4<<20)
+	for _, flat := range []bool{true, false} {
+		name := "Buffers"
+		if flat {
+			name = "Flat"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(payload)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var err error
+				if flat {
+					_, err = client.Call(ctx, echoKey, bytes.Join([][]byte{hdr, payload}, nil), call.CallOptions{})
+				} else {
+					_, err = client.CallBuffers(ctx, echoKey, [][]byte{hdr, payload}, call.CallOptions{})
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// releasingEndpoint is a pipe-based endpoint, like pipeEndpoint, whose
+// servers pass the results of their handlers to release.
+type releasingEndpoint struct {
+	handlers *call.HandlerMap
+	release  func([]byte)
+	t        testing.TB
+}
+
+func (r *releasingEndpoint) Dial(context.Context) (net.Conn, error) {
+	client, server := pipe(r.t)
+	opts := call.ServerOptions{Logger: logger(r.t), ReleaseResult: r.release}
+	call.ServeOn(context.Background(), server, r.handlers, opts)
+	return client, nil
+}
+
+func (r *releasingEndpoint) Address() string {
+	return "pipe://releasing"
+}
+
+func TestReleaseResult(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	released := make(chan []byte, 10)
+	endpoint := &releasingEndpoint{
+		handlers: makeHandlerMap(),
+		release:  func(result []byte) { released <- result },
+		t:        t,
+	}
+	var result []byte
+	endpoint.handlers.Set("", "result", func(context.Context, []byte) ([]byte, error) {
+		result = []byte("result")
+		return result, nil
+	})
+	client, err := call.Connect(ctx, call.NewConstantResolver(endpoint), call.ClientOptions{Logger: logger(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// The result of a successful call is released once it is sent.
+	reply, err := client.Call(ctx, call.MakeMethodKey("", "result"), nil, call.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(reply), "result"; got != want {
+		t.Fatalf("reply: got %q, want %q", got, want)
+	}
+	select {
+	case r := <-released:
+		if &r[0] != &result[0] {
+			t.Errorf("released %q, want the handler's result", r)
+		}
+	case <-ctx.Done():
+		t.Fatal("result not released")
+	}
+
+	// Failed calls don't release anything.
+	if _, err := client.Call(ctx, errorKey, []byte("x"), call.CallOptions{}); err == nil {
+		t.Fatal("unexpected success")
+	}
+	select {
+	case r := <-released:
+		t.Errorf("released %q after a failed call", r)
+	default:
+	}
+}
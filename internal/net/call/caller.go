@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// callerKey is the context key under which the server stores the name of the
+// caller that issued the request being handled.
+type callerKey struct{}
+
+// Caller returns the caller name that the client attached to the call being
+// handled (see CallOptions.Caller), or the empty string if there is none. ctx
+// must be the context passed to a Handler.
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// callerHeaderLen returns the number of bytes needed to serialize caller.
+func callerHeaderLen(caller string) int {
+	return 4 + len(caller)
+}
+
+// writeCaller serializes caller into b.
+// REQUIRES: len(b) >= callerHeaderLen(caller)
+func writeCaller(caller string, b []byte) {
+	binary.LittleEndian.PutUint32(b, uint32(len(caller)))
+	copy(b[4:], caller)
+}
+
+// readCaller deserializes a caller name from the front of b, returning the
+// caller name and the remainder of b.
+func readCaller(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, fmt.Errorf("missing caller header")
+	}
+	n := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+	if uint64(len(b)) < uint64(n) {
+		return "", nil, fmt.Errorf("truncated caller header: want %d bytes, got %d", n, len(b))
+	}
+	return string(b[:n]), b[n:], nil
+}
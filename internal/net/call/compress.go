@@ -0,0 +1,111 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// compressionHeaderSize is the size of the prefix of a
+// compressedRequestMessage's payload. See msg.go.
+const compressionHeaderSize = 4 + 1
+
+// maxDecompressedSize is the maximum size of a decompressed payload. It
+// matches the maximum size of a message read by readMessage.
+const maxDecompressedSize = 100 << 20
+
+// WireSizes holds the sizes of a call's argument and result as sent over the
+// network, i.e., after compression, if any. See CallOptions.WireSizes.
+type WireSizes struct {
+	Request int // size of the argument serialization
+	Reply   int // size of the result serialization
+}
+
+// requestCompression describes the compression of a request and its reply.
+// The zero value describes an uncompressed request whose reply is not
+// compressed.
+type requestCompression struct {
+	compressed   bool // is the argument serialization compressed?
+	replyMinSize int  // if positive, compress replies of at least this size
+}
+
+// gzipWriters holds *gzip.Writers that can be reused.
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, err := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		if err != nil {
+			// BestSpeed is a valid level.
+			panic(err)
+		}
+		return w
+	},
+}
+
+// compress returns the gzip compression of data.
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+	// Writes to a bytes.Buffer don't fail.
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// decompress returns the decompression of data, which was compressed by
+// compress.
+func decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	if len(out) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompress: payload larger than %d bytes", maxDecompressedSize)
+	}
+	return out, nil
+}
+
+// writeCompressionHeader writes the prefix of a compressedRequestMessage's
+// payload into the first compressionHeaderSize bytes of dst.
+func writeCompressionHeader(c requestCompression, dst []byte) {
+	binary.LittleEndian.PutUint32(dst, uint32(c.replyMinSize))
+	dst[4] = 0
+	if c.compressed {
+		dst[4] = 1
+	}
+}
+
+// readCompressionHeader reads the prefix of a compressedRequestMessage's
+// payload, returning the rest of the payload.
+func readCompressionHeader(msg []byte) (requestCompression, []byte, error) {
+	if len(msg) < compressionHeaderSize {
+		return requestCompression{}, nil, fmt.Errorf("missing compression header")
+	}
+	c := requestCompression{
+		replyMinSize: int(binary.LittleEndian.Uint32(msg)),
+		compressed:   msg[4] == 1,
+	}
+	return c, msg[compressionHeaderSize:], nil
+}
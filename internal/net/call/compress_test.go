@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("x"),
+		[]byte(strings.Repeat("hello ", 10000)),
+	} {
+		got, err := decompress(compress(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("round trip of %d bytes: got %d bytes", len(data), len(got))
+		}
+	}
+	if _, err := decompress([]byte("not gzip")); err == nil {
+		t.Error("decompress of invalid data: unexpected success")
+	}
+}
+
+func TestCompressionHeader(t *testing.T) {
+	want := requestCompression{compressed: true, replyMinSize: 4096}
+	msg := make([]byte, compressionHeaderSize+3)
+	writeCompressionHeader(want, msg)
+	copy(msg[compressionHeaderSize:], "abc")
+	got, rest, err := readCompressionHeader(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want || string(rest) != "abc" {
+		t.Fatalf("got %+v, %q, want %+v, %q", got, rest, want, "abc")
+	}
+	if _, _, err := readCompressionHeader(msg[:2]); err == nil {
+		t.Error("short header: unexpected success")
+	}
+}
+
+func TestCompressionFallback(t *testing.T) {
+	// A peer that doesn't support compression negotiates an older version,
+	// which makes the client send uncompressed requests.
+	var msg [4]byte
+	binary.LittleEndian.PutUint32(msg[:], uint32(initialVersion))
+	v, err := getVersion(0, msg[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v >= compressionVersion {
+		t.Fatalf("version negotiated with an initialVersion peer: got %d, want < %d", v, compressionVersion)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// # Draining
+//
+// A server that is about to shut down can be drained with a Drainer. When
+// drained, the server sends a drainMessage with id zero on every connection,
+// and it responds to every request it receives afterwards with a drainMessage
+// carrying the id of the request, without running the request's handler.
+//
+// A client stops sending calls to the endpoint of a server that announced it
+// is draining, until the resolver no longer returns the endpoint. A call
+// rejected by a draining server is known not to have run, so the client
+// transparently retries it on another endpoint.
+
+// errDraining is the error of a call rejected by a draining server.
+var errDraining = fmt.Errorf("%w: server is draining", Unreachable)
+
+// A Drainer drains the servers it is passed to, via ServerOptions.Drainer.
+// The zero value is ready to use. A nil *Drainer never drains.
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	inflight int                            // number of running calls
+	idle     chan struct{}                  // closed when draining and idle
+	conns    map[*serverConnection]struct{} // live server connections
+}
+
+// Draining returns whether Drain has been called.
+func (d *Drainer) Draining() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Drain makes the servers reject new calls without running them, and tells
+// their clients to send calls to other servers. It then waits for the calls
+// that are already running to finish, and returns nil, or for ctx to be done,
+// and returns ctx.Err(). Drain doesn't close any connection.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		d.idle = make(chan struct{})
+		if d.inflight == 0 {
+			close(d.idle)
+		}
+	}
+	idle := d.idle
+	conns := make([]*serverConnection, 0, len(d.conns))
+	for c := range d.conns {
+		conns = append(conns, c)
+	}
+	d.mu.Unlock()
+
+	for _, c := range conns {
+		c.announceDrain()
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// add registers a server connection with the drainer. It returns whether the
+// drainer is already draining, in which case the connection's client must be
+// told so.
+func (d *Drainer) add(c *serverConnection) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conns == nil {
+		d.conns = map[*serverConnection]struct{}{}
+	}
+	d.conns[c] = struct{}{}
+	return d.draining
+}
+
+// remove unregisters a server connection from the drainer.
+func (d *Drainer) remove(c *serverConnection) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.conns, c)
+}
+
+// startCall records the start of a call, unless the drainer is draining, in
+// which case it returns false and the call must be rejected.
+func (d *Drainer) startCall() bool {
+	if d == nil {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inflight++
+	return true
+}
+
+// endCall records the end of a call started with startCall.
+func (d *Drainer) endCall() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inflight--
+	if d.draining && d.inflight == 0 {
+		close(d.idle)
+	}
+}
+
+// announceDrain tells the client of the connection that the server is
+// draining.
+func (c *serverConnection) announceDrain() {
+	if err := writeMessage(c.c, &c.wlock, drainMessage, 0, nil, nil, c.opts.WriteFlattenLimit); err != nil {
+		c.shutdown("server send drain", err)
+	}
+}
+
+// rejectCall responds to the request with the provided id without running it,
+// because the server is draining.
+func (c *serverConnection) rejectCall(id uint64) {
+	if err := writeMessage(c.c, &c.wlock, drainMessage, id, nil, nil, c.opts.WriteFlattenLimit); err != nil {
+		c.shutdown("server send drain", err)
+	}
+}
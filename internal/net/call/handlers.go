@@ -37,10 +37,19 @@ func MakeMethodKey(component, method string) MethodKey {
 // successfully.
 type Handler func(ctx context.Context, args []byte) ([]byte, error)
 
+// StreamHandler is a Handler for streaming calls. Before returning, a
+// StreamHandler may stream chunks of data to the client by calling send.
+// Chunks are delivered in order, before the returned bytes. send blocks while
+// the client lags too far behind, and it returns an error if the call has been
+// canceled or the connection has been broken. send does not retain the chunk
+// after it returns.
+type StreamHandler func(ctx context.Context, args []byte, send func([]byte) error) ([]byte, error)
+
 // HandlerMap is a mapping from MethodID to a Handler. The zero value for a
 // HandlerMap is an empty map.
 type HandlerMap struct {
 	handlers map[MethodKey]Handler
+	streams  map[MethodKey]StreamHandler
 	names    map[MethodKey]string
 }
 
@@ -48,9 +57,25 @@ type HandlerMap struct {
 func (hm *HandlerMap) Set(component, method string, handler Handler) {
 	if hm.handlers == nil {
 		hm.handlers = map[MethodKey]Handler{}
+	}
+	if hm.names == nil {
 		hm.names = map[MethodKey]string{}
 	}
 	fp := MakeMethodKey(component, method)
 	hm.handlers[fp] = handler
 	hm.names[fp] = component + "." + method
 }
+
+// SetStream registers a streaming handler for the specified method of
+// component.
+func (hm *HandlerMap) SetStream(component, method string, handler StreamHandler) {
+	if hm.streams == nil {
+		hm.streams = map[MethodKey]StreamHandler{}
+	}
+	if hm.names == nil {
+		hm.names = map[MethodKey]string{}
+	}
+	fp := MakeMethodKey(component, method)
+	hm.streams[fp] = handler
+	hm.names[fp] = component + "." + method
+}
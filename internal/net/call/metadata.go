@@ -0,0 +1,122 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// MaxMetadataSize is the maximum number of bytes, summed over all keys and
+// values, of the metadata that can be sent along with a call.
+const MaxMetadataSize = 8 << 10
+
+// metadataKey is the context key for call metadata.
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx that carries the provided metadata. The
+// metadata is sent along with every call made using the returned context and
+// is made available to the handler via Metadata, unless the server is too old
+// to receive metadata. md must not be modified after it is passed to
+// WithMetadata.
+func WithMetadata(ctx context.Context, md map[string]string) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// Metadata returns the metadata stored in ctx, or nil if there is none. The
+// returned map must not be modified.
+func Metadata(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return md
+}
+
+// metadataSize returns the number of bytes of keys and values in md.
+func metadataSize(md map[string]string) int {
+	n := 0
+	for k, v := range md {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+// metadataHeaderLen returns the number of bytes needed to serialize md.
+func metadataHeaderLen(md map[string]string) int {
+	return 4 + 8*len(md) + metadataSize(md)
+}
+
+// writeMetadata serializes md into b.
+// REQUIRES: len(b) >= metadataHeaderLen(md)
+func writeMetadata(md map[string]string, b []byte) {
+	binary.LittleEndian.PutUint32(b, uint32(len(md)))
+	b = b[4:]
+	put := func(s string) {
+		binary.LittleEndian.PutUint32(b, uint32(len(s)))
+		copy(b[4:], s)
+		b = b[4+len(s):]
+	}
+	for k, v := range md {
+		put(k)
+		put(v)
+	}
+}
+
+// readMetadata deserializes metadata from the front of b, returning the
+// metadata (nil if empty) and the remainder of b.
+func readMetadata(b []byte) (map[string]string, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, fmt.Errorf("missing metadata header")
+	}
+	n := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+	if n == 0 {
+		return nil, b, nil
+	}
+	if uint64(n)*8 > uint64(len(b)) {
+		return nil, nil, fmt.Errorf("truncated metadata header")
+	}
+
+	size := 0
+	get := func() (string, error) {
+		if len(b) < 4 {
+			return "", fmt.Errorf("truncated metadata header")
+		}
+		l := binary.LittleEndian.Uint32(b)
+		b = b[4:]
+		if uint64(len(b)) < uint64(l) {
+			return "", fmt.Errorf("truncated metadata header")
+		}
+		size += int(l)
+		if size > MaxMetadataSize {
+			return "", fmt.Errorf("metadata exceeds %d bytes", MaxMetadataSize)
+		}
+		s := string(b[:l])
+		b = b[l:]
+		return s, nil
+	}
+	md := make(map[string]string, n)
+	for i := uint32(0); i < n; i++ {
+		k, err := get()
+		if err != nil {
+			return nil, nil, err
+		}
+		v, err := get()
+		if err != nil {
+			return nil, nil, err
+		}
+		md[k] = v
+	}
+	return md, b, nil
+}
@@ -20,6 +20,8 @@ import (
 	"io"
 	"net"
 	"sync"
+
+	"github.com/ServiceWeaver/weaver/internal/bufpool"
 )
 
 // messageType identifies a type of message sent across the wire.
@@ -31,6 +33,11 @@ const (
 	responseMessage
 	responseError
 	cancelMessage
+	streamMessage
+	streamAckMessage
+	drainMessage
+	compressedRequestMessage
+	compressedResponseMessage
 	// Other types to add?
 	// - chunked request/response messages?
 	// - health check
@@ -42,9 +49,27 @@ type version uint32
 
 const (
 	initialVersion version = iota
+
+	// compressionVersion adds compressedRequestMessage and
+	// compressedResponseMessage. Peers that negotiate an older version send
+	// uncompressed messages.
+	compressionVersion
+
+	// callerVersion adds the caller to the header of requests. Peers that
+	// negotiate an older version send requests without a caller.
+	callerVersion
+
+	// metadataVersion adds metadata to the header of requests. Peers that
+	// negotiate an older version send requests without metadata.
+	metadataVersion
+
+	// priorityVersion adds the call priority to the header of requests.
+	// Peers that negotiate an older version send requests without a
+	// priority.
+	priorityVersion
 )
 
-const currentVersion = initialVersion
+const currentVersion = priorityVersion
 
 // # Message formats
 //
@@ -58,11 +83,25 @@ const currentVersion = initialVersion
 //
 // versionMessage: this is the first message sent on a connection by both sides.
 //    version  [4]byte
+//    The client doesn't send requests until it receives the server's version
+//    message, since the format of a request depends on the version that the
+//    client and server negotiate.
 //
 // requestMessage:
 //    headerKey    [16]byte   -- fingerprint of method name
 //    deadline      [8]byte   -- zero, or deadline in microseconds
 //    traceContext [25]byte   -- zero, or trace context
+//    hasPriority   [1]byte   -- 1 if the call has a priority; only if
+//                               version >= priorityVersion
+//    priority      [8]byte   -- zero, or priority; only if version >=
+//                               priorityVersion
+//    callerLen     [4]byte   -- length of caller; only if version >= callerVersion
+//    caller  [callerLen]byte -- name of the caller, possibly empty
+//    metadataLen   [4]byte   -- number of metadata entries; only if version >=
+//                               metadataVersion
+//    metadata                -- metadataLen (key, value) pairs, where keys and
+//                               values are encoded as a [4]byte length
+//                               followed by the bytes of the string
 //    remainder               -- call argument serialization
 //
 // responseMessage:
@@ -73,6 +112,28 @@ const currentVersion = initialVersion
 //
 // cancelMessage:
 //    payload is empty
+//
+// streamMessage: sent by the server before the response of a streaming call.
+//    payload holds a chunk of streamed data
+//
+// streamAckMessage: sent by the client for every streamMessage it consumes.
+//    payload is empty
+//
+// drainMessage: sent by a draining server. If id is zero, it announces that
+//    the server is draining. Otherwise, it is the response to the request
+//    with the given id, which the server rejected without running it.
+//    payload is empty
+//
+// compressedRequestMessage: a requestMessage whose argument serialization may
+//    be gzip-compressed. Only sent on connections with version >=
+//    compressionVersion.
+//    replyMinSize  [4]byte   -- replies of at least this many bytes are
+//                               compressed; zero if replies are not
+//    compressed    [1]byte   -- 1 if the argument serialization is compressed
+//    request                 -- as in requestMessage
+//
+// compressedResponseMessage: a responseMessage whose payload holds the
+//    gzip-compressed call result serialization.
 
 // writeMessage formats and sends a message over w.
 //
@@ -90,28 +151,56 @@ func writeMessage(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, ext
 	return writeFlat(w, wlock, mt, id, extraHdr, payload)
 }
 
+// writeMessageBuffers is like writeMessage, but the payload is formed by
+// concatenating extraHdr and all of the buffers in payload. Large buffers are
+// handed to w as they are, without being copied into a single buffer first.
+func writeMessageBuffers(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, extraHdr []byte, payload [][]byte, flattenLimit int) error {
+	if 16+len(extraHdr)+buffersLen(payload) > flattenLimit {
+		return writeChunked(w, wlock, mt, id, extraHdr, payload...)
+	}
+	return writeFlat(w, wlock, mt, id, extraHdr, payload...)
+}
+
+// vectorScratch holds the scratch space of a vectored write. See writeChunked.
+type vectorScratch struct {
+	hdr  [16]byte
+	vec  [][]byte
+	bufs net.Buffers // consumes vec when written
+}
+
+var vectorScratches = sync.Pool{
+	New: func() any { return &vectorScratch{vec: make([][]byte, 0, 4)} },
+}
+
 // writeChunked writes the header, extra header, and the payload into w using
-// three different w.Write() calls.
-func writeChunked(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, extraHdr []byte, payload []byte) error {
-	// We use an iovec with up to three entries.
-	var vec [3][]byte
+// a single vectored write, i.e., without concatenating them first.
+func writeChunked(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, extraHdr []byte, payload ...[]byte) error {
+	scratch := vectorScratches.Get().(*vectorScratch)
+	defer func() {
+		// Don't retain references to the payload.
+		for i := range scratch.vec {
+			scratch.vec[i] = nil
+		}
+		scratch.vec = scratch.vec[:0]
+		scratch.bufs = nil
+		vectorScratches.Put(scratch)
+	}()
 
-	nh, np := len(extraHdr), len(payload)
-	var hdr [16]byte
+	nh, np := len(extraHdr), buffersLen(payload)
+	hdr := scratch.hdr[:]
 	binary.LittleEndian.PutUint64(hdr[0:], id)
 	binary.LittleEndian.PutUint64(hdr[8:], uint64(mt)|(uint64(nh+np)<<8))
 
-	vec[0] = hdr[:]
-	vec[1] = extraHdr
-	vec[2] = payload
-	buf := net.Buffers(vec[:])
+	scratch.vec = append(scratch.vec[:0], hdr, extraHdr)
+	scratch.vec = append(scratch.vec, payload...)
+	scratch.bufs = scratch.vec
 
 	// buf.WriteTo is not guaranteed to write the entire contents of buf
 	// atomically, so we guard the write with a lock to prevent writes from
 	// interleaving.
 	wlock.Lock()
 	defer wlock.Unlock()
-	n, err := buf.WriteTo(w)
+	n, err := scratch.bufs.WriteTo(w)
 	if err == nil && n != 16+int64(nh)+int64(np) {
 		err = fmt.Errorf("partial write")
 	}
@@ -120,14 +209,18 @@ func writeChunked(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, ext
 
 // writeFlat concatenates the header, extra header, and the payload into
 // a single flat byte slice, and writes it into w using a single w.Write() call.
-func writeFlat(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, extraHdr []byte, payload []byte) error {
-	nh, np := len(extraHdr), len(payload)
-	data := make([]byte, 16+nh+np)
+func writeFlat(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, extraHdr []byte, payload ...[]byte) error {
+	nh, np := len(extraHdr), buffersLen(payload)
+	data := bufpool.Get(16 + nh + np)[:16+nh+np]
+	defer bufpool.Put(data)
 	binary.LittleEndian.PutUint64(data[0:], id)
 	val := uint64(mt) | (uint64(nh+np) << 8)
 	binary.LittleEndian.PutUint64(data[8:], val)
 	copy(data[16:], extraHdr)
-	copy(data[16+nh:], payload)
+	off := 16 + nh
+	for _, p := range payload {
+		off += copy(data[off:], p)
+	}
 
 	// Write while holding the lock, since we don't know if the underlying
 	// io.Write is atomic.
@@ -142,12 +235,34 @@ func writeFlat(w io.Writer, wlock *sync.Mutex, mt messageType, id uint64, extraH
 	return err
 }
 
+// buffersLen returns the total length of the provided buffers.
+func buffersLen(bufs [][]byte) int {
+	n := 0
+	for _, b := range bufs {
+		n += len(b)
+	}
+	return n
+}
+
+// flatten returns the concatenation of the provided buffers.
+func flatten(bufs [][]byte) []byte {
+	if len(bufs) == 1 {
+		return bufs[0]
+	}
+	flat := make([]byte, 0, buffersLen(bufs))
+	for _, b := range bufs {
+		flat = append(flat, b...)
+	}
+	return flat
+}
+
 // readMessage reads, parses, and returns the next message from r.
 func readMessage(r io.Reader) (messageType, uint64, []byte, error) {
 	// Read the header.
 	const headerSize = 16
-	var hdr [headerSize]byte
-	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+	hdr := bufpool.Get(headerSize)[:headerSize]
+	defer bufpool.Put(hdr)
+	if _, err := io.ReadFull(r, hdr); err != nil {
 		return 0, 0, nil, err
 	}
 
@@ -161,7 +276,8 @@ func readMessage(r io.Reader) (messageType, uint64, []byte, error) {
 		return 0, 0, nil, fmt.Errorf("overly large message length %d", dataLen)
 	}
 
-	// Read the payload.
+	// Read the payload. The payload isn't pooled, since the values decoded
+	// from it may share its memory.
 	msg := make([]byte, int(dataLen))
 	if _, err := io.ReadFull(r, msg); err != nil {
 		return 0, 0, nil, err
@@ -108,6 +108,7 @@ func BenchmarkReadWrite(b *testing.B) {
 				flatten := flatten
 				name := fmt.Sprintf("%s/%s/%s", network, sizeString(size), flatten)
 				b.Run(name, func(b *testing.B) {
+					b.ReportAllocs()
 					numIters := b.N
 					payload := make([]byte, size)
 					var mu sync.Mutex
@@ -38,6 +38,16 @@ type ClientOptions struct {
 	// If non-zero, all writes smaller than this limit are flattened into
 	// a single buffer before being written on the connection.
 	WriteFlattenLimit int
+
+	// If positive, the period between TCP keep-alive probes sent on dialed
+	// connections. If negative, keep-alive probes are disabled. If zero, the
+	// operating system defaults are used.
+	KeepAlive time.Duration
+
+	// If positive, a connection that has had no in-progress calls for the
+	// provided duration is closed. The next call to its endpoint
+	// transparently dials a new connection.
+	IdleTimeout time.Duration
 }
 
 // ServerOption are the options to configure an RPC server.
@@ -55,6 +65,16 @@ type ServerOptions struct {
 	// If non-zero, all writes smaller than this limit are flattened into
 	// a single buffer before being written on the connection.
 	WriteFlattenLimit int
+
+	// If not nil, the drainer that drains the server. See Drainer.
+	Drainer *Drainer
+
+	// If not nil, ReleaseResult is called with the result of every
+	// successful call to a Handler once the result has been written, after
+	// which the server doesn't use the result anymore. It lets handlers
+	// reuse the memory of their results. Handlers must not retain their
+	// results if ReleaseResult is set.
+	ReleaseResult func([]byte)
 }
 
 // CallOptions are call-specific options.
@@ -70,6 +90,25 @@ type CallOptions struct {
 	// Balancer that the client was constructed with (provided in
 	// ClientOptions).
 	Balancer Balancer
+
+	// Caller, if not empty, is the name of the caller issuing the call. It is
+	// sent to the server, where handlers can retrieve it using Caller.
+	Caller string
+
+	// Method, if not empty, is the name of the method being called. It is not
+	// sent to the server, but a Balancer can use it to pick an endpoint.
+	Method string
+
+	// CompressMinBytes, if positive, enables the gzip compression of the
+	// call's argument and result, if they are at least this many bytes long.
+	// Compression is only used if the server supports it. Streamed chunks are
+	// never compressed.
+	CompressMinBytes int
+
+	// WireSizes, if not nil, is filled with the sizes of the call's argument
+	// and result as sent over the network. The reply size is only set if the
+	// call succeeds.
+	WireSizes *WireSizes
 }
 
 // withDefaults returns a copy of the ClientOptions with zero values replaced
@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"context"
+	"encoding/binary"
+)
+
+// priorityHeaderLen is the number of bytes needed to serialize a call
+// priority: a byte that is 1 if the call has a priority, and the priority.
+const priorityHeaderLen = 9
+
+// priorityKey is the context key for call priorities.
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that carries the provided call priority.
+// The priority is sent along with every call made using the returned context
+// and is made available to the handler via Priority, unless the server is too
+// old to receive priorities.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// Priority returns the call priority stored in ctx and whether ctx carries a
+// priority at all.
+func Priority(ctx context.Context) (int, bool) {
+	priority, ok := ctx.Value(priorityKey{}).(int)
+	return priority, ok
+}
+
+// writePriority serializes the call priority (if any) contained in ctx into b.
+// REQUIRES: len(b) >= priorityHeaderLen
+func writePriority(ctx context.Context, b []byte) {
+	priority, ok := Priority(ctx)
+	if !ok {
+		return
+	}
+	b[0] = 1
+	binary.LittleEndian.PutUint64(b[1:], uint64(int64(priority)))
+}
+
+// readPriority returns the call priority stored in b, and whether b stores a
+// priority at all.
+// REQUIRES: len(b) >= priorityHeaderLen
+func readPriority(b []byte) (int, bool) {
+	if b[0] == 0 {
+		return 0, false
+	}
+	return int(int64(binary.LittleEndian.Uint64(b[1:]))), true
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"context"
+)
+
+// # Streaming calls
+//
+// A streaming call is a call whose handler (a StreamHandler) streams chunks of
+// data to the client before returning. Every chunk is sent to the client in a
+// streamMessage, and the call ends with a regular response message.
+//
+// Streams are flow controlled. A server may have at most streamWindow chunks
+// of a call in flight, i.e., sent but not yet consumed by the client. The
+// client sends a streamAckMessage whenever it consumes a chunk, which allows
+// the server to send another one. A handler that streams faster than the
+// client consumes blocks in send, and the client never buffers more than
+// streamWindow chunks of a call.
+
+// streamWindow is the maximum number of chunks of a streaming call that may be
+// in flight.
+const streamWindow = 16
+
+// isStream returns whether the provided request message is for a streaming
+// handler.
+func (hm *HandlerMap) isStream(msg []byte) bool {
+	if len(msg) < len(MethodKey{}) {
+		return false
+	}
+	var hkey MethodKey
+	copy(hkey[:], msg)
+	_, ok := hm.streams[hkey]
+	return ok
+}
+
+// ClientStream is the client side of a streaming call. It is not safe for
+// concurrent use.
+type ClientStream struct {
+	ctx          context.Context
+	conn         *clientConnection
+	rpc          *call
+	flattenLimit int
+
+	finished bool   // has the call finished?
+	response []byte // the response, if finished
+	err      error  // the error, if finished
+}
+
+// CallStream makes a streaming RPC over connection c. The chunks streamed by
+// the server and the final result of the call are read from the returned
+// ClientStream. The call is canceled when ctx is done.
+func (rc *reconnectingConnection) CallStream(ctx context.Context, h MethodKey, arg []byte, opts CallOptions) (*ClientStream, error) {
+	rpc := &call{}
+	rpc.doneSignal = make(chan struct{})
+	rpc.chunks = make(chan []byte, streamWindow)
+	conn, err := rc.sendRequest(ctx, h, [][]byte{arg}, opts, rpc)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientStream{
+		ctx:          ctx,
+		conn:         conn,
+		rpc:          rpc,
+		flattenLimit: rc.opts.WriteFlattenLimit,
+	}, nil
+}
+
+// Recv returns the next chunk of data streamed by the server, blocking until
+// one is available. It returns false once the call has finished, after which
+// Result returns the result of the call.
+func (s *ClientStream) Recv() ([]byte, bool) {
+	if s.finished {
+		return nil, false
+	}
+	select {
+	case chunk := <-s.rpc.chunks:
+		s.ack()
+		return chunk, true
+	case <-s.rpc.doneSignal:
+		// Deliver the chunks that arrived before the response.
+		select {
+		case chunk := <-s.rpc.chunks:
+			return chunk, true
+		default:
+		}
+		s.finish(s.rpc.response, s.rpc.err)
+		return nil, false
+	case <-s.ctx.Done():
+		s.conn.cancelCall(s.ctx, s.rpc, s.flattenLimit)
+		s.finish(nil, s.ctx.Err())
+		return nil, false
+	}
+}
+
+// Result returns the result of the call. It must be called only after Recv
+// returns false.
+func (s *ClientStream) Result() ([]byte, error) {
+	if !s.finished {
+		panic("call: ClientStream.Result called before the call finished")
+	}
+	return s.response, s.err
+}
+
+// Close cancels the call if it hasn't finished yet. It is safe to call Close
+// more than once, and after the call has finished.
+func (s *ClientStream) Close() {
+	if s.finished {
+		return
+	}
+	s.conn.cancelCall(s.ctx, s.rpc, s.flattenLimit)
+	s.finish(nil, context.Canceled)
+}
+
+// finish marks the call as finished.
+func (s *ClientStream) finish(response []byte, err error) {
+	s.finished = true
+	s.response = response
+	s.err = err
+}
+
+// ack acknowledges the receipt of a chunk, allowing the server to send
+// another one.
+func (s *ClientStream) ack() {
+	if err := writeMessage(s.conn.c, &s.conn.wlock, streamAckMessage, s.rpc.id, nil, nil, s.flattenLimit); err != nil {
+		s.conn.shutdown("client send stream ack", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+)
+
+var streamKey = call.MakeMethodKey("", "stream")
+
+// streamClient returns a client to a server whose "stream" method is handled
+// by the provided handler.
+func streamClient(t *testing.T, handler call.StreamHandler) call.Connection {
+	t.Helper()
+	h := &call.HandlerMap{}
+	h.SetStream("", "stream", handler)
+	ep := &pipeEndpoint{name: "stream", handlers: h, t: t}
+	opts := call.ClientOptions{Logger: logger(t)}
+	client, err := call.Connect(context.Background(), call.NewConstantResolver(ep), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// recvAll receives all of the chunks streamed over s.
+func recvAll(s *call.ClientStream) []string {
+	var chunks []string
+	for {
+		chunk, ok := s.Recv()
+		if !ok {
+			return chunks
+		}
+		chunks = append(chunks, string(chunk))
+	}
+}
+
+func TestStream(t *testing.T) {
+	// The handler streams its argument n times, where n is the argument.
+	client := streamClient(t, func(_ context.Context, arg []byte, send func([]byte) error) ([]byte, error) {
+		n, err := strconv.Atoi(string(arg))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			if err := send([]byte(strconv.Itoa(i))); err != nil {
+				return nil, err
+			}
+		}
+		return []byte("done"), nil
+	})
+
+	for _, n := range []int{0, 1, 10, 1000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			s, err := client.CallStream(context.Background(), streamKey, []byte(strconv.Itoa(n)), call.CallOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			chunks := recvAll(s)
+			if len(chunks) != n {
+				t.Fatalf("got %d chunks, want %d", len(chunks), n)
+			}
+			for i, chunk := range chunks {
+				if want := strconv.Itoa(i); chunk != want {
+					t.Fatalf("chunk %d: got %q, want %q", i, chunk, want)
+				}
+			}
+			result, err := s.Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(result), "done"; got != want {
+				t.Fatalf("Result: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	// The handler streams a chunk and then fails.
+	client := streamClient(t, func(_ context.Context, _ []byte, send func([]byte) error) ([]byte, error) {
+		if err := send([]byte("chunk")); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: stream failed", os.ErrInvalid)
+	})
+
+	s, err := client.CallStream(context.Background(), streamKey, nil, call.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, want := fmt.Sprint(recvAll(s)), "[chunk]"; got != want {
+		t.Fatalf("chunks: got %s, want %s", got, want)
+	}
+	if _, err := s.Result(); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("Result: got %v, want %v", err, os.ErrInvalid)
+	}
+}
+
+func TestStreamFlowControl(t *testing.T) {
+	// The handler streams chunks until it is canceled.
+	var sent atomic.Int64
+	canceled := make(chan error, 1)
+	client := streamClient(t, func(ctx context.Context, _ []byte, send func([]byte) error) ([]byte, error) {
+		for {
+			if err := send([]byte("chunk")); err != nil {
+				canceled <- err
+				return nil, err
+			}
+			sent.Add(1)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := client.CallStream(ctx, streamKey, nil, call.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the client consuming chunks, the server should be able to send
+	// only a window's worth of chunks.
+	waitUntil(t, func() bool { return sent.Load() > 0 })
+	time.Sleep(shortDelay)
+	window := sent.Load()
+	if window >= 100 {
+		t.Fatalf("server sent %d chunks to an idle client", window)
+	}
+
+	// Every consumed chunk allows the server to send another one.
+	for i := 0; i < 10; i++ {
+		if _, ok := s.Recv(); !ok {
+			t.Fatal("stream ended unexpectedly")
+		}
+	}
+	waitUntil(t, func() bool { return sent.Load() == window+10 })
+
+	// Closing the stream cancels the handler.
+	s.Close()
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("send: got %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("handler not canceled")
+	}
+	if _, ok := s.Recv(); ok {
+		t.Fatal("Recv succeeded after Close")
+	}
+}
+
+func TestStreamContextCanceled(t *testing.T) {
+	client := streamClient(t, func(ctx context.Context, _ []byte, send func([]byte) error) ([]byte, error) {
+		if err := send([]byte("chunk")); err != nil {
+			return nil, err
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := client.CallStream(ctx, streamKey, nil, call.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, ok := s.Recv(); !ok {
+		t.Fatal("stream ended unexpectedly")
+	}
+	cancel()
+	if _, ok := s.Recv(); ok {
+		t.Fatal("Recv succeeded after cancellation")
+	}
+	if _, err := s.Result(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Result: got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package call
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// oldVersions are the versions that predate currentVersion and change the
+// format of requests.
+var oldVersions = []version{compressionVersion, callerVersion, metadataVersion}
+
+// writeOldRequest writes a request in the format of the provided version.
+func writeOldRequest(w io.Writer, v version, id uint64, key MethodKey, caller string, md map[string]string, arg []byte) error {
+	n := msgHeaderSize
+	if v >= priorityVersion {
+		n += priorityHeaderLen
+	}
+	if v >= callerVersion {
+		n += callerHeaderLen(caller)
+	}
+	if v >= metadataVersion {
+		n += metadataHeaderLen(md)
+	}
+	hdr := make([]byte, n)
+	copy(hdr, key[:])
+	b := hdr[msgHeaderSize:]
+	if v >= priorityVersion {
+		b = b[priorityHeaderLen:] // no priority
+	}
+	if v >= callerVersion {
+		writeCaller(caller, b)
+		b = b[callerHeaderLen(caller):]
+	}
+	if v >= metadataVersion {
+		writeMetadata(md, b)
+	}
+	var wlock sync.Mutex
+	return writeFlat(w, &wlock, requestMessage, id, hdr, arg)
+}
+
+// readOldRequest returns the caller, metadata, and argument of a request in
+// the format of the provided version.
+func readOldRequest(v version, msg []byte) (string, map[string]string, []byte, error) {
+	if len(msg) < msgHeaderSize {
+		return "", nil, nil, fmt.Errorf("missing request header")
+	}
+	var caller string
+	var md map[string]string
+	var err error
+	payload := msg[msgHeaderSize:]
+	if v >= priorityVersion {
+		if len(payload) < priorityHeaderLen {
+			return "", nil, nil, fmt.Errorf("missing priority header")
+		}
+		payload = payload[priorityHeaderLen:]
+	}
+	if v >= callerVersion {
+		if caller, payload, err = readCaller(payload); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	if v >= metadataVersion {
+		if md, payload, err = readMetadata(payload); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	return caller, md, payload, nil
+}
+
+// describe returns a description of a call, as seen by a server.
+func describe(caller string, md map[string]string, arg []byte) string {
+	return fmt.Sprintf("caller=%q metadata=%v arg=%q", caller, md, arg)
+}
+
+// exchangeVersions sends the provided version to the peer on c and returns
+// the version that the peer sends.
+func exchangeVersions(c io.ReadWriter, v version) (version, error) {
+	var msg [4]byte
+	binary.LittleEndian.PutUint32(msg[:], uint32(v))
+	var wlock sync.Mutex
+	if err := writeFlat(c, &wlock, versionMessage, 0, nil, msg[:]); err != nil {
+		return 0, err
+	}
+	mt, _, peer, err := readMessage(c)
+	if err != nil {
+		return 0, err
+	}
+	if mt != versionMessage || len(peer) < 4 {
+		return 0, fmt.Errorf("got message of type %d, want a version message", mt)
+	}
+	return version(binary.LittleEndian.Uint32(peer)), nil
+}
+
+func TestNewClientOldServer(t *testing.T) {
+	for _, v := range oldVersions {
+		t.Run(fmt.Sprint(v), func(t *testing.T) {
+			// Run a server that speaks version v and describes the request
+			// it receives.
+			lis, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lis.Close()
+			errs := make(chan error, 1)
+			go func() {
+				errs <- func() error {
+					c, err := lis.Accept()
+					if err != nil {
+						return err
+					}
+					defer c.Close()
+					if _, err := exchangeVersions(c, v); err != nil {
+						return err
+					}
+					mt, id, msg, err := readMessage(c)
+					if err != nil {
+						return err
+					}
+					if mt != requestMessage {
+						return fmt.Errorf("got message of type %d, want a request", mt)
+					}
+					caller, md, arg, err := readOldRequest(v, msg)
+					if err != nil {
+						return err
+					}
+					var wlock sync.Mutex
+					return writeFlat(c, &wlock, responseMessage, id, nil, []byte(describe(caller, md, arg)))
+				}()
+			}()
+
+			// The client must only send what the server understands.
+			ctx := WithMetadata(context.Background(), map[string]string{"key": "value"})
+			conn, err := Connect(ctx, NewConstantResolver(TCP(lis.Addr().String())), ClientOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			got, err := conn.Call(ctx, MakeMethodKey("component", "method"), []byte("hello"), CallOptions{Caller: "caller"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var caller string
+			var md map[string]string
+			if v >= callerVersion {
+				caller = "caller"
+			}
+			if v >= metadataVersion {
+				md = map[string]string{"key": "value"}
+			}
+			if want := describe(caller, md, []byte("hello")); string(got) != want {
+				t.Errorf("Call: got %s, want %s", got, want)
+			}
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestOldClientNewServer(t *testing.T) {
+	for _, v := range oldVersions {
+		t.Run(fmt.Sprint(v), func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			var hmap HandlerMap
+			hmap.Set("component", "method", func(ctx context.Context, arg []byte) ([]byte, error) {
+				return []byte(describe(Caller(ctx), Metadata(ctx), arg)), nil
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ServeOn(ctx, server, &hmap, ServerOptions{})
+
+			// Send a request in the format of version v, which the server
+			// must parse as such.
+			if got, err := exchangeVersions(client, v); err != nil {
+				t.Fatal(err)
+			} else if got != currentVersion {
+				t.Fatalf("server version: got %d, want %d", got, currentVersion)
+			}
+			md := map[string]string{"key": "value"}
+			if err := writeOldRequest(client, v, 1, MakeMethodKey("component", "method"), "caller", md, []byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			mt, id, msg, err := readMessage(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mt != responseMessage || id != 1 {
+				t.Fatalf("got message of type %d for call %d, want a response for call 1: %q", mt, id, msg)
+			}
+			var caller string
+			if v >= callerVersion {
+				caller = "caller"
+			}
+			if v < metadataVersion {
+				md = nil
+			}
+			if want := describe(caller, md, []byte("hello")); string(msg) != want {
+				t.Errorf("response: got %s, want %s", msg, want)
+			}
+		})
+	}
+}
@@ -41,4 +41,7 @@ type App interface {
 
 	// GetImpl fetches the component implementation with type t from wlet.
 	GetImpl(requester string, t reflect.Type) (any, error)
+
+	// Shutdown shuts down the components hosted by wlet.
+	Shutdown(ctx context.Context) error
 }
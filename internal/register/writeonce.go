@@ -69,6 +69,14 @@ func (w *WriteOnce[T]) Read() T {
 	return w.val
 }
 
+// TryRead returns the value of the register and true if it has been written,
+// or the zero value and false otherwise. Unlike Read, TryRead never blocks.
+func (w *WriteOnce[T]) TryRead() (T, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.val, w.written
+}
+
 // init initializes the register. We have an init method rather than a
 // WriteOnce constructor so that the zero value of WriteOnce is valid.
 //
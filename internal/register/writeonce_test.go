@@ -91,3 +91,14 @@ func TestTryWrite(t *testing.T) {
 		t.Fatalf("Read: got %v, want %v", got, want)
 	}
 }
+
+func TestTryRead(t *testing.T) {
+	var r register.WriteOnce[int]
+	if got, ok := r.TryRead(); ok {
+		t.Fatalf("TryRead: got %v, want unwritten register", got)
+	}
+	r.Write(x)
+	if got, ok := r.TryRead(); !ok || got != x {
+		t.Fatalf("TryRead: got (%v, %v), want (%v, true)", got, ok, x)
+	}
+}
@@ -82,6 +82,140 @@ func EqualSlices(replicas []string) *protos.Assignment {
 	return &protos.Assignment{Slices: slices}
 }
 
+// imbalanceTolerance is how much more load than the average, as a fraction of
+// the average, a replica may receive before BalanceLoad reassigns the key space.
+const imbalanceTolerance = 0.2
+
+// BalanceLoad returns an assignment of the key space to the provided replicas
+// in which every replica receives roughly the same load. loads holds the load
+// reported by every replica, keyed by replica, while the current assignment
+// was in effect; reports for other versions of the assignment are ignored.
+//
+// The key space is only split at the boundaries of the reported subslices, so
+// the load of a single hot key, which load reports place in a subslice of its
+// own, is never split across replicas. BalanceLoad returns false if there is
+// no load to balance, or if no replica receives more than 20% more load than
+// the average. The returned assignment has the version after the current one.
+func BalanceLoad(current *protos.Assignment, replicas []string, loads map[string]*protos.LoadReport_ComponentLoad) (*protos.Assignment, bool) {
+	if len(replicas) == 0 {
+		return nil, false
+	}
+
+	// Gather the load of every subslice and of every replica.
+	type segment struct {
+		start, end uint64 // [start, end)
+		load       float64
+	}
+	var segments []segment
+	var total, busiest float64
+	for _, report := range loads {
+		if report.GetVersion() != current.GetVersion() {
+			continue
+		}
+		var replicaLoad float64
+		for _, sl := range report.Load {
+			if len(sl.Splits) == 0 {
+				segments = append(segments, segment{sl.Start, sl.End, sl.Load})
+			}
+			for i, split := range sl.Splits {
+				end := sl.End
+				if i+1 < len(sl.Splits) {
+					end = sl.Splits[i+1].Start
+				}
+				segments = append(segments, segment{split.Start, end, split.Load})
+			}
+			replicaLoad += sl.Load
+		}
+		total += replicaLoad
+		if replicaLoad > busiest {
+			busiest = replicaLoad
+		}
+	}
+	if total <= 0 || busiest <= (1+imbalanceTolerance)*total/float64(len(replicas)) {
+		return nil, false
+	}
+
+	// Split the key space into intervals at every subslice boundary. The ith
+	// interval is [bounds[i], bounds[i+1]), and the last one ends at the end
+	// of the key space.
+	bounds := []uint64{0}
+	for _, s := range segments {
+		bounds = append(bounds, s.start, s.end)
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	if bounds[len(bounds)-1] == math.MaxUint64 {
+		bounds = bounds[:len(bounds)-1]
+	}
+	end := func(i int) uint64 {
+		if i+1 < len(bounds) {
+			return bounds[i+1]
+		}
+		return math.MaxUint64
+	}
+
+	// Spread the load of every subslice uniformly over its intervals, and
+	// compute prefix[i], the load of the intervals before the ith one.
+	intervalLoads := make([]float64, len(bounds))
+	for _, s := range segments {
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= s.start })
+		if i == len(bounds) {
+			// s starts at the end of the key space, in the last interval.
+			i--
+		}
+		if s.end <= s.start {
+			intervalLoads[i] += s.load
+			continue
+		}
+		width := float64(s.end - s.start)
+		for ; i < len(bounds) && bounds[i] < s.end; i++ {
+			intervalLoads[i] += s.load * float64(end(i)-bounds[i]) / width
+		}
+	}
+	prefix := make([]float64, len(bounds)+1)
+	for i, load := range intervalLoads {
+		prefix[i+1] = prefix[i] + load
+	}
+	sum := prefix[len(bounds)]
+
+	// Cut the intervals into one contiguous range per replica, placing the rth
+	// cut at the interval boundary whose preceding load is closest to r/n of
+	// the total load. Every range gets at least one interval, if possible.
+	n, m := len(replicas), len(bounds)
+	cuts := []int{0}
+	for r := 1; r < n; r++ {
+		prev := cuts[len(cuts)-1]
+		if prev+1 >= m {
+			break
+		}
+		target := float64(r) / float64(n) * sum
+		hi := m - (n - r)
+		if hi < prev+1 {
+			hi = prev + 1
+		}
+		best := prev + 1
+		for k := prev + 2; k <= hi; k++ {
+			if math.Abs(prefix[k]-target) < math.Abs(prefix[best]-target) {
+				best = k
+			}
+		}
+		cuts = append(cuts, best)
+	}
+
+	// Assign the ranges to the replicas, sorted to make the assignment
+	// deterministic.
+	replicas = slices.Clone(replicas)
+	sort.Strings(replicas)
+	assignment := &protos.Assignment{Version: current.GetVersion() + 1}
+	for i, cut := range cuts {
+		assignment.Slices = append(assignment.Slices, &protos.Assignment_Slice{
+			Start:    bounds[cut],
+			Replicas: []string{replicas[i]},
+		})
+	}
+	return assignment, true
+}
+
 // nextPowerOfTwo returns the least power of 2 that is greater or equal to x.
 func nextPowerOfTwo(x int) int {
 	switch {
@@ -16,6 +16,7 @@ package routing
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/ServiceWeaver/weaver/runtime/protos"
@@ -93,6 +94,94 @@ func TestEqualSlicesThreeReplicas(t *testing.T) {
 	}
 }
 
+func TestBalanceLoadHotKey(t *testing.T) {
+	// Replica a owns [0, half) and b owns [half, max]. Key hot, owned by a,
+	// receives most of the load, so the boundary between a and b shifts to
+	// right after hot.
+	const half uint64 = 1 << 63
+	const hot uint64 = 1 << 40
+	current := &protos.Assignment{
+		Slices: []*protos.Assignment_Slice{
+			{Start: 0, Replicas: []string{"a"}},
+			{Start: half, Replicas: []string{"b"}},
+		},
+		Version: 1,
+	}
+	loads := map[string]*protos.LoadReport_ComponentLoad{
+		"a": {
+			Version: 1,
+			Load: []*protos.LoadReport_SliceLoad{{
+				Start: 0,
+				End:   half,
+				Load:  100,
+				Splits: []*protos.LoadReport_SubsliceLoad{
+					{Start: 0, Load: 5},
+					{Start: hot, Load: 90},
+					{Start: hot + 1, Load: 5},
+				},
+			}},
+		},
+		"b": {
+			Version: 1,
+			Load: []*protos.LoadReport_SliceLoad{{
+				Start:  half,
+				End:    math.MaxUint64,
+				Load:   10,
+				Splits: []*protos.LoadReport_SubsliceLoad{{Start: half, Load: 10}},
+			}},
+		},
+	}
+	got, ok := BalanceLoad(current, []string{"a", "b"}, loads)
+	if !ok {
+		t.Fatal("BalanceLoad: unexpectedly balanced")
+	}
+	want := &protos.Assignment{
+		Slices: []*protos.Assignment_Slice{
+			{Start: 0, Replicas: []string{"a"}},
+			{Start: hot + 1, Replicas: []string{"b"}},
+		},
+		Version: 2,
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Fatalf("BalanceLoad: (-want +got):\n%s", diff)
+	}
+}
+
+func TestBalanceLoadBalanced(t *testing.T) {
+	current := EqualSlices([]string{"a", "b"})
+	report := func(start, end uint64, version uint64) *protos.LoadReport_ComponentLoad {
+		return &protos.LoadReport_ComponentLoad{
+			Version: version,
+			Load: []*protos.LoadReport_SliceLoad{{
+				Start:  start,
+				End:    end,
+				Load:   10,
+				Splits: []*protos.LoadReport_SubsliceLoad{{Start: start, Load: 10}},
+			}},
+		}
+	}
+	half := current.Slices[1].Start
+	for _, test := range []struct {
+		name  string
+		loads map[string]*protos.LoadReport_ComponentLoad
+	}{
+		{"NoLoad", nil},
+		{"Balanced", map[string]*protos.LoadReport_ComponentLoad{
+			"a": report(0, half, 0),
+			"b": report(half, math.MaxUint64, 0),
+		}},
+		{"Stale", map[string]*protos.LoadReport_ComponentLoad{
+			"a": report(0, half, 42),
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got, ok := BalanceLoad(current, []string{"a", "b"}, test.loads); ok {
+				t.Fatalf("BalanceLoad: got %v, want no new assignment", got)
+			}
+		})
+	}
+}
+
 func TestNextPowerOfTwo(t *testing.T) {
 	for _, test := range []struct{ x, want int }{
 		{0, 1}, {1, 1},
@@ -25,9 +25,15 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
 	"time"
 )
 
+// caRenewBefore is how long before its expiration a CA certificate stored by
+// LoadOrGenerateCACert is replaced.
+const caRenewBefore = 30 * 24 * time.Hour
+
 // GenerateCACert generates a self-signed CA certificate and a corresponding
 // private key.
 //
@@ -38,6 +44,82 @@ func GenerateCACert() (*x509.Certificate, crypto.PrivateKey, error) {
 	return generateLeafCert(true /*isCA*/, "ca")
 }
 
+// LoadOrGenerateCACert returns the CA certificate and private key stored, in
+// PEM format, in the provided file. If the file doesn't exist, or if the
+// certificate is about to expire, it generates a new certificate, as
+// GenerateCACert does, and stores it in the file. The file is created
+// exclusively, so processes that load the same file concurrently end up with
+// the same certificate.
+//
+// Anyone who can read the file can issue certificates that the CA validates,
+// so the file is only readable by its owner.
+func LoadOrGenerateCACert(file string) (*x509.Certificate, crypto.PrivateKey, error) {
+	for {
+		data, err := os.ReadFile(file)
+		switch {
+		case err == nil:
+			cert, key, err := pemDecode(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if time.Now().Add(caRenewBefore).Before(cert.NotAfter) {
+				return cert, key, nil
+			}
+			if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, nil, err
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, nil, err
+		}
+
+		cert, key, err := GenerateCACert()
+		if err != nil {
+			return nil, nil, err
+		}
+		certPEM, keyPEM, err := PEMEncode(cert, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		created, err := createExclusive(file, append(certPEM, keyPEM...))
+		if err != nil {
+			return nil, nil, err
+		}
+		if created {
+			return cert, key, nil
+		}
+		// Another process created the file first. Use its certificate.
+	}
+}
+
+// createExclusive creates the provided file, with the provided contents, if
+// it doesn't already exist. It returns whether it created the file. The file
+// is either created in full or not at all.
+func createExclusive(file string, data []byte) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	// Unlike os.Rename, os.Link fails if the file already exists.
+	if err := os.Link(tmp.Name(), file); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // GenerateSignedCert generates a certificate for the given DNS names, signed
 // by the given Certificate Authority, and a corresponding private key.
 //
@@ -152,3 +234,31 @@ func VerifySignedCert(certDER []byte, ca *x509.Certificate) ([]string, error) {
 	}
 	return verifiedCert[0].DNSNames, nil
 }
+
+// pemDecode returns the certificate and the private key stored in the provided
+// PEM-encoded blocks, as encoded by PEMEncode.
+func pemDecode(data []byte) (*x509.Certificate, crypto.PrivateKey, error) {
+	var cert *x509.Certificate
+	var key crypto.PrivateKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var err error
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err = x509.ParseCertificate(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if cert == nil || key == nil {
+		return nil, nil, fmt.Errorf("missing certificate or private key")
+	}
+	return cert, key, nil
+}
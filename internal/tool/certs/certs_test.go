@@ -16,6 +16,8 @@ package certs_test
 
 import (
 	"crypto/x509"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -74,3 +76,33 @@ func TestVerifySignedCert(t *testing.T) {
 		t.Errorf("unexpected certificate names. (-want +got): %s", diff)
 	}
 }
+
+func TestLoadOrGenerateCACert(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ca", "ca.pem")
+	caCert, caKey, err := certs.LoadOrGenerateCACert(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	} else if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file permissions: got %v, want %v", perm, os.FileMode(0600))
+	}
+
+	// Loading the file again returns the same CA, which validates the
+	// certificates signed by the first one.
+	loaded, _, err := certs.LoadOrGenerateCACert(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equal(caCert) {
+		t.Fatal("LoadOrGenerateCACert returned a different certificate")
+	}
+	cert, _, err := certs.GenerateSignedCert(caCert, caKey, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := certs.VerifySignedCert(cert.Raw, loaded); err != nil {
+		t.Errorf("cannot verify certificate with the loaded CA: %v", err)
+	}
+}
@@ -0,0 +1,482 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ServiceWeaver/weaver/internal/files"
+	"github.com/ServiceWeaver/weaver/runtime"
+)
+
+// jsonSchemaDialect is the JSON Schema dialect of the generated schemas.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is a JSON Schema [1] object. Only the subset of keywords needed
+// to describe config structs and the arguments and results of component
+// methods is included.
+//
+// [1]: https://json-schema.org/
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // string or []string
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // bool or *jsonSchema
+}
+
+// generateConfigSchemas generates a weaver_config_schemas.json file containing
+// a JSON Schema for the config type T of every component that embeds
+// weaver.WithConfig[T]. The file maps full component names to schemas. For
+// example, given the following component:
+//
+//	type cacheConfig struct {
+//	    // Maximum number of cached entries.
+//	    Size int `toml:"max_size"`
+//	}
+//
+//	type cache struct {
+//	    weaver.Implements[Cache]
+//	    weaver.WithConfig[cacheConfig]
+//	}
+//
+// generateConfigSchemas generates the following file:
+//
+//	{
+//	  "example.com/mypkg/Cache": {
+//	    "$schema": "https://json-schema.org/draft/2020-12/schema",
+//	    "title": "cacheConfig",
+//	    "type": "object",
+//	    "properties": {
+//	      "max_size": {
+//	        "description": "Maximum number of cached entries.",
+//	        "type": "integer"
+//	      }
+//	    },
+//	    "additionalProperties": false
+//	  }
+//	}
+//
+// Field names and types follow the rules used to decode the TOML config file,
+// so only `toml` struct tags affect property names.
+func (g *generator) generateConfigSchemas() error {
+	filename := filepath.Join(g.pkgDir(), configSchemasFile)
+	b := schemaBuilder{docs: fieldDocs(g.pkg.Syntax), visiting: map[*types.Named]bool{}}
+	schemas := map[string]*jsonSchema{}
+	for _, comp := range g.components {
+		if comp.config == nil {
+			continue
+		}
+		schema := b.schema(comp.config)
+		schema.Schema = jsonSchemaDialect
+		if schema.Type == "object" {
+			// A component's section may also hold settings that Service
+			// Weaver reads itself, which aren't part of its config struct.
+			if schema.Properties == nil {
+				schema.Properties = map[string]*jsonSchema{}
+			}
+			schema.Properties[runtime.MethodTimeoutsKey] = methodTimeoutsSchema(comp)
+			zero := 0
+			schema.Properties[runtime.CompressMinBytesKey] = &jsonSchema{
+				Description: "Minimum size, in bytes, of the compressed arguments and results of remote calls.",
+				Type:        "integer",
+				Minimum:     &zero,
+			}
+			schema.Properties[runtime.MetricsWindowKey] = &jsonSchema{
+				Description: "Duration of the sliding window over which recent method invocations and errors are counted.",
+				Type:        []string{"string", "integer"},
+			}
+			schema.Properties[runtime.RateLimitsKey] = rateLimitsSchema(comp)
+			schema.Properties[runtime.AllowedCallersKey] = allowedCallersSchema(comp)
+			if comp.router != nil {
+				schema.Properties[runtime.AffinityKey] = affinitySchema()
+			}
+		}
+		if named, ok := comp.config.(*types.Named); ok {
+			schema.Title = named.Obj().Name()
+		}
+		schemas[comp.fullIntfName()] = schema
+	}
+	if len(schemas) == 0 {
+		// Remove a schema file left behind by a previous run, if any.
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return err
+	}
+	dst := files.NewWriter(filename)
+	defer dst.Cleanup()
+	if _, err := dst.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return dst.Close()
+}
+
+// methodTimeoutsSchema returns the JSON Schema of the method timeouts of the
+// provided component. See runtime.MethodTimeoutsKey.
+func methodTimeoutsSchema(comp *component) *jsonSchema {
+	properties := map[string]*jsonSchema{}
+	for _, m := range comp.methods() {
+		properties[m.Name()] = &jsonSchema{Type: []string{"string", "integer"}}
+	}
+	return &jsonSchema{
+		Description:          "Timeouts of the component's methods, keyed by method name.",
+		Type:                 "object",
+		Properties:           properties,
+		AdditionalProperties: false,
+	}
+}
+
+// rateLimitsSchema returns the JSON Schema of the method rate limits of the
+// provided component. See runtime.RateLimitsKey.
+func rateLimitsSchema(comp *component) *jsonSchema {
+	zero := 0
+	limit := &jsonSchema{
+		Type: "object",
+		Properties: map[string]*jsonSchema{
+			"qps": {
+				Description: "Maximum sustained rate of calls per second, or 0 for no limit.",
+				Type:        "number",
+				Minimum:     &zero,
+			},
+			"burst": {
+				Description: "Maximum number of calls admitted at once.",
+				Type:        "integer",
+				Minimum:     &zero,
+			},
+			"mode": {
+				Description: "Whether calls over the limit are rejected or wait.",
+				Type:        "string",
+				Enum:        []string{runtime.RateLimitReject, runtime.RateLimitWait},
+			},
+		},
+		AdditionalProperties: false,
+	}
+	properties := map[string]*jsonSchema{}
+	for _, m := range comp.methods() {
+		properties[m.Name()] = limit
+	}
+	return &jsonSchema{
+		Description:          "Rate limits of the component's methods, keyed by method name.",
+		Type:                 "object",
+		Properties:           properties,
+		AdditionalProperties: false,
+	}
+}
+
+// allowedCallersSchema returns the JSON Schema of the allowed callers of the
+// methods of the provided component. See runtime.AllowedCallersKey.
+func allowedCallersSchema(comp *component) *jsonSchema {
+	callers := &jsonSchema{Type: "array", Items: &jsonSchema{Type: "string"}}
+	properties := map[string]*jsonSchema{runtime.AllMethods: callers}
+	for _, m := range comp.methods() {
+		properties[m.Name()] = callers
+	}
+	return &jsonSchema{
+		Description:          "Callers allowed to call the component's methods, keyed by method name.",
+		Type:                 "object",
+		Properties:           properties,
+		AdditionalProperties: false,
+	}
+}
+
+// affinitySchema returns the JSON Schema of the session affinity settings of
+// a routed component. See runtime.AffinityKey.
+func affinitySchema() *jsonSchema {
+	zero := 0
+	return &jsonSchema{
+		Description: "Session affinity of calls to the component.",
+		Type:        "object",
+		Properties: map[string]*jsonSchema{
+			"ttl": {
+				Description: "How long a client remembers the replica of an unused routing key.",
+				Type:        []string{"string", "integer"},
+			},
+			"size": {
+				Description: "Maximum number of routing keys every client remembers.",
+				Type:        "integer",
+				Minimum:     &zero,
+			},
+		},
+		AdditionalProperties: false,
+	}
+}
+
+// fieldDocs returns the doc comments of all struct fields and type
+// declarations in the provided files, keyed by the position of the declared
+// name. Trailing line comments are used for fields without a doc comment.
+func fieldDocs(syntax []*ast.File) map[token.Pos]string {
+	docs := map[token.Pos]string{}
+	for _, file := range syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.GenDecl:
+				if x.Tok != token.TYPE {
+					return true
+				}
+				for _, spec := range x.Specs {
+					spec := spec.(*ast.TypeSpec)
+					doc := spec.Doc
+					if doc == nil && len(x.Specs) == 1 {
+						doc = x.Doc
+					}
+					if doc != nil {
+						docs[spec.Name.Pos()] = strings.TrimSpace(doc.Text())
+					}
+				}
+			case *ast.Field:
+				doc := x.Doc
+				if doc == nil {
+					doc = x.Comment
+				}
+				if doc == nil {
+					return true
+				}
+				for _, name := range x.Names {
+					docs[name.Pos()] = strings.TrimSpace(doc.Text())
+				}
+				if len(x.Names) == 0 {
+					// Embedded field.
+					docs[x.Type.Pos()] = strings.TrimSpace(doc.Text())
+				}
+			}
+			return true
+		})
+	}
+	return docs
+}
+
+// schemaBuilder builds JSON Schemas for Go types.
+type schemaBuilder struct {
+	docs     map[token.Pos]string  // see fieldDocs
+	visiting map[*types.Named]bool // named types currently being visited
+}
+
+// schema returns the JSON Schema of values of type t, as decoded from a TOML
+// config file. Types that can't be decoded from TOML (e.g., channels and
+// functions) get the empty schema, which accepts any value.
+func (b *schemaBuilder) schema(t types.Type) *jsonSchema {
+	// Handle types with custom decoding first.
+	if isDuration(t) {
+		// Durations are decoded from strings like "1m30s" or from integer
+		// nanoseconds.
+		return &jsonSchema{Type: []string{"string", "integer"}}
+	}
+	if isTime(t) {
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	}
+	if isTextUnmarshaler(t) {
+		return &jsonSchema{Type: "string"}
+	}
+
+	switch x := t.(type) {
+	case *types.Named:
+		if b.visiting[x] {
+			// Recursive types are cut off at the first repetition.
+			return &jsonSchema{Type: "object"}
+		}
+		b.visiting[x] = true
+		defer delete(b.visiting, x)
+		schema := b.schema(x.Underlying())
+		if schema.Description == "" {
+			schema.Description = b.docs[x.Obj().Pos()]
+		}
+		return schema
+
+	case *types.Basic:
+		switch {
+		case x.Info()&types.IsBoolean != 0:
+			return &jsonSchema{Type: "boolean"}
+		case x.Info()&types.IsInteger != 0:
+			if x.Info()&types.IsUnsigned != 0 {
+				zero := 0
+				return &jsonSchema{Type: "integer", Minimum: &zero}
+			}
+			return &jsonSchema{Type: "integer"}
+		case x.Info()&types.IsFloat != 0:
+			return &jsonSchema{Type: "number"}
+		case x.Info()&types.IsString != 0:
+			return &jsonSchema{Type: "string"}
+		}
+		return &jsonSchema{}
+
+	case *types.Pointer:
+		return b.schema(x.Elem())
+
+	case *types.Slice:
+		return &jsonSchema{Type: "array", Items: b.schema(x.Elem())}
+
+	case *types.Array:
+		n := int(x.Len())
+		return &jsonSchema{Type: "array", Items: b.schema(x.Elem()), MaxItems: &n}
+
+	case *types.Map:
+		if k, ok := x.Key().Underlying().(*types.Basic); !ok || k.Info()&types.IsString == 0 {
+			// TOML tables can only be decoded into maps with string keys.
+			return &jsonSchema{Type: "object"}
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: b.schema(x.Elem())}
+
+	case *types.Struct:
+		schema := &jsonSchema{
+			Type:       "object",
+			Properties: map[string]*jsonSchema{},
+			// Unknown keys in a component's config section are rejected.
+			AdditionalProperties: false,
+		}
+		b.addFields(schema, x)
+		return schema
+
+	default:
+		// Interfaces, channels, functions, etc.
+		return &jsonSchema{}
+	}
+}
+
+// addFields adds a property to the provided schema for every field of the
+// provided struct that can be set from a config file. Untagged embedded
+// structs have their fields promoted, like the TOML decoder does.
+func (b *schemaBuilder) addFields(schema *jsonSchema, s *types.Struct) {
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		tag := reflect.StructTag(s.Tag(i)).Get("toml")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Embedded() && name == "" {
+			t := f.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if embedded, ok := t.Underlying().(*types.Struct); ok {
+				if named, ok := t.(*types.Named); ok {
+					if b.visiting[named] {
+						continue
+					}
+					b.visiting[named] = true
+					b.addFields(schema, embedded)
+					delete(b.visiting, named)
+				} else {
+					b.addFields(schema, embedded)
+				}
+				continue
+			}
+		}
+
+		if !f.Exported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name()
+		}
+		if _, ok := schema.Properties[name]; ok {
+			// Fields of the outer struct shadow promoted fields.
+			continue
+		}
+		prop := b.schema(f.Type())
+		if doc := b.docs[f.Pos()]; doc != "" {
+			prop.Description = doc
+		}
+		if def, ok := reflect.StructTag(s.Tag(i)).Lookup(runtime.DefaultTag); ok {
+			prop.Default = schemaDefault(f.Type(), def)
+		}
+		schema.Properties[name] = prop
+	}
+}
+
+// schemaDefault returns the JSON value of the provided default of a field of
+// type t, as specified by a runtime.DefaultTag. Defaults of fields that don't
+// hold booleans or numbers are strings. Defaults that can't be parsed are
+// ignored; they are reported when the config is parsed.
+func schemaDefault(t types.Type, def string) any {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok || isDuration(t) || isTextUnmarshaler(t) {
+		return def
+	}
+	switch {
+	case b.Info()&types.IsBoolean != 0:
+		if v, err := strconv.ParseBool(def); err == nil {
+			return v
+		}
+	case b.Info()&types.IsInteger != 0:
+		if v, err := strconv.ParseInt(def, 10, 64); err == nil {
+			return v
+		}
+		if v, err := strconv.ParseUint(def, 10, 64); err == nil {
+			return v
+		}
+	case b.Info()&types.IsFloat != 0:
+		if v, err := strconv.ParseFloat(def, 64); err == nil {
+			return v
+		}
+	default:
+		return def
+	}
+	return nil
+}
+
+// isDuration returns whether t is time.Duration.
+func isDuration(t types.Type) bool {
+	return isNamed(t, "time", "Duration")
+}
+
+// isTime returns whether t is time.Time.
+func isTime(t types.Type) bool {
+	return isNamed(t, "time", "Time")
+}
+
+// isNamed returns whether t is the named type pkg.name.
+func isNamed(t types.Type, pkg, name string) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == pkg && n.Obj().Name() == name
+}
+
+// isTextUnmarshaler returns whether *t has an UnmarshalText method, in which
+// case values of type t are decoded from strings.
+func isTextUnmarshaler(t types.Type) bool {
+	if _, ok := t.(*types.Named); !ok {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "UnmarshalText")
+	_, ok := obj.(*types.Func)
+	return ok
+}
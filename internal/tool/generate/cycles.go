@@ -0,0 +1,99 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// findRefCycles returns an error for every cycle of weaver.Ref fields among
+// the provided components, e.g., when component A has a weaver.Ref[B] field
+// and component B has a weaver.Ref[A] field, or when a component has a
+// weaver.Ref to itself. Such components can't be constructed in the same
+// process: constructing A requires constructing B, which requires constructing
+// A.
+//
+// Note that only the components passed to a single invocation of "weaver
+// generate" are checked, so cycles through components in other packages may
+// go unnoticed.
+func findRefCycles(fset *token.FileSet, components []*component) []error {
+	byName := map[string]*component{}
+	for _, c := range components {
+		byName[c.fullIntfName()] = c
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Find the cycles using a depth-first search. A cycle is reported when
+	// the search reaches a component that is on the current path.
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := map[string]int{}
+	var path []string
+	var errs []error
+	var visit func(string)
+	visit = func(name string) {
+		state[name] = onPath
+		path = append(path, name)
+		c := byName[name]
+		for _, ref := range c.refs {
+			next := fullName(ref)
+			if _, ok := byName[next]; !ok {
+				continue
+			}
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onPath:
+				if next == name {
+					// A component that refers to itself is almost certainly
+					// a mistake, so we report it with a dedicated message.
+					errs = append(errs, errorf(fset, c.impl.Obj().Pos(),
+						"weaver.Ref cycle: %s -> %s. Component %s has a weaver.Ref to itself; call the methods of its implementation directly instead.",
+						name, name, c.intfName()))
+					continue
+				}
+				var cycle []string
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == next {
+						cycle = append(cycle, path[i:]...)
+						break
+					}
+				}
+				cycle = append(cycle, next)
+				start := byName[next]
+				errs = append(errs, errorf(fset, start.impl.Obj().Pos(),
+					"weaver.Ref cycle: %s. Components in a cycle of weaver.Ref fields can't be constructed in the same process.",
+					strings.Join(cycle, " -> ")))
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return errs
+}
@@ -22,7 +22,7 @@ import (
 	"github.com/ServiceWeaver/weaver"
 )
 
-//go:generate ../../../../cmd/weaver/weaver generate
+//go:generate ../../../../cmd/weaver/weaver generate -allow_ref_cycles
 
 type message struct {
 	weaver.AutoMarshal
@@ -71,8 +71,8 @@ type a struct {
 type b struct {
 	weaver.Implements[B]
 	a    weaver.Ref[A]   //nolint:unused
-	lis1 weaver.Listener `weaver:"renamed_listener"` //nolint:unused
-	lis2 weaver.Listener //nolint:unused
+	lis1 weaver.Listener `weaver:"renamed_listener_b"` //nolint:unused
+	lis2 weaver.Listener `weaver:"lis2_b"`             //nolint:unused
 	weaver.WithConfig[config]
 	weaver.WithRouter[router]
 }
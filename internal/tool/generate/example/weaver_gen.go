@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -42,13 +42,16 @@ func init() {
 		Routed:    true,
 		Listeners: []string{"lis2", "renamed_listener"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return a_local_stub{impl: impl.(A), tracer: tracer, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M1", Remote: false}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M2", Remote: false})}
+			return a_intercept(a_local_stub{impl: impl.(A), caller: caller, tracer: tracer, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M1", Remote: false}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M2", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return a_client_stub{stub: stub, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M1", Remote: true}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M2", Remote: true})}
+			return a_intercept(a_client_stub{stub: stub, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M1", Remote: true}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Method: "M2", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return a_server_stub{impl: impl.(A), addLoad: addLoad}
+			return a_server_stub{impl: a_intercept(impl.(A), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return a_intercept(next.(A), interceptor, call)
 		},
 		RefData: "⟦627f661b:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/tool/generate/example/A→github.com/ServiceWeaver/weaver/internal/tool/generate/example/B⟧\n⟦26168bd7:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/internal/tool/generate/example/A→lis2,renamed_listener⟧\n",
 	})
@@ -57,17 +60,20 @@ func init() {
 		Iface:     reflect.TypeOf((*B)(nil)).Elem(),
 		Impl:      reflect.TypeOf(b{}),
 		Routed:    true,
-		Listeners: []string{"lis2", "renamed_listener"},
+		Listeners: []string{"lis2_b", "renamed_listener_b"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return b_local_stub{impl: impl.(B), tracer: tracer, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M1", Remote: false}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M2", Remote: false})}
+			return b_intercept(b_local_stub{impl: impl.(B), caller: caller, tracer: tracer, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M1", Remote: false}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M2", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return b_client_stub{stub: stub, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M1", Remote: true}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M2", Remote: true})}
+			return b_intercept(b_client_stub{stub: stub, m1Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M1", Remote: true}), m2Metrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Method: "M2", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return b_server_stub{impl: impl.(B), addLoad: addLoad}
+			return b_server_stub{impl: b_intercept(impl.(B), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return b_intercept(next.(B), interceptor, call)
 		},
-		RefData: "⟦6971bce2:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/tool/generate/example/B→github.com/ServiceWeaver/weaver/internal/tool/generate/example/A⟧\n⟦c9c43570:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/internal/tool/generate/example/B→lis2,renamed_listener⟧\n",
+		RefData: "⟦6971bce2:wEaVeReDgE:github.com/ServiceWeaver/weaver/internal/tool/generate/example/B→github.com/ServiceWeaver/weaver/internal/tool/generate/example/A⟧\n⟦1d041577:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/internal/tool/generate/example/B→lis2_b,renamed_listener_b⟧\n",
 	})
 }
 
@@ -90,6 +96,7 @@ var _ func(context.Context, int, string, bool, [10]int, []string, map[bool]int,
 
 type a_local_stub struct {
 	impl      A
+	caller    string
 	tracer    trace.Tracer
 	m1Metrics *codegen.MethodMetrics
 	m2Metrics *codegen.MethodMetrics
@@ -115,6 +122,7 @@ func (s a_local_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A")
 	return s.impl.M1(ctx, a0, a1, a2, a3, a4, a5, a6)
 }
 
@@ -135,11 +143,13 @@ func (s a_local_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A")
 	return s.impl.M2(ctx, a0, a1, a2, a3, a4, a5, a6)
 }
 
 type b_local_stub struct {
 	impl      B
+	caller    string
 	tracer    trace.Tracer
 	m1Metrics *codegen.MethodMetrics
 	m2Metrics *codegen.MethodMetrics
@@ -165,6 +175,7 @@ func (s b_local_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B")
 	return s.impl.M1(ctx, a0, a1, a2, a3, a4, a5, a6)
 }
 
@@ -185,6 +196,7 @@ func (s b_local_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B")
 	return s.impl.M2(ctx, a0, a1, a2, a3, a4, a5, a6)
 }
 
@@ -202,8 +214,11 @@ var _ A = (*a_client_stub)(nil)
 func (s a_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.m1Metrics.Begin()
-	defer func() { s.m1Metrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.m1Metrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -216,6 +231,7 @@ func (s a_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -243,11 +259,12 @@ func (s a_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 	shardKey := _hashA(r.M1(ctx, a0, a1, a2, a3, a4, a5, a6))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -262,8 +279,11 @@ func (s a_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 func (s a_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.m2Metrics.Begin()
-	defer func() { s.m2Metrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.m2Metrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -276,6 +296,7 @@ func (s a_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -303,11 +324,12 @@ func (s a_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 	shardKey := _hashA(r.M2(ctx, a0, a1, a2, a3, a4, a5, a6))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -331,8 +353,11 @@ var _ B = (*b_client_stub)(nil)
 func (s b_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.m1Metrics.Begin()
-	defer func() { s.m1Metrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.m1Metrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -345,6 +370,7 @@ func (s b_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -372,11 +398,12 @@ func (s b_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 	shardKey := _hashB(r.M1(ctx, a0, a1, a2, a3, a4, a5, a6))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -391,8 +418,11 @@ func (s b_client_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 func (s b_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.m2Metrics.Begin()
-	defer func() { s.m2Metrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.m2Metrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -405,6 +435,7 @@ func (s b_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -432,11 +463,12 @@ func (s b_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 	shardKey := _hashB(r.M2(ctx, a0, a1, a2, a3, a4, a5, a6))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -451,8 +483,9 @@ func (s b_client_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [1
 // Server stub implementations.
 
 type a_server_stub struct {
-	impl    A
-	addLoad func(key uint64, load float64)
+	impl       A
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that a_server_stub implements the codegen.Server interface.
@@ -470,6 +503,11 @@ func (s a_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s a_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s a_server_stub) m1(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -495,12 +533,18 @@ func (s a_server_stub) m1(ctx context.Context, args []byte) (res []byte, err err
 	var a6 message
 	(&a6).WeaverUnmarshal(dec)
 	var r router
-	s.addLoad(_hashA(r.M1(ctx, a0, a1, a2, a3, a4, a5, a6)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.M1(ctx, a0, a1, a2, a3, a4, a5, a6)
+	loadKey := _hashA(r.M1(ctx, a0, a1, a2, a3, a4, a5, a6))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 pair
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", "M1", func(ctx context.Context) (err error) {
+		r0, err = s.impl.M1(ctx, a0, a1, a2, a3, a4, a5, a6)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -534,12 +578,18 @@ func (s a_server_stub) m2(ctx context.Context, args []byte) (res []byte, err err
 	var a6 message
 	(&a6).WeaverUnmarshal(dec)
 	var r router
-	s.addLoad(_hashA(r.M2(ctx, a0, a1, a2, a3, a4, a5, a6)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.M2(ctx, a0, a1, a2, a3, a4, a5, a6)
+	loadKey := _hashA(r.M2(ctx, a0, a1, a2, a3, a4, a5, a6))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 pair
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/A", "M2", func(ctx context.Context) (err error) {
+		r0, err = s.impl.M2(ctx, a0, a1, a2, a3, a4, a5, a6)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -549,8 +599,9 @@ func (s a_server_stub) m2(ctx context.Context, args []byte) (res []byte, err err
 }
 
 type b_server_stub struct {
-	impl    B
-	addLoad func(key uint64, load float64)
+	impl       B
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that b_server_stub implements the codegen.Server interface.
@@ -568,6 +619,11 @@ func (s b_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s b_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s b_server_stub) m1(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -593,12 +649,18 @@ func (s b_server_stub) m1(ctx context.Context, args []byte) (res []byte, err err
 	var a6 message
 	(&a6).WeaverUnmarshal(dec)
 	var r router
-	s.addLoad(_hashB(r.M1(ctx, a0, a1, a2, a3, a4, a5, a6)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.M1(ctx, a0, a1, a2, a3, a4, a5, a6)
+	loadKey := _hashB(r.M1(ctx, a0, a1, a2, a3, a4, a5, a6))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 pair
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", "M1", func(ctx context.Context) (err error) {
+		r0, err = s.impl.M1(ctx, a0, a1, a2, a3, a4, a5, a6)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -632,12 +694,18 @@ func (s b_server_stub) m2(ctx context.Context, args []byte) (res []byte, err err
 	var a6 message
 	(&a6).WeaverUnmarshal(dec)
 	var r router
-	s.addLoad(_hashB(r.M2(ctx, a0, a1, a2, a3, a4, a5, a6)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.M2(ctx, a0, a1, a2, a3, a4, a5, a6)
+	loadKey := _hashB(r.M2(ctx, a0, a1, a2, a3, a4, a5, a6))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 pair
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/internal/tool/generate/example/B", "M2", func(ctx context.Context) (err error) {
+		r0, err = s.impl.M2(ctx, a0, a1, a2, a3, a4, a5, a6)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -646,6 +714,76 @@ func (s b_server_stub) m2(ctx context.Context, args []byte) (res []byte, err err
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type a_intercept_stub struct {
+	next        A
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that a_intercept_stub implements the A interface.
+var _ A = (*a_intercept_stub)(nil)
+
+// a_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func a_intercept(next A, interceptor codegen.Interceptor, call codegen.Call) A {
+	if interceptor == nil {
+		return next
+	}
+	return a_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s a_intercept_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "M1", []any{a0, a1, a2, a3, a4, a5, a6}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.M1(ctx, codegen.Arg[int](args, 0), codegen.Arg[string](args, 1), codegen.Arg[bool](args, 2), codegen.Arg[[10]int](args, 3), codegen.Arg[[]string](args, 4), codegen.Arg[map[bool]int](args, 5), codegen.Arg[message](args, 6))
+		return []any{r0}, err
+	})
+	return codegen.Result[pair](results, 0), err
+}
+
+func (s a_intercept_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "M2", []any{a0, a1, a2, a3, a4, a5, a6}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.M2(ctx, codegen.Arg[int](args, 0), codegen.Arg[string](args, 1), codegen.Arg[bool](args, 2), codegen.Arg[[10]int](args, 3), codegen.Arg[[]string](args, 4), codegen.Arg[map[bool]int](args, 5), codegen.Arg[message](args, 6))
+		return []any{r0}, err
+	})
+	return codegen.Result[pair](results, 0), err
+}
+
+type b_intercept_stub struct {
+	next        B
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that b_intercept_stub implements the B interface.
+var _ B = (*b_intercept_stub)(nil)
+
+// b_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func b_intercept(next B, interceptor codegen.Interceptor, call codegen.Call) B {
+	if interceptor == nil {
+		return next
+	}
+	return b_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s b_intercept_stub) M1(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "M1", []any{a0, a1, a2, a3, a4, a5, a6}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.M1(ctx, codegen.Arg[int](args, 0), codegen.Arg[string](args, 1), codegen.Arg[bool](args, 2), codegen.Arg[[10]int](args, 3), codegen.Arg[[]string](args, 4), codegen.Arg[map[bool]int](args, 5), codegen.Arg[message](args, 6))
+		return []any{r0}, err
+	})
+	return codegen.Result[pair](results, 0), err
+}
+
+func (s b_intercept_stub) M2(ctx context.Context, a0 int, a1 string, a2 bool, a3 [10]int, a4 []string, a5 map[bool]int, a6 message) (r0 pair, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "M2", []any{a0, a1, a2, a3, a4, a5, a6}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.M2(ctx, codegen.Arg[int](args, 0), codegen.Arg[string](args, 1), codegen.Arg[bool](args, 2), codegen.Arg[[10]int](args, 3), codegen.Arg[[]string](args, 4), codegen.Arg[map[bool]int](args, 5), codegen.Arg[message](args, 6))
+		return []any{r0}, err
+	})
+	return codegen.Result[pair](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*message)(nil)
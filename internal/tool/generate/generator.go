@@ -49,11 +49,16 @@ import (
 
 const (
 	generatedCodeFile = "weaver_gen.go"
+	generatedMockFile = "weaver_gen_mock_test.go"
+	legacyMockFile    = "weaver_gen_mock.go" // written by older versions
+	configSchemasFile = "weaver_config_schemas.json"
+	openAPIFile       = "weaver_openapi.json"
+	protoServicesFile = "weaver_services.proto"
 
 	Usage = `Generate code for a Service Weaver application.
 
 Usage:
-  weaver generate [packages]
+  weaver generate [-mocks] [-schema=<format>] [-schema_dir=<dir>] [packages]
 
 Description:
   "weaver generate" generates code for the Service Weaver applications in the
@@ -63,6 +68,11 @@ Description:
   file in the package's directory. For example, "weaver generate . ./foo" will
   create ./weaver_gen.go and ./foo/weaver_gen.go.
 
+  If any component in a package embeds weaver.WithConfig[T], a
+  weaver_config_schemas.json file is also written to the package's directory.
+  It contains a JSON Schema for every component's config type T, describing
+  the keys that are valid in the component's section of the config file.
+
   You specify packages for "weaver generate" in the same way you specify
   packages for go build, go test, go vet, etc. See "go help packages" for more
   information.
@@ -74,6 +84,38 @@ Description:
 
   and then use the normal "go generate" command.
 
+Flags:
+  -allow_ref_cycles
+          Report cycles of weaver.Ref fields among the components, e.g.,
+          component A with a weaver.Ref[B] field and component B with a
+          weaver.Ref[A] field, or a component with a weaver.Ref to itself, as
+          warnings. By default, they are reported as errors, since components
+          in such a cycle can't be constructed in the same process.
+
+  -mocks  Also generate a weaver_gen_mock_test.go file in every package. The
+          file contains a mock implementation of every component interface in
+          the package, for use in the package's tests. A mock of component
+          interface Foo is called MockFoo. For every method Bar of Foo, MockFoo
+          has a BarFunc field that is called by MockFoo.Bar, and a BarCalls
+          method that returns the arguments of all calls to Bar. Mocks can be
+          passed to weavertest.Fake. Without -mocks, a mock file generated by a
+          previous run is removed.
+
+  -schema=<format>
+          Also generate a schema of the services provided by the components
+          in every package, so that clients not written with Service Weaver
+          can call them, e.g., through a gateway served on a Listener. The
+          format is "openapi", for an OpenAPI 3.1 document written to a
+          weaver_openapi.json file, or "proto", for protobuf service
+          definitions written to a weaver_services.proto file. Methods that
+          can't be represented in the format are left out with a warning.
+
+  -schema_dir=<dir>
+          Write service schemas to the provided directory rather than to the
+          directory of every package. The name of every schema file is
+          prefixed with the path of its package, with slashes replaced by
+          underscores.
+
 Examples:
   # Generate code for the package in the current directory.
   weaver generate
@@ -85,13 +127,38 @@ Examples:
   weaver generate ./foo
 
   # Generate code for all packages in all subdirectories of current directory.
-  weaver generate ./...`
+  weaver generate ./...
+
+  # Generate code and an OpenAPI document for the package in the current
+  # directory.
+  weaver generate -schema=openapi .
+
+  # Generate code and mocks for the package in the current directory.
+  weaver generate -mocks .`
 )
 
 // Options controls the operation of Generate.
 type Options struct {
 	// If non-nil, use the specified function to report warnings.
 	Warn func(error)
+
+	// If true, generate a weaver_gen_mock_test.go file with mocks of the
+	// component interfaces in every package. Otherwise, remove such a file
+	// left behind by a previous run.
+	Mocks bool
+
+	// If true, cycles of weaver.Ref fields among the components are reported
+	// as warnings. Otherwise, they are reported as errors.
+	AllowRefCycles bool
+
+	// If non-empty, generate a schema of the services provided by the
+	// components in every package, in the given format: SchemaOpenAPI or
+	// SchemaProto.
+	Schema string
+
+	// The directory in which service schemas are written. If empty, the
+	// schema of a package is written to the package's directory.
+	SchemaDir string
 }
 
 // Generate generates Service Weaver code for the specified packages.
@@ -101,6 +168,16 @@ func Generate(dir string, pkgs []string, opt Options) error {
 	if opt.Warn == nil {
 		opt.Warn = func(err error) { fmt.Fprintln(os.Stderr, err) }
 	}
+	switch opt.Schema {
+	case "", SchemaOpenAPI, SchemaProto:
+	default:
+		return fmt.Errorf("unknown schema format %q: want %q or %q", opt.Schema, SchemaOpenAPI, SchemaProto)
+	}
+	if opt.Schema != "" && opt.SchemaDir != "" {
+		if err := os.MkdirAll(opt.SchemaDir, 0755); err != nil {
+			return err
+		}
+	}
 	fset := token.NewFileSet()
 	cfg := &packages.Config{
 		Mode:       packages.NeedName | packages.NeedSyntax | packages.NeedImports | packages.NeedTypes | packages.NeedTypesInfo,
@@ -114,32 +191,72 @@ func Generate(dir string, pkgs []string, opt Options) error {
 		return fmt.Errorf("packages.Load: %w", err)
 	}
 
-	var automarshals typeutil.Map
+	// Find the types with codecs registered using codegen.RegisterTypeCodec
+	// in any of the packages, before generating code for any of them.
+	var codecs typeutil.Map
 	var errs []error
 	for _, pkg := range pkgList {
-		g, err := newGenerator(opt, pkg, fset, &automarshals)
+		for _, file := range pkg.Syntax {
+			if isGeneratedFile(fset.Position(file.Package).Filename) {
+				continue
+			}
+			ts, err := findTypeCodecs(pkg, file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, t := range ts {
+				codecs.Set(t, struct{}{})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	var automarshals typeutil.Map
+	var components []*component
+	for _, pkg := range pkgList {
+		g, err := newGenerator(opt, pkg, fset, &automarshals, &codecs)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
+		components = append(components, g.components...)
 		if err := g.generate(); err != nil {
 			errs = append(errs, err)
 		}
 	}
+
+	for _, err := range findRefCycles(fset, components) {
+		if opt.AllowRefCycles {
+			opt.Warn(err)
+		} else {
+			errs = append(errs, err)
+		}
+	}
 	return errors.Join(errs...)
 }
 
-// parseNonWeaverGenFile parses a Go file, except for weaver_gen.go files whose
-// contents are ignored since those contents may reference types that no longer
-// exist.
+// isGeneratedFile returns whether the provided file was generated by "weaver
+// generate".
+func isGeneratedFile(filename string) bool {
+	base := filepath.Base(filename)
+	return base == generatedCodeFile || base == generatedMockFile || base == legacyMockFile
+}
+
+// parseNonWeaverGenFile parses a Go file, except for weaver_gen.go and mock
+// files whose contents are ignored since those contents may reference types
+// that no longer exist.
 func parseNonWeaverGenFile(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
-	if filepath.Base(filename) == generatedCodeFile {
+	if isGeneratedFile(filename) {
 		return parser.ParseFile(fset, filename, src, parser.PackageClauseOnly)
 	}
 	return parser.ParseFile(fset, filename, src, parser.ParseComments|parser.DeclarationErrors)
 }
 
 type generator struct {
+	opt            Options
 	pkg            *packages.Package
 	tset           *typeSet
 	fileset        *token.FileSet
@@ -168,7 +285,7 @@ func errorf(fset *token.FileSet, pos token.Pos, format string, args ...interface
 	return fmt.Errorf("%s: %w", prefix, fmt.Errorf(format, args...))
 }
 
-func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, automarshals *typeutil.Map) (*generator, error) {
+func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, automarshals, codecs *typeutil.Map) (*generator, error) {
 	// Abort if there were any errors loading the package.
 	var errs []error
 	for _, err := range pkg.Errors {
@@ -180,11 +297,11 @@ func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, autom
 
 	// Search every file in the package for types that embed the
 	// weaver.AutoMarshal struct.
-	tset := newTypeSet(pkg, automarshals, &typeutil.Map{})
+	tset := newTypeSet(pkg, automarshals, &typeutil.Map{}, codecs)
 	for _, file := range pkg.Syntax {
 		filename := fset.Position(file.Package).Filename
-		if filepath.Base(filename) == generatedCodeFile {
-			// Ignore weaver_gen.go files.
+		if isGeneratedFile(filename) {
+			// Ignore weaver_gen.go and mock files.
 			continue
 		}
 		ts, err := findAutoMarshals(pkg, file)
@@ -193,7 +310,14 @@ func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, autom
 			continue
 		}
 		for _, t := range ts {
-			tset.automarshalCandidates.Set(t, struct{}{})
+			if t.t.TypeParams().Len() > 0 {
+				tset.genericAutomarshals.Set(t.t, struct{}{})
+			} else {
+				tset.automarshalCandidates.Set(t.t, struct{}{})
+			}
+			if t.versioned {
+				tset.versioned.Set(t.t, struct{}{})
+			}
 		}
 	}
 	if err := errors.Join(errs...); err != nil {
@@ -218,10 +342,11 @@ func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, autom
 
 	// Find and process all components.
 	components := map[string]*component{}
+	listeners := map[string]componentListener{}
 	for _, file := range pkg.Syntax {
 		filename := fset.Position(file.Package).Filename
-		if filepath.Base(filename) == generatedCodeFile {
-			// Ignore weaver_gen.go files.
+		if isGeneratedFile(filename) {
+			// Ignore weaver_gen.go and mock files.
 			continue
 		}
 
@@ -245,6 +370,24 @@ func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, autom
 				continue
 			}
 			components[c.fullIntfName()] = c
+
+			// Check for listener duplicates, two listener fields with the
+			// same name. Listener names must be unique across the entire
+			// application binary, but packages may be linked into different
+			// binaries, so we can only check a package at a time here.
+			// Collisions across packages are detected when the application
+			// starts.
+			for _, lis := range c.listeners {
+				if existing, ok := listeners[lis.name]; ok {
+					errs = append(errs, errorf(pkg.Fset, lis.pos,
+						"Duplicate listener %q: field %s.%s of component %s and field %s.%s of component %s (%v) have the same listener name. Rename one of them using a `weaver:\"name\"` struct tag.",
+						lis.name, c.implName(), lis.field, c.fullIntfName(),
+						existing.comp.implName(), existing.field, existing.comp.fullIntfName(),
+						fset.Position(existing.pos)))
+					continue
+				}
+				listeners[lis.name] = componentListener{listener: lis, comp: c}
+			}
 		}
 	}
 	if err := errors.Join(errs...); err != nil {
@@ -252,6 +395,7 @@ func newGenerator(opt Options, pkg *packages.Package, fset *token.FileSet, autom
 	}
 
 	return &generator{
+		opt:        opt,
 		pkg:        pkg,
 		tset:       tset,
 		fileset:    fset,
@@ -294,8 +438,8 @@ func findComponents(opt Options, pkg *packages.Package, f *ast.File, tset *typeS
 
 // findAutoMarshals returns the types in the provided file which embed the
 // weaver.AutoMarshal struct.
-func findAutoMarshals(pkg *packages.Package, f *ast.File) ([]*types.Named, error) {
-	var automarshals []*types.Named
+func findAutoMarshals(pkg *packages.Package, f *ast.File) ([]automarshalDecl, error) {
+	var automarshals []automarshalDecl
 	var errs []error
 	for _, decl := range f.Decls {
 		gendecl, ok := decl.(*ast.GenDecl)
@@ -336,44 +480,162 @@ func findAutoMarshals(pkg *packages.Package, f *ast.File) ([]*types.Named, error
 			}
 
 			// Check for an embedded weaver.AutoMarshal field.
-			automarshal := false
+			automarshal, versioned := false, false
 			for i := 0; i < t.NumFields(); i++ {
 				f := t.Field(i)
 				if f.Embedded() && isWeaverAutoMarshal(f.Type()) {
 					automarshal = true
+					switch tag := reflect.StructTag(t.Tag(i)).Get("weaver"); tag {
+					case "":
+					case "versioned":
+						versioned = true
+					default:
+						errs = append(errs, errorf(pkg.Fset, spec.Pos(),
+							"weaver.AutoMarshal in %v has unknown struct tag %q", formatType(pkg, n), tag))
+					}
 					break
 				}
 			}
 			if !automarshal {
 				continue
 			}
+			if err := checkFieldTags(t); err != nil {
+				errs = append(errs, errorf(pkg.Fset, spec.Pos(), "%v: %v", formatType(pkg, n), err))
+			}
 
-			// Ignore generic types. Generic types don't play well with
-			// embedded AutoMarshals. For example, consider the following type
-			// declaration:
+			// Note that a generic type may embed weaver.AutoMarshal. For
+			// example, consider the following type declaration:
 			//
 			//     type Register[A any] struct {
 			//         weaver.AutoMarshal
 			//         a A
 			//     }
 			//
-			// Is Register[A] serializable? It depends on A. Plus, we cannot
-			// really generate WeaverMarshal and WeaverUnmarshal methods for
-			// specific instantiations of Register[A]. Because of these
-			// complications, we ignore generic types.
-			//
-			// TODO(mwhittaker): Handle generics somehow?
-			if n.TypeParams() != nil { // generics have non-nil TypeParams()
-				errs = append(errs, errorf(pkg.Fset, spec.Pos(),
-					"generic struct %v cannot embed weaver.AutoMarshal. See serviceweaver.dev/docs.html#serializable-types for more information.",
-					formatType(pkg, n)))
-				continue
+			// Is Register[A] serializable? It depends on A, so every
+			// instantiation of Register found in the package is checked
+			// separately. See typeSet.checkSerializable.
+			automarshals = append(automarshals, automarshalDecl{n, versioned})
+		}
+	}
+	return automarshals, errors.Join(errs...)
+}
+
+// checkFieldTags checks the weaver struct tags of the fields of the provided
+// AutoMarshal struct. See fieldWireName.
+func checkFieldTags(s *types.Struct) error {
+	var errs []error
+	seen := map[string]string{} // wire name -> Go name
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		if isWeaverAutoMarshal(f.Type()) {
+			continue
+		}
+		wire, excluded := fieldWireName(s, i)
+		if excluded {
+			continue
+		}
+		if !token.IsIdentifier(wire) {
+			errs = append(errs, fmt.Errorf("field %s has invalid wire name %q in its struct tag. A wire name must be a Go identifier, or \"-\" to exclude the field from marshalling.", f.Name(), wire))
+			continue
+		}
+		if other, ok := seen[wire]; ok {
+			errs = append(errs, fmt.Errorf("fields %s and %s have the same wire name %q. Rename one of them using a `weaver:\"name\"` struct tag.", other, f.Name(), wire))
+			continue
+		}
+		seen[wire] = f.Name()
+	}
+	return errors.Join(errs...)
+}
+
+// automarshalDecl is a struct type that embeds weaver.AutoMarshal.
+type automarshalDecl struct {
+	t         *types.Named
+	versioned bool // uses the versioned encoding; see runtime/codegen/versioned.go
+}
+
+// findTypeCodecs returns the types whose codecs are registered in the provided
+// file using codegen.RegisterTypeCodec. Every such call must appear directly
+// in an init function, and its type argument must be a named type.
+func findTypeCodecs(pkg *packages.Package, f *ast.File) ([]*types.Named, error) {
+	var codecs []*types.Named
+	var errs []error
+	for _, decl := range f.Decls {
+		fn, isInit := decl.(*ast.FuncDecl)
+		isInit = isInit && fn.Recv == nil && fn.Name.Name == "init"
+		ast.Inspect(decl, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			id := calledIdent(call.Fun)
+			if id == nil || !isRegisterTypeCodec(pkg.TypesInfo.Uses[id]) {
+				return true
+			}
+			if !isInit || !isTopLevelStmt(fn, call) {
+				errs = append(errs, errorf(pkg.Fset, call.Pos(),
+					"codegen.RegisterTypeCodec must be called directly in an init function"))
+				return true
 			}
+			inst, ok := pkg.TypesInfo.Instances[id]
+			if !ok || inst.TypeArgs.Len() != 1 {
+				errs = append(errs, errorf(pkg.Fset, call.Pos(),
+					"cannot determine the type argument of codegen.RegisterTypeCodec"))
+				return true
+			}
+			t, ok := inst.TypeArgs.At(0).(*types.Named)
+			if !ok {
+				errs = append(errs, errorf(pkg.Fset, call.Pos(),
+					"codegen.RegisterTypeCodec: %v is not a named type", formatType(pkg, inst.TypeArgs.At(0))))
+				return true
+			}
+			codecs = append(codecs, t)
+			return true
+		})
+	}
+	return codecs, errors.Join(errs...)
+}
+
+// calledIdent returns the identifier of the function called by a call
+// expression with the provided function expression, e.g., "f" for f(),
+// pkg.f(), and pkg.f[T](). It returns nil for more complex expressions.
+func calledIdent(fun ast.Expr) *ast.Ident {
+	switch x := fun.(type) {
+	case *ast.Ident:
+		return x
+	case *ast.SelectorExpr:
+		return x.Sel
+	case *ast.IndexExpr:
+		return calledIdent(x.X)
+	case *ast.IndexListExpr:
+		return calledIdent(x.X)
+	default:
+		return nil
+	}
+}
 
-			automarshals = append(automarshals, n)
+// isRegisterTypeCodec returns whether the provided object is the
+// codegen.RegisterTypeCodec function.
+func isRegisterTypeCodec(obj types.Object) bool {
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+	return fn.Pkg().Path() == path.Join(weaverPackagePath, "runtime", "codegen") && fn.Name() == "RegisterTypeCodec"
+}
+
+// isTopLevelStmt returns whether the provided call is a statement in the body
+// of fn, rather than being nested inside a closure or a control flow
+// statement that might not run.
+func isTopLevelStmt(fn *ast.FuncDecl, call *ast.CallExpr) bool {
+	if fn.Body == nil {
+		return false
+	}
+	for _, stmt := range fn.Body.List {
+		if e, ok := stmt.(*ast.ExprStmt); ok && e.X == call {
+			return true
 		}
 	}
-	return automarshals, errors.Join(errs...)
+	return false
 }
 
 // extractComponent attempts to extract a component from the provided TypeSpec.
@@ -399,11 +661,13 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 	}
 
 	// Find any weaver.Implements[T] or weaver.WithRouter[T] embedded fields.
-	var intf *types.Named   // The component interface type
-	var router *types.Named // Router type (if any)
-	var isMain bool         // Is intf weaver.Main?
-	var refs []*types.Named // T for which weaver.Ref[T] exists in struct
-	var listeners []string  // Names of all listener fields declared in struct
+	var intf *types.Named     // The component interface type
+	var router *types.Named   // Router type (if any)
+	var observer *types.Named // Observer type (if any)
+	var config types.Type     // Config type (if any)
+	var isMain bool           // Is intf weaver.Main?
+	var refs []*types.Named   // T for which weaver.Ref[T] exists in struct
+	var listeners []listener  // All listener fields declared in struct
 	for _, f := range s.Fields.List {
 		typeAndValue, ok := pkg.TypesInfo.Types[f.Type]
 		if !ok {
@@ -411,8 +675,8 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 		}
 		t := typeAndValue.Type
 
-		if isWeaverRef(t) {
-			// The field f has type weaver.Ref[T].
+		if isWeaverRef(t) || isWeaverBalancedRef(t) {
+			// The field f has type weaver.Ref[T] or weaver.BalancedRef[T, B].
 			arg := t.(*types.Named).TypeArgs().At(0)
 			if isWeaverMain(arg) {
 				return nil, errorf(pkg.Fset, f.Pos(),
@@ -426,7 +690,7 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 			}
 			refs = append(refs, named)
 		} else if isWeaverListener(t) {
-			lis, err := getListenerNamesFromStructField(pkg, f)
+			lis, err := getListenersFromStructField(pkg, f)
 			if err != nil {
 				return nil, err
 			}
@@ -481,6 +745,37 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 					formatType(pkg, named))
 			}
 			router = named
+
+		// The field f is an embedded weaver.WithObserver[T].
+		case isWeaverWithObserver(t):
+			// Check that T is a named type inside the package.
+			arg := t.(*types.Named).TypeArgs().At(0)
+			named, ok := arg.(*types.Named)
+			if !ok {
+				return nil, errorf(pkg.Fset, f.Pos(),
+					"weaver.WithObserver argument %s is not a named type.",
+					formatType(pkg, arg))
+			}
+			if named.Obj().Pkg() != pkg.Types {
+				return nil, errorf(pkg.Fset, f.Pos(),
+					"weaver.WithObserver argument %s is a type outside the current package.",
+					formatType(pkg, named))
+			}
+			observer = named
+
+		// The field f is an embedded weaver.WithPriority[T].
+		case isWeaverWithPriority(t):
+			// Check that T has a Priority(context.Context) int method.
+			arg := t.(*types.Named).TypeArgs().At(0)
+			if !isPrioritizer(arg) {
+				return nil, errorf(pkg.Fset, f.Pos(),
+					"weaver.WithPriority argument %s has no method Priority(context.Context) int.",
+					formatType(pkg, arg))
+			}
+
+		// The field f is an embedded weaver.WithConfig[T].
+		case isWeaverWithConfig(t):
+			config = t.(*types.Named).TypeArgs().At(0)
 		}
 	}
 
@@ -519,10 +814,22 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 		opt.Warn(err)
 	}
 
+	// Find the observed methods, if needed.
+	var observed map[string]bool
+	if observer != nil {
+		var err error
+		if observed, err = observerMethods(pkg, intf, observer); err != nil {
+			return nil, err
+		}
+	}
+
 	comp := &component{
 		intf:      intf,
 		impl:      impl,
 		router:    router,
+		observer:  observer,
+		observed:  observed,
+		config:    config,
 		isMain:    isMain,
 		refs:      refs,
 		listeners: listeners,
@@ -531,7 +838,7 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 	// Find routing information if needed.
 	if comp.router != nil {
 		var err error
-		comp.routingKey, comp.routedMethods, err = routerMethods(pkg, intf, router)
+		comp.routingKey, comp.routedMethods, comp.routeAll, comp.routeCtx, comp.customHash, err = routerMethods(pkg, intf, router)
 		if err != nil {
 			return nil, errorf(pkg.Fset, spec.Pos(), "%w", err)
 		}
@@ -540,9 +847,9 @@ func extractComponent(opt Options, pkg *packages.Package, file *ast.File, tset *
 	return comp, nil
 }
 
-// getListenerNamesFromStructField extracts listener names from the given
-// weaver.Listener field in the component implementation struct.
-func getListenerNamesFromStructField(pkg *packages.Package, f *ast.Field) ([]string, error) {
+// getListenersFromStructField extracts listeners from the given weaver.Listener
+// field in the component implementation struct.
+func getListenersFromStructField(pkg *packages.Package, f *ast.Field) ([]listener, error) {
 	// Try to get the listener name from the struct tag.
 	if f.Tag != nil {
 		tag := reflect.StructTag(strings.TrimPrefix(
@@ -551,27 +858,56 @@ func getListenerNamesFromStructField(pkg *packages.Package, f *ast.Field) ([]str
 			return nil, errorf(pkg.Fset, f.Pos(),
 				"Tag %s repeated for multiple fields", tag)
 		}
-		if name, ok := tag.Lookup("weaver"); ok {
-			if !token.IsIdentifier(name) {
-				return nil, errorf(pkg.Fset, f.Pos(),
-					"Listener tag %s is not a valid Go identifier", tag)
+		if value, ok := tag.Lookup("weaver"); ok {
+			// The tag has the form "[name][,tls][,reuseport]".
+			name, opts, _ := strings.Cut(value, ",")
+			if opts != "" {
+				for _, opt := range strings.Split(opts, ",") {
+					if opt != "tls" && opt != "reuseport" {
+						return nil, errorf(pkg.Fset, f.Pos(),
+							"Listener tag %s has unknown option %q", tag, opt)
+					}
+				}
+			}
+			if name != "" {
+				if !token.IsIdentifier(name) {
+					return nil, errorf(pkg.Fset, f.Pos(),
+						"Listener tag %s is not a valid Go identifier", tag)
+				}
+				field := "Listener" // embedded field
+				if len(f.Names) == 1 {
+					field = f.Names[0].Name
+				}
+				return []listener{{name: name, field: field, pos: f.Pos()}}, nil
 			}
-			return []string{name}, nil
 		}
 		// fallthrough
 	}
 
 	// Get the listener name(s) from the struct field name(s).
 	if f.Names == nil { // embedded field
-		return []string{"Listener"}, nil
+		return []listener{{name: "Listener", field: "Listener", pos: f.Pos()}}, nil
 	}
-	var ret []string
+	var ret []listener
 	for _, fname := range f.Names {
-		ret = append(ret, fname.Name)
+		ret = append(ret, listener{name: fname.Name, field: fname.Name, pos: fname.Pos()})
 	}
 	return ret, nil
 }
 
+// listener is a weaver.Listener field in a component implementation struct.
+type listener struct {
+	name  string    // listener name, e.g., "foo" for `weaver:"foo"`
+	field string    // field name
+	pos   token.Pos // position of the field
+}
+
+// componentListener is a listener along with the component that declares it.
+type componentListener struct {
+	listener
+	comp *component
+}
+
 // component represents a Service Weaver component.
 //
 // A component is divided into an interface and implementation. For example, in
@@ -588,11 +924,17 @@ type component struct {
 	intf          *types.Named    // component interface
 	impl          *types.Named    // component implementation
 	router        *types.Named    // router, or nil if there is no router
+	observer      *types.Named    // observer, or nil if there is no observer
+	observed      map[string]bool // the set of methods with an observer method
 	routingKey    types.Type      // routing key, or nil if there is no router
 	routedMethods map[string]bool // the set of methods with a routing function
+	routeAll      bool            // router has a catch-all Route method
+	routeCtx      bool            // router has a RouteFromContext method
+	customHash    bool            // router has a Hash method for routing keys
+	config        types.Type      // config type, or nil if there is no config
 	isMain        bool            // intf is weaver.Main
 	refs          []*types.Named  // List of T where a weaver.Ref[T] field is in impl struct
-	listeners     []string        // Names of listener fields declared in impl struct
+	listeners     []listener      // Listener fields declared in impl struct
 }
 
 func fullName(t *types.Named) string {
@@ -614,7 +956,27 @@ func (c *component) fullIntfName() string {
 	return fullName(c.intf)
 }
 
+// listenerNames returns the sorted names of the component's listeners.
+func (c *component) listenerNames() []string {
+	names := make([]string, len(c.listeners))
+	for i, lis := range c.listeners {
+		names[i] = lis.name
+	}
+	sort.Strings(names) // generate stable code
+	return names
+}
+
 // methods returns the component interface's methods.
+// streamElem returns the type of the values streamed by the provided method,
+// and whether the method returns a weaver.Stream at all.
+func streamElem(m *types.Func) (types.Type, bool) {
+	sig := m.Type().(*types.Signature)
+	if sig.Results().Len() != 2 || !isWeaverStream(sig.Results().At(0).Type()) {
+		return nil, false
+	}
+	return sig.Results().At(0).Type().(*types.Named).TypeArgs().At(0), true
+}
+
 func (c *component) methods() []*types.Func {
 	underlying := c.intf.Underlying().(*types.Interface)
 	methods := make([]*types.Func, underlying.NumMethods())
@@ -670,8 +1032,8 @@ func validateMethods(pkg *packages.Package, tset *typeSet, intf *types.Named) er
 			if err := errors.Join(tset.checkSerializable(arg.Type())...); err != nil {
 				// TODO(mwhittaker): Print a link to documentation on which types are serializable.
 				errs = append(errs, bad("argument",
-					"Argument %d has type %s, which is not serializable. All arguments, besides the initial context.Context, must be serializable.\n%w",
-					i, formatType(pkg, arg.Type()), err))
+					"Argument %d%s has type %s, which is not serializable. All arguments, besides the initial context.Context, must be serializable.\n%w",
+					i, paramName(arg), formatType(pkg, arg.Type()), err))
 			}
 		}
 
@@ -682,20 +1044,46 @@ func validateMethods(pkg *packages.Package, tset *typeSet, intf *types.Named) er
 			errs = append(errs, bad("return", "The last return must have type error."))
 		}
 
-		// All results but error must be serializable.
+		// All results but error must be serializable, except for a stream of
+		// serializable values.
 		for i := 0; i < t.Results().Len()-1; i++ {
 			res := t.Results().At(i)
+			if isWeaverStream(res.Type()) {
+				if i != 0 || t.Results().Len() != 2 {
+					errs = append(errs, bad("return",
+						"Return %d%s has type %v. A method that returns a weaver.Stream must have results (weaver.Stream[T], error).",
+						i, paramName(res), formatType(pkg, res.Type())))
+					continue
+				}
+				elem := res.Type().(*types.Named).TypeArgs().At(0)
+				if err := errors.Join(tset.checkSerializable(elem)...); err != nil {
+					errs = append(errs, bad("return",
+						"Return %d%s has type %v, but %v is not serializable. The values of a weaver.Stream must be serializable.\n%w",
+						i, paramName(res), formatType(pkg, res.Type()), formatType(pkg, elem), err))
+				}
+				continue
+			}
 			if err := errors.Join(tset.checkSerializable(res.Type())...); err != nil {
 				// TODO(mwhittaker): Print a link to documentation on which types are serializable.
 				errs = append(errs, bad("return",
-					"Return %d has type %v, which is not serializable. All returns, besides the final error, must be serializable.\n%w",
-					i, formatType(pkg, res.Type()), err))
+					"Return %d%s has type %v, which is not serializable. All returns, besides the final error, must be serializable.\n%w",
+					i, paramName(res), formatType(pkg, res.Type()), err))
 			}
 		}
 	}
 	return errors.Join(errs...)
 }
 
+// paramName returns " (name)" for a named parameter or result, and the empty
+// string for an unnamed one. It is used to point at the offending parameter in
+// error messages.
+func paramName(v *types.Var) string {
+	if v.Name() == "" || v.Name() == "_" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", v.Name())
+}
+
 // checkMistypedInit returns an error if the provided component implementation
 // has an Init method that does not have type "func(context.Context) error".
 func checkMistypedInit(pkg *packages.Package, tset *typeSet, impl *types.Named) error {
@@ -741,7 +1129,30 @@ func checkMistypedInit(pkg *packages.Package, tset *typeSet, impl *types.Named)
 //	type fooRouter struct{}
 //	func (fooRouter) A(context.Context) int {...}
 //	func (fooRouter) B(context.Context, int) int {...}
-func routerMethods(pkg *packages.Package, intf, router *types.Named) (types.Type, map[string]bool, error) {
+//
+// Instead of (or in addition to) routing functions for individual methods, a
+// router may have a catch-all Route method that routes every method without
+// a dedicated routing function:
+//
+//	func (fooRouter) Route(ctx context.Context, method string, args ...any) int {...}
+//
+// routerMethods returns whether such a Route method is present. Alternatively,
+// a router may extract the routing key from the context alone, e.g., when the
+// key is a tenant id propagated with the call:
+//
+//	func (fooRouter) RouteFromContext(ctx context.Context) int {...}
+//
+// routerMethods returns whether such a RouteFromContext method is present. A
+// router can't have both a Route and a RouteFromContext method. A router may
+// also have a Hash method that hashes routing keys, instead of the default
+// hash:
+//
+//	func (fooRouter) Hash(key int) uint64 {...}
+//
+// routerMethods returns whether such a Hash method is present. A component
+// method named Route is routed by a router method named Route with identical
+// arguments, as usual.
+func routerMethods(pkg *packages.Package, intf, router *types.Named) (types.Type, map[string]bool, bool, bool, bool, error) {
 	underlying := intf.Underlying().(*types.Interface)
 	componentMethods := map[string]*types.Signature{}
 	for i := 0; i < underlying.NumMethods(); i++ {
@@ -751,62 +1162,266 @@ func routerMethods(pkg *packages.Package, intf, router *types.Named) (types.Type
 
 	// Verify that every router method corresponds to a component method. This
 	// is so we avoid errors where one has been renamed but not the other.
-	// Also check that they all have the same return type.
+	// Also check that they all have the same return type. We report every
+	// mismatched method, not just the first, so that a rename that affects
+	// several methods can be fixed in one go.
+	var errs []error
 	var routingKey types.Type
 	routedMethods := map[string]bool{}
+	var routeAll, routeCtx *types.Func
+	var hash *types.Func
 	for i, n := 0, router.NumMethods(); i < n; i++ {
 		m := router.Method(i)
 		pos := m.Origin().Pos()
-		componentMethod, ok := componentMethods[m.Name()]
-		if !ok {
-			return nil, nil, errorf(pkg.Fset, pos,
-				"Routing function %q does not match any method of %q.",
-				m.Name(), intf.Obj().Name())
-		}
 		mt := m.Type().(*types.Signature)
-
-		// Router method args must match component method args.
-		if !types.Identical(mt.Params(), componentMethod.Params()) {
-			return nil, nil, errorf(pkg.Fset, pos,
-				"Component %q method arguments %s do not match router method arguments %s",
-				intf.Obj().Name(), formatType(pkg, componentMethod.Params()), formatType(pkg, mt.Params()))
+		componentMethod, ok := componentMethods[m.Name()]
+		switch {
+		case !ok && isCatchAllRouter(m):
+			routeAll = m
+		case !ok && isContextRouter(m):
+			routeCtx = m
+		case !ok && isHashRouter(m):
+			// Checked below, once the routing key is known.
+			hash = m
+			continue
+		case !ok:
+			// Likely a typo, or a method that was renamed or removed.
+			var b strings.Builder
+			fmt.Fprintf(&b, "Routing function %q does not match any method of %q.", m.Name(), intf.Obj().Name())
+			unmatched := unmatchedMethods(underlying, router)
+			if guess := closestName(m.Name(), unmatched); guess != "" {
+				fmt.Fprintf(&b, " Did you mean %q?", guess)
+			}
+			if len(unmatched) > 0 {
+				fmt.Fprintf(&b, " Methods of %q without a routing function: %s.", intf.Obj().Name(), strings.Join(unmatched, ", "))
+			}
+			errs = append(errs, errorf(pkg.Fset, pos, "%s", b.String()))
+			continue
+		case !types.Identical(mt.Params(), componentMethod.Params()) || mt.Variadic() != componentMethod.Variadic():
+			// Router method args must match component method args.
+			errs = append(errs, errorf(pkg.Fset, pos,
+				"Routing function %q has signature %s, but method %q of %q has signature %s. A routing function must take the same arguments as the method it routes.",
+				m.Name(), formatType(pkg, mt), m.Name(), intf.Obj().Name(), formatType(pkg, componentMethod)))
+			continue
+		default:
+			routedMethods[m.Name()] = true
 		}
 
 		// All router methods must have the same routable return type.
 		if mt.Results().Len() != 1 {
-			return nil, nil, errorf(pkg.Fset, pos,
+			errs = append(errs, errorf(pkg.Fset, pos,
 				"Routing function %q must return exactly one value (it returns %d)",
-				m.Name(), mt.Results().Len())
+				m.Name(), mt.Results().Len()))
+			continue
 		}
 		ret := mt.Results().At(0).Type()
-		if i == 0 {
+		if routingKey == nil {
 			if !isValidRouterType(ret) {
-				return nil, nil, errorf(pkg.Fset, pos,
+				errs = append(errs, errorf(pkg.Fset, pos,
 					"Router method %q has invalid routing key type %q. A routing key type should be an integer, float, string, or a struct with every field being an integer, float, or string.",
-					m.Name(), formatType(pkg, ret))
+					m.Name(), formatType(pkg, ret)))
+				continue
+			}
+			if excluded := excludedFields(ret); len(excluded) > 0 {
+				errs = append(errs, errorf(pkg.Fset, pos,
+					"Router method %q has routing key type %q, which excludes field %s from marshalling with a `weaver:\"-\"` struct tag. Every field of a routing key is part of the key, so none can be excluded.",
+					m.Name(), formatType(pkg, ret), excluded[0]))
+				continue
 			}
 			routingKey = ret
 		} else if !types.Identical(ret, routingKey) {
-			return nil, nil, errorf(pkg.Fset, pos,
+			errs = append(errs, errorf(pkg.Fset, pos,
 				"Return type of %q (%s) does not match previously seen routing key type (%s)",
-				m.Name(), formatType(pkg, ret), formatType(pkg, routingKey))
+				m.Name(), formatType(pkg, ret), formatType(pkg, routingKey)))
 		}
-		routedMethods[m.Name()] = true
+	}
+	if routingKey != nil && hash != nil {
+		if key := hash.Type().(*types.Signature).Params().At(0).Type(); !types.Identical(key, routingKey) {
+			errs = append(errs, errorf(pkg.Fset, hash.Origin().Pos(),
+				"Hash method takes a %s, but the routing key type is %s. A router's Hash method must take a routing key.",
+				formatType(pkg, key), formatType(pkg, routingKey)))
+		}
+	}
+	if routeAll != nil && routeCtx != nil {
+		errs = append(errs, errorf(pkg.Fset, routeCtx.Origin().Pos(),
+			"Router %s has both a Route and a RouteFromContext method. A router can route the methods without a routing function using only one of them.",
+			router.Obj().Name()))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, false, false, false, err
 	}
 
 	if routingKey == nil {
-		return nil, nil, errorf(pkg.Fset, router.Obj().Pos(),
+		return nil, nil, false, false, false, errorf(pkg.Fset, router.Obj().Pos(),
 			"No routing methods found on declarated router type (%s) for component %q",
 			router.Obj().Name(), intf.Obj().Name())
 	}
-	return routingKey, routedMethods, nil
+	return routingKey, routedMethods, routeAll != nil, routeCtx != nil, hash != nil, nil
+}
+
+// observerMethods returns the names of the methods of the component interface
+// intf that are intercepted by a method of observer. An observer method
+// intercepts the component method with the same name, and must take the
+// component method's arguments, with an additional argument of type intf
+// right after the context, and return the component method's results. For
+// example, a Foo method of the following component
+//
+//	type Foo interface {
+//	    Foo(context.Context, int) (string, error)
+//	}
+//
+// is intercepted by an observer method like this:
+//
+//	func (*fooObserver) Foo(ctx context.Context, next Foo, x int) (string, error) {...}
+//
+// Observer methods that don't share a name with a component method are
+// ignored.
+func observerMethods(pkg *packages.Package, intf, observer *types.Named) (map[string]bool, error) {
+	underlying := intf.Underlying().(*types.Interface)
+	observed := map[string]bool{}
+	for i := 0; i < underlying.NumMethods(); i++ {
+		m := underlying.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(observer), true, pkg.Types, m.Name())
+		om, ok := obj.(*types.Func)
+		if !ok {
+			continue
+		}
+
+		// Construct the expected signature of the observer method.
+		mt := m.Type().(*types.Signature)
+		params := []*types.Var{mt.Params().At(0), types.NewParam(token.NoPos, pkg.Types, "next", intf)}
+		for j := 1; j < mt.Params().Len(); j++ {
+			params = append(params, mt.Params().At(j))
+		}
+		want := types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), mt.Results(), mt.Variadic())
+		if got := om.Type().(*types.Signature); !types.Identical(got, want) {
+			return nil, errorf(pkg.Fset, om.Pos(),
+				"Observer method %q has signature %s, but should have signature %s. An observer method must take the arguments of the method it observes, with an additional argument of type %s after the context, and return the same results.",
+				m.Name(), formatType(pkg, got), formatType(pkg, want), formatType(pkg, intf))
+		}
+		observed[m.Name()] = true
+	}
+	return observed, nil
+}
+
+// unmatchedMethods returns the sorted names of the methods of intf for which
+// router does not have a routing function.
+func unmatchedMethods(intf *types.Interface, router *types.Named) []string {
+	routed := map[string]bool{}
+	for i := 0; i < router.NumMethods(); i++ {
+		routed[router.Method(i).Name()] = true
+	}
+	var unmatched []string
+	for i := 0; i < intf.NumMethods(); i++ {
+		if name := intf.Method(i).Name(); !routed[name] {
+			unmatched = append(unmatched, name)
+		}
+	}
+	sort.Strings(unmatched)
+	return unmatched
+}
+
+// closestName returns the candidate that is closest to name, if it is close
+// enough to plausibly be a misspelling of name. Otherwise, it returns "".
+func closestName(name string, candidates []string) string {
+	const maxDistance = 2
+	best, bestDistance := "", maxDistance+1
+	for _, c := range candidates {
+		d := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost // substitution
+			if d := prev[j] + 1; d < curr[j] {
+				curr[j] = d // deletion
+			}
+			if d := curr[j-1] + 1; d < curr[j] {
+				curr[j] = d // insertion
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// isOrdered returns whether t supports the < operator, i.e., whether its
+// underlying type is an integer, float, or string.
+func isOrdered(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsOrdered != 0
+}
+
+// isCatchAllRouter returns true iff m has the signature of a catch-all router
+// method, i.e., Route(context.Context, string, ...any).
+func isCatchAllRouter(m *types.Func) bool {
+	if m.Name() != "Route" {
+		return false
+	}
+	sig := m.Type().(*types.Signature)
+	params := sig.Params()
+	if !sig.Variadic() || params.Len() != 3 {
+		return false
+	}
+	if !isContext(params.At(0).Type()) {
+		return false
+	}
+	if b, ok := params.At(1).Type().(*types.Basic); !ok || b.Kind() != types.String {
+		return false
+	}
+	elem := params.At(2).Type().(*types.Slice).Elem()
+	i, ok := elem.Underlying().(*types.Interface)
+	return ok && i.Empty()
+}
+
+// isContextRouter returns true iff m has the signature of a router's
+// RouteFromContext method, i.e., RouteFromContext(context.Context) K for some
+// type K. It doesn't check that K is a valid routing key.
+func isContextRouter(m *types.Func) bool {
+	if m.Name() != "RouteFromContext" {
+		return false
+	}
+	params := m.Type().(*types.Signature).Params()
+	return params.Len() == 1 && isContext(params.At(0).Type())
+}
+
+// isHashRouter returns true iff m has the signature of a router's Hash
+// method, i.e., func(K) uint64 for some type K. It doesn't check that K is the
+// routing key.
+func isHashRouter(m *types.Func) bool {
+	if m.Name() != "Hash" {
+		return false
+	}
+	sig := m.Type().(*types.Signature)
+	if sig.Params().Len() != 1 || sig.Results().Len() != 1 {
+		return false
+	}
+	b, ok := sig.Results().At(0).Type().(*types.Basic)
+	return ok && b.Kind() == types.Uint64
 }
 
 type printFn func(format string, args ...interface{})
 
 // TODO(mwhittaker): Have generate return an error.
 func (g *generator) generate() error {
-	if len(g.components)+g.tset.automarshalCandidates.Len() == 0 {
+	if len(g.components)+g.tset.automarshalCandidates.Len()+g.tset.genericAutomarshals.Len() == 0 {
 		// There's nothing to generate.
 		return nil
 	}
@@ -829,6 +1444,7 @@ func (g *generator) generate() error {
 		g.generateLocalStubs(fn)
 		g.generateClientStubs(fn)
 		g.generateServerStubs(fn)
+		g.generateInterceptStubs(fn)
 		g.generateAutoMarshalMethods(fn)
 		g.generateRouterMethods(fn)
 		g.generateEncDecMethods(fn)
@@ -855,11 +1471,27 @@ func (g *generator) generate() error {
 		fn := func(format string, args ...interface{}) {
 			fmt.Fprintln(&header, fmt.Sprintf(format, args...))
 		}
-		g.generateImports(fn)
+		g.generateImports(fn, g.tset)
 	}
 
 	// Create a generated file.
 	filename := filepath.Join(g.pkgDir(), generatedCodeFile)
+	if err := writeGeneratedFile(filename, header, body); err != nil {
+		return err
+	}
+
+	if err := g.generateConfigSchemas(); err != nil {
+		return err
+	}
+	if err := g.generateServiceSchema(); err != nil {
+		return err
+	}
+	return g.generateMocks()
+}
+
+// writeGeneratedFile formats the provided header and body and writes them to
+// the provided file.
+func writeGeneratedFile(filename string, header, body bytes.Buffer) error {
 	dst := files.NewWriter(filename)
 	defer dst.Cleanup()
 
@@ -905,15 +1537,16 @@ func (g *generator) componentRef(comp *component) string {
 	return comp.intfName() // We already checked that interface is in the same package.
 }
 
-// generateImports generates code to import all the dependencies.
-func (g *generator) generateImports(p printFn) {
+// generateImports generates code to import all the dependencies recorded in
+// the provided type set.
+func (g *generator) generateImports(p printFn, tset *typeSet) {
 	p(`// Code generated by "weaver generate". DO NOT EDIT.`)
 	p("//go:build !ignoreWeaverGen")
 	p("")
 	p("package %s", g.pkg.Name)
 	p("")
 	p(`import (`)
-	for _, imp := range g.tset.imports() {
+	for _, imp := range tset.imports() {
 		if imp.alias == "" {
 			p(`	%s`, strconv.Quote(imp.path))
 		} else {
@@ -1051,6 +1684,10 @@ func (g *generator) generateRouterChecks(p printFn) {
 		for _, m := range unrouted {
 			p(`var _ = (&%s{}).%s // unrouted`, checker, m)
 		}
+		if c.routeCtx {
+			// e.g., var _ weaver.ContextRouter[string] = (*router)(nil)
+			p(`var _ %s[%s] = (*%s)(nil)`, g.weaver().qualify("ContextRouter"), g.tset.genTypeString(c.routingKey), g.tset.genTypeString(c.router))
+		}
 	}
 }
 
@@ -1081,13 +1718,21 @@ func (g *generator) generateRegisteredComponents(p printFn) {
 
 		// E.g.,
 		//   func(impl any, caller string, tracer trace.Tracer) any {
-		//       return foo_local_stub{impl: impl.(Foo), tracer: tracer, ...}
+		//       return foo_local_stub{impl: impl.(Foo), caller: caller, tracer: tracer, ...}
 		//   }
 		b.Reset()
 		for _, m := range comp.methods() {
 			emitMetricInitializer(m, false)
 		}
-		localStubFn := fmt.Sprintf(`func(impl any, caller string, tracer %v) any { return %s_local_stub{impl: impl.(%s), tracer: tracer%s } }`, g.trace().qualify("Tracer"), notExported(name), g.componentRef(comp), b.String())
+		if comp.observer != nil {
+			fmt.Fprintf(&b, ", observer: %s", g.observer(comp))
+		}
+		localStub := fmt.Sprintf(`%s_local_stub{impl: impl.(%s), caller: caller, tracer: tracer%s }`, notExported(name), g.componentRef(comp), b.String())
+		if !comp.isMain {
+			// E.g., foo_intercept(foo_local_stub{...}, codegen.LocalInterceptor(impl), ...)
+			localStub = fmt.Sprintf(`%s_intercept(%s, %s(impl), %s{Caller: caller, Component: %q})`, notExported(name), localStub, g.codegen().qualify("LocalInterceptor"), g.codegen().qualify("Call"), comp.fullIntfName())
+		}
+		localStubFn := fmt.Sprintf(`func(impl any, caller string, tracer %v) any { return %s }`, g.trace().qualify("Tracer"), localStub)
 
 		// E.g.,
 		//   func(stub *codegen.Stub, caller string) any {
@@ -1097,14 +1742,26 @@ func (g *generator) generateRegisteredComponents(p printFn) {
 		for _, m := range comp.methods() {
 			emitMetricInitializer(m, true)
 		}
-		clientStubFn := fmt.Sprintf(`func(stub %s, caller string) any { return %s_client_stub{stub: stub%s } }`,
-			g.codegen().qualify("Stub"), notExported(name), b.String())
+		clientStub := fmt.Sprintf(`%s_client_stub{stub: stub%s }`, notExported(name), b.String())
+		if !comp.isMain {
+			clientStub = fmt.Sprintf(`%s_intercept(%s, %s(), %s{Caller: caller, Component: %q, Remote: true})`, notExported(name), clientStub, g.codegen().qualify("ClientInterceptor"), g.codegen().qualify("Call"), comp.fullIntfName())
+		}
+		clientStubFn := fmt.Sprintf(`func(stub %s, caller string) any { return %s }`,
+			g.codegen().qualify("Stub"), clientStub)
 
 		// E.g.,
 		//   func(impl any, addLoad func(uint64, float64)) codegen.Server {
-		//       return foo_server_stub{impl: impl.(Foo), addLoad: addLoad}
+		//       return foo_server_stub{impl: impl.(Foo), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		//   }
-		serverStubFn := fmt.Sprintf(`func(impl any, addLoad func(uint64, float64)) %s { return %s_server_stub{impl: impl.(%s), addLoad: addLoad } }`, g.codegen().qualify("Server"), notExported(name), g.componentRef(comp))
+		b.Reset()
+		if comp.observer != nil {
+			fmt.Fprintf(&b, ", observer: %s", g.observer(comp))
+		}
+		serverImpl := fmt.Sprintf(`impl.(%s)`, g.componentRef(comp))
+		if !comp.isMain {
+			serverImpl = fmt.Sprintf(`%s_intercept(%s, %s(impl), %s{Component: %q, Remote: true})`, notExported(name), serverImpl, g.codegen().qualify("ServerInterceptor"), g.codegen().qualify("Call"), comp.fullIntfName())
+		}
+		serverStubFn := fmt.Sprintf(`func(impl any, addLoad func(uint64, float64)) %s { return %s_server_stub{impl: %s, addLoad: addLoad, middleware: &%s{}%s } }`, g.codegen().qualify("Server"), notExported(name), serverImpl, g.codegen().qualify("ServerMiddlewares"), b.String())
 
 		var refData strings.Builder
 		myName := comp.fullIntfName()
@@ -1112,7 +1769,7 @@ func (g *generator) generateRegisteredComponents(p printFn) {
 			refData.WriteString(codegen.MakeEdgeString(myName, fullName(ref)))
 		}
 		if len(comp.listeners) > 0 {
-			refData.WriteString(codegen.MakeListenersString(myName, comp.listeners))
+			refData.WriteString(codegen.MakeListenersString(myName, comp.listenerNames()))
 		}
 
 		// E.g.,
@@ -1133,7 +1790,7 @@ func (g *generator) generateRegisteredComponents(p printFn) {
 		}
 		if len(comp.listeners) > 0 {
 			listeners := make([]string, len(comp.listeners))
-			for i, lis := range comp.listeners {
+			for i, lis := range comp.listenerNames() {
 				listeners[i] = fmt.Sprintf("%q", lis)
 			}
 			p(`		Listeners: []string{%s},`, strings.Join(listeners, ", "))
@@ -1141,6 +1798,9 @@ func (g *generator) generateRegisteredComponents(p printFn) {
 		p(`		LocalStubFn: %s,`, localStubFn)
 		p(`		ClientStubFn: %s,`, clientStubFn)
 		p(`		ServerStubFn: %s,`, serverStubFn)
+		if !comp.isMain {
+			p(`		InterceptFn: func(next any, interceptor %s, call %s) any { return %s_intercept(next.(%s), interceptor, call) },`, g.codegen().qualify("Interceptor"), g.codegen().qualify("Call"), notExported(name), g.componentRef(comp))
+		}
 		p(`		RefData: %s,`, strconv.Quote(refData.String()))
 		p(`	})`)
 	}
@@ -1159,6 +1819,10 @@ func (g *generator) generateLocalStubs(p printFn) {
 		p(``)
 		p(`type %s struct{`, stub)
 		p(`	impl %s`, g.componentRef(comp))
+		if comp.observer != nil {
+			p(`	observer *%s`, g.tset.genTypeString(comp.observer))
+		}
+		p(`	caller string`)
 		p(`	tracer %s`, g.trace().qualify("Tracer"))
 		for _, m := range comp.methods() {
 			p(`	%sMetrics *%s`, notExported(m.Name()), g.codegen().qualify("MethodMetrics"))
@@ -1205,7 +1869,128 @@ func (g *generator) generateLocalStubs(p printFn) {
 			}
 			argList := b.String()
 			p(``)
-			p(`	return s.impl.%s(%s)`, m.Name(), argList)
+			p(`	ctx = %s(ctx, s.caller, %q)`, g.codegen().qualify("WithLocalCall"), comp.fullIntfName())
+			if comp.observed[m.Name()] {
+				p(`	return s.observer.%s(%s)`, m.Name(), strings.Replace(argList, "ctx", "ctx, s.impl", 1))
+			} else {
+				p(`	return s.impl.%s(%s)`, m.Name(), argList)
+			}
+			p(`}`)
+		}
+	}
+}
+
+// generateInterceptStubs generates intercept stubs for the components. An
+// intercept stub wraps another implementation of a component interface (e.g.,
+// a local, client, or server stub) and passes every method call through an
+// interceptor. For example, given the following component interface:
+//
+//	type Foo interface {
+//	    Get(ctx context.Context, key string) (string, error)
+//	}
+//
+// generateInterceptStubs generates the following code:
+//
+//	type foo_intercept_stub struct {
+//	    next        Foo
+//	    interceptor codegen.Interceptor
+//	    call        codegen.Call
+//	}
+//
+//	func foo_intercept(next Foo, interceptor codegen.Interceptor, call codegen.Call) Foo {
+//	    if interceptor == nil {
+//	        return next
+//	    }
+//	    return foo_intercept_stub{next: next, interceptor: interceptor, call: call}
+//	}
+//
+//	func (s foo_intercept_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+//	    results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+//	        r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0))
+//	        return []any{r0}, err
+//	    })
+//	    return codegen.Result[string](results, 0), err
+//	}
+func (g *generator) generateInterceptStubs(p printFn) {
+	var components []*component
+	for _, comp := range g.components {
+		// weaver.Main has no methods, so there is nothing to intercept.
+		if !comp.isMain {
+			components = append(components, comp)
+		}
+	}
+	if len(components) == 0 {
+		return
+	}
+
+	p(``)
+	p(``)
+	p(`// Intercept stub implementations.`)
+
+	interceptor := g.codegen().qualify("Interceptor")
+	call := g.codegen().qualify("Call")
+	for _, comp := range components {
+		name := notExported(comp.intfName())
+		stub := name + "_intercept_stub"
+		intf := g.componentRef(comp)
+		p(``)
+		p(`type %s struct{`, stub)
+		p(`	next %s`, intf)
+		p(`	interceptor %s`, interceptor)
+		p(`	call %s`, call)
+		p(`}`)
+		p(``)
+		p(`// Check that %s implements the %s interface.`, stub, g.tset.genTypeString(comp.intf))
+		p(`var _ %s = (*%s)(nil)`, g.tset.genTypeString(comp.intf), stub)
+		p(``)
+		p(`// %s_intercept returns next, wrapped in an intercept stub if interceptor`, name)
+		p(`// is not nil.`)
+		p(`func %s_intercept(next %s, interceptor %s, call %s) %s {`, name, intf, interceptor, call, intf)
+		p(`	if interceptor == nil {`)
+		p(`		return next`)
+		p(`	}`)
+		p(`	return %s{next: next, interceptor: interceptor, call: call}`, stub)
+		p(`}`)
+
+		for _, m := range comp.methods() {
+			mt := m.Type().(*types.Signature)
+
+			// Box the arguments, e.g., []any{a0, a1}.
+			var args, unboxed []string
+			for i := 1; i < mt.Params().Len(); i++ {
+				args = append(args, fmt.Sprintf("a%d", i-1))
+				arg := fmt.Sprintf("%s[%s](args, %d)", g.codegen().qualify("Arg"), g.tset.genTypeString(mt.Params().At(i).Type()), i-1)
+				if mt.Variadic() && i == mt.Params().Len()-1 {
+					arg += "..."
+				}
+				unboxed = append(unboxed, arg)
+			}
+			boxed := "nil"
+			if len(args) > 0 {
+				boxed = fmt.Sprintf("[]any{%s}", strings.Join(args, ", "))
+			}
+			callArgs := strings.Join(append([]string{"ctx"}, unboxed...), ", ")
+
+			var results, unboxedResults []string
+			for i := 0; i < mt.Results().Len()-1; i++ {
+				results = append(results, fmt.Sprintf("r%d", i))
+				unboxedResults = append(unboxedResults, fmt.Sprintf("%s[%s](results, %d)", g.codegen().qualify("Result"), g.tset.genTypeString(mt.Results().At(i).Type()), i))
+			}
+
+			p(``)
+			p(`func (s %s) %s(%s) (%s) {`, stub, m.Name(), g.args(mt), g.returns(mt))
+			if len(results) == 0 {
+				p(`	_, err = %s(ctx, s.interceptor, s.call, %q, %s, func(ctx context.Context, args []any) ([]any, error) {`, g.codegen().qualify("Intercept"), m.Name(), boxed)
+				p(`		return nil, s.next.%s(%s)`, m.Name(), callArgs)
+				p(`	})`)
+				p(`	return err`)
+			} else {
+				p(`	results, err := %s(ctx, s.interceptor, s.call, %q, %s, func(ctx context.Context, args []any) ([]any, error) {`, g.codegen().qualify("Intercept"), m.Name(), boxed)
+				p(`		%s, err := s.next.%s(%s)`, strings.Join(results, ", "), m.Name(), callArgs)
+				p(`		return []any{%s}, err`, strings.Join(results, ", "))
+				p(`	})`)
+				p(`	return %s, err`, strings.Join(unboxedResults, ", "))
+			}
 			p(`}`)
 		}
 	}
@@ -1246,13 +2031,19 @@ func (g *generator) generateClientStubs(p printFn) {
 
 		for _, m := range comp.methods() {
 			mt := m.Type().(*types.Signature)
+			elem, streaming := streamElem(m)
 			p(``)
 			p(`func (s %s) %s(%s) (%s) {`, stub, m.Name(), g.args(mt), g.returns(mt))
 
 			p(`	// Update metrics.`)
 			p(`	var requestBytes, replyBytes int`)
+			p(`	var transportErr bool`)
 			p(`	begin := s.%sMetrics.Begin()`, notExported(m.Name()))
-			p(`	defer func() { s.%sMetrics.End(begin, err != nil, requestBytes, replyBytes) }()`, notExported(m.Name()))
+			if !streaming {
+				p(`	defer func() {`)
+				p(`		s.%sMetrics.EndWithKind(begin, %s(err, transportErr), requestBytes, replyBytes)`, notExported(m.Name()), g.codegen().qualify("ErrorKindOf"))
+				p(`	}()`)
+			}
 			p(``)
 
 			// Create a child span iff tracing is enabled in ctx.
@@ -1262,6 +2053,21 @@ func (g *generator) generateClientStubs(p printFn) {
 			p(`		ctx, span = s.stub.Tracer().Start(ctx, "%s.%s.%s", trace.WithSpanKind(trace.SpanKindClient))`, g.pkg.Name, comp.intfName(), m.Name())
 			p(`	}`)
 
+			if streaming {
+				// A streaming call ends when the caller is done iterating
+				// over the stream, so the metrics and span are updated by end.
+				p(``)
+				p(`	// end records the outcome of the call.`)
+				p(`	end := func(err error) {`)
+				p(`		s.%sMetrics.EndWithKind(begin, %s(err, transportErr), requestBytes, replyBytes)`, notExported(m.Name()), g.codegen().qualify("ErrorKindOf"))
+				p(`		if err != nil {`)
+				p(`			span.RecordError(err)`)
+				p(`			span.SetStatus(%s, err.Error())`, g.codes().qualify("Error"))
+				p(`		}`)
+				p(`		span.End()`)
+				p(`	}`)
+			}
+
 			// Handle cleanup.
 			p(``)
 			p(`	defer func() {`)
@@ -1269,16 +2075,25 @@ func (g *generator) generateClientStubs(p printFn) {
 			p(`		if err == nil {`)
 			p(`			err = %s(recover())`, g.codegen().qualify("CatchPanics"))
 			p(`			if err != nil {`)
+			p(`				transportErr = true`)
 			p(`				err = %s(%s, err)`, g.errorsPackage().qualify("Join"), g.weaver().qualify("RemoteCallError"))
 			p(`			}`)
 			p(`		}`)
 			p(``)
-			p(`		if err != nil {`)
-			p(`			span.RecordError(err)`)
-			p(`			span.SetStatus(%s, err.Error())`, g.codes().qualify("Error"))
-			p(`		}`)
-			p(`		span.End()`)
-			p(``)
+			if streaming {
+				p(`		// If the remote method started streaming, the call ends once the`)
+				p(`		// caller is done iterating over the stream.`)
+				p(`		if r0 == nil {`)
+				p(`			end(err)`)
+				p(`		}`)
+			} else {
+				p(`		if err != nil {`)
+				p(`			span.RecordError(err)`)
+				p(`			span.SetStatus(%s, err.Error())`, g.codes().qualify("Error"))
+				p(`		}`)
+				p(`		span.End()`)
+				p(``)
+			}
 			p(`	}()`)
 			p(``)
 
@@ -1322,17 +2137,11 @@ func (g *generator) generateClientStubs(p printFn) {
 			}
 
 			// Set the routing key, if there is one.
-			if comp.routedMethods[m.Name()] {
+			if key := g.routingKey(comp, m); key != "" {
 				p(``)
 				p(`	// Set the shardKey.`)
 				p(`     var r %s`, g.tset.genTypeString(comp.router))
-				n := mt.Params().Len()
-				args := make([]string, n)
-				args[0] = "ctx"
-				for i := 1; i < n; i++ {
-					args[i] = fmt.Sprintf("a%d", i-1)
-				}
-				p(`	shardKey := _hash%s(r.%s(%s))`, exported(comp.intfName()), m.Name(), strings.Join(args, ", "))
+				p(`	shardKey := _hash%s(%s)`, exported(comp.intfName()), key)
 			} else {
 				p(`	var shardKey uint64`)
 			}
@@ -1343,12 +2152,24 @@ func (g *generator) generateClientStubs(p printFn) {
 			data := "nil"
 			if mt.Params().Len() > 1 {
 				data = "enc.Data()"
-				p(`	requestBytes = len(enc.Data())`)
+				p(`	requestBytes = enc.Size()`)
+			}
+			if streaming {
+				g.generateStreamCall(p, methodIndex[m.Name()], data, elem)
+				p(`}`)
+				continue
 			}
 			p(`	var results []byte`)
-			p(`	results, err = s.stub.Run(ctx, %d, %s, shardKey)`, methodIndex[m.Name()], data)
+			if mt.Params().Len() > 1 {
+				// Pass the encoded arguments as a list of buffers, so that
+				// large byte slices are sent without being copied.
+				p(`	results, err = s.stub.RunBuffers(ctx, %d, enc.Buffers(), shardKey)`, methodIndex[m.Name()])
+			} else {
+				p(`	results, err = s.stub.Run(ctx, %d, nil, shardKey)`, methodIndex[m.Name()])
+			}
 			p(`	replyBytes = len(results)`)
 			p(`	if err != nil {`)
+			p(`		transportErr = true`)
 			p(`		err = %s(%s, err)`, g.errorsPackage().qualify("Join"), g.weaver().qualify("RemoteCallError"))
 			p(`		return`)
 			p(`	}`)
@@ -1361,7 +2182,7 @@ func (g *generator) generateClientStubs(p printFn) {
 			for i := 0; i < mt.Results().Len()-1; i++ { // Skip final error
 				rt := mt.Results().At(i).Type()
 				res := fmt.Sprintf("r%d", i)
-				if x, ok := rt.(*types.Pointer); ok && (g.tset.isProto(x) || g.tset.hasMarshalBinary(x)) {
+				if x, ok := rt.(*types.Pointer); ok && g.tset.isCustomMarshaled(x) {
 					// To decode a pointer *t where t is a proto or
 					// BinaryUnmarshaler, we need to instantiate a zero value
 					// of type t before calling the appropriate decoding
@@ -1382,6 +2203,85 @@ func (g *generator) generateClientStubs(p printFn) {
 	}
 }
 
+// generateStreamCall generates the code in a client stub that calls a remote
+// method that returns a stream of values of type elem. See
+// generateClientStubs.
+func (g *generator) generateStreamCall(p printFn, method int, data string, elem types.Type) {
+	p(`	var stream %s`, g.codegen().qualify("StreamReader"))
+	p(`	stream, err = s.stub.RunStream(ctx, %d, %s, shardKey)`, method, data)
+	p(`	if err != nil {`)
+	p(`		transportErr = true`)
+	p(`		err = %s(%s, err)`, g.errorsPackage().qualify("Join"), g.weaver().qualify("RemoteCallError"))
+	p(`		return`)
+	p(`	}`)
+	p(``)
+	p(`	// Wait for the remote method to start streaming.`)
+	p(`	if _, ok := stream.Recv(); !ok {`)
+	p(`		// The remote method returned an error instead.`)
+	p(`		var results []byte`)
+	p(`		results, err = stream.Result()`)
+	p(`		replyBytes = len(results)`)
+	p(`		if err != nil {`)
+	p(`			transportErr = true`)
+	p(`			err = %s(%s, err)`, g.errorsPackage().qualify("Join"), g.weaver().qualify("RemoteCallError"))
+	p(`			return`)
+	p(`		}`)
+	p(`		dec := %s(results)`, g.codegen().qualify("NewDecoder"))
+	p(`		err = dec.Error()`)
+	p(`		return`)
+	p(`	}`)
+	p(``)
+	p(`	// Decode the streamed values.`)
+	p(`	r0 = %s(stream, func(dec *%s) (x %s) {`, g.codegen().qualify("ReadStream"), g.codegen().qualify("Decoder"), g.tset.genTypeString(elem))
+	if x, ok := elem.(*types.Pointer); ok && g.tset.isCustomMarshaled(x) {
+		// See the decoding of results in generateClientStubs.
+		p(`		var tmp %s`, g.tset.genTypeString(x.Elem()))
+		p(`		%s`, g.decode("dec", ref("tmp"), x.Elem()))
+		p(`		x = %s`, ref("tmp"))
+	} else {
+		p(`		%s`, g.decode("dec", ref("x"), elem))
+	}
+	p(`		return`)
+	p(`	}, func(n int, results []byte, err error) error {`)
+	p(`		replyBytes += n`)
+	p(`		if err != nil {`)
+	p(`			transportErr = true`)
+	p(`			err = %s(%s, err)`, g.errorsPackage().qualify("Join"), g.weaver().qualify("RemoteCallError"))
+	p(`		} else if results != nil {`)
+	p(`			dec := %s(results)`, g.codegen().qualify("NewDecoder"))
+	p(`			err = dec.Error()`)
+	p(`		}`)
+	p(`		end(err)`)
+	p(`		return err`)
+	p(`	})`)
+	p(`	return`)
+}
+
+// routingKey returns an expression that computes the routing key for a call
+// to the provided method of comp, or the empty string if the method is not
+// routed. The expression refers to a router value r, a context ctx, and the
+// method arguments a0, a1, and so on.
+func (g *generator) routingKey(comp *component, m *types.Func) string {
+	sig := m.Type().(*types.Signature)
+	var args strings.Builder
+	for i := 1; i < sig.Params().Len(); i++ { // Skip initial context.Context
+		fmt.Fprintf(&args, ", a%d", i-1)
+		if comp.routedMethods[m.Name()] && sig.Variadic() && i == sig.Params().Len()-1 {
+			args.WriteString("...")
+		}
+	}
+	switch {
+	case comp.routedMethods[m.Name()]:
+		return fmt.Sprintf("r.%s(ctx%s)", m.Name(), args.String())
+	case comp.routeAll:
+		return fmt.Sprintf("r.Route(ctx, %q%s)", m.Name(), args.String())
+	case comp.routeCtx:
+		return "r.RouteFromContext(ctx)"
+	default:
+		return ""
+	}
+}
+
 // args returns a textual representation of the arguments of the provided
 // signature. The first argument must be a context.Context. The returned code
 // names the first argument ctx and all subsequent arguments a0, a1, and so on.
@@ -1426,7 +2326,7 @@ func (g *generator) preallocatable(t types.Type) bool {
 //
 // REQUIRES: t is serializable.
 func (g *generator) isWeaverEncoded(t types.Type) bool {
-	if g.tset.isProto(t) || g.tset.hasMarshalBinary(t) {
+	if g.tset.isCustomMarshaled(t) {
 		return false
 	}
 
@@ -1524,6 +2424,10 @@ func (g *generator) size(e string, t types.Type) string {
 			return fmt.Sprintf("(4 + (len(%s) * %d))", e, g.tset.sizeOfType(x.Elem()))
 
 		case *types.Slice:
+			if isByteSlice(x) {
+				// Large byte slices aren't copied into the encoder's buffer.
+				return fmt.Sprintf("%s(%s)", g.codegen().qualify("BytesSize"), e)
+			}
 			return fmt.Sprintf("(4 + (len(%s) * %d))", e, g.tset.sizeOfType(x.Elem()))
 
 		case *types.Map:
@@ -1701,7 +2605,11 @@ func (g *generator) generateServerStubs(p printFn) {
 		p(``)
 		p(`type %s struct{`, stub)
 		p(`	impl %s`, g.componentRef(comp))
+		if comp.observer != nil {
+			p(`	observer *%s`, g.tset.genTypeString(comp.observer))
+		}
 		p(`	addLoad func(key uint64, load float64)`)
+		p(`	middleware *%s`, g.codegen().qualify("ServerMiddlewares"))
 		p(`}`)
 		p(``)
 
@@ -1709,10 +2617,25 @@ func (g *generator) generateServerStubs(p printFn) {
 		p(`var _ %s = (*%s)(nil)`, g.codegen().qualify("Server"), stub)
 		p(``)
 
+		var streams []*types.Func
+		for _, m := range comp.methods() {
+			if _, ok := streamElem(m); ok {
+				streams = append(streams, m)
+			}
+		}
+		if len(streams) > 0 {
+			p(`// Check that %s implements the %s interface.`, stub, g.codegen().qualify("StreamServer"))
+			p(`var _ %s = (*%s)(nil)`, g.codegen().qualify("StreamServer"), stub)
+			p(``)
+		}
+
 		p(`// GetStubFn implements the codegen.Server interface.`)
 		p(`func (s %s) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {`, stub)
 		p(`	switch method {`)
 		for _, m := range comp.methods() {
+			if _, ok := streamElem(m); ok {
+				continue
+			}
 			p(`	case "%s":`, m.Name())
 			p(`		return s.%s`, notExported(m.Name()))
 		}
@@ -1721,13 +2644,40 @@ func (g *generator) generateServerStubs(p printFn) {
 		p(`	}`)
 		p(`}`)
 
+		if len(streams) > 0 {
+			p(``)
+			p(`// GetStreamFn implements the codegen.StreamServer interface.`)
+			p(`func (s %s) GetStreamFn(method string) func(ctx context.Context, args []byte, send func([]byte) error) ([]byte, error) {`, stub)
+			p(`	switch method {`)
+			for _, m := range streams {
+				p(`	case "%s":`, m.Name())
+				p(`		return s.%s`, notExported(m.Name()))
+			}
+			p(`	default:`)
+			p(`		return nil`)
+			p(`	}`)
+			p(`}`)
+		}
+
+		p(``)
+		p(`// Use implements the codegen.Server interface.`)
+		p(`func (s %s) Use(mw %s) {`, stub, g.codegen().qualify("ServerMiddleware"))
+		p(`	s.middleware.Use(mw)`)
+		p(`}`)
+
 		// Generate server stub implementation for the methods exported by the component.
 		for _, m := range comp.methods() {
 			mt := m.Type().(*types.Signature)
+			elem, streaming := streamElem(m)
 
 			p(``)
-			p(`func (s %s) %s(ctx context.Context, args []byte) (res []byte, err error) {`,
-				stub, notExported(m.Name()))
+			if streaming {
+				p(`func (s %s) %s(ctx context.Context, args []byte, send func([]byte) error) (res []byte, err error) {`,
+					stub, notExported(m.Name()))
+			} else {
+				p(`func (s %s) %s(ctx context.Context, args []byte) (res []byte, err error) {`,
+					stub, notExported(m.Name()))
+			}
 
 			// Handle errors triggered during execution.
 			p(`	// Catch and return any panics detected during encoding/decoding/rpc.`)
@@ -1746,7 +2696,7 @@ func (g *generator) generateServerStubs(p printFn) {
 			for i := 1; i < mt.Params().Len(); i++ { // Skip initial context.Context
 				at := mt.Params().At(i).Type()
 				arg := fmt.Sprintf("a%d", i-1)
-				if x, ok := at.(*types.Pointer); ok && (g.tset.isProto(x) || g.tset.hasMarshalBinary(x)) {
+				if x, ok := at.(*types.Pointer); ok && g.tset.isCustomMarshaled(x) {
 					// To decode a pointer *t where t is a proto or
 					// BinaryUnmarshaler, we need to instantiate a zero value
 					// of type t before calling the appropriate decoding
@@ -1772,17 +2722,20 @@ func (g *generator) generateServerStubs(p printFn) {
 			}
 			argList := b.String()
 
-			// Add load, if needed.
-			if comp.routedMethods[m.Name()] {
+			// Add load, if needed. The load is added once the method returns,
+			// so that the method can report its own load.
+			if key := g.routingKey(comp, m); key != "" {
 				p(`     var r %s`, g.tset.genTypeString(comp.router))
-				p(`	s.addLoad(_hash%s(r.%s(%s)), 1.0)`, exported(comp.intfName()), m.Name(), argList)
+				p(`	loadKey := _hash%s(%s)`, exported(comp.intfName()), key)
+				p(`	ctx, load := %s(ctx)`, g.codegen().qualify("WithCallLoad"))
+				p(`	defer func() { s.addLoad(loadKey, load.Load()) }()`)
 			}
 
 			b.Reset()
 			p(``)
-			p(`	// TODO(rgrandl): The deferred function above will recover from panics in the`)
-			p(`	// user code: fix this.`)
-			p(`	// Call the local method.`)
+			p(`	// Call the local method. The deferred function above only catches`)
+			p(`	// encoding and decoding panics. Other panics are contained by the`)
+			p(`	// runtime (see weaver.ErrPanic).`)
 			for i := 0; i < mt.Results().Len()-1; i++ { // Skip final error
 				if b.Len() == 0 {
 					fmt.Fprintf(&b, "r%d", i)
@@ -1791,24 +2744,49 @@ func (g *generator) generateServerStubs(p printFn) {
 				}
 			}
 
-			var res string
-			if b.Len() == 0 {
-				res = "appErr"
+			var call string
+			if comp.observed[m.Name()] {
+				call = fmt.Sprintf("s.observer.%s(%s)", m.Name(), strings.Replace(argList, "ctx", "ctx, s.impl", 1))
 			} else {
-				res = fmt.Sprintf("%s, appErr", b.String())
+				call = fmt.Sprintf("s.impl.%s(%s)", m.Name(), argList)
 			}
 
-			p(`	%s := s.impl.%s(%s)`, res, m.Name(), argList)
+			// The method runs inside the middleware chain, which may return
+			// an error of its own without calling it.
+			for i := 0; i < mt.Results().Len()-1; i++ { // Skip final error
+				p(`	var r%d %s`, i, g.tset.genTypeString(mt.Results().At(i).Type()))
+			}
+			if b.Len() == 0 {
+				p(`	appErr := s.middleware.Run(ctx, %q, %q, func(ctx context.Context) error {`, comp.fullIntfName(), m.Name())
+				p(`		return %s`, call)
+			} else {
+				p(`	appErr := s.middleware.Run(ctx, %q, %q, func(ctx context.Context) (err error) {`, comp.fullIntfName(), m.Name())
+				p(`		%s, err = %s`, b.String(), call)
+				p(`		return err`)
+			}
+			p(`	})`)
+
+			if streaming {
+				p(`	if appErr == nil {`)
+				p(`		// Stream the results.`)
+				p(`		appErr = %s[%s](send, r0, func(enc *%s, x %s) {`,
+					g.codegen().qualify("WriteStream"), g.tset.genTypeString(elem), g.codegen().qualify("Encoder"), g.tset.genTypeString(elem))
+				p(`			%s`, g.encode("enc", "x", elem))
+				p(`		})`)
+				p(`	}`)
+			}
 
 			p(``)
 			p(`	// Encode the results.`)
 			p(` enc := %s()`, g.codegen().qualify("NewEncoder"))
 
 			b.Reset()
-			for i := 0; i < mt.Results().Len()-1; i++ { // Skip final error
-				rt := mt.Results().At(i).Type()
-				res := fmt.Sprintf("r%d", i)
-				p(`	%s`, g.encode("enc", res, rt))
+			if !streaming { // The stream has already been sent.
+				for i := 0; i < mt.Results().Len()-1; i++ { // Skip final error
+					rt := mt.Results().At(i).Type()
+					res := fmt.Sprintf("r%d", i)
+					p(`	%s`, g.encode("enc", res, rt))
+				}
 			}
 			p(`	enc.Error(appErr)`)
 			p(`	return enc.Data(), nil`)
@@ -1820,7 +2798,7 @@ func (g *generator) generateServerStubs(p printFn) {
 // generateAutoMarshalMethods generates WeaverMarshal and WeaverUnmarshal methods
 // for any types that declares itself as weaver.AutoMarshal.
 func (g *generator) generateAutoMarshalMethods(p printFn) {
-	if g.tset.automarshalCandidates.Len() > 0 {
+	if g.tset.automarshalCandidates.Len()+g.tset.automarshalInstances.Len() > 0 {
 		p(``)
 		p(`// AutoMarshal implementations.`)
 	}
@@ -1834,7 +2812,6 @@ func (g *generator) generateAutoMarshalMethods(p printFn) {
 
 	ts := g.tset.genTypeString
 	for _, t := range sorted {
-		var innerTypes []types.Type
 		s := t.Underlying().(*types.Struct)
 
 		// Generate AutoMarshal assertion. For example, consider the following
@@ -1864,41 +2841,205 @@ func (g *generator) generateAutoMarshalMethods(p printFn) {
 		p(`type __is_%s[T ~%s] struct{}`, t.(*types.Named).Obj().Name(), ts(s))
 		p(`var _ __is_%s[%s]`, t.(*types.Named).Obj().Name(), ts(t))
 
-		// Generate WeaverMarshal method.
+		// Register error types, so that errors of the type are reconstructed
+		// with their original type when returned by remote calls.
+		if implementsError(types.NewPointer(t)) {
+			p(`func init() { %s[%s]() }`, g.codegen().qualify("RegisterError"), ts(t))
+		}
+
+		// Generate WeaverMarshal and WeaverUnmarshal methods.
 		fmt := g.tset.importPackage("fmt", "fmt")
 		p(``)
 		p(`func (x *%s) WeaverMarshal(enc *%s) {`, ts(t), g.codegen().qualify("Encoder"))
 		p(`	if x == nil {`)
 		p(`		panic(%s("%s.WeaverMarshal: nil receiver"))`, fmt.qualify("Errorf"), ts(t))
 		p(`	}`)
-		for i := 0; i < s.NumFields(); i++ {
-			fi := s.Field(i)
-			if !isWeaverAutoMarshal(fi.Type()) {
-				p(`	%s`, g.encode("enc", "x."+fi.Name(), fi.Type()))
-				innerTypes = append(innerTypes, fi.Type())
-			}
-		}
+		g.generateAutoMarshalBody(p, t)
 		p(`}`)
-
-		// Generate WeaverUnmarshal method.
 		p(``)
 		p(`func (x *%s) WeaverUnmarshal(dec *%s) {`, ts(t), g.codegen().qualify("Decoder"))
 		p(`	if x == nil {`)
 		p(`		panic(%s("%s.WeaverUnmarshal: nil receiver"))`, fmt.qualify("Errorf"), ts(t))
 		p(`	}`)
-		for i := 0; i < s.NumFields(); i++ {
-			fi := s.Field(i)
-			if !isWeaverAutoMarshal(fi.Type()) {
-				p(`	%s`, g.decode("dec", "&x."+fi.Name(), fi.Type()))
-			}
-		}
+		g.generateAutoUnmarshalBody(p, t)
 		p(`}`)
 
 		// Generate encoding/decoding methods for any inner types.
-		for _, inner := range innerTypes {
-			g.generateEncDecMethodsFor(p, inner)
+		for _, fi := range autoMarshalFields(s) {
+			g.generateEncDecMethodsFor(p, fi.Type())
+		}
+	}
+
+	g.generateGenericAutoMarshalMethods(p)
+}
+
+// generateGenericAutoMarshalMethods generates WeaverMarshal and
+// WeaverUnmarshal methods for the generic types that declare themselves
+// weaver.AutoMarshal. Go doesn't allow methods of specific instantiations of a
+// generic type, so the methods dispatch to functions generated for the
+// instantiations found in the package. For example, consider the following
+// Page type, instantiated as Page[int]:
+//
+//	type Page[T any] struct {
+//	    weaver.AutoMarshal
+//	    Items []T
+//	}
+//
+// We generate the following code:
+//
+//	func (x *Page[T]) WeaverMarshal(enc *codegen.Encoder) {
+//	    switch x := any(x).(type) {
+//	    case *Page[int]:
+//	        serviceweaver_marshal_Page_int_...(enc, x)
+//	    default:
+//	        panic(...)
+//	    }
+//	}
+//
+//	func serviceweaver_marshal_Page_int_...(enc *codegen.Encoder, x *Page[int]) {
+//	    ...
+//	}
+//
+// and similarly for WeaverUnmarshal.
+func (g *generator) generateGenericAutoMarshalMethods(p printFn) {
+	generics := g.tset.genericAutomarshals.Keys()
+	if len(generics) == 0 {
+		return
+	}
+	sort.Slice(generics, func(i, j int) bool {
+		return generics[i].String() < generics[j].String()
+	})
+	instances := g.tset.automarshalInstances.Keys()
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].String() < instances[j].String()
+	})
+
+	ts := g.tset.genTypeString
+	fmt := g.tset.importPackage("fmt", "fmt")
+	for _, t := range generics {
+		n := t.(*types.Named)
+		var insts []*types.Named
+		for _, inst := range instances {
+			if inst := inst.(*types.Named); inst.Origin() == n {
+				insts = append(insts, inst)
+			}
+		}
+
+		// Generate AutoMarshal assertions for the instantiations. See
+		// generateAutoMarshalMethods.
+		for _, inst := range insts {
+			name := sanitize(inst)
+			p(``)
+			p(`var _ %s = (*%s)(nil)`, g.codegen().qualify("AutoMarshal"), ts(inst))
+			p(`type __is_%s[T ~%s] struct{}`, name, ts(inst.Underlying()))
+			p(`var _ __is_%s[%s]`, name, ts(inst))
+			if implementsError(types.NewPointer(inst)) {
+				p(`func init() { %s[%s]() }`, g.codegen().qualify("RegisterError"), ts(inst))
+			}
+		}
+
+		// Generate the generic WeaverMarshal and WeaverUnmarshal methods.
+		params := make([]string, n.TypeParams().Len())
+		for i := range params {
+			params[i] = n.TypeParams().At(i).Obj().Name()
+		}
+		recvType := n.Obj().Name() + "[" + strings.Join(params, ", ") + "]"
+		for _, m := range []struct{ method, fn, arg, argType string }{
+			{"WeaverMarshal", "marshal", "enc", "Encoder"},
+			{"WeaverUnmarshal", "unmarshal", "dec", "Decoder"},
+		} {
+			p(``)
+			p(`func (x *%s) %s(%s *%s) {`, recvType, m.method, m.arg, g.codegen().qualify(m.argType))
+			p(`	if x == nil {`)
+			p(`		panic(%s("%s.%s: nil receiver"))`, fmt.qualify("Errorf"), n.Obj().Name(), m.method)
+			p(`	}`)
+			p(`	switch x := any(x).(type) {`)
+			for _, inst := range insts {
+				p(`	case *%s:`, ts(inst))
+				p(`		serviceweaver_%s_%s(%s, x)`, m.fn, sanitize(inst), m.arg)
+			}
+			p(`	default:`)
+			p(`		panic(%s("%%T.%s: instantiation not found by \"weaver generate\" in package %s", x))`, fmt.qualify("Errorf"), m.method, n.Obj().Pkg().Path())
+			p(`	}`)
+			p(`}`)
 		}
+
+		// Generate the functions for the instantiations.
+		for _, inst := range insts {
+			p(``)
+			p(`func serviceweaver_marshal_%s(enc *%s, x *%s) {`, sanitize(inst), g.codegen().qualify("Encoder"), ts(inst))
+			g.generateAutoMarshalBody(p, inst)
+			p(`}`)
+			p(``)
+			p(`func serviceweaver_unmarshal_%s(dec *%s, x *%s) {`, sanitize(inst), g.codegen().qualify("Decoder"), ts(inst))
+			g.generateAutoUnmarshalBody(p, inst)
+			p(`}`)
+			for _, fi := range autoMarshalFields(inst.Underlying().(*types.Struct)) {
+				g.generateEncDecMethodsFor(p, fi.Type())
+			}
+		}
+	}
+}
+
+// generateAutoMarshalBody generates the statements that encode x, of type *t,
+// where t is an AutoMarshal type, into enc.
+func (g *generator) generateAutoMarshalBody(p printFn, t types.Type) {
+	fields := autoMarshalFields(t.Underlying().(*types.Struct))
+	if g.isVersioned(t) {
+		// See runtime/codegen/versioned.go for the encoding format.
+		p(`	enc.Len(%d)`, len(fields))
+		for i, fi := range fields {
+			if i == 0 {
+				p(`	start := enc.BeginField(%q)`, fi.wire)
+			} else {
+				p(`	start = enc.BeginField(%q)`, fi.wire)
+			}
+			p(`	%s`, g.encode("enc", "x."+fi.Name(), fi.Type()))
+			p(`	enc.EndField(start)`)
+		}
+		return
 	}
+	for _, fi := range fields {
+		p(`	%s`, g.encode("enc", "x."+fi.Name(), fi.Type()))
+	}
+}
+
+// generateAutoUnmarshalBody generates the statements that decode x, of type
+// *t, where t is an AutoMarshal type, from dec.
+func (g *generator) generateAutoUnmarshalBody(p printFn, t types.Type) {
+	fields := autoMarshalFields(t.Underlying().(*types.Struct))
+	if g.isVersioned(t) {
+		// Fields missing from the encoding are left zero, and unknown
+		// fields are skipped.
+		p(`	*x = %s{}`, g.tset.genTypeString(t))
+		p(`	for n := dec.Len(); n > 0; n-- {`)
+		if len(fields) == 0 {
+			p(`		dec.Field()`)
+		} else {
+			p(`		name, fdec := dec.Field()`)
+			p(`		switch name {`)
+			for _, fi := range fields {
+				p(`		case %q:`, fi.wire)
+				p(`			%s`, g.decode("fdec", "&x."+fi.Name(), fi.Type()))
+			}
+			p(`		}`)
+		}
+		p(`	}`)
+		return
+	}
+	if len(excludedFields(t)) > 0 {
+		// Fields excluded from marshalling are left zero.
+		p(`	*x = %s{}`, g.tset.genTypeString(t))
+	}
+	for _, fi := range fields {
+		p(`	%s`, g.decode("dec", "&x."+fi.Name(), fi.Type()))
+	}
+}
+
+// isVersioned returns whether the provided AutoMarshal type, or the generic
+// type it instantiates, uses the versioned encoding.
+func (g *generator) isVersioned(t types.Type) bool {
+	return g.tset.versioned.At(t.(*types.Named).Origin()) != nil
 }
 
 // generateRouterMethods generates methods for router types.
@@ -1918,6 +3059,17 @@ func (g *generator) generateRouterMethods(p printFn) {
 
 // generateRouterMethodsFor generates router methods for the provided router type.
 func (g *generator) generateRouterMethodsFor(p printFn, comp *component, t types.Type) {
+	if comp.customHash {
+		p(`// _hash%s returns a 64 bit hash of the provided value, computed by the router.`, exported(comp.intfName()))
+		p(`func _hash%s(r %s) uint64 {`, exported(comp.intfName()), g.tset.genTypeString(t))
+		p(`	var router %s`, g.tset.genTypeString(comp.router))
+		p(`	return router.Hash(r)`)
+		p(`}`)
+		p(``)
+		g.generateOrderedCodeFor(p, comp, t)
+		return
+	}
+
 	p(`// _hash%s returns a 64 bit hash of the provided value.`, exported(comp.intfName()))
 	p(`func _hash%s(r %s) uint64 {`, exported(comp.intfName()), g.tset.genTypeString(t))
 	p(`	var h %s`, g.codegen().qualify("Hasher"))
@@ -1938,7 +3090,12 @@ func (g *generator) generateRouterMethodsFor(p printFn, comp *component, t types
 	p(`	return h.Sum64()`)
 	p(`}`)
 	p(``)
+	g.generateOrderedCodeFor(p, comp, t)
+}
 
+// generateOrderedCodeFor generates the order-preserving serialization of the
+// routing key t of the provided component.
+func (g *generator) generateOrderedCodeFor(p printFn, comp *component, t types.Type) {
 	p(`// _orderedCode%s returns an order-preserving serialization of the provided value.`, exported(comp.intfName()))
 	p(`func _orderedCode%s(r %s) %s {`, exported(comp.intfName()), g.tset.genTypeString(t), g.codegen().qualify("OrderedCode"))
 	p(`	var enc %s`, g.codegen().qualify("OrderedEncoder"))
@@ -1999,9 +3156,11 @@ func (g *generator) encode(stub, e string, t types.Type) string {
 	// enc(stub, e: []t) = serviceweaver_enc_[[]t](&stub, e)
 	// enc(stub, e: map[k]v) = serviceweaver_enc_[map[k]v](&stub, e)
 	// enc(stub, e: struct{...}) = serviceweaver_enc_[struct{...}](&stub, &e)
+	// enc(stub, e: type t u) = codegen.EncodeRegistered(stub, e) // t has a registered codec
 	// enc(stub, e: type t u) = stub.EncodeProto(&e)           // t implements proto.Message
 	// enc(stub, e: type t u) = (e).WeaverMarshal(stub)         // t implements AutoMarshal
 	// enc(stub, e: type t u) = stub.EncodeBinaryMarshaler(&e) // t implements BinaryMarshaler
+	// enc(stub, e: type t u) = stub.EncodeMarshaler(&e)       // t has Marshal
 	// enc(stub, e: type t u) = serviceweaver_enc_[t](&stub, &e)       // under(u) = struct{...}
 	// enc(stub, e: type t u) = enc(&stub, under(t)(e))        // otherwise
 	switch x := t.(type) {
@@ -2034,6 +3193,9 @@ func (g *generator) encode(stub, e string, t types.Type) string {
 		return fmt.Sprintf("%s(%s, %s)", f(x), stub, ref(e))
 
 	case *types.Named:
+		if g.tset.hasTypeCodec(x) {
+			return fmt.Sprintf("%s(%s, %s)", g.codegen().qualify("EncodeRegistered"), stub, e)
+		}
 		if g.tset.isProto(x) {
 			return fmt.Sprintf("%s.EncodeProto(%s)", stub, ref(e))
 		}
@@ -2043,6 +3205,9 @@ func (g *generator) encode(stub, e string, t types.Type) string {
 		if g.tset.hasMarshalBinary(x) {
 			return fmt.Sprintf("%s.EncodeBinaryMarshaler(%s)", stub, ref(e))
 		}
+		if g.tset.hasMarshal(x) {
+			return fmt.Sprintf("%s.EncodeMarshaler(%s)", stub, ref(e))
+		}
 		under := x.Underlying()
 		if _, ok := under.(*types.Struct); ok {
 			return fmt.Sprintf("%s(%s, %s)", f(x), stub, ref(e))
@@ -2072,9 +3237,11 @@ func (g *generator) decode(stub, v string, t types.Type) string {
 	// dec(stub, v: []t) = v := *v = serviceweaver_dec_[[]t](stub)
 	// dec(stub, v: map[k]v) = *v := serviceweaver_dec_[map[k]v](stub)
 	// dec(stub, v: struct{...}) = serviceweaver_dec_[struct{...}](stub, &v)
+	// dec(stub, v: type t u) = codegen.DecodeRegistered(stub, v) // t has a registered codec
 	// dec(stub, v: type t u) = stub.DecodeProto(v)             // t implements proto.Message
 	// dec(stub, v: type t u) = (v).WeaverUnmarshal(stub)        // t implements AutoMarshal
 	// dec(stub, v: type t u) = stub.DecodeBinaryUnmarshaler(v) // t implements BinaryUnmarshaler
+	// dec(stub, v: type t u) = stub.DecodeUnmarshaler(v)       // t has Unmarshal
 	// dec(stub, v: type t u) = serviceweaver_dec_[t](stub, v)          // under(u) = struct{...}
 	// dec(stub, v: type t u) = dec(stub, (*under(t))(v))       // otherwise
 	switch x := t.(type) {
@@ -2107,6 +3274,9 @@ func (g *generator) decode(stub, v string, t types.Type) string {
 		return fmt.Sprintf("%s(%s, %s)", f(x), stub, v)
 
 	case *types.Named:
+		if g.tset.hasTypeCodec(x) {
+			return fmt.Sprintf("%s(%s, %s)", g.codegen().qualify("DecodeRegistered"), stub, v)
+		}
 		if g.tset.isProto(x) {
 			return fmt.Sprintf("%s.DecodeProto(%s)", stub, v)
 		}
@@ -2116,6 +3286,9 @@ func (g *generator) decode(stub, v string, t types.Type) string {
 		if g.tset.hasMarshalBinary(x) {
 			return fmt.Sprintf("%s.DecodeBinaryUnmarshaler(%s)", stub, v)
 		}
+		if g.tset.hasMarshal(x) {
+			return fmt.Sprintf("%s.DecodeUnmarshaler(%s)", stub, v)
+		}
 		under := x.Underlying()
 		if _, ok := under.(*types.Struct); ok {
 			return fmt.Sprintf("%s(%s, %s)", f(x), stub, v)
@@ -2147,6 +3320,10 @@ func (g *generator) generateEncDecMethods(p printFn) {
 			}
 
 			// Generate for result types, skipping the error.
+			if elem, ok := streamElem(method); ok {
+				g.generateEncDecMethodsFor(printer, elem)
+				continue
+			}
 			for j := 0; j < sig.Results().Len()-1; j++ {
 				g.generateEncDecMethodsFor(printer, sig.Results().At(j).Type())
 			}
@@ -2172,11 +3349,11 @@ func (g *generator) generateEncDecMethodsFor(p printFn, t types.Type) {
 		// (e.g., enc.Int(42), dec.Bool()).
 
 	case *types.Pointer:
-		if g.tset.isProto(x) || g.tset.hasMarshalBinary(x) {
-			// Types implementing proto.Marshal or encoding.BinaryMarshaler and
-			// encoding.BinaryUnmarshaler don't need encoding or decoding
-			// methods. Instead, we call methods directly on a
-			// codegen.Encoder or codegen.Decoder (e.g.,
+		if g.tset.isCustomMarshaled(x) {
+			// Types implementing proto.Marshal, encoding.BinaryMarshaler and
+			// encoding.BinaryUnmarshaler, or Marshal and Unmarshal don't need
+			// encoding or decoding methods. Instead, we call methods directly
+			// on a codegen.Encoder or codegen.Decoder (e.g.,
 			// enc.EncodeProto(x), dec.DecodeBinaryUnmarshaler(x)).
 			return
 		}
@@ -2223,6 +3400,21 @@ func (g *generator) generateEncDecMethodsFor(p printFn, t types.Type) {
 		p(`}`)
 
 	case *types.Slice:
+		if isByteSlice(x) {
+			// Encode byte slices in bulk rather than byte by byte. Decoded
+			// byte slices share the memory of the received message.
+			p(``)
+			p(`func serviceweaver_enc_%s(enc *%s, arg %s) {`, sanitize(x), g.codegen().qualify("Encoder"), ts(x))
+			p(`	enc.Bytes(arg)`)
+			p(`}`)
+
+			p(``)
+			p(`func serviceweaver_dec_%s(dec *%s) %s {`, sanitize(x), g.codegen().qualify("Decoder"), ts(x))
+			p(`	return dec.Bytes()`)
+			p(`}`)
+			return
+		}
+
 		g.generateEncDecMethodsFor(p, x.Elem())
 
 		p(``)
@@ -2261,10 +3453,21 @@ func (g *generator) generateEncDecMethodsFor(p printFn, t types.Type) {
 		p(`		return`)
 		p(`	}`)
 		p(`	enc.Len(len(arg))`)
-		p(`	for k, v := range arg {`)
-		p(`		%s`, g.encode("enc", "k", x.Key()))
-		p(`		%s`, g.encode("enc", "v", x.Elem()))
-		p(`	}`)
+		if isOrdered(x.Key()) {
+			// Encode the entries in key order, so that equal maps have
+			// equal encodings. Maps with other key types, like structs, are
+			// encoded in iteration order.
+			p(`	for _, k := range %s(arg) {`, g.codegen().qualify("SortedKeys"))
+			p(`		v := arg[k]`)
+			p(`		%s`, g.encode("enc", "k", x.Key()))
+			p(`		%s`, g.encode("enc", "v", x.Elem()))
+			p(`	}`)
+		} else {
+			p(`	for k, v := range arg {`)
+			p(`		%s`, g.encode("enc", "k", x.Key()))
+			p(`		%s`, g.encode("enc", "v", x.Elem()))
+			p(`	}`)
+		}
 		p(`}`)
 
 		p(``)
@@ -2289,10 +3492,11 @@ func (g *generator) generateEncDecMethodsFor(p printFn, t types.Type) {
 		panic(fmt.Sprintf("generateEncDecFor: unexpected type: %v", t))
 
 	case *types.Named:
-		if g.tset.isProto(x) || g.tset.automarshals.At(x) != nil || g.tset.implementsAutoMarshal(x) || g.tset.hasMarshalBinary(x) {
+		if g.tset.isCustomMarshaled(x) || g.tset.automarshals.At(x) != nil || g.tset.implementsAutoMarshal(x) {
 			// Types implementing proto.Marshal, weaver.AutoMarshal, or
-			// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler don't
-			// need encoding or decoding methods. Instead, we call methods
+			// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler, and
+			// types with registered codecs don't need encoding or decoding
+			// methods. Instead, we call methods
 			// directly on a codegen.Encoder or codegen.Decoder (e.g.,
 			// enc.EncodeProto(x), dec.DecodeBinaryUnmarshaler(x)).
 			return
@@ -2318,6 +3522,12 @@ func (g *generator) codegen() importPkg {
 	return g.tset.importPackage(path, "codegen")
 }
 
+// observer returns an expression that evaluates to the observer of the
+// provided component, given its implementation impl.
+func (g *generator) observer(comp *component) string {
+	return fmt.Sprintf("%s[%s](impl)", g.codegen().qualify("Observer"), g.tset.genTypeString(comp.observer))
+}
+
 // trace imports and returns the otel trace package.
 func (g *generator) trace() importPkg {
 	return g.tset.importPackage("go.opentelemetry.io/otel/trace", "trace")
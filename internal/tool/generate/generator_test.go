@@ -18,6 +18,8 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -27,6 +29,8 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 var (
@@ -301,6 +305,612 @@ func TestGeneratorErrors(t *testing.T) {
 	}
 }
 
+// TestGeneratorMocks runs "weaver generate -mocks" on a package and checks
+// that the generated mocks compile and behave as expected when used in a test.
+func TestGeneratorMocks(t *testing.T) {
+	const src = `package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type Pair struct {
+	weaver.AutoMarshal
+	Key, Value string
+}
+
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key, value string) error
+	Pairs(context.Context, int) ([]Pair, int, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type cache struct {
+	weaver.Implements[Cache]
+}
+
+func (*cache) Get(context.Context, string) (string, error)        { return "", nil }
+func (*cache) Put(context.Context, string, string) error           { return nil }
+func (*cache) Pairs(context.Context, int) ([]Pair, int, error)     { return nil, 0, nil }
+func (*cache) Delete(context.Context, ...string) error             { return nil }
+
+type store interface {
+	Ping(m context.Context) error
+}
+
+type storeImpl struct {
+	weaver.Implements[store]
+}
+
+func (*storeImpl) Ping(context.Context) error { return nil }
+`
+
+	const test = `package foo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+)
+
+func TestMocks(t *testing.T) {
+	ctx := context.Background()
+	m := &MockCache{
+		GetFunc: func(ctx context.Context, key string) (string, error) { return key + "!", nil },
+		PairsFunc: func(_ context.Context, n int) ([]Pair, int, error) { return []Pair{{Key: "k"}}, n, nil },
+		DeleteFunc: func(ctx context.Context, keys ...string) error { return nil },
+	}
+	weavertest.Fake[Cache](m)
+
+	if got, _ := m.Get(ctx, "a"); got != "a!" {
+		t.Errorf("Get: got %q, want %q", got, "a!")
+	}
+	m.Get(ctx, "b")
+	if got, want := m.GetCalls(), []MockCacheGetCall{{Key: "a"}, {Key: "b"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetCalls: got %v, want %v", got, want)
+	}
+	if _, n, _ := m.Pairs(ctx, 42); n != 42 {
+		t.Errorf("Pairs: got %d, want 42", n)
+	}
+	if got, want := m.PairsCalls(), []MockCachePairsCall{{A0: 42}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PairsCalls: got %v, want %v", got, want)
+	}
+	m.Delete(ctx, "x", "y")
+	if got, want := m.DeleteCalls(), []MockCacheDeleteCall{{Keys: []string{"x", "y"}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteCalls: got %v, want %v", got, want)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Put without PutFunc: unexpected success")
+			}
+		}()
+		m.Put(ctx, "k", "v")
+	}()
+	if n := len(m.PutCalls()); n != 1 {
+		t.Errorf("PutCalls: got %d calls, want 1", n)
+	}
+
+	var _ store = &mockStore{}
+}
+`
+
+	// Write the package and run "weaver generate -mocks".
+	tmp := t.TempDir()
+	save := func(f, data string) {
+		if err := os.WriteFile(filepath.Join(tmp, f), []byte(data), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", f, err)
+		}
+	}
+	save("foo.go", src)
+	save("go.mod", goModFile)
+	save(legacyMockFile, "package foo\n") // left behind by an older version
+	run := func(name string, args ...string) {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = tmp
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s %v: %v\n%s", name, args, err, out)
+		}
+	}
+	run("go", "mod", "tidy")
+	opt := Options{
+		Warn:  func(err error) { t.Log(err) },
+		Mocks: true,
+	}
+	if err := Generate(tmp, []string{tmp}, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check that the mocks compile and work.
+	save("foo_test.go", test)
+	run("go", "mod", "tidy")
+	run("go", "vet", ".")
+	run("go", "test", ".")
+	if _, err := os.Stat(filepath.Join(tmp, legacyMockFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s was not removed: %v", legacyMockFile, err)
+	}
+
+	// Mocks are removed when "weaver generate" runs without -mocks.
+	if err := Generate(tmp, []string{tmp}, Options{Warn: opt.Warn}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, generatedMockFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s was not removed: %v", generatedMockFile, err)
+	}
+}
+
+// TestGeneratorConfigSchemas runs "weaver generate" on a package with
+// configured components and checks the generated weaver_config_schemas.json.
+func TestGeneratorConfigSchemas(t *testing.T) {
+	const src = `package foo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type A interface {
+	M(context.Context) error
+}
+
+// aConfig configures component A.
+type aConfig struct {
+	// Name of the thing.
+	Name    string   ` + "`toml:\"name\"`" + `
+	Size    uint32   ` + "`default:\"10\"`" + ` // Number of things.
+	Ratio   float64
+	Enabled bool     ` + "`default:\"true\"`" + `
+	Tags    []string
+	Limits  map[string]int
+	Timeout time.Duration ` + "`default:\"1m\"`" + `
+	Nested  *nested
+	Ignored int ` + "`toml:\"-\"`" + `
+	hidden  int
+
+	embedded
+}
+
+type nested struct {
+	Pair [2]int
+	Next *nested
+}
+
+type embedded struct {
+	Extra string
+}
+
+type a struct {
+	weaver.Implements[A]
+	weaver.WithConfig[aConfig]
+}
+
+func (*a) M(context.Context) error { return nil }
+
+type B interface {
+	M(context.Context) error
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+func (*b) M(context.Context) error { return nil }
+`
+
+	const want = `{
+  "foo/A": {
+    "$schema": "https://json-schema.org/draft/2020-12/schema",
+    "title": "aConfig",
+    "description": "aConfig configures component A.",
+    "type": "object",
+    "properties": {
+      "Enabled": {"type": "boolean", "default": true},
+      "Extra": {"type": "string"},
+      "Limits": {"type": "object", "additionalProperties": {"type": "integer"}},
+      "Nested": {
+        "type": "object",
+        "properties": {
+          "Next": {"type": "object"},
+          "Pair": {"type": "array", "items": {"type": "integer"}, "maxItems": 2}
+        },
+        "additionalProperties": false
+      },
+      "Ratio": {"type": "number"},
+      "Size": {"description": "Number of things.", "type": "integer", "default": 10, "minimum": 0},
+      "Tags": {"type": "array", "items": {"type": "string"}},
+      "Timeout": {"type": ["string", "integer"], "default": "1m"},
+      "allowed_callers": {
+        "description": "Callers allowed to call the component's methods, keyed by method name.",
+        "type": "object",
+        "properties": {
+          "*": {"type": "array", "items": {"type": "string"}},
+          "M": {"type": "array", "items": {"type": "string"}}
+        },
+        "additionalProperties": false
+      },
+      "compress_min_bytes": {
+        "description": "Minimum size, in bytes, of the compressed arguments and results of remote calls.",
+        "type": "integer",
+        "minimum": 0
+      },
+      "method_timeouts": {
+        "description": "Timeouts of the component's methods, keyed by method name.",
+        "type": "object",
+        "properties": {"M": {"type": ["string", "integer"]}},
+        "additionalProperties": false
+      },
+      "metrics_window": {
+        "description": "Duration of the sliding window over which recent method invocations and errors are counted.",
+        "type": ["string", "integer"]
+      },
+      "rate_limits": {
+        "description": "Rate limits of the component's methods, keyed by method name.",
+        "type": "object",
+        "properties": {
+          "M": {
+            "type": "object",
+            "properties": {
+              "burst": {"description": "Maximum number of calls admitted at once.", "type": "integer", "minimum": 0},
+              "mode": {"description": "Whether calls over the limit are rejected or wait.", "type": "string", "enum": ["reject", "wait"]},
+              "qps": {"description": "Maximum sustained rate of calls per second, or 0 for no limit.", "type": "number", "minimum": 0}
+            },
+            "additionalProperties": false
+          }
+        },
+        "additionalProperties": false
+      },
+      "name": {"description": "Name of the thing.", "type": "string"}
+    },
+    "additionalProperties": false
+  }
+}`
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "foo.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(goModFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir = tmp
+	if out, err := tidy.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy: %v\n%s", err, out)
+	}
+	opt := Options{Warn: func(err error) { t.Log(err) }}
+	if err := Generate(tmp, []string{tmp}, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmp, configSchemasFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, expected any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("config schemas (-want +got):\n%s", diff)
+	}
+}
+
+func TestGeneratorServiceSchemas(t *testing.T) {
+	const src = `package foo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Cache caches values.
+type Cache interface {
+	// Get returns the value of a key.
+	Get(ctx context.Context, key string) (string, error)
+	Put(context.Context, string, *Entry) error
+	Entries(ctx context.Context, limit uint32) ([]Entry, time.Time, error)
+	Keys(context.Context) (weaver.Stream[string], error)
+	Scale(context.Context, complex128) error
+}
+
+// Entry is a cache entry.
+type Entry struct {
+	weaver.AutoMarshal
+	Key   string
+	Value []byte // Raw value.
+	TTL   time.Duration
+	Tags  map[string]int32
+	Meta  *Meta
+}
+
+type Meta struct {
+	weaver.AutoMarshal
+	Version int
+	Owners  [2]string
+}
+
+type cache struct {
+	weaver.Implements[Cache]
+}
+
+func (*cache) Get(context.Context, string) (string, error)                 { return "", nil }
+func (*cache) Put(context.Context, string, *Entry) error                    { return nil }
+func (*cache) Entries(context.Context, uint32) ([]Entry, time.Time, error) { return nil, time.Time{}, nil }
+func (*cache) Keys(context.Context) (weaver.Stream[string], error)          { return nil, nil }
+func (*cache) Scale(context.Context, complex128) error                      { return nil }
+`
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "foo.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(goModFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir = tmp
+	if out, err := tidy.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy: %v\n%s", err, out)
+	}
+
+	for _, test := range []struct {
+		format string
+		file   string
+		want   string
+		warns  []string // substrings of the expected warnings
+	}{
+		{SchemaOpenAPI, openAPIFile, wantOpenAPI, []string{"Cache.Keys", "Cache.Scale"}},
+		{SchemaProto, protoServicesFile, wantProto, []string{"Cache.Scale"}},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			// Generate the schema twice, to check that it is stable.
+			dir := t.TempDir()
+			var outputs []string
+			for i := 0; i < 2; i++ {
+				var warns []string
+				opt := Options{
+					Warn:      func(err error) { warns = append(warns, err.Error()) },
+					Schema:    test.format,
+					SchemaDir: dir,
+				}
+				if err := Generate(tmp, []string{tmp}, opt); err != nil {
+					t.Fatal(err)
+				}
+				if len(warns) != len(test.warns) {
+					t.Fatalf("got warnings %q, want %d warnings", warns, len(test.warns))
+				}
+				for j, want := range test.warns {
+					if !strings.Contains(warns[j], want) {
+						t.Errorf("warning %q doesn't contain %q", warns[j], want)
+					}
+				}
+				data, err := os.ReadFile(filepath.Join(dir, "foo_"+test.file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				outputs = append(outputs, string(data))
+			}
+			if outputs[0] != outputs[1] {
+				t.Fatalf("unstable schema:\n%s\n\n%s", outputs[0], outputs[1])
+			}
+			var got, want any = outputs[0], test.want
+			if test.format == SchemaOpenAPI {
+				if err := json.Unmarshal([]byte(outputs[0]), &got); err != nil {
+					t.Fatalf("invalid JSON: %v\n%s", err, outputs[0])
+				}
+				if err := json.Unmarshal([]byte(test.want), &want); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("schema (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+const wantOpenAPI = `{
+  "openapi": "3.1.0",
+  "info": {"title": "foo", "version": "1"},
+  "paths": {
+    "/foo/Cache/Entries": {
+      "post": {
+        "operationId": "Cache_Entries",
+        "tags": ["foo/Cache"],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {
+            "type": "object",
+            "properties": {"limit": {"type": "integer", "format": "int64", "minimum": 0}},
+            "required": ["limit"]
+          }}}
+        },
+        "responses": {
+          "200": {
+            "description": "The results of the method.",
+            "content": {"application/json": {"schema": {
+              "type": "object",
+              "properties": {
+                "r0": {"type": "array", "items": {"$ref": "#/components/schemas/Entry"}},
+                "r1": {"type": "string", "format": "date-time"}
+              },
+              "required": ["r0", "r1"]
+            }}}
+          },
+          "default": {
+          "description": "The error returned by the method.",
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/weaver.Error"}}}
+        }
+        }
+      }
+    },
+    "/foo/Cache/Get": {
+      "post": {
+        "operationId": "Cache_Get",
+        "tags": ["foo/Cache"],
+        "description": "Get returns the value of a key.",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {
+            "type": "object",
+            "properties": {"key": {"type": "string"}},
+            "required": ["key"]
+          }}}
+        },
+        "responses": {
+          "200": {
+            "description": "The results of the method.",
+            "content": {"application/json": {"schema": {
+              "type": "object",
+              "properties": {"r0": {"type": "string"}},
+              "required": ["r0"]
+            }}}
+          },
+          "default": {
+          "description": "The error returned by the method.",
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/weaver.Error"}}}
+        }
+        }
+      }
+    },
+    "/foo/Cache/Put": {
+      "post": {
+        "operationId": "Cache_Put",
+        "tags": ["foo/Cache"],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {
+            "type": "object",
+            "properties": {"a0": {"type": "string"}, "a1": {"$ref": "#/components/schemas/Entry"}},
+            "required": ["a0", "a1"]
+          }}}
+        },
+        "responses": {
+          "200": {
+            "description": "The results of the method.",
+            "content": {"application/json": {"schema": {"type": "object"}}}
+          },
+          "default": {
+          "description": "The error returned by the method.",
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/weaver.Error"}}}
+        }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Entry": {
+        "description": "Entry is a cache entry.",
+        "type": "object",
+        "properties": {
+          "Key": {"type": "string"},
+          "Meta": {"$ref": "#/components/schemas/Meta"},
+          "TTL": {"description": "A duration, in nanoseconds.", "type": "integer", "format": "int64"},
+          "Tags": {"type": "object", "additionalProperties": {"type": "integer", "format": "int32"}},
+          "Value": {"description": "Raw value.", "type": "string", "contentEncoding": "base64"}
+        },
+        "required": ["Key", "Value", "TTL", "Tags", "Meta"]
+      },
+      "Meta": {
+        "type": "object",
+        "properties": {
+          "Owners": {"type": "array", "items": {"type": "string"}, "minItems": 2, "maxItems": 2},
+          "Version": {"type": "integer", "format": "int64"}
+        },
+        "required": ["Version", "Owners"]
+      },
+      "weaver.Error": {
+        "description": "An error returned by a component method.",
+        "type": "object",
+        "properties": {"message": {"type": "string"}},
+        "required": ["message"]
+      }
+    }
+  }
+}`
+
+const wantProto = `// Code generated by "weaver generate". DO NOT EDIT.
+
+syntax = "proto3";
+
+package foo;
+
+import "google/protobuf/duration.proto";
+import "google/protobuf/timestamp.proto";
+
+option go_package = "foo";
+
+// Cache caches values.
+service Cache {
+  rpc Entries(Cache_EntriesRequest) returns (Cache_EntriesResponse);
+  // Get returns the value of a key.
+  rpc Get(Cache_GetRequest) returns (Cache_GetResponse);
+  rpc Keys(Cache_KeysRequest) returns (stream Cache_KeysResponse);
+  rpc Put(Cache_PutRequest) returns (Cache_PutResponse);
+}
+
+message Cache_EntriesRequest {
+  uint32 limit = 1;
+}
+
+message Cache_EntriesResponse {
+  repeated Entry r0 = 1;
+  google.protobuf.Timestamp r1 = 2;
+}
+
+message Cache_GetRequest {
+  string key = 1;
+}
+
+message Cache_GetResponse {
+  string r0 = 1;
+}
+
+message Cache_KeysRequest {
+}
+
+message Cache_KeysResponse {
+  string r0 = 1;
+}
+
+message Cache_PutRequest {
+  string a0 = 1;
+  Entry a1 = 2;
+}
+
+message Cache_PutResponse {
+}
+
+// Entry is a cache entry.
+message Entry {
+  string Key = 1;
+  // Raw value.
+  bytes Value = 2;
+  google.protobuf.Duration TTL = 3;
+  map<string, int32> Tags = 4;
+  Meta Meta = 5;
+}
+
+message Meta {
+  int64 Version = 1;
+  repeated string Owners = 2;
+}
+`
+
 func TestSanitize(t *testing.T) {
 	// Test plan: Check that sanitize returns the expected sanitized name for
 	// various types. Also check that sanitize is injective; i.e. every type
@@ -0,0 +1,256 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// generateMocks generates a weaver_gen_mock_test.go file containing a mock
+// implementation of every component interface in the package, if mocks are
+// enabled, and otherwise removes such a file left behind by a previous run.
+// Mock files are test files, so that mocks are not compiled into the
+// application binary. generateMocks also removes the weaver_gen_mock.go
+// files written by older versions of "weaver generate". For example,
+// given the following component interface:
+//
+//	type Cache interface {
+//	    Get(ctx context.Context, key string) (string, error)
+//	}
+//
+// generateMocks generates the following mock (comments and locking elided):
+//
+//	type MockCache struct {
+//	    GetFunc func(ctx context.Context, key string) (string, error)
+//	    getCalls []MockCacheGetCall
+//	}
+//
+//	type MockCacheGetCall struct {
+//	    Key string
+//	}
+//
+//	func (m *MockCache) Get(ctx context.Context, key string) (string, error) {
+//	    m.getCalls = append(m.getCalls, MockCacheGetCall{Key: key})
+//	    if m.GetFunc == nil {
+//	        panic(...)
+//	    }
+//	    return m.GetFunc(ctx, key)
+//	}
+//
+//	func (m *MockCache) GetCalls() []MockCacheGetCall { ... }
+func (g *generator) generateMocks() error {
+	if err := removeFile(filepath.Join(g.pkgDir(), legacyMockFile)); err != nil {
+		return err
+	}
+	filename := filepath.Join(g.pkgDir(), generatedMockFile)
+	if !g.opt.Mocks {
+		return removeFile(filename)
+	}
+
+	var components []*component
+	for _, comp := range g.components {
+		// weaver.Main has no methods, so there is nothing to mock.
+		if !comp.isMain {
+			components = append(components, comp)
+		}
+	}
+	if len(components) == 0 {
+		return removeFile(filename)
+	}
+
+	// The mocks are placed in their own file, so they need their own set of
+	// imports.
+	tset := newTypeSet(g.pkg, g.tset.automarshals, g.tset.automarshalCandidates, g.tset.codecs)
+
+	var body bytes.Buffer
+	{
+		fn := func(format string, args ...interface{}) {
+			fmt.Fprintln(&body, fmt.Sprintf(format, args...))
+		}
+		for _, comp := range components {
+			g.generateMock(fn, tset, comp)
+		}
+	}
+
+	var header bytes.Buffer
+	{
+		fn := func(format string, args ...interface{}) {
+			fmt.Fprintln(&header, fmt.Sprintf(format, args...))
+		}
+		g.generateImports(fn, tset)
+	}
+
+	return writeGeneratedFile(filename, header, body)
+}
+
+// removeFile removes the provided file, if it exists.
+func removeFile(filename string) error {
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// generateMock generates a mock implementation of the provided component's
+// interface. See generateMocks for an example.
+func (g *generator) generateMock(p printFn, tset *typeSet, comp *component) {
+	mock := mockName(comp)
+	sync := tset.importPackage("sync", "sync")
+
+	p(``)
+	p(`// %s is a mock implementation of the %s component interface. Set the`, mock, comp.intfName())
+	p(`// <Method>Func field of a method to control its behavior. Calling a method`)
+	p(`// whose <Method>Func field is nil panics. A %s can be passed to`, mock)
+	p(`// weavertest.Fake to replace the %s component in tests.`, comp.intfName())
+	p(`type %s struct {`, mock)
+	for _, m := range comp.methods() {
+		sig := m.Type().(*types.Signature)
+		p(`	%sFunc func(%s) (%s)`, m.Name(), mockParams(tset, sig), mockResults(tset, sig))
+	}
+	p(``)
+	p(`	mu %s // guards the following fields`, sync.qualify("Mutex"))
+	for _, m := range comp.methods() {
+		p(`	%sCalls []%s`, notExported(m.Name()), mockCallName(comp, m))
+	}
+	p(`}`)
+	p(``)
+	p(`// Check that %s implements the %s interface.`, mock, comp.intfName())
+	p(`var _ %s = (*%s)(nil)`, tset.genTypeString(comp.intf), mock)
+
+	for _, m := range comp.methods() {
+		sig := m.Type().(*types.Signature)
+		names := mockParamNames(sig)
+		call := mockCallName(comp, m)
+
+		// The recorded arguments of a call.
+		p(``)
+		p(`// %s records the arguments of a call to %s.%s.`, call, mock, m.Name())
+		p(`type %s struct {`, call)
+		for i := 1; i < sig.Params().Len(); i++ {
+			p(`	%s %s`, exported(names[i]), tset.genTypeString(sig.Params().At(i).Type()))
+		}
+		p(`}`)
+
+		// The mocked method.
+		var fields, args strings.Builder
+		fmt.Fprint(&args, names[0])
+		for i := 1; i < sig.Params().Len(); i++ {
+			fmt.Fprintf(&fields, "%s: %s, ", exported(names[i]), names[i])
+			fmt.Fprintf(&args, ", %s", names[i])
+		}
+		if sig.Variadic() {
+			fmt.Fprint(&args, "...")
+		}
+		p(``)
+		p(`// %s calls m.%sFunc and records the call.`, m.Name(), m.Name())
+		p(`func (m *%s) %s(%s) (%s) {`, mock, m.Name(), mockParams(tset, sig), mockResults(tset, sig))
+		p(`	m.mu.Lock()`)
+		p(`	m.%sCalls = append(m.%sCalls, %s{%s})`, notExported(m.Name()), notExported(m.Name()), call, strings.TrimSuffix(fields.String(), ", "))
+		p(`	m.mu.Unlock()`)
+		p(`	if m.%sFunc == nil {`, m.Name())
+		p(`		panic(%q)`, fmt.Sprintf("%s.%s called, but %s.%sFunc is nil; set %sFunc to mock %s", mock, m.Name(), mock, m.Name(), m.Name(), m.Name()))
+		p(`	}`)
+		p(`	return m.%sFunc(%s)`, m.Name(), args.String())
+		p(`}`)
+
+		// The call recording accessor.
+		p(``)
+		p(`// %sCalls returns the arguments of all calls to %s, in the order in which`, m.Name(), m.Name())
+		p(`// they were made.`)
+		p(`func (m *%s) %sCalls() []%s {`, mock, m.Name(), call)
+		p(`	m.mu.Lock()`)
+		p(`	defer m.mu.Unlock()`)
+		p(`	return append([]%s(nil), m.%sCalls...)`, call, notExported(m.Name()))
+		p(`}`)
+	}
+}
+
+// mockName returns the name of the mock of the provided component's
+// interface. The mock is exported iff the interface is exported.
+func mockName(comp *component) string {
+	name := comp.intfName()
+	if ast.IsExported(name) {
+		return "Mock" + name
+	}
+	return "mock" + exported(name)
+}
+
+// mockCallName returns the name of the type that records the arguments of a
+// call to the provided method of the provided component's mock.
+func mockCallName(comp *component, m *types.Func) string {
+	return mockName(comp) + m.Name() + "Call"
+}
+
+// mockParamNames returns the names of the parameters of the provided
+// signature. The parameter names declared in the component interface are
+// used, if possible, to make mocks easier to read. If any parameter is
+// unnamed, or if names would clash, the parameters are named ctx, a0, a1, and
+// so on instead.
+func mockParamNames(sig *types.Signature) []string {
+	n := sig.Params().Len()
+	names := make([]string, n)
+	seen := map[string]bool{"m": true} // the mock's receiver
+	for i := 0; i < n; i++ {
+		name := sig.Params().At(i).Name()
+		if name == "" || name == "_" || seen[name] || seen[exported(name)] {
+			names[0] = "ctx"
+			for i := 1; i < n; i++ {
+				names[i] = fmt.Sprintf("a%d", i-1)
+			}
+			return names
+		}
+		// Parameter names are also used as field names of the call records,
+		// so the exported name must be unique as well.
+		seen[name] = true
+		seen[exported(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// mockParams returns a textual representation of the parameters of the
+// provided signature, named according to mockParamNames.
+func mockParams(tset *typeSet, sig *types.Signature) string {
+	names := mockParamNames(sig)
+	params := make([]string, sig.Params().Len())
+	for i := range params {
+		t := sig.Params().At(i).Type()
+		if sig.Variadic() && i == len(params)-1 {
+			// For variadic functions, the final parameter is guaranteed to
+			// be a slice. Instead of a parameter of type []t, we print ...t.
+			params[i] = fmt.Sprintf("%s ...%s", names[i], tset.genTypeString(t.(*types.Slice).Elem()))
+			continue
+		}
+		params[i] = fmt.Sprintf("%s %s", names[i], tset.genTypeString(t))
+	}
+	return strings.Join(params, ", ")
+}
+
+// mockResults returns a textual representation of the (unnamed) results of
+// the provided signature.
+func mockResults(tset *typeSet, sig *types.Signature) string {
+	results := make([]string, sig.Results().Len())
+	for i := range results {
+		results[i] = tset.genTypeString(sig.Results().At(i).Type())
+	}
+	return strings.Join(results, ", ")
+}
@@ -0,0 +1,752 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ServiceWeaver/weaver/internal/files"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Service schema formats, as accepted by Options.Schema.
+const (
+	SchemaOpenAPI = "openapi" // OpenAPI 3.1 document, in JSON
+	SchemaProto   = "proto"   // protobuf service definitions
+)
+
+// errorSchemaName is the name of the OpenAPI schema of the errors returned by
+// component methods. Go type names can't contain a dot, so it doesn't collide
+// with the schemas of Go types.
+const errorSchemaName = "weaver.Error"
+
+// schemaField is an argument or result of a component method, or a field of a
+// struct, as described in a service schema.
+type schemaField struct {
+	name string
+	t    types.Type
+	pos  token.Pos // position of the declaration, used to find its doc
+}
+
+// schemaMethod is a component method, as described in a service schema.
+type schemaMethod struct {
+	name      string
+	doc       string
+	params    []schemaField // arguments, excluding the initial context.Context
+	results   []schemaField // results, excluding the final error
+	streaming bool          // does the method return a weaver.Stream?
+}
+
+// generateServiceSchema generates a schema of the services provided by the
+// package's components, in the format selected by Options.Schema, so that
+// clients not written with Service Weaver can call the components, e.g.,
+// through a gateway that exposes them on a Listener. Every component becomes
+// a service, and every component method an operation whose request holds the
+// method's arguments and whose response holds the method's results.
+//
+// Methods whose arguments or results can't be represented in the selected
+// format, e.g., because they are channels or have custom serialization, are
+// left out of the schema and reported with Options.Warn.
+func (g *generator) generateServiceSchema() error {
+	var filename string
+	switch g.opt.Schema {
+	case "":
+		return nil
+	case SchemaOpenAPI:
+		filename = g.schemaFile(openAPIFile)
+	case SchemaProto:
+		filename = g.schemaFile(protoServicesFile)
+	default:
+		return fmt.Errorf("unknown schema format %q", g.opt.Schema)
+	}
+
+	// Gather the methods that can be represented.
+	docs := fieldDocs(g.pkg.Syntax)
+	var components []*component
+	methods := map[*component][]schemaMethod{}
+	for _, comp := range g.components {
+		if comp.isMain {
+			continue
+		}
+		components = append(components, comp)
+		methods[comp] = g.schemaMethods(comp, docs)
+	}
+	if len(components) == 0 {
+		// Remove a schema file left behind by a previous run, if any.
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].intfName() < components[j].intfName()
+	})
+
+	var data []byte
+	if g.opt.Schema == SchemaOpenAPI {
+		var err error
+		if data, err = g.openAPIDocument(components, methods, docs); err != nil {
+			return err
+		}
+	} else {
+		data = g.protoServices(components, methods, docs)
+	}
+	dst := files.NewWriter(filename)
+	defer dst.Cleanup()
+	if _, err := dst.Write(data); err != nil {
+		return err
+	}
+	return dst.Close()
+}
+
+// schemaFile returns the path of the service schema file with the provided
+// base name. The file is placed in Options.SchemaDir, if set, with a name
+// prefixed by the package path, and in the package's directory otherwise.
+func (g *generator) schemaFile(base string) string {
+	if g.opt.SchemaDir == "" {
+		return filepath.Join(g.pkgDir(), base)
+	}
+	prefix := strings.ReplaceAll(g.pkg.PkgPath, "/", "_")
+	return filepath.Join(g.opt.SchemaDir, prefix+"_"+base)
+}
+
+// schemaMethods returns the methods of the provided component that can be
+// represented in the selected schema format. Other methods are reported with
+// Options.Warn.
+func (g *generator) schemaMethods(comp *component, docs map[token.Pos]string) []schemaMethod {
+	var methods []schemaMethod
+	for _, m := range comp.methods() {
+		sig := m.Type().(*types.Signature)
+		method := schemaMethod{name: m.Name(), doc: docs[m.Pos()]}
+		for i := 1; i < sig.Params().Len(); i++ { // Skip initial context.Context
+			v := sig.Params().At(i)
+			method.params = append(method.params, schemaField{varName(v, "a", i-1), v.Type(), v.Pos()})
+		}
+		if elem, ok := streamElem(m); ok {
+			method.streaming = true
+			method.results = []schemaField{{name: "r0", t: elem}}
+		} else {
+			for i := 0; i < sig.Results().Len()-1; i++ { // Skip final error
+				v := sig.Results().At(i)
+				method.results = append(method.results, schemaField{varName(v, "r", i), v.Type(), v.Pos()})
+			}
+		}
+
+		if err := g.checkSchemaMethod(method); err != nil {
+			g.opt.Warn(errorf(g.fileset, m.Pos(), "Method %s.%s can't be represented in the %s schema and is left out: %v", comp.intfName(), m.Name(), g.opt.Schema, err))
+			continue
+		}
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// varName returns the name of the provided argument or result, or the
+// provided prefix followed by its index if it is unnamed.
+func varName(v *types.Var, prefix string, i int) string {
+	if v.Name() == "" || v.Name() == "_" {
+		return fmt.Sprintf("%s%d", prefix, i)
+	}
+	return v.Name()
+}
+
+// checkSchemaMethod returns an error if the provided method can't be
+// represented in the selected schema format.
+func (g *generator) checkSchemaMethod(m schemaMethod) error {
+	if m.streaming && g.opt.Schema == SchemaOpenAPI {
+		return fmt.Errorf("streaming methods are not supported")
+	}
+	for _, fields := range [][]schemaField{m.params, m.results} {
+		for _, f := range fields {
+			if err := g.checkSchemaType(f.t, map[*types.Named]bool{}); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSchemaType returns an error if values of the provided type can't be
+// represented in the selected schema format.
+func (g *generator) checkSchemaType(t types.Type, visiting map[*types.Named]bool) error {
+	proto := g.opt.Schema == SchemaProto
+	if isTime(t) || isDuration(t) {
+		return nil
+	}
+	if g.tset.isCustomMarshaled(t) {
+		return fmt.Errorf("type %s has custom serialization", t)
+	}
+
+	switch x := t.(type) {
+	case *types.Named:
+		if visiting[x] {
+			return nil
+		}
+		visiting[x] = true
+		if s, ok := x.Underlying().(*types.Struct); ok {
+			return g.checkSchemaFields(s, visiting)
+		}
+		return g.checkSchemaType(x.Underlying(), visiting)
+
+	case *types.Basic:
+		if x.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0 {
+			return nil
+		}
+
+	case *types.Pointer:
+		return g.checkSchemaType(x.Elem(), visiting)
+
+	case *types.Slice, *types.Array:
+		if isBytes(t) {
+			return nil
+		}
+		elem := elemType(t)
+		if proto && isRepeated(elem) {
+			return fmt.Errorf("type %s is a list of lists or maps", t)
+		}
+		return g.checkSchemaType(elem, visiting)
+
+	case *types.Map:
+		k, ok := x.Key().Underlying().(*types.Basic)
+		keys := types.IsString | types.IsInteger
+		if proto {
+			keys |= types.IsBoolean
+		}
+		if !ok || k.Info()&keys == 0 {
+			return fmt.Errorf("type %s has keys of type %s", t, x.Key())
+		}
+		if proto && isRepeated(x.Elem()) {
+			return fmt.Errorf("type %s has lists or maps as values", t)
+		}
+		return g.checkSchemaType(x.Elem(), visiting)
+
+	case *types.Struct:
+		if proto {
+			return fmt.Errorf("anonymous struct type %s is not supported", t)
+		}
+		return g.checkSchemaFields(x, visiting)
+	}
+	return fmt.Errorf("type %s is not supported", t)
+}
+
+// checkSchemaFields returns an error if any field of the provided struct
+// can't be represented in the selected schema format.
+func (g *generator) checkSchemaFields(s *types.Struct, visiting map[*types.Named]bool) error {
+	for _, f := range structFields(s) {
+		if err := g.checkSchemaType(f.t, visiting); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// structFields returns the serialized fields of the provided struct, named by
+// their wire names.
+func structFields(s *types.Struct) []schemaField {
+	var fields []schemaField
+	for _, f := range autoMarshalFields(s) {
+		fields = append(fields, schemaField{f.wire, f.Type(), f.Pos()})
+	}
+	return fields
+}
+
+// isBytes returns whether t is a slice or array of bytes.
+func isBytes(t types.Type) bool {
+	elem := elemType(t)
+	if elem == nil {
+		return false
+	}
+	b, ok := elem.(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+// isRepeated returns whether values of type t are represented as a list or a
+// map, other than a list of bytes.
+func isRepeated(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if isBytes(t) || isTime(t) || isDuration(t) {
+		return false
+	}
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Array, *types.Map:
+		return true
+	}
+	return false
+}
+
+// elemType returns the element type of a slice or array type, or nil if t is
+// neither.
+func elemType(t types.Type) types.Type {
+	switch x := t.Underlying().(type) {
+	case *types.Slice:
+		return x.Elem()
+	case *types.Array:
+		return x.Elem()
+	}
+	return nil
+}
+
+// schemaNames assigns unique schema names to named Go types.
+type schemaNames struct {
+	names typeutil.Map    // *types.Named -> string
+	taken map[string]bool // assigned names
+}
+
+// name returns the schema name of the provided type: its name, followed by its
+// type arguments, if any, and prefixed by its package name if another type
+// already has the name.
+func (n *schemaNames) name(t *types.Named) string {
+	if name, ok := n.names.At(t).(string); ok {
+		return name
+	}
+	name := t.Obj().Name()
+	if t.TypeArgs().Len() > 0 {
+		s := types.TypeString(t, func(*types.Package) string { return "" })
+		name = strings.Trim(strings.Map(func(r rune) rune {
+			if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return '_'
+		}, s), "_")
+	}
+	if n.taken[name] && t.Obj().Pkg() != nil {
+		name = t.Obj().Pkg().Name() + "_" + name
+	}
+	base := name
+	for i := 2; n.taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	n.taken[name] = true
+	n.names.Set(t, name)
+	return name
+}
+
+// OpenAPI documents. Only the subset of the specification [1] needed to
+// describe component methods is included.
+//
+// [1]: https://spec.openapis.org/oas/v3.1.0
+type (
+	openAPIDoc struct {
+		OpenAPI    string                      `json:"openapi"`
+		Info       openAPIInfo                 `json:"info"`
+		Paths      map[string]*openAPIPathItem `json:"paths"`
+		Components openAPIComponents           `json:"components"`
+	}
+
+	openAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	openAPIPathItem struct {
+		Post *openAPIOperation `json:"post"`
+	}
+
+	openAPIOperation struct {
+		OperationID string                  `json:"operationId"`
+		Tags        []string                `json:"tags"`
+		Description string                  `json:"description,omitempty"`
+		RequestBody *openAPIBody            `json:"requestBody"`
+		Responses   map[string]*openAPIBody `json:"responses"`
+	}
+
+	openAPIBody struct {
+		Description string                      `json:"description,omitempty"`
+		Required    bool                        `json:"required,omitempty"`
+		Content     map[string]openAPIMediaType `json:"content"`
+	}
+
+	openAPIMediaType struct {
+		Schema *jsonSchema `json:"schema"`
+	}
+
+	openAPIComponents struct {
+		Schemas map[string]*jsonSchema `json:"schemas"`
+	}
+)
+
+// openAPIDocument returns an OpenAPI document describing the provided
+// methods of the provided components. The method Get of component
+// example.com/mypkg/Cache, for example, is described as the operation
+// "POST /example.com/mypkg/Cache/Get", whose request body is a JSON object
+// with a property for every argument of Get, and whose response body is a JSON
+// object with a property for every result of Get other than the final error.
+// Arguments and results are named as in the method's declaration, or a0, a1,
+// ... and r0, r1, ... if unnamed.
+func (g *generator) openAPIDocument(components []*component, methods map[*component][]schemaMethod, docs map[token.Pos]string) ([]byte, error) {
+	b := openAPIBuilder{
+		docs:    docs,
+		names:   &schemaNames{taken: map[string]bool{errorSchemaName: true}},
+		schemas: map[string]*jsonSchema{},
+	}
+	b.schemas[errorSchemaName] = &jsonSchema{
+		Description: "An error returned by a component method.",
+		Type:        "object",
+		Properties:  map[string]*jsonSchema{"message": {Type: "string"}},
+		Required:    []string{"message"},
+	}
+	errorRef := &jsonSchema{Ref: "#/components/schemas/" + errorSchemaName}
+
+	doc := openAPIDoc{
+		OpenAPI:    "3.1.0",
+		Info:       openAPIInfo{Title: g.pkg.PkgPath, Version: "1"},
+		Paths:      map[string]*openAPIPathItem{},
+		Components: openAPIComponents{Schemas: b.schemas},
+	}
+	for _, comp := range components {
+		for _, m := range methods[comp] {
+			doc.Paths["/"+comp.fullIntfName()+"/"+m.name] = &openAPIPathItem{
+				Post: &openAPIOperation{
+					OperationID: comp.intfName() + "_" + m.name,
+					Tags:        []string{comp.fullIntfName()},
+					Description: m.doc,
+					RequestBody: &openAPIBody{
+						Required: true,
+						Content:  jsonContent(b.object(m.params)),
+					},
+					Responses: map[string]*openAPIBody{
+						"200": {
+							Description: "The results of the method.",
+							Content:     jsonContent(b.object(m.results)),
+						},
+						"default": {
+							Description: "The error returned by the method.",
+							Content:     jsonContent(errorRef),
+						},
+					},
+				},
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// jsonContent returns the content of a request or response body holding a
+// JSON value with the provided schema.
+func jsonContent(schema *jsonSchema) map[string]openAPIMediaType {
+	return map[string]openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// openAPIBuilder builds the OpenAPI schemas of Go types, as serialized to
+// JSON.
+type openAPIBuilder struct {
+	docs    map[token.Pos]string   // see fieldDocs
+	names   *schemaNames           // names of named struct types
+	schemas map[string]*jsonSchema // schemas of named struct types, by name
+}
+
+// object returns the schema of an object with the provided fields.
+func (b *openAPIBuilder) object(fields []schemaField) *jsonSchema {
+	schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+	for _, f := range fields {
+		prop := b.schema(f.t)
+		if doc := b.docs[f.pos]; doc != "" {
+			prop.Description = doc
+		}
+		schema.Properties[f.name] = prop
+		schema.Required = append(schema.Required, f.name)
+	}
+	return schema
+}
+
+// schema returns the schema of values of the provided type, which must be
+// representable.
+func (b *openAPIBuilder) schema(t types.Type) *jsonSchema {
+	switch {
+	case isTime(t):
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case isDuration(t):
+		return &jsonSchema{Type: "integer", Format: "int64", Description: "A duration, in nanoseconds."}
+	case isBytes(t):
+		return &jsonSchema{Type: "string", ContentEncoding: "base64"}
+	}
+
+	switch x := t.(type) {
+	case *types.Named:
+		s, ok := x.Underlying().(*types.Struct)
+		if !ok {
+			return b.schema(x.Underlying())
+		}
+		name := b.names.name(x)
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = nil // guards against recursive types
+			schema := b.object(structFields(s))
+			schema.Description = b.docs[x.Obj().Pos()]
+			b.schemas[name] = schema
+		}
+		return &jsonSchema{Ref: "#/components/schemas/" + name}
+
+	case *types.Basic:
+		zero := 0
+		switch x.Kind() {
+		case types.Bool:
+			return &jsonSchema{Type: "boolean"}
+		case types.Int8, types.Int16, types.Int32:
+			return &jsonSchema{Type: "integer", Format: "int32"}
+		case types.Int, types.Int64:
+			return &jsonSchema{Type: "integer", Format: "int64"}
+		case types.Uint8, types.Uint16:
+			return &jsonSchema{Type: "integer", Format: "int32", Minimum: &zero}
+		case types.Uint32:
+			return &jsonSchema{Type: "integer", Format: "int64", Minimum: &zero}
+		case types.Uint, types.Uint64, types.Uintptr:
+			return &jsonSchema{Type: "integer", Minimum: &zero}
+		case types.Float32:
+			return &jsonSchema{Type: "number", Format: "float"}
+		case types.Float64:
+			return &jsonSchema{Type: "number", Format: "double"}
+		case types.String:
+			return &jsonSchema{Type: "string"}
+		}
+
+	case *types.Pointer:
+		return b.schema(x.Elem())
+
+	case *types.Slice:
+		return &jsonSchema{Type: "array", Items: b.schema(x.Elem())}
+
+	case *types.Array:
+		n := int(x.Len())
+		return &jsonSchema{Type: "array", Items: b.schema(x.Elem()), MinItems: &n, MaxItems: &n}
+
+	case *types.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: b.schema(x.Elem())}
+
+	case *types.Struct:
+		return b.object(structFields(x))
+	}
+	panic(fmt.Sprintf("generator: type %v can't be represented in an OpenAPI schema", t))
+}
+
+// protoServices returns a protobuf file with a service for every provided
+// component. The method Get of component Cache, for example, becomes the rpc
+// Get of service Cache, with messages Cache_GetRequest and Cache_GetResponse
+// holding its arguments and results. Methods that return a weaver.Stream
+// become server streaming rpcs, with one response message per streamed value.
+// Named struct types become messages of the same name.
+func (g *generator) protoServices(components []*component, methods map[*component][]schemaMethod, docs map[token.Pos]string) []byte {
+	b := protoBuilder{
+		docs:     docs,
+		names:    &schemaNames{taken: map[string]bool{}},
+		messages: map[string]string{},
+		imports:  map[string]bool{},
+	}
+	for _, comp := range components {
+		for _, m := range methods[comp] {
+			b.names.taken[comp.intfName()+"_"+m.name+"Request"] = true
+			b.names.taken[comp.intfName()+"_"+m.name+"Response"] = true
+		}
+	}
+
+	var services, requests bytes.Buffer
+	for _, comp := range components {
+		writeComment(&services, "", docs[comp.intf.Obj().Pos()])
+		fmt.Fprintf(&services, "service %s {\n", comp.intfName())
+		for _, m := range methods[comp] {
+			req := comp.intfName() + "_" + m.name + "Request"
+			resp := comp.intfName() + "_" + m.name + "Response"
+			stream := ""
+			if m.streaming {
+				stream = "stream "
+			}
+			writeComment(&services, "  ", m.doc)
+			fmt.Fprintf(&services, "  rpc %s(%s) returns (%s%s);\n", m.name, req, stream, resp)
+			requests.WriteString("\n")
+			requests.WriteString(b.message(req, "", m.params))
+			requests.WriteString("\n")
+			requests.WriteString(b.message(resp, "", m.results))
+		}
+		services.WriteString("}\n\n")
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, `// Code generated by "weaver generate". DO NOT EDIT.`)
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, `syntax = "proto3";`)
+	fmt.Fprintln(&out)
+	fmt.Fprintf(&out, "package %s;\n\n", protoPackage(g.pkg.PkgPath))
+	if len(b.imports) > 0 {
+		imports := make([]string, 0, len(b.imports))
+		for imp := range b.imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		for _, imp := range imports {
+			fmt.Fprintf(&out, "import %q;\n", imp)
+		}
+		fmt.Fprintln(&out)
+	}
+	fmt.Fprintf(&out, "option go_package = %q;\n\n", g.pkg.PkgPath)
+	out.Write(services.Bytes())
+	out.WriteString(strings.TrimPrefix(requests.String(), "\n"))
+
+	names := make([]string, 0, len(b.messages))
+	for name := range b.messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.WriteString("\n")
+		out.WriteString(b.messages[name])
+	}
+	return out.Bytes()
+}
+
+// protoPackage returns the protobuf package of the provided Go package path.
+// For example, "example.com/my-pkg" becomes "example_com.my_pkg".
+func protoPackage(pkgPath string) string {
+	parts := strings.Split(pkgPath, "/")
+	for i, part := range parts {
+		part = strings.Map(func(r rune) rune {
+			if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return '_'
+		}, part)
+		if part == "" || ('0' <= part[0] && part[0] <= '9') {
+			part = "_" + part
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+// writeComment writes the provided doc comment, if any, to b as a protobuf
+// comment with the provided indentation.
+func writeComment(b *bytes.Buffer, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(b, "%s// %s\n", indent, line)
+	}
+}
+
+// protoBuilder builds the protobuf messages of Go types.
+type protoBuilder struct {
+	docs     map[token.Pos]string // see fieldDocs
+	names    *schemaNames         // names of messages of named struct types
+	messages map[string]string    // messages of named struct types, by name
+	imports  map[string]bool      // imported files
+}
+
+// message returns the definition of a message with the provided name, doc
+// and fields.
+func (b *protoBuilder) message(name, doc string, fields []schemaField) string {
+	var out bytes.Buffer
+	writeComment(&out, "", doc)
+	fmt.Fprintf(&out, "message %s {\n", name)
+	for i, f := range fields {
+		writeComment(&out, "  ", b.docs[f.pos])
+		fmt.Fprintf(&out, "  %s %s = %d;\n", b.fieldType(f.t), f.name, i+1)
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// fieldType returns the type of a message field holding values of the
+// provided type, which must be representable, with the "repeated" or
+// "optional" label if needed.
+func (b *protoBuilder) fieldType(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		typ := b.fieldType(p.Elem())
+		if _, ok := p.Elem().Underlying().(*types.Basic); ok {
+			// A nil pointer to a scalar is told apart from the zero value.
+			return "optional " + typ
+		}
+		return typ
+	}
+	if isBytes(t) || isTime(t) || isDuration(t) {
+		return b.typeName(t)
+	}
+	switch x := t.Underlying().(type) {
+	case *types.Slice:
+		return "repeated " + b.typeName(x.Elem())
+	case *types.Array:
+		return "repeated " + b.typeName(x.Elem())
+	case *types.Map:
+		return fmt.Sprintf("map<%s, %s>", b.typeName(x.Key()), b.typeName(x.Elem()))
+	}
+	return b.typeName(t)
+}
+
+// typeName returns the protobuf type of values of the provided type, which
+// must be representable and not repeated.
+func (b *protoBuilder) typeName(t types.Type) string {
+	switch {
+	case isTime(t):
+		b.imports["google/protobuf/timestamp.proto"] = true
+		return "google.protobuf.Timestamp"
+	case isDuration(t):
+		b.imports["google/protobuf/duration.proto"] = true
+		return "google.protobuf.Duration"
+	case isBytes(t):
+		return "bytes"
+	}
+
+	switch x := t.(type) {
+	case *types.Named:
+		s, ok := x.Underlying().(*types.Struct)
+		if !ok {
+			return b.typeName(x.Underlying())
+		}
+		name := b.names.name(x)
+		if _, ok := b.messages[name]; !ok {
+			b.messages[name] = "" // guards against recursive types
+			b.messages[name] = b.message(name, b.docs[x.Obj().Pos()], structFields(s))
+		}
+		return name
+
+	case *types.Basic:
+		switch x.Kind() {
+		case types.Bool:
+			return "bool"
+		case types.Int8, types.Int16, types.Int32:
+			return "int32"
+		case types.Int, types.Int64:
+			return "int64"
+		case types.Uint8, types.Uint16, types.Uint32:
+			return "uint32"
+		case types.Uint, types.Uint64, types.Uintptr:
+			return "uint64"
+		case types.Float32:
+			return "float"
+		case types.Float64:
+			return "double"
+		case types.String:
+			return "string"
+		}
+
+	case *types.Pointer:
+		return b.typeName(x.Elem())
+	}
+	panic(fmt.Sprintf("generator: type %v can't be represented in a protobuf schema", t))
+}
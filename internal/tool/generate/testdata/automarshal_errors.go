@@ -0,0 +1,61 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// func init() { codegen.RegisterError[NotFound]() }
+// func init() { codegen.RegisterError[Conflict]() }
+// func (x *Pair) WeaverMarshal(enc *codegen.Encoder)
+
+// UNEXPECTED
+// codegen.RegisterError[Pair]
+
+// AutoMarshal types that implement error, with value or pointer receivers,
+// are registered as error types.
+package foo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type NotFound struct {
+	weaver.AutoMarshal
+	Key string
+}
+
+func (e NotFound) Error() string { return fmt.Sprintf("%q not found", e.Key) }
+
+type Conflict struct {
+	weaver.AutoMarshal
+	Version int
+}
+
+func (e *Conflict) Error() string { return fmt.Sprintf("conflict at version %d", e.Version) }
+
+type Pair struct {
+	weaver.AutoMarshal
+	A, B int
+}
+
+type Foo interface {
+	Get(context.Context, Pair) (NotFound, *Conflict, error)
+}
+
+type impl struct{ weaver.Implements[Foo] }
+
+func (l *impl) Get(context.Context, Pair) (NotFound, *Conflict, error) {
+	return NotFound{}, nil, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// func (x *cached) WeaverMarshal(enc *codegen.Encoder) {
+// enc.String(x.Key)
+// enc.Int(x.Count)
+// func (x *cached) WeaverUnmarshal(dec *codegen.Decoder) {
+// *x = cached{}
+// x.Key = dec.String()
+// x.Count = dec.Int()
+// start := enc.BeginField("user_id")
+// start = enc.BeginField("Name")
+// case "user_id":
+// x.UserID = fdec.String()
+// case "Name":
+
+// UNEXPECTED
+// x.hits
+// x.done
+// x.Age
+// "UserID"
+// serviceweaver_size_ptr_cached
+
+// Fields of AutoMarshal structs with a `weaver:"-"` tag are not marshaled, and
+// fields with a `weaver:"name"` tag are named by their tag in the versioned
+// encoding.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type cached struct {
+	weaver.AutoMarshal
+	Key   string
+	Count int    `weaver:"count"`
+	hits  int    `weaver:"-"`
+	done  func() `weaver:"-"` // not serializable, but excluded
+}
+
+type user struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	UserID             string `weaver:"user_id"`
+	Name               string
+	Age                int `weaver:"-"`
+}
+
+type foo interface {
+	M(context.Context, cached, user) error
+	N(context.Context, *cached) error
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) M(context.Context, cached, user) error { return nil }
+func (impl) N(context.Context, *cached) error      { return nil }
@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// func (x *versioned) WeaverMarshal(enc *codegen.Encoder) {
+// enc.Len(2)
+// start := enc.BeginField("A")
+// start = enc.BeginField("B")
+// enc.EndField(start)
+// *x = versioned{}
+// name, fdec := dec.Field()
+// case "A":
+// x.A = fdec.Int()
+// case "B":
+// x.B = serviceweaver_dec_map_string_bool_
+// func (x *empty) WeaverUnmarshal(dec *codegen.Decoder) {
+// dec.Field()
+
+// Structs that embed weaver.AutoMarshal with a `weaver:"versioned"` tag use
+// the versioned encoding.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type versioned struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	A                  int
+	B                  map[string]bool
+}
+
+type empty struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+}
+
+type foo interface {
+	M(context.Context, versioned, empty) error
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) M(context.Context, versioned, empty) error { return nil }
@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// wEaVeReDgE:foo/A→foo/B
+// wEaVeReDgE:foo/A→foo/C
+
+// A weaver.BalancedRef is a reference to a component, like a weaver.Ref.
+package foo
+
+import (
+	"github.com/ServiceWeaver/weaver"
+)
+
+type A interface{}
+type B interface{}
+type C interface{}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.BalancedRef[B, weaver.LeastLoaded]
+	c weaver.Ref[C]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+type c struct {
+	weaver.Implements[C]
+}
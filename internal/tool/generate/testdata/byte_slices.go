@@ -0,0 +1,52 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// size += codegen.BytesSize(a0)
+// size += codegen.BytesSize(a1)
+// s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+// func serviceweaver_enc_slice_byte_
+// enc.Bytes(arg)
+// return dec.Bytes()
+// serviceweaver_enc_slice_slice_byte_
+
+// UNEXPECTED
+// enc.Byte(arg[i])
+// res[i] = dec.Byte()
+
+// Byte slices, including named ones, are encoded in bulk.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type Blob []byte
+
+type impl struct{ weaver.Implements[Foo] }
+
+type Foo interface {
+	Put(context.Context, []byte, Blob) error
+	Get(context.Context, string) ([][]byte, error)
+}
+
+func (l *impl) Put(context.Context, []byte, Blob) error {
+	return nil
+}
+
+func (l *impl) Get(context.Context, string) ([][]byte, error) {
+	return nil, nil
+}
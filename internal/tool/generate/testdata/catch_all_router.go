@@ -0,0 +1,45 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// shardKey := _hashRouted(r.A(ctx))
+// shardKey := _hashRouted(r.Route(ctx, "B", a0, a1))
+// loadKey := _hashRouted(r.Route(ctx, "B", a0, a1))
+// defer func() { s.addLoad(loadKey, load.Load()) }()
+// var _ func(context.Context, string, ...any) int = (&router{}).Route
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type Routed interface {
+	A(context.Context) error
+	B(context.Context, string, ...int) error
+}
+
+type routed struct {
+	weaver.Implements[Routed]
+	weaver.WithRouter[router]
+}
+
+func (routed) A(context.Context) error                 { return nil }
+func (routed) B(context.Context, string, ...int) error { return nil }
+
+type router struct{}
+
+func (router) A(context.Context) int                     { return 42 }
+func (router) Route(context.Context, string, ...any) int { return 0 }
@@ -0,0 +1,45 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// shardKey := _hashRouted(r.A(ctx, a0))
+// shardKey := _hashRouted(r.RouteFromContext(ctx))
+// loadKey := _hashRouted(r.RouteFromContext(ctx))
+// var _ func(context.Context) string = (&router{}).RouteFromContext
+// var _ weaver.ContextRouter[string] = (*router)(nil)
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type Routed interface {
+	A(context.Context, string) error
+	B(context.Context, int) error
+}
+
+type routed struct {
+	weaver.Implements[Routed]
+	weaver.WithRouter[router]
+}
+
+func (routed) A(context.Context, string) error { return nil }
+func (routed) B(context.Context, int) error    { return nil }
+
+type router struct{}
+
+func (router) A(_ context.Context, key string) string  { return key }
+func (router) RouteFromContext(context.Context) string { return "" }
@@ -0,0 +1,54 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// shardKey := _hashRouted(r.A(ctx, a0, a1))
+// var router router
+// return router.Hash(r)
+// var _ func(k key) uint64 = (&router{}).Hash
+// func _orderedCodeRouted(r key) codegen.OrderedCode {
+
+// UNEXPECTED
+// h.WriteString(string(r.Tenant))
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type Routed interface {
+	A(context.Context, string, int) error
+}
+
+type routed struct {
+	weaver.Implements[Routed]
+	weaver.WithRouter[router]
+}
+
+func (routed) A(context.Context, string, int) error { return nil }
+
+type key struct {
+	Tenant string
+	Shard  int
+}
+
+type router struct{}
+
+func (router) A(_ context.Context, tenant string, shard int) key {
+	return key{tenant, shard}
+}
+
+func (router) Hash(k key) uint64 { return uint64(len(k.Tenant)) }
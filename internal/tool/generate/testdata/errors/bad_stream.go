@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: Return 1 has type weaver.Stream[int]. A method that returns a weaver.Stream must have results (weaver.Stream[T], error).
+// ERROR: Return 0 has type weaver.Stream[chan int], but chan int is not serializable.
+// ERROR: Argument 1 has type weaver.Stream[int], which is not serializable.
+
+// Streams must be the only result, must stream serializable values, and can't
+// be passed as arguments.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	A(context.Context) (int, weaver.Stream[int], error)
+	B(context.Context) (weaver.Stream[chan int], error)
+	C(context.Context, weaver.Stream[int]) error
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) A(context.Context) (int, weaver.Stream[int], error) { return 0, nil, nil }
+func (impl) B(context.Context) (weaver.Stream[chan int], error) { return nil, nil }
+func (impl) C(context.Context, weaver.Stream[int]) error        { return nil }
@@ -0,0 +1,36 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: Argument 1 (events) has type chan int, which is not serializable
+
+// Non-serializable arguments and results are reported by name.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	A(ctx context.Context, events chan int) error
+	B(ctx context.Context) (n int, done chan struct{}, err error)
+}
+
+type impl struct {
+	weaver.Implements[foo]
+}
+
+func (impl) A(context.Context, chan int) error             { return nil }
+func (impl) B(context.Context) (int, chan struct{}, error) { return 0, nil, nil }
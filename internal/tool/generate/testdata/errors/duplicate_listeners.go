@@ -0,0 +1,37 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: Duplicate listener "lis": field barImpl.renamed of component foo/bar and field fooImpl.lis of component foo/foo
+
+// Listener names must be unique across components, including names given by
+// weaver struct tags.
+package foo
+
+import (
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface{}
+
+type fooImpl struct {
+	weaver.Implements[foo]
+	lis weaver.Listener
+}
+
+type bar interface{}
+
+type barImpl struct {
+	weaver.Implements[bar]
+	renamed weaver.Listener `weaver:"lis"`
+}
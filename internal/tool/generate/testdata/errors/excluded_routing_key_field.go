@@ -0,0 +1,46 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: which excludes field Region from marshalling
+
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type key struct {
+	weaver.AutoMarshal
+	User   string
+	Region string `weaver:"-"`
+}
+
+type foo interface {
+	M(context.Context, string, string) error
+}
+
+type router struct{}
+
+func (router) M(_ context.Context, user, region string) key {
+	return key{User: user, Region: region}
+}
+
+type impl struct {
+	weaver.Implements[foo]
+	weaver.WithRouter[router]
+}
+
+func (impl) M(context.Context, string, string) error { return nil }
@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: weaver.WithPriority argument fooPriority has no method Priority(context.Context) int
+
+// The Priority method of a prioritizer must return an int.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	M(context.Context) error
+}
+
+type impl struct {
+	weaver.Implements[foo]
+	weaver.WithPriority[fooPriority]
+}
+
+func (impl) M(context.Context) error { return nil }
+
+type fooPriority struct{}
+
+func (fooPriority) Priority(context.Context) int64 { return 0 }
@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: Return type of "Route" (string) does not match previously seen routing key type (int)
+
+// Catch-all routing function with a different routing key type.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	A(context.Context, int) error
+	B(context.Context, string) error
+}
+
+type impl struct {
+	weaver.Implements[foo]
+	weaver.WithRouter[fooRouter]
+}
+
+func (*impl) A(context.Context, int) error    { return nil }
+func (*impl) B(context.Context, string) error { return nil }
+
+type fooRouter struct{}
+
+func (fooRouter) A(_ context.Context, x int) int               { return x }
+func (fooRouter) Route(context.Context, string, ...any) string { return "" }
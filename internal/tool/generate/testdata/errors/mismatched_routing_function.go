@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// ERROR: method arguments (context.Context, int, bool, string) do not match
+// ERROR: method "A" of "foo" has signature func(context.Context, int, bool, string) error
 
 // Mismatched routing function.
 package foo
@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: Routing function "Lookpu" does not match any method of "foo". Did you mean "Lookup"?
+
+// Misspelled routing function.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	Lookup(context.Context, string) error
+	Store(context.Context, string) error
+}
+
+type impl struct {
+	weaver.Implements[foo]
+	weaver.WithRouter[fooRouter]
+}
+
+func (*impl) Lookup(context.Context, string) error { return nil }
+func (*impl) Store(context.Context, string) error  { return nil }
+
+type fooRouter struct{}
+
+func (fooRouter) Lookpu(_ context.Context, key string) string { return key }
+func (fooRouter) Store(_ context.Context, key string) string  { return key }
@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: Routing function "Put" has signature func(_ context.Context, key string) string, but method "Put" of "foo" has signature func(context.Context, string, string) error
+
+// Every mismatched routing function is reported, not just the first.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	Get(context.Context, string) error
+	Put(context.Context, string, string) error
+}
+
+type impl struct {
+	weaver.Implements[foo]
+	weaver.WithRouter[fooRouter]
+}
+
+func (*impl) Get(context.Context, string) error         { return nil }
+func (*impl) Put(context.Context, string, string) error { return nil }
+
+type fooRouter struct{}
+
+func (fooRouter) Gte(_ context.Context, key string) string { return key }
+func (fooRouter) Put(_ context.Context, key string) string { return key }
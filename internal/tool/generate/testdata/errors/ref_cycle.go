@@ -0,0 +1,53 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ERROR: weaver.Ref cycle: foo/A -> foo/B -> foo/C -> foo/A
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type A interface {
+	M(context.Context) error
+}
+
+type B interface {
+	M(context.Context) error
+}
+
+type C interface {
+	M(context.Context) error
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+}
+
+type b struct {
+	weaver.Implements[B]
+	c weaver.Ref[C]
+}
+
+type c struct {
+	weaver.Implements[C]
+	a weaver.Ref[A]
+}
+
+func (a) M(context.Context) error { return nil }
+func (b) M(context.Context) error { return nil }
+func (c) M(context.Context) error { return nil }
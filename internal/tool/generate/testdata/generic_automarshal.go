@@ -0,0 +1,67 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// var _ codegen.AutoMarshal = (*page[int])(nil)
+// type __is_page_int_
+// var _ codegen.AutoMarshal = (*page[string])(nil)
+// func (x *page[T]) WeaverMarshal(enc *codegen.Encoder) {
+// switch x := any(x).(type) {
+// case *page[int]:
+// case *page[string]:
+// func (x *page[T]) WeaverUnmarshal(dec *codegen.Decoder) {
+// func serviceweaver_marshal_page_int_
+// func serviceweaver_unmarshal_page_string_
+// func (x *pair[K, V]) WeaverMarshal(enc *codegen.Encoder) {
+// case *pair[string, bool]:
+// enc.Len(2)
+// start := enc.BeginField("Key")
+
+// UNEXPECTED
+// case *page[bool]:
+
+// Generic structs that embed weaver.AutoMarshal get marshaling code for every
+// instantiation used in the package.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type page[T any] struct {
+	weaver.AutoMarshal
+	Items []T
+	Next  int
+}
+
+type pair[K comparable, V any] struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	Key                K
+	Value              V
+}
+
+type foo interface {
+	A(context.Context, page[int]) (page[string], error)
+	B(context.Context, []pair[string, bool]) error
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) A(context.Context, page[int]) (page[string], error) {
+	return page[string]{}, nil
+}
+
+func (impl) B(context.Context, []pair[string, bool]) error { return nil }
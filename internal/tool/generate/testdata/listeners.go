@@ -0,0 +1,45 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// Listeners: []string{"a", "c", "renamed", "secure", "shared"},
+// Listeners: []string{"b", "other_a"},
+
+// Listener names come from field names or weaver struct tags, and the tls and
+// reuseport tag options do not change a listener's name. Fields with the same
+// name in different components don't collide if a tag renames one of them.
+package foo
+
+import (
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface{}
+
+type impl struct {
+	weaver.Implements[foo]
+	a weaver.Listener
+	b weaver.Listener `weaver:"renamed"`
+	d weaver.Listener `weaver:"secure,tls"`
+	c weaver.Listener `weaver:",tls"`
+	e weaver.Listener `weaver:"shared,tls,reuseport"`
+}
+
+type bar interface{}
+
+type barImpl struct {
+	weaver.Implements[bar]
+	a weaver.Listener `weaver:"other_a"`
+	b weaver.Listener
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// enc.EncodeMarshaler(&x.A)
+// dec.DecodeUnmarshaler(&x.A)
+// serviceweaver_enc_ptr_byPointer
+// serviceweaver_dec_slice_ptr_byPointer
+
+// Types with Marshal and Unmarshal methods (e.g., gogoproto messages) are
+// serialized using those methods.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type byValue struct{ notSerializable chan int }
+type byPointer struct{ notSerializable chan int }
+
+func (byValue) Marshal() ([]byte, error)    { return nil, nil }
+func (byValue) Unmarshal([]byte) error      { return nil }
+func (*byPointer) Marshal() ([]byte, error) { return nil, nil }
+func (*byPointer) Unmarshal([]byte) error   { return nil }
+
+type wrapper struct {
+	weaver.AutoMarshal
+	A byValue
+	B *byPointer
+	C []*byPointer
+}
+
+type foo interface {
+	M(context.Context, byValue, *byPointer, []*byPointer) (*byPointer, error)
+	N(context.Context, wrapper) error
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) M(context.Context, byValue, *byPointer, []*byPointer) (*byPointer, error) {
+	return nil, nil
+}
+
+func (impl) N(context.Context, wrapper) error { return nil }
@@ -16,11 +16,13 @@
 // var a0 map[int][]X
 // var a1 map[int]bool
 // var a2 map[[10]int]int
-// r0, appErr := s.impl.A
+// r0, err = s.impl.A
 // serviceweaver_enc_map_int_slice_X
 // serviceweaver_enc_map_int_bool
 // serviceweaver_dec_map_array_10_int_int
 // serviceweaver_enc_map_Y_map_string_slice_X
+// for _, k := range codegen.SortedKeys(arg) {
+// for k, v := range arg {
 
 // UNEXPECTED
 // Preallocate
@@ -17,7 +17,10 @@
 // codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "foo/foo", Method: "Method", Remote: true})
 // methodMetrics *codegen.MethodMetrics
 // begin := s.methodMetrics.Begin(
-// s.methodMetrics.End(begin
+// s.methodMetrics.End(begin, err != nil, 0, 0)
+// var transportErr bool
+// s.methodMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+// transportErr = true
 
 package foo
 
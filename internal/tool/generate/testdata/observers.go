@@ -0,0 +1,54 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// observer *fooObserver
+// return foo_intercept(foo_local_stub{impl: impl.(foo), caller: caller, tracer: tracer, aMetrics:
+// observer: codegen.Observer[fooObserver](impl)
+// return foo_server_stub{impl: foo_intercept(impl.(foo), codegen.ServerInterceptor(impl), codegen.Call{Component: "foo/foo", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}, observer: codegen.Observer[fooObserver](impl)}
+// return s.observer.A(ctx, s.impl, a0, a1...)
+// return s.impl.B(ctx)
+// r0, err = s.observer.A(ctx, s.impl, a0, a1...)
+// return s.impl.B(ctx)
+
+// Calls to methods with an observer method are intercepted by the observer.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	A(context.Context, int, ...string) (int, error)
+	B(context.Context) error
+}
+
+type impl struct {
+	weaver.Implements[foo]
+	weaver.WithObserver[fooObserver]
+}
+
+func (*impl) A(context.Context, int, ...string) (int, error) { return 0, nil }
+func (*impl) B(context.Context) error                        { return nil }
+
+type fooObserver struct{}
+
+func (*fooObserver) A(ctx context.Context, next foo, x int, ys ...string) (int, error) {
+	return next.A(ctx, x, ys...)
+}
+
+// Helper methods of an observer are ignored.
+func (*fooObserver) helper() {}
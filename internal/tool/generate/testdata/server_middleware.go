@@ -0,0 +1,54 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// package foo
+// middleware *codegen.ServerMiddlewares
+// addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+// func (s foo_server_stub) Use(mw codegen.ServerMiddleware) {
+// s.middleware.Use(mw)
+// appErr := s.middleware.Run(ctx, "foo/Foo", "A", func(ctx context.Context) error {
+// return s.impl.A(ctx, a0)
+// var r0 string
+// var r1 int
+// appErr := s.middleware.Run(ctx, "foo/Foo", "B", func(ctx context.Context) (err error) {
+// r0, r1, err = s.impl.B(ctx)
+
+// UNEXPECTED
+// appErr := s.impl.A(ctx, a0)
+// r0, r1, appErr := s.impl.B(ctx)
+
+// Server stubs run methods through the middleware chain.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type Foo interface {
+	A(context.Context, int) error
+	B(context.Context) (string, int, error)
+}
+
+type impl struct{ weaver.Implements[Foo] }
+
+func (l *impl) A(context.Context, int) error {
+	return nil
+}
+
+func (l *impl) B(context.Context) (string, int, error) {
+	return "", 0, nil
+}
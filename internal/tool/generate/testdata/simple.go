@@ -17,8 +17,8 @@
 // func init() {
 // codegen.Register(codegen.Registration{
 // impl{}
-// return foo_local_stub{impl: impl.(foo),
-// return foo_client_stub{stub: stub,
+// return foo_intercept(foo_local_stub{impl: impl.(foo),
+// return foo_intercept(foo_client_stub{stub: stub,
 // type foo_local_stub struct
 // type foo_client_stub struct
 // M(ctx context.Context) (err error) {
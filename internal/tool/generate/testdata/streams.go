@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// func (s foo_client_stub) Values(ctx context.Context, a0 int) (r0 weaver.Stream[*pair], err error) {
+// stream, err = s.stub.RunStream(ctx, 2, enc.Data(), shardKey)
+// r0 = codegen.ReadStream(stream, func(dec *codegen.Decoder) (x *pair) {
+// var _ codegen.StreamServer = (*foo_server_stub)(nil)
+// func (s foo_server_stub) GetStreamFn(method string) func(ctx context.Context, args []byte, send func([]byte) error) ([]byte, error) {
+// func (s foo_server_stub) values(ctx context.Context, args []byte, send func([]byte) error) (res []byte, err error) {
+// appErr = codegen.WriteStream[*pair](send, r0, func(enc *codegen.Encoder, x *pair) {
+// serviceweaver_enc_ptr_pair_
+// func (s foo_server_stub) names(ctx context.Context, args []byte, send func([]byte) error) (res []byte, err error) {
+// appErr = codegen.WriteStream[string](send, r0, func(enc *codegen.Encoder, x string) {
+
+// Methods that return a weaver.Stream stream their values.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type pair struct {
+	weaver.AutoMarshal
+	X, Y int
+}
+
+type foo interface {
+	Names(context.Context) (weaver.Stream[string], error)
+	Values(context.Context, int) (weaver.Stream[*pair], error)
+	Sum(context.Context, []int) (int, error)
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) Names(context.Context) (weaver.Stream[string], error) {
+	return nil, nil
+}
+
+func (impl) Values(context.Context, int) (weaver.Stream[*pair], error) {
+	return nil, nil
+}
+
+func (impl) Sum(context.Context, []int) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// codegen.EncodeRegistered(enc, x.ID)
+// codegen.DecodeRegistered(dec, &x.ID)
+// codegen.EncodeRegistered(enc, a0)
+// codegen.DecodeRegistered(dec, &a0)
+// codegen.EncodeRegistered(enc, arg[i])
+// codegen.DecodeRegistered(dec, &res[i])
+
+// Types with codecs registered using codegen.RegisterTypeCodec are serialized
+// using the registered functions.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/uuid"
+)
+
+// opaque is not serializable on its own.
+type opaque struct{ c chan int }
+
+func init() {
+	codegen.RegisterTypeCodec(
+		func(enc *codegen.Encoder, id uuid.UUID) { enc.Bytes(id[:]) },
+		func(dec *codegen.Decoder) uuid.UUID { return uuid.UUID(dec.Bytes()) },
+	)
+	codegen.RegisterTypeCodec[opaque](
+		func(*codegen.Encoder, opaque) {},
+		func(*codegen.Decoder) opaque { return opaque{} },
+	)
+}
+
+type record struct {
+	weaver.AutoMarshal
+	ID uuid.UUID
+	O  opaque
+}
+
+type foo interface {
+	M(context.Context, uuid.UUID, []opaque) (record, error)
+}
+
+type impl struct{ weaver.Implements[foo] }
+
+func (impl) M(context.Context, uuid.UUID, []opaque) (record, error) {
+	return record{}, nil
+}
@@ -12,6 +12,15 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// EXPECTED
+// type foo_intercept_stub struct
+// return foo_intercept(foo_local_stub{
+// codegen.LocalInterceptor(impl)
+// codegen.ClientInterceptor()
+// foo_server_stub{impl: foo_intercept(impl.(foo), codegen.ServerInterceptor(impl)
+// s.next.B(ctx, codegen.Arg[bool](args, 0), codegen.Arg[[]int](args, 1)...)
+
+// Variadic methods.
 package foo
 
 import (
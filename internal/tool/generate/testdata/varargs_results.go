@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// EXPECTED
+// Log(ctx context.Context, a0 string, a1 ...string) (r0 string, r1 int, err error)
+// r0, r1, err = s.impl.Log(ctx, a0, a1...)
+// r0, r1, err := s.next.Log(ctx, codegen.Arg[string](args, 0), codegen.Arg[[]string](args, 1)...)
+
+// Variadic methods with multiple results.
+package foo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+type foo interface {
+	Log(ctx context.Context, format string, args ...string) (string, int, error)
+}
+
+type impl struct {
+	weaver.Implements[foo]
+}
+
+func (impl) Log(ctx context.Context, format string, args ...string) (string, int, error) {
+	return format, len(args), nil
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"go/types"
 	"path"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -36,6 +37,13 @@ type typeSet struct {
 
 	automarshals          *typeutil.Map // types that implement AutoMarshal
 	automarshalCandidates *typeutil.Map // types that declare themselves AutoMarshal
+	codecs                *typeutil.Map // types registered with codegen.RegisterTypeCodec
+	versioned             typeutil.Map  // AutoMarshal types with a versioned encoding
+
+	// Generic types of the package that declare themselves AutoMarshal, and
+	// their instantiations found to be serializable. See checkSerializable.
+	genericAutomarshals  typeutil.Map
+	automarshalInstances typeutil.Map
 
 	// If checked[t] != nil, then checked[t] is the cached result of calling
 	// check(pkg, t, string[]{}). Otherwise, if checked[t] == nil, then t has
@@ -91,7 +99,7 @@ func (i importPkg) qualify(member string) string {
 }
 
 // newTypeSet returns the container for types found in pkg.
-func newTypeSet(pkg *packages.Package, automarshals, automarshalCandidates *typeutil.Map) *typeSet {
+func newTypeSet(pkg *packages.Package, automarshals, automarshalCandidates, codecs *typeutil.Map) *typeSet {
 	return &typeSet{
 		pkg:                   pkg,
 		imported:              []importPkg{},
@@ -99,6 +107,7 @@ func newTypeSet(pkg *packages.Package, automarshals, automarshalCandidates *type
 		importedByName:        map[string]importPkg{},
 		automarshals:          automarshals,
 		automarshalCandidates: automarshalCandidates,
+		codecs:                codecs,
 	}
 }
 
@@ -289,8 +298,36 @@ func (tset *typeSet) checkSerializable(t types.Type) []error {
 			// No need to check if x is an unexported type from another package
 			// since the Go compiler takes care of that.
 
+			if origin := x.Origin(); origin != x {
+				// x is an instantiation of a generic type. If the generic
+				// type declares itself AutoMarshal, x is serializable if the
+				// fields of x, with the type arguments of x substituted, are.
+				// The generated WeaverMarshal and WeaverUnmarshal methods of
+				// the generic type only handle the instantiations found in
+				// the package that declares it.
+				if tset.genericAutomarshals.At(origin) != nil {
+					serializable := true
+					s := x.Underlying().(*types.Struct)
+					for _, f := range autoMarshalFields(s) {
+						b := check(f.Type(), path+"."+f.Name(), true)
+						serializable = serializable && b
+					}
+					tset.checked.Set(t, serializable)
+					if serializable {
+						tset.automarshals.Set(t, struct{}{})
+						tset.automarshalInstances.Set(t, struct{}{})
+					}
+					break
+				}
+				if embedsAutoMarshal(origin) {
+					addError(fmt.Errorf("generic type %s embeds weaver.AutoMarshal, so it can only be instantiated in package %s, which declares it", origin.Obj().Name(), origin.Obj().Pkg().Path()))
+					tset.checked.Set(t, false)
+					break
+				}
+			}
+
 			// Check if the type implements one of the marshaler interfaces.
-			if tset.isProto(x) || tset.automarshals.At(t) != nil || tset.implementsAutoMarshal(x) || tset.hasMarshalBinary(x) {
+			if tset.isCustomMarshaled(x) || tset.automarshals.At(t) != nil || tset.implementsAutoMarshal(x) {
 				tset.checked.Set(t, true)
 				break
 			}
@@ -316,10 +353,10 @@ func (tset *typeSet) checkSerializable(t types.Type) []error {
 
 			// If the underlying type is a struct that has been declared to
 			// implement the AutoMarshal interface but hasn't yet been checked,
-			// then we need to recurse to detect cycles.
+			// then we need to recurse to detect cycles. Fields excluded from
+			// marshalling don't need to be serializable.
 			serializable := true
-			for i := 0; i < s.NumFields(); i++ {
-				f := s.Field(i)
+			for _, f := range autoMarshalFields(s) {
 				// We store the result of calling check in b rather than
 				// writing serializable = serializable && check(...) because we
 				// don't want to short circuit and avoid calling check.
@@ -372,6 +409,16 @@ func (tset *typeSet) checkSerializable(t types.Type) []error {
 			valSerializable := check(x.Elem(), path+".value", true)
 			tset.checked.Set(t, keySerializable && valSerializable)
 
+		case *types.Chan:
+			addError(fmt.Errorf("not a serializable type; channels cannot be serialized"))
+			// For a better error message, we don't memoize this.
+			return false
+
+		case *types.Signature:
+			addError(fmt.Errorf("not a serializable type; functions cannot be serialized"))
+			// For a better error message, we don't memoize this.
+			return false
+
 		default:
 			addError(fmt.Errorf("not a serializable type"))
 			// For a better error message, we don't memoize this.
@@ -451,6 +498,13 @@ func (tset *typeSet) sizeOfType(t types.Type) int {
 		return size
 
 	case *types.Named:
+		if tset.hasTypeCodec(x) || len(excludedFields(x)) > 0 {
+			// A registered codec may encode a value using any number of
+			// bytes, regardless of its underlying type, and the fields of an
+			// AutoMarshal struct that aren't marshaled take no bytes.
+			tset.sizes.Set(t, -1)
+			return -1
+		}
 		size := tset.sizeOfType(x.Underlying())
 		tset.sizes.Set(t, size)
 		return size
@@ -538,6 +592,13 @@ func (tset *typeSet) isMeasurable(t types.Type) bool {
 	case *types.Named:
 		if isWeaverAutoMarshal(x) {
 			tset.measurable.Set(t, true)
+		} else if tset.versioned.At(x.Origin()) != nil {
+			// For simplicity, we don't measure the field headers.
+			tset.measurable.Set(t, false)
+		} else if len(excludedFields(x)) > 0 {
+			// For simplicity, we don't measure structs with fields that
+			// aren't marshaled.
+			tset.measurable.Set(t, false)
 		} else if x.Obj().Pkg() != rootPkg {
 			tset.measurable.Set(t, false)
 		} else {
@@ -736,24 +797,53 @@ func isDecoderPtr(t types.Type) bool {
 // implements the encoding.BinaryMarshaler and binary.BinaryUnmarshaler
 // interfaces.
 func (tset *typeSet) hasMarshalBinary(t types.Type) bool {
+	return tset.hasMarshalMethods(t, "MarshalBinary", "UnmarshalBinary")
+}
+
+// hasMarshal returns whether the provided type is a concrete type with
+// Marshal() ([]byte, error) and Unmarshal([]byte) error methods. Messages
+// generated by gogoproto and by other alternative protobuf compilers have
+// such methods but don't implement proto.Message.
+func (tset *typeSet) hasMarshal(t types.Type) bool {
+	return tset.hasMarshalMethods(t, "Marshal", "Unmarshal")
+}
+
+// hasTypeCodec returns whether a codec for the provided type is registered
+// using codegen.RegisterTypeCodec.
+func (tset *typeSet) hasTypeCodec(t types.Type) bool {
+	return tset.codecs != nil && tset.codecs.At(t) != nil
+}
+
+// isCustomMarshaled returns whether the provided type is serialized using its
+// own serialization methods (i.e., proto serialization, MarshalBinary and
+// UnmarshalBinary, or Marshal and Unmarshal) or using a registered codec,
+// rather than using Service Weaver's encoding or AutoMarshal.
+func (tset *typeSet) isCustomMarshaled(t types.Type) bool {
+	return tset.hasTypeCodec(t) || tset.isProto(t) || tset.hasMarshalBinary(t) || tset.hasMarshal(t)
+}
+
+// hasMarshalMethods returns whether the provided type is a concrete type with
+// a marshal method of the form marshal() ([]byte, error) and an unmarshal
+// method of the form unmarshal([]byte) error.
+func (tset *typeSet) hasMarshalMethods(t types.Type, marshal, unmarshal string) bool {
 	if _, ok := t.Underlying().(*types.Interface); ok {
-		// A superinterface of BinaryMarshaler and BinaryUnmarshaler does
-		// "implement" the interfaces, but we only accept concrete types that
-		// implement the interfaces.
+		// An interface with the marshal and unmarshal methods (e.g., a
+		// superinterface of BinaryMarshaler and BinaryUnmarshaler) does have
+		// the methods, but we only accept concrete types.
 		return false
 	}
 
-	obj, _, _ := types.LookupFieldOrMethod(t, true, tset.pkg.Types, "MarshalBinary")
-	marshal, ok := obj.(*types.Func)
+	obj, _, _ := types.LookupFieldOrMethod(t, true, tset.pkg.Types, marshal)
+	m, ok := obj.(*types.Func)
 	if !ok {
 		return false
 	}
-	obj, _, _ = types.LookupFieldOrMethod(t, true, tset.pkg.Types, "UnmarshalBinary")
-	unmarshal, ok := obj.(*types.Func)
+	obj, _, _ = types.LookupFieldOrMethod(t, true, tset.pkg.Types, unmarshal)
+	u, ok := obj.(*types.Func)
 	if !ok {
 		return false
 	}
-	return isMarshalBinary(t, marshal) && isUnmarshalBinary(t, unmarshal)
+	return isMarshalMethod(t, m, marshal) && isUnmarshalMethod(t, u, unmarshal)
 }
 
 func isByteSlice(t types.Type) bool {
@@ -768,9 +858,10 @@ func isByteSlice(t types.Type) bool {
 	return e.Kind() == types.Byte
 }
 
-// isMarshalBinary returns true if m is MarshalBinary() ([]byte, error).
-func isMarshalBinary(t types.Type, m *types.Func) bool {
-	if m.Name() != "MarshalBinary" {
+// isMarshalMethod returns true if m is name() ([]byte, error), e.g.,
+// MarshalBinary() ([]byte, error).
+func isMarshalMethod(t types.Type, m *types.Func, name string) bool {
+	if m.Name() != name {
 		return false
 	}
 	sig, ok := m.Type().(*types.Signature)
@@ -799,9 +890,10 @@ func isMarshalBinary(t types.Type, m *types.Func) bool {
 	}
 }
 
-// isUnmarshalBinary returns true if m is UnmarshalBinary([]byte) error
-func isUnmarshalBinary(t types.Type, m *types.Func) bool {
-	if m.Name() != "UnmarshalBinary" {
+// isUnmarshalMethod returns true if m is name([]byte) error, e.g.,
+// UnmarshalBinary([]byte) error.
+func isUnmarshalMethod(t types.Type, m *types.Func, name string) bool {
+	if m.Name() != name {
 		return false
 	}
 	sig, ok := m.Type().(*types.Signature)
@@ -849,6 +941,10 @@ func isWeaverRef(t types.Type) bool {
 	return isWeaverType(t, "Ref", 1)
 }
 
+func isWeaverBalancedRef(t types.Type) bool {
+	return isWeaverType(t, "BalancedRef", 2)
+}
+
 func isWeaverListener(t types.Type) bool {
 	return isWeaverType(t, "Listener", 0)
 }
@@ -861,10 +957,115 @@ func isWeaverWithRouter(t types.Type) bool {
 	return isWeaverType(t, "WithRouter", 1)
 }
 
+func isWeaverWithConfig(t types.Type) bool {
+	return isWeaverType(t, "WithConfig", 1)
+}
+
+func isWeaverWithPriority(t types.Type) bool {
+	return isWeaverType(t, "WithPriority", 1)
+}
+
+// isPrioritizer returns whether *t has a Priority(context.Context) int method.
+func isPrioritizer(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "Priority")
+	m, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := m.Type().(*types.Signature)
+	if sig.Params().Len() != 1 || sig.Results().Len() != 1 || sig.Variadic() {
+		return false
+	}
+	basic, ok := sig.Results().At(0).Type().(*types.Basic)
+	return isContext(sig.Params().At(0).Type()) && ok && basic.Kind() == types.Int
+}
+
+func isWeaverWithObserver(t types.Type) bool {
+	return isWeaverType(t, "WithObserver", 1)
+}
+
+func isWeaverStream(t types.Type) bool {
+	return isWeaverType(t, "Stream", 1)
+}
+
 func isWeaverAutoMarshal(t types.Type) bool {
 	return isWeaverType(t, "AutoMarshal", 0)
 }
 
+// embedsAutoMarshal returns whether t is a struct type that embeds
+// weaver.AutoMarshal.
+func embedsAutoMarshal(t types.Type) bool {
+	s, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < s.NumFields(); i++ {
+		if f := s.Field(i); f.Embedded() && isWeaverAutoMarshal(f.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// autoMarshalField is a serialized field of a struct that embeds
+// weaver.AutoMarshal.
+type autoMarshalField struct {
+	*types.Var
+	wire string // see fieldWireName
+}
+
+// fieldWireName returns the wire name of the i-th field of the provided
+// AutoMarshal struct, and whether the field is excluded from marshalling. The
+// wire name of a field is the name set by its `weaver:"name"` struct tag, if
+// any, or else its Go name. It names the field in the versioned encoding (see
+// runtime/codegen/versioned.go) and in service schemas, so that renaming the
+// field in Go doesn't change them. A field with a `weaver:"-"` struct tag is
+// excluded.
+func fieldWireName(s *types.Struct, i int) (string, bool) {
+	switch tag := reflect.StructTag(s.Tag(i)).Get("weaver"); tag {
+	case "-":
+		return "", true
+	case "":
+		return s.Field(i).Name(), false
+	default:
+		return tag, false
+	}
+}
+
+// autoMarshalFields returns the fields of the provided AutoMarshal struct
+// that are serialized, i.e., every field but the embedded weaver.AutoMarshal
+// and the fields excluded with a `weaver:"-"` struct tag.
+func autoMarshalFields(s *types.Struct) []autoMarshalField {
+	var fields []autoMarshalField
+	for i := 0; i < s.NumFields(); i++ {
+		fi := s.Field(i)
+		if isWeaverAutoMarshal(fi.Type()) {
+			continue
+		}
+		if wire, excluded := fieldWireName(s, i); !excluded {
+			fields = append(fields, autoMarshalField{fi, wire})
+		}
+	}
+	return fields
+}
+
+// excludedFields returns the names of the fields of t, a struct type that
+// embeds weaver.AutoMarshal, that are excluded from marshalling with a
+// `weaver:"-"` struct tag. It returns nil for other types.
+func excludedFields(t types.Type) []string {
+	if !embedsAutoMarshal(t) {
+		return nil
+	}
+	s := t.Underlying().(*types.Struct)
+	var names []string
+	for i := 0; i < s.NumFields(); i++ {
+		if _, excluded := fieldWireName(s, i); excluded {
+			names = append(names, s.Field(i).Name())
+		}
+	}
+	return names
+}
+
 func isContext(t types.Type) bool {
 	n, ok := t.(*types.Named)
 	if !ok {
@@ -881,6 +1082,13 @@ func isError(t types.Type) bool {
 	return n.Obj().Pkg() == nil && n.Obj().Name() == "error"
 }
 
+// implementsError returns whether the provided type implements the error
+// interface.
+func implementsError(t types.Type) bool {
+	errorType := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	return types.Implements(t, errorType)
+}
+
 // isPrimitiveRouter returns whether the provided type is a valid primitive
 // router type (i.e. an integer, a float, or a string).
 func isPrimitiveRouter(t types.Type) bool {
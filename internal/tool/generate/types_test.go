@@ -80,6 +80,17 @@ func (t *target) UnmarshalBinary([]byte) error { return nil }
 type target struct { next *target }
 func (t *target) MarshalBinary() ([]byte, error) { return nil, nil }
 func (t *target) UnmarshalBinary([]byte) error { return nil }
+`, ""},
+
+		{"Marshaler", `
+type target struct{ notSerializable chan int }
+func (t *target) Marshal() ([]byte, error) { return nil, nil }
+func (t *target) Unmarshal([]byte) error { return nil }
+`, ""},
+		{"Marshaler by value", `
+type target struct{ notSerializable chan int }
+func (t target) Marshal() ([]byte, error) { return nil, nil }
+func (t target) Unmarshal([]byte) error { return nil }
 `, ""},
 
 		// Non-serializable types:
@@ -98,6 +109,19 @@ type target struct{
 	notSerializable chan int
 }
 func (t *target) UnmarshalBinary([]byte) error { return nil }
+`, "not serializable"},
+		{"missing Unmarshal", `
+type target struct{
+	notSerializable chan int
+}
+func (t *target) Marshal() ([]byte, error) { return nil, nil }
+`, "not serializable"},
+		{"bad Unmarshal", `
+type target struct{
+	notSerializable chan int
+}
+func (t *target) Marshal() ([]byte, error) { return nil, nil }
+func (t *target) Unmarshal(string) error { return nil }
 `, "not serializable"},
 		{"interface", `
 type target interface{
@@ -337,7 +361,7 @@ func compile(t *testing.T, contents string) (*typeSet, types.Type) {
 	}
 	var automarshals typeutil.Map
 	var automarshalCandidates typeutil.Map
-	tset := newTypeSet(pkgs[0], &automarshals, &automarshalCandidates)
+	tset := newTypeSet(pkgs[0], &automarshals, &automarshalCandidates, &typeutil.Map{})
 	return tset, target
 }
 
@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 	"syscall"
 
+	"github.com/ServiceWeaver/weaver/internal/exports"
 	"github.com/ServiceWeaver/weaver/internal/status"
 	"github.com/ServiceWeaver/weaver/internal/tool/config"
 	"github.com/ServiceWeaver/weaver/runtime"
@@ -142,10 +143,13 @@ persists, please file an issue at https://github.com/ServiceWeaver/weaver/issues
 		}
 	}()
 
-	// Deploy main.
+	// Deploy main and the exported components.
 	if err := d.startMain(); err != nil {
 		return fmt.Errorf("start main process: %w", err)
 	}
+	if err := d.startExported(); err != nil {
+		return fmt.Errorf("start exported components: %w", err)
+	}
 
 	// Wait for the status server to become active.
 	client := status.NewClient(lis.Addr().String())
@@ -191,6 +195,10 @@ persists, please file an issue at https://github.com/ServiceWeaver/weaver/issues
 			fmt.Fprintf(os.Stderr, "unregister deployment: %v\n", err)
 			code = 1
 		}
+		if err := exports.Unpublish(exportsDir, deploymentId); err != nil {
+			fmt.Fprintf(os.Stderr, "unpublish exported components: %v\n", err)
+			code = 1
+		}
 		os.Exit(code)
 	}()
 
@@ -26,6 +26,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ServiceWeaver/weaver/internal/exports"
 	imetrics "github.com/ServiceWeaver/weaver/internal/metrics"
 	"github.com/ServiceWeaver/weaver/internal/proxy"
 	"github.com/ServiceWeaver/weaver/internal/routing"
@@ -51,12 +52,17 @@ import (
 // The default number of times a component is replicated.
 const defaultReplication = 2
 
+// How often the key space of routed components is reassigned based on the
+// load reported by their replicas.
+const rebalanceInterval = 30 * time.Second
+
 // A deployer manages an application deployment.
 type deployer struct {
 	ctx          context.Context
 	ctxCancel    context.CancelFunc
 	deploymentId string
 	config       *MultiConfig
+	placement    *runtime.Placement
 	started      time.Time
 	logger       *slog.Logger
 	caCert       *x509.Certificate
@@ -68,6 +74,10 @@ type deployer struct {
 	// statsProcessor tracks and computes stats to be rendered on the /statusz page.
 	statsProcessor *imetrics.StatsProcessor
 
+	// exported lists the components that the weavelets of other deployments
+	// may call. See weaver.ExternalRef.
+	exported []string
+
 	mu      sync.Mutex            // guards the following
 	err     error                 // error that stopped the babysitter
 	groups  map[string]*group     // groups, by component name
@@ -79,6 +89,7 @@ type deployer struct {
 type group struct {
 	name        string                          // group name
 	envelopes   []*envelope.Envelope            // envelopes, one per weavelet
+	leader      *envelope.Envelope              // replica running one-replica tasks
 	pids        []int64                         // weavelet pids
 	started     map[string]bool                 // started components
 	addresses   map[string]bool                 // weavelet addresses
@@ -126,18 +137,29 @@ func newDeployer(ctx context.Context, deploymentId string, config *MultiConfig)
 	var caCert *x509.Certificate
 	var caKey crypto.PrivateKey
 	if config.Mtls {
-		caCert, caKey, err = certs.GenerateCACert()
+		// All deployments share a CA, so that the weavelets of one
+		// deployment can call the exported components of another. The
+		// identities in their certificates tell the deployments apart.
+		caCert, caKey, err = certs.LoadOrGenerateCACert(caFile)
 		if err != nil {
-			return nil, fmt.Errorf("cannot generate signing certificate: %w", err)
+			return nil, fmt.Errorf("cannot load signing certificate: %w", err)
 		}
 	}
 
+	// Let the weavelets find the exported components of other deployments.
+	config.App.Env = append(config.App.Env, fmt.Sprintf("%s=%s", exports.DirKey, exportsDir))
+
 	// Create the trace saver.
 	traceDB, err := perfetto.Open(ctx, perfettoFile)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open Perfetto database: %w", err)
 	}
 
+	placement, err := runtime.ParsePlacement(config.App)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	d := &deployer{
 		ctx:            ctx,
@@ -150,6 +172,7 @@ func newDeployer(ctx context.Context, deploymentId string, config *MultiConfig)
 		statsProcessor: imetrics.NewStatsProcessor(),
 		deploymentId:   deploymentId,
 		config:         config,
+		placement:      placement,
 		started:        time.Now(),
 		proxies:        map[string]*proxyInfo{},
 	}
@@ -166,6 +189,22 @@ func newDeployer(ctx context.Context, deploymentId string, config *MultiConfig)
 		return err
 	})
 
+	// Start a goroutine that rebalances the load of routed components.
+	d.running.Go(func() error {
+		ticker := time.NewTicker(rebalanceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := d.rebalance(); err != nil {
+					d.logger.Error("rebalance", "err", err)
+				}
+			}
+		}
+	})
+
 	// Start a goroutine that watches for context cancelation.
 	d.running.Go(func() error {
 		<-d.ctx.Done()
@@ -199,7 +238,7 @@ func (d *deployer) computeGroups() error {
 		}
 		var certPEM, keyPEM []byte
 		if d.config.Mtls {
-			cert, key, err := certs.GenerateSignedCert(d.caCert, d.caKey, component)
+			cert, key, err := certs.GenerateSignedCert(d.caCert, d.caKey, runtime.PeerIdentity(d.deploymentId, component))
 			if err != nil {
 				return nil, fmt.Errorf("cannot generate cert: %w", err)
 			}
@@ -263,6 +302,24 @@ func (d *deployer) computeGroups() error {
 		return err
 	}
 
+	// Ensure we have a group for every exported component, which may not be
+	// called by any component of the application. A component can only be
+	// exported in its config section.
+	for component := range d.config.App.Sections {
+		exported, err := runtime.ParseExported(component, d.config.App.Sections)
+		if err != nil {
+			return err
+		}
+		if !exported {
+			continue
+		}
+		if _, err := ensureGroup(component); err != nil {
+			return err
+		}
+		d.exported = append(d.exported, component)
+	}
+	slices.Sort(d.exported)
+
 	d.groups = groups
 	return nil
 }
@@ -305,7 +362,9 @@ func (g *group) routing(component string) *protos.RoutingInfo {
 }
 
 // startColocationGroup starts the colocation group hosting the provided
-// component, if it hasn't been started already.
+// component, if it hasn't been started already. A group that hosts a
+// singleton component runs a single replica; every other group runs
+// defaultReplication replicas.
 //
 // REQUIRES: d.mu is held.
 func (d *deployer) startColocationGroup(g *group) error {
@@ -315,53 +374,171 @@ func (d *deployer) startColocationGroup(g *group) error {
 	if d.err != nil {
 		return d.err
 	}
-	if len(g.envelopes) == defaultReplication {
+	n := defaultReplication
+	if d.isSingleton(g) {
+		n = 1
+	}
+	if len(g.envelopes) == n {
 		// Already started.
 		return nil
 	}
 
-	components := maps.Keys(g.started)
-	for r := 0; r < defaultReplication; r++ {
-		// Start the weavelet and capture its logs, traces, and metrics.
-		info := &protos.EnvelopeInfo{
-			App:           d.config.App.Name,
-			DeploymentId:  d.deploymentId,
-			Id:            uuid.New().String(),
-			Sections:      d.config.App.Sections,
-			SingleProcess: false,
-			SingleMachine: true,
-			RunMain:       g.started[runtime.Main],
-			Mtls:          d.config.Mtls,
-		}
-		e, err := envelope.NewEnvelope(d.ctx, info, d.config.App)
-		if err != nil {
+	for r := 0; r < n; r++ {
+		if err := d.startReplica(g); err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+// startReplica starts a new replica of the provided colocation group.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) startReplica(g *group) error {
+	// Start the weavelet and capture its logs, traces, and metrics.
+	info := &protos.EnvelopeInfo{
+		App:           d.config.App.Name,
+		DeploymentId:  d.deploymentId,
+		Id:            uuid.New().String(),
+		Sections:      d.config.App.Sections,
+		SingleProcess: false,
+		SingleMachine: true,
+		RunMain:       g.started[runtime.Main],
+		Mtls:          d.config.Mtls,
+	}
+	e, err := envelope.NewEnvelope(d.ctx, info, d.config.App)
+	if err != nil {
+		return err
+	}
 
-		// Make sure the version of the deployer matches the version of the
-		// compiled binary.
-		wlet := e.WeaveletInfo()
+	// Make sure the version of the deployer matches the version of the
+	// compiled binary.
+	wlet := e.WeaveletInfo()
 
-		d.running.Go(func() error {
-			h := &handler{
-				deployer:   d,
-				g:          g,
-				subscribed: map[string]bool{},
-				envelope:   e,
+	d.running.Go(func() error {
+		h := &handler{
+			deployer:   d,
+			g:          g,
+			subscribed: map[string]bool{},
+			envelope:   e,
+		}
+		err := e.Serve(h)
+		if d.isSingleton(g) {
+			// Fail over to a new replica, unless the deployer is stopping.
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.err != nil || d.ctx.Err() != nil {
+				return err
 			}
-			err := e.Serve(h)
-			d.stop(err)
-			return err
-		})
-		if err := d.registerReplica(g, wlet); err != nil {
-			return err
+			d.logger.Error("Singleton replica failed; starting a new one", "err", err, "group", g.name)
+			if err := d.replaceReplica(g, e); err != nil {
+				if d.err == nil {
+					d.err = err
+				}
+				d.ctxCancel()
+				return err
+			}
+			return nil
 		}
-		if err := e.UpdateComponents(components); err != nil {
+		d.stop(err)
+		return err
+	})
+	if err := d.registerReplica(g, wlet); err != nil {
+		return err
+	}
+	if err := e.UpdateComponents(maps.Keys(g.started)); err != nil {
+		return err
+	}
+	g.envelopes = append(g.envelopes, e)
+	if g.leader == nil {
+		return d.electLeader(g)
+	}
+	return nil
+}
+
+// electLeader makes the oldest replica of the provided colocation group its
+// leader. The leader runs the background tasks that must run on exactly one
+// replica.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) electLeader(g *group) error {
+	g.leader = nil
+	if len(g.envelopes) == 0 {
+		return nil
+	}
+	if err := g.envelopes[0].UpdateLeader(true); err != nil {
+		return err
+	}
+	g.leader = g.envelopes[0]
+	return nil
+}
+
+// replaceReplica replaces a failed replica of the provided colocation group
+// with a new one. Until the new replica is registered, the group has no
+// replicas, and calls to its components fail with weaver.ErrUnavailable.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) replaceReplica(g *group, failed *envelope.Envelope) error {
+	// Forget the failed replica.
+	info := failed.WeaveletInfo()
+	g.envelopes = remove(g.envelopes, failed)
+	g.pids = remove(g.pids, info.Pid)
+	delete(g.addresses, info.DialAddr)
+	for _, other := range d.groups {
+		for component, subs := range other.subscribers {
+			other.subscribers[component] = remove(subs, failed)
+		}
+	}
+	if err := d.publishExports(); err != nil {
+		return err
+	}
+
+	// Notify subscribers.
+	replicas := maps.Keys(g.addresses)
+	for component, assignment := range g.assignments {
+		g.assignments[component] = routingAlgo(assignment, replicas)
+	}
+	for component := range g.started {
+		routing := g.routing(component)
+		for _, sub := range g.subscribers[component] {
+			if err := sub.UpdateRoutingInfo(routing); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Pick a new leader, if the failed replica was the leader.
+	if g.leader == failed {
+		if err := d.electLeader(g); err != nil {
 			return err
 		}
-		g.envelopes = append(g.envelopes, e)
 	}
-	return nil
+
+	// Start a new replica.
+	return d.startReplica(g)
+}
+
+// remove returns xs without any occurrences of x.
+func remove[T comparable](xs []T, x T) []T {
+	var result []T
+	for _, y := range xs {
+		if y != x {
+			result = append(result, y)
+		}
+	}
+	return result
+}
+
+// isSingleton returns whether the provided colocation group hosts a
+// singleton component.
+func (d *deployer) isSingleton(g *group) bool {
+	var components []string
+	for component, h := range d.groups {
+		if h == g {
+			components = append(components, component)
+		}
+	}
+	return d.placement.IsSingleton(components...)
 }
 
 func (d *deployer) startMain() error {
@@ -370,6 +547,17 @@ func (d *deployer) startMain() error {
 	})
 }
 
+// startExported starts the colocation groups that host the exported
+// components, which the weavelets of other deployments may call at any time.
+func (d *deployer) startExported() error {
+	for _, component := range d.exported {
+		if err := d.activateComponent(&protos.ActivateComponentRequest{Component: component}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ActivateComponent implements the envelope.EnvelopeHandler interface.
 func (h *handler) ActivateComponent(_ context.Context, req *protos.ActivateComponentRequest) (*protos.ActivateComponentReply, error) {
 	if err := h.subscribeTo(req); err != nil {
@@ -413,10 +601,15 @@ func (h *handler) GetSelfCertificate(context.Context, *protos.GetSelfCertificate
 
 // VerifyClientCertificate implements the envelope.EnvelopeHandler interface.
 func (h *handler) VerifyClientCertificate(_ context.Context, req *protos.VerifyClientCertificateRequest) (*protos.VerifyClientCertificateReply, error) {
-	groupName, err := h.verifyCertificate(req.CertChain)
+	deploymentId, groupName, err := h.verifyCertificate(req.CertChain)
 	if err != nil {
 		return nil, err
 	}
+	if deploymentId != h.deploymentId {
+		// The weavelets of other deployments may only call the exported
+		// components.
+		return &protos.VerifyClientCertificateReply{Components: h.exported}, nil
+	}
 
 	// Find which weavelet components the client is allowed to call.
 	g, ok := h.groups[groupName]
@@ -428,10 +621,16 @@ func (h *handler) VerifyClientCertificate(_ context.Context, req *protos.VerifyC
 
 // VerifyServerCertificate implements the envelope.EnvelopeHandler interface.
 func (h *handler) VerifyServerCertificate(_ context.Context, req *protos.VerifyServerCertificateRequest) (*protos.VerifyServerCertificateReply, error) {
-	actual, err := h.verifyCertificate(req.CertChain)
+	deploymentId, actual, err := h.verifyCertificate(req.CertChain)
 	if err != nil {
 		return nil, err
 	}
+	if deploymentId != h.deploymentId {
+		// The target component belongs to another application, and is
+		// called through a weaver.ExternalRef. Check that the server's
+		// deployment exports it.
+		return &protos.VerifyServerCertificateReply{}, verifyExporter(deploymentId, req.TargetComponent)
+	}
 
 	// Find the expected group name for the target component.
 	g, ok := h.groups[req.TargetComponent]
@@ -444,24 +643,45 @@ func (h *handler) VerifyServerCertificate(_ context.Context, req *protos.VerifyS
 	return &protos.VerifyServerCertificateReply{}, nil
 }
 
-// verifyCertificate verifies and returns the group name stored in the given
-// certificate chain.
-func (h *handler) verifyCertificate(certChain [][]byte) (string, error) {
+// verifyExporter returns an error if the provided deployment doesn't publish
+// the provided component in exportsDir.
+func verifyExporter(deploymentId, component string) error {
+	published, err := exports.List(exportsDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range published {
+		if e.DeploymentId != deploymentId {
+			continue
+		}
+		if _, ok := e.Components[component]; !ok {
+			return fmt.Errorf("deployment %q doesn't export component %q", deploymentId, component)
+		}
+		return nil
+	}
+	return fmt.Errorf("deployment %q of server for component %q not found", deploymentId, component)
+}
+
+// verifyCertificate verifies the given certificate chain and returns the
+// deployment and the group name stored in it. See runtime.PeerIdentity.
+// Since all deployments share a CA, the chain may belong to another
+// deployment.
+func (h *handler) verifyCertificate(certChain [][]byte) (string, string, error) {
 	if n := len(certChain); n != 1 {
-		return "", fmt.Errorf("invalid cert chain length: want 1, got %d", n)
+		return "", "", fmt.Errorf("invalid cert chain length: want 1, got %d", n)
 	}
 	names, err := certs.VerifySignedCert(certChain[0], h.caCert)
 	if err != nil {
-		return "", fmt.Errorf("cannot verify the cert: %w", err)
+		return "", "", fmt.Errorf("cannot verify the cert: %w", err)
 	}
 	if len(names) != 1 {
-		return "", fmt.Errorf("invalid cert: expected a single name, got %v", names)
+		return "", "", fmt.Errorf("invalid cert: expected a single name, got %v", names)
 	}
-	name := names[0]
-	if name == "" {
-		return "", fmt.Errorf("empty group name %q in cert", name)
+	deploymentId, group, err := runtime.ParsePeerIdentity(names[0])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid cert: %w", err)
 	}
-	return name, nil
+	return deploymentId, group, nil
 }
 
 func (d *deployer) activateComponent(req *protos.ActivateComponentRequest) error {
@@ -474,7 +694,8 @@ func (d *deployer) activateComponent(req *protos.ActivateComponentRequest) error
 	}
 
 	// Update the set of components in the target co-location group.
-	if !target.started[req.Component] {
+	started := target.started[req.Component]
+	if !started {
 		target.started[req.Component] = true
 
 		// Notify the weavelets.
@@ -484,16 +705,20 @@ func (d *deployer) activateComponent(req *protos.ActivateComponentRequest) error
 				return err
 			}
 		}
+	}
 
-		// Create an initial assignment.
-		if req.Routed {
-			replicas := maps.Keys(target.addresses)
-			assignment := routingAlgo(&protos.Assignment{}, replicas)
-			target.assignments[req.Component] = assignment
-			d.logger.Debug(fmt.Sprintf("Initial assignment for component %s:\n%s", req.Component, routing.FormatAssignment(assignment)))
-		}
+	// Create an initial assignment. Note that an exported component is
+	// started before any of its callers reports whether it is routed.
+	assign := req.Routed && target.assignments[req.Component] == nil
+	if assign {
+		replicas := maps.Keys(target.addresses)
+		assignment := routingAlgo(&protos.Assignment{}, replicas)
+		target.assignments[req.Component] = assignment
+		d.logger.Debug(fmt.Sprintf("Initial assignment for component %s:\n%s", req.Component, routing.FormatAssignment(assignment)))
+	}
 
-		// Notify the subscribers.
+	// Notify the subscribers.
+	if !started || assign {
 		routing := target.routing(req.Component)
 		for _, sub := range target.subscribers[req.Component] {
 			if err := sub.UpdateRoutingInfo(routing); err != nil {
@@ -535,7 +760,30 @@ func (d *deployer) registerReplica(g *group, info *protos.WeaveletInfo) error {
 		}
 	}
 
-	return nil
+	return d.publishExports()
+}
+
+// publishExports publishes the addresses of the replicas of the exported
+// components, so that the weavelets of other deployments can call them.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) publishExports() error {
+	if len(d.exported) == 0 {
+		return nil
+	}
+	e := exports.Export{
+		DeploymentId: d.deploymentId,
+		App:          d.config.App.Name,
+		Started:      d.started,
+		Mtls:         d.config.Mtls,
+		Components:   map[string][]string{},
+	}
+	for _, component := range d.exported {
+		replicas := maps.Keys(d.groups[component].addresses)
+		slices.Sort(replicas)
+		e.Components[component] = replicas
+	}
+	return exports.Publish(exportsDir, e)
 }
 
 // HandleLogEntry implements the envelope.EnvelopeHandler interface.
@@ -706,6 +954,80 @@ func (d *deployer) Metrics(context.Context) (*status.Metrics, error) {
 	return m, nil
 }
 
+// rebalance reassigns the key space of every routed component whose replicas
+// report uneven load, and notifies the component's subscribers of the new
+// assignment.
+//
+// REQUIRES: d.mu is NOT held.
+func (d *deployer) rebalance() error {
+	// Snapshot the replicas of every group that hosts a routed component.
+	// Their load is collected without holding d.mu, since collecting the load
+	// of a replica is a round trip to it.
+	d.mu.Lock()
+	replicas := map[*group][]*envelope.Envelope{}
+	for _, g := range d.groups {
+		// Note that d.groups has an entry for every component of a group.
+		if _, ok := replicas[g]; ok || len(g.assignments) == 0 {
+			continue
+		}
+		replicas[g] = slices.Clone(g.envelopes)
+	}
+	d.mu.Unlock()
+
+	for g, envelopes := range replicas {
+		// Collect the load of every replica. Collecting the load resets it,
+		// so every report covers the time since the previous rebalance.
+		reports := map[string]*protos.LoadReport{}
+		for _, e := range envelopes {
+			report, err := e.GetLoad()
+			if err != nil {
+				return err
+			}
+			reports[e.WeaveletInfo().DialAddr] = report
+		}
+		if err := d.balanceLoad(g, reports); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// balanceLoad reassigns the key space of every routed component of the
+// provided group based on the provided load reports, keyed by replica address,
+// and notifies the component's subscribers of the new assignment.
+//
+// REQUIRES: d.mu is NOT held.
+func (d *deployer) balanceLoad(g *group, reports map[string]*protos.LoadReport) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	replicas := maps.Keys(g.addresses)
+	for component, assignment := range g.assignments {
+		// Ignore the reports of replicas that went away since their load was
+		// collected.
+		loads := map[string]*protos.LoadReport_ComponentLoad{}
+		for replica, report := range reports {
+			if load, ok := report.Loads[component]; ok && g.addresses[replica] {
+				loads[replica] = load
+			}
+		}
+		assignment, ok := routing.BalanceLoad(assignment, replicas, loads)
+		if !ok {
+			continue
+		}
+		g.assignments[component] = assignment
+		d.logger.Debug(fmt.Sprintf("Rebalanced assignment for component %s:\n%s", component, routing.FormatAssignment(assignment)))
+
+		routing := g.routing(component)
+		for _, sub := range g.subscribers[component] {
+			if err := sub.UpdateRoutingInfo(routing); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func routingAlgo(currAssignment *protos.Assignment, candidates []string) *protos.Assignment {
 	assignment := routing.EqualSlices(candidates)
 	assignment.Version = currAssignment.Version + 1
@@ -34,6 +34,13 @@ var (
 	registryDir  = filepath.Join(dataDir, "registry")
 	perfettoFile = filepath.Join(dataDir, "perfetto.db")
 
+	// The CA that signs the certificates of every deployment's weavelets,
+	// so that the weavelets of different deployments can verify one another
+	// when calling exported components, and the directory in which the
+	// deployments publish the addresses of their exported components.
+	caFile     = filepath.Join(dataDir, "ca.pem")
+	exportsDir = filepath.Join(dataDir, "exports")
+
 	dashboardSpec = &status.DashboardSpec{
 		Tool:         "weaver multi",
 		PerfettoFile: perfettoFile,
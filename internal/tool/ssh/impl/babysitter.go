@@ -113,6 +113,15 @@ func RunBabysitter(ctx context.Context) error {
 	if err := b.registerReplica(winfo); err != nil {
 		return err
 	}
+
+	// The first replica of every colocation group runs the group's
+	// one-replica background tasks. Note that no other replica takes over if
+	// it fails, since the manager doesn't detect failed replicas.
+	if info.ReplicaId == 0 {
+		if err := e.UpdateLeader(true); err != nil {
+			return err
+		}
+	}
 	c := metricsCollector{logger: b.logger, envelope: e, info: info}
 	go c.run(ctx)
 	return e.Serve(b)
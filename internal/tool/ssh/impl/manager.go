@@ -119,6 +119,9 @@ type manager struct {
 	// itself.
 	colocation map[string]string
 
+	// placement holds the placement hints of the application.
+	placement *runtime.Placement
+
 	mu      sync.Mutex                                    // guards following structures, but not contents
 	groups  map[string]*group                             // groups, by group name
 	proxies map[string]*proxyInfo                         // proxies, by listener name
@@ -190,6 +193,11 @@ func RunManager(ctx context.Context, config *SshConfig, locations map[string]str
 		}
 	}
 
+	placement, err := runtime.ParsePlacement(dep.App)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the manager.
 	m := &manager{
 		ctx:            ctx,
@@ -201,6 +209,7 @@ func RunManager(ctx context.Context, config *SshConfig, locations map[string]str
 		statsProcessor: imetrics.NewStatsProcessor(),
 		started:        time.Now(),
 		colocation:     colocation,
+		placement:      placement,
 		groups:         map[string]*group{},
 		proxies:        map[string]*proxyInfo{},
 		metrics:        map[groupReplicaInfo][]*protos.MetricSnapshot{},
@@ -424,6 +433,20 @@ func (m *manager) group(component string) *group {
 	return g
 }
 
+// isSingleton returns whether the provided colocation group hosts a
+// singleton component.
+func (m *manager) isSingleton(g *group) bool {
+	// A component that isn't colocated with others forms a group named after
+	// it.
+	components := []string{g.name}
+	for component, name := range m.colocation {
+		if name == g.name {
+			components = append(components, component)
+		}
+	}
+	return m.placement.IsSingleton(components...)
+}
+
 // allAddresses returns a copy of all current addresses in the group.
 //
 // REQUIRES: g.mu is NOT held.
@@ -587,12 +610,23 @@ func (m *manager) startColocationGroup(g *group, runMain bool) error {
 	g.started = true
 
 	// Start the colocation group. Right now, the number of replicas for each
-	// colocation group is equal to the number of locations.
+	// colocation group is equal to the number of locations, except for groups
+	// that host a singleton component, which run a single replica.
+	//
+	// Note that, unlike the multiprocess deployer, the SSH deployer doesn't
+	// detect failed replicas, so a failed replica of a singleton group is not
+	// replaced, and the group is unavailable until the application is
+	// redeployed.
 	//
 	// TODO(rgrandl): Implement some smarter logic to determine the number of
 	// replicas for each group.
+	locations := maps.Keys(m.locations)
+	slices.Sort(locations)
+	if m.isSingleton(g) {
+		locations = locations[:1]
+	}
 	replicaId := 0
-	for loc := range m.locations {
+	for _, loc := range locations {
 		info := &BabysitterInfo{
 			ManagerAddr: m.mgrAddress,
 			Deployment:  m.config.Deployment,
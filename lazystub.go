@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bounds of the backoff between two attempts to initialize the stub of a
+// remote component. See lazyStub.
+const (
+	stubRetryMinBackoff = 100 * time.Millisecond
+	stubRetryMaxBackoff = 10 * time.Second
+)
+
+// lazyStub holds the stub of a remote component, which is initialized on
+// first use. Unlike a sync.Once, a lazyStub doesn't cache failures forever:
+// if an attempt to initialize the stub fails, e.g., because no replica of the
+// component was available yet, the next use after a backoff starts a new
+// attempt. Uses in between fail right away with the error of the last
+// attempt. Once an attempt succeeds, the stub is returned without locking.
+type lazyStub struct {
+	stub atomic.Pointer[stub] // the initialized stub, or nil
+
+	mu       sync.Mutex   // guards the following fields
+	attempt  *stubAttempt // the latest attempt, or nil if there was none
+	failures int          // number of consecutive failed attempts
+}
+
+// stubAttempt is an attempt to initialize a stub.
+type stubAttempt struct {
+	done  chan struct{} // closed when the attempt finishes
+	err   error         // outcome of the attempt, once done
+	retry time.Time     // if err != nil, when the next attempt can start
+}
+
+// get returns the stub. If the stub isn't initialized, get starts an attempt
+// to initialize it with newStub, unless an attempt is in progress or the
+// backoff after the last failed attempt hasn't elapsed. Attempts run in the
+// background, so they are not affected by ctx; get waits for the current
+// attempt to finish or for ctx to be done, whichever happens first.
+func (l *lazyStub) get(ctx context.Context, newStub func() (*stub, error)) (*stub, error) {
+	if s := l.stub.Load(); s != nil {
+		return s, nil
+	}
+
+	l.mu.Lock()
+	a := l.attempt
+	if a == nil || a.finished() && a.err != nil && !time.Now().Before(a.retry) {
+		a = &stubAttempt{done: make(chan struct{})}
+		l.attempt = a
+		go l.run(a, newStub)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-a.done:
+		if a.err != nil {
+			return nil, a.err
+		}
+		return l.stub.Load(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run runs attempt a.
+func (l *lazyStub) run(a *stubAttempt, newStub func() (*stub, error)) {
+	s, err := newStub()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		l.failures++
+		a.err = err
+		a.retry = time.Now().Add(stubBackoff(l.failures))
+	} else {
+		l.failures = 0
+		l.stub.Store(s)
+	}
+	close(a.done)
+}
+
+// finished returns whether the attempt has finished.
+func (a *stubAttempt) finished() bool {
+	select {
+	case <-a.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stubBackoff returns how long to wait for after the provided number of
+// consecutive failed attempts to initialize a stub, before trying again.
+func stubBackoff(failures int) time.Duration {
+	backoff := stubRetryMinBackoff
+	for i := 1; i < failures && backoff < stubRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > stubRetryMaxBackoff {
+		backoff = stubRetryMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLazyStubRecovers(t *testing.T) {
+	// Simulate a component whose replicas come up after the first attempt to
+	// connect to it fails.
+	errNoReplicas := errors.New("no replicas")
+	var up atomic.Bool
+	var attempts atomic.Int32
+	want := &stub{component: "pkg/C"}
+	newStub := func() (*stub, error) {
+		attempts.Add(1)
+		if !up.Load() {
+			return nil, errNoReplicas
+		}
+		return want, nil
+	}
+
+	var l lazyStub
+	ctx := context.Background()
+	if _, err := l.get(ctx, newStub); !errors.Is(err, errNoReplicas) {
+		t.Fatalf("get before replicas are up: got %v, want %v", err, errNoReplicas)
+	}
+
+	// During the backoff, uses fail right away with the cached error.
+	up.Store(true)
+	if _, err := l.get(ctx, newStub); !errors.Is(err, errNoReplicas) {
+		t.Fatalf("get during backoff: got %v, want %v", err, errNoReplicas)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("attempts during backoff: got %d, want 1", got)
+	}
+
+	// After the backoff, a new attempt succeeds.
+	time.Sleep(stubRetryMinBackoff)
+	got, err := l.get(ctx, newStub)
+	if err != nil {
+		t.Fatalf("get after replicas are up: %v", err)
+	}
+	if got != want {
+		t.Fatalf("get after replicas are up: got %v, want %v", got, want)
+	}
+
+	// Once established, the stub is reused without new attempts.
+	for i := 0; i < 10; i++ {
+		if got, err := l.get(ctx, newStub); err != nil || got != want {
+			t.Fatalf("get %d: got (%v, %v), want (%v, nil)", i, got, err, want)
+		}
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("attempts: got %d, want 2", got)
+	}
+}
+
+func TestLazyStubContext(t *testing.T) {
+	// A caller that gives up doesn't cancel the attempt.
+	release := make(chan struct{})
+	want := &stub{component: "pkg/C"}
+	newStub := func() (*stub, error) {
+		<-release
+		return want, nil
+	}
+	var l lazyStub
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.get(ctx, newStub); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get with a canceled context: got %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if got, err := l.get(context.Background(), newStub); err != nil || got != want {
+		t.Fatalf("get: got (%v, %v), want (%v, nil)", got, err, want)
+	}
+}
+
+func TestStubBackoff(t *testing.T) {
+	for _, test := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, stubRetryMinBackoff},
+		{2, 2 * stubRetryMinBackoff},
+		{3, 4 * stubRetryMinBackoff},
+		{100, stubRetryMaxBackoff},
+	} {
+		if got := stubBackoff(test.failures); got != test.want {
+			t.Errorf("stubBackoff(%d): got %v, want %v", test.failures, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// Timeouts applied to the HTTP servers started by Listener.Serve.
+	serveReadHeaderTimeout = 10 * time.Second
+	serveIdleTimeout       = 2 * time.Minute
+
+	// How long Listener.Serve waits for in-flight requests to finish after
+	// the weavelet starts shutting down.
+	serveShutdownTimeout = 10 * time.Second
+)
+
+// Serve serves HTTP requests received on the listener using the provided
+// handler. Requests for the reserved HealthzURL path are answered by Serve
+// and never reach handler. If the component implementation that owns the
+// listener has a method
+//
+//	HealthCheck(context.Context) error
+//
+// health checks are delegated to it, and a non-nil error is reported as
+// unhealthy. Otherwise, health checks always succeed. Either way, health
+// checks fail once the weavelet hosting the component starts shutting down,
+// so that load balancers stop sending it requests.
+//
+// Serve blocks until the server fails or the weavelet hosting the component
+// shuts down. In the latter case, the server is shut down gracefully: it stops
+// accepting new connections and waits a bounded amount of time for in-flight
+// requests to complete, after which Serve returns nil.
+//
+//	func (s *server) Init(ctx context.Context) error {
+//	    go s.lis.Serve(http.HandlerFunc(s.handle))
+//	    return nil
+//	}
+func (l *Listener) Serve(handler http.Handler) error {
+	return l.serve(nil, handler)
+}
+
+// Serve serves HTTP requests received on lis, like [Listener.Serve], using
+// handler instrumented by [InstrumentHandler] with the name of the listener
+// as the label. In addition, the request contexts carry the logger of the
+// component that owns lis, so that [LoggerFromContext] returns it when called
+// by the handler.
+//
+// Serve shuts the server down gracefully, and returns nil, when either ctx is
+// canceled or the weavelet hosting the component shuts down. Because the
+// instrumented handler is an http.Handler, any router can be served:
+//
+//	func (s *server) Init(ctx context.Context) error {
+//	    mux := http.NewServeMux()
+//	    mux.HandleFunc("/hello", s.hello)
+//	    go weaver.Serve(ctx, s.lis, mux)
+//	    return nil
+//	}
+//
+// Note that, in the example above, ctx is the context passed to Init, which
+// is not canceled when Init returns.
+func Serve(ctx context.Context, lis Listener, handler http.Handler) error {
+	label := lis.name
+	if label == "" {
+		label = lis.String()
+	}
+	handler = InstrumentHandler(label, handler)
+	if logger := lis.logger; logger != nil {
+		inner := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inner.ServeHTTP(w, r.WithContext(withRequestLogger(r.Context(), logger)))
+		})
+	}
+	return lis.serve(ctx.Done(), handler)
+}
+
+// serve implements Listener.Serve. The server is also shut down when done is
+// closed.
+func (l *Listener) serve(done <-chan struct{}, handler http.Handler) error {
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == HealthzURL {
+				l.serveHealthz(w, r)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: serveReadHeaderTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+
+	if l.logger != nil {
+		l.logger.Debug("Serving HTTP", "address", l.String())
+	}
+	defer l.servers.start()()
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(l.Listener) }()
+
+	var stopped <-chan struct{}
+	if l.ctx != nil {
+		stopped = l.ctx.Done()
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-stopped:
+	case <-done:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown of HTTP server on %s: %w", l, err)
+	}
+	return nil
+}
+
+// serveHealthz handles a health check request.
+func (l *Listener) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	if l.draining != nil && l.draining() {
+		// Tell load balancers to stop sending requests to this replica.
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	if l.health == nil {
+		HealthzHandler(w, r)
+		return
+	}
+	if err := l.health(r.Context()); err != nil {
+		if l.logger != nil {
+			l.logger.Error("Health check failed", "address", l.String(), "err", err)
+		}
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintf(w, "OK")
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+func get(t *testing.T, addr, path string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(fmt.Sprintf("http://%s%s", addr, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestListenerServe(t *testing.T) {
+	draining := func() bool { return true }
+	for _, test := range []struct {
+		name     string
+		health   func(context.Context) error
+		draining func() bool
+		code     int
+	}{
+		{"NoHealthCheck", nil, nil, http.StatusOK},
+		{"Healthy", func(context.Context) error { return nil }, nil, http.StatusOK},
+		{"Unhealthy", func(context.Context) error { return fmt.Errorf("sick") }, nil, http.StatusServiceUnavailable},
+		{"Draining", func(context.Context) error { return nil }, draining, http.StatusServiceUnavailable},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			lis := Listener{Listener: l, ctx: ctx, health: test.health, draining: test.draining}
+
+			served := make(chan error, 1)
+			go func() {
+				served <- lis.Serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprintf(w, "hello %s", r.URL.Path)
+				}))
+			}()
+
+			addr := l.Addr().String()
+			if code, _ := get(t, addr, HealthzURL); code != test.code {
+				t.Errorf("healthz: got status %d, want %d", code, test.code)
+			}
+			if code, body := get(t, addr, "/foo"); code != http.StatusOK || body != "hello /foo" {
+				t.Errorf("/foo: got (%d, %q), want (%d, %q)", code, body, http.StatusOK, "hello /foo")
+			}
+
+			// Canceling the weavelet context should shut down the server.
+			cancel()
+			if err := <-served; err != nil {
+				t.Fatalf("Serve: %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeProxyAddr(t *testing.T) {
+	for _, test := range []struct{ addr, want string }{
+		{"", ""},
+		{"proxy:80", "proxy:80"},
+		{"127.0.0.1:80", "127.0.0.1:80"},
+		{"[2001:db8::1]:80", "[2001:db8::1]:80"},
+		{"2001:db8::1:80", "[2001:db8::1]:80"},
+		{"::1:8080", "[::1]:8080"},
+		{"::ffff:10.0.0.1:80", "[::ffff:10.0.0.1]:80"},
+		{"proxy", "proxy"},
+	} {
+		t.Run(test.addr, func(t *testing.T) {
+			got := normalizeProxyAddr(test.addr)
+			if got != test.want {
+				t.Fatalf("normalizeProxyAddr(%q): got %q, want %q", test.addr, got, test.want)
+			}
+			if got == "" || got == "proxy" {
+				return
+			}
+			// The normalized address must be dialable.
+			if _, _, err := net.SplitHostPort(got); err != nil {
+				t.Fatalf("normalizeProxyAddr(%q): %q is not a host:port: %v", test.addr, got, err)
+			}
+		})
+	}
+}
+
+func TestProxyAddrIPv6(t *testing.T) {
+	// Dial the proxy address reported for an IPv6 proxy without brackets.
+	inner, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer inner.Close()
+	port := inner.Addr().(*net.TCPAddr).Port
+	lis := Listener{Listener: inner, proxyAddr: normalizeProxyAddr(fmt.Sprintf("::1:%d", port))}
+	if got, want := lis.ProxyAddr(), fmt.Sprintf("[::1]:%d", port); got != want {
+		t.Fatalf("ProxyAddr(): got %q, want %q", got, want)
+	}
+	conn, err := net.Dial("tcp", lis.ProxyAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lis := Listener{Listener: l, logger: logger, name: "TestServe"}
+
+	var gotLogger *slog.Logger
+	var gotTraceID trace.TraceID
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		gotLogger = LoggerFromContext(r.Context())
+		gotTraceID = trace.SpanContextFromContext(r.Context()).TraceID()
+		fmt.Fprint(w, "hello")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, lis, mux) }()
+
+	addr := l.Addr().String()
+	if code, _ := get(t, addr, HealthzURL); code != http.StatusOK {
+		t.Errorf("healthz: got status %d, want %d", code, http.StatusOK)
+	}
+	if code, _ := get(t, addr, "/missing"); code != http.StatusNotFound {
+		t.Errorf("/missing: got status %d, want %d", code, http.StatusNotFound)
+	}
+
+	// Send a request that carries a trace context.
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/hello", addr), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if gotLogger != logger {
+		t.Errorf("LoggerFromContext: got %v, want the component's logger", gotLogger)
+	}
+	if got := gotTraceID.String(); got != traceID {
+		t.Errorf("trace id: got %s, want %s", got, traceID)
+	}
+
+	// Requests are counted by status code class. Health checks aren't
+	// counted.
+	counts := map[string]float64{}
+	for _, snap := range metrics.Snapshot() {
+		if snap.Name == "serviceweaver_http_request_count" && snap.Labels["label"] == "TestServe" {
+			counts[snap.Labels["class"]] += snap.Value
+		}
+	}
+	if diff := cmp.Diff(map[string]float64{"2xx": 1, "4xx": 1}, counts); diff != "" {
+		t.Errorf("request counts (-want +got):\n%s", diff)
+	}
+
+	// Canceling ctx should shut down the server.
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for code, want := range map[int]string{
+		101: "1xx",
+		200: "2xx",
+		302: "3xx",
+		404: "4xx",
+		503: "5xx",
+		999: "other",
+	} {
+		if got := statusClass(code); got != want {
+			t.Errorf("statusClass(%d): got %q, want %q", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	// Key and short key of the app config section that holds deployer
+	// independent options of listeners, keyed by listener name. For example:
+	//
+	//	[listeners]
+	//	myListener = {advertise = "api.example.com:443"}
+	//	otherListener = {reuse_port = true, accept_backlog = 4096}
+	listenersKey      = "github.com/ServiceWeaver/weaver/listeners"
+	shortListenersKey = "listeners"
+)
+
+// listenerConfig holds the deployer independent options of a single listener.
+type listenerConfig struct {
+	// The address that clients should dial to reach the listener, e.g., the
+	// address of an external load balancer that the process can't discover
+	// by itself. If set, it is returned by Listener.String and
+	// Listener.ProxyAddr in place of the listener's proxy or bind address.
+	Advertise string `toml:"advertise"`
+
+	// Options of the listener's TCP socket. See SocketOptions.
+	ReusePort     bool   `toml:"reuse_port"`
+	TCPKeepAlive  string `toml:"tcp_keep_alive"` // e.g., "30s"; "0s" disables keep-alives
+	AcceptBacklog int    `toml:"accept_backlog"`
+	NoDelay       *bool  `toml:"nodelay"` // nil means true
+}
+
+// listenerConfigs holds the options of listeners, keyed by listener name.
+type listenerConfigs map[string]listenerConfig
+
+// Validate implements the interface consulted by runtime.ParseConfigSection.
+func (c listenerConfigs) Validate() error {
+	for name, cfg := range c {
+		if cfg.Advertise != "" {
+			if err := validateAdvertise(cfg.Advertise); err != nil {
+				return fmt.Errorf("listener %q: %w", name, err)
+			}
+		}
+		if _, err := cfg.socketOptions(); err != nil {
+			return fmt.Errorf("listener %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// proxyAddr returns the address that the named listener reports to clients
+// (see Listener.ProxyAddr): its advertised address if it has one, or else the
+// provided address of its proxy, in the form accepted by net.Dial.
+func (c listenerConfigs) proxyAddr(name, proxyAddr string) string {
+	if advertise := c[name].Advertise; advertise != "" {
+		return advertise
+	}
+	return normalizeProxyAddr(proxyAddr)
+}
+
+// validateAdvertise returns an error if addr is not a <host>:<port> address
+// with a non-empty host and a valid port. IPv6 hosts must be enclosed in
+// square brackets, as in "[2001:db8::1]:443".
+func validateAdvertise(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("advertise address %q is not of the form host:port: %w", addr, err)
+	}
+	if host == "" {
+		return fmt.Errorf("advertise address %q has an empty host", addr)
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return fmt.Errorf("advertise address %q has an invalid port %q", addr, port)
+	}
+	return nil
+}
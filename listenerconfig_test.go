@@ -0,0 +1,94 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+)
+
+func TestListenerConfig(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		section string
+		want    string // expected error, if any
+	}{
+		{"Hostname", `foo = {advertise = "api.example.com:443"}`, ""},
+		{"IPv4", `foo = {advertise = "203.0.113.7:8080"}`, ""},
+		{"IPv6", `foo = {advertise = "[2001:db8::1]:443"}`, ""},
+		{"Empty", `foo = {}`, ""},
+		{"NoPort", `foo = {advertise = "api.example.com"}`, "not of the form host:port"},
+		{"UnbracketedIPv6", `foo = {advertise = "2001:db8::1:443"}`, "not of the form host:port"},
+		{"EmptyHost", `foo = {advertise = ":443"}`, "empty host"},
+		{"NamedPort", `foo = {advertise = "api.example.com:https"}`, "invalid port"},
+		{"ZeroPort", `foo = {advertise = "api.example.com:0"}`, "invalid port"},
+		{"LargePort", `foo = {advertise = "api.example.com:65536"}`, "invalid port"},
+		{"SocketOptions", `foo = {tcp_keep_alive = "30s", nodelay = false}`, ""},
+		{"NoKeepAlive", `foo = {tcp_keep_alive = "0s"}`, ""},
+		{"BadKeepAlive", `foo = {tcp_keep_alive = "30"}`, "invalid tcp_keep_alive"},
+		{"NegativeKeepAlive", `foo = {tcp_keep_alive = "-1s"}`, "must not be negative"},
+		{"NegativeBacklog", `foo = {accept_backlog = -1}`, "must not be negative"},
+		{"UnknownKey", `foo = {local_address = ":8080"}`, "unknown keys"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sections := map[string]string{shortListenersKey: test.section}
+			var got listenerConfigs
+			err := runtime.ParseConfigSection(listenersKey, shortListenersKey, sections, &got)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestListenerAdvertise(t *testing.T) {
+	inner, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+
+	configs := listenerConfigs{"foo": {Advertise: "api.example.com:443"}}
+	for _, test := range []struct {
+		name      string
+		proxyAddr string // reported by the deployer
+		tls       bool
+		want      string // want String()
+		wantProxy string // want ProxyAddr()
+	}{
+		{"foo", "proxy:80", false, "api.example.com:443", "api.example.com:443"},
+		{"foo", "", false, "api.example.com:443", "api.example.com:443"},
+		{"foo", "", true, "https://api.example.com:443", "api.example.com:443"},
+		{"bar", "2001:db8::1:80", false, "[2001:db8::1]:80", "[2001:db8::1]:80"},
+		{"bar", "", false, inner.Addr().String(), ""},
+	} {
+		lis := Listener{Listener: inner, proxyAddr: configs.proxyAddr(test.name, test.proxyAddr), tls: test.tls}
+		if got := lis.String(); got != test.want {
+			t.Errorf("%s, %q: String(): got %q, want %q", test.name, test.proxyAddr, got, test.want)
+		}
+		if got := lis.ProxyAddr(); got != test.wantProxy {
+			t.Errorf("%s, %q: ProxyAddr(): got %q, want %q", test.name, test.proxyAddr, got, test.wantProxy)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+const (
+	// Key and short key of the app config section that holds the TLS
+	// certificates of listeners, keyed by listener name. For example:
+	//
+	//	[tls]
+	//	myListener = {cert_file = "cert.pem", key_file = "key.pem"}
+	listenerTLSKey      = "github.com/ServiceWeaver/weaver/tls"
+	shortListenerTLSKey = "tls"
+)
+
+// listenerTLSConfig holds the TLS configuration of a single listener.
+type listenerTLSConfig struct {
+	CertFile string `toml:"cert_file"` // PEM encoded certificate chain
+	KeyFile  string `toml:"key_file"`  // PEM encoded private key
+}
+
+// listenerTLSConfigs holds the TLS configurations of listeners, keyed by
+// listener name.
+type listenerTLSConfigs map[string]listenerTLSConfig
+
+// Validate implements the interface consulted by runtime.ParseConfigSection.
+func (c listenerTLSConfigs) Validate() error {
+	for name, cfg := range c {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return fmt.Errorf("listener %q: both cert_file and key_file must be specified", name)
+		}
+	}
+	return nil
+}
+
+// certReloader holds a certificate loaded from a pair of files and serves it
+// to TLS handshakes. The certificate can be reloaded at any time; connections
+// that have already completed their handshake are not affected.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// newCertReloader returns a certReloader for the certificate stored in the
+// provided files, failing if the certificate cannot be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload reloads the certificate from its files. On error, the previously
+// loaded certificate remains in use.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate %q: %w", r.certFile, err)
+	}
+	r.cert.Store(&cert)
+	return nil
+}
+
+// getCertificate is suitable for use as tls.Config.GetCertificate.
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// newTLSListener returns a listener that terminates TLS for the connections
+// accepted by inner, using the certificate held by r.
+func newTLSListener(inner net.Listener, r *certReloader) net.Listener {
+	return tls.NewListener(inner, &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	})
+}
+
+// addCertificate registers the certificate of a TLS listener. All registered
+// certificates are reloaded from their files whenever the weavelet receives a
+// SIGHUP, until the weavelet shuts down.
+func (w *weavelet) addCertificate(r *certReloader) {
+	w.certsMu.Lock()
+	w.certs = append(w.certs, r)
+	w.certsMu.Unlock()
+
+	w.sighupOnce.Do(func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		go func() {
+			defer signal.Stop(sighup)
+			for {
+				select {
+				case <-w.ctx.Done():
+					return
+				case <-sighup:
+					w.reloadCertificates()
+				}
+			}
+		}()
+	})
+}
+
+// reloadCertificates reloads the certificates of all TLS listeners.
+func (w *weavelet) reloadCertificates() {
+	w.certsMu.Lock()
+	defer w.certsMu.Unlock()
+	for _, r := range w.certs {
+		if err := r.reload(); err != nil {
+			w.env.SystemLogger().Error("Cannot reload listener certificate; keeping the old one", "err", err)
+			continue
+		}
+		w.env.SystemLogger().Info("Reloaded listener certificate", "cert", r.certFile)
+	}
+}
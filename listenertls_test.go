@@ -0,0 +1,216 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+)
+
+// writeCert writes a new self-signed certificate with the provided serial
+// number to certFile and its private key to keyFile.
+func writeCert(t *testing.T, serial int64, certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dial establishes a TLS connection to addr and returns it along with the
+// serial number of the server's certificate.
+func dial(t *testing.T, addr string) (*tls.Conn, int64) {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestTLSListenerReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCert(t, 1, certFile, keyFile)
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := newTLSListener(inner, r)
+	defer lis.Close()
+
+	// Echo every connection.
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	echo := func(conn net.Conn, msg string) {
+		t.Helper()
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf); got != msg {
+			t.Fatalf("echo: got %q, want %q", got, msg)
+		}
+	}
+
+	old, serial := dial(t, lis.Addr().String())
+	defer old.Close()
+	if serial != 1 {
+		t.Fatalf("serial: got %d, want 1", serial)
+	}
+	echo(old, "before")
+
+	// Rotate the certificate. New connections should use it, and the old
+	// connection should keep working.
+	writeCert(t, 2, certFile, keyFile)
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	conn, serial := dial(t, lis.Addr().String())
+	defer conn.Close()
+	if serial != 2 {
+		t.Fatalf("serial: got %d, want 2", serial)
+	}
+	echo(conn, "new")
+	echo(old, "after")
+
+	// A failed reload keeps the current certificate.
+	if err := os.WriteFile(certFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err == nil {
+		t.Fatal("unexpected success reloading a bad certificate")
+	}
+	conn2, serial := dial(t, lis.Addr().String())
+	defer conn2.Close()
+	if serial != 2 {
+		t.Fatalf("serial: got %d, want 2", serial)
+	}
+}
+
+func TestListenerStringTLS(t *testing.T) {
+	inner, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+	addr := inner.Addr().String()
+
+	for _, test := range []struct {
+		lis  Listener
+		want string
+	}{
+		{Listener{Listener: inner}, addr},
+		{Listener{Listener: inner, tls: true}, "https://" + addr},
+		{Listener{Listener: inner, proxyAddr: "proxy:80"}, "proxy:80"},
+		{Listener{Listener: inner, proxyAddr: "proxy:443", tls: true}, "https://proxy:443"},
+		{Listener{Listener: inner, proxyAddr: normalizeProxyAddr("2001:db8::1:80")}, "[2001:db8::1]:80"},
+		{Listener{Listener: inner, proxyAddr: normalizeProxyAddr("2001:db8::1:443"), tls: true}, "https://[2001:db8::1]:443"},
+	} {
+		if got := test.lis.String(); got != test.want {
+			t.Errorf("String(): got %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestListenerTLSConfig(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		section string
+		want    string // expected error, if any
+	}{
+		{"Valid", `foo = {cert_file = "c.pem", key_file = "k.pem"}`, ""},
+		{"MissingKey", `foo = {cert_file = "c.pem"}`, "both cert_file and key_file"},
+		{"UnknownKey", `foo = {cert = "c.pem", key_file = "k.pem"}`, "unknown keys"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sections := map[string]string{shortListenerTLSKey: test.section}
+			var got listenerTLSConfigs
+			err := runtime.ParseConfigSection(listenerTLSKey, shortListenerTLSKey, sections, &got)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := listenerTLSConfig{CertFile: "c.pem", KeyFile: "k.pem"}
+				if got["foo"] != want {
+					t.Fatalf("got %v, want %v", got["foo"], want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
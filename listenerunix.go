@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unixScheme prefixes listener addresses that name Unix domain sockets.
+const unixScheme = "unix://"
+
+// unixSocket is a listener address of the form "unix://<path>?mode=<mode>",
+// e.g., "unix:///tmp/app.sock?mode=0660". The mode is optional.
+type unixSocket struct {
+	path string      // path of the socket file
+	mode os.FileMode // permissions of the socket file, if set
+	set  bool        // was a mode specified?
+}
+
+// parseUnixSocket parses a listener address. It returns false if the address
+// doesn't name a Unix domain socket.
+func parseUnixSocket(addr string) (unixSocket, bool, error) {
+	if !strings.HasPrefix(addr, unixScheme) {
+		return unixSocket{}, false, nil
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return unixSocket{}, false, fmt.Errorf("invalid unix socket address %q: %w", addr, err)
+	}
+	// "unix:///tmp/app.sock" has an absolute path, while "unix://app.sock"
+	// parses the relative path as a host.
+	s := unixSocket{path: u.Host + u.Path}
+	if s.path == "" {
+		return unixSocket{}, false, fmt.Errorf("invalid unix socket address %q: empty path", addr)
+	}
+	if mode := u.Query().Get("mode"); mode != "" {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || m > 0777 {
+			return unixSocket{}, false, fmt.Errorf("invalid unix socket address %q: bad mode %q", addr, mode)
+		}
+		s.mode, s.set = os.FileMode(m), true
+	}
+	return s, true, nil
+}
+
+// listen listens on the socket. A socket file left behind by a process that
+// is no longer listening on it is replaced.
+func (s unixSocket) listen() (*net.UnixListener, error) {
+	if fi, err := os.Lstat(s.path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %q is in use", s.path)
+		}
+		if err := os.Remove(s.path); err != nil {
+			return nil, fmt.Errorf("remove stale unix socket: %w", err)
+		}
+	}
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: s.path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if s.set {
+		if err := os.Chmod(s.path, s.mode); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("chmod unix socket: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// listenerAddress returns the address clients dial to reach l.
+func listenerAddress(l net.Listener) string {
+	if addr := l.Addr(); addr.Network() == "unix" {
+		return unixScheme + addr.String()
+	}
+	return l.Addr().String()
+}
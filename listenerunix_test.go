@@ -0,0 +1,112 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseUnixSocket(t *testing.T) {
+	for _, test := range []struct {
+		addr   string
+		isUnix bool
+		want   unixSocket
+	}{
+		{"localhost:9000", false, unixSocket{}},
+		{":0", false, unixSocket{}},
+		{"unix:///tmp/app.sock", true, unixSocket{path: "/tmp/app.sock"}},
+		{"unix://app.sock", true, unixSocket{path: "app.sock"}},
+		{"unix:///tmp/app.sock?mode=0660", true, unixSocket{path: "/tmp/app.sock", mode: 0660, set: true}},
+	} {
+		got, isUnix, err := parseUnixSocket(test.addr)
+		if err != nil {
+			t.Errorf("parseUnixSocket(%q): %v", test.addr, err)
+			continue
+		}
+		if isUnix != test.isUnix || got != test.want {
+			t.Errorf("parseUnixSocket(%q): got (%+v, %v), want (%+v, %v)", test.addr, got, isUnix, test.want, test.isUnix)
+		}
+	}
+
+	for _, addr := range []string{"unix://", "unix:///tmp/app.sock?mode=rw", "unix:///tmp/app.sock?mode=01777"} {
+		if _, _, err := parseUnixSocket(addr); err == nil {
+			t.Errorf("parseUnixSocket(%q): unexpected success", addr)
+		}
+	}
+}
+
+func TestUnixSocketListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	s := unixSocket{path: path, mode: 0600, set: true}
+	l, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("mode: got %v, want %v", got, want)
+	}
+	if got, want := listenerAddress(l), "unix://"+path; got != want {
+		t.Errorf("listenerAddress: got %q, want %q", got, want)
+	}
+
+	// A socket that is being listened on can't be taken over.
+	if _, err := s.listen(); err == nil {
+		t.Fatal("listen on in-use socket: unexpected success")
+	}
+
+	// Closing the listener removes the socket file.
+	l.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("socket file not removed: %v", err)
+	}
+}
+
+func TestUnixSocketListenStale(t *testing.T) {
+	// Leave a socket file behind, as a crashed process would.
+	path := filepath.Join(t.TempDir(), "app.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := unixSocket{path: path}.listen()
+	if err != nil {
+		t.Fatalf("listen on stale socket: %v", err)
+	}
+	l.Close()
+}
+
+func TestListenerStringUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	l, err := unixSocket{path: path}.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, lis := range []Listener{{Listener: l}, {Listener: l, tls: true}} {
+		if got, want := lis.String(), "unix://"+path; got != want {
+			t.Errorf("String(): got %q, want %q", got, want)
+		}
+	}
+}
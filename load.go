@@ -15,6 +15,7 @@
 package weaver
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
@@ -23,10 +24,53 @@ import (
 	"time"
 
 	"github.com/DataDog/hyperloglog"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
 	"github.com/ServiceWeaver/weaver/runtime/protos"
 	"github.com/lightstep/varopt"
 )
 
+// Bounds on the load of a single call. Loads reported with ReportLoad are
+// clamped to [minCallLoad, maxCallLoad], so that one misreported call doesn't
+// dwarf the load of all other calls.
+const (
+	minCallLoad = 1e-3
+	maxCallLoad = 1e3
+)
+
+// ReportLoad reports the load of the routed component method call that ctx
+// belongs to. By default, every call to a routed method adds a load of 1 to
+// its routing key. A method that calls ReportLoad adds the sum of the reported
+// loads instead, so that, for example, a call that scans a large table can
+// report a higher load than a call that reads a single row:
+//
+//	func (c *cache) Scan(ctx context.Context, prefix string) ([]string, error) {
+//	    rows := c.scan(prefix)
+//	    weaver.ReportLoad(ctx, float64(len(rows)))
+//	    return rows, nil
+//	}
+//
+// Loads have no units; they are only compared to each other. The load of a
+// call is clamped to [0.001, 1000], and NaN loads are ignored. The load of
+// the routing keys is used to assign keys to replicas, so that every replica
+// receives roughly the same load.
+//
+// ReportLoad is a no-op if ctx doesn't belong to a remote call to a routed
+// method, e.g., because the call is local. In particular, the load reported
+// by a component method called locally from a routed method isn't added to
+// the load of the routed call.
+//
+// Only the multiprocess deployer ("weaver multi") rebalances the routing keys
+// of a component based on their load. Other deployers assign keys to replicas
+// evenly and ignore the reported loads.
+func ReportLoad(ctx context.Context, load float64) {
+	if math.IsNaN(load) {
+		return
+	}
+	if l := codegen.CallLoadFromContext(ctx); l != nil {
+		l.Add(load)
+	}
+}
+
 func approxEqual(a, b float64) bool {
 	const float64EqualityThreshold = 1e-9
 	return math.Abs(a-b) <= float64EqualityThreshold
@@ -82,11 +126,13 @@ func newLoadCollector(component string, addr string) *loadCollector {
 	}
 }
 
-// add adds load for the provided key.
+// add adds load for the provided key. The load is clamped to [minCallLoad,
+// maxCallLoad].
 func (lc *loadCollector) add(key uint64, v float64) error {
-	if v != 1.0 {
-		panic("load != 1.0 not yet implemented")
+	if math.IsNaN(v) {
+		v = 1.0
 	}
+	v = math.Max(minCallLoad, math.Min(v, maxCallLoad))
 
 	// Find the corresponding slice.
 	lc.mu.Lock()
@@ -138,10 +184,8 @@ func (lc *loadCollector) add(key uint64, v float64) error {
 	// hyperloglog if the number of unique elements gets too big?
 	summary.count.Add(hyperloglog.Murmur64(key))
 
-	// Update the sample. Note that Add takes in a key and a weight, but we are
-	// recording unweighted samples, so we use a constant weight of 1.0 for
-	// every key.
-	if _, err := summary.sample.Add(key, 1.0); err != nil {
+	// Update the sample, weighting every key by its load.
+	if _, err := summary.sample.Add(key, v); err != nil {
 		return fmt.Errorf("cannot sample %d: %v", key, err)
 	}
 	return nil
@@ -237,14 +281,16 @@ func (s *sliceSummary) splits(delta time.Duration) []*protos.LoadReport_Subslice
 	// splits are used. Moreover, if adjacent splits are formed from a single
 	// hot key, they are combined.
 
-	// Materialize and sort the sample.
+	// Materialize and sort the sample. Every key is sampled with a
+	// probability proportional to its load, and comes with a weight that
+	// estimates the load of the keys it stands for.
 	k := s.sample.Size()
-	xs := make([]uint64, k)
+	xs := make([]weightedKey, k)
 	for i := 0; i < k; i++ {
-		x, _ := s.sample.Get(i)
-		xs[i] = x.(uint64)
+		x, w := s.sample.Get(i)
+		xs[i] = weightedKey{key: x.(uint64), weight: w}
 	}
-	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+	sort.Slice(xs, func(i, j int) bool { return xs[i].key < xs[j].key })
 
 	// Determine the number of splits. More splits is better, but if we don't
 	// have many points in our sample, then using a large number of splits will
@@ -282,9 +328,16 @@ func (s *sliceSummary) splits(delta time.Duration) []*protos.LoadReport_Subslice
 	return splits
 }
 
+// weightedKey is a sampled key, along with its weight.
+type weightedKey struct {
+	key    uint64
+	weight float64
+}
+
 // subslices returns n splits of the provided points with roughly the same
-// load. For example, given xs = []uint64{10, 20, 30, 40, 50, 60, 70, 80}, n =
-// 4, and a load of 10.0, subslices will return the following four splits:
+// load. For example, given xs = []uint64{10, 20, 30, 40, 50, 60, 70, 80}, all
+// with the same weight, n = 4, and a load of 10.0, subslices will return the
+// following four splits:
 //
 //   - {Start: 10, Load: 2.5} // [10, 30)
 //   - {Start: 30, Load: 2.5} // [30, 50)
@@ -295,9 +348,9 @@ func (s *sliceSummary) splits(delta time.Duration) []*protos.LoadReport_Subslice
 // subslices only guarantees that the returned splits are contiguous and
 // sorted.
 //
-// REQUIRES xs is sorted in increasing order
+// REQUIRES xs is sorted in increasing order of key
 // REQUIRES n > 0
-func subslices(load float64, xs []uint64, n int) []*protos.LoadReport_SubsliceLoad {
+func subslices(load float64, xs []weightedKey, n int) []*protos.LoadReport_SubsliceLoad {
 	quantum := load / float64(n)
 	ps := percentiles(xs, n)
 	subslices := []*protos.LoadReport_SubsliceLoad{{Start: ps[0], Load: quantum}}
@@ -314,21 +367,40 @@ func subslices(load float64, xs []uint64, n int) []*protos.LoadReport_SubsliceLo
 	return subslices
 }
 
-// percentiles returns n equally spaced percentiles of the provided sorted set
-// of points. For example, given xs = []uint64{10, 20, 30, 40, 50, 60, 70, 80}
-// and n = 4, percentiles will return []uint64{10, 30, 50, 70} where
+// percentiles returns n equally spaced weighted percentiles of the provided
+// sorted set of points. For example, given xs = []uint64{10, 20, 30, 40, 50,
+// 60, 70, 80}, all with the same weight, and n = 4, percentiles will return
+// []uint64{10, 30, 50, 70} where
 //
 //   - 10 is the 0th percentile,
 //   - 30 is the 25th percentile,
 //   - 50 is the 50th percentile,
 //   - 70 is the 75th percentile,
 //
-// REQUIRES xs is sorted in increasing order
+// A point with a larger weight spans a larger range of percentiles. If 80 had
+// the same weight as all other points combined, for example, percentiles
+// would return []uint64{10, 40, 80, 80}.
+//
+// REQUIRES xs is sorted in increasing order of key
 // REQUIRES n > 0
-func percentiles(xs []uint64, n int) []uint64 {
+func percentiles(xs []weightedKey, n int) []uint64 {
+	var total float64
+	for _, x := range xs {
+		total += x.weight
+	}
+
+	// The ith percentile is the first point whose cumulative weight, including
+	// its own, exceeds i/n of the total weight.
 	ps := make([]uint64, n)
+	j := 0
+	cumulative := xs[0].weight
 	for i := 0; i < n; i++ {
-		ps[i] = xs[int(float64(i)/float64(n)*float64(len(xs)))]
+		target := float64(i) / float64(n) * total
+		for cumulative <= target && j < len(xs)-1 {
+			j++
+			cumulative += xs[j].weight
+		}
+		ps[i] = xs[j].key
 	}
 	return ps
 }
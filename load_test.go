@@ -15,11 +15,14 @@
 package weaver
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"testing"
 	"time"
 
+	"github.com/ServiceWeaver/weaver/internal/routing"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
 	"github.com/ServiceWeaver/weaver/runtime/protos"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -138,6 +141,97 @@ func TestLoadCollector(t *testing.T) {
 	}
 }
 
+func TestLoadCollectorClampsLoad(t *testing.T) {
+	assignment := &protos.Assignment{
+		Slices: []*protos.Assignment_Slice{{Start: 0, Replicas: []string{"test://a"}}},
+	}
+	lc := newLoadCollector("component", "test://a")
+	lc.now = func() time.Time { return at(0) }
+	lc.updateAssignment(assignment)
+
+	lc.add(0, 2.5)
+	lc.add(1, 1e9)         // clamped to maxCallLoad
+	lc.add(2, -1)          // clamped to minCallLoad
+	lc.add(3, math.NaN())  // treated as 1
+	lc.add(4, math.Inf(1)) // clamped to maxCallLoad
+
+	lc.now = func() time.Time { return at(1) }
+	got := lc.report().Load[0].Load
+	if want := 2.5 + maxCallLoad + minCallLoad + 1 + maxCallLoad; !approxEqual(got, want) {
+		t.Fatalf("load: got %f, want %f", got, want)
+	}
+}
+
+func TestReportLoadInLocalCall(t *testing.T) {
+	ctx, load := codegen.WithCallLoad(context.Background())
+	ReportLoad(ctx, 2)
+
+	// A method called locally from the routed method doesn't add to the load
+	// of the routed call.
+	ReportLoad(codegen.WithLocalCall(ctx, "caller", "callee"), 100)
+	if got, want := load.Load(), 2.0; got != want {
+		t.Fatalf("load: got %f, want %f", got, want)
+	}
+}
+
+func TestReportedLoadShiftsAssignment(t *testing.T) {
+	// Replica a owns [0, half) and replica b owns [half, max]. Both receive
+	// the same number of calls, but the calls for key hot, owned by a, are
+	// expensive.
+	const half = uint64(1) << 63
+	const hot = half / 4
+	assignment := &protos.Assignment{
+		Slices: []*protos.Assignment_Slice{
+			{Start: 0, Replicas: []string{"test://a"}},
+			{Start: half, Replicas: []string{"test://b"}},
+		},
+	}
+	replicas := []string{"test://a", "test://b"}
+
+	for _, test := range []struct {
+		name    string
+		hotLoad float64 // load of every call for the hot key
+		shift   bool    // whether the assignment should change
+	}{
+		{"Unweighted", 1.0, false},
+		{"Weighted", 1000.0, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			loads := map[string]*protos.LoadReport_ComponentLoad{}
+			for _, replica := range replicas {
+				lc := newLoadCollector("component", replica)
+				lc.now = func() time.Time { return at(0) }
+				lc.updateAssignment(assignment)
+				for i := uint64(0); i < 1000; i++ {
+					lc.add(i*(math.MaxUint64/1000), 1.0)
+				}
+				for i := 0; i < 10; i++ {
+					lc.add(hot, test.hotLoad)
+				}
+				lc.now = func() time.Time { return at(10) }
+				loads[replica] = lc.report()
+			}
+
+			got, ok := routing.BalanceLoad(assignment, replicas, loads)
+			if ok != test.shift {
+				t.Fatalf("BalanceLoad: got %v, want new assignment %t", got, test.shift)
+			}
+			if !ok {
+				return
+			}
+
+			// The hot key carries most of the load, so it gets a replica of
+			// its own, and a's share of the key space shrinks.
+			if n := len(got.Slices); n != 2 {
+				t.Fatalf("got %d slices, want 2:\n%s", n, routing.FormatAssignment(got))
+			}
+			if boundary := got.Slices[1].Start; boundary > hot {
+				t.Fatalf("got boundary %d, want <= %d:\n%s", boundary, hot, routing.FormatAssignment(got))
+			}
+		})
+	}
+}
+
 func TestLoadCollectorSizeAndSplitEstimates(t *testing.T) {
 	// Test plan: Add load for n different keys. The size estimate should be
 	// close to n, but almost certainly isn't exactly n. We check that the size
@@ -306,7 +400,7 @@ func TestSubslices(t *testing.T) {
 	} {
 		name := fmt.Sprintf("%f/%v/%d", test.load, test.xs, test.n)
 		t.Run(name, func(t *testing.T) {
-			got := subslices(test.load, test.xs, test.n)
+			got := subslices(test.load, unweighted(test.xs), test.n)
 			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
 				t.Fatalf("subslices (-want +got):\n%s", diff)
 			}
@@ -314,6 +408,15 @@ func TestSubslices(t *testing.T) {
 	}
 }
 
+// unweighted returns the provided keys, each with a weight of 1.
+func unweighted(xs []uint64) []weightedKey {
+	keys := make([]weightedKey, len(xs))
+	for i, x := range xs {
+		keys[i] = weightedKey{key: x, weight: 1.0}
+	}
+	return keys
+}
+
 func TestPercentiles(t *testing.T) {
 	for _, test := range []struct {
 		xs   []uint64
@@ -331,6 +434,27 @@ func TestPercentiles(t *testing.T) {
 		{[]uint64{0}, 5, []uint64{0, 0, 0, 0, 0}},
 		{[]uint64{0, 1}, 5, []uint64{0, 0, 0, 1, 1}},
 		{[]uint64{0, 1, 2, 3}, 8, []uint64{0, 0, 1, 1, 2, 2, 3, 3}},
+	} {
+		name := fmt.Sprintf("%v/%d", test.xs, test.n)
+		t.Run(name, func(t *testing.T) {
+			got := percentiles(unweighted(test.xs), test.n)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("percentiles (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWeightedPercentiles(t *testing.T) {
+	for _, test := range []struct {
+		xs   []weightedKey
+		n    int
+		want []uint64
+	}{
+		{[]weightedKey{{10, 1}, {20, 1}, {30, 1}, {40, 1}, {50, 1}, {60, 1}, {70, 1}, {80, 7}}, 4, []uint64{10, 40, 80, 80}},
+		{[]weightedKey{{0, 9}, {1, 1}}, 2, []uint64{0, 0}},
+		{[]weightedKey{{0, 1}, {1, 1}, {2, 2}}, 2, []uint64{0, 2}},
+		{[]weightedKey{{0, 0.5}, {1, 0.5}, {2, 3}}, 4, []uint64{0, 2, 2, 2}},
 	} {
 		name := fmt.Sprintf("%v/%d", test.xs, test.n)
 		t.Run(name, func(t *testing.T) {
@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"golang.org/x/exp/slog"
+)
+
+// logFieldsKey is the context key for the fields added by WithLogFields.
+type logFieldsKey struct{}
+
+// WithLogFields returns a copy of ctx that carries the provided log fields,
+// in addition to any fields already carried by ctx. The loggers returned by
+// LoggerFromContext for ctx, or for any context derived from it, include the
+// fields in every log entry:
+//
+//	func (f *foo) Handle(ctx context.Context, req Request) error {
+//	    ctx = weaver.WithLogFields(ctx, slog.String("requestID", req.ID))
+//	    return f.helper(ctx) // helper logs with LoggerFromContext(ctx)
+//	}
+//
+// The fields are not propagated across processes. A remote component method
+// call starts with no fields.
+func WithLogFields(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	prev, _ := ctx.Value(logFieldsKey{}).([]slog.Attr)
+	fields := make([]slog.Attr, 0, len(prev)+len(attrs))
+	fields = append(fields, prev...)
+	fields = append(fields, attrs...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// LoggerFromContext returns the logger of the component whose method is
+// handling ctx, i.e., the logger returned by its Logger method, with the
+// fields added to ctx by WithLogFields. It can be called anywhere in the call
+// stack of a component method, without access to the component.
+//
+// The context of an HTTP request served by [Serve] carries the logger of the
+// component that owns the listener. If ctx is neither the context of a
+// component method call nor of such a request (e.g., it is the context passed
+// to Init), LoggerFromContext returns slog.Default() with the fields carried
+// by ctx.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger := componentLogger(ctx)
+	if logger == nil {
+		logger, _ = ctx.Value(requestLoggerKey{}).(*slog.Logger)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	fields, _ := ctx.Value(logFieldsKey{}).([]slog.Attr)
+	if len(fields) == 0 {
+		return logger
+	}
+	args := make([]any, len(fields))
+	for i, f := range fields {
+		args[i] = f
+	}
+	return logger.With(args...)
+}
+
+// requestLoggerKey is the context key of the logger of the component serving
+// an HTTP request. See Serve.
+type requestLoggerKey struct{}
+
+// withRequestLogger returns a copy of ctx that carries the provided logger.
+func withRequestLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, requestLoggerKey{}, logger)
+}
+
+// componentLogger returns the logger of the local component whose method is
+// handling ctx, or nil if there is none. Both the handlers of remote calls and
+// the generated local stubs record the called component in ctx before
+// invoking a method.
+func componentLogger(ctx context.Context) *slog.Logger {
+	info, ok := codegen.CallerInfoFromContext(ctx)
+	if !ok || info.Callee == "" {
+		return nil
+	}
+	w, err := weaveletFromContext(ctx)
+	if err != nil {
+		return nil
+	}
+	c, ok := w.componentsByName[info.Callee]
+	if !ok {
+		return nil
+	}
+	return c.ctxLogger.Load()
+}
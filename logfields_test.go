@@ -0,0 +1,78 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"golang.org/x/exp/slog"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	c := &component{}
+	c.ctxLogger.Store(slog.New(slog.NewTextHandler(&buf, nil)).With("component", "pkg/A"))
+	w := &weavelet{componentsByName: map[string]*component{"pkg/A": c}}
+
+	ctx := withWeavelet(context.Background(), w)
+	ctx = codegen.WithLocalCall(ctx, "pkg/Main", "pkg/A")
+	withRequest := WithLogFields(ctx, slog.String("requestID", "r1"))
+	withUser := WithLogFields(withRequest, slog.String("userID", "u1"))
+
+	for _, test := range []struct {
+		name    string
+		ctx     context.Context
+		want    []string
+		notWant []string
+	}{
+		{"NoFields", ctx, []string{"component=pkg/A"}, []string{"requestID", "userID"}},
+		{"OneField", withRequest, []string{"component=pkg/A", "requestID=r1"}, []string{"userID"}},
+		{"TwoFields", withUser, []string{"component=pkg/A", "requestID=r1", "userID=u1"}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			LoggerFromContext(test.ctx).Info("hello")
+			got := buf.String()
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %q, want %q", got, want)
+				}
+			}
+			for _, notWant := range test.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("got %q, don't want %q", got, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerFromContextNoComponent(t *testing.T) {
+	// Without a component method call, the default logger is used.
+	w := &weavelet{componentsByName: map[string]*component{}}
+	for _, ctx := range []context.Context{
+		context.Background(),
+		withWeavelet(context.Background(), w),
+		codegen.WithLocalCall(withWeavelet(context.Background(), w), "pkg/Main", "pkg/Unknown"),
+	} {
+		if logger := LoggerFromContext(ctx); logger == nil {
+			t.Errorf("LoggerFromContext: got nil logger")
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slog"
+)
+
+const (
+	logConfigKey      = "github.com/ServiceWeaver/weaver/logging"
+	shortLogConfigKey = "logging"
+)
+
+// logConfig holds the log levels of components, as specified in the logging
+// section of the config. For example:
+//
+//	[logging]
+//	log_level = "info"
+//
+//	[logging.components."github.com/example/app/Cache"]
+//	log_level = "warn"
+type logConfig struct {
+	// The level of every component that doesn't have its own, or empty to
+	// log entries of every level.
+	LogLevel string `toml:"log_level"`
+
+	// Per-component overrides of LogLevel, keyed by full component name.
+	Components map[string]componentLogConfig `toml:"components"`
+}
+
+// componentLogConfig holds the log level of a single component.
+type componentLogConfig struct {
+	LogLevel string `toml:"log_level"`
+}
+
+// Validate implements the interface consulted by runtime.ParseConfigSection.
+func (c *logConfig) Validate() error {
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		return err
+	}
+	for name, cfg := range c.Components {
+		if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+			return fmt.Errorf("component %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// level returns the minimum level of the log entries of the provided
+// component, or nil if entries of every level are logged. A component's own
+// level takes precedence over the default level.
+func (c *logConfig) level(component string) slog.Leveler {
+	level := c.LogLevel
+	if cfg, ok := c.Components[component]; ok && cfg.LogLevel != "" {
+		level = cfg.LogLevel
+	}
+	l, err := parseLogLevel(level)
+	if err != nil {
+		// Levels are validated when the config is parsed.
+		panic(err)
+	}
+	return l
+}
+
+// parseLogLevel parses a level like "debug", "info", "warn", "error", or
+// "info+2". It returns nil for the empty string.
+func parseLogLevel(s string) (slog.Leveler, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return nil, fmt.Errorf("invalid log_level %q", s)
+	}
+	return l, nil
+}
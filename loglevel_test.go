@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+	"golang.org/x/exp/slog"
+)
+
+func TestLogConfig(t *testing.T) {
+	const section = `
+log_level = "info"
+
+[components."pkg/Noisy"]
+log_level = "warn"
+
+[components."pkg/Verbose"]
+log_level = "DEBUG"
+
+[components."pkg/Default"]
+`
+	var cfg logConfig
+	sections := map[string]string{shortLogConfigKey: section}
+	if err := runtime.ParseConfigSection(logConfigKey, shortLogConfigKey, sections, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		component string
+		want      slog.Level
+	}{
+		{"pkg/Noisy", slog.LevelWarn},
+		{"pkg/Verbose", slog.LevelDebug},
+		{"pkg/Default", slog.LevelInfo},
+		{"pkg/Other", slog.LevelInfo},
+	} {
+		if got := cfg.level(test.component); got == nil || got.Level() != test.want {
+			t.Errorf("level(%q): got %v, want %v", test.component, got, test.want)
+		}
+	}
+
+	// Without a default level, components without their own level log
+	// entries of every level.
+	var empty logConfig
+	if got := empty.level("pkg/Other"); got != nil {
+		t.Errorf("level with no config: got %v, want nil", got)
+	}
+}
+
+func TestLogConfigErrors(t *testing.T) {
+	for _, test := range []struct{ section, want string }{
+		{`log_level = "loud"`, `invalid log_level "loud"`},
+		{"[components.\"pkg/A\"]\nlog_level = \"quiet\"", `component "pkg/A": invalid log_level "quiet"`},
+		{`level = "info"`, "unknown keys"},
+	} {
+		var cfg logConfig
+		sections := map[string]string{logConfigKey: test.section}
+		err := runtime.ParseConfigSection(logConfigKey, shortLogConfigKey, sections, &cfg)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got error %v, want %q", test.section, err, test.want)
+		}
+	}
+}
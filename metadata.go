@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"golang.org/x/exp/maps"
+)
+
+// SetMetadata returns a copy of ctx that carries the provided metadata
+// key-value pair, in addition to any metadata already carried by ctx. If key
+// is already present, its value is replaced.
+//
+// Metadata is propagated along with component method calls, both local and
+// remote, similar to gRPC metadata. A component method can retrieve the
+// metadata of the call it is handling using Metadata, and any component
+// methods it calls in turn using the same context will receive the metadata
+// as well. For example, if component A sets metadata and calls B, which
+// calls C, then C receives the metadata set by A, unless B overrides it.
+//
+//	ctx = weaver.SetMetadata(ctx, "tenant", "acme")
+//	err := a.Foo(ctx) // A.Foo and the methods it calls see tenant=acme.
+//
+// The total size of all metadata keys and values is limited to 8 KiB. Remote
+// method calls with larger metadata fail without being executed.
+func SetMetadata(ctx context.Context, key, value string) context.Context {
+	old := call.Metadata(ctx)
+	md := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		md[k] = v
+	}
+	md[key] = value
+	return call.WithMetadata(ctx, md)
+}
+
+// Metadata returns the metadata carried by ctx. It returns an empty map if
+// ctx carries no metadata. Modifications of the returned map do not affect
+// ctx. See SetMetadata for details.
+func Metadata(ctx context.Context) map[string]string {
+	md := call.Metadata(ctx)
+	if md == nil {
+		return map[string]string{}
+	}
+	return maps.Clone(md)
+}
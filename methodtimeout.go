@@ -0,0 +1,71 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// parseMethodTimeouts returns the method timeouts of the provided component,
+// as listed under runtime.MethodTimeoutsKey in the component's config
+// section. It returns an error if a timeout names a method that the
+// component doesn't have.
+func parseMethodTimeouts(info *codegen.Registration, sections map[string]string) (map[string]time.Duration, error) {
+	timeouts, err := runtime.ParseMethodTimeouts(info.Name, sections)
+	if err != nil {
+		return nil, err
+	}
+	for method := range timeouts {
+		if _, ok := info.Iface.MethodByName(method); !ok {
+			return nil, fmt.Errorf("section %q: %s: unknown method %q", info.Name, runtime.MethodTimeoutsKey, method)
+		}
+	}
+	return timeouts, nil
+}
+
+// withMethodTimeouts wraps handle, a handle to component c used by requester,
+// so that every call to a method with a timeout in c's config runs with that
+// timeout. A call with an earlier deadline keeps its deadline. Local and
+// remote calls behave the same, so a call times out the same way wherever c
+// runs.
+func withMethodTimeouts(c *component, handle any, requester string, remote bool) any {
+	if len(c.methodTimeouts) == 0 || c.info.InterceptFn == nil {
+		return handle
+	}
+	timeout := func(ctx context.Context, call codegen.Call, next codegen.Handler) ([]any, error) {
+		d, ok := c.methodTimeouts[call.Method]
+		if !ok {
+			return next(ctx, call.Args)
+		}
+		tctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		results, err := next(tctx, call.Args)
+		if err == nil || ctx.Err() != nil || tctx.Err() != context.DeadlineExceeded {
+			// The call succeeded, or failed for reasons other than its timeout.
+			return results, err
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			err = errors.Join(err, context.DeadlineExceeded)
+		}
+		return results, fmt.Errorf("%s.%s: exceeded its %v timeout: %w", c.info.Name, call.Method, d, err)
+	}
+	return c.info.InterceptFn(handle, timeout, codegen.Call{Caller: requester, Component: c.info.Name, Remote: remote})
+}
@@ -29,6 +29,11 @@
 //     You can use a histogram to measure things like the latency of every HTTP
 //     request your program has received so far.
 //
+// The package also provides a [SlidingWindowCounter], which counts the events
+// that happened recently (e.g., in the last minute). Unlike the metrics above,
+// a SlidingWindowCounter is not exported, and is intended for use by
+// in-process logic that adapts to recent behavior.
+//
 // # Declaring Metrics
 //
 // Declare metrics using [NewCounter], [NewGauge], or [NewHistogram]. We
@@ -71,6 +76,27 @@
 //		exampleHistogram.Put(3)
 //	}
 //
+// Use [NewGaugeFunc] for a gauge that is cheaper to compute on demand than to
+// keep up to date. Its callback is invoked every time metrics are collected.
+//
+//	var queueDepth = metrics.NewGaugeFunc(
+//		"queue_depth",
+//		"The number of items in the queue",
+//		func() float64 { return float64(queue.Len()) },
+//	)
+//
+// Metrics are process-wide, so a test that checks the value of a metric may
+// observe updates made by other tests. Test builds, i.e., builds with the
+// "testing" build tag, add a Reset method to [Counter], [Histogram], and
+// [SlidingWindowCounter] that zeroes the metric:
+//
+//	func TestRequests(t *testing.T) {
+//		requestCount.Reset()
+//		// ...
+//	}
+//
+//	$ go test -tags testing ./...
+//
 // # Metric Labels
 //
 // You can declare a metric with a set of key-value labels. For example, if you
@@ -133,6 +159,25 @@
 //	    Bar string `weaver:"my_custom_name"` // exported as "my_custom_name"
 //	}
 //
+// A labeled metric records at most 20,000 distinct sets of label values by
+// default. Values for label sets past the limit are funneled into a single
+// overflow metric whose label values are all "__overflow__". Use the
+// [CardinalityLimit] option to change the limit of a metric.
+//
+// If the label names aren't known until runtime, e.g., when they come from
+// an event's attributes, use [NewDynamicCounter], which takes the labels as a
+// map instead of a struct:
+//
+//	var events = metrics.NewDynamicCounter("events", "The number of events")
+//
+//	func record(e event) {
+//	    events.Inc(map[string]string{"type": e.Type, "sku": e.SKU})
+//	}
+//
+// Every distinct set of labels is recorded as a separate counter, subject to
+// the same cardinality limit. Label names must be valid Prometheus label
+// names, i.e., they must match [a-zA-Z_][a-zA-Z0-9_]*.
+//
 // # Exporting Metrics
 //
 // Service Weaver integrates metrics into the environment where your
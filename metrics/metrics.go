@@ -59,8 +59,8 @@ type CounterMap[L comparable] struct {
 // NewCounterMap returns a new CounterMap.
 // It is typically called during package initialization since it
 // panics if called more than once in the same process with the same name.
-func NewCounterMap[L comparable](name, help string) *CounterMap[L] {
-	return &CounterMap[L]{metrics.RegisterMap[L](protos.MetricType_COUNTER, name, help, nil)}
+func NewCounterMap[L comparable](name, help string, opts ...MapOption) *CounterMap[L] {
+	return &CounterMap[L]{registerMap[L](protos.MetricType_COUNTER, name, help, nil, opts)}
 }
 
 // Name returns the name of the CounterMap.
@@ -75,6 +75,53 @@ func (c *CounterMap[L]) Get(labels L) *Counter {
 	return &Counter{c.impl.Get(labels)}
 }
 
+// A DynamicCounter is a collection of Counters with the same name, whose label
+// names and values are determined at runtime, e.g., an event type or a product
+// SKU, rather than by a label struct type. Unlike a CounterMap, the same
+// DynamicCounter can record counts with different sets of label names.
+//
+// Label names must be valid Prometheus label names, i.e., they must match
+// [a-zA-Z_][a-zA-Z0-9_]*. Prefer a CounterMap when the label names are known
+// at compile time, as it is faster.
+type DynamicCounter struct {
+	impl *metrics.DynamicMap
+}
+
+// NewDynamicCounter returns a new DynamicCounter.
+// It is typically called during package initialization since it
+// panics if called more than once in the same process with the same name.
+func NewDynamicCounter(name, help string, opts ...MapOption) *DynamicCounter {
+	var options mapOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	m := metrics.RegisterDynamicMap(protos.MetricType_COUNTER, name, help, nil)
+	m.SetCardinalityLimit(options.cardinalityLimit)
+	return &DynamicCounter{impl: m}
+}
+
+// Name returns the name of the DynamicCounter.
+func (c *DynamicCounter) Name() string {
+	return c.impl.Name()
+}
+
+// Get returns the Counter with the provided labels, constructing it if it
+// doesn't already exist. Multiple calls to Get with equal labels will return
+// the same Counter. Get panics if a label name is invalid.
+func (c *DynamicCounter) Get(labels map[string]string) *Counter {
+	return &Counter{c.impl.Get(labels)}
+}
+
+// Inc increases the Counter with the provided labels by one.
+func (c *DynamicCounter) Inc(labels map[string]string) {
+	c.impl.Get(labels).Inc()
+}
+
+// Add increases the Counter with the provided labels by delta.
+func (c *DynamicCounter) Add(labels map[string]string, delta float64) {
+	c.impl.Get(labels).Add(delta)
+}
+
 // A Gauge is a float-valued metric that can hold an arbitrary numerical value,
 // which can increase or decrease over time. For example, you can use a Gauge
 // to measure the current memory usage or the current number of outstanding
@@ -120,8 +167,8 @@ type GaugeMap[L comparable] struct {
 // NewGaugeMap returns a new GaugeMap.
 // It is typically called during package initialization since it
 // panics if called more than once in the same process with the same name.
-func NewGaugeMap[L comparable](name, help string) *GaugeMap[L] {
-	return &GaugeMap[L]{metrics.RegisterMap[L](protos.MetricType_GAUGE, name, help, nil)}
+func NewGaugeMap[L comparable](name, help string, opts ...MapOption) *GaugeMap[L] {
+	return &GaugeMap[L]{registerMap[L](protos.MetricType_GAUGE, name, help, nil, opts)}
 }
 
 // Name returns the name of the GaugeMap.
@@ -136,6 +183,61 @@ func (g *GaugeMap[L]) Get(labels L) *Gauge {
 	return &Gauge{g.impl.Get(labels)}
 }
 
+// A GaugeFunc is a Gauge whose value is computed by a callback whenever
+// metrics are collected, rather than updated as it changes. For example, you
+// can use a GaugeFunc to report the current depth of a queue or the number of
+// items in a cache.
+//
+// A callback is never invoked concurrently with itself. If it panics, the
+// panic is logged and the gauge reports NaN. Callbacks run while metrics are
+// being collected, so they should be fast and must not create new metrics.
+type GaugeFunc struct {
+	impl *metrics.Metric
+}
+
+// NewGaugeFunc returns a new GaugeFunc whose value is computed by fn.
+// It is typically called during package initialization since it
+// panics if called more than once in the same process with the same name.
+// Use NewGaugeFuncMap to make a GaugeFunc with labels.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{impl: metrics.Register(protos.MetricType_GAUGE, name, help, nil)}
+	g.impl.SetFunc(fn)
+	return g
+}
+
+// Name returns the name of the GaugeFunc.
+func (g *GaugeFunc) Name() string {
+	return g.impl.Name()
+}
+
+// A GaugeFuncMap is a collection of GaugeFuncs with the same name and label
+// schema but with different label values. See package documentation for a
+// description of L.
+type GaugeFuncMap[L comparable] struct {
+	impl *metrics.MetricMap[L]
+}
+
+// NewGaugeFuncMap returns a new GaugeFuncMap.
+// It is typically called during package initialization since it
+// panics if called more than once in the same process with the same name.
+func NewGaugeFuncMap[L comparable](name, help string, opts ...MapOption) *GaugeFuncMap[L] {
+	return &GaugeFuncMap[L]{registerMap[L](protos.MetricType_GAUGE, name, help, nil, opts)}
+}
+
+// Name returns the name of the GaugeFuncMap.
+func (g *GaugeFuncMap[L]) Name() string {
+	return g.impl.Name()
+}
+
+// Register sets the callback of the GaugeFunc with the provided labels,
+// constructing the GaugeFunc if it doesn't already exist, and returns it.
+// Registering a callback for labels that already have one replaces it.
+func (g *GaugeFuncMap[L]) Register(labels L, fn func() float64) *GaugeFunc {
+	m := g.impl.Get(labels)
+	m.SetFunc(fn)
+	return &GaugeFunc{m}
+}
+
 // A Histogram is a metric that counts the number of values that fall in
 // specified ranges (i.e. buckets). For example, you can use a Histogram to
 // measure the distribution of request latencies.
@@ -178,6 +280,41 @@ func (h *Histogram) Put(val float64) {
 	h.impl.Put(val)
 }
 
+// Snapshot returns a point-in-time copy of the histogram. The bucket counts
+// and sum are read together, so every recorded value is reflected in both or
+// in neither.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	bounds, counts, sum := h.impl.Histogram()
+	s := HistogramSnapshot{
+		Buckets: bounds,
+		Counts:  make([]int64, len(counts)),
+		Sum:     sum,
+	}
+	for i, c := range counts {
+		s.Counts[i] = int64(c)
+		s.Count += int64(c)
+	}
+	return s
+}
+
+// A HistogramSnapshot is a point-in-time copy of a Histogram, returned by
+// [Histogram.Snapshot].
+type HistogramSnapshot struct {
+	// Buckets are the histogram's bucket boundaries. See [NewHistogram] for a
+	// description of how they define the buckets.
+	Buckets []float64
+
+	// Counts[i] is the number of values recorded in bucket i. There are
+	// len(Buckets)+1 buckets.
+	Counts []int64
+
+	// Sum is the sum of all recorded values.
+	Sum float64
+
+	// Count is the number of recorded values, i.e., the sum of Counts.
+	Count int64
+}
+
 // A HistogramMap is a collection of Histograms with the same name and label
 // schema but with different label values. See package documentation for a
 // description of L.
@@ -188,8 +325,8 @@ type HistogramMap[L comparable] struct {
 // NewHistogramMap returns a new HistogramMap.
 // It is typically called during package initialization since it
 // panics if called more than once in the same process with the same name.
-func NewHistogramMap[L comparable](name, help string, bounds []float64) *HistogramMap[L] {
-	return &HistogramMap[L]{metrics.RegisterMap[L](protos.MetricType_HISTOGRAM, name, help, bounds)}
+func NewHistogramMap[L comparable](name, help string, bounds []float64, opts ...MapOption) *HistogramMap[L] {
+	return &HistogramMap[L]{registerMap[L](protos.MetricType_HISTOGRAM, name, help, bounds, opts)}
 }
 
 // Name returns the name of the HistogramMap.
@@ -203,3 +340,37 @@ func (h *HistogramMap[L]) Name() string {
 func (h *HistogramMap[L]) Get(labels L) *Histogram {
 	return &Histogram{h.impl.Get(labels)}
 }
+
+// A MapOption configures a CounterMap, DynamicCounter, GaugeMap,
+// GaugeFuncMap, or HistogramMap.
+type MapOption func(*mapOptions)
+
+type mapOptions struct {
+	cardinalityLimit int
+}
+
+// CardinalityLimit returns a MapOption that limits the number of distinct
+// labels a map records separately. Once the limit is reached, Get returns a
+// single overflow metric, whose label values are all "__overflow__", for every
+// label it hasn't seen before. This guards against a bug, like putting user
+// IDs into a label, creating an unbounded number of metrics.
+//
+// If limit is negative, the map is unbounded. If the option is not provided,
+// or limit is zero, the map uses the limit specified by the
+// metric_cardinality_limit field of the application config, which defaults to
+// 20,000.
+func CardinalityLimit(limit int) MapOption {
+	return func(o *mapOptions) { o.cardinalityLimit = limit }
+}
+
+// registerMap registers and returns a new metric map with the provided
+// options.
+func registerMap[L comparable](typ protos.MetricType, name, help string, bounds []float64, opts []MapOption) *metrics.MetricMap[L] {
+	var options mapOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	m := metrics.RegisterMap[L](typ, name, help, bounds)
+	m.SetCardinalityLimit(options.cardinalityLimit)
+	return m
+}
@@ -15,7 +15,11 @@
 package metrics_test
 
 import (
+	"math"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -139,6 +143,26 @@ func TestCounterMap(t *testing.T) {
 	})
 }
 
+func TestCounterMapCardinalityLimit(t *testing.T) {
+	type labels struct{ User string }
+	c := metrics.NewCounterMap[labels](uuid.New().String(), "", metrics.CardinalityLimit(1))
+	c.Get(labels{"alice"}).Add(1)
+	c.Get(labels{"bob"}).Add(2)
+	c.Get(labels{"carol"}).Add(3)
+	expect(t, &imetrics.MetricSnapshot{
+		Type:   protos.MetricType_COUNTER,
+		Name:   c.Name(),
+		Labels: map[string]string{"user": "alice"},
+		Value:  1,
+	})
+	expect(t, &imetrics.MetricSnapshot{
+		Type:   protos.MetricType_COUNTER,
+		Name:   c.Name(),
+		Labels: map[string]string{"user": imetrics.OverflowLabelValue},
+		Value:  5,
+	})
+}
+
 func TestGauge(t *testing.T) {
 	g := metrics.NewGauge(uuid.New().String(), "")
 	g.Set(42)
@@ -172,6 +196,82 @@ func TestGaugeMap(t *testing.T) {
 
 }
 
+func TestGaugeFunc(t *testing.T) {
+	var calls int
+	g := metrics.NewGaugeFunc(uuid.New().String(), "", func() float64 {
+		calls++
+		return float64(calls)
+	})
+	expect(t, &imetrics.MetricSnapshot{
+		Type:  protos.MetricType_GAUGE,
+		Name:  g.Name(),
+		Value: 1,
+	})
+	expect(t, &imetrics.MetricSnapshot{
+		Type:  protos.MetricType_GAUGE,
+		Name:  g.Name(),
+		Value: 2,
+	})
+}
+
+func TestGaugeFuncMap(t *testing.T) {
+	type labels struct{ A string }
+	g := metrics.NewGaugeFuncMap[labels](uuid.New().String(), "")
+	g.Register(labels{"1"}, func() float64 { return 1 })
+	g.Register(labels{"2"}, func() float64 { return 2 })
+	g.Register(labels{"2"}, func() float64 { return 3 }) // replaces 2
+	expect(t, &imetrics.MetricSnapshot{
+		Type:   protos.MetricType_GAUGE,
+		Name:   g.Name(),
+		Labels: map[string]string{"a": "1"},
+		Value:  1,
+	})
+	expect(t, &imetrics.MetricSnapshot{
+		Type:   protos.MetricType_GAUGE,
+		Name:   g.Name(),
+		Labels: map[string]string{"a": "2"},
+		Value:  3,
+	})
+}
+
+func TestGaugeFuncPanic(t *testing.T) {
+	g := metrics.NewGaugeFunc(uuid.New().String(), "", func() float64 {
+		panic("oops")
+	})
+	for _, m := range imetrics.Snapshot() {
+		if m.Name == g.Name() && !math.IsNaN(m.Value) {
+			t.Fatalf("got %v, want NaN", m.Value)
+		}
+	}
+}
+
+func TestGaugeFuncNotConcurrent(t *testing.T) {
+	var running, overlaps atomic.Int32
+	g := metrics.NewGaugeFunc(uuid.New().String(), "", func() float64 {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return 0
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var e imetrics.Exporter
+			for j := 0; j < 10; j++ {
+				e.Export()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := overlaps.Load(); n > 0 {
+		t.Fatalf("callback of %q invoked concurrently %d times", g.Name(), n)
+	}
+}
+
 func TestHistogram(t *testing.T) {
 	bounds := []float64{1, 10, 20}
 	h := metrics.NewHistogram(uuid.New().String(), "", bounds)
@@ -185,6 +285,69 @@ func TestHistogram(t *testing.T) {
 	})
 }
 
+func TestHistogramSnapshot(t *testing.T) {
+	h := metrics.NewHistogram(uuid.New().String(), "", []float64{1, 10, 20})
+	if diff := cmp.Diff(metrics.HistogramSnapshot{
+		Buckets: []float64{1, 10, 20},
+		Counts:  []int64{0, 0, 0, 0},
+	}, h.Snapshot()); diff != "" {
+		t.Fatalf("empty Snapshot (-want +got):\n%s", diff)
+	}
+
+	for _, v := range []float64{0.5, 5, 10, 15, 42} {
+		h.Put(v)
+	}
+	want := metrics.HistogramSnapshot{
+		Buckets: []float64{1, 10, 20},
+		Counts:  []int64{1, 1, 2, 1},
+		Sum:     72.5,
+		Count:   5,
+	}
+	got := h.Snapshot()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Snapshot (-want +got):\n%s", diff)
+	}
+
+	// The snapshot is a copy.
+	got.Counts[0] = 100
+	if diff := cmp.Diff(want, h.Snapshot()); diff != "" {
+		t.Fatalf("Snapshot after mutation (-want +got):\n%s", diff)
+	}
+}
+
+func TestHistogramSnapshotConsistent(t *testing.T) {
+	// Every Put records the value 1, so a consistent snapshot has a sum equal
+	// to its count.
+	h := metrics.NewHistogram(uuid.New().String(), "", []float64{0.5, 2})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					h.Put(1)
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	for i := 0; i < 100000; i++ {
+		s := h.Snapshot()
+		if s.Sum != float64(s.Count) || s.Counts[1] != s.Count {
+			t.Fatalf("inconsistent snapshot: %+v", s)
+		}
+	}
+}
+
 func TestHistogramMap(t *testing.T) {
 	name := uuid.New().String()
 	bounds := []float64{1, 10, 20}
@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build testing
+
+package metrics
+
+// The Reset methods below are only compiled into test builds, i.e., with
+// "go test -tags testing". Metrics are process-wide, so tests that check the
+// value of a metric reset it first to avoid observing updates made by other
+// tests. Production code should never reset a metric.
+
+// Reset sets the counter to zero.
+func (c *Counter) Reset() {
+	c.impl.Reset()
+}
+
+// Reset zeroes the counts and sum of the histogram.
+func (h *Histogram) Reset() {
+	h.impl.Reset()
+}
+
+// Reset discards all events counted so far.
+func (c *SlidingWindowCounter) Reset() {
+	for i := range c.buckets {
+		c.buckets[i].Store(0)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build testing
+
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/metrics"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReset(t *testing.T) {
+	counter := metrics.NewCounter("TestReset_counter", "")
+	histogram := metrics.NewHistogram("TestReset_histogram", "", []float64{0, 10})
+	window := metrics.NewSlidingWindowCounter(time.Minute)
+	counter.Add(3)
+	histogram.Put(5)
+	histogram.Put(50)
+	window.Inc()
+
+	counter.Reset()
+	histogram.Reset()
+	window.Reset()
+	want := metrics.HistogramSnapshot{Buckets: []float64{0, 10}, Counts: []int64{0, 0, 0}}
+	if diff := cmp.Diff(want, histogram.Snapshot()); diff != "" {
+		t.Errorf("histogram after Reset (-want +got):\n%s", diff)
+	}
+	if got := window.Count(); got != 0 {
+		t.Errorf("window after Reset: got %d, want 0", got)
+	}
+
+	// Reset metrics keep counting.
+	histogram.Put(5)
+	window.Inc()
+	want = metrics.HistogramSnapshot{Buckets: []float64{0, 10}, Counts: []int64{0, 1, 0}, Count: 1, Sum: 5}
+	if diff := cmp.Diff(want, histogram.Snapshot()); diff != "" {
+		t.Errorf("histogram (-want +got):\n%s", diff)
+	}
+	if got := window.Count(); got != 1 {
+		t.Errorf("window: got %d, want 1", got)
+	}
+}
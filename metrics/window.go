@@ -0,0 +1,144 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// A SlidingWindowCounter counts events that happened within a recent window
+// of time, e.g., the number of errors in the last minute. Unlike a Counter, a
+// SlidingWindowCounter is not exported to a monitoring system. Instead, it is
+// intended for in-process logic that adapts to recent behavior (e.g., load
+// shedding when the error rate is high).
+//
+// A SlidingWindowCounter divides its window into one-second buckets. Events
+// are counted in the bucket of the second in which they happen, and buckets
+// older than the window are discarded. Inc is lock-free and safe to call on
+// the critical path of an application.
+type SlidingWindowCounter struct {
+	start   time.Time        // creation time; buckets are relative to it
+	now     func() time.Time // returns the current time
+	buckets []atomic.Uint64  // circular buffer of buckets, one per second
+}
+
+// Every bucket packs the second it belongs to, relative to the counter's start
+// time, in its upper 32 bits, and the number of events that happened during
+// that second in its lower 32 bits. This allows a bucket to be reset and
+// incremented with a single compare-and-swap.
+const bucketCountBits = 32
+
+// NewSlidingWindowCounter returns a new SlidingWindowCounter that counts
+// events over the provided window. The window is rounded up to a whole number
+// of seconds, and is at least one second long.
+func NewSlidingWindowCounter(window time.Duration) *SlidingWindowCounter {
+	return newSlidingWindowCounter(window, time.Now)
+}
+
+func newSlidingWindowCounter(window time.Duration, now func() time.Time) *SlidingWindowCounter {
+	return &SlidingWindowCounter{
+		start:   now(),
+		now:     now,
+		buckets: make([]atomic.Uint64, seconds(window)),
+	}
+}
+
+// seconds returns the provided duration, rounded up to a whole number of
+// seconds, in seconds. The result is at least one.
+func seconds(d time.Duration) int {
+	n := int((d + time.Second - 1) / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Window returns the duration of the window over which events are counted.
+func (c *SlidingWindowCounter) Window() time.Duration {
+	return time.Duration(len(c.buckets)) * time.Second
+}
+
+// second returns the current second, relative to the counter's start time.
+func (c *SlidingWindowCounter) second() uint64 {
+	return uint64(c.now().Sub(c.start) / time.Second)
+}
+
+// Inc records a single event.
+func (c *SlidingWindowCounter) Inc() {
+	sec := c.second()
+	b := &c.buckets[sec%uint64(len(c.buckets))]
+	for {
+		old := b.Load()
+		next := sec<<bucketCountBits | 1
+		if old>>bucketCountBits == sec {
+			next = old + 1
+		}
+		if b.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// Count returns the number of events that happened within the window,
+// including the current second.
+func (c *SlidingWindowCounter) Count() uint64 {
+	return c.countLast(uint64(len(c.buckets)))
+}
+
+// CountOver returns the number of events that happened within the last d,
+// including the current second. Like the window, d is rounded up to a whole
+// number of seconds and is at least one second long. If d is longer than the
+// window, CountOver is equivalent to Count.
+func (c *SlidingWindowCounter) CountOver(d time.Duration) uint64 {
+	return c.countLast(c.clamp(d))
+}
+
+// countLast returns the number of events that happened within the last n
+// seconds, including the current second.
+//
+// REQUIRES: n <= len(c.buckets)
+func (c *SlidingWindowCounter) countLast(n uint64) uint64 {
+	sec := c.second()
+	var count uint64
+	for i := range c.buckets {
+		v := c.buckets[i].Load()
+		if s := v >> bucketCountBits; s <= sec && sec-s < n {
+			count += v & (1<<bucketCountBits - 1)
+		}
+	}
+	return count
+}
+
+// Rate returns the average number of events per second within the window.
+func (c *SlidingWindowCounter) Rate() float64 {
+	return float64(c.Count()) / c.Window().Seconds()
+}
+
+// RateOver returns the average number of events per second within the last
+// d, as counted by CountOver.
+func (c *SlidingWindowCounter) RateOver(d time.Duration) float64 {
+	n := c.clamp(d)
+	return float64(c.countLast(n)) / float64(n)
+}
+
+// clamp returns the number of seconds in d, as counted by CountOver.
+func (c *SlidingWindowCounter) clamp(d time.Duration) uint64 {
+	n := uint64(seconds(d))
+	if w := uint64(len(c.buckets)); n > w {
+		return w
+	}
+	return n
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := newSlidingWindowCounter(3*time.Second, clock.Now)
+
+	check := func(want uint64) {
+		t.Helper()
+		if got := c.Count(); got != want {
+			t.Errorf("Count: got %d, want %d", got, want)
+		}
+		if got, want := c.Rate(), float64(want)/3; got != want {
+			t.Errorf("Rate: got %f, want %f", got, want)
+		}
+	}
+
+	check(0)
+	c.Inc()
+	c.Inc()
+	check(2) // [2]
+	clock.Advance(time.Second)
+	c.Inc()
+	check(3) // [2, 1]
+	clock.Advance(time.Second)
+	check(3) // [2, 1, 0]
+	clock.Advance(time.Second)
+	c.Inc()
+	check(2) // [1, 0, 1]
+	clock.Advance(10 * time.Second)
+	check(0) // [0, 0, 0]
+}
+
+func TestSlidingWindowCounterCountOver(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := newSlidingWindowCounter(4*time.Second, clock.Now)
+	for i := 0; i < 4; i++ {
+		for j := 0; j <= i; j++ {
+			c.Inc()
+		}
+		clock.Advance(time.Second)
+	}
+	clock.Advance(-time.Second) // buckets: [1, 2, 3, 4]
+
+	for _, test := range []struct {
+		d    time.Duration
+		want uint64
+	}{
+		{0, 4},
+		{time.Second, 4},
+		{1500 * time.Millisecond, 7},
+		{3 * time.Second, 9},
+		{4 * time.Second, 10},
+		{time.Hour, 10},
+	} {
+		if got := c.CountOver(test.d); got != test.want {
+			t.Errorf("CountOver(%v): got %d, want %d", test.d, got, test.want)
+		}
+	}
+	if got, want := c.RateOver(2*time.Second), 3.5; got != want {
+		t.Errorf("RateOver(2s): got %f, want %f", got, want)
+	}
+	if got, want := c.RateOver(time.Hour), 2.5; got != want {
+		t.Errorf("RateOver(1h): got %f, want %f", got, want)
+	}
+}
+
+func TestSlidingWindowCounterWindow(t *testing.T) {
+	for _, test := range []struct {
+		window time.Duration
+		want   time.Duration
+	}{
+		{0, time.Second},
+		{time.Millisecond, time.Second},
+		{time.Second, time.Second},
+		{1500 * time.Millisecond, 2 * time.Second},
+		{time.Minute, time.Minute},
+	} {
+		if got := NewSlidingWindowCounter(test.window).Window(); got != test.want {
+			t.Errorf("NewSlidingWindowCounter(%v).Window(): got %v, want %v", test.window, got, test.want)
+		}
+	}
+}
+
+func TestSlidingWindowCounterConcurrentInc(t *testing.T) {
+	c := NewSlidingWindowCounter(time.Minute)
+	const n, m = 10, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < m; j++ {
+				c.Inc()
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := c.Count(), uint64(n*m); got != want {
+		t.Errorf("Count: got %d, want %d", got, want)
+	}
+}
+
+func BenchmarkSlidingWindowCounterInc(b *testing.B) {
+	c := NewSlidingWindowCounter(time.Minute)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc()
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+// WithObserver[T] is a type that can be embedded inside a component
+// implementation struct to intercept the component's method calls. T is an
+// observer type with methods that mirror the methods of the component
+// interface, but that take an extra next parameter, of the component
+// interface type, right after the context. For example, consider a Cache
+// component:
+//
+//	type Cache interface {
+//	    Get(ctx context.Context, key string) (string, error)
+//	    Put(ctx context.Context, key, value string) error
+//	}
+//
+// The following observer rejects unauthenticated calls to Put:
+//
+//	type cacheObserver struct{}
+//
+//	func (*cacheObserver) Put(ctx context.Context, next Cache, key, value string) error {
+//	    if !authenticated(ctx) {
+//	        return errUnauthenticated
+//	    }
+//	    return next.Put(ctx, key, value)
+//	}
+//
+//	type cache struct {
+//	    weaver.Implements[Cache]
+//	    weaver.WithObserver[cacheObserver]
+//	    ...
+//	}
+//
+// When a method call arrives at a component, Service Weaver invokes the
+// observer's method with the same name as the called method, passing the
+// component implementation as next. The observer decides whether to call next
+// and with what arguments, or to short-circuit the call and return results of
+// its own. Methods without a corresponding observer method are invoked
+// directly. A method of *T with the same name as a component method must have
+// the signature described above; "weaver generate" reports an error if it
+// doesn't.
+//
+// Observers intercept both local and remote method calls. Every component
+// instance has its own observer, starting from the zero value of T. An
+// observer's methods may be invoked concurrently.
+type WithObserver[T any] struct {
+	observer T
+}
+
+// Observer returns the observer of the component that embeds this
+// [weaver.WithObserver].
+func (wo *WithObserver[T]) Observer() *T {
+	return &wo.observer
+}
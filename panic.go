@@ -0,0 +1,50 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// recoverPanic, when deferred by the handler of a remote call to the provided
+// method of component c, recovers from a panic in the method or in the
+// encoding of its results. It records the panic, logs its stack, and makes the
+// handler return an error wrapping ErrPanic in place of the reply, so that a
+// single bad call can't take down the process and every other component it
+// hosts. In single machine deployments, the error includes the stack.
+//
+// recoverPanic lets the panic propagate if the component's config sets
+// crash_on_panic. Local calls never go through a handler, so their panics
+// always propagate to the caller.
+func (w *weavelet) recoverPanic(c *component, method string, err *error) {
+	if c.crashOnPanic {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	codegen.MethodPanics.Get(codegen.ComponentMethodLabels{Component: c.info.Name, Method: method}).Inc()
+	c.logger.Error("Remote call panicked", "method", method, "panic", r, "stack", string(stack))
+	if w.info.SingleMachine {
+		*err = fmt.Errorf("component %s: method %s: %w: %v\n\n%s", c.info.Name, method, ErrPanic, r, stack)
+	} else {
+		*err = fmt.Errorf("component %s: method %s: %w: %v", c.info.Name, method, ErrPanic, r)
+	}
+}
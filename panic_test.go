@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+	"golang.org/x/exp/slog"
+)
+
+// panicky is a handler that panics.
+func panicky(w *weavelet, c *component) (err error) {
+	defer w.recoverPanic(c, "Panicky", &err)
+	panic("boom")
+}
+
+func TestRecoverPanic(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		singleMachine bool
+	}{
+		{"SingleMachine", true},
+		{"MultiMachine", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			w := &weavelet{info: &protos.EnvelopeInfo{SingleMachine: test.singleMachine}}
+			c := &component{
+				info:   &codegen.Registration{Name: "pkg/Panic" + test.name},
+				logger: slog.New(slog.NewTextHandler(&logs, nil)),
+			}
+			err := panicky(w, c)
+			if !errors.Is(err, ErrPanic) {
+				t.Fatalf("got %v, want %v", err, ErrPanic)
+			}
+			if !strings.Contains(err.Error(), "boom") {
+				t.Errorf("error %q doesn't contain the panic value", err)
+			}
+			// The stack is logged, and only returned to callers in single
+			// machine deployments.
+			if got := strings.Contains(err.Error(), "panicky"); got != test.singleMachine {
+				t.Errorf("error %q has stack: got %t, want %t", err, got, test.singleMachine)
+			}
+			if !strings.Contains(logs.String(), "panicky") {
+				t.Errorf("logs %q don't contain the stack", logs.String())
+			}
+
+			var count float64
+			for _, snap := range metrics.Snapshot() {
+				if snap.Name == codegen.MethodPanics.Name() && snap.Labels["component"] == c.info.Name {
+					count = snap.Value
+				}
+			}
+			if count != 1 {
+				t.Errorf("panic count: got %v, want 1", count)
+			}
+		})
+	}
+}
+
+func TestRecoverPanicCrashOnPanic(t *testing.T) {
+	w := &weavelet{info: &protos.EnvelopeInfo{}}
+	c := &component{info: &codegen.Registration{Name: "pkg/Crash"}, crashOnPanic: true}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recovered %v, want boom", r)
+		}
+	}()
+	err := panicky(w, c)
+	t.Fatalf("panicky returned %v, want panic", err)
+}
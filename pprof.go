@@ -0,0 +1,213 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	httppprof "net/http/pprof"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/profiling"
+)
+
+// profileMethodKey holds the key for the method that collects the profile of
+// a weavelet on behalf of a peer.
+var profileMethodKey = call.MakeMethodKey("", "profile")
+
+// cpuProfileMu serializes the CPU profiles collected by Profile. The Go
+// runtime supports only one CPU profile per process at a time.
+var cpuProfileMu sync.Mutex
+
+// ProfileOptions configures the profile collected by Profile.
+type ProfileOptions struct {
+	// Type is the type of the profile: "cpu", "heap", "goroutine", or
+	// "mutex". Note that mutex profiles are empty unless the program enables
+	// them with runtime.SetMutexProfileFraction.
+	Type string
+
+	// Duration is how long a CPU profile is collected for. It is required for
+	// CPU profiles and ignored by the other types.
+	Duration time.Duration
+
+	// Replica, if not empty, is the address of the single replica to
+	// profile, as reported in ComponentStatus.Replicas. Otherwise, every
+	// replica of the component is profiled.
+	Replica string
+}
+
+// validate returns an error if the options are invalid.
+func (o ProfileOptions) validate() error {
+	switch o.Type {
+	case "cpu":
+		if o.Duration <= 0 {
+			return fmt.Errorf("invalid CPU profile duration %v", o.Duration)
+		}
+	case "heap", "goroutine", "mutex":
+	default:
+		return fmt.Errorf("invalid profile type %q: want cpu, heap, goroutine, or mutex", o.Type)
+	}
+	return nil
+}
+
+// Profile collects a profile of every replica of the component with the
+// provided full name, e.g., "example.com/pkg/Cache", and returns the merged
+// profile in the gzipped protobuf format read by "go tool pprof". For
+// example, to collect a 30 second CPU profile of every replica of Cache:
+//
+//	opts := weaver.ProfileOptions{Type: "cpu", Duration: 30 * time.Second}
+//	prof, err := weaver.Profile(ctx, "example.com/pkg/Cache", opts)
+//
+// The replicas are the ones in the component's ComponentStatus, so Profile
+// can reach the components hosted by the caller's weavelet and the
+// components that the caller references. The replicas are profiled
+// concurrently. If some replicas fail, Profile returns the merged profile of
+// the others along with an error describing the failures.
+func Profile(ctx context.Context, component string, opts ProfileOptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	w, err := weaveletFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := w.getComponent(component)
+	if err != nil {
+		return nil, err
+	}
+	status := w.status().Component(component)
+
+	// Profile the weavelet itself if it is the only known replica.
+	local := func() ([]byte, error) { return collectProfile(ctx, opts.Type, opts.Duration) }
+	if len(status.Replicas) == 0 {
+		if !status.Local {
+			return nil, fmt.Errorf("profile %q: %w: no replicas available", component, ErrUnavailable)
+		}
+		if opts.Replica != "" {
+			return nil, fmt.Errorf("profile %q: unknown replica %q", component, opts.Replica)
+		}
+		return local()
+	}
+
+	enc := codegen.NewEncoder()
+	enc.String(opts.Type)
+	enc.Int64(int64(opts.Duration))
+	args := enc.Data()
+	var groups [][]func() ([]byte, error)
+	for _, addr := range status.Replicas {
+		addr := addr
+		switch {
+		case opts.Replica != "" && addr != opts.Replica:
+			continue
+		case addr == w.dialAddr:
+			groups = append(groups, []func() ([]byte, error){local})
+			continue
+		}
+		remote := func() ([]byte, error) {
+			stub, err := w.getStub(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+			callOpts := call.CallOptions{Balancer: &pinnedBalancer{addr: addr}}
+			prof, err := stub.conn.Call(ctx, profileMethodKey, args, callOpts)
+			if err != nil {
+				return nil, fmt.Errorf("replica %s: %w", addr, err)
+			}
+			return prof, nil
+		}
+		groups = append(groups, []func() ([]byte, error){remote})
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("profile %q: unknown replica %q", component, opts.Replica)
+	}
+	return profiling.ProfileGroups(groups)
+}
+
+// handleProfile is the handler of profileMethodKey.
+func handleProfile(ctx context.Context, args []byte) (prof []byte, err error) {
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+	dec := codegen.NewDecoder(args)
+	typ := dec.String()
+	duration := time.Duration(dec.Int64())
+	return collectProfile(ctx, typ, duration)
+}
+
+// collectProfile returns a profile of the calling process.
+func collectProfile(ctx context.Context, typ string, duration time.Duration) ([]byte, error) {
+	if err := (ProfileOptions{Type: typ, Duration: duration}).validate(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if typ != "cpu" {
+		if err := pprof.Lookup(typ).WriteTo(&buf, 0); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	cpuProfileMu.Lock()
+	defer cpuProfileMu.Unlock()
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		pprof.StopCPUProfile()
+		return buf.Bytes(), nil
+	case <-ctx.Done():
+		pprof.StopCPUProfile()
+		return nil, ctx.Err()
+	}
+}
+
+// servePprof serves the net/http/pprof handlers on the provided address until
+// the weavelet is shut down.
+func (w *weavelet) servePprof(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("pprof listener: %w", err)
+	}
+	w.pprofAddr = lis.Addr().String()
+
+	// Use a dedicated mux, separate from the http.DefaultServeMux used by many
+	// applications.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-w.ctx.Done()
+		server.Close()
+	}()
+	go server.Serve(lis) //nolint:errcheck // returns when the server is closed
+	w.env.SystemLogger().Info("Serving pprof handlers", "address", w.pprofAddr)
+	return nil
+}
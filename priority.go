@@ -0,0 +1,366 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/metrics"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// priorityAgingInterval is how long a call waits in a replica's queue before
+// its priority is raised by one. Aging bounds how long a steady stream of
+// calls of higher priority can hold back a call of lower priority.
+const priorityAgingInterval = time.Second
+
+// The range of call priorities. A replica clamps the priority of every call it
+// receives to this range, so that the number of queues per component, and of
+// metrics labeled with a priority, stays bounded no matter what priorities
+// callers ask for.
+const (
+	minPriority = 0
+	maxPriority = 9
+)
+
+// WithCallPriority returns a copy of ctx that carries the provided call
+// priority. Higher priorities are more urgent, and calls without a priority
+// have priority 0. Priorities range from 0 to 9; a replica treats priorities
+// outside of this range as the nearest bound. For example, an interactive
+// frontend can let its calls jump ahead of the calls made by a batch job to
+// the same component:
+//
+//	ctx = weaver.WithCallPriority(ctx, 1)
+//	results, err := search.Query(ctx, q)
+//
+// The priority is sent along with remote component method calls made using
+// the returned context, and with the calls made by those methods in turn,
+// like metadata (see SetMetadata). A replica that queues the calls it
+// receives (see WithPriority) uses it to order the calls, unless the
+// component embeds WithPriority, in which case the component decides the
+// priority of its calls itself.
+func WithCallPriority(ctx context.Context, priority int) context.Context {
+	return call.WithPriority(ctx, priority)
+}
+
+// CallPriority returns the call priority carried by ctx, and whether ctx
+// carries a priority at all. See WithCallPriority.
+func CallPriority(ctx context.Context) (int, bool) {
+	return call.Priority(ctx)
+}
+
+// WithPriority[T] is a type that can be embedded inside a component
+// implementation struct to execute the remote calls that the component
+// receives in priority order when a replica of the component is saturated. T
+// is a type with a method that returns the priority of a call, given the
+// call's context:
+//
+//	func (T) Priority(ctx context.Context) int
+//
+// Higher priorities are more urgent. Priorities range from 0 to 9, like the
+// priorities of WithCallPriority. For example, the following search
+// component serves interactive queries before batch queries:
+//
+//	type searchPriority struct{}
+//
+//	func (searchPriority) Priority(ctx context.Context) int {
+//	    if weaver.Metadata(ctx)["batch"] == "true" {
+//	        return 0
+//	    }
+//	    return 1
+//	}
+//
+//	type search struct {
+//	    weaver.Implements[Search]
+//	    weaver.WithPriority[searchPriority]
+//	}
+//
+// Priority is called by the replica that executes the call, on a zero value of
+// T, with a context that carries the call's metadata, caller (see
+// CallerIdentity), and the priority requested by the caller, if any (see
+// CallPriority). A component that embeds WithPriority thus has the last word
+// on the priority of its calls. The calls to a component that doesn't embed
+// WithPriority have the priority requested by their caller (see
+// WithCallPriority), or 0.
+//
+// Calls only wait if the component's config section limits the number of
+// calls a replica executes at once:
+//
+//	["github.com/example/search/Search"]
+//	max_concurrent_calls = 64
+//
+// A replica that is executing that many calls queues the calls it receives
+// in separate queues per priority. When a call finishes, the oldest queued
+// call of the highest priority starts. To keep calls of low priority from
+// waiting forever, the priority of a queued call is raised by one for every
+// second it waits. Local calls are never queued. The
+// serviceweaver_component_queued_calls gauge records the number of queued
+// calls to a component, by priority.
+//
+// The queues are unbounded unless the section also sets max_queued_calls.
+// Calls received while the queues are full fail with ErrServerBusy, as do all
+// the calls received while the replica is at its limit if the section sets
+// busy_policy = "reject".
+type WithPriority[T any] struct{}
+
+// prioritizerType returns T. See callPrioritizer.
+func (WithPriority[T]) prioritizerType() reflect.Type { return reflection.Type[T]() }
+
+// callPrioritizer returns a function that calls the Priority method of the
+// prioritizer of the component implementation type impl (see WithPriority),
+// or nil if impl doesn't embed WithPriority.
+func callPrioritizer(impl reflect.Type) (func(context.Context) int, error) {
+	prioritized, ok := reflect.New(impl).Interface().(interface{ prioritizerType() reflect.Type })
+	if !ok {
+		return nil, nil
+	}
+	t := prioritized.prioritizerType()
+	p, ok := reflect.New(t).Interface().(interface{ Priority(context.Context) int })
+	if !ok {
+		return nil, fmt.Errorf("weaver.WithPriority argument %v has no method Priority(context.Context) int", t)
+	}
+	return p.Priority, nil
+}
+
+// schedule waits until a remote call to the provided method of component c,
+// with the provided context, may start. It returns the priority of the call
+// and a function to call when the call finishes.
+func (c *component) schedule(ctx context.Context, method string) (int, func(), error) {
+	priority := 0
+	if c.prioritize != nil {
+		priority = c.prioritize(ctx)
+	} else if p, ok := call.Priority(ctx); ok {
+		priority = p
+	}
+	priority = clampPriority(priority)
+	if c.scheduler == nil {
+		return priority, func() {}, nil
+	}
+	if err := c.scheduler.acquire(ctx, priority); errors.Is(err, ErrServerBusy) {
+		return 0, nil, fmt.Errorf("component %s: method %s: %w", c.info.Name, method, err)
+	} else if err != nil {
+		return 0, nil, fmt.Errorf("component %s: method %s: caller gave up while the call was queued: %w", c.info.Name, method, err)
+	}
+	return priority, c.scheduler.release, nil
+}
+
+// clampPriority returns the provided priority, clamped to the range of call
+// priorities.
+func clampPriority(priority int) int {
+	if priority < minPriority {
+		return minPriority
+	}
+	if priority > maxPriority {
+		return maxPriority
+	}
+	return priority
+}
+
+// scheduler limits the number of calls that a replica of a component executes
+// at once. Calls over the limit wait in a FIFO queue per priority, and every
+// call that finishes hands its slot to the waiting call with the highest aged
+// priority (see agedPriority). Calls that can't wait, because the queue is
+// full or the component rejects calls when busy, fail with ErrServerBusy.
+type scheduler struct {
+	component string           // full component name, for metrics
+	limit     int              // maximum number of calls executing at once
+	reject    bool             // reject calls over the limit, rather than queue them
+	maxQueued int              // maximum number of waiting calls, or 0 if unbounded
+	aging     time.Duration    // see priorityAgingInterval
+	now       func() time.Time // returns the current time
+	rejected  *metrics.Counter // number of calls failed with ErrServerBusy
+
+	mu      sync.Mutex
+	running int     // number of calls executing
+	queued  int     // number of waiting calls
+	tiers   []*tier // tiers with waiting calls, by decreasing priority
+}
+
+// tier holds the waiting calls of a given priority.
+type tier struct {
+	priority int
+	waiters  list.List      // of *waiter, oldest first
+	queued   *metrics.Gauge // number of waiters
+}
+
+// waiter is a call waiting to start.
+type waiter struct {
+	ready  chan struct{} // closed when the call may start
+	queued time.Time     // when the call was queued
+}
+
+// newScheduler returns a scheduler that executes up to limit calls to the
+// provided component at once, and handles the calls over the limit as
+// dictated by queue.
+func newScheduler(component string, limit int, queue runtime.CallQueue) *scheduler {
+	labels := codegen.ComponentLabels{Component: component}
+	codegen.ComponentPoolSize.Get(labels).Set(float64(limit))
+	return &scheduler{
+		component: component,
+		limit:     limit,
+		reject:    queue.Policy == runtime.BusyReject,
+		maxQueued: queue.MaxQueued,
+		aging:     priorityAgingInterval,
+		now:       time.Now,
+		rejected:  codegen.ComponentBusyRejections.Get(labels),
+	}
+}
+
+// acquire waits until a call with the provided priority may start, or until
+// ctx is done. It returns ErrServerBusy right away if the call can't start
+// and can't wait either. If acquire returns nil, the caller must call release
+// when the call finishes.
+func (s *scheduler) acquire(ctx context.Context, priority int) error {
+	s.mu.Lock()
+	if s.running < s.limit && len(s.tiers) == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	if s.reject || (s.maxQueued > 0 && s.queued >= s.maxQueued) {
+		s.mu.Unlock()
+		s.rejected.Inc()
+		return ErrServerBusy
+	}
+	t := s.tier(priority)
+	w := &waiter{ready: make(chan struct{}), queued: s.now()}
+	e := t.waiters.PushBack(w)
+	t.queued.Add(1)
+	s.queued++
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// The call was handed a slot right as ctx was done. Pass it on.
+			s.releaseLocked()
+		default:
+			t.waiters.Remove(e)
+			t.queued.Sub(1)
+			s.queued--
+			if t.waiters.Len() == 0 {
+				s.removeTier(t)
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// release marks a call that started after acquire returned nil as finished.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+// REQUIRES: s.mu is held.
+func (s *scheduler) releaseLocked() {
+	if len(s.tiers) == 0 {
+		s.running--
+		return
+	}
+	t := s.next()
+	w := t.waiters.Remove(t.waiters.Front()).(*waiter)
+	t.queued.Sub(1)
+	s.queued--
+	if t.waiters.Len() == 0 {
+		s.removeTier(t)
+	}
+	close(w.ready)
+}
+
+// next returns the tier whose oldest waiting call should start next, i.e., the
+// call with the highest aged priority. Ties go to the call that has waited
+// the longest.
+//
+// REQUIRES: s.mu is held, and s.tiers is not empty.
+func (s *scheduler) next() *tier {
+	now := s.now()
+	var best *tier
+	var bestPriority int
+	var bestQueued time.Time
+	for _, t := range s.tiers {
+		w := t.waiters.Front().Value.(*waiter)
+		p := agedPriority(t.priority, now.Sub(w.queued), s.aging)
+		if best == nil || p > bestPriority || (p == bestPriority && w.queued.Before(bestQueued)) {
+			best, bestPriority, bestQueued = t, p, w.queued
+		}
+	}
+	return best
+}
+
+// agedPriority returns the provided priority of a call that has waited for
+// the provided duration, raised by one for every aging interval it has waited.
+func agedPriority(priority int, waited, aging time.Duration) int {
+	if aging <= 0 || waited < aging {
+		return priority
+	}
+	boost := int(waited / aging)
+	if priority > math.MaxInt-boost {
+		return math.MaxInt
+	}
+	return priority + boost
+}
+
+// waiting returns the number of waiting calls.
+func (s *scheduler) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued
+}
+
+// tier returns the tier of the provided priority, adding it if needed.
+//
+// REQUIRES: s.mu is held.
+func (s *scheduler) tier(priority int) *tier {
+	i := sort.Search(len(s.tiers), func(i int) bool { return s.tiers[i].priority <= priority })
+	if i < len(s.tiers) && s.tiers[i].priority == priority {
+		return s.tiers[i]
+	}
+	labels := codegen.ComponentPriorityLabels{Component: s.component, Priority: priority}
+	t := &tier{priority: priority, queued: codegen.ComponentQueuedCalls.Get(labels)}
+	s.tiers = append(s.tiers, nil)
+	copy(s.tiers[i+1:], s.tiers[i:])
+	s.tiers[i] = t
+	return t
+}
+
+// removeTier removes the provided empty tier.
+//
+// REQUIRES: s.mu is held.
+func (s *scheduler) removeTier(t *tier) {
+	for i, u := range s.tiers {
+		if u == t {
+			s.tiers = append(s.tiers[:i], s.tiers[i+1:]...)
+			return
+		}
+	}
+}
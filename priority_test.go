@@ -0,0 +1,388 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+)
+
+// tierPriority reads the priority of a call from its "tier" metadata.
+type tierPriority struct{}
+
+func (tierPriority) Priority(ctx context.Context) int {
+	p, _ := strconv.Atoi(Metadata(ctx)["tier"])
+	return p
+}
+
+// badPriority has a Priority method with the wrong signature.
+type badPriority struct{}
+
+func (badPriority) Priority() int { return 0 }
+
+type tierPrioritized struct {
+	WithPriority[tierPriority]
+}
+
+type badPrioritized struct {
+	WithPriority[badPriority]
+}
+
+func TestCallPrioritizer(t *testing.T) {
+	prioritize, err := callPrioritizer(reflection.Type[tierPrioritized]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := SetMetadata(context.Background(), "tier", "3")
+	if got, want := prioritize(ctx), 3; got != want {
+		t.Errorf("Priority: got %d, want %d", got, want)
+	}
+
+	if p, err := callPrioritizer(reflection.Type[struct{}]()); err != nil {
+		t.Error(err)
+	} else if p != nil {
+		t.Error("callPrioritizer: got a prioritizer for a component without one")
+	}
+	if _, err := callPrioritizer(reflection.Type[badPrioritized]()); err == nil {
+		t.Error("callPrioritizer(badPrioritized): unexpected success")
+	}
+}
+
+func TestDispatchMetricsPriority(t *testing.T) {
+	dm := &dispatchMetrics{component: "pkg/C", method: "prioritized"}
+	for _, priority := range []int{0, 2, 2} {
+		m := dm.get("caller", priority)
+		m.EndDispatch(m.BeginDispatch(time.Now()))
+	}
+
+	got := map[string]uint64{}
+	for _, s := range metrics.Snapshot() {
+		if s.Name == codegen.MethodQueueLatencies.Name() && s.Labels["method"] == "prioritized" {
+			for _, count := range s.Counts {
+				got[s.Labels["priority"]] += count
+			}
+		}
+	}
+	if want := map[string]uint64{"0": 1, "2": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queued calls by priority: got %v, want %v", got, want)
+	}
+}
+
+// waitForWaiting waits until s has n waiting calls.
+func waitForWaiting(t *testing.T, s *scheduler, n int) {
+	t.Helper()
+	for start := time.Now(); s.waiting() != n; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("got %d waiting calls, want %d", s.waiting(), n)
+		}
+	}
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	ctx := context.Background()
+	s := newScheduler("", 1, runtime.CallQueue{Policy: runtime.BusyQueue})
+	if err := s.acquire(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queue calls with different priorities, one at a time, so that calls
+	// of the same priority are queued in a known order.
+	started := make(chan string, 5)
+	queue := func(name string, priority int) {
+		go func() {
+			if err := s.acquire(ctx, priority); err != nil {
+				t.Error(err)
+				return
+			}
+			started <- name
+		}()
+	}
+	for i, c := range []struct {
+		name     string
+		priority int
+	}{
+		{"low1", 0},
+		{"high1", 2},
+		{"mid", 1},
+		{"high2", 2},
+		{"low2", 0},
+	} {
+		queue(c.name, c.priority)
+		waitForWaiting(t, s, i+1)
+	}
+
+	// Every release starts the next call.
+	for _, want := range []string{"high1", "high2", "mid", "low1", "low2"} {
+		s.release()
+		if got := <-started; got != want {
+			t.Errorf("started %s, want %s", got, want)
+		}
+	}
+	s.release()
+	if err := s.acquire(ctx, 0); err != nil {
+		t.Fatalf("acquire after all calls finished: %v", err)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := newScheduler("", 1, runtime.CallQueue{Policy: runtime.BusyQueue})
+	if err := s.acquire(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() { errs <- s.acquire(ctx, 1) }()
+	waitForWaiting(t, s, 1)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire: got %v, want context.Canceled", err)
+	}
+	if n := s.waiting(); n != 0 {
+		t.Fatalf("got %d waiting calls after cancellation, want 0", n)
+	}
+
+	// The cancelled call doesn't hold a slot.
+	s.release()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.acquire(ctx, 0); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestSchedulerAging(t *testing.T) {
+	ctx := context.Background()
+	s := newScheduler("pkg/Aging", 1, runtime.CallQueue{Policy: runtime.BusyQueue})
+	now := time.Now()
+	var mu sync.Mutex
+	s.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	if err := s.acquire(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan string, 3)
+	queue := func(name string, priority int, n int) {
+		go func() {
+			if err := s.acquire(ctx, priority); err != nil {
+				t.Error(err)
+				return
+			}
+			started <- name
+		}()
+		waitForWaiting(t, s, n)
+	}
+
+	// low has waited long enough to overtake mid, but not high.
+	queue("low", 0, 1)
+	advance(2500 * time.Millisecond)
+	queue("mid", 1, 2)
+	queue("high", 3, 3)
+	for _, want := range []string{"high", "low", "mid"} {
+		s.release()
+		if got := <-started; got != want {
+			t.Errorf("started %s, want %s", got, want)
+		}
+	}
+}
+
+func TestAgedPriority(t *testing.T) {
+	for _, test := range []struct {
+		priority int
+		waited   time.Duration
+		want     int
+	}{
+		{1, 0, 1},
+		{1, 999 * time.Millisecond, 1},
+		{1, time.Second, 2},
+		{-5, 3500 * time.Millisecond, -2},
+		{math.MaxInt - 1, 10 * time.Second, math.MaxInt},
+	} {
+		if got := agedPriority(test.priority, test.waited, time.Second); got != test.want {
+			t.Errorf("agedPriority(%d, %v): got %d, want %d", test.priority, test.waited, got, test.want)
+		}
+	}
+}
+
+func TestSchedulerQueuedCallsMetric(t *testing.T) {
+	ctx := context.Background()
+	s := newScheduler("pkg/Queued", 1, runtime.CallQueue{Policy: runtime.BusyQueue})
+	if err := s.acquire(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i, priority := range []int{0, 2, 2} {
+		go s.acquire(ctx, priority)
+		waitForWaiting(t, s, i+1)
+	}
+
+	queued := func() map[string]float64 {
+		got := map[string]float64{}
+		for _, snap := range metrics.Snapshot() {
+			if snap.Name == codegen.ComponentQueuedCalls.Name() && snap.Labels["component"] == "pkg/Queued" {
+				got[snap.Labels["priority"]] = snap.Value
+			}
+		}
+		return got
+	}
+	if got, want := queued(), map[string]float64{"0": 1, "2": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queued calls: got %v, want %v", got, want)
+	}
+	for i := 0; i < 3; i++ {
+		s.release()
+	}
+	waitForWaiting(t, s, 0)
+	if got, want := queued(), map[string]float64{"0": 0, "2": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queued calls after release: got %v, want %v", got, want)
+	}
+}
+
+func TestSchedulerBusy(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name   string
+		queue  runtime.CallQueue
+		queued int // calls that wait before calls are rejected
+	}{
+		{"Reject", runtime.CallQueue{Policy: runtime.BusyReject}, 0},
+		{"BoundedQueue", runtime.CallQueue{Policy: runtime.BusyQueue, MaxQueued: 2}, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScheduler("pkg/Busy"+test.name, 1, test.queue)
+			if err := s.acquire(ctx, 0); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < test.queued; i++ {
+				go s.acquire(ctx, 0)
+				waitForWaiting(t, s, i+1)
+			}
+			if err := s.acquire(ctx, 0); !errors.Is(err, ErrServerBusy) {
+				t.Fatalf("acquire: got %v, want %v", err, ErrServerBusy)
+			}
+
+			// Once a waiting call starts, there's room in the queue again.
+			if test.queued > 0 {
+				s.release()
+				waitForWaiting(t, s, test.queued-1)
+				acquired := make(chan error, 1)
+				go func() { acquired <- s.acquire(ctx, 0) }()
+				waitForWaiting(t, s, test.queued)
+				for i := 0; i < test.queued; i++ {
+					s.release()
+				}
+				if err := <-acquired; err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulerMetrics(t *testing.T) {
+	s := newScheduler("pkg/Pool", 3, runtime.CallQueue{Policy: runtime.BusyReject})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := s.acquire(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.acquire(ctx, 0)
+	s.acquire(ctx, 0)
+
+	got := map[string]float64{}
+	for _, snap := range metrics.Snapshot() {
+		if snap.Labels["component"] == "pkg/Pool" {
+			got[snap.Name] = snap.Value
+		}
+	}
+	want := map[string]float64{
+		codegen.ComponentPoolSize.Name():       3,
+		codegen.ComponentBusyRejections.Name(): 2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metrics: got %v, want %v", got, want)
+	}
+}
+
+func TestScheduleCallPriority(t *testing.T) {
+	ctx := WithCallPriority(context.Background(), 2)
+	if got, ok := CallPriority(ctx); !ok || got != 2 {
+		t.Fatalf("CallPriority: got %d, %t, want 2, true", got, ok)
+	}
+
+	// Without a prioritizer, the caller's priority is used.
+	c := &component{}
+	priority, release, err := c.schedule(ctx, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if priority != 2 {
+		t.Errorf("priority without a prioritizer: got %d, want 2", priority)
+	}
+
+	// A prioritizer overrides the caller's priority.
+	c.prioritize, err = callPrioritizer(reflection.Type[tierPrioritized]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	priority, release, err = c.schedule(SetMetadata(ctx, "tier", "5"), "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if priority != 5 {
+		t.Errorf("priority with a prioritizer: got %d, want 5", priority)
+	}
+}
+
+func TestScheduleClampsPriority(t *testing.T) {
+	c := &component{}
+	for _, test := range []struct{ requested, want int }{
+		{-3, minPriority},
+		{minPriority, minPriority},
+		{4, 4},
+		{maxPriority, maxPriority},
+		{1 << 40, maxPriority},
+	} {
+		priority, release, err := c.schedule(WithCallPriority(context.Background(), test.requested), "m")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		if priority != test.want {
+			t.Errorf("schedule with priority %d: got %d, want %d", test.requested, priority, test.want)
+		}
+	}
+}
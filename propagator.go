@@ -0,0 +1,206 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+)
+
+// Propagator[T] marshals and unmarshals the values of type T that contexts
+// carry across remote component method calls. See RegisterPropagator.
+type Propagator[T any] interface {
+	// Marshal encodes a value.
+	Marshal(value T) ([]byte, error)
+
+	// Unmarshal decodes a value encoded by Marshal.
+	Unmarshal(data []byte) (T, error)
+}
+
+// ContextKey[T] is a typed key of the values of type T carried by contexts.
+// Unlike the values stored with context.WithValue, the values stored with a
+// ContextKey are propagated along with component method calls, local and
+// remote. A ContextKey is created by RegisterPropagator.
+type ContextKey[T any] struct {
+	name string
+	p    Propagator[T]
+}
+
+// propagatedKeyPrefix prefixes the metadata keys of the values carried by
+// remote calls. See ContextKey.
+const propagatedKeyPrefix = "weaver-value-"
+
+var (
+	propagatorsMu sync.RWMutex
+	propagators   = map[string]func([]byte) (any, error){} // by key name
+)
+
+// RegisterPropagator registers a propagator of the context values with the
+// provided name and returns the key of the values. For example:
+//
+//	var principalKey = weaver.RegisterPropagator[Principal]("principal", principalPropagator{})
+//
+//	// In the caller.
+//	ctx = principalKey.With(ctx, Principal{User: "alice"})
+//	err := server.Handle(ctx)
+//
+//	// In the callee, even if it runs in another process.
+//	p, ok := principalKey.Value(ctx)
+//
+// Every process of an application must register the same propagators, so
+// RegisterPropagator is typically called to initialize a package-level
+// variable. A process that receives a value with a name it doesn't know
+// forwards the value, unchanged, along with the calls it makes. It panics if
+// name is empty or a propagator with the provided name is already registered.
+//
+// Values travel in the metadata of remote calls (see SetMetadata), under keys
+// with a reserved prefix, and count towards the size limit of the metadata.
+func RegisterPropagator[T any](name string, p Propagator[T]) *ContextKey[T] {
+	if name == "" {
+		panic("weaver.RegisterPropagator: empty name")
+	}
+	propagatorsMu.Lock()
+	defer propagatorsMu.Unlock()
+	if _, ok := propagators[name]; ok {
+		panic(fmt.Sprintf("weaver.RegisterPropagator: name %q already registered", name))
+	}
+	propagators[name] = func(data []byte) (any, error) { return p.Unmarshal(data) }
+	return &ContextKey[T]{name: name, p: p}
+}
+
+// propagator returns the unmarshal function of the propagator with the
+// provided name.
+func propagator(name string) (func([]byte) (any, error), bool) {
+	propagatorsMu.RLock()
+	defer propagatorsMu.RUnlock()
+	unmarshal, ok := propagators[name]
+	return unmarshal, ok
+}
+
+// Name returns the name of the key.
+func (k *ContextKey[T]) Name() string {
+	return k.name
+}
+
+// With returns a copy of ctx that carries the provided value under key k,
+// in addition to the values already carried by ctx. A value already stored
+// under k is replaced. The value is marshaled by the key's propagator when a
+// remote call is made with the returned context; if marshaling fails, the
+// call fails without being sent.
+func (k *ContextKey[T]) With(ctx context.Context, value T) context.Context {
+	old := contextValuesFrom(ctx)
+	values := make(contextValues, len(old)+1)
+	for name, v := range old {
+		values[name] = v
+	}
+	values[k.name] = contextValue{
+		value:   value,
+		marshal: func() ([]byte, error) { return k.p.Marshal(value) },
+	}
+	return context.WithValue(ctx, contextValuesKey{}, values)
+}
+
+// Value returns the value carried by ctx under key k, if any.
+func (k *ContextKey[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := contextValuesFrom(ctx)[k.name]
+	value, isT := v.value.(T)
+	return value, ok && isT
+}
+
+// contextValuesKey is the context key of the contextValues of a context.
+type contextValuesKey struct{}
+
+// contextValues holds the values stored in a context with ContextKey.With,
+// keyed by key name. It is never modified once stored in a context.
+type contextValues map[string]contextValue
+
+// contextValue is a value stored in a context with ContextKey.With, or
+// received with a remote call.
+type contextValue struct {
+	value   any                    // the value, or nil if unknown
+	marshal func() ([]byte, error) // marshals value, if data is nil
+	data    []byte                 // the marshaled value, if received
+}
+
+func contextValuesFrom(ctx context.Context) contextValues {
+	values, _ := ctx.Value(contextValuesKey{}).(contextValues)
+	return values
+}
+
+// injectContextValues returns a copy of ctx whose metadata includes the
+// marshaled values stored in ctx with ContextKey.With. It is called right
+// before a remote call is sent.
+func injectContextValues(ctx context.Context) (context.Context, error) {
+	values := contextValuesFrom(ctx)
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	old := call.Metadata(ctx)
+	md := make(map[string]string, len(old)+len(values))
+	for k, v := range old {
+		md[k] = v
+	}
+	for name, v := range values {
+		data := v.data
+		if data == nil {
+			var err error
+			if data, err = v.marshal(); err != nil {
+				return nil, fmt.Errorf("marshal context value %q: %w", name, err)
+			}
+		}
+		md[propagatedKeyPrefix+name] = string(data)
+	}
+	return call.WithMetadata(ctx, md), nil
+}
+
+// extractContextValues returns a copy of ctx that carries the values received
+// in the metadata of a remote call, with their metadata keys removed. It is
+// called right after a remote call is received.
+func extractContextValues(ctx context.Context) (context.Context, error) {
+	md := call.Metadata(ctx)
+	var values contextValues
+	for k := range md {
+		if strings.HasPrefix(k, propagatedKeyPrefix) {
+			values = contextValues{}
+			break
+		}
+	}
+	if values == nil {
+		return ctx, nil
+	}
+	rest := map[string]string{}
+	for k, v := range md {
+		name, ok := strings.CutPrefix(k, propagatedKeyPrefix)
+		if !ok {
+			rest[k] = v
+			continue
+		}
+		data := []byte(v)
+		var value any
+		if unmarshal, ok := propagator(name); ok {
+			var err error
+			if value, err = unmarshal(data); err != nil {
+				return nil, fmt.Errorf("unmarshal context value %q: %w", name, err)
+			}
+		}
+		values[name] = contextValue{value: value, data: data}
+	}
+	ctx = call.WithMetadata(ctx, rest)
+	return context.WithValue(ctx, contextValuesKey{}, values), nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+)
+
+// intPropagator propagates ints. It fails to marshal negative ints.
+type intPropagator struct{}
+
+func (intPropagator) Marshal(x int) ([]byte, error) {
+	if x < 0 {
+		return nil, errors.New("negative")
+	}
+	return []byte(strconv.Itoa(x)), nil
+}
+
+func (intPropagator) Unmarshal(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
+var budgetKey = RegisterPropagator[int]("test-budget", intPropagator{})
+
+// roundTrip returns the context received by the server of a remote call made
+// with ctx.
+func roundTrip(t *testing.T, ctx context.Context) context.Context {
+	t.Helper()
+	ctx, err := injectContextValues(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := call.WithMetadata(context.Background(), call.Metadata(ctx))
+	received, err = extractContextValues(received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return received
+}
+
+func TestContextKeyRoundTrip(t *testing.T) {
+	ctx := SetMetadata(context.Background(), "tenant", "acme")
+	ctx = budgetKey.With(ctx, 1)
+	ctx = budgetKey.With(ctx, 42)
+	if got, ok := budgetKey.Value(ctx); !ok || got != 42 {
+		t.Errorf("local Value: got (%d, %t), want (42, true)", got, ok)
+	}
+
+	received := roundTrip(t, ctx)
+	if got, ok := budgetKey.Value(received); !ok || got != 42 {
+		t.Errorf("remote Value: got (%d, %t), want (42, true)", got, ok)
+	}
+	md := Metadata(received)
+	if len(md) != 1 || md["tenant"] != "acme" {
+		t.Errorf("remote Metadata: got %v, want only tenant=acme", md)
+	}
+
+	// The value keeps propagating along with the calls made by the server.
+	if got, ok := budgetKey.Value(roundTrip(t, received)); !ok || got != 42 {
+		t.Errorf("forwarded Value: got (%d, %t), want (42, true)", got, ok)
+	}
+}
+
+func TestContextKeyUnknownName(t *testing.T) {
+	// A value whose propagator isn't registered in the server is forwarded
+	// unchanged.
+	md := map[string]string{propagatedKeyPrefix + "unknown": "opaque"}
+	ctx, err := extractContextValues(call.WithMetadata(context.Background(), md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Metadata(ctx); len(got) != 0 {
+		t.Errorf("Metadata: got %v, want empty", got)
+	}
+	ctx, err = injectContextValues(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := call.Metadata(ctx)[propagatedKeyPrefix+"unknown"], "opaque"; got != want {
+		t.Errorf("forwarded value: got %q, want %q", got, want)
+	}
+}
+
+func TestContextKeyErrors(t *testing.T) {
+	ctx := budgetKey.With(context.Background(), -1)
+	if _, err := injectContextValues(ctx); err == nil {
+		t.Error("injectContextValues: unexpected success for a value that can't be marshaled")
+	}
+
+	md := map[string]string{propagatedKeyPrefix + budgetKey.Name(): "not a number"}
+	if _, err := extractContextValues(call.WithMetadata(context.Background(), md)); err == nil {
+		t.Error("extractContextValues: unexpected success for a value that can't be unmarshaled")
+	}
+}
+
+func TestRegisterPropagatorTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterPropagator: no panic for a name already registered")
+		}
+	}()
+	RegisterPropagator[int](budgetKey.Name(), intPropagator{})
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// errQuiesced is wrapped by the error of a remote call rejected, without
+// running, by a quiesced component. It wraps ErrUnavailable.
+var errQuiesced = fmt.Errorf("component is quiesced: %w", ErrUnavailable)
+
+// quiescedRetries is the number of times a remote call rejected by a
+// quiesced replica is retried, on the replica picked by the component's
+// balancer, before the rejection is returned to the caller.
+const quiescedRetries = 3
+
+// Quiesce stops the component with the provided full name, hosted by the
+// weavelet running the caller, from executing new remote calls, e.g., to run
+// a schema migration. Remote calls to the component are rejected with an error
+// that wraps ErrUnavailable; callers transparently retry the rejected calls on
+// other replicas of the component, if any. Quiesce then waits for the remote
+// calls that the component is executing to finish, or for ctx to be done, in
+// which case it returns ctx.Err() and the component stays quiesced.
+//
+// Calls from components running in the same process don't go through the
+// network and are not affected. Quiesce has no effect on other replicas of the
+// component. Call Resume to execute remote calls again.
+func Quiesce(ctx context.Context, component string) error {
+	c, err := quiesceTarget(ctx, "weaver.Quiesce", component)
+	if err != nil {
+		return err
+	}
+	return c.quiesce.quiesce(ctx, c.info.Name)
+}
+
+// Resume undoes Quiesce, so that the component with the provided full name,
+// hosted by the weavelet running the caller, executes remote calls again. It is
+// a no-op if the component is not quiesced.
+func Resume(ctx context.Context, component string) error {
+	c, err := quiesceTarget(ctx, "weaver.Resume", component)
+	if err != nil {
+		return err
+	}
+	c.quiesce.resume(c.info.Name)
+	return nil
+}
+
+// quiesceTarget returns the component with the provided name, which must be
+// hosted by the weavelet of ctx.
+func quiesceTarget(ctx context.Context, fn, name string) (*component, error) {
+	w, err := weaveletFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s(%q): %w", fn, name, err)
+	}
+	c, err := w.getComponent(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s(%q): %w", fn, name, err)
+	}
+	if !w.initializedSet()[c] {
+		return nil, fmt.Errorf("%s(%q): component not hosted by this weavelet", fn, name)
+	}
+	return c, nil
+}
+
+// quiesceGate admits the remote calls executed by a component, unless the
+// component is quiesced. The zero value admits every call.
+type quiesceGate struct {
+	mu       sync.Mutex
+	quiesced bool
+	inflight int           // number of admitted calls that are running
+	idle     chan struct{} // closed when quiesced and no call is running
+}
+
+// enter admits a call, unless the component is quiesced, in which case it
+// returns false and the call must be rejected. An admitted call must be
+// followed by a call to exit.
+func (g *quiesceGate) enter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.quiesced {
+		return false
+	}
+	g.inflight++
+	return true
+}
+
+// exit records the end of a call admitted by enter.
+func (g *quiesceGate) exit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inflight--
+	if g.quiesced && g.inflight == 0 {
+		close(g.idle)
+	}
+}
+
+// quiesce stops admitting calls, and waits for the admitted calls to finish
+// or for ctx to be done.
+func (g *quiesceGate) quiesce(ctx context.Context, component string) error {
+	g.mu.Lock()
+	if !g.quiesced {
+		g.quiesced = true
+		g.idle = make(chan struct{})
+		if g.inflight == 0 {
+			close(g.idle)
+		}
+		codegen.ComponentQuiesced.Get(codegen.ComponentLabels{Component: component}).Set(1)
+	}
+	idle := g.idle
+	g.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resume admits calls again.
+func (g *quiesceGate) resume(component string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.quiesced {
+		g.quiesced = false
+		codegen.ComponentQuiesced.Get(codegen.ComponentLabels{Component: component}).Set(0)
+	}
+}
+
+// admitRemoteCall admits a remote call to c, returning the function to call
+// when the call ends, or it rejects the call if c is quiesced.
+func (c *component) admitRemoteCall(method string) (func(), error) {
+	if !c.quiesce.enter() {
+		return nil, fmt.Errorf("component %s: method %s: %w", c.info.Name, method, errQuiesced)
+	}
+	return c.quiesce.exit, nil
+}
+
+// isQuiesced returns whether err is the error of a remote call rejected by a
+// quiesced component.
+func isQuiesced(err error) bool {
+	return errors.Is(err, errQuiesced)
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+func TestQuiesceGate(t *testing.T) {
+	var g quiesceGate
+	if !g.enter() {
+		t.Fatal("enter: rejected before quiesce")
+	}
+
+	// Quiesce waits for the admitted call to exit.
+	done := make(chan error)
+	go func() { done <- g.quiesce(context.Background(), "pkg/C") }()
+	select {
+	case err := <-done:
+		t.Fatalf("quiesce returned %v while a call was running", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if g.enter() {
+		t.Fatal("enter: admitted while quiesced")
+	}
+	g.exit()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	g.resume("pkg/C")
+	if !g.enter() {
+		t.Fatal("enter: rejected after resume")
+	}
+
+	// Quiesce gives up when its context is done, but stays quiesced.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.quiesce(ctx, "pkg/C"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("quiesce: got %v, want context.Canceled", err)
+	}
+	if g.enter() {
+		t.Fatal("enter: admitted while quiesced")
+	}
+	g.exit()
+}
+
+func TestQuiescedError(t *testing.T) {
+	c := &component{info: &codegen.Registration{Name: "pkg/C"}}
+	c.quiesce.quiesce(context.Background(), "pkg/C") //nolint:errcheck // no calls
+	_, err := c.admitRemoteCall("M")
+	if !isQuiesced(err) || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("admitRemoteCall: got %v, want quiesced ErrUnavailable", err)
+	}
+
+	// The error is still recognized after it is sent to the caller.
+	enc := codegen.NewEncoder()
+	enc.Error(err)
+	err = codegen.NewDecoder(enc.Data()).Error()
+	if !isQuiesced(err) || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("decoded error: got %v, want quiesced ErrUnavailable", err)
+	}
+}
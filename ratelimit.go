@@ -0,0 +1,174 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"golang.org/x/exp/slices"
+)
+
+// newRateLimiters returns rate limiters for the methods of the provided
+// component that have a rate limit, as listed under runtime.RateLimitsKey in
+// the component's config section. The events delivered to a subscriber
+// component are limited as calls to its eventMethod. It returns an error if a
+// limit names a method that the component doesn't have.
+func newRateLimiters(info *codegen.Registration, sections map[string]string) (map[string]*rateLimiter, error) {
+	limits, err := runtime.ParseRateLimits(info.Name, sections)
+	if err != nil {
+		return nil, err
+	}
+	var limiters map[string]*rateLimiter
+	for method, limit := range limits {
+		if !slices.Contains(methodNames(info), method) {
+			return nil, fmt.Errorf("section %q: %s: unknown method %q", info.Name, runtime.RateLimitsKey, method)
+		}
+		if limit.QPS == 0 {
+			// The limit is disabled.
+			continue
+		}
+		if limiters == nil {
+			limiters = map[string]*rateLimiter{}
+		}
+		limiters[method] = newRateLimiter(limit)
+	}
+	return limiters, nil
+}
+
+// rateLimit admits a call to the provided method of component c, made by
+// caller, according to the method's rate limit, if any. It returns an error
+// wrapping ErrRateLimited if the call is rejected.
+func (c *component) rateLimit(ctx context.Context, caller, method string, remote bool) error {
+	limiter, ok := c.rateLimiters[method]
+	if !ok {
+		return nil
+	}
+	delayed, err := limiter.acquire(ctx)
+	labels := codegen.MethodLabels{Caller: caller, Component: c.info.Name, Method: method, Remote: remote}
+	if delayed {
+		codegen.MethodRateLimitDelays.Get(labels).Inc()
+	}
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrRateLimited):
+		codegen.MethodRateLimited.Get(labels).Inc()
+		return fmt.Errorf("%s.%s: %v calls per second: %w", c.info.Name, method, limiter.limit.QPS, err)
+	default:
+		return fmt.Errorf("%s.%s: waiting for rate limit: %w", c.info.Name, method, err)
+	}
+}
+
+// withRateLimits wraps handle, a local handle to component c used by
+// requester, so that calls to methods with a rate limit are admitted by the
+// limit. Remote calls are admitted by the weavelet that executes them; see
+// weavelet.addHandlers.
+func withRateLimits(c *component, handle any, requester string) any {
+	if len(c.rateLimiters) == 0 || c.info.InterceptFn == nil {
+		return handle
+	}
+	limit := func(ctx context.Context, call codegen.Call, next codegen.Handler) ([]any, error) {
+		if err := c.rateLimit(ctx, requester, call.Method, false); err != nil {
+			return nil, err
+		}
+		return next(ctx, call.Args)
+	}
+	return c.info.InterceptFn(handle, limit, codegen.Call{Caller: requester, Component: c.info.Name})
+}
+
+// rateLimiter is a token bucket that enforces a method's rate limit. The
+// bucket holds up to limit.Burst tokens and is refilled at limit.QPS tokens
+// per second. Every call takes a token.
+type rateLimiter struct {
+	limit runtime.RateLimit
+
+	mu     sync.Mutex
+	tokens float64   // available tokens; negative if calls are waiting
+	last   time.Time // when tokens was last refilled
+}
+
+func newRateLimiter(limit runtime.RateLimit) *rateLimiter {
+	return &rateLimiter{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// acquire admits a call. If the call is over the limit, acquire fails with
+// ErrRateLimited, unless the limit's mode is runtime.RateLimitWait and a token
+// becomes available before ctx's deadline, in which case acquire waits for it
+// and reports that the call was delayed.
+func (r *rateLimiter) acquire(ctx context.Context) (delayed bool, err error) {
+	var max time.Duration
+	if r.limit.Mode == runtime.RateLimitWait {
+		max = math.MaxInt64
+		if deadline, ok := ctx.Deadline(); ok {
+			max = time.Until(deadline)
+		}
+	}
+	wait, ok := r.reserve(max)
+	if !ok {
+		return false, ErrRateLimited
+	}
+	if wait == 0 {
+		return false, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		r.release()
+		return true, ctx.Err()
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait before
+// it may use it. If the wait would be longer than max, reserve takes nothing
+// and returns false.
+func (r *rateLimiter) reserve(max time.Duration) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.limit.QPS
+	if burst := float64(r.limit.Burst); r.tokens > burst {
+		r.tokens = burst
+	}
+	r.last = now
+	if r.tokens >= 1 {
+		r.tokens--
+		return 0, true
+	}
+	wait := time.Duration((1 - r.tokens) / r.limit.QPS * float64(time.Second))
+	if wait > max {
+		return 0, false
+	}
+	r.tokens--
+	return wait, true
+}
+
+// release returns a token taken by reserve that the caller didn't use.
+func (r *rateLimiter) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if burst := float64(r.limit.Burst); r.tokens+1 <= burst {
+		r.tokens++
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime"
+)
+
+func TestRateLimiterReject(t *testing.T) {
+	r := newRateLimiter(runtime.RateLimit{QPS: 0.001, Burst: 2, Mode: runtime.RateLimitReject})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if delayed, err := r.acquire(ctx); delayed || err != nil {
+			t.Fatalf("acquire %d: got (%t, %v), want (false, nil)", i, delayed, err)
+		}
+	}
+	if _, err := r.acquire(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("acquire: got %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	const qps = 20
+	r := newRateLimiter(runtime.RateLimit{QPS: qps, Burst: 1, Mode: runtime.RateLimitWait})
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		delayed, err := r.acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i > 0; delayed != want {
+			t.Errorf("acquire %d: got delayed %t, want %t", i, delayed, want)
+		}
+	}
+	if d, want := time.Since(start), 2*time.Second/qps; d < want*9/10 {
+		t.Errorf("acquire: took %v, want at least %v", d, want)
+	}
+}
+
+func TestRateLimiterWaitDeadline(t *testing.T) {
+	r := newRateLimiter(runtime.RateLimit{QPS: 0.001, Burst: 1, Mode: runtime.RateLimitWait})
+	if _, err := r.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next token comes long after the deadline, so the call is rejected
+	// right away, rather than waiting for the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := r.acquire(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("acquire: got %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestRateLimiterCancelledWait(t *testing.T) {
+	r := newRateLimiter(runtime.RateLimit{QPS: 10, Burst: 1, Mode: runtime.RateLimitWait})
+	if _, err := r.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cancelled waiter gives its token back.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire: got %v, want %v", err, context.Canceled)
+	}
+	r.mu.Lock()
+	tokens := r.tokens
+	r.mu.Unlock()
+	if tokens < -0.5 {
+		t.Errorf("tokens: got %v, want the cancelled waiter's token back", tokens)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+)
+
+// resolverRefreshInterval is how often the replicas of a component with a
+// custom Resolver are re-resolved, even if the Resolver doesn't signal a
+// change.
+const resolverRefreshInterval = 30 * time.Second
+
+// An Address is the network address of a replica of a component, e.g.,
+// "tcp://10.0.0.7:9000" or "unix:///tmp/replica.sock".
+type Address string
+
+// A Resolver finds the replicas of a remote component. By default, the
+// replicas of a component are those reported by the deployer. To source them
+// from an external system instead, e.g., Consul, etcd, or a service mesh,
+// embed [weaver.WithResolver] in the component implementation:
+//
+//	type cache struct {
+//	    weaver.Implements[Cache]
+//	    weaver.WithResolver[consulResolver]
+//	}
+//
+// Every process that calls the component has its own instance of the
+// Resolver. The process calls Resolve when it first needs the component's
+// replicas, whenever the channel returned by Changed receives a value, and
+// every 30 seconds otherwise, so that a Resolver whose source can't notify
+// it of changes still sees them with a bounded delay. If Resolve fails, the
+// process keeps sending calls to the replicas returned by the last successful
+// call to Resolve, and tries again at the next change or refresh.
+//
+// A Resolver only decides where calls are sent. Whether a component is hosted
+// in the calling process, and how the calls of a routed component are
+// assigned to replicas, is still decided by the deployer. If the deployment
+// uses mutual TLS, calls to the returned addresses use it too.
+type Resolver interface {
+	// Resolve returns the addresses of the replicas of the provided
+	// component, identified by its full name, e.g.,
+	// "github.com/example/app/Cache".
+	Resolve(component string) ([]Address, error)
+
+	// Changed returns a channel that receives a value whenever the result of
+	// Resolve may have changed. It is called once. A Resolver that can't
+	// detect changes may return nil, and relies on periodic refreshes.
+	Changed() <-chan struct{}
+}
+
+// WithResolver[R] is a type that can be embedded inside a component
+// implementation struct to indicate that the replicas of the component should
+// be found using a Resolver of type *R. See [weaver.Resolver] for details.
+//
+// Every process creates a new instance of R, starting from its zero value.
+// *R must implement [weaver.Resolver]; if it doesn't, the application fails to
+// start.
+type WithResolver[R any] struct{}
+
+// newResolver returns a new instance of *R. Like newBalancer, the method has
+// an unusual name so that it is unlikely to be shadowed.
+//
+//nolint:unused
+func (WithResolver[R]) newResolver() (Resolver, error) {
+	var r R
+	if resolver, ok := any(&r).(Resolver); ok {
+		return resolver, nil
+	}
+	return nil, fmt.Errorf("*%v does not implement weaver.Resolver", reflection.Type[R]())
+}
+
+// newResolver returns a new instance of the Resolver of the provided
+// component, or nil if the component does not embed weaver.WithResolver.
+func newResolver(info *codegen.Registration) (Resolver, error) {
+	impl := reflect.New(info.Impl).Interface()
+	r, ok := impl.(interface{ newResolver() (Resolver, error) })
+	if !ok {
+		return nil, nil
+	}
+	return r.newResolver()
+}
+
+// watchResolver feeds the replicas returned by r for component c to rr, until
+// ctx is canceled. The replicas are re-resolved whenever r signals a change,
+// and every refresh interval.
+func watchResolver(ctx context.Context, logger *slog.Logger, c *component, r Resolver, rr *routingResolver, refresh time.Duration) {
+	changed := r.Changed()
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	var last []string
+	for {
+		if addrs, err := resolveAddresses(c, r); err != nil {
+			logger.Error("Resolving replicas failed", "component", c.info.Name, "err", err)
+		} else if last == nil || !slices.Equal(addrs, last) {
+			endpoints, err := parseEndpoints(addrs, c.clientTLS)
+			if err != nil {
+				logger.Error("Resolving replicas failed", "component", c.info.Name, "err", err)
+			} else {
+				logger.Debug("Resolved replicas", "component", c.info.Name, "replicas", addrs)
+				last = addrs
+				rr.update(endpoints)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// resolveAddresses returns the replicas of component c reported by r, in the
+// form expected by parseEndpoints.
+func resolveAddresses(c *component, r Resolver) ([]string, error) {
+	addrs, err := r.Resolve(c.info.Name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(addrs))
+	for i, addr := range addrs {
+		result[i] = string(addr)
+	}
+	return dialAddresses(c, result), nil
+}
+
+// dialAddresses returns the addresses at which the replicas of component c,
+// found outside of the deployer, should be dialed: if calls to c use mutual
+// TLS, the addresses are prefixed with "mtls://", unless they already are.
+func dialAddresses(c *component, addrs []string) []string {
+	if c.clientTLS == nil {
+		return addrs
+	}
+	result := make([]string, len(addrs))
+	for i, addr := range addrs {
+		if !strings.HasPrefix(addr, "mtls://") {
+			addr = "mtls://" + addr
+		}
+		result[i] = addr
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/exp/slog"
+)
+
+// fakeResolver is a Resolver that returns the addresses it is set to.
+type fakeResolver struct {
+	mu      sync.Mutex
+	addrs   []Address
+	err     error
+	calls   int
+	changed chan struct{}
+}
+
+func (r *fakeResolver) Resolve(string) ([]Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return r.addrs, r.err
+}
+
+func (r *fakeResolver) Changed() <-chan struct{} { return r.changed }
+
+// set sets the addresses and error returned by Resolve, and signals the
+// change.
+func (r *fakeResolver) set(addrs []Address, err error) {
+	r.mu.Lock()
+	r.addrs, r.err = addrs, err
+	r.mu.Unlock()
+	r.changed <- struct{}{}
+}
+
+func (r *fakeResolver) numCalls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+type resolvedImpl struct {
+	WithResolver[fakeResolver]
+}
+
+type unresolvedImpl struct{}
+
+type badResolver struct{}
+
+type badResolvedImpl struct {
+	WithResolver[badResolver]
+}
+
+func TestNewResolver(t *testing.T) {
+	for _, test := range []struct {
+		impl    reflect.Type
+		want    Resolver
+		wantErr string
+	}{
+		{reflect.TypeOf(resolvedImpl{}), &fakeResolver{}, ""},
+		{reflect.TypeOf(unresolvedImpl{}), nil, ""},
+		{reflect.TypeOf(badResolvedImpl{}), nil, "does not implement weaver.Resolver"},
+	} {
+		t.Run(test.impl.Name(), func(t *testing.T) {
+			got, err := newResolver(&codegen.Registration{Impl: test.impl})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("newResolver: got error %v, want error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("newResolver: got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// waitForReplicas waits until rr returns a new version with the provided
+// replicas, and returns that version.
+func waitForReplicas(t *testing.T, rr *routingResolver, version *call.Version, want []string) *call.Version {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	endpoints, version, err := rr.Resolve(ctx, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		got[i] = e.Address()
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("replicas (-want +got):\n%s", diff)
+	}
+	return version
+}
+
+func TestWatchResolver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &component{info: &codegen.Registration{Name: "pkg/Cache"}}
+	r := &fakeResolver{
+		addrs:   []Address{"tcp://10.0.0.1:9000"},
+		changed: make(chan struct{}),
+	}
+	rr := newRoutingResolver()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	done := make(chan struct{})
+	go func() {
+		watchResolver(ctx, logger, c, r, rr, time.Hour)
+		close(done)
+	}()
+
+	// The initial replicas are resolved right away.
+	version := waitForReplicas(t, rr, rr.version, []string{"tcp://10.0.0.1:9000"})
+
+	// A change is picked up.
+	r.set([]Address{"tcp://10.0.0.1:9000", "tcp://10.0.0.2:9000"}, nil)
+	version = waitForReplicas(t, rr, version, []string{"tcp://10.0.0.1:9000", "tcp://10.0.0.2:9000"})
+
+	// A failed Resolve, an unchanged set of replicas, or an unparsable address
+	// don't update the replicas.
+	r.set(nil, fmt.Errorf("registry unavailable"))
+	r.set([]Address{"tcp://10.0.0.1:9000", "tcp://10.0.0.2:9000"}, nil)
+	r.set([]Address{"bogus"}, nil)
+	r.set([]Address{"tcp://10.0.0.3:9000"}, nil)
+	waitForReplicas(t, rr, version, []string{"tcp://10.0.0.3:9000"})
+
+	cancel()
+	<-done
+	if got, want := r.numCalls(), 6; got != want {
+		t.Errorf("Resolve calls: got %d, want %d", got, want)
+	}
+}
+
+func TestWatchResolverRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &component{info: &codegen.Registration{Name: "pkg/Cache"}}
+	r := &fakeResolver{addrs: []Address{"tcp://10.0.0.1:9000"}} // nil Changed
+	rr := newRoutingResolver()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	go watchResolver(ctx, logger, c, r, rr, time.Millisecond)
+
+	version := waitForReplicas(t, rr, rr.version, []string{"tcp://10.0.0.1:9000"})
+	r.mu.Lock()
+	r.addrs = []Address{"tcp://10.0.0.2:9000"}
+	r.mu.Unlock()
+	waitForReplicas(t, rr, version, []string{"tcp://10.0.0.2:9000"})
+}
+
+func TestResolveAddressesMTLS(t *testing.T) {
+	c := &component{info: &codegen.Registration{Name: "pkg/Cache"}, clientTLS: &tls.Config{}}
+	r := &fakeResolver{addrs: []Address{"tcp://10.0.0.1:9000", "mtls://tcp://10.0.0.2:9000"}}
+	got, err := resolveAddresses(c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"mtls://tcp://10.0.0.1:9000", "mtls://tcp://10.0.0.2:9000"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("resolveAddresses (-want +got):\n%s", diff)
+	}
+}
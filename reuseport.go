@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"net"
+	goruntime "runtime"
+)
+
+// listenTCP listens on the provided TCP address for the listener with the
+// provided name, applying the provided socket options, and returns the
+// listener along with the options applied to it. If opts.ReusePort is set but
+// the platform doesn't support SO_REUSEPORT, a warning is logged and the
+// socket is created without it. Note that this only happens for the reuseport
+// tag option, since setting reuse_port in the config on such a platform fails
+// at startup.
+func (w *weavelet) listenTCP(name, addr string, opts SocketOptions) (net.Listener, SocketOptions, error) {
+	if opts.ReusePort && setReusePort == nil {
+		w.env.SystemLogger().Warn("SO_REUSEPORT is not supported on this platform; listening without it", "listener", name, "os", goruntime.GOOS)
+		opts.ReusePort = false
+	}
+	l, err := listenWithOptions(w.ctx, addr, opts)
+	if err != nil {
+		return nil, SocketOptions{}, err
+	}
+	return l, opts, nil
+}
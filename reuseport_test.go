@@ -0,0 +1,43 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListenReusePort(t *testing.T) {
+	if setReusePort == nil {
+		t.Skip("SO_REUSEPORT is not supported on this platform")
+	}
+	ctx := context.Background()
+	opts := SocketOptions{ReusePort: true}
+	l1, err := listenWithOptions(ctx, "localhost:0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l1.Close()
+
+	// A second listener can bind to the same port.
+	l2, err := listenWithOptions(ctx, l1.Addr().String(), opts)
+	if err != nil {
+		t.Fatalf("second listen on %v: %v", l1.Addr(), err)
+	}
+	defer l2.Close()
+	if got, want := l2.Addr().String(), l1.Addr().String(); got != want {
+		t.Fatalf("second listener address: got %q, want %q", got, want)
+	}
+}
@@ -28,22 +28,54 @@ import (
 
 // routingBalancer balances requests according to a routing assignment.
 type routingBalancer struct {
-	balancer  call.Balancer // default balancer
-	tlsConfig *tls.Config   // tls config to use; may be nil.
-
-	mu         sync.RWMutex
-	assignment *protos.Assignment
-	index      index
+	balancer  call.Balancer  // default balancer
+	tlsConfig *tls.Config    // tls config to use; may be nil.
+	affinity  *affinityCache // session affinity; nil if disabled
+
+	mu           sync.RWMutex
+	assignment   *protos.Assignment
+	index        index
+	endpointList []call.Endpoint          // endpoints passed to Update
+	endpoints    map[string]call.Endpoint // endpointList, keyed by address
 }
 
-// newRoutingBalancer returns a new routingBalancer.
-func newRoutingBalancer(tlsConfig *tls.Config) *routingBalancer {
-	return &routingBalancer{balancer: call.RoundRobin(), tlsConfig: tlsConfig}
+var _ call.LoadAwareBalancer = &routingBalancer{}
+
+// newRoutingBalancer returns a new routingBalancer that uses the provided
+// balancer for calls that cannot be routed.
+func newRoutingBalancer(tlsConfig *tls.Config, balancer call.Balancer) *routingBalancer {
+	return &routingBalancer{balancer: balancer, tlsConfig: tlsConfig}
 }
 
 // Update implements the call.Balancer interface.
 func (rb *routingBalancer) Update(endpoints []call.Endpoint) {
 	rb.balancer.Update(endpoints)
+	if rb.affinity == nil {
+		return
+	}
+
+	// Update is called before every call with the connection's endpoints,
+	// which change only when the resolver returns new ones, so avoid
+	// re-indexing the same endpoints every time.
+	rb.mu.RLock()
+	same := sameEndpoints(rb.endpointList, endpoints)
+	rb.mu.RUnlock()
+	if same {
+		return
+	}
+	byAddr := make(map[string]call.Endpoint, len(endpoints))
+	for _, endpoint := range endpoints {
+		byAddr[endpoint.Address()] = endpoint
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.endpointList = endpoints
+	rb.endpoints = byAddr
+}
+
+// sameEndpoints returns whether a and b are the same slice.
+func sameEndpoints(a, b []call.Endpoint) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
 }
 
 // update updates the balancer with the provided assignment
@@ -61,12 +93,46 @@ func (rb *routingBalancer) update(assignment *protos.Assignment) {
 
 // Pick implements the call.Balancer interface.
 func (rb *routingBalancer) Pick(opts call.CallOptions) (call.Endpoint, error) {
+	return rb.PickWithLoad(opts, nil)
+}
+
+// PickWithLoad implements the call.LoadAwareBalancer interface.
+func (rb *routingBalancer) PickWithLoad(opts call.CallOptions, pending []int) (call.Endpoint, error) {
 	if opts.ShardKey == 0 {
 		// If the method we're calling is not sharded (which is guaranteed to
 		// be true for nonsharded components), then the shard key is 0.
-		return rb.balancer.Pick(opts)
+		return rb.fallback(opts, pending)
 	}
+	if rb.affinity == nil {
+		return rb.pickAssigned(opts, pending)
+	}
+
+	// Prefer the replica that served the previous calls for the key, as long
+	// as it's still one of the endpoints, even if the assignment has since
+	// moved the key elsewhere. Otherwise, remember the replica picked now.
+	// Note that affinity is best-effort; the remembered replica may not be
+	// the one that ends up serving the call.
+	if addr, ok := rb.affinity.get(opts.ShardKey); ok {
+		rb.mu.RLock()
+		endpoint, ok := rb.endpoints[addr]
+		rb.mu.RUnlock()
+		if ok {
+			rb.affinity.hits.Inc()
+			return endpoint, nil
+		}
+	}
+	rb.affinity.misses.Inc()
+	endpoint, err := rb.pickAssigned(opts, pending)
+	if err != nil {
+		return nil, err
+	}
+	rb.affinity.put(opts.ShardKey, endpoint.Address())
+	return endpoint, nil
+}
 
+// pickAssigned picks an endpoint for a call with a non-zero shard key using
+// the current assignment, if any.
+func (rb *routingBalancer) pickAssigned(opts call.CallOptions, pending []int) (call.Endpoint, error) {
 	// Grab the current assignment. It's possible that the current assignment
 	// changes between when we release the lock and when we pick an endpoint,
 	// but using a slightly stale assignment is okay.
@@ -78,14 +144,14 @@ func (rb *routingBalancer) Pick(opts call.CallOptions) (call.Endpoint, error) {
 	if assignment == nil {
 		// There is no assignment. This is possible if we haven't received an
 		// assignment from the assigner yet.
-		return rb.balancer.Pick(opts)
+		return rb.fallback(opts, pending)
 	}
 
 	slice, ok := index.find(opts.ShardKey)
 	if !ok {
 		// TODO(mwhittaker): Shouldn't this be impossible. Understand better
 		// when this happens.
-		return rb.balancer.Pick(opts)
+		return rb.fallback(opts, pending)
 	}
 
 	// TODO(mwhittaker): Double check that the endpoint in the slice is one of
@@ -101,6 +167,14 @@ func (rb *routingBalancer) Pick(opts call.CallOptions) (call.Endpoint, error) {
 	return endpoints[0], nil
 }
 
+// fallback picks an endpoint using the default balancer.
+func (rb *routingBalancer) fallback(opts call.CallOptions, pending []int) (call.Endpoint, error) {
+	if lb, ok := rb.balancer.(call.LoadAwareBalancer); ok && pending != nil {
+		return lb.PickWithLoad(opts, pending)
+	}
+	return rb.balancer.Pick(opts)
+}
+
 // routingResolver is a dummy resolver that returns whatever endpoints are
 // passed to the update method.
 type routingResolver struct {
@@ -131,6 +205,13 @@ func (rr *routingResolver) update(endpoints []call.Endpoint) {
 	rr.changed.Broadcast()
 }
 
+// snapshot returns the resolver's current endpoints.
+func (rr *routingResolver) snapshot() []call.Endpoint {
+	rr.m.Lock()
+	defer rr.m.Unlock()
+	return rr.endpoints
+}
+
 // Resolve implements the call.Resolver interface.
 func (rr *routingResolver) Resolve(ctx context.Context, version *call.Version) ([]call.Endpoint, *call.Version, error) {
 	rr.m.Lock()
@@ -0,0 +1,121 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// RoutingKeyMetadataKey is the metadata key under which calls made through a
+// handle returned by Ref.ForKey carry the handle's key.
+const RoutingKeyMetadataKey = "weaver-routing-key"
+
+// hashRoutingKey returns the shard key of the provided routing key. It hashes
+// a key the same way the code generated for a router method that returns the
+// key does, and panics if the key's type can't be returned by a router method.
+func hashRoutingKey(key any) uint64 {
+	var h codegen.Hasher
+	if err := writeRoutingKey(&h, reflect.ValueOf(key)); err != nil {
+		panic(fmt.Errorf("weaver: routing key %v: %w", key, err))
+	}
+	return h.Sum64()
+}
+
+// shardKey returns the shard key of the provided routing key. The key is
+// hashed by the Hash method of c's router, if the router has one for keys of
+// the key's type, or by hashRoutingKey otherwise.
+func (c *component) shardKey(key any) uint64 {
+	if c.routerHash != nil {
+		if h, ok := c.routerHash(key); ok {
+			return h
+		}
+	}
+	return hashRoutingKey(key)
+}
+
+// routerHash returns a function that calls the Hash method of the router of
+// the component implementation type impl (see WithRouter), or nil if impl has
+// no router or its router has no Hash method. The returned function reports
+// false for keys that Hash doesn't take.
+func routerHash(impl reflect.Type) func(key any) (uint64, bool) {
+	routed, ok := reflect.New(impl).Interface().(interface{ routerType() reflect.Type })
+	if !ok {
+		return nil
+	}
+	router := routed.routerType()
+	m, ok := reflect.PointerTo(router).MethodByName("Hash")
+	if !ok || m.Type.NumIn() != 2 || m.Type.NumOut() != 1 || m.Type.Out(0).Kind() != reflect.Uint64 {
+		return nil
+	}
+	keyType := m.Type.In(1)
+	return func(key any) (uint64, bool) {
+		v := reflect.ValueOf(key)
+		if !v.IsValid() || v.Type() != keyType {
+			return 0, false
+		}
+		out := m.Func.Call([]reflect.Value{reflect.New(router), v})
+		return out[0].Uint(), true
+	}
+}
+
+// writeRoutingKey writes v to h.
+func writeRoutingKey(h *codegen.Hasher, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Int:
+		h.WriteInt(int(v.Int()))
+	case reflect.Int8:
+		h.WriteInt8(int8(v.Int()))
+	case reflect.Int16:
+		h.WriteInt16(int16(v.Int()))
+	case reflect.Int32:
+		h.WriteInt32(int32(v.Int()))
+	case reflect.Int64:
+		h.WriteInt64(v.Int())
+	case reflect.Uint:
+		h.WriteUint(uint(v.Uint()))
+	case reflect.Uint8:
+		h.WriteUint8(uint8(v.Uint()))
+	case reflect.Uint16:
+		h.WriteUint16(uint16(v.Uint()))
+	case reflect.Uint32:
+		h.WriteUint32(uint32(v.Uint()))
+	case reflect.Uint64:
+		h.WriteUint64(v.Uint())
+	case reflect.Float32:
+		h.WriteFloat32(float32(v.Float()))
+	case reflect.Float64:
+		h.WriteFloat64(v.Float())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if f.Kind() == reflect.Struct {
+				return fmt.Errorf("nested struct field %s", v.Type().Field(i).Name)
+			}
+			if err := writeRoutingKey(h, f); err != nil {
+				return err
+			}
+		}
+	case reflect.Invalid:
+		return fmt.Errorf("nil key")
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/net/call"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+)
+
+func TestHashRoutingKey(t *testing.T) {
+	type tenant string
+	type key struct {
+		a int
+		b string
+		c float32
+	}
+	hash := func(write func(h *codegen.Hasher)) uint64 {
+		var h codegen.Hasher
+		write(&h)
+		return h.Sum64()
+	}
+	for _, test := range []struct {
+		key  any
+		want uint64
+	}{
+		{"acme", hash(func(h *codegen.Hasher) { h.WriteString("acme") })},
+		{tenant("acme"), hash(func(h *codegen.Hasher) { h.WriteString("acme") })},
+		{42, hash(func(h *codegen.Hasher) { h.WriteInt(42) })},
+		{uint16(42), hash(func(h *codegen.Hasher) { h.WriteUint16(42) })},
+		{3.5, hash(func(h *codegen.Hasher) { h.WriteFloat64(3.5) })},
+		{key{1, "x", 2.5}, hash(func(h *codegen.Hasher) {
+			h.WriteInt(1)
+			h.WriteString("x")
+			h.WriteFloat32(2.5)
+		})},
+	} {
+		if got := hashRoutingKey(test.key); got != test.want {
+			t.Errorf("hashRoutingKey(%#v): got %d, want %d", test.key, got, test.want)
+		}
+	}
+}
+
+func TestHashRoutingKeyPanics(t *testing.T) {
+	type nested struct{ k struct{ x int } }
+	for _, key := range []any{nil, []byte("x"), map[string]int{}, nested{}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("hashRoutingKey(%#v): unexpected success", key)
+				}
+			}()
+			hashRoutingKey(key)
+		}()
+	}
+}
+
+type tenantShard struct {
+	Tenant string
+	Shard  int
+}
+
+// tenantRouter routes by (tenant, shard), sending odd and even shards to
+// different halves of the hash space.
+type tenantRouter struct{}
+
+func (tenantRouter) Hash(k tenantShard) uint64 {
+	return uint64(k.Shard&1)<<63 | uint64(len(k.Tenant))
+}
+
+type tenantRouted struct{ WithRouter[tenantRouter] }
+
+type plainRouter struct{}
+
+type plainRouted struct{ WithRouter[plainRouter] }
+
+func TestRouterHash(t *testing.T) {
+	if routerHash(reflection.Type[plainRouted]()) != nil {
+		t.Error("routerHash: got a hash for a router without a Hash method")
+	}
+	if routerHash(reflection.Type[struct{}]()) != nil {
+		t.Error("routerHash: got a hash for an unrouted component")
+	}
+
+	c := &component{routerHash: routerHash(reflection.Type[tenantRouted]())}
+	if c.routerHash == nil {
+		t.Fatal("routerHash: got nil for a router with a Hash method")
+	}
+	if got, want := c.shardKey("acme"), hashRoutingKey("acme"); got != want {
+		t.Errorf("shardKey(%q): got %d, want default hash %d", "acme", got, want)
+	}
+
+	// Each replica serves half of the hash space.
+	rb := routingBalancer{}
+	rb.update(&protos.Assignment{
+		Slices: []*protos.Assignment_Slice{
+			{Start: 0, Replicas: []string{"tcp://a"}},
+			{Start: 1 << 63, Replicas: []string{"tcp://b"}},
+		},
+	})
+	pick := func(shardKey uint64) call.Endpoint {
+		t.Helper()
+		e, err := rb.Pick(call.CallOptions{ShardKey: shardKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	// Find two shards of a tenant that the default hash sends to the same
+	// replica, but that tenantRouter sends to different replicas.
+	k1 := tenantShard{"acme", 0}
+	var k2 tenantShard
+	for shard := 1; ; shard += 2 {
+		k2 = tenantShard{"acme", shard}
+		if pick(hashRoutingKey(k1)) == pick(hashRoutingKey(k2)) {
+			break
+		}
+	}
+	if pick(c.shardKey(k1)) == pick(c.shardKey(k2)) {
+		t.Errorf("keys %v and %v: both routed to %v, want different replicas", k1, k2, pick(c.shardKey(k1)))
+	}
+}
@@ -11,10 +11,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -38,11 +38,16 @@ func init() {
 		Impl:      reflect.TypeOf(a{}),
 		Listeners: []string{"aLis1", "aLis2", "aLis3"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return a_local_stub{impl: impl.(A), tracer: tracer}
+			return a_intercept(a_local_stub{impl: impl.(A), caller: caller, tracer: tracer}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return a_intercept(a_client_stub{stub: stub}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A", Remote: true})
 		},
-		ClientStubFn: func(stub codegen.Stub, caller string) any { return a_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return a_server_stub{impl: impl.(A), addLoad: addLoad}
+			return a_server_stub{impl: a_intercept(impl.(A), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return a_intercept(next.(A), interceptor, call)
 		},
 		RefData: "⟦193f6c94:wEaVeReDgE:github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A→github.com/ServiceWeaver/weaver/runtime/bin/testprogram/B⟧\n⟦8cd483a3:wEaVeReDgE:github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A→github.com/ServiceWeaver/weaver/runtime/bin/testprogram/C⟧\n⟦93cd9612:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A→aLis1,aLis2,aLis3⟧\n",
 	})
@@ -52,11 +57,16 @@ func init() {
 		Impl:      reflect.TypeOf(b{}),
 		Listeners: []string{"Listener"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return b_local_stub{impl: impl.(B), tracer: tracer}
+			return b_intercept(b_local_stub{impl: impl.(B), caller: caller, tracer: tracer}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/B"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return b_intercept(b_client_stub{stub: stub}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/B", Remote: true})
 		},
-		ClientStubFn: func(stub codegen.Stub, caller string) any { return b_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return b_server_stub{impl: impl.(B), addLoad: addLoad}
+			return b_server_stub{impl: b_intercept(impl.(B), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/B", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return b_intercept(next.(B), interceptor, call)
 		},
 		RefData: "⟦7551e870:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/runtime/bin/testprogram/B→Listener⟧\n",
 	})
@@ -66,11 +76,16 @@ func init() {
 		Impl:      reflect.TypeOf(c{}),
 		Listeners: []string{"cLis"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return c_local_stub{impl: impl.(C), tracer: tracer}
+			return c_intercept(c_local_stub{impl: impl.(C), caller: caller, tracer: tracer}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/C"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return c_intercept(c_client_stub{stub: stub}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/C", Remote: true})
 		},
-		ClientStubFn: func(stub codegen.Stub, caller string) any { return c_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return c_server_stub{impl: impl.(C), addLoad: addLoad}
+			return c_server_stub{impl: c_intercept(impl.(C), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/runtime/bin/testprogram/C", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return c_intercept(next.(C), interceptor, call)
 		},
 		RefData: "⟦105ddfd4:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/runtime/bin/testprogram/C→cLis⟧\n",
 	})
@@ -80,11 +95,11 @@ func init() {
 		Impl:      reflect.TypeOf(app{}),
 		Listeners: []string{"appLis"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return main_local_stub{impl: impl.(weaver.Main), tracer: tracer}
+			return main_local_stub{impl: impl.(weaver.Main), caller: caller, tracer: tracer}
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any { return main_client_stub{stub: stub} },
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad}
+			return main_server_stub{impl: impl.(weaver.Main), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
 		},
 		RefData: "⟦d90475cb:wEaVeReDgE:github.com/ServiceWeaver/weaver/Main→github.com/ServiceWeaver/weaver/runtime/bin/testprogram/A⟧\n⟦b7bc7e7d:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/Main→appLis⟧\n",
 	})
@@ -106,6 +121,7 @@ var _ weaver.Unrouted = (*app)(nil)
 
 type a_local_stub struct {
 	impl   A
+	caller string
 	tracer trace.Tracer
 }
 
@@ -114,6 +130,7 @@ var _ A = (*a_local_stub)(nil)
 
 type b_local_stub struct {
 	impl   B
+	caller string
 	tracer trace.Tracer
 }
 
@@ -122,6 +139,7 @@ var _ B = (*b_local_stub)(nil)
 
 type c_local_stub struct {
 	impl   C
+	caller string
 	tracer trace.Tracer
 }
 
@@ -130,6 +148,7 @@ var _ C = (*c_local_stub)(nil)
 
 type main_local_stub struct {
 	impl   weaver.Main
+	caller string
 	tracer trace.Tracer
 }
 
@@ -169,8 +188,9 @@ var _ weaver.Main = (*main_client_stub)(nil)
 // Server stub implementations.
 
 type a_server_stub struct {
-	impl    A
-	addLoad func(key uint64, load float64)
+	impl       A
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that a_server_stub implements the codegen.Server interface.
@@ -184,9 +204,15 @@ func (s a_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s a_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 type b_server_stub struct {
-	impl    B
-	addLoad func(key uint64, load float64)
+	impl       B
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that b_server_stub implements the codegen.Server interface.
@@ -200,9 +226,15 @@ func (s b_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s b_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 type c_server_stub struct {
-	impl    C
-	addLoad func(key uint64, load float64)
+	impl       C
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that c_server_stub implements the codegen.Server interface.
@@ -216,9 +248,15 @@ func (s c_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s c_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 type main_server_stub struct {
-	impl    weaver.Main
-	addLoad func(key uint64, load float64)
+	impl       weaver.Main
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that main_server_stub implements the codegen.Server interface.
@@ -231,3 +269,64 @@ func (s main_server_stub) GetStubFn(method string) func(ctx context.Context, arg
 		return nil
 	}
 }
+
+// Use implements the codegen.Server interface.
+func (s main_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+// Intercept stub implementations.
+
+type a_intercept_stub struct {
+	next        A
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that a_intercept_stub implements the A interface.
+var _ A = (*a_intercept_stub)(nil)
+
+// a_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func a_intercept(next A, interceptor codegen.Interceptor, call codegen.Call) A {
+	if interceptor == nil {
+		return next
+	}
+	return a_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+type b_intercept_stub struct {
+	next        B
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that b_intercept_stub implements the B interface.
+var _ B = (*b_intercept_stub)(nil)
+
+// b_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func b_intercept(next B, interceptor codegen.Interceptor, call codegen.Call) B {
+	if interceptor == nil {
+		return next
+	}
+	return b_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+type c_intercept_stub struct {
+	next        C
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that c_intercept_stub implements the C interface.
+var _ C = (*c_intercept_stub)(nil)
+
+// c_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func c_intercept(next C, interceptor codegen.Interceptor, call codegen.Call) C {
+	if interceptor == nil {
+		return next
+	}
+	return c_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import "context"
+
+// CallerInfo describes the caller of a component method.
+type CallerInfo struct {
+	// Component is the full name of the calling component, e.g.,
+	// "github.com/ServiceWeaver/weaver/Main".
+	Component string
+
+	// Identity is the verified identity of the calling weavelet, as presented
+	// in its mTLS certificate. It is empty for local calls and for remote
+	// calls made without mTLS.
+	Identity string
+
+	// Local is true iff the caller runs in the same process as the callee.
+	Local bool
+
+	// Callee is the full name of the component whose method is being
+	// called. It is empty for calls made through code generated by older
+	// versions of "weaver generate".
+	Callee string
+}
+
+// callerInfoKey is the context key for a CallerInfo.
+type callerInfoKey struct{}
+
+// WithCallerInfo returns a copy of ctx that carries the provided caller
+// information. It is called before a component method is invoked.
+func WithCallerInfo(ctx context.Context, info CallerInfo) context.Context {
+	return context.WithValue(ctx, callerInfoKey{}, info)
+}
+
+// WithLocalCaller returns a copy of ctx that records that the component method
+// about to be invoked is being called by the provided local component. The
+// returned context doesn't carry the CallLoad of the caller, if any, so that
+// the load reported by the callee isn't added to the load of the caller.
+func WithLocalCaller(ctx context.Context, caller string) context.Context {
+	return WithCallerInfo(withoutCallLoad(ctx), CallerInfo{Component: caller, Local: true})
+}
+
+// WithLocalCall is like WithLocalCaller, but also records the component
+// whose method is about to be invoked.
+func WithLocalCall(ctx context.Context, caller, callee string) context.Context {
+	return WithCallerInfo(withoutCallLoad(ctx), CallerInfo{Component: caller, Local: true, Callee: callee})
+}
+
+// CallerInfoFromContext returns the caller information stored in ctx, if any.
+func CallerInfoFromContext(ctx context.Context) (CallerInfo, bool) {
+	info, ok := ctx.Value(callerInfoKey{}).(CallerInfo)
+	return info, ok
+}
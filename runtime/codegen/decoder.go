@@ -75,6 +75,14 @@ func (d *Decoder) DecodeBinaryUnmarshaler(value encoding.BinaryUnmarshaler) {
 	}
 }
 
+// DecodeUnmarshaler deserializes the value from a byte slice using its
+// Unmarshal method.
+func (d *Decoder) DecodeUnmarshaler(value interface{ Unmarshal([]byte) error }) {
+	if err := value.Unmarshal(d.Bytes()); err != nil {
+		panic(makeDecodeError("error decoding Unmarshaler %T: %w", value, err))
+	}
+}
+
 // Read reads and returns n bytes from the decoder and advances the decode past
 // the read bytes.
 func (d *Decoder) Read(n int) []byte {
@@ -195,7 +203,9 @@ func (d *Decoder) Bytes() []byte {
 	if n < 0 {
 		panic(makeDecodeError("unable to decode bytes; expected length >= 0 got %d", n))
 	}
-	return d.Read(int(n))
+	// Cap the result so that appending to it can't clobber the rest of the
+	// data being decoded.
+	return d.Read(int(n))[:n:n]
 }
 
 // Len attempts to decode an int32.
@@ -223,7 +233,8 @@ func (d *Decoder) Error() error {
 	for i := 0; i < n; i++ {
 		msg := d.String()
 		f := d.String()
-		err = append(err, decodedErrorEntry{msg, f})
+		val := d.decodeErrorValue()
+		err = append(err, decodedErrorEntry{msg, f, val})
 	}
 	// Note that we intentionally return nil when n==0 so that the deserialization
 	// of a serialized nil error remains nil
@@ -235,6 +246,7 @@ type decodedErrorStack []decodedErrorEntry
 type decodedErrorEntry struct {
 	msg string // Error() result
 	fmt string // Result of fmtError
+	val error  // The reconstructed error, if its type is registered
 }
 
 // Error implements error.Error.
@@ -248,11 +260,41 @@ func (e decodedErrorStack) Unwrap() error {
 	return nil
 }
 
-// Is returns true if either e or an error it wraps has the same type as target.
+// Is returns true if the error at the top of e matches target. A
+// reconstructed error matches target if it is equal to target or its Is
+// method says so. Any error matches target if it had the same type and value
+// as target when it was encoded.
 func (e decodedErrorStack) Is(target error) bool {
+	if val := e[0].val; val != nil {
+		if reflect.TypeOf(val).Comparable() && val == target {
+			return true
+		}
+		if x, ok := val.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return true
+		}
+	}
 	return e[0].fmt == fmtError(target)
 }
 
+// As sets target to the error at the top of e, and returns true, if the error
+// was reconstructed and is assignable to target. See RegisterError.
+func (e decodedErrorStack) As(target any) bool {
+	val := e[0].val
+	if val == nil {
+		return false
+	}
+	if x, ok := val.(interface{ As(any) bool }); ok && x.As(target) {
+		return true
+	}
+	// errors.As checks that target is a non-nil pointer.
+	v := reflect.ValueOf(target).Elem()
+	if !reflect.TypeOf(val).AssignableTo(v.Type()) {
+		return false
+	}
+	v.Set(reflect.ValueOf(val))
+	return true
+}
+
 // fmtError serializes an error value including its type info using fmt.Sprintf.
 func fmtError(v error) string {
 	// Include package and type info explicitly since %#v uses a shortened path.
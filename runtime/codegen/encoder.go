@@ -21,6 +21,9 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/ServiceWeaver/weaver/internal/bufpool"
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -44,8 +47,18 @@ func (e encoderError) Unwrap() error {
 type Encoder struct {
 	data  []byte    // Contains the serialized arguments.
 	space [100]byte // Prellocated buffer to avoid allocations for small size arguments.
+
+	// chunks holds serialized data that precedes data. Byte slices of at
+	// least borrowMinBytes are appended to chunks as is, instead of being
+	// copied into data. See Buffers.
+	chunks [][]byte
+	fields int // number of enclosing BeginField calls
 }
 
+// borrowMinBytes is the length at or above which Bytes stores a reference to
+// its argument instead of copying it.
+const borrowMinBytes = 32 << 10
+
 func NewEncoder() *Encoder {
 	var enc Encoder
 	enc.data = enc.space[:0] // Arrange to use builtin buffer
@@ -58,10 +71,12 @@ func (e *Encoder) Reset(n int) {
 	// TODO(mwhittaker): Have a NewEncoder method that takes in an initial
 	// buffer? Or at least an initial capacity? And then pipe that through
 	// NewCaller.
+	e.chunks = nil
+	e.fields = 0
 	if n <= cap(e.data) {
 		e.data = e.data[:0]
 	} else {
-		e.data = make([]byte, 0, n)
+		e.data = bufpool.Get(n)
 	}
 }
 
@@ -89,9 +104,47 @@ func (e *Encoder) EncodeBinaryMarshaler(value encoding.BinaryMarshaler) {
 	e.Bytes(enc)
 }
 
+// EncodeMarshaler serializes value into a byte slice using its Marshal method.
+// Messages generated by gogoproto and other alternative protobuf compilers
+// have such a method.
+func (e *Encoder) EncodeMarshaler(value interface{ Marshal() ([]byte, error) }) {
+	enc, err := value.Marshal()
+	if err != nil {
+		panic(makeEncodeError("error encoding Marshaler %T: %w", value, err))
+	}
+	e.Bytes(enc)
+}
+
 // Data returns the byte slice that contains the serialized arguments.
 func (e *Encoder) Data() []byte {
-	return e.data
+	if len(e.chunks) == 0 {
+		return e.data
+	}
+	data := bufpool.Get(e.Size())
+	for _, chunk := range e.chunks {
+		data = append(data, chunk...)
+	}
+	return append(data, e.data...)
+}
+
+// Buffers returns the serialized arguments as a sequence of byte slices whose
+// concatenation is equal to Data(). Unlike Data, Buffers never copies, but
+// the returned slices may alias byte slices passed to Bytes, which must not
+// be modified until the returned slices are no longer in use.
+func (e *Encoder) Buffers() [][]byte {
+	if len(e.chunks) == 0 {
+		return [][]byte{e.data}
+	}
+	return append(e.chunks[:len(e.chunks):len(e.chunks)], e.data)
+}
+
+// Size returns the number of bytes serialized so far.
+func (e *Encoder) Size() int {
+	n := len(e.data)
+	for _, chunk := range e.chunks {
+		n += len(chunk)
+	}
+	return n
 }
 
 // Grow increases the size of the encoder's data if needed. Only appends a new
@@ -229,11 +282,29 @@ func (e *Encoder) Bytes(arg []byte) {
 	if n > math.MaxUint32 {
 		panic(makeEncodeError("unable to encode bytes; length doesn't fit in 4 bytes"))
 	}
+	if n >= borrowMinBytes && e.fields == 0 {
+		// Avoid copying large slices. Note that we can't do this inside a
+		// versioned field, because EndField patches the field length at an
+		// offset into e.data.
+		binary.LittleEndian.PutUint32(e.Grow(4), uint32(n))
+		e.chunks = append(e.chunks, e.data, arg)
+		e.data = e.data[len(e.data):]
+		return
+	}
 	data := e.Grow(4 + n)
 	binary.LittleEndian.PutUint32(data, uint32(n))
 	copy(data[4:], arg)
 }
 
+// BytesSize returns the number of bytes that Bytes(arg) copies into the
+// encoder's buffer. Generated code uses BytesSize to size the buffer.
+func BytesSize(arg []byte) int {
+	if len(arg) >= borrowMinBytes {
+		return 4
+	}
+	return 4 + len(arg)
+}
+
 // Len attempts to encode l as an int32.
 //
 // Panics if l is bigger than an int32 or a negative length (except -1).
@@ -250,6 +321,18 @@ func (e *Encoder) Len(l int) {
 	e.Int32(int32(l))
 }
 
+// SortedKeys returns the keys of m in increasing order. Generated code uses
+// SortedKeys to encode maps with ordered keys deterministically, so that equal
+// maps have equal encodings.
+func SortedKeys[K constraints.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 // Error encodes an arg of type error. We save enough type information
 // to allow errors.Unwrap() and errors.Is() to work correctly.
 func (e *Encoder) Error(err error) {
@@ -264,9 +347,6 @@ func (e *Encoder) Error(err error) {
 	for _, err := range stack {
 		e.String(err.Error())
 		e.String(fmtError(err))
-
-		// TODO(sanjay): If a wrapped errors can be serialized using Gob, consider
-		// saving that serialization. This may allow us to implement the As() method
-		// that reconstructs an error value with the original type at the receiver.
+		e.encodeErrorValue(err)
 	}
 }
@@ -239,6 +239,94 @@ func TestErrorValues(t *testing.T) {
 	}
 }
 
+// codeError is an error type registered with RegisterError. Its
+// WeaverMarshal and WeaverUnmarshal methods are written by hand, as they
+// would be generated for a weaver.AutoMarshal struct.
+type codeError struct {
+	Code int
+	Msg  string
+}
+
+var _ AutoMarshal = &codeError{}
+
+func (c codeError) Error() string { return fmt.Sprintf("code %d: %s", c.Code, c.Msg) }
+
+func (c *codeError) WeaverMarshal(enc *Encoder) {
+	enc.Int(c.Code)
+	enc.String(c.Msg)
+}
+
+func (c *codeError) WeaverUnmarshal(dec *Decoder) {
+	c.Code = dec.Int()
+	c.Msg = dec.String()
+}
+
+func init() {
+	RegisterError[codeError]()
+}
+
+func TestRegisteredErrorValues(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+	}{
+		{"value", codeError{404, "not found"}},
+		{"pointer", &codeError{404, "not found"}},
+		{"wrapped-value", fmt.Errorf("get: %w", codeError{404, "not found"})},
+		{"wrapped-pointer", fmt.Errorf("get: %w", fmt.Errorf("lookup: %w", &codeError{404, "not found"}))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc := newEncoder()
+			enc.Error(test.err)
+			dec := Decoder{data: enc.data}
+			got := dec.Error()
+			if !dec.Empty() {
+				t.Fatalf("leftover bytes in decoder")
+			}
+			if got.Error() != test.err.Error() {
+				t.Errorf("message: got %q, want %q", got.Error(), test.err.Error())
+			}
+
+			// errors.As reconstructs the error with its original type.
+			var want codeError
+			if errors.As(test.err, &want) {
+				var ce codeError
+				if !errors.As(got, &ce) {
+					t.Fatalf("errors.As(%v, *codeError): got false, want true", got)
+				}
+				if ce != want {
+					t.Errorf("errors.As: got %v, want %v", ce, want)
+				}
+			} else {
+				var ce *codeError
+				if !errors.As(got, &ce) {
+					t.Fatalf("errors.As(%v, **codeError): got false, want true", got)
+				}
+				if *ce != (codeError{404, "not found"}) {
+					t.Errorf("errors.As: got %v, want %v", *ce, codeError{404, "not found"})
+				}
+			}
+			if errors.As(got, &customTestError{}) {
+				t.Errorf("errors.As(%v, *customTestError): got true, want false", got)
+			}
+		})
+	}
+}
+
+func TestRegisteredErrorIs(t *testing.T) {
+	sentinel := codeError{500, "internal"}
+	enc := newEncoder()
+	enc.Error(fmt.Errorf("call: %w", sentinel))
+	dec := Decoder{data: enc.data}
+	got := dec.Error()
+	if !errors.Is(got, sentinel) {
+		t.Errorf("errors.Is(%v, %v): got false, want true", got, sentinel)
+	}
+	if errors.Is(got, codeError{404, "not found"}) {
+		t.Errorf("errors.Is(%v, %v): got true, want false", got, codeError{404, "not found"})
+	}
+}
+
 // encode serializes args using the encoder enc.
 func encode(enc *Encoder, args []interface{}) {
 	for _, elem := range args {
@@ -344,3 +432,162 @@ func decode(dec *Decoder, out []interface{}) []interface{} {
 	}
 	return results
 }
+
+// marshaler is a type with Marshal and Unmarshal methods.
+type marshaler struct {
+	s   string
+	err error // if not nil, returned by Marshal and Unmarshal
+}
+
+func (m *marshaler) Marshal() ([]byte, error) { return []byte(m.s), m.err }
+
+func (m *marshaler) Unmarshal(data []byte) error {
+	m.s = string(data)
+	return m.err
+}
+
+func TestEncodeDecodeMarshaler(t *testing.T) {
+	enc := NewEncoder()
+	enc.EncodeMarshaler(&marshaler{s: "hello"})
+	enc.String("trailer")
+
+	dec := NewDecoder(enc.Data())
+	var got marshaler
+	dec.DecodeUnmarshaler(&got)
+	if got.s != "hello" {
+		t.Errorf("DecodeUnmarshaler: got %q, want %q", got.s, "hello")
+	}
+	if got, want := dec.String(), "trailer"; got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+
+	// Errors returned by Marshal and Unmarshal turn into panics.
+	want := errors.New("marshal error")
+	err := convertCallPanicToError(func() { NewEncoder().EncodeMarshaler(&marshaler{err: want}) })
+	if !errors.Is(err, want) {
+		t.Errorf("EncodeMarshaler: got error %v, want %v", err, want)
+	}
+	err = convertCallPanicToError(func() { NewDecoder(enc.Data()).DecodeUnmarshaler(&marshaler{err: want}) })
+	if !errors.Is(err, want) {
+		t.Errorf("DecodeUnmarshaler: got error %v, want %v", err, want)
+	}
+}
+
+func TestVersionedFields(t *testing.T) {
+	enc := NewEncoder()
+	enc.Len(3)
+	start := enc.BeginField("a")
+	enc.Int(1)
+	enc.EndField(start)
+	start = enc.BeginField("unknown")
+	enc.String("skipped")
+	enc.Bool(true)
+	enc.EndField(start)
+	start = enc.BeginField("b")
+	enc.String("two")
+	enc.EndField(start)
+
+	dec := NewDecoder(enc.Data())
+	var a int
+	var b string
+	for n := dec.Len(); n > 0; n-- {
+		name, fdec := dec.Field()
+		switch name {
+		case "a":
+			a = fdec.Int()
+		case "b":
+			b = fdec.String()
+		}
+	}
+	if !dec.Empty() {
+		t.Fatal("trailing bytes after decoding")
+	}
+	if a != 1 || b != "two" {
+		t.Fatalf("got (%d, %q), want (1, \"two\")", a, b)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	m := map[string]int{"c": 3, "a": 1, "b": 2}
+	if diff := cmp.Diff([]string{"a", "b", "c"}, SortedKeys(m)); diff != "" {
+		t.Errorf("SortedKeys (-want +got):\n%s", diff)
+	}
+	if got := SortedKeys(map[int]bool{}); len(got) != 0 {
+		t.Errorf("SortedKeys(empty): got %v, want empty", got)
+	}
+}
+
+func TestLargeBytes(t *testing.T) {
+	large := []byte(strings.Repeat("x", borrowMinBytes))
+	enc := NewEncoder()
+	enc.Int(1)
+	enc.Bytes(large)
+	enc.String("after")
+	start := enc.BeginField("f")
+	enc.Bytes(large) // copied, since it's inside a versioned field
+	enc.EndField(start)
+
+	// The large slice outside of the field is not copied.
+	bufs := enc.Buffers()
+	if got, want := len(bufs), 3; got != want {
+		t.Fatalf("Buffers: got %d buffers, want %d", got, want)
+	}
+	if &bufs[1][0] != &large[0] {
+		t.Error("Buffers: large slice was copied")
+	}
+	data := enc.Data()
+	if got, want := len(data), enc.Size(); got != want {
+		t.Errorf("Size: got %d, want %d", got, want)
+	}
+	var joined []byte
+	for _, buf := range bufs {
+		joined = append(joined, buf...)
+	}
+	if !reflect.DeepEqual(joined, data) {
+		t.Error("Buffers and Data differ")
+	}
+
+	dec := NewDecoder(data)
+	if got, want := dec.Int(), 1; got != want {
+		t.Errorf("Int: got %d, want %d", got, want)
+	}
+	b := dec.Bytes()
+	if !reflect.DeepEqual(b, large) {
+		t.Error("Bytes: wrong value")
+	}
+	if got, want := cap(b), len(large); got != want {
+		t.Errorf("Bytes: got capacity %d, want %d", got, want)
+	}
+	if got, want := dec.String(), "after"; got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+	name, fdec := dec.Field()
+	if name != "f" || !reflect.DeepEqual(fdec.Bytes(), large) {
+		t.Errorf("Field: got %q, wrong value", name)
+	}
+	if !dec.Empty() {
+		t.Fatal("trailing bytes after decoding")
+	}
+}
+
+func BenchmarkEncodeBytes(b *testing.B) {
+	arg := make([]byte, 4<<20)
+	for _, bench := range []struct {
+		name   string
+		encode func(*Encoder) int
+	}{
+		{"Data", func(enc *Encoder) int { return len(enc.Data()) }},
+		{"Buffers", func(enc *Encoder) int { return len(enc.Buffers()) }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(arg)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				enc := NewEncoder()
+				enc.Int(i)
+				enc.Bytes(arg)
+				bench.encode(enc)
+			}
+		})
+	}
+}
@@ -16,6 +16,9 @@ package codegen
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"sync"
 )
 
 // CatchPanics recovers from panic() calls that occur during encoding,
@@ -33,3 +36,103 @@ func CatchPanics(r interface{}) error {
 	}
 	panic(r)
 }
+
+// errorCodecs maps the reflect.Type and the name of every error type
+// registered with RegisterError to its *errorCodec.
+var errorCodecs sync.Map
+
+// errorCodec encodes and decodes the values of a registered error type.
+type errorCodec struct {
+	name   string                // name of the type, e.g., "*pkg/path.Error"
+	encode func(*Encoder, error) // encodes a value of the type
+	decode func(*Decoder) error  // decodes a value of the type
+}
+
+// RegisterError registers the weaver.AutoMarshal struct type T, and the
+// pointer type *T, as error types whose values can be sent across component
+// boundaries. When a component method returns an error, the error and every
+// error it wraps are serialized, and the ones whose type is registered are
+// reconstructed on the caller's side, so that errors.As and errors.Is work on
+// them as they would on the original errors. Errors of unregistered types are
+// reconstructed as opaque errors that only preserve their messages.
+//
+// Only the types that implement error are registered. RegisterError is
+// called by the code generated by "weaver generate" for every AutoMarshal
+// type that implements error; there is no need to call it directly.
+func RegisterError[T any, PT interface {
+	*T
+	AutoMarshal
+}]() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if _, ok := any((*T)(nil)).(error); ok {
+		registerErrorCodec(reflect.PointerTo(t), &errorCodec{
+			encode: func(enc *Encoder, err error) { any(err).(PT).WeaverMarshal(enc) },
+			decode: func(dec *Decoder) error {
+				x := PT(new(T))
+				x.WeaverUnmarshal(dec)
+				return any(x).(error)
+			},
+		})
+	}
+	var zero T
+	if _, ok := any(zero).(error); ok {
+		registerErrorCodec(t, &errorCodec{
+			encode: func(enc *Encoder, err error) {
+				x := any(err).(T)
+				PT(&x).WeaverMarshal(enc)
+			},
+			decode: func(dec *Decoder) error {
+				var x T
+				PT(&x).WeaverUnmarshal(dec)
+				return any(x).(error)
+			},
+		})
+	}
+}
+
+// registerErrorCodec registers the codec of the provided error type.
+func registerErrorCodec(t reflect.Type, c *errorCodec) {
+	c.name = errorTypeName(t)
+	errorCodecs.Store(t, c)
+	errorCodecs.Store(c.name, c)
+}
+
+// errorTypeName returns the fully qualified name of the provided named type
+// or pointer to a named type.
+func errorTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return "*" + errorTypeName(t.Elem())
+	}
+	return fmt.Sprintf("%s.%s", t.PkgPath(), t.Name())
+}
+
+// encodeErrorValue encodes err if its type is registered with RegisterError,
+// or an empty marker otherwise.
+func (e *Encoder) encodeErrorValue(err error) {
+	v := reflect.ValueOf(err)
+	c, ok := errorCodecs.Load(v.Type())
+	if !ok || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		e.String("")
+		return
+	}
+	codec := c.(*errorCodec)
+	e.String(codec.name)
+	enc := NewEncoder()
+	codec.encode(enc, err)
+	e.Bytes(enc.Data())
+}
+
+// decodeErrorValue decodes an error encoded by encodeErrorValue. It returns
+// nil if the error's type isn't registered.
+func (d *Decoder) decodeErrorValue() error {
+	name := d.String()
+	if name == "" {
+		return nil
+	}
+	data := d.Bytes()
+	c, ok := errorCodecs.Load(name)
+	if !ok {
+		return nil
+	}
+	return c.(*errorCodec).decode(NewDecoder(data))
+}
@@ -25,6 +25,22 @@ import (
 //
 // If the same sequence of values is added to two differ Hashers, they
 // will produce the same result, even if they are in different processes.
+//
+// The hash of routing keys decides which replica a call is routed to, so the
+// algorithm is fixed and must not change:
+//
+//  1. Every value is appended, in the order it is added, to a byte string.
+//     Integers are appended as little-endian two's complement numbers of
+//     their size, except that int and uint always take 8 bytes. Floats are
+//     appended as their little-endian IEEE 754 bits. Strings are appended as
+//     their 4-byte little-endian length followed by their bytes, so that,
+//     e.g., ("ab", "c") and ("a", "bc") produce different byte strings.
+//  2. Sum64 interprets the first 8 bytes of the SHA-256 digest of the byte
+//     string as a little-endian uint64, mapping 0 to 1 and 2^64-1 to 2^64-2.
+//
+// The hash of a struct routing key is computed by adding its fields, other
+// than an embedded weaver.AutoMarshal, in declaration order. As a result,
+// swapping the values of two fields of a key generally changes its hash.
 type Hasher struct {
 	// TODO: improve performance:
 	// - do not accumulate everything; hash as we go
@@ -45,3 +45,52 @@ func TestHash(t *testing.T) {
 		t.Errorf("unstable hash value %016x (expecting %016x)", a, expected)
 	}
 }
+
+// hashTenantShard hashes a (tenant, shard) routing key the way the code
+// generated by "weaver generate" hashes a struct{Tenant string; Shard int}.
+func hashTenantShard(tenant string, shard int) uint64 {
+	var h Hasher
+	h.WriteString(tenant)
+	h.WriteInt(shard)
+	return h.Sum64()
+}
+
+func TestHashCompositeKey(t *testing.T) {
+	// Swapping the values of two fields changes the hash.
+	hashPair := func(a, b int) uint64 {
+		var h Hasher
+		h.WriteInt(a)
+		h.WriteInt(b)
+		return h.Sum64()
+	}
+	if a, b := hashPair(1, 2), hashPair(2, 1); a == b {
+		t.Errorf("hash(1, 2) == hash(2, 1) == %016x", a)
+	}
+
+	// Moving bytes between two string fields changes the hash.
+	hashStrings := func(a, b string) uint64 {
+		var h Hasher
+		h.WriteString(a)
+		h.WriteString(b)
+		return h.Sum64()
+	}
+	if a, b := hashStrings("ab", "c"), hashStrings("a", "bc"); a == b {
+		t.Errorf("hash(ab, c) == hash(a, bc) == %016x", a)
+	}
+
+	// The hash of a composite key is stable across processes and restarts.
+	// The expected values follow from the algorithm documented on Hasher.
+	for _, test := range []struct {
+		tenant string
+		shard  int
+		want   uint64
+	}{
+		{"acme", 0, 0x825559c10eceaf00},
+		{"acme", 1, 0x4fb60a465e00b72b},
+		{"", 0, 0xb43207b5f07bec15},
+	} {
+		if got := hashTenantShard(test.tenant, test.shard); got != test.want {
+			t.Errorf("hash(%q, %d): got %016x, want %016x", test.tenant, test.shard, got, test.want)
+		}
+	}
+}
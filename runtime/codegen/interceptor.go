@@ -0,0 +1,160 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"context"
+	"sync"
+)
+
+// Call describes a component method call intercepted by an Interceptor.
+type Call struct {
+	Caller    string // full name of the calling component, if known
+	Component string // full name of the called component
+	Method    string // name of the called method
+	Remote    bool   // is the call remote?
+	Args      []any  // the method arguments, excluding the context
+}
+
+// A Handler executes a component method call with the provided arguments,
+// excluding the context. It returns the method results, excluding the final
+// error.
+type Handler func(ctx context.Context, args []any) ([]any, error)
+
+// An Interceptor intercepts a component method call. It may inspect or
+// modify the context and arguments before calling next, inspect or modify the
+// results returned by next, or return results of its own without calling next
+// at all. The arguments and results passed to and returned from next must have
+// the types declared by the intercepted method.
+type Interceptor func(ctx context.Context, call Call, next Handler) ([]any, error)
+
+var (
+	// Interceptors registered by AddClientInterceptor and AddServerInterceptor.
+	interceptorsMu     sync.Mutex
+	clientInterceptors []Interceptor
+	serverInterceptors []Interceptor
+)
+
+// AddClientInterceptor registers an interceptor for all component method
+// calls made by the current process. It only affects stubs created after it
+// is called.
+func AddClientInterceptor(i Interceptor) {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	clientInterceptors = append(clientInterceptors, i)
+}
+
+// AddServerInterceptor registers an interceptor for all component method
+// calls received by components in the current process. It only affects stubs
+// created after it is called.
+func AddServerInterceptor(i Interceptor) {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	serverInterceptors = append(serverInterceptors, i)
+}
+
+// ClientInterceptor returns the interceptor to use for remote calls made by
+// the current process, or nil if there is none.
+func ClientInterceptor() Interceptor {
+	interceptorsMu.Lock()
+	defer interceptorsMu.Unlock()
+	return Chain(clientInterceptors...)
+}
+
+// ServerInterceptor returns the interceptor to use for remote calls received
+// by the provided component implementation, or nil if there is none. The
+// interceptors registered by AddServerInterceptor run before the ones returned
+// by the implementation's Interceptors method, if any.
+func ServerInterceptor(impl any) Interceptor {
+	interceptorsMu.Lock()
+	interceptors := append([]Interceptor{}, serverInterceptors...)
+	interceptorsMu.Unlock()
+	if x, ok := impl.(interface{ Interceptors() []Interceptor }); ok {
+		interceptors = append(interceptors, x.Interceptors()...)
+	}
+	return Chain(interceptors...)
+}
+
+// LocalInterceptor returns the interceptor to use for local calls to the
+// provided component implementation, or nil if there is none. A local call is
+// both made and received by the current process, so the client interceptors
+// run first, followed by the server interceptors.
+func LocalInterceptor(impl any) Interceptor {
+	return Chain(ClientInterceptor(), ServerInterceptor(impl))
+}
+
+// Chain returns an interceptor that invokes the provided interceptors in
+// order, with the first interceptor being the outermost one. Nil interceptors
+// are skipped. Chain returns nil if there are no interceptors.
+func Chain(interceptors ...Interceptor) Interceptor {
+	var nonNil []Interceptor
+	for _, i := range interceptors {
+		if i != nil {
+			nonNil = append(nonNil, i)
+		}
+	}
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	}
+	return func(ctx context.Context, call Call, next Handler) ([]any, error) {
+		return chain(ctx, call, nonNil, next)
+	}
+}
+
+// chain invokes interceptors[0], passing it a handler that invokes the rest of
+// the interceptors and finally next.
+func chain(ctx context.Context, call Call, interceptors []Interceptor, next Handler) ([]any, error) {
+	if len(interceptors) == 0 {
+		return next(ctx, call.Args)
+	}
+	return interceptors[0](ctx, call, func(ctx context.Context, args []any) ([]any, error) {
+		call.Args = args
+		return chain(ctx, call, interceptors[1:], next)
+	})
+}
+
+// Intercept invokes interceptor on a call to the provided method with the
+// provided arguments. call holds the Caller, Component, and Remote fields of
+// the call. If the caller isn't known, it is taken from ctx.
+func Intercept(ctx context.Context, interceptor Interceptor, call Call, method string, args []any, next Handler) ([]any, error) {
+	call.Method = method
+	call.Args = args
+	if call.Caller == "" {
+		if caller, ok := CallerInfoFromContext(ctx); ok {
+			call.Caller = caller.Component
+		}
+	}
+	return interceptor(ctx, call, next)
+}
+
+// Arg returns args[i] as a T. It returns the zero value of T if args[i] is
+// missing or nil.
+func Arg[T any](args []any, i int) T {
+	var x T
+	if i < len(args) && args[i] != nil {
+		x = args[i].(T)
+	}
+	return x
+}
+
+// Result returns results[i] as a T. It returns the zero value of T if
+// results[i] is missing or nil, e.g., because an interceptor returned an error
+// without calling the method.
+func Result[T any](results []any, i int) T {
+	return Arg[T](results, i)
+}
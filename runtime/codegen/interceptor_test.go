@@ -0,0 +1,96 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChain(t *testing.T) {
+	// Each interceptor records its name and appends it to the first argument.
+	var order []string
+	named := func(name string) Interceptor {
+		return func(ctx context.Context, call Call, next Handler) ([]any, error) {
+			order = append(order, name)
+			return next(ctx, []any{call.Args[0].(string) + name})
+		}
+	}
+	ic := Chain(named("a"), nil, named("b"), named("c"))
+	next := func(_ context.Context, args []any) ([]any, error) {
+		return []any{args[0]}, nil
+	}
+	results, err := ic(context.Background(), Call{Args: []any{">"}}, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "c"}, order); diff != "" {
+		t.Errorf("order (-want +got):\n%s", diff)
+	}
+	if got, want := Result[string](results, 0), ">abc"; got != want {
+		t.Errorf("result: got %q, want %q", got, want)
+	}
+}
+
+func TestChainEmpty(t *testing.T) {
+	if ic := Chain(); ic != nil {
+		t.Error("Chain(): got non-nil interceptor")
+	}
+	if ic := Chain(nil, nil); ic != nil {
+		t.Error("Chain(nil, nil): got non-nil interceptor")
+	}
+}
+
+func TestInterceptShortCircuit(t *testing.T) {
+	errStop := errors.New("stop")
+	stop := func(context.Context, Call, Handler) ([]any, error) {
+		return nil, errStop
+	}
+	next := func(context.Context, []any) ([]any, error) {
+		t.Fatal("next unexpectedly called")
+		return nil, nil
+	}
+	results, err := Intercept(context.Background(), stop, Call{}, "M", []any{1}, next)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("got error %v, want %v", err, errStop)
+	}
+	// Missing results are replaced with zero values.
+	if got := Result[int](results, 0); got != 0 {
+		t.Errorf("Result: got %d, want 0", got)
+	}
+	if got := Result[[]string](results, 1); got != nil {
+		t.Errorf("Result: got %v, want nil", got)
+	}
+}
+
+func TestInterceptCaller(t *testing.T) {
+	var got Call
+	record := func(ctx context.Context, call Call, next Handler) ([]any, error) {
+		got = call
+		return next(ctx, call.Args)
+	}
+	next := func(context.Context, []any) ([]any, error) { return nil, nil }
+	ctx := WithLocalCaller(context.Background(), "pkg/Caller")
+	if _, err := Intercept(ctx, record, Call{Component: "pkg/Callee"}, "M", []any{"x"}, next); err != nil {
+		t.Fatal(err)
+	}
+	want := Call{Caller: "pkg/Caller", Component: "pkg/Callee", Method: "M", Args: []any{"x"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("call (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"context"
+	"sync"
+)
+
+// CallLoad records the load of a single execution of a routed component
+// method, as reported by the method with weaver.ReportLoad.
+type CallLoad struct {
+	mu       sync.Mutex
+	load     float64
+	reported bool
+}
+
+// callLoadKey is the context key for a *CallLoad.
+type callLoadKey struct{}
+
+// WithCallLoad returns a copy of ctx that carries a new CallLoad, along with
+// the CallLoad. It is called by server stubs before a routed method runs.
+func WithCallLoad(ctx context.Context) (context.Context, *CallLoad) {
+	l := &CallLoad{}
+	return context.WithValue(ctx, callLoadKey{}, l), l
+}
+
+// withoutCallLoad returns a copy of ctx that doesn't carry a CallLoad, or ctx
+// itself if it doesn't carry one.
+func withoutCallLoad(ctx context.Context) context.Context {
+	if CallLoadFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, callLoadKey{}, (*CallLoad)(nil))
+}
+
+// CallLoadFromContext returns the CallLoad stored in ctx, or nil if ctx
+// doesn't belong to the execution of a remote call to a routed method.
+func CallLoadFromContext(ctx context.Context) *CallLoad {
+	l, _ := ctx.Value(callLoadKey{}).(*CallLoad)
+	return l
+}
+
+// Add adds the provided load to the call.
+func (l *CallLoad) Add(load float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.load += load
+	l.reported = true
+}
+
+// Load returns the sum of the loads added to the call, or 1 if none were.
+func (l *CallLoad) Load() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.reported {
+		return 1.0
+	}
+	return l.load
+}
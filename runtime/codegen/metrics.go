@@ -15,6 +15,7 @@
 package codegen
 
 import (
+	"sync"
 	"time"
 
 	"github.com/ServiceWeaver/weaver/metrics"
@@ -31,7 +32,11 @@ var (
 	)
 	MethodErrors = metrics.NewCounterMap[MethodLabels](
 		"serviceweaver_method_error_count",
-		"Count of Service Weaver component method invocations that result in an error",
+		"Count of Service Weaver component method invocations that result in an error returned by the method",
+	)
+	MethodTransportErrors = metrics.NewCounterMap[MethodLabels](
+		"serviceweaver_method_transport_error_count",
+		"Count of remote Service Weaver component method invocations that fail in the network or the Service Weaver runtime, rather than in the method",
 	)
 	MethodLatencies = metrics.NewHistogramMap[MethodLabels](
 		"serviceweaver_method_latency_micros",
@@ -48,35 +53,231 @@ var (
 		"Number of bytes in Service Weaver component method replies",
 		metrics.NonNegativeBuckets,
 	)
+	MethodQueueLatencies = metrics.NewHistogramMap[MethodLabels](
+		"serviceweaver_method_queue_wait_micros",
+		"Duration, in microseconds, that remote Service Weaver component method calls wait on the server before their execution starts",
+		metrics.NonNegativeBuckets,
+	)
+	MethodWireBytesRequest = metrics.NewHistogramMap[MethodLabels](
+		"serviceweaver_method_wire_bytes_request",
+		"Number of bytes in compressible Service Weaver remote component method requests, as sent over the network",
+		metrics.NonNegativeBuckets,
+	)
+	MethodWireBytesReply = metrics.NewHistogramMap[MethodLabels](
+		"serviceweaver_method_wire_bytes_reply",
+		"Number of bytes in compressible Service Weaver remote component method replies, as received from the network",
+		metrics.NonNegativeBuckets,
+	)
+	MethodHedges = metrics.NewCounterMap[MethodLabels](
+		"serviceweaver_method_hedged_count",
+		"Count of extra requests sent for hedged remote Service Weaver component method calls",
+	)
+	MethodRateLimited = metrics.NewCounterMap[MethodLabels](
+		"serviceweaver_method_rate_limited_count",
+		"Count of Service Weaver component method calls rejected by the method's rate limit",
+	)
+	MethodRateLimitDelays = metrics.NewCounterMap[MethodLabels](
+		"serviceweaver_method_rate_limit_delayed_count",
+		"Count of Service Weaver component method calls delayed by the method's rate limit",
+	)
+	MethodPermissionDenied = metrics.NewCounterMap[MethodLabels](
+		"serviceweaver_method_permission_denied_count",
+		"Count of Service Weaver component method calls rejected because the caller is not allowed to call the method",
+	)
+	EventsPublished = metrics.NewCounterMap[EventLabels](
+		"serviceweaver_event_published_count",
+		"Count of events queued for delivery to a Service Weaver subscriber component",
+	)
+	EventsDelivered = metrics.NewCounterMap[EventLabels](
+		"serviceweaver_event_delivered_count",
+		"Count of events handled successfully by a Service Weaver subscriber component",
+	)
+	EventsDropped = metrics.NewCounterMap[EventLabels](
+		"serviceweaver_event_dropped_count",
+		"Count of events dropped because the queue of a Service Weaver subscriber component was full",
+	)
+	EventDeliveryErrors = metrics.NewCounterMap[EventLabels](
+		"serviceweaver_event_delivery_error_count",
+		"Count of failed attempts to deliver an event to a Service Weaver subscriber component, which are retried",
+	)
+	MethodPanics = metrics.NewCounterMap[ComponentMethodLabels](
+		"serviceweaver_method_panic_count",
+		"Count of remote Service Weaver component method calls that panicked",
+	)
+	ComponentRestarts = metrics.NewCounterMap[ComponentLabels](
+		"serviceweaver_component_restart_total",
+		"Count of attempts to create a Service Weaver component after a previous attempt failed",
+	)
+	ComponentLastRestart = metrics.NewGaugeMap[ComponentLabels](
+		"serviceweaver_component_last_restart_unix",
+		"Unix time, in seconds, of the most recent restart of a Service Weaver component",
+	)
+	ComponentQuiesced = metrics.NewGaugeMap[ComponentLabels](
+		"serviceweaver_component_quiesced",
+		"Whether a Service Weaver component is quiesced by weaver.Quiesce (1) or not (0)",
+	)
+	ComponentQueuedCalls = metrics.NewGaugeMap[ComponentPriorityLabels](
+		"serviceweaver_component_queued_calls",
+		"Number of remote calls to a Service Weaver component waiting to start, by priority",
+	)
+	ComponentPoolSize = metrics.NewGaugeMap[ComponentLabels](
+		"serviceweaver_component_pool_size",
+		"Maximum number of remote calls a replica of a Service Weaver component executes at once",
+	)
+	ComponentBusyRejections = metrics.NewCounterMap[ComponentLabels](
+		"serviceweaver_component_busy_rejection_count",
+		"Count of remote calls to a Service Weaver component rejected with weaver.ErrServerBusy",
+	)
+	RoutingAffinityHits = metrics.NewCounterMap[ComponentLabels](
+		"serviceweaver_routing_affinity_hit_count",
+		"Count of routed calls to a Service Weaver component sent to the replica remembered for their routing key",
+	)
+	RoutingAffinityMisses = metrics.NewCounterMap[ComponentLabels](
+		"serviceweaver_routing_affinity_miss_count",
+		"Count of routed calls to a Service Weaver component with no available remembered replica for their routing key",
+	)
 )
 
+type ComponentLabels struct {
+	Component string // full component name
+}
+
+type ComponentMethodLabels struct {
+	Component string // full component name
+	Method    string // component method's name
+}
+
+type ComponentPriorityLabels struct {
+	Component string // full component name
+	Priority  int    // priority of the calls (see weaver.WithPriority)
+}
+
+type EventLabels struct {
+	Event      string // event type, e.g., "example.com/users.UserCreated"
+	Publisher  string // full publishing component name
+	Subscriber string // full subscriber component name
+}
+
 type MethodLabels struct {
 	Caller    string // full calling component name
 	Component string // full callee component name
 	Method    string // callee component method's name
 	Remote    bool   // Is this a remote call?
+
+	// Priority of the call, as computed by the server executing it (see
+	// weaver.WithPriority). Zero for metrics recorded by callers, which
+	// don't know the priority of their calls.
+	Priority int
 }
 
 // MethodMetrics contains metrics for a single Service Weaver component method.
 type MethodMetrics struct {
-	remote       bool
-	Count        *metrics.Counter   // See MethodCounts.
-	ErrorCount   *metrics.Counter   // See MethodErrors.
-	Latency      *metrics.Histogram // See MethodLatencies.
-	BytesRequest *metrics.Histogram // See MethodBytesRequest.
-	BytesReply   *metrics.Histogram // See MethodBytesReply.
+	remote              bool
+	Count               *metrics.Counter   // See MethodCounts.
+	ErrorCount          *metrics.Counter   // See MethodErrors.
+	TransportErrorCount *metrics.Counter   // See MethodTransportErrors.
+	Latency             *metrics.Histogram // See MethodLatencies.
+	BytesRequest        *metrics.Histogram // See MethodBytesRequest.
+	BytesReply          *metrics.Histogram // See MethodBytesReply.
+	QueueLatency        *metrics.Histogram // See MethodQueueLatencies.
+	HedgedCount         *metrics.Counter   // See MethodHedges.
+
+	// Counts of recent invocations and errors. Nil unless enabled via
+	// MethodMetricsOptions.WindowDuration, e.g., with the metrics_window
+	// setting in the component's config section.
+	RecentCount      *metrics.SlidingWindowCounter
+	RecentErrorCount *metrics.SlidingWindowCounter
+
+	// Counts recent invocations for Throughput.
+	calls *metrics.SlidingWindowCounter
 }
 
-// MethodMetricsFor returns metrics for the specified method.
+// MaxThroughputWindow is the longest window over which
+// MethodMetrics.Throughput averages, unless
+// MethodMetricsOptions.WindowDuration is longer.
+const MaxThroughputWindow = time.Minute
+
+// MethodMetricsOptions configures the metrics returned by
+// MethodMetricsWithOptions.
+type MethodMetricsOptions struct {
+	// If positive, MethodMetrics.RecentCount and MethodMetrics.RecentErrorCount
+	// count invocations and errors over a sliding window of this duration.
+	WindowDuration time.Duration
+}
+
+var (
+	methodMetricsMu      sync.Mutex
+	methodMetricsOptions = map[string]MethodMetricsOptions{} // by component
+	methodMetrics        = map[MethodLabels]*MethodMetrics{}
+)
+
+// SetMethodMetricsOptions sets the options of the metrics returned by
+// MethodMetricsFor for the methods of the component with the provided full
+// name. It only affects the metrics that haven't been requested yet, so the
+// runtime calls it, with the options set in the component's config section
+// (see runtime.MetricsWindowKey), before it creates any stub.
+func SetMethodMetricsOptions(component string, opts MethodMetricsOptions) {
+	methodMetricsMu.Lock()
+	defer methodMetricsMu.Unlock()
+	methodMetricsOptions[component] = opts
+}
+
+// MethodMetricsFor returns metrics for the specified method, configured with
+// the options set for its component by SetMethodMetricsOptions, if any. Every
+// call with the same labels returns the same metrics, so code outside of the
+// generated stubs can read the metrics that the stubs record, e.g., with
+// Throughput.
 func MethodMetricsFor(labels MethodLabels) *MethodMetrics {
-	return &MethodMetrics{
-		remote:       labels.Remote,
-		Count:        MethodCounts.Get(labels),
-		ErrorCount:   MethodErrors.Get(labels),
-		Latency:      MethodLatencies.Get(labels),
-		BytesRequest: MethodBytesRequest.Get(labels),
-		BytesReply:   MethodBytesReply.Get(labels),
+	methodMetricsMu.Lock()
+	defer methodMetricsMu.Unlock()
+	if m, ok := methodMetrics[labels]; ok {
+		return m
+	}
+	m := MethodMetricsWithOptions(labels, methodMetricsOptions[labels.Component])
+	methodMetrics[labels] = m
+	return m
+}
+
+// MethodMetricsWithOptions returns new metrics for the specified method,
+// configured according to the provided options. Unlike MethodMetricsFor, it
+// returns different metrics on every call, although they share the counters
+// and histograms exported by the metrics package.
+func MethodMetricsWithOptions(labels MethodLabels, opts MethodMetricsOptions) *MethodMetrics {
+	m := &MethodMetrics{
+		remote:              labels.Remote,
+		Count:               MethodCounts.Get(labels),
+		ErrorCount:          MethodErrors.Get(labels),
+		TransportErrorCount: MethodTransportErrors.Get(labels),
+		Latency:             MethodLatencies.Get(labels),
+		BytesRequest:        MethodBytesRequest.Get(labels),
+		BytesReply:          MethodBytesReply.Get(labels),
+		QueueLatency:        MethodQueueLatencies.Get(labels),
+		HedgedCount:         MethodHedges.Get(labels),
+	}
+	if opts.WindowDuration > 0 {
+		m.RecentCount = metrics.NewSlidingWindowCounter(opts.WindowDuration)
+		m.RecentErrorCount = metrics.NewSlidingWindowCounter(opts.WindowDuration)
+	}
+	window := MaxThroughputWindow
+	if opts.WindowDuration > window {
+		window = opts.WindowDuration
 	}
+	m.calls = metrics.NewSlidingWindowCounter(window)
+	return m
+}
+
+// Throughput returns the number of invocations per second of the method,
+// averaged over the last window, including the current second. The window is
+// rounded up to a whole number of seconds, and is capped at
+// MaxThroughputWindow, or at MethodMetricsOptions.WindowDuration if longer.
+// Throughput returns 0 if window is not positive or if there were no
+// invocations within the window. It is safe to call concurrently with
+// invocations of the method.
+func (m *MethodMetrics) Throughput(window time.Duration) float64 {
+	if m.calls == nil || window <= 0 {
+		return 0
+	}
+	return m.calls.RateOver(window)
 }
 
 // MethodCallHandle holds information needed to finalize metric
@@ -90,12 +291,59 @@ func (m *MethodMetrics) Begin() MethodCallHandle {
 	return MethodCallHandle{time.Now()}
 }
 
-// End ends metric update recording for a call to method m.
+// An ErrorKind classifies the outcome of a method call.
+type ErrorKind int
+
+const (
+	NoError        ErrorKind = iota // the call succeeded
+	AppError                        // the method returned an error
+	TransportError                  // the call failed before or after the method ran, e.g., in the network
+)
+
+// ErrorKindOf returns the kind of the error returned by a method call.
+// transport reports whether err was produced by the stub making the call,
+// rather than returned by the method.
+func ErrorKindOf(err error, transport bool) ErrorKind {
+	switch {
+	case err == nil:
+		return NoError
+	case transport:
+		return TransportError
+	default:
+		return AppError
+	}
+}
+
+// End ends metric update recording for a call to method m. A failed call is
+// counted as an AppError. See EndWithKind.
 func (m *MethodMetrics) End(h MethodCallHandle, failed bool, requestBytes, replyBytes int) {
+	kind := NoError
+	if failed {
+		kind = AppError
+	}
+	m.EndWithKind(h, kind, requestBytes, replyBytes)
+}
+
+// EndWithKind ends metric update recording for a call to method m that had
+// the provided outcome. Application errors are counted by MethodErrors, and
+// transport errors by MethodTransportErrors.
+func (m *MethodMetrics) EndWithKind(h MethodCallHandle, kind ErrorKind, requestBytes, replyBytes int) {
 	latency := time.Since(h.start).Microseconds()
 	m.Count.Inc()
-	if failed {
+	if m.calls != nil {
+		m.calls.Inc()
+	}
+	switch kind {
+	case AppError:
 		m.ErrorCount.Inc()
+	case TransportError:
+		m.TransportErrorCount.Inc()
+	}
+	if m.RecentCount != nil {
+		m.RecentCount.Inc()
+		if kind != NoError {
+			m.RecentErrorCount.Inc()
+		}
 	}
 	m.Latency.Put(float64(latency))
 	if m.remote {
@@ -103,3 +351,28 @@ func (m *MethodMetrics) End(h MethodCallHandle, failed bool, requestBytes, reply
 		m.BytesReply.Put(float64(replyBytes))
 	}
 }
+
+// DispatchHandle holds information needed to record the time a remote call
+// to a method waits on the server before its execution starts.
+type DispatchHandle struct {
+	received time.Time
+}
+
+// BeginDispatch starts recording the queue wait of a remote call to method m
+// that was received from the network at the provided time. If received is
+// zero, the queue wait is measured from now.
+func (m *MethodMetrics) BeginDispatch(received time.Time) DispatchHandle {
+	if received.IsZero() {
+		received = time.Now()
+	}
+	return DispatchHandle{received}
+}
+
+// EndDispatch ends recording the queue wait of a remote call to method m. It
+// should be called right before the method starts executing. Unlike Begin and
+// End, which measure the execution of the method, BeginDispatch and
+// EndDispatch measure the time the call spends waiting to be executed, e.g.,
+// for a goroutine to run it or for the component to be initialized.
+func (m *MethodMetrics) EndDispatch(h DispatchHandle) {
+	m.QueueLatency.Put(float64(time.Since(h.received).Microseconds()))
+}
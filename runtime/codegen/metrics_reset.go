@@ -0,0 +1,37 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build testing
+
+package codegen
+
+// Reset zeroes all of the method's metrics. Like the Reset methods of the
+// metrics package, it is only compiled into test builds.
+func (m *MethodMetrics) Reset() {
+	m.Count.Reset()
+	m.ErrorCount.Reset()
+	m.TransportErrorCount.Reset()
+	m.Latency.Reset()
+	m.BytesRequest.Reset()
+	m.BytesReply.Reset()
+	m.QueueLatency.Reset()
+	m.HedgedCount.Reset()
+	if m.RecentCount != nil {
+		m.RecentCount.Reset()
+		m.RecentErrorCount.Reset()
+	}
+	if m.calls != nil {
+		m.calls.Reset()
+	}
+}
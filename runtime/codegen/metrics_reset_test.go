@@ -0,0 +1,53 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build testing
+
+package codegen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+)
+
+func TestMethodMetricsReset(t *testing.T) {
+	labels := MethodLabels{Caller: "caller", Component: "component", Method: "reset", Remote: true}
+	m := MethodMetricsWithOptions(labels, MethodMetricsOptions{WindowDuration: time.Minute})
+	m.End(m.Begin(), true, 10, 20)
+	m.EndDispatch(m.BeginDispatch(time.Time{}))
+	m.HedgedCount.Inc()
+
+	m.Reset()
+	for _, s := range metrics.Snapshot() {
+		if s.Labels["method"] != "reset" {
+			continue
+		}
+		if s.Value != 0 {
+			t.Errorf("%s after Reset: got %v, want 0", s.Name, s.Value)
+		}
+		for i, c := range s.Counts {
+			if c != 0 {
+				t.Errorf("%s after Reset: got count %d in bucket %d, want 0", s.Name, c, i)
+			}
+		}
+	}
+	if got := m.RecentCount.Count(); got != 0 {
+		t.Errorf("RecentCount after Reset: got %d, want 0", got)
+	}
+	if got := m.RecentErrorCount.Count(); got != 0 {
+		t.Errorf("RecentErrorCount after Reset: got %d, want 0", got)
+	}
+}
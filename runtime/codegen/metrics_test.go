@@ -15,8 +15,11 @@
 package codegen
 
 import (
+	"fmt"
 	"testing"
 	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
 )
 
 func BenchmarkMetrics(b *testing.B) {
@@ -64,3 +67,110 @@ func BenchmarkMetrics(b *testing.B) {
 		}
 	})
 }
+
+func TestMethodMetricsWindow(t *testing.T) {
+	labels := MethodLabels{Caller: "caller", Component: "component", Method: "window"}
+	if m := MethodMetricsFor(labels); m.RecentCount != nil || m.RecentErrorCount != nil {
+		t.Fatal("sliding window counters enabled by default")
+	}
+
+	m := MethodMetricsWithOptions(labels, MethodMetricsOptions{WindowDuration: time.Minute})
+	m.End(m.Begin(), false, 0, 0)
+	m.End(m.Begin(), true, 0, 0)
+	m.End(m.Begin(), true, 0, 0)
+	if got, want := m.RecentCount.Count(), uint64(3); got != want {
+		t.Errorf("RecentCount: got %d, want %d", got, want)
+	}
+	if got, want := m.RecentErrorCount.Count(), uint64(2); got != want {
+		t.Errorf("RecentErrorCount: got %d, want %d", got, want)
+	}
+}
+
+func TestSetMethodMetricsOptions(t *testing.T) {
+	SetMethodMetricsOptions("windowed", MethodMetricsOptions{WindowDuration: time.Minute})
+	labels := MethodLabels{Caller: "caller", Component: "windowed", Method: "m"}
+	m := MethodMetricsFor(labels)
+	if m.RecentCount == nil || m.RecentErrorCount == nil {
+		t.Fatal("sliding window counters not enabled by SetMethodMetricsOptions")
+	}
+	m.End(m.Begin(), false, 0, 0)
+
+	// The metrics are shared by every caller of MethodMetricsFor.
+	if got := MethodMetricsFor(labels); got != m {
+		t.Fatal("MethodMetricsFor returned different metrics for the same labels")
+	}
+	if got, want := m.RecentCount.Count(), uint64(1); got != want {
+		t.Errorf("RecentCount: got %d, want %d", got, want)
+	}
+}
+
+func TestMethodMetricsThroughput(t *testing.T) {
+	labels := MethodLabels{Caller: "caller", Component: "component", Method: "throughput"}
+	m := MethodMetricsFor(labels)
+	if got := m.Throughput(time.Minute); got != 0 {
+		t.Errorf("Throughput before any call: got %f, want 0", got)
+	}
+	for i := 0; i < 5; i++ {
+		m.End(m.Begin(), false, 0, 0)
+	}
+	if got, want := m.Throughput(10*time.Second), 0.5; got != want {
+		t.Errorf("Throughput(10s): got %f, want %f", got, want)
+	}
+	if got, want := m.Throughput(time.Hour), 5/MaxThroughputWindow.Seconds(); got != want {
+		t.Errorf("Throughput(1h): got %f, want %f", got, want)
+	}
+	if got := m.Throughput(0); got != 0 {
+		t.Errorf("Throughput(0): got %f, want 0", got)
+	}
+}
+
+func TestMethodMetricsQueueWait(t *testing.T) {
+	labels := MethodLabels{Caller: "caller", Component: "component", Method: "queue", Remote: true}
+	m := MethodMetricsFor(labels)
+	m.EndDispatch(m.BeginDispatch(time.Now().Add(-5 * time.Millisecond)))
+
+	for _, s := range metrics.Snapshot() {
+		if s.Name != MethodQueueLatencies.Name() || s.Labels["method"] != "queue" {
+			continue
+		}
+		if s.Value < 5000 {
+			t.Errorf("queue wait: got %vus, want at least 5000us", s.Value)
+		}
+		return
+	}
+	t.Fatalf("metric %s not found", MethodQueueLatencies.Name())
+}
+
+func TestMethodMetricsErrorKinds(t *testing.T) {
+	labels := MethodLabels{Caller: "caller", Component: "component", Method: "kinds", Remote: true}
+	m := MethodMetricsWithOptions(labels, MethodMetricsOptions{WindowDuration: time.Minute})
+	failure := fmt.Errorf("failure")
+	m.EndWithKind(m.Begin(), ErrorKindOf(nil, false), 0, 0)
+	m.EndWithKind(m.Begin(), ErrorKindOf(failure, false), 0, 0)
+	m.EndWithKind(m.Begin(), ErrorKindOf(failure, true), 0, 0)
+	m.EndWithKind(m.Begin(), ErrorKindOf(failure, true), 0, 0)
+	m.End(m.Begin(), true, 0, 0) // counted as an application error
+
+	want := map[string]float64{
+		MethodCounts.Name():          5,
+		MethodErrors.Name():          2,
+		MethodTransportErrors.Name(): 2,
+	}
+	for _, s := range metrics.Snapshot() {
+		if s.Labels["method"] != "kinds" {
+			continue
+		}
+		if w, ok := want[s.Name]; ok {
+			if s.Value != w {
+				t.Errorf("%s: got %v, want %v", s.Name, s.Value, w)
+			}
+			delete(want, s.Name)
+		}
+	}
+	for name := range want {
+		t.Errorf("metric %s not found", name)
+	}
+	if got, want := m.RecentErrorCount.Count(), uint64(4); got != want {
+		t.Errorf("RecentErrorCount: got %d, want %d", got, want)
+	}
+}
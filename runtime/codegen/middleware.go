@@ -0,0 +1,64 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"context"
+	"sync"
+)
+
+// A ServerMiddleware wraps the execution of a component method call received
+// by a Server. The arguments have already been decoded when the middleware
+// runs. component and method identify the call, using the same names as
+// MethodLabels. A middleware may return an error without calling handler,
+// e.g., to reject an unauthorized call, in which case the error is returned
+// to the caller as the method's error.
+type ServerMiddleware func(ctx context.Context, component, method string, handler func(context.Context) error) error
+
+// ServerMiddlewares is a chain of ServerMiddleware, used by generated server
+// stubs to implement Server.Use. The zero value is an empty chain. It is safe
+// to call Use concurrently with Run, though middleware is typically
+// registered before the server receives any calls.
+type ServerMiddlewares struct {
+	mu  sync.Mutex
+	mws []ServerMiddleware
+}
+
+// Use appends mw to the chain.
+func (m *ServerMiddlewares) Use(mw ServerMiddleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mws = append(m.mws, mw)
+}
+
+// Run invokes handler through the chain. The middleware is called in the
+// order it was registered, with the first middleware being the outermost one.
+func (m *ServerMiddlewares) Run(ctx context.Context, component, method string, handler func(context.Context) error) error {
+	m.mu.Lock()
+	mws := m.mws
+	m.mu.Unlock()
+	return runMiddleware(ctx, component, method, mws, handler)
+}
+
+// runMiddleware invokes mws[0], passing it a handler that invokes the rest of
+// the middleware and finally handler.
+func runMiddleware(ctx context.Context, component, method string, mws []ServerMiddleware, handler func(context.Context) error) error {
+	if len(mws) == 0 {
+		return handler(ctx)
+	}
+	return mws[0](ctx, component, method, func(ctx context.Context) error {
+		return runMiddleware(ctx, component, method, mws[1:], handler)
+	})
+}
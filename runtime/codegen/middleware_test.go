@@ -0,0 +1,90 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServerMiddlewareOrder(t *testing.T) {
+	type key struct{}
+	var order []string
+	named := func(name string) ServerMiddleware {
+		return func(ctx context.Context, component, method string, handler func(context.Context) error) error {
+			order = append(order, name+":"+component+"."+method)
+			prefix, _ := ctx.Value(key{}).(string)
+			return handler(context.WithValue(ctx, key{}, prefix+name))
+		}
+	}
+
+	var m ServerMiddlewares
+	m.Use(named("a"))
+	m.Use(named("b"))
+	m.Use(named("c"))
+	var got string
+	err := m.Run(context.Background(), "pkg/Foo", "Bar", func(ctx context.Context) error {
+		order = append(order, "method")
+		got, _ = ctx.Value(key{}).(string)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a:pkg/Foo.Bar", "b:pkg/Foo.Bar", "c:pkg/Foo.Bar", "method"}
+	if diff := cmp.Diff(want, order); diff != "" {
+		t.Errorf("order (-want +got):\n%s", diff)
+	}
+	if got != "abc" {
+		t.Errorf("context value: got %q, want %q", got, "abc")
+	}
+}
+
+func TestServerMiddlewareEmpty(t *testing.T) {
+	var m ServerMiddlewares
+	errMethod := errors.New("method")
+	err := m.Run(context.Background(), "pkg/Foo", "Bar", func(context.Context) error {
+		return errMethod
+	})
+	if !errors.Is(err, errMethod) {
+		t.Errorf("Run: got %v, want %v", err, errMethod)
+	}
+}
+
+func TestServerMiddlewareShortCircuit(t *testing.T) {
+	errDenied := errors.New("denied")
+	var m ServerMiddlewares
+	m.Use(func(ctx context.Context, _, _ string, _ func(context.Context) error) error {
+		return errDenied
+	})
+	called := false
+	m.Use(func(ctx context.Context, _, _ string, handler func(context.Context) error) error {
+		called = true
+		return handler(ctx)
+	})
+	err := m.Run(context.Background(), "pkg/Foo", "Bar", func(context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, errDenied) {
+		t.Errorf("Run: got %v, want %v", err, errDenied)
+	}
+	if called {
+		t.Error("Run: unexpectedly called the rest of the chain")
+	}
+}
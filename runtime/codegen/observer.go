@@ -0,0 +1,27 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+// Observer returns the observer of type *T of the provided component
+// implementation, i.e. the observer of its embedded weaver.WithObserver[T].
+// If impl doesn't embed a weaver.WithObserver[T], e.g., because impl is a fake
+// installed by weavertest, Observer returns a new zero-valued observer, so
+// that the component's calls are intercepted all the same.
+func Observer[T any](impl any) *T {
+	if o, ok := impl.(interface{ Observer() *T }); ok {
+		return o.Observer()
+	}
+	return new(T)
+}
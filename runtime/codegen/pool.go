@@ -0,0 +1,27 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import "github.com/ServiceWeaver/weaver/internal/bufpool"
+
+// ReleaseData returns the serialized results of a method, as returned by
+// Encoder.Data, to the pool of byte slices that encoders take their buffers
+// from. The caller must own data, and data must not be used after the call.
+//
+// NOTE that this function should only be called by the Service Weaver
+// runtime, on the results returned by a generated server stub.
+func ReleaseData(data []byte) {
+	bufpool.Put(data)
+}
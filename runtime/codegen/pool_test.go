@@ -0,0 +1,45 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkEncodeResults(b *testing.B) {
+	for _, size := range []int{100, 16 << 10, 1 << 20} {
+		data := make([]byte, size)
+		for _, release := range []bool{false, true} {
+			name := fmt.Sprintf("%d/NoRelease", size)
+			if release {
+				name = fmt.Sprintf("%d/Release", size)
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					// Encode results like a server stub does.
+					enc := NewEncoder()
+					enc.Reset(4 + len(data) + 8)
+					enc.Int(i)
+					enc.Bytes(data)
+					if results := enc.Data(); release {
+						ReleaseData(results)
+					}
+				}
+			})
+		}
+	}
+}
@@ -23,7 +23,6 @@ import (
 
 	"github.com/ServiceWeaver/weaver/internal/config"
 	"github.com/ServiceWeaver/weaver/runtime"
-	"github.com/ServiceWeaver/weaver/runtime/protos"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -64,6 +63,12 @@ type Registration struct {
 	ClientStubFn func(stub Stub, caller string) any
 	ServerStubFn func(impl any, load func(key uint64, load float64)) Server
 
+	// InterceptFn wraps a handle to the component, returned by LocalStubFn or
+	// ClientStubFn, so that its calls pass through the provided interceptor.
+	// It is nil for weaver.Main and for code generated by older versions of
+	// "weaver generate".
+	InterceptFn func(next any, interceptor Interceptor, call Call) any
+
 	// RefData holds a string containing the result of MakeEdgeString(Name, Dst)
 	// for all components named Dst used by this component.
 	RefData string
@@ -149,15 +154,86 @@ func ComponentConfigValidator(path, cfg string) error {
 		// Not for a known component.
 		return nil
 	}
+	sections := map[string]string{path: cfg}
+	timeouts, err := runtime.ParseMethodTimeouts(path, sections)
+	if err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+	for method := range timeouts {
+		if _, ok := info.Iface.MethodByName(method); !ok {
+			return fmt.Errorf("%v: bad config: %s: unknown method %q", info.Iface, runtime.MethodTimeoutsKey, method)
+		}
+	}
+
+	if _, err := runtime.ParseCompressMinBytes(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	if _, err := runtime.ParseMaxConcurrentCalls(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	if _, err := runtime.ParseCallQueue(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	if _, err := runtime.ParseEager(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	if _, err := runtime.ParseCrashOnPanic(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	if _, err := runtime.ParseExported(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	limits, err := runtime.ParseRateLimits(path, sections)
+	if err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+	for method := range limits {
+		if _, ok := info.Iface.MethodByName(method); !ok {
+			return fmt.Errorf("%v: bad config: %s: unknown method %q", info.Iface, runtime.RateLimitsKey, method)
+		}
+	}
+
+	allowed, err := runtime.ParseAllowedCallers(path, sections)
+	if err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+	for method := range allowed {
+		if _, ok := info.Iface.MethodByName(method); !ok && method != runtime.AllMethods {
+			return fmt.Errorf("%v: bad config: %s: unknown method %q", info.Iface, runtime.AllowedCallersKey, method)
+		}
+	}
+
+	if _, err := runtime.ParseEventQueue(path, sections); err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+
+	affinity, err := runtime.ParseAffinity(path, sections)
+	if err != nil {
+		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
+	}
+	if affinity != nil && !info.Routed {
+		return fmt.Errorf("%v: bad config: %s is only supported for routed components", info.Iface, runtime.AffinityKey)
+	}
+
 	componentConfig := config.Config(reflect.New(info.Impl))
 	if componentConfig == nil {
-		return fmt.Errorf("unexpected configuration for component %v "+
-			"that does not support configuration (add a "+
-			"weaver.WithConfig[configType] embedded field to %v)",
-			info.Name, info.Iface)
+		// A component without a config may only set the settings that
+		// Service Weaver reads itself, like method timeouts.
+		if err := runtime.ParseComponentConfig(path, sections, &struct{}{}); err != nil {
+			return fmt.Errorf("unexpected configuration for component %v "+
+				"that does not support configuration (add a "+
+				"weaver.WithConfig[configType] embedded field to %v)",
+				info.Name, info.Iface)
+		}
+		return nil
 	}
-	config := &protos.AppConfig{Sections: map[string]string{path: cfg}}
-	if err := runtime.ParseConfigSection(path, "", config.Sections, componentConfig); err != nil {
+	if err := runtime.ParseComponentConfig(path, sections, componentConfig); err != nil {
 		return fmt.Errorf("%v: bad config: %w", info.Iface, err)
 	}
 	return nil
@@ -182,7 +258,7 @@ func CallGraph() []CallEdge {
 			if ref.PkgPath() == "github.com/ServiceWeaver/weaver" &&
 				strings.HasPrefix(ref.Name(), "Ref[") &&
 				ref.Kind() == reflect.Struct &&
-				ref.NumField() == 1 &&
+				ref.NumField() == 4 &&
 				ref.Field(0).Name == "value" {
 				result = append(result, CallEdge{reg.Iface, ref.Field(0).Type})
 			}
@@ -15,6 +15,7 @@
 package codegen_test
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -27,8 +28,19 @@ import (
 )
 
 func TestComponentConfigValidator(t *testing.T) {
-	if err := codegen.ComponentConfigValidator(typeWithConfig, `Foo = "hello"`); err != nil {
-		t.Fatal(err)
+	for _, test := range []struct {
+		path   string
+		config string
+	}{
+		{typeWithConfig, `Foo = "hello"`},
+		{typeWithConfig, "Foo = \"hello\"\nmethod_timeouts = {Query = \"1s\"}"},
+		{typeWithoutConfig, `method_timeouts = {Query = "200ms"}`},
+		{typeWithoutConfig, `rate_limits = {Query = {qps = 10, burst = 2}}`},
+		{typeWithoutConfig, `allowed_callers = {Query = ["pkg/Admin"], "*" = ["external"]}`},
+	} {
+		if err := codegen.ComponentConfigValidator(test.path, test.config); err != nil {
+			t.Errorf("%s: %q: %v", test.path, test.config, err)
+		}
 	}
 }
 
@@ -54,6 +66,31 @@ func TestComponentConfigValidatorErrors(t *testing.T) {
 			config:        `Bar = -100`,
 			expectedError: "invalid value",
 		},
+		{
+			path:          typeWithoutConfig,
+			config:        `method_timeouts = {Search = "1s"}`,
+			expectedError: `unknown method "Search"`,
+		},
+		{
+			path:          typeWithConfig,
+			config:        `method_timeouts = {Query = "-1s"}`,
+			expectedError: "non-positive timeout",
+		},
+		{
+			path:          typeWithConfig,
+			config:        `rate_limits = {Search = {qps = 1}}`,
+			expectedError: `rate_limits: unknown method "Search"`,
+		},
+		{
+			path:          typeWithConfig,
+			config:        `allowed_callers = {Search = ["pkg/Admin"]}`,
+			expectedError: `allowed_callers: unknown method "Search"`,
+		},
+		{
+			path:          typeWithoutConfig,
+			config:        `affinity = {ttl = "1m"}`,
+			expectedError: "only supported for routed components",
+		},
 	} {
 		t.Run(test.expectedError, func(t *testing.T) {
 			err := codegen.ComponentConfigValidator(test.path, test.config)
@@ -85,10 +122,14 @@ const (
 	typeWithConfig    = "codegen_test/withConfig"
 )
 
-type componentWithoutConfig interface{}
+type componentWithoutConfig interface {
+	Query(context.Context) error
+}
 type componentWithoutConfigImpl struct{}
 
-type componentWithConfig interface{}
+type componentWithConfig interface {
+	Query(context.Context) error
+}
 type componentWithConfigImpl struct {
 	weaver.WithConfig[testconfig]
 }
@@ -0,0 +1,140 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+)
+
+// A component method that returns a stream (a weaver.Stream[T]) streams its
+// values to a remote caller in chunks:
+//
+//   - The first chunk is empty. It signals that the method returned without
+//     an error and started streaming. If the method returns an error instead,
+//     no chunks are streamed.
+//   - Every subsequent chunk holds a batch of values, encoded as the number of
+//     values followed by the values themselves.
+//
+// The serialized results of the method, sent after all chunks, hold the error
+// returned by the method or by the stream, if any.
+
+// streamChunkSize is the size above which a batch of streamed values is sent.
+const streamChunkSize = 32 << 10
+
+// WriteStream streams the values of the provided stream, the result of a
+// method that returns a stream, using send. Values are encoded with encode.
+// It returns the error returned by the stream, or the error returned by send
+// if the values could not be sent.
+func WriteStream[T any](send func([]byte) error, stream func(yield func(T) bool) error, encode func(*Encoder, T)) error {
+	// Signal the start of the stream.
+	if err := send(nil); err != nil {
+		return err
+	}
+	if stream == nil {
+		return nil
+	}
+
+	enc := NewEncoder()
+	n := 0
+	enc.Len(0) // updated by flush
+	flush := func() error {
+		binary.LittleEndian.PutUint32(enc.Data(), uint32(n))
+		err := send(enc.Data())
+		enc.Reset(0)
+		enc.Len(0)
+		n = 0
+		return err
+	}
+
+	var sendErr error
+	err := stream(func(x T) bool {
+		encode(enc, x)
+		n++
+		if len(enc.Data()) >= streamChunkSize {
+			sendErr = flush()
+		}
+		return sendErr == nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if n > 0 {
+		// Send the values yielded before the stream ended, even if it failed.
+		if sendErr := flush(); sendErr != nil {
+			return sendErr
+		}
+	}
+	return err
+}
+
+// ReadStream returns a function that iterates over the values streamed by a
+// remote method that returns a stream, once the method has started streaming.
+// Values are decoded with decode.
+//
+// end is called when the iteration finishes, with the number of bytes read,
+// the serialized results of the method, and the error, if any, encountered
+// while reading the stream. If the iteration was stopped early, the method is
+// canceled and end is called with nil results. The error returned by end is
+// returned by the iteration.
+func ReadStream[T any](stream StreamReader, decode func(*Decoder) T, end func(n int, results []byte, err error) error) func(yield func(T) bool) error {
+	var iterated atomic.Bool
+	return func(yield func(T) bool) error {
+		if !iterated.CompareAndSwap(false, true) {
+			return fmt.Errorf("stream iterated more than once")
+		}
+		defer stream.Close()
+
+		n := 0
+		for {
+			chunk, ok := stream.Recv()
+			if !ok {
+				break
+			}
+			n += len(chunk)
+			values, err := decodeChunk(chunk, decode)
+			if err != nil {
+				return end(n, nil, err)
+			}
+			for _, x := range values {
+				if !yield(x) {
+					return end(n, nil, nil)
+				}
+			}
+		}
+		results, err := stream.Result()
+		return end(n+len(results), results, err)
+	}
+}
+
+// decodeChunk decodes the values in a chunk of a stream.
+func decodeChunk[T any](chunk []byte, decode func(*Decoder) T) (values []T, err error) {
+	defer func() {
+		if err == nil {
+			err = CatchPanics(recover())
+		}
+	}()
+	dec := NewDecoder(chunk)
+	n := dec.Len()
+	if n < 0 {
+		return nil, makeDecodeError("invalid number of streamed values %d", n)
+	}
+	values = make([]T, n)
+	for i := range values {
+		values[i] = decode(dec)
+	}
+	return values, nil
+}
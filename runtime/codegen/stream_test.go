@@ -0,0 +1,188 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeStream is a StreamReader that reads the chunks recorded by send.
+type fakeStream struct {
+	chunks  [][]byte
+	results []byte
+	closed  bool
+}
+
+var _ StreamReader = &fakeStream{}
+
+func (f *fakeStream) send(chunk []byte) error {
+	f.chunks = append(f.chunks, append([]byte(nil), chunk...))
+	return nil
+}
+
+func (f *fakeStream) Recv() ([]byte, bool) {
+	if len(f.chunks) == 0 {
+		return nil, false
+	}
+	chunk := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return chunk, true
+}
+
+func (f *fakeStream) Result() ([]byte, error) { return f.results, nil }
+func (f *fakeStream) Close()                  { f.closed = true }
+
+// streamOf returns a stream of n strings, failing at the end if fail is true.
+func streamOf(n int, fail bool) func(yield func(string) bool) error {
+	return func(yield func(string) bool) error {
+		for i := 0; i < n; i++ {
+			if !yield(strings.Repeat("x", i)) {
+				return nil
+			}
+		}
+		if fail {
+			return fmt.Errorf("stream failed")
+		}
+		return nil
+	}
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		n      int
+		fail   bool
+		chunks int // minimum number of chunks
+	}{
+		{"Empty", 0, false, 1},
+		{"One", 1, false, 2},
+		{"Many", 1000, false, 10},
+		{"Fail", 1000, true, 10},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Write the stream.
+			f := &fakeStream{}
+			err := WriteStream(f.send, streamOf(test.n, test.fail), (*Encoder).String)
+			if test.fail != (err != nil) {
+				t.Fatalf("WriteStream: unexpected error %v", err)
+			}
+			if len(f.chunks) < test.chunks {
+				t.Fatalf("got %d chunks, want at least %d", len(f.chunks), test.chunks)
+			}
+			if len(f.chunks[0]) != 0 {
+				t.Fatalf("non-empty first chunk %v", f.chunks[0])
+			}
+			bytes := 0
+			for _, chunk := range f.chunks {
+				bytes += len(chunk)
+			}
+			enc := NewEncoder()
+			enc.Error(err)
+			f.results = enc.Data()
+
+			// Read the stream back.
+			f.Recv() // skip the first chunk
+			var got []string
+			var endBytes int
+			stream := ReadStream(f, (*Decoder).String, func(n int, results []byte, err error) error {
+				endBytes = n
+				if err != nil {
+					return err
+				}
+				return NewDecoder(results).Error()
+			})
+			err = stream(func(s string) bool {
+				got = append(got, s)
+				return true
+			})
+			if test.fail != (err != nil) {
+				t.Fatalf("ReadStream: unexpected error %v", err)
+			}
+			if len(got) != test.n {
+				t.Fatalf("got %d values, want %d", len(got), test.n)
+			}
+			for i, s := range got {
+				if s != strings.Repeat("x", i) {
+					t.Fatalf("value %d: got %q", i, s)
+				}
+			}
+			if want := bytes + len(f.results); endBytes != want {
+				t.Errorf("end: got %d bytes, want %d", endBytes, want)
+			}
+			if !f.closed {
+				t.Error("stream not closed")
+			}
+
+			// A stream can be iterated only once.
+			if err := stream(func(string) bool { return true }); err == nil {
+				t.Error("unexpected success iterating stream twice")
+			}
+		})
+	}
+}
+
+func TestStreamStop(t *testing.T) {
+	f := &fakeStream{}
+	if err := WriteStream(f.send, streamOf(100, false), (*Encoder).String); err != nil {
+		t.Fatal(err)
+	}
+	f.Recv() // skip the first chunk
+
+	ended := false
+	stream := ReadStream(f, (*Decoder).String, func(n int, results []byte, err error) error {
+		ended = true
+		if results != nil || err != nil {
+			t.Errorf("end: got (%v, %v), want (nil, nil)", results, err)
+		}
+		return nil
+	})
+	i := 0
+	if err := stream(func(string) bool { i++; return i < 10 }); err != nil {
+		t.Fatal(err)
+	}
+	if i != 10 {
+		t.Errorf("got %d values, want 10", i)
+	}
+	if !ended || !f.closed {
+		t.Errorf("stream not ended and closed")
+	}
+}
+
+func TestWriteStreamSendError(t *testing.T) {
+	// Send fails once values are streamed. The stream should be stopped.
+	sendErr := errors.New("send failed")
+	send := func(chunk []byte) error {
+		if len(chunk) > 0 {
+			return sendErr
+		}
+		return nil
+	}
+	stopped := false
+	stream := func(yield func(string) bool) error {
+		for yield(strings.Repeat("x", 1000)) {
+		}
+		stopped = true
+		return nil
+	}
+	if err := WriteStream(send, stream, (*Encoder).String); !errors.Is(err, sendErr) {
+		t.Fatalf("WriteStream: got %v, want %v", err, sendErr)
+	}
+	if !stopped {
+		t.Fatal("stream not stopped")
+	}
+}
@@ -35,6 +35,31 @@ type Stub interface {
 	// serialized arguments and results, respectively. shardKey is the shard
 	// key for routed components, and 0 otherwise.
 	Run(ctx context.Context, method int, args []byte, shardKey uint64) (results []byte, err error)
+
+	// RunBuffers is like Run, but the serialized arguments are the
+	// concatenation of args, as returned by Encoder.Buffers. The slices in
+	// args are sent without being copied.
+	RunBuffers(ctx context.Context, method int, args [][]byte, shardKey uint64) (results []byte, err error)
+
+	// RunStream is like Run, but for methods that return a stream. The
+	// streamed data and the serialized results are read from the returned
+	// StreamReader.
+	RunStream(ctx context.Context, method int, args []byte, shardKey uint64) (StreamReader, error)
+}
+
+// A StreamReader reads the data streamed by a remote method that returns a
+// stream. It is not safe for concurrent use.
+type StreamReader interface {
+	// Recv returns the next chunk of streamed data, blocking until one is
+	// available. It returns false once the method has finished.
+	Recv() ([]byte, bool)
+
+	// Result returns the serialized results of the method. It must be
+	// called only after Recv returns false.
+	Result() ([]byte, error)
+
+	// Close cancels the method if it hasn't finished yet.
+	Close()
 }
 
 // A Server allows a Service Weaver component in one process to receive and execute
@@ -44,8 +69,28 @@ type Server interface {
 	// GetStubFn returns a handler function for the given method. For example,
 	// if a Service Weaver component defined an Echo method, then GetStubFn("Echo")
 	// would return a handler that deserializes the arguments, executes the
-	// method, and serializes the results.
+	// method, and serializes the results. The handler must not retain the
+	// serialized results it returns, since the runtime may reuse their
+	// memory once it has sent them (see ReleaseData).
 	//
 	// TODO(mwhittaker): Rename GetHandler? This is returning a call.Handler.
 	GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error)
+
+	// Use registers middleware that wraps every method executed by the
+	// handlers returned by GetStubFn (and GetStreamFn, for a StreamServer).
+	// Middleware is called in the order it was registered, after the
+	// arguments are decoded and before the method runs. Use should be called
+	// before the server receives any calls.
+	Use(mw ServerMiddleware)
+}
+
+// A StreamServer is a Server for a component with methods that return a
+// stream.
+type StreamServer interface {
+	Server
+
+	// GetStreamFn returns a handler function for the given method, which
+	// must return a stream. The handler streams the method's results by
+	// calling send. See GetStubFn.
+	GetStreamFn(method string) func(ctx context.Context, args []byte, send func([]byte) error) ([]byte, error)
 }
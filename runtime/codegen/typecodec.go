@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// typeCodecs maps every type T registered with RegisterTypeCodec to its
+// *typeCodec[T]. It is written during initialization and read on every
+// encode and decode of a registered type, which is what sync.Map is good at.
+var typeCodecs sync.Map
+
+// typeCodec holds the functions registered for type T.
+type typeCodec[T any] struct {
+	marshal   func(*Encoder, T)
+	unmarshal func(*Decoder) T
+}
+
+// RegisterTypeCodec registers functions to encode and decode values of type T,
+// allowing T to be used in component method arguments and results, and in the
+// fields of weaver.AutoMarshal structs, even if it is not otherwise
+// serializable. This is useful for third-party value types that Service Weaver
+// cannot serialize on its own, such as uuid.UUID or decimal.Decimal:
+//
+//	func init() {
+//	    codegen.RegisterTypeCodec(
+//	        func(enc *codegen.Encoder, id uuid.UUID) { enc.Bytes(id[:]) },
+//	        func(dec *codegen.Decoder) uuid.UUID { return uuid.UUID(dec.Bytes()) },
+//	    )
+//	}
+//
+// T must be a named type, and RegisterTypeCodec must be called directly in an
+// init function of a package processed by "weaver generate". The code
+// generator detects these calls and makes the generated code use the
+// registered functions for values of type T. RegisterTypeCodec panics if a
+// codec for T is already registered.
+func RegisterTypeCodec[T any](marshal func(*Encoder, T), unmarshal func(*Decoder) T) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	c := &typeCodec[T]{marshal: marshal, unmarshal: unmarshal}
+	if _, loaded := typeCodecs.LoadOrStore(t, c); loaded {
+		panic(fmt.Errorf("RegisterTypeCodec: codec for type %v already registered", t))
+	}
+}
+
+// findTypeCodec returns the codec registered for type T, or nil if there is
+// none.
+func findTypeCodec[T any]() *typeCodec[T] {
+	c, ok := typeCodecs.Load(reflect.TypeOf((*T)(nil)).Elem())
+	if !ok {
+		return nil
+	}
+	return c.(*typeCodec[T])
+}
+
+// EncodeRegistered encodes v using the codec registered for type T with
+// RegisterTypeCodec.
+func EncodeRegistered[T any](enc *Encoder, v T) {
+	c := findTypeCodec[T]()
+	if c == nil {
+		panic(makeEncodeError("no codec registered for type %v", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	c.marshal(enc, v)
+}
+
+// DecodeRegistered decodes a value into v using the codec registered for type
+// T with RegisterTypeCodec.
+func DecodeRegistered[T any](dec *Decoder, v *T) {
+	c := findTypeCodec[T]()
+	if c == nil {
+		panic(makeDecodeError("no codec registered for type %v", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	*v = c.unmarshal(dec)
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+type celsius float64
+type fahrenheit float64
+type kelvin float64
+
+func init() {
+	// Temperatures in celsius are encoded in tenths of a degree.
+	RegisterTypeCodec(
+		func(enc *Encoder, c celsius) { enc.Int64(int64(c * 10)) },
+		func(dec *Decoder) celsius { return celsius(dec.Int64()) / 10 },
+	)
+}
+
+func TestTypeCodecRoundTrip(t *testing.T) {
+	enc := NewEncoder()
+	EncodeRegistered(enc, celsius(21.5))
+	EncodeRegistered(enc, celsius(-3))
+
+	dec := NewDecoder(enc.Data())
+	var a, b celsius
+	DecodeRegistered(dec, &a)
+	DecodeRegistered(dec, &b)
+	if a != 21.5 || b != -3 {
+		t.Fatalf("got %v and %v, want 21.5 and -3", a, b)
+	}
+}
+
+func TestTypeCodecDuplicate(t *testing.T) {
+	register := func() {
+		RegisterTypeCodec(
+			func(enc *Encoder, f fahrenheit) { enc.Float64(float64(f)) },
+			func(dec *Decoder) fahrenheit { return fahrenheit(dec.Float64()) },
+		)
+	}
+	register()
+	defer func() {
+		err, ok := recover().(error)
+		if !ok || !strings.Contains(err.Error(), "already registered") {
+			t.Fatalf("got panic %v, want already registered", err)
+		}
+	}()
+	register()
+}
+
+func TestTypeCodecUnregistered(t *testing.T) {
+	catch := func(f func()) (err error) {
+		defer func() { err = CatchPanics(recover()) }()
+		f()
+		return nil
+	}
+	err := catch(func() { EncodeRegistered(NewEncoder(), kelvin(0)) })
+	if err == nil || !strings.Contains(err.Error(), "no codec registered") {
+		t.Errorf("EncodeRegistered: got %v, want no codec registered", err)
+	}
+	err = catch(func() {
+		var k kelvin
+		DecodeRegistered(NewDecoder(nil), &k)
+	})
+	if err == nil || !strings.Contains(err.Error(), "no codec registered") {
+		t.Errorf("DecodeRegistered: got %v, want no codec registered", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Structs that embed weaver.AutoMarshal with a `weaver:"versioned"` struct tag
+// use a versioned encoding that tolerates changes to the set of fields in the
+// struct. Such a struct is encoded as the number of encoded fields followed by
+// every field, where a field is encoded as its name followed by the
+// length-prefixed encoding of its value:
+//
+//	struct { X int; Y string }{1, "a"}
+//	=> 2, "X", 8, <1>, "Y", 5, <"a">
+//
+// A decoder skips fields it doesn't know about and leaves fields that are
+// missing from the encoding with their zero value. Because fields are
+// identified by name, fields can also be reordered.
+
+// BeginField begins the encoding of the field with the provided name of a
+// struct with a versioned encoding. It returns a value that must be passed to
+// EndField after the field's value has been encoded.
+func (e *Encoder) BeginField(name string) int {
+	e.String(name)
+	e.fields++
+	start := len(e.data)
+	e.Grow(4) // filled in by EndField
+	return start
+}
+
+// EndField ends the encoding of a field begun with BeginField.
+func (e *Encoder) EndField(start int) {
+	e.fields--
+	n := len(e.data) - start - 4
+	if n > math.MaxUint32 {
+		panic(makeEncodeError("unable to encode field; length doesn't fit in 4 bytes"))
+	}
+	binary.LittleEndian.PutUint32(e.data[start:], uint32(n))
+}
+
+// Field decodes a field of a struct with a versioned encoding. It returns the
+// name of the field and a decoder for the field's value. The value need not
+// be decoded, in which case it is skipped.
+func (d *Decoder) Field() (string, *Decoder) {
+	name := d.String()
+	n := d.Uint32()
+	return name, NewDecoder(d.Read(int(n)))
+}
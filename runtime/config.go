@@ -26,26 +26,36 @@ import (
 )
 
 // ParseConfig parses the specified configuration input, which should
-// hold a set of sections in TOML format from the specified file.
-// The section corresponding to the common Service Weaver application
-// configuration is parsed and returned as a *AppConfig.
+// hold a set of sections in TOML format from the specified file, or in YAML
+// format if the file has a .yaml or .yml extension. The sections are
+// returned in TOML format either way. The section corresponding to the common
+// Service Weaver application configuration is parsed and returned as a
+// *AppConfig.
 //
 // sectionValidator(key, val) is used to validate every section config entry.
 func ParseConfig(file string, input string, sectionValidator func(string, string) error) (*protos.AppConfig, error) {
-	// Extract sections from toml file.
-	var sections map[string]toml.Primitive
-	_, err := toml.Decode(input, &sections)
-	if err != nil {
-		return nil, err
-	}
 	config := &protos.AppConfig{Sections: map[string]string{}}
-	for k, v := range sections {
-		var buf strings.Builder
-		err := toml.NewEncoder(&buf).Encode(v)
+	if isYAMLConfig(file) {
+		sections, err := yamlSections(input)
 		if err != nil {
-			return nil, fmt.Errorf("encoding section %q: %w", k, err)
+			return nil, fmt.Errorf("parse YAML config %q: %w", file, err)
+		}
+		config.Sections = sections
+	} else {
+		// Extract sections from toml file.
+		var sections map[string]toml.Primitive
+		_, err := toml.Decode(input, &sections)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range sections {
+			var buf strings.Builder
+			err := toml.NewEncoder(&buf).Encode(v)
+			if err != nil {
+				return nil, fmt.Errorf("encoding section %q: %w", k, err)
+			}
+			config.Sections[k] = buf.String()
 		}
-		config.Sections[k] = buf.String()
 	}
 
 	// Parse app section.
@@ -80,12 +90,23 @@ func ParseConfigSection(key, shortKey string, sections map[string]string, dst an
 		return nil
 	}
 
-	// Parse and validate the section.
-	md, err := toml.Decode(section, dst)
+	return parseSection(key, section, dst, nil)
+}
+
+// parseSection parses and validates the provided section, whose key is key,
+// into dst. Settings under the reserved keys are ignored.
+func parseSection(key, section string, dst any, reserved map[string]bool) error {
+	md, err := decodeSection(section, dst)
 	if err != nil {
 		return err
 	}
-	if unknown := md.Undecoded(); len(unknown) != 0 {
+	var unknown []toml.Key
+	for _, k := range md.Undecoded() {
+		if !reserved[k[0]] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) != 0 {
 		return fmt.Errorf("section %q has unknown keys %v", key, unknown)
 	}
 	if x, ok := dst.(interface{ Validate() error }); ok {
@@ -96,21 +117,726 @@ func ParseConfigSection(key, shortKey string, sections map[string]string, dst an
 	return nil
 }
 
-func extractApp(file string, config *protos.AppConfig) error {
-	const appKey = "github.com/ServiceWeaver/weaver"
-	const shortAppKey = "serviceweaver"
+// BaseConfigKey is the key of the config section that holds settings shared by
+// the configs of all components. See ParseComponentConfig.
+const BaseConfigKey = "base"
+
+// ParseComponentConfig parses the config of the component with the provided
+// full name into dst. Every field of dst is set from the component's section
+// if the section sets it, or else from the base section (see BaseConfigKey)
+// if that sets it. Other fields are left unchanged. Settings of the base
+// section that don't correspond to fields of dst are ignored, since the base
+// section is shared by components with different configs. Base sections
+// don't nest: a component's config has at most one base. Settings are
+// matched to the fields of dst by name, then by toml tag, then by yaml tag.
+// Settings that Service Weaver reads itself, like MethodTimeoutsKey and
+// EagerKey, are not parsed into dst.
+//
+// Before the sections are parsed, fields of dst with a DefaultTag are set to
+// their defaults. A field is thus set to the value in the config, if any, then
+// to its default, if any, and is otherwise left unchanged. Note that a value of
+// zero in the config overrides the default.
+func ParseComponentConfig(component string, sections map[string]string, dst any) error {
+	if err := setConfigDefaults(dst); err != nil {
+		return fmt.Errorf("component %q: %w", component, err)
+	}
+	section, ok := sections[component]
+	base, hasBase := sections[BaseConfigKey]
+	if !hasBase {
+		if !ok {
+			return nil
+		}
+		return parseSection(component, section, dst, componentSettingKeys)
+	}
+	if _, err := decodeSection(base, dst); err != nil {
+		return fmt.Errorf("section %q: %w", BaseConfigKey, err)
+	}
+	if ok {
+		return parseSection(component, section, dst, componentSettingKeys)
+	}
+	// The component has no section of its own, so validate the settings it
+	// inherited from the base section.
+	if x, ok := dst.(interface{ Validate() error }); ok {
+		if err := x.Validate(); err != nil {
+			return fmt.Errorf("section %q: %w", BaseConfigKey, err)
+		}
+	}
+	return nil
+}
+
+// MethodTimeoutsKey is the key, in the config section of a component, of the
+// timeouts of the component's methods, keyed by method name. For example:
+//
+//	["github.com/example/search/Search"]
+//	method_timeouts = {Query = "200ms", Reindex = "10m"}
+//
+// See ParseMethodTimeouts.
+const MethodTimeoutsKey = "method_timeouts"
+
+// ParseMethodTimeouts returns the method timeouts listed in the config
+// section of the component with the provided full name, or nil if there are
+// none. It doesn't check that the methods exist.
+func ParseMethodTimeouts(component string, sections map[string]string) (map[string]time.Duration, error) {
+	section, ok := sections[component]
+	if !ok {
+		return nil, nil
+	}
+	var config struct {
+		MethodTimeouts map[string]time.Duration `toml:"method_timeouts"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return nil, fmt.Errorf("section %q: %w", component, err)
+	}
+	for method, timeout := range config.MethodTimeouts {
+		if timeout <= 0 {
+			return nil, fmt.Errorf("section %q: %s: non-positive timeout %v for method %q", component, MethodTimeoutsKey, timeout, method)
+		}
+	}
+	return config.MethodTimeouts, nil
+}
+
+// MetricsWindowKey is the key, in the config section of a component, of the
+// duration of the sliding window over which the recent invocations and errors
+// of the component's methods are counted. For example:
+//
+//	["github.com/example/search/Search"]
+//	metrics_window = "1m"
+//
+// See ParseMetricsWindow and codegen.MethodMetricsOptions.
+const MetricsWindowKey = "metrics_window"
+
+// ParseMetricsWindow returns the sliding window duration listed in the config
+// section of the component with the provided full name, or 0 if recent
+// invocations of the component's methods are not counted.
+func ParseMetricsWindow(component string, sections map[string]string) (time.Duration, error) {
+	section, ok := sections[component]
+	if !ok {
+		return 0, nil
+	}
+	var config struct {
+		MetricsWindow time.Duration `toml:"metrics_window"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return 0, fmt.Errorf("section %q: %w", component, err)
+	}
+	if config.MetricsWindow < 0 {
+		return 0, fmt.Errorf("section %q: negative %s %v", component, MetricsWindowKey, config.MetricsWindow)
+	}
+	return config.MetricsWindow, nil
+}
+
+// CompressMinBytesKey is the key, in the config section of a component, of
+// the minimum size, in bytes, of the compressed arguments and results of
+// remote calls to the component's methods. For example:
+//
+//	["github.com/example/search/Search"]
+//	compress_min_bytes = 4096
+//
+// See ParseCompressMinBytes.
+const CompressMinBytesKey = "compress_min_bytes"
+
+// ParseCompressMinBytes returns the compression threshold listed in the config
+// section of the component with the provided full name, or 0 if calls to the
+// component are not compressed.
+func ParseCompressMinBytes(component string, sections map[string]string) (int, error) {
+	section, ok := sections[component]
+	if !ok {
+		return 0, nil
+	}
+	var config struct {
+		CompressMinBytes int `toml:"compress_min_bytes"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return 0, fmt.Errorf("section %q: %w", component, err)
+	}
+	if config.CompressMinBytes < 0 {
+		return 0, fmt.Errorf("section %q: negative %s %d", component, CompressMinBytesKey, config.CompressMinBytes)
+	}
+	return config.CompressMinBytes, nil
+}
+
+// MaxConcurrentCallsKey is the key, in the config section of a component, of
+// the maximum number of remote calls that every replica of the component
+// executes at once. For example:
+//
+//	["github.com/example/search/Search"]
+//	max_concurrent_calls = 64
+//
+// See ParseMaxConcurrentCalls.
+const MaxConcurrentCallsKey = "max_concurrent_calls"
+
+// ParseMaxConcurrentCalls returns the concurrency limit listed in the config
+// section of the component with the provided full name, or 0 if the number of
+// concurrent calls to the component is not limited.
+func ParseMaxConcurrentCalls(component string, sections map[string]string) (int, error) {
+	section, ok := sections[component]
+	if !ok {
+		return 0, nil
+	}
+	var config struct {
+		MaxConcurrentCalls int `toml:"max_concurrent_calls"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return 0, fmt.Errorf("section %q: %w", component, err)
+	}
+	if config.MaxConcurrentCalls < 0 {
+		return 0, fmt.Errorf("section %q: negative %s %d", component, MaxConcurrentCallsKey, config.MaxConcurrentCalls)
+	}
+	return config.MaxConcurrentCalls, nil
+}
+
+// MaxQueuedCallsKey and BusyPolicyKey are the keys, in the config section of
+// a component, of what a replica of the component does with the remote calls
+// it receives while it is executing max_concurrent_calls calls. For example:
+//
+//	["github.com/example/search/Search"]
+//	max_concurrent_calls = 64
+//	max_queued_calls = 256
+//
+// See ParseCallQueue.
+const (
+	MaxQueuedCallsKey = "max_queued_calls"
+	BusyPolicyKey     = "busy_policy"
+)
+
+// Busy policies. See CallQueue.Policy.
+const (
+	BusyQueue  = "queue"
+	BusyReject = "reject"
+)
+
+// CallQueue says what a replica of a component does with the remote calls it
+// receives while it is executing as many calls as the component's
+// max_concurrent_calls allows.
+type CallQueue struct {
+	// BusyQueue queues the calls, and BusyReject fails them right away.
+	Policy string
+
+	// The maximum number of calls queued at once, when Policy is BusyQueue.
+	// Calls received while the queue is full fail right away. Zero means the
+	// queue is unbounded.
+	MaxQueued int
+}
+
+// ParseCallQueue returns the call queue settings listed in the config section
+// of the component with the provided full name. Unset settings are filled in
+// with their defaults: an unbounded queue. It is an error to set either
+// setting without max_concurrent_calls, or to bound the queue of a component
+// that doesn't queue calls.
+func ParseCallQueue(component string, sections map[string]string) (CallQueue, error) {
+	queue := CallQueue{Policy: BusyQueue}
+	section, ok := sections[component]
+	if !ok {
+		return queue, nil
+	}
+	var config struct {
+		MaxConcurrentCalls int    `toml:"max_concurrent_calls"`
+		MaxQueuedCalls     int    `toml:"max_queued_calls"`
+		BusyPolicy         string `toml:"busy_policy"`
+	}
+	md, err := toml.Decode(section, &config)
+	if err != nil {
+		return CallQueue{}, fmt.Errorf("section %q: %w", component, err)
+	}
+	if !md.IsDefined(MaxQueuedCallsKey) && !md.IsDefined(BusyPolicyKey) {
+		return queue, nil
+	}
+	if config.MaxConcurrentCalls <= 0 {
+		return CallQueue{}, fmt.Errorf("section %q: %s and %s require a positive %s", component, MaxQueuedCallsKey, BusyPolicyKey, MaxConcurrentCallsKey)
+	}
+	switch config.BusyPolicy {
+	case "", BusyQueue:
+	case BusyReject:
+		queue.Policy = BusyReject
+	default:
+		return CallQueue{}, fmt.Errorf("section %q: %s %q is not %q or %q", component, BusyPolicyKey, config.BusyPolicy, BusyQueue, BusyReject)
+	}
+	if config.MaxQueuedCalls < 0 {
+		return CallQueue{}, fmt.Errorf("section %q: negative %s %d", component, MaxQueuedCallsKey, config.MaxQueuedCalls)
+	}
+	if config.MaxQueuedCalls > 0 && queue.Policy == BusyReject {
+		return CallQueue{}, fmt.Errorf("section %q: %s is set, but %s is %q", component, MaxQueuedCallsKey, BusyPolicyKey, BusyReject)
+	}
+	queue.MaxQueued = config.MaxQueuedCalls
+	return queue, nil
+}
+
+// EagerKey is the key, in the config section of a component, of whether the
+// component is created when a process that hosts it starts, rather than when
+// it is first used. For example:
+//
+//	["github.com/example/cache/Cache"]
+//	eager = true
+//
+// See ParseEager.
+const EagerKey = "eager"
+
+// ParseEager returns whether the config section of the component with the
+// provided full name marks the component as eager.
+func ParseEager(component string, sections map[string]string) (bool, error) {
+	section, ok := sections[component]
+	if !ok {
+		return false, nil
+	}
+	var config struct {
+		Eager bool `toml:"eager"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return false, fmt.Errorf("section %q: %w", component, err)
+	}
+	return config.Eager, nil
+}
+
+// CrashOnPanicKey is the key, in the config section of a component, of
+// whether a panic in a remote call to one of the component's methods crashes
+// the process, rather than failing the call. For example:
+//
+//	["github.com/example/cache/Cache"]
+//	crash_on_panic = true
+//
+// See ParseCrashOnPanic.
+const CrashOnPanicKey = "crash_on_panic"
+
+// ParseCrashOnPanic returns whether the config section of the component with
+// the provided full name asks for panics in remote calls to the component to
+// crash the process.
+func ParseCrashOnPanic(component string, sections map[string]string) (bool, error) {
+	section, ok := sections[component]
+	if !ok {
+		return false, nil
+	}
+	var config struct {
+		CrashOnPanic bool `toml:"crash_on_panic"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return false, fmt.Errorf("section %q: %w", component, err)
+	}
+	return config.CrashOnPanic, nil
+}
+
+// ExportedKey is the key, in the config section of a component, of whether
+// the component can be called by other applications, through a
+// weaver.ExternalRef. For example:
+//
+//	["github.com/example/inventory/Catalog"]
+//	exported = true
+//
+// See ParseExported.
+const ExportedKey = "exported"
+
+// ParseExported returns whether the config section of the component with the
+// provided full name exports the component to other applications.
+func ParseExported(component string, sections map[string]string) (bool, error) {
+	section, ok := sections[component]
+	if !ok {
+		return false, nil
+	}
+	var config struct {
+		Exported bool `toml:"exported"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return false, fmt.Errorf("section %q: %w", component, err)
+	}
+	return config.Exported, nil
+}
+
+// RateLimitsKey is the key, in the config section of a component, of the
+// rate limits of the component's methods, keyed by method name. For example:
+//
+//	["github.com/example/mail/Mailer"]
+//	rate_limits = {Send = {qps = 50, burst = 10, mode = "reject"}}
+//
+// See ParseRateLimits.
+const RateLimitsKey = "rate_limits"
+
+// Rate limit modes. See RateLimit.Mode.
+const (
+	RateLimitReject = "reject"
+	RateLimitWait   = "wait"
+)
+
+// RateLimit is the rate limit of a component method, enforced separately by
+// every replica of the component.
+type RateLimit struct {
+	// The maximum sustained rate of calls per second. Zero disables the
+	// limit.
+	QPS float64 `toml:"qps"`
+
+	// The maximum number of calls admitted at once after a quiet period. If
+	// zero, it defaults to one.
+	Burst int `toml:"burst"`
+
+	// What to do with calls over the limit: RateLimitReject fails them right
+	// away, and RateLimitWait delays them until they fit under the limit, or
+	// fails them if they can't before their deadline. If empty, it defaults
+	// to RateLimitReject.
+	Mode string `toml:"mode"`
+}
+
+// ParseRateLimits returns the method rate limits listed in the config section
+// of the component with the provided full name, or nil if there are none.
+// Unset fields are filled in with their defaults. It doesn't check that the
+// methods exist.
+func ParseRateLimits(component string, sections map[string]string) (map[string]RateLimit, error) {
+	section, ok := sections[component]
+	if !ok {
+		return nil, nil
+	}
+	var config struct {
+		RateLimits map[string]RateLimit `toml:"rate_limits"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return nil, fmt.Errorf("section %q: %w", component, err)
+	}
+	for method, limit := range config.RateLimits {
+		switch {
+		case limit.QPS < 0:
+			return nil, fmt.Errorf("section %q: %s: negative qps %v for method %q", component, RateLimitsKey, limit.QPS, method)
+		case limit.Burst < 0:
+			return nil, fmt.Errorf("section %q: %s: negative burst %d for method %q", component, RateLimitsKey, limit.Burst, method)
+		}
+		switch limit.Mode {
+		case "":
+			limit.Mode = RateLimitReject
+		case RateLimitReject, RateLimitWait:
+		default:
+			return nil, fmt.Errorf("section %q: %s: mode %q for method %q is not %q or %q", component, RateLimitsKey, limit.Mode, method, RateLimitReject, RateLimitWait)
+		}
+		if limit.Burst == 0 {
+			limit.Burst = 1
+		}
+		config.RateLimits[method] = limit
+	}
+	return config.RateLimits, nil
+}
+
+// AllowedCallersKey is the key, in the config section of a component, of the
+// callers allowed to call the component's methods, keyed by method name. A
+// caller is the full name of a component, or ExternalCaller. The method
+// AllMethods applies to the methods without an entry of their own. For
+// example:
+//
+//	["github.com/example/inventory/Catalog"]
+//	allowed_callers = {Delete = ["github.com/example/inventory/Admin"], "*" = ["github.com/example/shop/Frontend", "external"]}
+//
+// See ParseAllowedCallers.
+const AllowedCallersKey = "allowed_callers"
+
+const (
+	// ExternalCaller, listed as an allowed caller, allows calls from callers
+	// that are not components of the application, e.g., the components of
+	// other applications that call the component through a
+	// weaver.ExternalRef.
+	ExternalCaller = "external"
+
+	// AllMethods, used as a method name under AllowedCallersKey, lists the
+	// allowed callers of the methods that aren't listed by name.
+	AllMethods = "*"
+)
 
-	// appConfig holds the data from under appKey in the TOML config.
-	// It matches the contents of the Config proto.
-	type appConfig struct {
-		Name     string
-		Binary   string
-		Args     []string
-		Env      []string
-		Colocate [][]string
-		Rollout  time.Duration
+// ParseAllowedCallers returns the allowed callers of the methods listed in
+// the config section of the component with the provided full name, or nil if
+// there are none. It doesn't check that the methods exist.
+func ParseAllowedCallers(component string, sections map[string]string) (map[string][]string, error) {
+	section, ok := sections[component]
+	if !ok {
+		return nil, nil
+	}
+	var config struct {
+		AllowedCallers map[string][]string `toml:"allowed_callers"`
 	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return nil, fmt.Errorf("section %q: %w", component, err)
+	}
+	for method, callers := range config.AllowedCallers {
+		for _, caller := range callers {
+			if caller == "" {
+				return nil, fmt.Errorf("section %q: %s: empty caller for method %q", component, AllowedCallersKey, method)
+			}
+		}
+	}
+	return config.AllowedCallers, nil
+}
 
+// AffinityKey is the key, in the config section of a routed component, of
+// the session affinity settings of calls to the component. For example:
+//
+//	["github.com/example/chat/Session"]
+//	affinity = {ttl = "10m", size = 10000}
+//
+// See ParseAffinity.
+const AffinityKey = "affinity"
+
+// Default session affinity settings. See Affinity.
+const (
+	DefaultAffinityTTL  = 5 * time.Minute
+	DefaultAffinitySize = 10000
+)
+
+// Affinity configures session affinity for calls to a routed component. With
+// affinity, every client remembers which replica served the first call for a
+// routing key and sends later calls for the key to the same replica, even if
+// the routing assignment changes, for as long as the replica is available.
+type Affinity struct {
+	// How long a client remembers the replica of a key after the key was
+	// last called. If zero, it defaults to DefaultAffinityTTL.
+	TTL time.Duration `toml:"ttl"`
+
+	// The maximum number of keys every client remembers. If zero, it
+	// defaults to DefaultAffinitySize.
+	Size int `toml:"size"`
+}
+
+// ParseAffinity returns the session affinity settings listed in the config
+// section of the component with the provided full name, or nil if affinity
+// is disabled. Unset fields are filled in with their defaults.
+func ParseAffinity(component string, sections map[string]string) (*Affinity, error) {
+	section, ok := sections[component]
+	if !ok {
+		return nil, nil
+	}
+	var config struct {
+		Affinity *Affinity `toml:"affinity"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return nil, fmt.Errorf("section %q: %w", component, err)
+	}
+	affinity := config.Affinity
+	switch {
+	case affinity == nil:
+		return nil, nil
+	case affinity.TTL < 0:
+		return nil, fmt.Errorf("section %q: %s: negative ttl %v", component, AffinityKey, affinity.TTL)
+	case affinity.Size < 0:
+		return nil, fmt.Errorf("section %q: %s: negative size %d", component, AffinityKey, affinity.Size)
+	}
+	if affinity.TTL == 0 {
+		affinity.TTL = DefaultAffinityTTL
+	}
+	if affinity.Size == 0 {
+		affinity.Size = DefaultAffinitySize
+	}
+	return affinity, nil
+}
+
+// EventQueueKey is the key, in the config section of a component that embeds
+// weaver.Subscriber, of the queues that buffer the events published to the
+// component. For example:
+//
+//	["github.com/example/mail/Welcomer"]
+//	event_queue = {size = 10000, overflow = "drop_oldest"}
+//
+// See ParseEventQueue.
+const EventQueueKey = "event_queue"
+
+// Event queue overflow policies. See EventQueue.Overflow.
+const (
+	EventOverflowBlock      = "block"
+	EventOverflowDropOldest = "drop_oldest"
+)
+
+// DefaultEventQueueSize is the default size of an event queue.
+const DefaultEventQueueSize = 1000
+
+// EventQueue configures the queues that buffer the events published to a
+// subscriber component. Every process that publishes events has its own
+// queue for every subscriber.
+type EventQueue struct {
+	// The maximum number of events buffered for the subscriber. If zero, it
+	// defaults to DefaultEventQueueSize.
+	Size int `toml:"size"`
+
+	// What to do with an event published while the queue is full:
+	// EventOverflowBlock makes the publisher wait until there is room for it,
+	// and EventOverflowDropOldest drops the oldest event in the queue to make
+	// room for it. If empty, it defaults to EventOverflowBlock.
+	Overflow string `toml:"overflow"`
+}
+
+// ParseEventQueue returns the event queue settings listed in the config
+// section of the component with the provided full name. Unset settings are
+// filled in with their defaults.
+func ParseEventQueue(component string, sections map[string]string) (EventQueue, error) {
+	queue := EventQueue{Size: DefaultEventQueueSize, Overflow: EventOverflowBlock}
+	section, ok := sections[component]
+	if !ok {
+		return queue, nil
+	}
+	var config struct {
+		EventQueue *EventQueue `toml:"event_queue"`
+	}
+	if _, err := toml.Decode(section, &config); err != nil {
+		return EventQueue{}, fmt.Errorf("section %q: %w", component, err)
+	}
+	if config.EventQueue == nil {
+		return queue, nil
+	}
+	if config.EventQueue.Size < 0 {
+		return EventQueue{}, fmt.Errorf("section %q: %s: negative size %d", component, EventQueueKey, config.EventQueue.Size)
+	}
+	if config.EventQueue.Size > 0 {
+		queue.Size = config.EventQueue.Size
+	}
+	switch config.EventQueue.Overflow {
+	case "", EventOverflowBlock:
+	case EventOverflowDropOldest:
+		queue.Overflow = EventOverflowDropOldest
+	default:
+		return EventQueue{}, fmt.Errorf("section %q: %s: overflow %q is not %q or %q", component, EventQueueKey, config.EventQueue.Overflow, EventOverflowBlock, EventOverflowDropOldest)
+	}
+	return queue, nil
+}
+
+// componentSettingKeys are the keys, in the config section of a component, of
+// settings that Service Weaver reads itself, rather than the component's
+// config struct.
+var componentSettingKeys = map[string]bool{
+	MethodTimeoutsKey:     true,
+	CompressMinBytesKey:   true,
+	MetricsWindowKey:      true,
+	MaxConcurrentCallsKey: true,
+	MaxQueuedCallsKey:     true,
+	BusyPolicyKey:         true,
+	RateLimitsKey:         true,
+	AffinityKey:           true,
+	EagerKey:              true,
+	CrashOnPanicKey:       true,
+	ExportedKey:           true,
+	AllowedCallersKey:     true,
+	EventQueueKey:         true,
+}
+
+const (
+	appKey      = "github.com/ServiceWeaver/weaver"
+	shortAppKey = "serviceweaver"
+)
+
+// appConfig holds the data from under appKey in the TOML config. Except for
+// the fields in NetworkConfig, MetricsConfig, and ProfilingConfig, which are
+// read directly by weavelets, it matches the contents of the Config proto.
+type appConfig struct {
+	Name     string
+	Binary   string
+	Args     []string
+	Env      []string
+	Colocate [][]string
+	Rollout  time.Duration
+	NetworkConfig
+	MetricsConfig
+	ProfilingConfig
+}
+
+// NetworkConfig configures how a weavelet connects to remote components. It
+// is specified in the app config section of a config file.
+type NetworkConfig struct {
+	// If positive, a weavelet that cannot reach a remote component within
+	// this duration fails to start the component that references it.
+	ComponentDialTimeout time.Duration `toml:"component_dial_timeout"`
+
+	// If positive, the period between TCP keep-alive probes sent on
+	// connections to remote components.
+	KeepAlive time.Duration `toml:"keep_alive"`
+
+	// If positive, connections to remote components that have been idle for
+	// this duration are closed. They are re-dialed on their next use.
+	IdleTimeout time.Duration `toml:"idle_timeout"`
+
+	// If positive, how long a shutting down weavelet waits for the remote
+	// calls it is executing to finish before closing its connections.
+	DrainTimeout time.Duration `toml:"drain_timeout"`
+}
+
+// MetricsConfig configures the metrics recorded by a weavelet. It is
+// specified in the app config section of a config file.
+type MetricsConfig struct {
+	// If non-zero, the maximum number of distinct labels recorded by every
+	// metric map that doesn't set its own limit. If negative, these maps are
+	// unbounded. See metrics.CardinalityLimit.
+	MetricCardinalityLimit int `toml:"metric_cardinality_limit"`
+
+	// If positive, the interval at which a weavelet samples Go runtime and
+	// process metrics, like the number of goroutines and the heap size.
+	RuntimeMetricsInterval time.Duration `toml:"runtime_metrics_interval"`
+
+	// If true, a weavelet doesn't record Go runtime and process metrics.
+	DisableRuntimeMetrics bool `toml:"disable_runtime_metrics"`
+}
+
+// ProfilingConfig configures the profiling endpoints of a weavelet. It is
+// specified in the app config section of a config file.
+type ProfilingConfig struct {
+	// If not empty, the address on which every weavelet serves the
+	// net/http/pprof handlers, e.g., "localhost:0". Use port 0 when a machine
+	// runs more than one weavelet, so that every weavelet picks its own port.
+	PprofAddress string `toml:"pprof_address"`
+}
+
+// Validate implements the interface consulted by ParseConfigSection.
+func (c *appConfig) Validate() error {
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"component_dial_timeout", c.ComponentDialTimeout},
+		{"keep_alive", c.KeepAlive},
+		{"idle_timeout", c.IdleTimeout},
+		{"drain_timeout", c.DrainTimeout},
+		{"runtime_metrics_interval", c.RuntimeMetricsInterval},
+	} {
+		if d.value < 0 {
+			return fmt.Errorf("negative %s %v", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+// ParseNetworkConfig returns the NetworkConfig specified in the app config
+// section of the provided config sections. Unspecified fields are zero.
+func ParseNetworkConfig(sections map[string]string) (NetworkConfig, error) {
+	parsed := &appConfig{}
+	if err := ParseConfigSection(appKey, shortAppKey, sections, parsed); err != nil {
+		return NetworkConfig{}, err
+	}
+	return parsed.NetworkConfig, nil
+}
+
+// ParseColocation returns the colocation groups listed in the provided config
+// sections, i.e., the colocate entries of the app config section followed by
+// the colocate entries of the [placement] section, in the order of
+// AppConfig.Colocate.
+func ParseColocation(sections map[string]string) ([][]string, error) {
+	parsed := &appConfig{}
+	if err := ParseConfigSection(appKey, shortAppKey, sections, parsed); err != nil {
+		return nil, err
+	}
+	placement := &Placement{}
+	if err := ParseConfigSection(placementKey, shortPlacementKey, sections, placement); err != nil {
+		return nil, err
+	}
+	return append(parsed.Colocate, placement.Colocate...), nil
+}
+
+// ParseMetricsConfig returns the MetricsConfig specified in the app config
+// section of the provided config sections. Unspecified fields are zero.
+func ParseMetricsConfig(sections map[string]string) (MetricsConfig, error) {
+	parsed := &appConfig{}
+	if err := ParseConfigSection(appKey, shortAppKey, sections, parsed); err != nil {
+		return MetricsConfig{}, err
+	}
+	return parsed.MetricsConfig, nil
+}
+
+// ParseProfilingConfig returns the ProfilingConfig specified in the app
+// config section of the provided config sections. Unspecified fields are zero.
+func ParseProfilingConfig(sections map[string]string) (ProfilingConfig, error) {
+	parsed := &appConfig{}
+	if err := ParseConfigSection(appKey, shortAppKey, sections, parsed); err != nil {
+		return ProfilingConfig{}, err
+	}
+	return parsed.ProfilingConfig, nil
+}
+
+func extractApp(file string, config *protos.AppConfig) error {
 	parsed := &appConfig{}
 	if err := ParseConfigSection(appKey, shortAppKey, config.Sections, parsed); err != nil {
 		return err
@@ -127,11 +853,17 @@ func extractApp(file string, config *protos.AppConfig) error {
 		config.Colocate = append(config.Colocate, group)
 	}
 
+	// Parse placement hints.
+	placement, err := extractPlacement(config)
+	if err != nil {
+		return err
+	}
+
 	// Canonicalize the config.
 	if err := canonicalizeConfig(config, filepath.Dir(file)); err != nil {
 		return err
 	}
-	return nil
+	return checkPlacement(config, placement.Separate)
 }
 
 // canonicalizeConfig updates the provided config to canonical
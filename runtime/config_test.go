@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ServiceWeaver/weaver/runtime"
 	"github.com/ServiceWeaver/weaver/runtime/codegen"
@@ -102,6 +103,540 @@ func TestParseConfigSection(t *testing.T) {
 	}
 }
 
+type componentConfig struct {
+	Foo    string
+	Bar    int
+	Nested struct {
+		X string
+		Y string
+	}
+}
+
+func (c *componentConfig) Validate() error {
+	if c.Bar < 0 {
+		return fmt.Errorf("negative Bar")
+	}
+	return nil
+}
+
+func TestParseComponentConfig(t *testing.T) {
+	const base = `
+[base]
+Foo = "base"
+Bar = 1
+Nested = { X = "base", Y = "base" }
+Other = "for another component"
+`
+	for _, test := range []struct {
+		name   string
+		config string
+		want   componentConfig
+	}{
+		{"NoSections", ``, componentConfig{}},
+		{"NoBase", "[\"pkg/C\"]\nFoo = 'c'\n", componentConfig{Foo: "c"}},
+		{"OnlyBase", base, componentConfig{Foo: "base", Bar: 1, Nested: struct{ X, Y string }{"base", "base"}}},
+		{
+			"Override",
+			base + "[\"pkg/C\"]\nBar = 2\nNested = { Y = 'c' }\n",
+			componentConfig{Foo: "base", Bar: 2, Nested: struct{ X, Y string }{"base", "c"}},
+		},
+		{
+			"ServiceWeaverSettings",
+			"[\"pkg/C\"]\nFoo = 'c'\nmethod_timeouts = { Query = '200ms' }\ncompress_min_bytes = 1024\n",
+			componentConfig{Foo: "c"},
+		},
+		{
+			"OtherComponent",
+			base + "[\"pkg/D\"]\nBar = 2\n",
+			componentConfig{Foo: "base", Bar: 1, Nested: struct{ X, Y string }{"base", "base"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := runtime.ParseConfig("weaver.toml", test.config, codegen.ComponentConfigValidator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got componentConfig
+			if err := runtime.ParseComponentConfig("pkg/C", config.Sections, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("ParseComponentConfig: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseComponentConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"BaseType", "[base]\nBar = 'one'\n", "incompatible types"},
+		{"BaseInvalid", "[base]\nBar = -1\n", "negative Bar"},
+		{"ComponentInvalid", "[base]\nBar = 1\n[\"pkg/C\"]\nBar = -1\n", "negative Bar"},
+		{"ComponentUnknown", "[base]\nBar = 1\n[\"pkg/C\"]\nBaz = 1\n", "unknown keys"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := runtime.ParseConfig("weaver.toml", test.config, codegen.ComponentConfigValidator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got componentConfig
+			err = runtime.ParseComponentConfig("pkg/C", config.Sections, &got)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("ParseComponentConfig: got %v, want error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	const config = `
+serviceweaver:
+  name: yamlapp
+  binary: ./app
+base:
+  Bar: 1
+pkg/C:
+  Foo: c
+  Nested:
+    Y: c
+  method_timeouts:
+    Query: 200ms
+`
+	for _, file := range []string{"weaver.yaml", "weaver.yml"} {
+		t.Run(file, func(t *testing.T) {
+			app, err := runtime.ParseConfig(file, config, codegen.ComponentConfigValidator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := app.Name, "yamlapp"; got != want {
+				t.Errorf("Name: got %q, want %q", got, want)
+			}
+			var got componentConfig
+			if err := runtime.ParseComponentConfig("pkg/C", app.Sections, &got); err != nil {
+				t.Fatal(err)
+			}
+			want := componentConfig{Foo: "c", Bar: 1, Nested: struct{ X, Y string }{"", "c"}}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ParseComponentConfig (-want +got):\n%s", diff)
+			}
+			timeouts, err := runtime.ParseMethodTimeouts("pkg/C", app.Sections)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := timeouts["Query"], 200*time.Millisecond; got != want {
+				t.Errorf("Query timeout: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"NotASection", "pkg/C: 42\n", "want a mapping"},
+		{"Invalid", "pkg/C:\n  Bar: -1\n", "negative Bar"},
+		{"Malformed", "pkg/C: [\n", "YAML"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			app, err := runtime.ParseConfig("weaver.yaml", test.config, codegen.ComponentConfigValidator)
+			if err == nil {
+				var got componentConfig
+				err = runtime.ParseComponentConfig("pkg/C", app.Sections, &got)
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got %v, want error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+type taggedConfig struct {
+	MaxSize  int    `yaml:"max_size"`
+	Timeout  string `toml:"timeout_toml" yaml:"timeout_yaml"`
+	Endpoint string `yaml:"endpoint_url"`
+}
+
+func TestParseComponentConfigYAMLTags(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		section string
+		want    taggedConfig
+	}{
+		{"YAMLTag", "max_size = 10", taggedConfig{MaxSize: 10}},
+		{"FieldName", "MaxSize = 10", taggedConfig{MaxSize: 10}},
+		{"TOMLTag", "timeout_toml = '1s'", taggedConfig{Timeout: "1s"}},
+		{"Mixed", "max_size = 1\nendpoint_url = 'x'", taggedConfig{MaxSize: 1, Endpoint: "x"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sections := map[string]string{"pkg/C": test.section}
+			var got taggedConfig
+			if err := runtime.ParseComponentConfig("pkg/C", sections, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// A field name takes precedence over a yaml tag, and a toml tag replaces
+	// a yaml tag, so these settings are unknown.
+	for _, section := range []string{"MaxSize = 10\nmax_size = 20", "timeout_yaml = '1s'"} {
+		sections := map[string]string{"pkg/C": section}
+		var got taggedConfig
+		err := runtime.ParseComponentConfig("pkg/C", sections, &got)
+		if err == nil || !strings.Contains(err.Error(), "unknown keys") {
+			t.Errorf("%q: got %v, want unknown keys error", section, err)
+		}
+	}
+}
+
+func TestParseMethodTimeouts(t *testing.T) {
+	const config = `
+["pkg/C"]
+Foo = "c"
+method_timeouts = { Query = "200ms", Reindex = "10m" }
+`
+	app, err := runtime.ParseConfig("weaver.toml", config, codegen.ComponentConfigValidator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := runtime.ParseMethodTimeouts("pkg/C", app.Sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"Query": 200 * time.Millisecond, "Reindex": 10 * time.Minute}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ParseMethodTimeouts: (-want +got):\n%s", diff)
+	}
+
+	// A component without a section has no timeouts.
+	if got, err := runtime.ParseMethodTimeouts("pkg/D", app.Sections); err != nil || got != nil {
+		t.Fatalf("ParseMethodTimeouts(pkg/D): got %v, %v, want nil, nil", got, err)
+	}
+
+	// Timeouts must be positive.
+	sections := map[string]string{"pkg/C": "method_timeouts = { Query = \"0s\" }"}
+	if _, err := runtime.ParseMethodTimeouts("pkg/C", sections); err == nil || !strings.Contains(err.Error(), "non-positive") {
+		t.Fatalf("ParseMethodTimeouts: got %v, want non-positive timeout error", err)
+	}
+}
+
+func TestParseCompressMinBytes(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    int
+	}{
+		{"", 0},
+		{"Foo = 'c'", 0},
+		{"compress_min_bytes = 4096", 4096},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseCompressMinBytes("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %d, want %d", test.section, got, test.want)
+		}
+	}
+
+	sections := map[string]string{"pkg/C": "compress_min_bytes = -1"}
+	if _, err := runtime.ParseCompressMinBytes("pkg/C", sections); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Fatalf("ParseCompressMinBytes: got %v, want negative compress_min_bytes error", err)
+	}
+}
+
+func TestParseMetricsWindow(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    time.Duration
+	}{
+		{"", 0},
+		{"Foo = 'c'", 0},
+		{`metrics_window = "1m"`, time.Minute},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseMetricsWindow("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %v, want %v", test.section, got, test.want)
+		}
+	}
+
+	sections := map[string]string{"pkg/C": `metrics_window = "-1s"`}
+	if _, err := runtime.ParseMetricsWindow("pkg/C", sections); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Fatalf("ParseMetricsWindow: got %v, want negative metrics_window error", err)
+	}
+}
+
+func TestParseMaxConcurrentCalls(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    int
+	}{
+		{"", 0},
+		{"Foo = 'c'", 0},
+		{"max_concurrent_calls = 64", 64},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseMaxConcurrentCalls("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %d, want %d", test.section, got, test.want)
+		}
+	}
+
+	sections := map[string]string{"pkg/C": "max_concurrent_calls = -1"}
+	if _, err := runtime.ParseMaxConcurrentCalls("pkg/C", sections); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Fatalf("ParseMaxConcurrentCalls: got %v, want negative max_concurrent_calls error", err)
+	}
+}
+
+func TestParseCallQueue(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    runtime.CallQueue
+	}{
+		{"", runtime.CallQueue{Policy: runtime.BusyQueue}},
+		{"max_concurrent_calls = 64", runtime.CallQueue{Policy: runtime.BusyQueue}},
+		{"max_concurrent_calls = 64\nmax_queued_calls = 256", runtime.CallQueue{Policy: runtime.BusyQueue, MaxQueued: 256}},
+		{"max_concurrent_calls = 64\nbusy_policy = 'queue'", runtime.CallQueue{Policy: runtime.BusyQueue}},
+		{"max_concurrent_calls = 64\nbusy_policy = 'reject'", runtime.CallQueue{Policy: runtime.BusyReject}},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseCallQueue("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.section, got, test.want)
+		}
+	}
+
+	for _, test := range []struct{ section, want string }{
+		{"max_queued_calls = 8", "require a positive max_concurrent_calls"},
+		{"busy_policy = 'reject'", "require a positive max_concurrent_calls"},
+		{"max_concurrent_calls = 64\nmax_queued_calls = -1", "negative max_queued_calls"},
+		{"max_concurrent_calls = 64\nbusy_policy = 'drop'", "busy_policy"},
+		{"max_concurrent_calls = 64\nmax_queued_calls = 8\nbusy_policy = 'reject'", "max_queued_calls is set"},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		if _, err := runtime.ParseCallQueue("pkg/C", sections); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got %v, want error containing %q", test.section, err, test.want)
+		}
+	}
+}
+
+func TestParseEager(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    bool
+	}{
+		{"", false},
+		{"Foo = 'c'", false},
+		{"eager = false", false},
+		{"eager = true", true},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseEager("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %t, want %t", test.section, got, test.want)
+		}
+	}
+
+	sections := map[string]string{"pkg/C": "eager = 'yes'"}
+	if _, err := runtime.ParseEager("pkg/C", sections); err == nil {
+		t.Fatal("ParseEager: unexpected success for a non-boolean eager")
+	}
+}
+
+func TestParseCrashOnPanic(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    bool
+	}{
+		{"", false},
+		{"crash_on_panic = false", false},
+		{"crash_on_panic = true", true},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseCrashOnPanic("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %t, want %t", test.section, got, test.want)
+		}
+	}
+
+	sections := map[string]string{"pkg/C": "crash_on_panic = 1"}
+	if _, err := runtime.ParseCrashOnPanic("pkg/C", sections); err == nil {
+		t.Fatal("ParseCrashOnPanic: unexpected success for a non-boolean crash_on_panic")
+	}
+}
+
+func TestParseExported(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    bool
+	}{
+		{"", false},
+		{"exported = false", false},
+		{"exported = true", true},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseExported("pkg/C", sections)
+		if err != nil {
+			t.Fatalf("%q: %v", test.section, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %t, want %t", test.section, got, test.want)
+		}
+	}
+
+	sections := map[string]string{"pkg/C": `exported = "yes"`}
+	if _, err := runtime.ParseExported("pkg/C", sections); err == nil {
+		t.Fatal("ParseExported: unexpected success for a non-boolean exported")
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	section := `
+[rate_limits]
+Send = { qps = 50, burst = 10, mode = "wait" }
+Query = { qps = 2.5 }
+Reindex = { qps = 0, burst = 10 }
+`
+	sections := map[string]string{"pkg/C": section}
+	got, err := runtime.ParseRateLimits("pkg/C", sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]runtime.RateLimit{
+		"Send":    {QPS: 50, Burst: 10, Mode: runtime.RateLimitWait},
+		"Query":   {QPS: 2.5, Burst: 1, Mode: runtime.RateLimitReject},
+		"Reindex": {QPS: 0, Burst: 10, Mode: runtime.RateLimitReject},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ParseRateLimits (-want +got):\n%s", diff)
+	}
+
+	for _, test := range []struct{ section, want string }{
+		{"rate_limits = { Send = { qps = -1 } }", "negative qps"},
+		{"rate_limits = { Send = { qps = 1, burst = -1 } }", "negative burst"},
+		{"rate_limits = { Send = { qps = 1, mode = 'drop' } }", "mode"},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		if _, err := runtime.ParseRateLimits("pkg/C", sections); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got %v, want error containing %q", test.section, err, test.want)
+		}
+	}
+}
+
+func TestParseAllowedCallers(t *testing.T) {
+	section := `
+[allowed_callers]
+Delete = ["pkg/Admin"]
+"*" = ["pkg/Frontend", "external"]
+`
+	sections := map[string]string{"pkg/C": section}
+	got, err := runtime.ParseAllowedCallers("pkg/C", sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"Delete":           {"pkg/Admin"},
+		runtime.AllMethods: {"pkg/Frontend", runtime.ExternalCaller},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ParseAllowedCallers (-want +got):\n%s", diff)
+	}
+
+	sections = map[string]string{"pkg/C": `allowed_callers = { Delete = [""] }`}
+	if _, err := runtime.ParseAllowedCallers("pkg/C", sections); err == nil || !strings.Contains(err.Error(), "empty caller") {
+		t.Errorf("got %v, want error containing %q", err, "empty caller")
+	}
+}
+
+func TestParseEventQueue(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    runtime.EventQueue
+	}{
+		{"", runtime.EventQueue{Size: runtime.DefaultEventQueueSize, Overflow: runtime.EventOverflowBlock}},
+		{`event_queue = {}`, runtime.EventQueue{Size: runtime.DefaultEventQueueSize, Overflow: runtime.EventOverflowBlock}},
+		{`event_queue = {size = 10}`, runtime.EventQueue{Size: 10, Overflow: runtime.EventOverflowBlock}},
+		{`event_queue = {overflow = "drop_oldest"}`, runtime.EventQueue{Size: runtime.DefaultEventQueueSize, Overflow: runtime.EventOverflowDropOldest}},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseEventQueue("pkg/C", sections)
+		if err != nil {
+			t.Errorf("%q: %v", test.section, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%q: ParseEventQueue (-want +got):\n%s", test.section, diff)
+		}
+	}
+
+	for _, test := range []struct{ section, want string }{
+		{"event_queue = {size = -1}", "negative size"},
+		{"event_queue = {overflow = 'drop_newest'}", "overflow"},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		if _, err := runtime.ParseEventQueue("pkg/C", sections); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got %v, want error containing %q", test.section, err, test.want)
+		}
+	}
+}
+
+func TestParseAffinity(t *testing.T) {
+	for _, test := range []struct {
+		section string
+		want    *runtime.Affinity
+	}{
+		{"", nil},
+		{`affinity = { ttl = "1m", size = 10 }`, &runtime.Affinity{TTL: time.Minute, Size: 10}},
+		{`affinity = { size = 10 }`, &runtime.Affinity{TTL: runtime.DefaultAffinityTTL, Size: 10}},
+		{`affinity = {}`, &runtime.Affinity{TTL: runtime.DefaultAffinityTTL, Size: runtime.DefaultAffinitySize}},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		got, err := runtime.ParseAffinity("pkg/C", sections)
+		if err != nil {
+			t.Errorf("%q: %v", test.section, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%q: ParseAffinity (-want +got):\n%s", test.section, diff)
+		}
+	}
+
+	for _, test := range []struct{ section, want string }{
+		{`affinity = { ttl = "-1s" }`, "negative ttl"},
+		{`affinity = { size = -1 }`, "negative size"},
+	} {
+		sections := map[string]string{"pkg/C": test.section}
+		if _, err := runtime.ParseAffinity("pkg/C", sections); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got %v, want error containing %q", test.section, err, test.want)
+		}
+	}
+}
+
 func TestConfigErrors(t *testing.T) {
 	type testCase struct {
 		name          string
@@ -152,6 +687,71 @@ rollout = "hello"
 `,
 			expectedError: "invalid duration",
 		},
+		{
+			name: "negative dial timeout",
+			cfg: `
+[serviceweaver]
+component_dial_timeout = "-1s"
+`,
+			expectedError: "negative component_dial_timeout",
+		},
+		{
+			name: "negative idle timeout",
+			cfg: `
+[serviceweaver]
+idle_timeout = "-1m"
+`,
+			expectedError: "negative idle_timeout",
+		},
+		{
+			name: "negative drain timeout",
+			cfg: `
+[serviceweaver]
+drain_timeout = "-5s"
+`,
+			expectedError: "negative drain_timeout",
+		},
+		{
+			name: "negative runtime metrics interval",
+			cfg: `
+[serviceweaver]
+runtime_metrics_interval = "-10s"
+`,
+			expectedError: "negative runtime_metrics_interval",
+		},
+		{
+			name: "placement-colocate-conflict",
+			cfg: `
+[serviceweaver]
+colocate = [["a", "b"]]
+
+[placement]
+colocate = [["b", "c"]]
+`,
+			expectedError: "placed multiple times",
+		},
+		{
+			name: "placement-separate-conflict",
+			cfg: `
+[serviceweaver]
+colocate = [["a", "b", "c"]]
+
+[placement]
+colocate = [["d", "e"]]
+separate = [["a", "c"], ["x", "e", "y", "d"]]
+`,
+			expectedError: `contradictory placement rules:
+  colocate ["a" "b" "c"] contradicts separate ["a" "c"]
+  colocate ["d" "e"] contradicts separate ["x" "e" "y" "d"]`,
+		},
+		{
+			name: "placement unknown key",
+			cfg: `
+[placement]
+together = [["a", "b"]]
+`,
+			expectedError: "unknown",
+		},
 	} {
 		t.Run(c.name, func(t *testing.T) {
 			_, err := runtime.ParseConfig("weaver.toml", c.cfg, codegen.ComponentConfigValidator)
@@ -164,3 +764,228 @@ rollout = "hello"
 		})
 	}
 }
+
+func TestNetworkConfig(t *testing.T) {
+	for _, test := range []struct {
+		cfg  string
+		want runtime.NetworkConfig
+	}{
+		{"", runtime.NetworkConfig{}},
+		{"[serviceweaver]\nname = 'app'\n", runtime.NetworkConfig{}},
+		{
+			"[serviceweaver]\ncomponent_dial_timeout = '30s'\n",
+			runtime.NetworkConfig{ComponentDialTimeout: 30 * time.Second},
+		},
+		{
+			"[serviceweaver]\nkeep_alive = '15s'\nidle_timeout = '5m'\n",
+			runtime.NetworkConfig{KeepAlive: 15 * time.Second, IdleTimeout: 5 * time.Minute},
+		},
+		{
+			"[serviceweaver]\ndrain_timeout = '20s'\n",
+			runtime.NetworkConfig{DrainTimeout: 20 * time.Second},
+		},
+	} {
+		config, err := runtime.ParseConfig("weaver.toml", test.cfg, codegen.ComponentConfigValidator)
+		if err != nil {
+			t.Fatalf("ParseConfig(%q): %v", test.cfg, err)
+		}
+		got, err := runtime.ParseNetworkConfig(config.Sections)
+		if err != nil {
+			t.Fatalf("ParseNetworkConfig(%q): %v", test.cfg, err)
+		}
+		if got != test.want {
+			t.Errorf("ParseNetworkConfig(%q): got %+v, want %+v", test.cfg, got, test.want)
+		}
+	}
+}
+
+func TestMetricsConfig(t *testing.T) {
+	for _, test := range []struct {
+		cfg  string
+		want runtime.MetricsConfig
+	}{
+		{"", runtime.MetricsConfig{}},
+		{
+			"[serviceweaver]\nmetric_cardinality_limit = 500\n",
+			runtime.MetricsConfig{MetricCardinalityLimit: 500},
+		},
+		{
+			"[serviceweaver]\nmetric_cardinality_limit = -1\n",
+			runtime.MetricsConfig{MetricCardinalityLimit: -1},
+		},
+		{
+			"[serviceweaver]\nruntime_metrics_interval = '1m'\n",
+			runtime.MetricsConfig{RuntimeMetricsInterval: time.Minute},
+		},
+		{
+			"[serviceweaver]\ndisable_runtime_metrics = true\n",
+			runtime.MetricsConfig{DisableRuntimeMetrics: true},
+		},
+	} {
+		config, err := runtime.ParseConfig("weaver.toml", test.cfg, codegen.ComponentConfigValidator)
+		if err != nil {
+			t.Fatalf("ParseConfig(%q): %v", test.cfg, err)
+		}
+		got, err := runtime.ParseMetricsConfig(config.Sections)
+		if err != nil {
+			t.Fatalf("ParseMetricsConfig(%q): %v", test.cfg, err)
+		}
+		if got != test.want {
+			t.Errorf("ParseMetricsConfig(%q): got %+v, want %+v", test.cfg, got, test.want)
+		}
+	}
+}
+
+func TestProfilingConfig(t *testing.T) {
+	for _, test := range []struct {
+		cfg  string
+		want runtime.ProfilingConfig
+	}{
+		{"", runtime.ProfilingConfig{}},
+		{
+			"[serviceweaver]\npprof_address = 'localhost:0'\n",
+			runtime.ProfilingConfig{PprofAddress: "localhost:0"},
+		},
+	} {
+		config, err := runtime.ParseConfig("weaver.toml", test.cfg, codegen.ComponentConfigValidator)
+		if err != nil {
+			t.Fatalf("ParseConfig(%q): %v", test.cfg, err)
+		}
+		got, err := runtime.ParseProfilingConfig(config.Sections)
+		if err != nil {
+			t.Fatalf("ParseProfilingConfig(%q): %v", test.cfg, err)
+		}
+		if got != test.want {
+			t.Errorf("ParseProfilingConfig(%q): got %+v, want %+v", test.cfg, got, test.want)
+		}
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	const cfg = `
+[serviceweaver]
+colocate = [["a", "b"]]
+
+[placement]
+colocate = [["c", "d"]]
+separate = [["a", "c"], ["b", "e"]]
+singleton = ["d"]
+`
+	config, err := runtime.ParseConfig("weaver.toml", cfg, codegen.ComponentConfigValidator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var colocate [][]string
+	for _, group := range config.Colocate {
+		colocate = append(colocate, group.Components)
+	}
+	if diff := cmp.Diff([][]string{{"a", "b"}, {"c", "d"}}, colocate); diff != "" {
+		t.Errorf("Colocate: (-want +got):\n%s", diff)
+	}
+	parsed, err := runtime.ParseColocation(config.Sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(colocate, parsed); diff != "" {
+		t.Errorf("ParseColocation: (-want +got):\n%s", diff)
+	}
+
+	placement, err := runtime.ParsePlacement(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &runtime.Placement{
+		Colocate:  [][]string{{"c", "d"}},
+		Separate:  [][]string{{"a", "c"}, {"b", "e"}},
+		Singleton: []string{"d"},
+	}
+	if diff := cmp.Diff(want, placement); diff != "" {
+		t.Errorf("ParsePlacement: (-want +got):\n%s", diff)
+	}
+	if !placement.IsSingleton("c", "d") {
+		t.Errorf("IsSingleton(c, d): got false, want true")
+	}
+	if placement.IsSingleton("a", "b") {
+		t.Errorf("IsSingleton(a, b): got true, want false")
+	}
+}
+
+type defaultedConfig struct {
+	Size    int           `toml:"size" default:"1000"`
+	Name    string        `default:"cache"`
+	Enabled bool          `default:"true"`
+	Ratio   float64       `default:"0.5"`
+	TTL     time.Duration `default:"1m"`
+	NoTag   int
+	Nested  struct {
+		Limit uint `default:"7"`
+	}
+}
+
+func TestParseComponentConfigDefaults(t *testing.T) {
+	defaults := func(f func(*defaultedConfig)) defaultedConfig {
+		c := defaultedConfig{Size: 1000, Name: "cache", Enabled: true, Ratio: 0.5, TTL: time.Minute}
+		c.Nested.Limit = 7
+		f(&c)
+		return c
+	}
+	for _, test := range []struct {
+		name    string
+		section string
+		want    defaultedConfig
+	}{
+		{"Absent", "", defaults(func(*defaultedConfig) {})},
+		{"Present", "size = 10\nName = 'lru'\nTTL = '5s'\nNoTag = 3", defaults(func(c *defaultedConfig) {
+			c.Size, c.Name, c.TTL, c.NoTag = 10, "lru", 5*time.Second, 3
+		})},
+		{"ExplicitZero", "size = 0\nEnabled = false\nName = ''\nNested = { Limit = 0 }", defaults(func(c *defaultedConfig) {
+			c.Size, c.Enabled, c.Name, c.Nested.Limit = 0, false, "", 0
+		})},
+		{"Base", "", defaults(func(*defaultedConfig) {})},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sections := map[string]string{}
+			if test.section != "" {
+				sections["pkg/C"] = test.section
+			}
+			if test.name == "Base" {
+				sections[runtime.BaseConfigKey] = "Other = 1"
+			}
+			var got defaultedConfig
+			if err := runtime.ParseComponentConfig("pkg/C", sections, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseComponentConfigBadDefaults(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		dst  any
+		want string
+	}{
+		{"Int", &struct {
+			Size int `default:"big"`
+		}{}, `field .Size: invalid default "big"`},
+		{"Overflow", &struct {
+			Size int8 `default:"1000"`
+		}{}, "out of range"},
+		{"Duration", &struct {
+			TTL time.Duration `default:"1 minute"`
+		}{}, "invalid default"},
+		{"Slice", &struct {
+			Hosts []string `default:"a,b"`
+		}{}, "can't have defaults"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := runtime.ParseComponentConfig("pkg/C", nil, test.dst)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got %v, want error containing %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// DefaultTag is the struct tag that specifies the default value of a field of
+// a component config, e.g.:
+//
+//	type cacheConfig struct {
+//	    Size int           `default:"1000"`
+//	    TTL  time.Duration `default:"1m"`
+//	}
+//
+// See ParseComponentConfig.
+const DefaultTag = "default"
+
+// setConfigDefaults sets every field of the struct pointed to by dst that has
+// a DefaultTag to the default value, including the fields of nested structs.
+// Fields of type bool, string, time.Duration, any integer or floating point
+// type, or types that implement encoding.TextUnmarshaler can have defaults.
+func setConfigDefaults(dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return setStructDefaults(v.Elem())
+}
+
+// setStructDefaults is like setConfigDefaults, but for struct value v.
+func setStructDefaults(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		def, ok := f.Tag.Lookup(DefaultTag)
+		if !ok {
+			if f.Type.Kind() == reflect.Struct {
+				if err := setStructDefaults(v.Field(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := setDefault(v.Field(i), def); err != nil {
+			return fmt.Errorf("field %s.%s: invalid default %q: %w", t.Name(), f.Name, def, err)
+		}
+	}
+	return nil
+}
+
+// setDefault parses def into v.
+func setDefault(v reflect.Value, def string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(def))
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(def)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.String:
+		v.SetString(def)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(def, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(def, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(def, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	default:
+		return fmt.Errorf("fields of type %v can't have defaults", v.Type())
+	}
+	return nil
+}
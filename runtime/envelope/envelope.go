@@ -289,6 +289,13 @@ func (e *Envelope) UpdateRoutingInfo(routing *protos.RoutingInfo) error {
 	return e.conn.UpdateRoutingInfoRPC(routing)
 }
 
+// UpdateLeader tells the weavelet whether it is the leader among the replicas
+// of its colocation group. The leader runs the background tasks that must run
+// on exactly one replica. See weaver.Task for details.
+func (e *Envelope) UpdateLeader(leader bool) error {
+	return e.conn.UpdateLeaderRPC(leader)
+}
+
 func (e *Envelope) logLines(component string, src io.Reader, h EnvelopeHandler) error {
 	// Fill partial log entry.
 	entry := &protos.LogEntry{
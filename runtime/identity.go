@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PeerIdentity returns the identity of the weavelets that host the provided
+// colocation group of the provided deployment. A colocation group is named
+// after the first component it hosts.
+//
+// Deployers that use mTLS must store the identity as the only DNS name of the
+// certificates they hand out to the group's weavelets. Weavelets rely on it
+// to tell which components a verified peer hosts, and whether the peer
+// belongs to another deployment.
+func PeerIdentity(deploymentId, group string) string {
+	return deploymentId + "/" + group
+}
+
+// ParsePeerIdentity returns the deployment and the colocation group of the
+// provided identity, as returned by PeerIdentity.
+func ParsePeerIdentity(id string) (deploymentId, group string, err error) {
+	deploymentId, group, ok := strings.Cut(id, "/")
+	if !ok || deploymentId == "" || group == "" {
+		return "", "", fmt.Errorf("invalid peer identity %q: want <deployment>/<group>", id)
+	}
+	return deploymentId, group, nil
+}
@@ -45,6 +45,10 @@ type Options struct {
 type LogHandler struct {
 	Opts  Options                      // configures the log entries
 	Write func(entry *protos.LogEntry) // called on every log entry
+
+	// If not nil, log entries below this level are dropped. Because the
+	// level is checked by Enabled, dropped entries are never formatted.
+	Level slog.Leveler
 }
 
 var _ slog.Handler = &LogHandler{}
@@ -56,9 +60,8 @@ func (h *LogHandler) Handle(ctx context.Context, rec slog.Record) error {
 }
 
 // Enabled implements the slog.Handler interface.
-func (h *LogHandler) Enabled(context.Context, slog.Level) bool {
-	// Support all logging levels.
-	return true
+func (h *LogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.Level == nil || level >= h.Level.Level()
 }
 
 // WithAttrs implements the slog.Handler interface.
@@ -71,6 +74,7 @@ func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	rh := &LogHandler{
 		Opts:  h.Opts,
 		Write: h.Write,
+		Level: h.Level,
 	}
 	rh.Opts.Attrs = appendAttrs(rh.Opts.Attrs, attrs)
 	return rh
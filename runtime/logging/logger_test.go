@@ -142,3 +142,25 @@ func newAttrLogger(app, version, component, weavelet string, saver func(*protos.
 		Write: saver,
 	})
 }
+
+func TestLevel(t *testing.T) {
+	var got []string
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	logger := slog.New(&LogHandler{
+		Write: func(e *protos.LogEntry) { got = append(got, e.Msg) },
+		Level: level,
+	}).With("foo", "bar")
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	level.Set(slog.LevelDebug)
+	logger.Debug("debug again")
+
+	want := []string{"warn", "error", "debug again"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected entries (-want +got):\n%s", diff)
+	}
+}
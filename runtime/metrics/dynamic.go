@@ -0,0 +1,122 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+)
+
+// DynamicMap is a collection of metrics with the same name, whose label names
+// and values are determined at runtime, rather than by a label struct type.
+// Every distinct set of labels is interned into a single metric, whose labels
+// are materialized only when the metric is exported.
+type DynamicMap struct {
+	mm *MetricMap[string] // metrics, by encoded labels
+}
+
+// RegisterDynamicMap registers and returns a new dynamic metric map. Panics if
+// a metric with the same name has already been registered.
+func RegisterDynamicMap(typ protos.MetricType, name string, help string, bounds []float64) *DynamicMap {
+	return &DynamicMap{mm: registerMap(typ, name, help, bounds, decodeLabels)}
+}
+
+// Name returns the name of the map.
+func (dm *DynamicMap) Name() string {
+	return dm.mm.Name()
+}
+
+// SetCardinalityLimit sets the maximum number of distinct label sets for which
+// the map creates a metric. See MetricMap.SetCardinalityLimit. The overflow
+// metric of a dynamic map has no labels.
+func (dm *DynamicMap) SetCardinalityLimit(limit int) {
+	dm.mm.SetCardinalityLimit(limit)
+}
+
+// Get returns the metric with the provided labels, constructing it if it
+// doesn't already exist. Multiple calls to Get with equal labels return the
+// same metric, no matter the order in which the labels were added to the map.
+// Get doesn't retain labels, so the caller is free to modify labels after Get
+// returns. Get panics if a label name is not a valid Prometheus label name,
+// i.e., if it doesn't match [a-zA-Z_][a-zA-Z0-9_]*.
+func (dm *DynamicMap) Get(labels map[string]string) *Metric {
+	return dm.mm.Get(encodeLabels(dm.mm.Name(), labels))
+}
+
+// encodeLabels returns a canonical encoding of the labels of the provided
+// metric, with the labels sorted by name. Every name and value is prefixed by
+// its length, so that distinct labels are never encoded the same way.
+func encodeLabels(metric string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	size := 0
+	for k, v := range labels {
+		if !isValidLabelName(k) {
+			panic(fmt.Errorf("metric %q: invalid label name %q", metric, k))
+		}
+		keys = append(keys, k)
+		size += len(k) + len(v) + 8
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.Grow(size)
+	for _, k := range keys {
+		v := labels[k]
+		b.WriteString(strconv.Itoa(len(k)))
+		b.WriteByte(':')
+		b.WriteString(k)
+		b.WriteString(strconv.Itoa(len(v)))
+		b.WriteByte(':')
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+// decodeLabels decodes labels encoded by encodeLabels.
+func decodeLabels(encoded string) map[string]string {
+	labels := map[string]string{}
+	next := func() string {
+		i := strings.IndexByte(encoded, ':')
+		n, _ := strconv.Atoi(encoded[:i])
+		s := encoded[i+1 : i+1+n]
+		encoded = encoded[i+1+n:]
+		return s
+	}
+	for encoded != "" {
+		k := next()
+		labels[k] = next()
+	}
+	return labels
+}
+
+// isValidLabelName returns whether name is a valid Prometheus label name.
+func isValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
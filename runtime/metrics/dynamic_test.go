@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDynamicMap(t *testing.T) {
+	clear()
+	counter := RegisterDynamicMap(counterType, "TestDynamicMap/counter", "", nil)
+
+	const n = 100
+	var wait sync.WaitGroup
+	for i := 0; i < n; i++ {
+		i := i
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			counter.Get(map[string]string{"event": "click", "sku": fmt.Sprint(i % 2)}).Inc()
+			counter.Get(map[string]string{"event": "view"}).Add(2)
+			counter.Get(nil).Inc()
+		}()
+	}
+	wait.Wait()
+
+	// Equal labels are interned into the same metric.
+	labels := map[string]string{"sku": "0", "event": "click"}
+	if got, want := counter.Get(labels), counter.Get(map[string]string{"event": "click", "sku": "0"}); got != want {
+		t.Fatalf("Get(%v): got %p, want %p", labels, got, want)
+	}
+
+	got := map[string]float64{}
+	for _, snap := range Snapshot() {
+		if snap.Name == counter.Name() {
+			got[fmt.Sprint(snap.Labels)] = snap.Value
+		}
+	}
+	want := map[string]float64{
+		"map[event:click sku:0]": n / 2,
+		"map[event:click sku:1]": n / 2,
+		"map[event:view]":        2 * n,
+		"map[]":                  n,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("metrics (-want +got):\n%s", diff)
+	}
+}
+
+func TestDynamicMapLabelEncoding(t *testing.T) {
+	for _, labels := range []map[string]string{
+		{},
+		{"a": ""},
+		{"a": "b:c", "b": "1:2"},
+		{"a": "1:b2:cd", "_x9": "π"},
+	} {
+		if got := decodeLabels(encodeLabels("m", labels)); !cmp.Equal(labels, got) {
+			t.Errorf("decode(encode(%v)): got %v", labels, got)
+		}
+	}
+
+	// Labels whose concatenations are equal are encoded differently.
+	a := encodeLabels("m", map[string]string{"a": "bc"})
+	b := encodeLabels("m", map[string]string{"ab": "c"})
+	if a == b {
+		t.Errorf("%q and %q have the same encoding", "a=bc", "ab=c")
+	}
+}
+
+func TestDynamicMapCardinalityLimit(t *testing.T) {
+	clear()
+	counter := RegisterDynamicMap(counterType, "TestDynamicMapCardinalityLimit/counter", "", nil)
+	counter.SetCardinalityLimit(1)
+	counter.Get(map[string]string{"sku": "a"}).Add(1)
+	counter.Get(map[string]string{"sku": "b"}).Add(2)
+	counter.Get(map[string]string{"event": "c"}).Add(3)
+
+	got := map[string]float64{}
+	for _, snap := range Snapshot() {
+		if snap.Name == counter.Name() {
+			got[fmt.Sprint(snap.Labels)] = snap.Value
+		}
+	}
+	want := map[string]float64{"map[sku:a]": 1, "map[]": 5}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("metrics (-want +got):\n%s", diff)
+	}
+}
+
+func TestDynamicMapInvalidLabelName(t *testing.T) {
+	clear()
+	counter := RegisterDynamicMap(counterType, "TestDynamicMapInvalidLabelName/counter", "", nil)
+	for _, name := range []string{"", "9lives", "product-sku", "a b"} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("unexpected success")
+				}
+			}()
+			counter.Get(map[string]string{name: "x"})
+		})
+	}
+}
@@ -52,6 +52,7 @@ func (e *Exporter) Export() *protos.MetricUpdate {
 		}
 
 		// Check to see if metric has changed.
+		metric.sample()
 		v := metric.get()
 		var current uint64
 		if metric.typ != protos.MetricType_HISTOGRAM {
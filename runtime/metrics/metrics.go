@@ -19,6 +19,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"os"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -29,7 +30,24 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+// DefaultCardinalityLimit is the default maximum number of distinct labels
+// per metric map. See MetricMap.SetCardinalityLimit.
+const DefaultCardinalityLimit = 20000
+
+// OverflowLabelValue is the value of every label of the metric that records
+// values for labels past a metric map's cardinality limit.
+const OverflowLabelValue = "__overflow__"
+
 var (
+	// defaultCardinalityLimit is the cardinality limit of metric maps that
+	// don't set their own.
+	defaultCardinalityLimit atomic.Int64
+
+	// droppedLabelsMap counts the labels funneled into overflow metrics. It is
+	// registered the first time a metric map overflows.
+	droppedLabelsOnce sync.Once
+	droppedLabelsMap  *MetricMap[droppedLabelsLabels]
+
 	// metricNames stores the name of every metric (labeled or not).
 	metricNamesMu sync.RWMutex
 	metricNames   = map[string]bool{}
@@ -71,7 +89,18 @@ type Metric struct {
 	fvalue atomicFloat64 // value for Counter and Gauge, sum for Histogram
 	ivalue atomic.Uint64 // integer increments for Counter (separated for speed)
 
+	// For gauges whose value is computed by a callback. fnMu is held while
+	// fn runs, so that fn is never invoked concurrently with itself.
+	fnMu       sync.Mutex
+	fn         func() float64 // if non-nil, sampled into fvalue on collection
+	fnPanicked bool           // has fn panicked? used to log a panic only once
+
 	// For histograms only:
+	//
+	// Put holds histMu for reading, so concurrent Puts don't block each
+	// other. Readers hold histMu for writing, which excludes all in-progress
+	// Puts and yields a consistent view of the counts and sum.
+	histMu   sync.RWMutex
 	putCount atomic.Uint64   // incremented on every Put, for change detection
 	bounds   []float64       // histogram bounds
 	counts   []atomic.Uint64 // histogram counts
@@ -148,6 +177,34 @@ func (m *MetricSnapshot) Clone() *MetricSnapshot {
 	return &c
 }
 
+func init() {
+	defaultCardinalityLimit.Store(DefaultCardinalityLimit)
+}
+
+// SetDefaultCardinalityLimit sets the cardinality limit of the metric maps
+// that don't set their own, including the maps that have already been
+// registered. If limit is not positive, those maps are unbounded.
+func SetDefaultCardinalityLimit(limit int) {
+	defaultCardinalityLimit.Store(int64(limit))
+}
+
+type droppedLabelsLabels struct {
+	Metric string
+}
+
+// droppedLabels returns the metric map that counts dropped labels.
+func droppedLabels() *MetricMap[droppedLabelsLabels] {
+	droppedLabelsOnce.Do(func() {
+		droppedLabelsMap = RegisterMap[droppedLabelsLabels](
+			protos.MetricType_COUNTER,
+			"serviceweaver_metric_dropped_labels",
+			"Number of distinct labels recorded in a metric's overflow series",
+			nil,
+		)
+	})
+	return droppedLabelsMap
+}
+
 // config configures the creation of a metric.
 type config struct {
 	Type   protos.MetricType
@@ -207,6 +264,42 @@ func (m *Metric) Set(val float64) {
 	m.fvalue.set(val)
 }
 
+// SetFunc registers a callback that computes the metric's value. The callback
+// is invoked whenever the metric is collected, i.e., when it is snapshotted or
+// exported, and its result overwrites the metric's value. If fn panics, the
+// panic is logged and the metric's value is set to NaN.
+func (m *Metric) SetFunc(fn func() float64) {
+	m.fnMu.Lock()
+	defer m.fnMu.Unlock()
+	m.fn = fn
+}
+
+// sample sets the metric's value to the result of its callback, if any.
+func (m *Metric) sample() {
+	m.fnMu.Lock()
+	defer m.fnMu.Unlock()
+	if m.fn == nil {
+		return
+	}
+	m.fvalue.set(m.call())
+}
+
+// call invokes the metric's callback, recovering from panics.
+//
+// REQUIRES: m.fnMu is held.
+func (m *Metric) call() (val float64) {
+	defer func() {
+		if r := recover(); r != nil {
+			if !m.fnPanicked {
+				m.fnPanicked = true
+				fmt.Fprintf(os.Stderr, "metric %q: callback panicked: %v\n", m.name, r)
+			}
+			val = math.NaN()
+		}
+	}()
+	return m.fn()
+}
+
 // Put adds the provided value to the metric's histogram.
 func (m *Metric) Put(val float64) {
 	var idx int
@@ -219,6 +312,7 @@ func (m *Metric) Put(val float64) {
 			idx++
 		}
 	}
+	m.histMu.RLock()
 	m.counts[idx].Add(1)
 
 	// Microsecond latencies are often zero for very fast functions.
@@ -226,6 +320,23 @@ func (m *Metric) Put(val float64) {
 		m.fvalue.add(val)
 	}
 	m.putCount.Add(1)
+	m.histMu.RUnlock()
+}
+
+// Reset zeroes the metric's value and, for histograms, its bucket counts. It
+// is intended for tests that need to observe a metric from a clean state.
+func (m *Metric) Reset() {
+	m.histMu.Lock()
+	defer m.histMu.Unlock()
+	m.fvalue.set(0)
+	m.ivalue.Store(0)
+	for i := range m.counts {
+		m.counts[i].Store(0)
+	}
+	if len(m.counts) > 0 {
+		// Make sure the reset histogram is exported.
+		m.putCount.Add(1)
+	}
 }
 
 // initIdAndLabels initializes the id and labels of a metric.
@@ -247,27 +358,50 @@ func (m *Metric) get() float64 {
 }
 
 // Snapshot returns a snapshot of the metric. You must call Init at least once
-// before calling Snapshot.
+// before calling Snapshot. If the metric's value is computed by a callback,
+// the callback is invoked.
 func (m *Metric) Snapshot() *MetricSnapshot {
-	var counts []uint64
-	if n := len(m.counts); n > 0 {
-		counts = make([]uint64, n)
-		for i := range m.counts {
-			counts[i] = m.counts[i].Load()
-		}
-	}
+	m.sample()
+	value, counts := m.read()
 	return &MetricSnapshot{
 		Id:     m.id,
 		Name:   m.name,
 		Type:   m.typ,
 		Help:   m.help,
 		Labels: maps.Clone(m.labels),
-		Value:  m.get(),
+		Value:  value,
 		Bounds: slices.Clone(m.bounds),
 		Counts: counts,
 	}
 }
 
+// read returns the current value of the metric and, for histograms, a copy of
+// the bucket counts. For histograms, the sum and counts are read atomically
+// with respect to Put.
+func (m *Metric) read() (float64, []uint64) {
+	n := len(m.counts)
+	if n == 0 {
+		return m.get(), nil
+	}
+	counts := make([]uint64, n)
+	m.histMu.Lock()
+	defer m.histMu.Unlock()
+	for i := range m.counts {
+		counts[i] = m.counts[i].Load()
+	}
+	return m.get(), counts
+}
+
+// Histogram returns a consistent copy of a histogram's bounds, bucket counts,
+// and sum of all values. It returns empty results for other metric types.
+func (m *Metric) Histogram() (bounds []float64, counts []uint64, sum float64) {
+	if len(m.counts) == 0 {
+		return nil, nil, 0
+	}
+	sum, counts = m.read()
+	return slices.Clone(m.bounds), counts, sum
+}
+
 // MetricDef returns a MetricDef derived from the metric. You must call Init at
 // least once before calling Snapshot.
 func (m *Metric) MetricDef() *protos.MetricDef {
@@ -283,16 +417,10 @@ func (m *Metric) MetricDef() *protos.MetricDef {
 
 // MetricValue returns a MetricValue derived from the metric.
 func (m *Metric) MetricValue() *protos.MetricValue {
-	var counts []uint64
-	if n := len(m.counts); n > 0 {
-		counts = make([]uint64, n)
-		for i := range m.counts {
-			counts[i] = m.counts[i].Load()
-		}
-	}
+	value, counts := m.read()
 	return &protos.MetricValue{
 		Id:     m.id,
-		Value:  m.get(),
+		Value:  value,
 		Counts: counts,
 	}
 }
@@ -304,16 +432,28 @@ func (m *Metric) MetricValue() *protos.MetricValue {
 // TODO(mwhittaker): Understand the behavior of prometheus and Google Cloud
 // Metrics when we add or remove metric labels over time.
 type MetricMap[L comparable] struct {
-	config    config             // configures the metrics returned by Get
-	extractor *labelExtractor[L] // extracts labels from a value of type L
-	mu        sync.Mutex         // guards metrics
-	metrics   map[L]*Metric      // cache of metrics, by label
+	config config                    // configures the metrics returned by Get
+	labels func(L) map[string]string // extracts labels from a value of type L
+	limit  atomic.Int64              // cardinality limit; see SetCardinalityLimit
+
+	mu       sync.Mutex     // guards the following fields
+	metrics  map[L]*Metric  // cache of metrics, by label
+	overflow *Metric        // metric for labels past the limit, or nil
+	dropped  map[L]struct{} // labels funneled into overflow, bounded
+	counter  *Metric        // counts dropped labels, or nil
+	warned   bool           // has the overflow warning been logged?
 }
 
 func RegisterMap[L comparable](typ protos.MetricType, name string, help string, bounds []float64) *MetricMap[L] {
 	if err := typecheckLabels[L](); err != nil {
 		panic(err)
 	}
+	return registerMap(typ, name, help, bounds, newLabelExtractor[L]().Extract)
+}
+
+// registerMap registers and returns a new metric map whose metrics have the
+// labels returned by the provided function.
+func registerMap[L comparable](typ protos.MetricType, name string, help string, bounds []float64, labels func(L) map[string]string) *MetricMap[L] {
 	if name == "" {
 		panic(fmt.Errorf("empty metric name"))
 	}
@@ -338,9 +478,9 @@ func RegisterMap[L comparable](typ protos.MetricType, name string, help string,
 	}
 	metricNames[name] = true
 	return &MetricMap[L]{
-		config:    config{Type: typ, Name: name, Help: help, Bounds: bounds},
-		extractor: newLabelExtractor[L](),
-		metrics:   map[L]*Metric{},
+		config:  config{Type: typ, Name: name, Help: help, Bounds: bounds},
+		labels:  labels,
+		metrics: map[L]*Metric{},
 	}
 }
 
@@ -349,6 +489,24 @@ func (mm *MetricMap[L]) Name() string {
 	return mm.config.Name
 }
 
+// SetCardinalityLimit sets the maximum number of distinct labels for which the
+// map creates a metric. Once the limit is reached, Get returns a single
+// overflow metric, whose label values are all OverflowLabelValue, for every
+// new label. If limit is zero, the map uses the default limit (see
+// SetDefaultCardinalityLimit). If limit is negative, the map is unbounded.
+func (mm *MetricMap[L]) SetCardinalityLimit(limit int) {
+	mm.limit.Store(int64(limit))
+}
+
+// cardinalityLimit returns the map's effective cardinality limit, or a
+// non-positive number if the map is unbounded.
+func (mm *MetricMap[L]) cardinalityLimit() int {
+	if limit := mm.limit.Load(); limit != 0 {
+		return int(limit)
+	}
+	return int(defaultCardinalityLimit.Load())
+}
+
 // Get returns the metric with the provided labels, constructing it if it
 // doesn't already exist. Multiple calls to Get with the same labels will
 // return the same metric.
@@ -358,15 +516,53 @@ func (mm *MetricMap[L]) Get(labels L) *Metric {
 	if metric, ok := mm.metrics[labels]; ok {
 		return metric
 	}
+	if limit := mm.cardinalityLimit(); limit > 0 && len(mm.metrics) >= limit {
+		return mm.overflowLocked(labels, limit)
+	}
 	config := mm.config
 	config.Labels = func() map[string]string {
-		return mm.extractor.Extract(labels)
+		return mm.labels(labels)
 	}
 	metric := newMetric(config)
 	mm.metrics[labels] = metric
 	return metric
 }
 
+// overflowLocked returns the overflow metric, recording that labels were
+// dropped. Labels that were already dropped are not counted again, as long as
+// no more than limit distinct labels have been dropped. Past that point, the
+// map stops remembering dropped labels, to bound its memory usage, and counts
+// every call.
+//
+// REQUIRES: mm.mu is held.
+func (mm *MetricMap[L]) overflowLocked(labels L, limit int) *Metric {
+	if _, ok := mm.dropped[labels]; ok {
+		return mm.overflow
+	}
+	if mm.overflow == nil {
+		config := mm.config
+		config.Labels = func() map[string]string {
+			labels := mm.labels(*new(L))
+			for name := range labels {
+				labels[name] = OverflowLabelValue
+			}
+			return labels
+		}
+		mm.overflow = newMetric(config)
+		mm.dropped = map[L]struct{}{}
+		mm.counter = droppedLabels().Get(droppedLabelsLabels{Metric: mm.config.Name})
+	}
+	if !mm.warned {
+		mm.warned = true
+		fmt.Fprintf(os.Stderr, "metric %q: more than %d distinct labels; recording new labels with value %q\n", mm.config.Name, limit, OverflowLabelValue)
+	}
+	if len(mm.dropped) < limit {
+		mm.dropped[labels] = struct{}{}
+	}
+	mm.counter.Inc()
+	return mm.overflow
+}
+
 // Snapshot returns a snapshot of all currently registered metrics. The
 // snapshot is not guaranteed to be atomic.
 func Snapshot() []*MetricSnapshot {
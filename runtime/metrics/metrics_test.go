@@ -38,6 +38,8 @@ const (
 func clear() {
 	metricNames = map[string]bool{}
 	metrics = []*Metric{}
+	droppedLabelsOnce = sync.Once{}
+	droppedLabelsMap = nil
 }
 
 func TestMetrics(t *testing.T) {
@@ -79,6 +81,39 @@ func TestMetrics(t *testing.T) {
 	}
 }
 
+func TestReset(t *testing.T) {
+	clear()
+	counter := Register(counterType, "TestReset/counter", "", nil)
+	histogram := Register(histogramType, "TestReset/histogram", "", []float64{0, 10})
+	counter.Inc()
+	counter.Add(2.5)
+	histogram.Put(5)
+	histogram.Put(50)
+
+	counter.Reset()
+	histogram.Reset()
+	if got := counter.Snapshot().Value; got != 0 {
+		t.Errorf("counter after Reset: got %f, want 0", got)
+	}
+	snap := histogram.Snapshot()
+	if snap.Value != 0 {
+		t.Errorf("histogram sum after Reset: got %f, want 0", snap.Value)
+	}
+	if diff := cmp.Diff([]uint64{0, 0, 0}, snap.Counts); diff != "" {
+		t.Errorf("histogram counts after Reset (-want +got):\n%s", diff)
+	}
+
+	// The metrics keep working after a reset.
+	counter.Inc()
+	histogram.Put(5)
+	if got := counter.Snapshot().Value; got != 1 {
+		t.Errorf("counter: got %f, want 1", got)
+	}
+	if diff := cmp.Diff([]uint64{0, 1, 0}, histogram.Snapshot().Counts); diff != "" {
+		t.Errorf("histogram counts (-want +got):\n%s", diff)
+	}
+}
+
 func TestGet(t *testing.T) {
 	clear()
 	type dog struct {
@@ -110,6 +145,86 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestCardinalityLimit(t *testing.T) {
+	clear()
+	type labels struct {
+		User string
+		Code int
+	}
+	counter := RegisterMap[labels](counterType, "TestCardinalityLimit/counter", "", nil)
+	counter.SetCardinalityLimit(2)
+
+	counter.Get(labels{"alice", 200}).Add(1)
+	counter.Get(labels{"bob", 200}).Add(2)
+	counter.Get(labels{"carol", 200}).Add(3)
+	counter.Get(labels{"dave", 404}).Add(4)
+	counter.Get(labels{"carol", 200}).Add(5)
+	counter.Get(labels{"alice", 200}).Add(6)
+
+	got := map[string]float64{}
+	for _, snap := range Snapshot() {
+		if snap.Name == counter.Name() {
+			got[snap.Labels["user"]+"/"+snap.Labels["code"]] = snap.Value
+		}
+	}
+	want := map[string]float64{
+		"alice/200":                 7,
+		"bob/200":                   2,
+		"__overflow__/__overflow__": 12,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("metrics (-want +got):\n%s", diff)
+	}
+
+	// carol and dave are each dropped, but carol is only counted once.
+	dropped := droppedLabels().Get(droppedLabelsLabels{Metric: counter.Name()})
+	if got, want := dropped.get(), 2.0; got != want {
+		t.Fatalf("dropped labels: got %v, want %v", got, want)
+	}
+}
+
+func TestDefaultCardinalityLimit(t *testing.T) {
+	clear()
+	defer SetDefaultCardinalityLimit(DefaultCardinalityLimit)
+	type labels struct{ X int }
+	counter := RegisterMap[labels](counterType, "TestDefaultCardinalityLimit/counter", "", nil)
+	unbounded := RegisterMap[labels](counterType, "TestDefaultCardinalityLimit/unbounded", "", nil)
+	unbounded.SetCardinalityLimit(-1)
+
+	// The default limit applies to maps registered before it is set.
+	SetDefaultCardinalityLimit(3)
+	for i := 0; i < 10; i++ {
+		counter.Get(labels{i})
+		unbounded.Get(labels{i})
+	}
+	if got, want := len(counter.metrics), 3; got != want {
+		t.Errorf("bounded map: got %d metrics, want %d", got, want)
+	}
+	if got, want := len(unbounded.metrics), 10; got != want {
+		t.Errorf("unbounded map: got %d metrics, want %d", got, want)
+	}
+}
+
+func TestCardinalityLimitAllocs(t *testing.T) {
+	clear()
+	type labels struct{ X int }
+	counter := RegisterMap[labels](counterType, "TestCardinalityLimitAllocs/counter", "", nil)
+	counter.SetCardinalityLimit(1)
+	counter.Get(labels{0})
+	counter.Get(labels{1}) // fills the set of dropped labels
+
+	// Get doesn't allocate for labels past the limit, whether or not they
+	// have been seen before.
+	i := 2
+	if allocs := testing.AllocsPerRun(100, func() {
+		counter.Get(labels{1}).Inc()
+		counter.Get(labels{i}).Inc()
+		i++
+	}); allocs != 0 {
+		t.Errorf("Get: got %v allocs, want 0", allocs)
+	}
+}
+
 func TestSnapshot(t *testing.T) {
 	clear()
 
@@ -0,0 +1,134 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	placementKey      = "github.com/ServiceWeaver/weaver/placement"
+	shortPlacementKey = "placement"
+)
+
+// Placement holds the placement hints listed in the [placement] section of a
+// config file. For example:
+//
+//	[placement]
+//	colocate = [["main/A", "main/B"]]
+//	separate = [["main/C", "main/D"]]
+//	singleton = ["main/E"]
+type Placement struct {
+	// Colocate lists groups of components that should be hosted by the same
+	// OS process.
+	Colocate [][]string
+
+	// Separate lists groups of components, no two of which should be hosted
+	// by the same OS process.
+	Separate [][]string
+
+	// Singleton lists components that should have at most one replica
+	// across the whole deployment. Deployers run a single replica of the
+	// colocation group hosting a singleton component. The multiprocess
+	// deployer starts a replacement if that replica fails; the SSH deployer
+	// doesn't replace failed replicas.
+	Singleton []string
+}
+
+// IsSingleton returns whether any of the provided components, typically the
+// components of a colocation group, is a singleton.
+func (p *Placement) IsSingleton(components ...string) bool {
+	for _, c := range components {
+		if slices.Contains(p.Singleton, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePlacement returns the placement hints in the [placement] section of
+// the provided config. It returns an empty Placement if the section is
+// missing.
+//
+// Note that ParseConfig appends the colocation groups of the [placement]
+// section to config.Colocate, so deployers that honor config.Colocate honor
+// the colocation hints as well.
+func ParsePlacement(config *protos.AppConfig) (*Placement, error) {
+	placement := &Placement{}
+	if err := ParseConfigSection(placementKey, shortPlacementKey, config.Sections, placement); err != nil {
+		return nil, err
+	}
+	return placement, nil
+}
+
+// extractPlacement parses the [placement] section of the provided config and
+// appends its colocation groups to config.Colocate.
+func extractPlacement(config *protos.AppConfig) (*Placement, error) {
+	placement, err := ParsePlacement(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, colocate := range placement.Colocate {
+		group := &protos.ComponentGroup{Components: colocate}
+		config.Colocate = append(config.Colocate, group)
+	}
+	return placement, nil
+}
+
+// checkPlacement checks that no two components that should be separated are
+// colocated. The returned error lists all contradictory rules.
+//
+// REQUIRES: checkSameProcess(c) succeeds.
+func checkPlacement(c *protos.AppConfig, separate [][]string) error {
+	// Map every component to the colocation group it belongs to. Every
+	// component belongs to at most one group.
+	groups := map[string]*protos.ComponentGroup{}
+	for _, group := range c.Colocate {
+		for _, component := range group.Components {
+			groups[component] = group
+		}
+	}
+
+	var conflicts []string
+	for _, components := range separate {
+		// Report every colocation group at most once per separation group.
+		reported := map[*protos.ComponentGroup]bool{}
+		seen := map[*protos.ComponentGroup]string{}
+		for _, component := range components {
+			group, ok := groups[component]
+			if !ok {
+				continue
+			}
+			other, ok := seen[group]
+			if !ok {
+				seen[group] = component
+				continue
+			}
+			if other == component || reported[group] {
+				continue
+			}
+			reported[group] = true
+			conflicts = append(conflicts, fmt.Sprintf("colocate %q contradicts separate %q", group.Components, components))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("contradictory placement rules:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+	return nil
+}
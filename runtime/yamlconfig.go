@@ -0,0 +1,136 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// isYAMLConfig returns whether the config file with the provided name holds
+// YAML, rather than TOML, based on the file's extension.
+func isYAMLConfig(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// yamlSections returns the sections of the provided YAML config, keyed by
+// section name, in TOML format. Every top-level key of the config must map
+// to a section. For example, the following YAML config
+//
+//	serviceweaver:
+//	  binary: ./app
+//	github.com/example/cache/Cache:
+//	  size: 100
+//
+// is equivalent to this TOML config:
+//
+//	[serviceweaver]
+//	binary = "./app"
+//
+//	["github.com/example/cache/Cache"]
+//	size = 100
+func yamlSections(input string) (map[string]string, error) {
+	var sections map[string]any
+	if err := yaml.Unmarshal([]byte(input), &sections); err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(sections))
+	for k, v := range sections {
+		section, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("section %q: got %T, want a mapping", k, v)
+		}
+		var buf strings.Builder
+		if err := toml.NewEncoder(&buf).Encode(section); err != nil {
+			return nil, fmt.Errorf("encoding section %q: %w", k, err)
+		}
+		result[k] = buf.String()
+	}
+	return result, nil
+}
+
+// decodeSection decodes the provided section into dst, like toml.Decode.
+// Settings are matched to the fields of dst by field name or toml tag, as
+// usual, and settings that match neither are matched to the top-level fields
+// of dst by yaml tag, so that a config struct shared with YAML-based tools
+// can be read from a config file in either format.
+func decodeSection(section string, dst any) (toml.MetaData, error) {
+	renames := yamlRenames(reflect.TypeOf(dst))
+	if len(renames) == 0 {
+		return toml.Decode(section, dst)
+	}
+	var settings map[string]any
+	if _, err := toml.Decode(section, &settings); err != nil {
+		return toml.MetaData{}, err
+	}
+	renamed := false
+	for tag, field := range renames {
+		v, ok := settings[tag]
+		if !ok {
+			continue
+		}
+		if _, ok := settings[field]; ok {
+			// The field is also set by name, which takes precedence.
+			continue
+		}
+		delete(settings, tag)
+		settings[field] = v
+		renamed = true
+	}
+	if !renamed {
+		return toml.Decode(section, dst)
+	}
+	var buf strings.Builder
+	if err := toml.NewEncoder(&buf).Encode(settings); err != nil {
+		return toml.MetaData{}, err
+	}
+	return toml.Decode(buf.String(), dst)
+}
+
+// yamlRenames returns a map from the yaml tag of every top-level field of
+// the struct pointed to by t that toml wouldn't otherwise match, to the
+// field's name.
+func yamlRenames(t reflect.Type) map[string]string {
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	t = t.Elem()
+	var renames map[string]string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("toml") != "" {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" || strings.EqualFold(tag, f.Name) {
+			continue
+		}
+		if renames == nil {
+			renames = map[string]string{}
+		}
+		renames[tag] = f.Name
+	}
+	return renames
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// RuntimeInfo describes a component and the deployment and weavelet hosting
+// it. See Instance.Runtime.
+//
+// Under weavertest, the IDs are synthetic, but every component and replica
+// in a test observes the same deployment ID for the duration of the test.
+type RuntimeInfo struct {
+	App           string // application name
+	DeploymentID  string // unique id of the deployment
+	WeaveletID    string // unique id of the weavelet hosting the component
+	Component     string // full component name, e.g., "example.com/pkg/Cache"
+	Routed        bool   // is the component routed? see WithRouter
+	SingleProcess bool   // is the application running in a single process?
+
+	// Listeners maps the name of every listener of the hosting weavelet
+	// that has been created so far to the address it is listening on.
+	Listeners map[string]string
+
+	// PprofAddress is the address on which the hosting weavelet serves the
+	// net/http/pprof handlers, or empty if the pprof_address config field is
+	// not set.
+	PprofAddress string
+}
+
+// Runtime implements the Instance interface.
+func (c *componentImpl) Runtime() RuntimeInfo {
+	w := c.component.wlet
+	return RuntimeInfo{
+		App:           w.info.App,
+		DeploymentID:  w.info.DeploymentId,
+		WeaveletID:    w.info.Id,
+		Component:     c.component.info.Name,
+		Routed:        c.component.info.Routed,
+		SingleProcess: w.info.SingleProcess,
+		Listeners:     w.listenerAddresses(),
+		PprofAddress:  w.pprofAddr,
+	}
+}
+
+// listenerAddresses returns the addresses of the weavelet's initialized
+// listeners, keyed by listener name.
+func (w *weavelet) listenerAddresses() map[string]string {
+	w.listenersMu.Lock()
+	defer w.listenersMu.Unlock()
+	addrs := map[string]string{}
+	for name, ls := range w.listeners {
+		select {
+		case <-ls.initialized:
+			addrs[name] = ls.addr
+		default:
+			// Not yet listening.
+		}
+	}
+	return addrs
+}
+
+// RegionEnvVar is the environment variable from which ComponentInfo reads the
+// region a component runs in. Deployers that place replicas in regions set it
+// in the environment of every process they start.
+const RegionEnvVar = "SERVICEWEAVER_REGION"
+
+// ComponentMetadata describes the component whose method is running. See
+// ComponentInfo.
+type ComponentMetadata struct {
+	ComponentName string // full component name, e.g., "example.com/pkg/Cache"
+	ReplicaCount  int    // number of replicas of the component currently known
+	Version       string // version of the application, i.e., its deployment id
+	Region        string // region the replica runs in; see RegionEnvVar
+}
+
+// ComponentInfo returns metadata about the component whose method is being
+// executed with the provided context. The context passed to a component
+// method, whether the method is called locally or remotely, carries the name
+// of the component, which ComponentInfo combines with what the weavelet
+// hosting the component currently knows about the deployment. For example:
+//
+//	func (c *cache) Get(ctx context.Context, key string) (string, error) {
+//	    info := weaver.ComponentInfo(ctx)
+//	    c.Logger(ctx).Debug("get", "replicas", info.ReplicaCount, "region", info.Region)
+//	    ...
+//	}
+//
+// ReplicaCount is computed like the replicas reported by Status, so it may
+// briefly lag behind the deployment as replicas start and stop.
+//
+// If ctx is not the context of a component method (e.g., it is the context
+// of a test or of an HTTP handler), ComponentInfo returns a ComponentMetadata
+// whose only non-zero field is ComponentName, set to the name of the running
+// program.
+func ComponentInfo(ctx context.Context) ComponentMetadata {
+	info, ok := codegen.CallerInfoFromContext(ctx)
+	if !ok || info.Callee == "" {
+		return ComponentMetadata{ComponentName: filepath.Base(os.Args[0])}
+	}
+	md := ComponentMetadata{
+		ComponentName: info.Callee,
+		Region:        os.Getenv(RegionEnvVar),
+	}
+	w, err := weaveletFromContext(ctx)
+	if err != nil {
+		return md
+	}
+	md.Version = w.info.DeploymentId
+	if c := w.status().Component(info.Callee); c != nil {
+		md.ReplicaCount = len(c.Replicas)
+		if md.ReplicaCount == 0 && c.Local {
+			// In a single process, a local component has no address, but it
+			// is running.
+			md.ReplicaCount = 1
+		}
+	}
+	return md
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"os"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/metrics"
+)
+
+// defaultRuntimeMetricsInterval is the interval at which runtime metrics are
+// sampled, if not specified in the config.
+const defaultRuntimeMetricsInterval = 10 * time.Second
+
+// runtimeLabels are the labels of the Go runtime and process metrics.
+type runtimeLabels struct {
+	Weavelet string // weavelet id
+}
+
+var (
+	// The following metrics are sampled periodically by every weavelet,
+	// unless disabled in the config.
+	runtimeGoroutines = metrics.NewGaugeMap[runtimeLabels](
+		"serviceweaver_runtime_goroutines",
+		"Number of goroutines in a Service Weaver process",
+	)
+	runtimeHeapAlloc = metrics.NewGaugeMap[runtimeLabels](
+		"serviceweaver_runtime_heap_alloc_bytes",
+		"Bytes of allocated heap objects in a Service Weaver process",
+	)
+	runtimeHeapInuse = metrics.NewGaugeMap[runtimeLabels](
+		"serviceweaver_runtime_heap_inuse_bytes",
+		"Bytes in in-use heap spans in a Service Weaver process",
+	)
+	runtimeGCCount = metrics.NewCounterMap[runtimeLabels](
+		"serviceweaver_runtime_gc_count",
+		"Number of completed garbage collection cycles in a Service Weaver process",
+	)
+	runtimeGCPauses = metrics.NewHistogramMap[runtimeLabels](
+		"serviceweaver_runtime_gc_pause_micros",
+		"Duration, in microseconds, of stop-the-world garbage collection pauses in a Service Weaver process",
+		metrics.NonNegativeBuckets,
+	)
+	processCPUSeconds = metrics.NewCounterMap[runtimeLabels](
+		"serviceweaver_process_cpu_seconds",
+		"Estimated CPU time, in seconds, spent by a Service Weaver process running Go code and the Go runtime",
+	)
+	processOpenFDs = metrics.NewGaugeMap[runtimeLabels](
+		"serviceweaver_process_open_fds",
+		"Number of open file descriptors in a Service Weaver process (Linux only)",
+	)
+	weaveletUptime = metrics.NewGaugeMap[runtimeLabels](
+		"serviceweaver_weavelet_uptime_seconds",
+		"Time, in seconds, since a Service Weaver weavelet started",
+	)
+)
+
+// runtimeSampler samples the Go runtime and process metrics of a weavelet.
+type runtimeSampler struct {
+	start time.Time
+
+	goroutines *metrics.Gauge
+	heapAlloc  *metrics.Gauge
+	heapInuse  *metrics.Gauge
+	gcCount    *metrics.Counter
+	gcPauses   *metrics.Histogram
+	cpuSeconds *metrics.Counter
+	openFDs    *metrics.Gauge
+	uptime     *metrics.Gauge
+
+	// The values of cumulative statistics as of the previous sample. The
+	// counters above are advanced by the difference.
+	numGC uint32
+	cpu   float64
+
+	cpuSamples []rtmetrics.Sample // total and idle CPU time
+}
+
+// newRuntimeSampler returns a sampler for the weavelet with the provided id.
+func newRuntimeSampler(weaveletID string) *runtimeSampler {
+	labels := runtimeLabels{Weavelet: weaveletID}
+	return &runtimeSampler{
+		start:      time.Now(),
+		goroutines: runtimeGoroutines.Get(labels),
+		heapAlloc:  runtimeHeapAlloc.Get(labels),
+		heapInuse:  runtimeHeapInuse.Get(labels),
+		gcCount:    runtimeGCCount.Get(labels),
+		gcPauses:   runtimeGCPauses.Get(labels),
+		cpuSeconds: processCPUSeconds.Get(labels),
+		openFDs:    processOpenFDs.Get(labels),
+		uptime:     weaveletUptime.Get(labels),
+		cpuSamples: []rtmetrics.Sample{
+			{Name: "/cpu/classes/total:cpu-seconds"},
+			{Name: "/cpu/classes/idle:cpu-seconds"},
+		},
+	}
+}
+
+// run samples metrics every interval until ctx is cancelled.
+func (s *runtimeSampler) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.sample()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample updates the metrics with the current state of the process.
+func (s *runtimeSampler) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	s.goroutines.Set(float64(runtime.NumGoroutine()))
+	s.heapAlloc.Set(float64(stats.HeapAlloc))
+	s.heapInuse.Set(float64(stats.HeapInuse))
+
+	// PauseNs is a circular buffer holding the pauses of the most recent 256
+	// garbage collections. Pauses that were overwritten before being sampled
+	// are lost.
+	if n := stats.NumGC - s.numGC; n > 0 {
+		s.gcCount.Add(float64(n))
+		if n > uint32(len(stats.PauseNs)) {
+			n = uint32(len(stats.PauseNs))
+		}
+		for i := stats.NumGC - n + 1; i <= stats.NumGC; i++ {
+			pause := stats.PauseNs[(i+uint32(len(stats.PauseNs))-1)%uint32(len(stats.PauseNs))]
+			s.gcPauses.Put(float64(pause) / 1000)
+		}
+		s.numGC = stats.NumGC
+	}
+
+	// The Go runtime estimates the CPU time available to the process, and
+	// how much of it was left idle.
+	rtmetrics.Read(s.cpuSamples)
+	total, idle := s.cpuSamples[0].Value, s.cpuSamples[1].Value
+	if total.Kind() == rtmetrics.KindFloat64 && idle.Kind() == rtmetrics.KindFloat64 {
+		if cpu := total.Float64() - idle.Float64(); cpu > s.cpu {
+			s.cpuSeconds.Add(cpu - s.cpu)
+			s.cpu = cpu
+		}
+	}
+
+	// The directory holds an entry for every open file descriptor, including
+	// the one used to read it.
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		s.openFDs.Set(float64(len(fds) - 1))
+	}
+
+	s.uptime.Set(time.Since(s.start).Seconds())
+}
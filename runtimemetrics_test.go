@@ -0,0 +1,67 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+)
+
+func TestRuntimeSampler(t *testing.T) {
+	const id = "TestRuntimeSampler"
+	s := newRuntimeSampler(id)
+	s.sample()
+	runtime.GC()
+	s.sample()
+
+	got := map[string]*metrics.MetricSnapshot{}
+	for _, m := range metrics.Snapshot() {
+		if m.Labels["weavelet"] == id {
+			got[m.Name] = m
+		}
+	}
+	for _, name := range []string{
+		"serviceweaver_runtime_goroutines",
+		"serviceweaver_runtime_heap_alloc_bytes",
+		"serviceweaver_runtime_heap_inuse_bytes",
+		"serviceweaver_runtime_gc_count",
+		"serviceweaver_weavelet_uptime_seconds",
+	} {
+		m, ok := got[name]
+		if !ok {
+			t.Errorf("metric %q not found", name)
+			continue
+		}
+		if m.Value <= 0 {
+			t.Errorf("metric %q: got %v, want > 0", name, m.Value)
+		}
+	}
+
+	// The forced garbage collection should be recorded in the pause
+	// histogram.
+	pauses, ok := got["serviceweaver_runtime_gc_pause_micros"]
+	if !ok {
+		t.Fatal("metric serviceweaver_runtime_gc_pause_micros not found")
+	}
+	var n uint64
+	for _, c := range pauses.Counts {
+		n += c
+	}
+	if n == 0 {
+		t.Error("no garbage collection pauses recorded")
+	}
+}
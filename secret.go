@@ -0,0 +1,272 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"golang.org/x/exp/slog"
+)
+
+// redacted is printed in place of the value of a Secret.
+const redacted = "REDACTED"
+
+// Secret is a secret value, like a password or an API key, that Service
+// Weaver reads from a secret source when it constructs a component. Add a
+// Secret field in a component implementation struct and tag it with the
+// secret's source, in the form "scheme:name":
+//
+//	type db struct {
+//	    weaver.Implements[DB]
+//	    password weaver.Secret `weaver:"env:DB_PASSWORD"`
+//	    apiKey   weaver.Secret `weaver:"file:/run/secrets/api_key"`
+//	    token    weaver.Secret `weaver:"vault:db/token,optional"`
+//	}
+//
+// The "env" scheme reads an environment variable, and the "file" scheme reads
+// the contents of a file, without a trailing newline. Other schemes are
+// served by providers registered with RegisterSecretProvider.
+//
+// A secret is required, unless its tag has the "optional" option. A component
+// with a missing required secret fails to start. The secrets are filled in
+// before the component's Init method is called.
+//
+// A Secret never reveals its value when printed, logged, or marshaled to
+// JSON, even as a field of a struct that is printed as a whole. Call Value to
+// get the value.
+type Secret struct {
+	// The value is returned by a function, since fmt prints a function as an
+	// address. This way, printing a struct with an unexported Secret field,
+	// which fmt can't call String on, doesn't reveal the value either.
+	value func() string
+}
+
+// newSecret returns a Secret with the provided value.
+func newSecret(value string) Secret {
+	return Secret{value: func() string { return value }}
+}
+
+// Value returns the value of the secret, or the empty string if an optional
+// secret wasn't found.
+func (s Secret) Value() string {
+	if s.value == nil {
+		return ""
+	}
+	return s.value()
+}
+
+// String implements the fmt.Stringer interface. It doesn't reveal the value.
+func (s Secret) String() string { return redacted }
+
+// GoString implements the fmt.GoStringer interface. It doesn't reveal the
+// value.
+func (s Secret) GoString() string { return "weaver.Secret{" + redacted + "}" }
+
+// LogValue implements the slog.LogValuer interface. It doesn't reveal the
+// value.
+func (s Secret) LogValue() slog.Value { return slog.StringValue(redacted) }
+
+// MarshalText implements the encoding.TextMarshaler interface, which is also
+// used when marshaling to JSON. It doesn't reveal the value.
+func (s Secret) MarshalText() ([]byte, error) { return []byte(redacted), nil }
+
+// A SecretProvider looks up secrets for the components of an application.
+// Register a provider for a scheme with RegisterSecretProvider to read the
+// Secret fields tagged with that scheme from a custom secret backend.
+//
+// A provider is called once per secret of a component, and may be called
+// concurrently. When an application runs in a single process, the secrets of
+// every component are looked up when the application starts, even those of
+// components that are never constructed, so that a missing secret is reported
+// right away. Otherwise, the secrets of a component are looked up when the
+// component is constructed, only in the processes that host it.
+type SecretProvider interface {
+	// LookupSecret returns the value of the secret with the provided name,
+	// which is the part of the field's tag after the scheme. It returns
+	// false if the secret doesn't exist, and an error if the lookup fails.
+	LookupSecret(ctx context.Context, name string) (string, bool, error)
+}
+
+// SecretProviderFunc is an adapter that allows the use of an ordinary
+// function as a SecretProvider.
+type SecretProviderFunc func(ctx context.Context, name string) (string, bool, error)
+
+// LookupSecret implements the SecretProvider interface.
+func (f SecretProviderFunc) LookupSecret(ctx context.Context, name string) (string, bool, error) {
+	return f(ctx, name)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"env":  SecretProviderFunc(lookupEnvSecret),
+		"file": SecretProviderFunc(lookupFileSecret),
+	}
+)
+
+// RegisterSecretProvider registers the provider of the secrets with the
+// provided scheme. It is typically called from an init function. It panics if
+// the scheme is empty or contains a colon, or if a provider for the scheme is
+// already registered.
+func RegisterSecretProvider(scheme string, p SecretProvider) {
+	if scheme == "" || strings.Contains(scheme, ":") {
+		panic(fmt.Sprintf("weaver.RegisterSecretProvider: invalid scheme %q", scheme))
+	}
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	if _, ok := secretProviders[scheme]; ok {
+		panic(fmt.Sprintf("weaver.RegisterSecretProvider: scheme %q already registered", scheme))
+	}
+	secretProviders[scheme] = p
+}
+
+// secretProvider returns the provider for the provided scheme.
+func secretProvider(scheme string) (SecretProvider, bool) {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	p, ok := secretProviders[scheme]
+	return p, ok
+}
+
+func lookupEnvSecret(_ context.Context, name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	return value, ok, nil
+}
+
+func lookupFileSecret(_ context.Context, name string) (string, bool, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), true, nil
+}
+
+// secretField is a Secret field in a component implementation struct.
+type secretField struct {
+	index    int    // field index
+	field    string // field name
+	scheme   string // provider scheme, e.g., "env"
+	name     string // secret name, e.g., "DB_PASSWORD"
+	optional bool   // is the secret optional?
+}
+
+// secretFields returns the Secret fields of the provided component
+// implementation struct type, checking that their tags are valid.
+func secretFields(impl reflect.Type) ([]secretField, error) {
+	if impl.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	secretType := reflection.Type[Secret]()
+	var fields []secretField
+	for i, n := 0, impl.NumField(); i < n; i++ {
+		f := impl.Field(i)
+		if f.Type != secretType {
+			continue
+		}
+		scheme, name, optional, err := parseSecretTag(f.Tag.Get("weaver"))
+		if err != nil {
+			return nil, fmt.Errorf("secret field %v.%s: %w", impl, f.Name, err)
+		}
+		if _, ok := secretProvider(scheme); !ok {
+			return nil, fmt.Errorf("secret field %v.%s: no provider registered for scheme %q", impl, f.Name, scheme)
+		}
+		fields = append(fields, secretField{
+			index:    i,
+			field:    f.Name,
+			scheme:   scheme,
+			name:     name,
+			optional: optional,
+		})
+	}
+	return fields, nil
+}
+
+// parseSecretTag parses the weaver struct tag of a Secret field. The tag has
+// the form "scheme:name[,optional]".
+func parseSecretTag(tag string) (scheme, name string, optional bool, err error) {
+	source, rest, _ := strings.Cut(tag, ",")
+	scheme, name, ok := strings.Cut(source, ":")
+	if !ok || scheme == "" || name == "" {
+		return "", "", false, fmt.Errorf(`secret tag %q is not of the form "scheme:name"`, tag)
+	}
+	if rest != "" {
+		if rest != "optional" {
+			return "", "", false, fmt.Errorf("secret tag %q has unknown option %q", tag, rest)
+		}
+		optional = true
+	}
+	return scheme, name, optional, nil
+}
+
+// lookup looks up the value of the secret. It returns an error if the secret
+// is required but missing. Note that errors never include secret values.
+func (f secretField) lookup(ctx context.Context) (Secret, error) {
+	p, ok := secretProvider(f.scheme)
+	if !ok {
+		return Secret{}, fmt.Errorf("secret %s: no provider registered for scheme %q", f.field, f.scheme)
+	}
+	value, ok, err := p.LookupSecret(ctx, f.name)
+	if err != nil {
+		return Secret{}, fmt.Errorf("secret %s: lookup %s:%s: %w", f.field, f.scheme, f.name, err)
+	}
+	if !ok {
+		if f.optional {
+			return Secret{}, nil
+		}
+		return Secret{}, fmt.Errorf("secret %s: required secret %s:%s not found", f.field, f.scheme, f.name)
+	}
+	return newSecret(value), nil
+}
+
+// lookupSecrets looks up the values of the provided secrets, in order. It
+// returns an error listing every required secret that is missing.
+func lookupSecrets(ctx context.Context, fields []secretField) ([]Secret, error) {
+	secrets := make([]Secret, len(fields))
+	var errs []error
+	for i, f := range fields {
+		secret, err := f.lookup(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		secrets[i] = secret
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// fillSecrets initializes the provided Secret fields of a component
+// implementation struct with the provided values, as returned by
+// lookupSecrets. impl should be a pointer to the struct.
+func fillSecrets(impl any, fields []secretField, secrets []Secret) {
+	s := reflect.ValueOf(impl).Elem()
+	for i, f := range fields {
+		setPossiblyUnexported(s.Field(f.index), reflect.ValueOf(secrets[i]))
+	}
+}
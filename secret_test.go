@@ -0,0 +1,174 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slog"
+)
+
+func init() {
+	RegisterSecretProvider("test", SecretProviderFunc(func(_ context.Context, name string) (string, bool, error) {
+		switch name {
+		case "found":
+			return "test-value", true, nil
+		case "broken":
+			return "", false, fmt.Errorf("backend unavailable")
+		}
+		return "", false, nil
+	}))
+}
+
+func TestParseSecretTag(t *testing.T) {
+	for _, test := range []struct {
+		tag      string
+		scheme   string
+		name     string
+		optional bool
+	}{
+		{"env:DB_PASSWORD", "env", "DB_PASSWORD", false},
+		{"file:/run/secrets/key", "file", "/run/secrets/key", false},
+		{"vault:db/token:v2,optional", "vault", "db/token:v2", true},
+	} {
+		scheme, name, optional, err := parseSecretTag(test.tag)
+		if err != nil {
+			t.Errorf("parseSecretTag(%q): %v", test.tag, err)
+			continue
+		}
+		if scheme != test.scheme || name != test.name || optional != test.optional {
+			t.Errorf("parseSecretTag(%q): got (%q, %q, %v), want (%q, %q, %v)", test.tag, scheme, name, optional, test.scheme, test.name, test.optional)
+		}
+	}
+
+	for _, tag := range []string{"", "DB_PASSWORD", ":name", "env:", "env:X,required"} {
+		if _, _, _, err := parseSecretTag(tag); err == nil {
+			t.Errorf("parseSecretTag(%q): unexpected success", tag)
+		}
+	}
+}
+
+func TestFillSecrets(t *testing.T) {
+	t.Setenv("WEAVER_TEST_SECRET", "env-value")
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("file-value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type impl struct {
+		A Secret `weaver:"env:WEAVER_TEST_SECRET"`
+		b Secret `weaver:"test:found"`
+		c Secret `weaver:"test:missing,optional"`
+		d Secret `weaver:"file:placeholder"`
+	}
+	fields, err := secretFields(reflect.TypeOf(impl{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields[3].name = file // only known at runtime
+	secrets, err := lookupSecrets(context.Background(), fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var x impl
+	fillSecrets(&x, fields, secrets)
+	for _, test := range []struct {
+		field string
+		got   Secret
+		want  string
+	}{
+		{"A", x.A, "env-value"},
+		{"b", x.b, "test-value"},
+		{"c", x.c, ""},
+		{"d", x.d, "file-value"},
+	} {
+		if got := test.got.Value(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.field, got, test.want)
+		}
+	}
+}
+
+func TestSecretErrors(t *testing.T) {
+	type untagged struct {
+		s Secret
+	}
+	if _, err := secretFields(reflect.TypeOf(untagged{})); err == nil {
+		t.Error("untagged: unexpected success")
+	}
+
+	type unknownScheme struct {
+		s Secret `weaver:"unknown:name"`
+	}
+	if _, err := secretFields(reflect.TypeOf(unknownScheme{})); err == nil || !strings.Contains(err.Error(), "no provider") {
+		t.Errorf("unknown scheme: got error %v, want no provider error", err)
+	}
+
+	type missing struct {
+		a Secret `weaver:"env:WEAVER_TEST_MISSING_SECRET"`
+		b Secret `weaver:"file:/nonexistent/secret"`
+		c Secret `weaver:"test:broken"`
+	}
+	fields, err := secretFields(reflect.TypeOf(missing{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = lookupSecrets(context.Background(), fields)
+	for _, want := range []string{"WEAVER_TEST_MISSING_SECRET not found", "/nonexistent/secret not found", "backend unavailable"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("lookupSecrets: got error %v, want error containing %q", err, want)
+		}
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	const value = "hunter2"
+	x := struct {
+		Exported   Secret
+		unexported Secret
+	}{
+		Exported:   newSecret(value),
+		unexported: newSecret(value),
+	}
+
+	var outputs []string
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		outputs = append(outputs, fmt.Sprintf(format, x), fmt.Sprintf(format, &x), fmt.Sprintf(format, x.Exported))
+	}
+	data, err := json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs = append(outputs, string(data))
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("impl", "struct", x, "secret", x.Exported)
+	outputs = append(outputs, buf.String())
+
+	for _, output := range outputs {
+		if strings.Contains(output, value) {
+			t.Errorf("secret value revealed: %s", output)
+		}
+	}
+	if got, want := x.unexported.Value(), value; got != want {
+		t.Errorf("Value: got %q, want %q", got, want)
+	}
+}
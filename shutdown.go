@@ -0,0 +1,143 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ServiceWeaver/weaver/internal/cond"
+)
+
+// Shutdown gracefully shuts down the components hosted by the weavelet
+// running the caller, e.g., from a signal handler in the app passed to Run:
+//
+//	func serve(ctx context.Context, app *app) error {
+//	    go func() {
+//	        <-sigterm
+//	        weaver.Shutdown(ctx)
+//	    }()
+//	    ...
+//	}
+//
+// Shutdown quiesces every hosted component (see Quiesce) and waits, for at
+// most the drain_timeout, for the remote calls they are executing to finish.
+// It then stops the servers started with Listener.Serve, Listener.ServeGRPC,
+// and Serve, waiting for their in-flight requests, and closes every Listener.
+// Next, it waits for the events published by the weavelet to be delivered
+// (see Publisher), and calls the Shutdown method of every component that
+// implements Finalizable. Shutdown returns when all of this is done, or when
+// ctx is done, in which case it returns an error that wraps ctx.Err().
+//
+// The weavelet is shut down at most once. Concurrent and later calls to
+// Shutdown wait for the shutdown in progress to finish, or for their ctx to
+// be done, and return its result. Run returns once the weavelet is shut down;
+// in a process that doesn't host weaver.Main, calling Shutdown makes Run
+// return.
+func Shutdown(ctx context.Context) error {
+	w, err := weaveletFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("weaver.Shutdown: %w", err)
+	}
+	return w.Shutdown(ctx)
+}
+
+// quiesceAll quiesces every component hosted by the weavelet, and waits, for
+// at most the drain timeout, for the remote calls they are executing to
+// finish.
+func (w *weavelet) quiesceAll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.drainTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for c := range w.initializedSet() {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.quiesce.quiesce(ctx, c.info.Name); err != nil {
+				w.env.SystemLogger().Error("Quiescing component did not finish", "component", c.info.Name, "err", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// stopListeners stops the servers started on the weavelet's listeners,
+// waiting for them to shut down or for ctx to be done, and then closes the
+// listeners.
+func (w *weavelet) stopListeners(ctx context.Context) {
+	w.cancelListeners()
+	if err := w.servers.wait(ctx); err != nil {
+		w.env.SystemLogger().Error("Stopping listener servers did not finish", "err", err)
+	}
+
+	// Note that closing a Unix domain socket listener removes its socket
+	// file.
+	w.listenersMu.Lock()
+	defer w.listenersMu.Unlock()
+	for _, ls := range w.listeners {
+		if ls.lis != nil {
+			ls.lis.Close()
+			ls.lis = nil
+		}
+	}
+}
+
+// activeServers counts the servers running on the listeners of a weavelet,
+// so that Shutdown can wait for them to stop. A nil *activeServers counts
+// nothing.
+type activeServers struct {
+	mu      sync.Mutex
+	changed *cond.Cond // broadcast when n drops to zero
+	n       int
+}
+
+func newActiveServers() *activeServers {
+	s := &activeServers{}
+	s.changed = cond.NewCond(&s.mu)
+	return s
+}
+
+// start records that a server started, and returns the function to call
+// when it stops.
+func (s *activeServers) start() func() {
+	if s == nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n++
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.n--
+		if s.n == 0 {
+			s.changed.Broadcast()
+		}
+	}
+}
+
+// wait waits for every started server to stop, or for ctx to be done.
+func (s *activeServers) wait(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.n > 0 {
+		if err := s.changed.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActiveServers(t *testing.T) {
+	ctx := context.Background()
+	s := newActiveServers()
+	if err := s.wait(ctx); err != nil {
+		t.Fatalf("wait with no servers: %v", err)
+	}
+
+	stop1, stop2 := s.start(), s.start()
+	stop1()
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := s.wait(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait with a running server: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	go stop2()
+	if err := s.wait(ctx); err != nil {
+		t.Fatalf("wait after servers stopped: %v", err)
+	}
+
+	// A nil *activeServers counts nothing.
+	var none *activeServers
+	none.start()()
+}
+
+func TestShutdownInProgress(t *testing.T) {
+	// Simulate a shutdown that is in progress.
+	w := &weavelet{shuttingDown: make(chan struct{}), shutdownDone: make(chan struct{})}
+	close(w.shuttingDown)
+
+	// Shutdown waits for the shutdown in progress, until its ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown during shutdown: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Once the shutdown finishes, Shutdown returns its result.
+	want := errors.New("component shutdown failed")
+	w.shutdownErr = want
+	close(w.shutdownDone)
+	if err := w.Shutdown(context.Background()); err != want {
+		t.Fatalf("Shutdown after shutdown: got %v, want %v", err, want)
+	}
+}
+
+func TestShutdownOutsideWeavelet(t *testing.T) {
+	if err := Shutdown(context.Background()); err == nil {
+		t.Fatal("Shutdown without a weavelet: unexpected success")
+	}
+}
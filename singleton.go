@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Singleton is a type that can be embedded inside a component implementation
+// struct to ensure that at most one instance of the component exists in a
+// process, e.g., because the component manages a process-wide resource like
+// a GPU context:
+//
+//	type gpu struct {
+//	    weaver.Implements[GPU]
+//	    weaver.Singleton
+//	    // ...
+//	}
+//
+// Service Weaver never creates more than one instance of a component in a
+// weavelet. Singleton extends that guarantee to the whole process: creating a
+// singleton component fails if another application running in the same
+// process, e.g., a parallel test, already has a live instance of it. The
+// instance is released when its application shuts down.
+//
+// Singleton does not limit replication. Every replica of a singleton
+// component, i.e., every weavelet process that hosts it, still has an
+// instance of its own. To run a single replica of a component, list it under
+// the singleton placement setting of the config file instead.
+type Singleton struct{}
+
+// processSingleton marks Singleton. The unusual name of the method makes it
+// unlikely to be shadowed by a method of the component implementation.
+//
+//nolint:unused
+func (Singleton) processSingleton() {}
+
+// isSingleton returns whether the provided component implementation type
+// embeds Singleton.
+func isSingleton(impl reflect.Type) bool {
+	_, ok := reflect.New(impl).Interface().(interface{ processSingleton() })
+	return ok
+}
+
+// singletons records the weavelet that holds the live instance of every
+// singleton component in this process, keyed by implementation type.
+var singletons struct {
+	mu     sync.Mutex
+	owners map[reflect.Type]*weavelet
+}
+
+// claimSingleton records that w is creating an instance of the provided
+// singleton component. It returns an error if another weavelet already has
+// an instance of the component. The claim lasts until w is shut down or its
+// context is done, or until releaseSingleton is called.
+func claimSingleton(w *weavelet, c *component) error {
+	singletons.mu.Lock()
+	defer singletons.mu.Unlock()
+	if singletons.owners == nil {
+		singletons.owners = map[reflect.Type]*weavelet{}
+	}
+	if owner, ok := singletons.owners[c.info.Impl]; ok && owner != w {
+		return fmt.Errorf("component %q embeds weaver.Singleton, but weavelet %s in this process already has an instance of it", c.info.Name, owner.info.Id)
+	}
+	singletons.owners[c.info.Impl] = w
+	go func() {
+		<-w.ctx.Done()
+		releaseSingleton(w, c)
+	}()
+	return nil
+}
+
+// releaseSingleton releases w's claim on the provided singleton component,
+// if any.
+func releaseSingleton(w *weavelet, c *component) {
+	singletons.mu.Lock()
+	defer singletons.mu.Unlock()
+	if singletons.owners[c.info.Impl] == w {
+		delete(singletons.owners, c.info.Impl)
+	}
+}
+
+// releaseSingletons releases all of w's claims.
+func releaseSingletons(w *weavelet) {
+	singletons.mu.Lock()
+	defer singletons.mu.Unlock()
+	for impl, owner := range singletons.owners {
+		if owner == w {
+			delete(singletons.owners, impl)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	goruntime "runtime"
+	"syscall"
+	"time"
+)
+
+// defaultTCPKeepAlive is the keep-alive period of the connections accepted by
+// a TCP listener that doesn't set tcp_keep_alive. It matches the default of
+// the net package.
+const defaultTCPKeepAlive = 15 * time.Second
+
+// SocketOptions are the options applied to the socket of a TCP listener. See
+// Listener.SocketOptions.
+type SocketOptions struct {
+	// Is SO_REUSEPORT set on the socket? Set with the reuseport option of the
+	// listener's weaver struct tag, or with reuse_port in the [listeners]
+	// section of the config file.
+	ReusePort bool
+
+	// The keep-alive period of accepted connections, or zero if keep-alives
+	// are disabled. Set with tcp_keep_alive.
+	TCPKeepAlive time.Duration
+
+	// The maximum length of the queue of connections waiting to be accepted,
+	// or zero if it is the operating system's default (e.g., the value of
+	// net.core.somaxconn on Linux). Set with accept_backlog.
+	AcceptBacklog int
+
+	// Is TCP_NODELAY set on accepted connections, disabling Nagle's
+	// algorithm? Set with nodelay.
+	NoDelay bool
+}
+
+// socketOptions returns the socket options set in the listener's config. It
+// returns an error if an option is invalid or is not supported on this
+// platform.
+func (c listenerConfig) socketOptions() (SocketOptions, error) {
+	opts := SocketOptions{
+		ReusePort:     c.ReusePort,
+		TCPKeepAlive:  defaultTCPKeepAlive,
+		AcceptBacklog: c.AcceptBacklog,
+		NoDelay:       true,
+	}
+	if c.ReusePort && setReusePort == nil {
+		return SocketOptions{}, fmt.Errorf("reuse_port is not supported on %s", goruntime.GOOS)
+	}
+	if c.TCPKeepAlive != "" {
+		d, err := time.ParseDuration(c.TCPKeepAlive)
+		if err != nil {
+			return SocketOptions{}, fmt.Errorf("invalid tcp_keep_alive %q: %w", c.TCPKeepAlive, err)
+		}
+		if d < 0 {
+			return SocketOptions{}, fmt.Errorf("invalid tcp_keep_alive %q: must not be negative", c.TCPKeepAlive)
+		}
+		opts.TCPKeepAlive = d
+	}
+	if c.AcceptBacklog < 0 {
+		return SocketOptions{}, fmt.Errorf("invalid accept_backlog %d: must not be negative", c.AcceptBacklog)
+	}
+	if c.AcceptBacklog > 0 && setBacklog == nil {
+		return SocketOptions{}, fmt.Errorf("accept_backlog is not supported on %s", goruntime.GOOS)
+	}
+	if c.NoDelay != nil {
+		opts.NoDelay = *c.NoDelay
+	}
+	return opts, nil
+}
+
+// hasSocketOptions returns whether the listener's config sets any socket
+// option.
+func (c listenerConfig) hasSocketOptions() bool {
+	return c.ReusePort || c.TCPKeepAlive != "" || c.AcceptBacklog != 0 || c.NoDelay != nil
+}
+
+// listenWithOptions listens on the provided TCP address, applying the
+// provided socket options. The options must be supported on this platform.
+func listenWithOptions(ctx context.Context, addr string, opts SocketOptions) (net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: opts.TCPKeepAlive}
+	if opts.TCPKeepAlive == 0 {
+		lc.KeepAlive = -1 // disable keep-alives
+	}
+	if opts.ReusePort {
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) { err = setReusePort(fd) }); cerr != nil {
+				return cerr
+			}
+			return err
+		}
+	}
+	l, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AcceptBacklog > 0 {
+		// The backlog can't be set by a Control function, which runs before
+		// the socket starts listening, so we listen again with the requested
+		// backlog, which updates it.
+		if err := listenBacklog(l, opts.AcceptBacklog); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("set accept backlog of %v: %w", l.Addr(), err)
+		}
+	}
+	if !opts.NoDelay {
+		// The net package sets TCP_NODELAY on every TCP connection.
+		l = noDelayListener{l}
+	}
+	return l, nil
+}
+
+// listenBacklog sets the backlog of a listening TCP socket.
+func listenBacklog(l net.Listener, backlog int) error {
+	tl, ok := l.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("unexpected listener type %T", l)
+	}
+	raw, err := tl.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if cerr := raw.Control(func(fd uintptr) { err = setBacklog(fd, backlog) }); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// noDelayListener is a TCP listener that clears TCP_NODELAY on the
+// connections it accepts, enabling Nagle's algorithm.
+type noDelayListener struct {
+	net.Listener
+}
+
+// Accept implements the net.Listener interface.
+func (l noDelayListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		// SetNoDelay only fails if the connection is already broken, in which
+		// case the error surfaces on its first read or write.
+		tc.SetNoDelay(false) //nolint:errcheck
+	}
+	return conn, nil
+}
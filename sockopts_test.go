@@ -0,0 +1,102 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketOptions(t *testing.T) {
+	noDelay := false
+	for _, test := range []struct {
+		name string
+		cfg  listenerConfig
+		want SocketOptions
+	}{
+		{"Defaults", listenerConfig{}, SocketOptions{TCPKeepAlive: defaultTCPKeepAlive, NoDelay: true}},
+		{"KeepAlive", listenerConfig{TCPKeepAlive: "30s"}, SocketOptions{TCPKeepAlive: 30 * time.Second, NoDelay: true}},
+		{"NoKeepAlive", listenerConfig{TCPKeepAlive: "0s"}, SocketOptions{NoDelay: true}},
+		{"NoDelay", listenerConfig{NoDelay: &noDelay}, SocketOptions{TCPKeepAlive: defaultTCPKeepAlive}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.cfg.socketOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("socketOptions: got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedSocketOptions(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		cfg         listenerConfig
+		unsupported bool // is the option unsupported on this platform?
+		want        string
+	}{
+		{"ReusePort", listenerConfig{ReusePort: true}, setReusePort == nil, "reuse_port is not supported"},
+		{"AcceptBacklog", listenerConfig{AcceptBacklog: 4096}, setBacklog == nil, "accept_backlog is not supported"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.cfg.socketOptions()
+			if !test.unsupported {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("socketOptions: got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestListenWithOptions(t *testing.T) {
+	opts := SocketOptions{TCPKeepAlive: 30 * time.Second}
+	if setBacklog != nil {
+		opts.AcceptBacklog = 16
+	}
+	l, err := listenWithOptions(context.Background(), "localhost:0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if _, ok := l.(noDelayListener); !ok {
+		t.Fatalf("listener with NoDelay unset: got %T, want noDelayListener", l)
+	}
+
+	// The listener accepts connections.
+	go func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,26 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux || darwin || freebsd
+
+package weaver
+
+import "golang.org/x/sys/unix"
+
+// setBacklog sets the backlog of the listening socket with the provided file
+// descriptor. Calling listen on a socket that is already listening updates its
+// backlog.
+var setBacklog = func(fd uintptr, backlog int) error {
+	return unix.Listen(int(fd), backlog)
+}
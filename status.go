@@ -0,0 +1,293 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+)
+
+// statusTTL is how long a weavelet caches the report returned by Status.
+const statusTTL = time.Second
+
+// StatusReport describes the placement and health of every component of an
+// application, as observed by the weavelet hosting the caller. See Status.
+type StatusReport struct {
+	App          string            // application name
+	DeploymentID string            // unique id of the deployment
+	WeaveletID   string            // unique id of the reporting weavelet
+	Time         time.Time         // when the report was computed
+	Components   []ComponentStatus // sorted by component name
+
+	// Draining reports whether the reporting weavelet is shutting down. A
+	// draining weavelet rejects new remote calls, which callers retry on other
+	// replicas, and fails the health checks of the listeners served with
+	// Listener.Serve.
+	Draining bool
+}
+
+// ComponentStatus describes a single component in a StatusReport.
+type ComponentStatus struct {
+	Name    string   // full component name, e.g., "example.com/pkg/Cache"
+	Local   bool     // is the component hosted by the reporting weavelet?
+	Routed  bool     // is the component routed? see WithRouter
+	Methods []string // names of the component's methods, in declaration order
+
+	// Replicas holds the addresses of the replicas that calls to the
+	// component are sent to. It is empty for a component that is only hosted
+	// in-process, as in a single process deployment.
+	Replicas []string
+
+	// Healthy reports whether the component can serve calls: a local
+	// component is healthy once it has been initialized, and a remote
+	// component is healthy once the deployer has assigned it a replica.
+	Healthy bool
+}
+
+// Component returns the status of the component with the provided full name,
+// or nil if the application has no such component.
+func (r *StatusReport) Component(name string) *ComponentStatus {
+	for i := range r.Components {
+		if r.Components[i].Name == name {
+			return &r.Components[i]
+		}
+	}
+	return nil
+}
+
+// Status returns a report of the placement, replicas, and health of every
+// component of the application, as currently known by the weavelet running
+// the caller. The report is consistent with the components, methods, and
+// replicas reported by the deployer's status tooling, but is computed
+// locally: the replica addresses are those of the routing information the
+// deployer most recently sent to this weavelet.
+//
+// Status is cheap enough to call frequently; reports are cached for a short
+// time, so consecutive calls may return the same report. It can be called
+// from any component method, from a component's Init method, and from the
+// function passed to Run.
+func Status(ctx context.Context) (*StatusReport, error) {
+	w, err := weaveletFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return w.status(), nil
+}
+
+// weaveletKey is the context key under which a weavelet stores itself.
+type weaveletKey struct{}
+
+// withWeavelet returns a copy of ctx that carries the provided weavelet.
+func withWeavelet(ctx context.Context, w *weavelet) context.Context {
+	return context.WithValue(ctx, weaveletKey{}, w)
+}
+
+// liveWeavelets holds the weavelets of this process that haven't stopped.
+var liveWeavelets struct {
+	mu        sync.Mutex
+	weavelets map[*weavelet]struct{}
+}
+
+// trackWeavelet records w as live until it is shut down or its context is
+// done.
+func trackWeavelet(w *weavelet) {
+	liveWeavelets.mu.Lock()
+	defer liveWeavelets.mu.Unlock()
+	if liveWeavelets.weavelets == nil {
+		liveWeavelets.weavelets = map[*weavelet]struct{}{}
+	}
+	liveWeavelets.weavelets[w] = struct{}{}
+	go func() {
+		<-w.ctx.Done()
+		untrackWeavelet(w)
+	}()
+}
+
+// untrackWeavelet records that w is no longer live.
+func untrackWeavelet(w *weavelet) {
+	liveWeavelets.mu.Lock()
+	defer liveWeavelets.mu.Unlock()
+	delete(liveWeavelets.weavelets, w)
+}
+
+// weaveletFromContext returns the weavelet carried by ctx. If ctx doesn't
+// carry one (e.g., it is the context of an HTTP request), it returns the
+// only live weavelet of the process, if there is exactly one.
+func weaveletFromContext(ctx context.Context) (*weavelet, error) {
+	if w, ok := ctx.Value(weaveletKey{}).(*weavelet); ok {
+		return w, nil
+	}
+	liveWeavelets.mu.Lock()
+	defer liveWeavelets.mu.Unlock()
+	switch n := len(liveWeavelets.weavelets); n {
+	case 0:
+		return nil, fmt.Errorf("weaver.Status: no running Service Weaver application")
+	case 1:
+		for w := range liveWeavelets.weavelets {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("weaver.Status: context is not derived from a Service Weaver context and the process runs %d applications", len(liveWeavelets.weavelets))
+}
+
+// status returns the weavelet's current status report, recomputing it if the
+// cached report is older than statusTTL.
+func (w *weavelet) status() *StatusReport {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	if w.statusReport == nil || time.Since(w.statusReport.Time) >= statusTTL {
+		w.statusReport = w.computeStatus()
+	}
+	return w.statusReport.clone()
+}
+
+// computeStatus computes the weavelet's status report.
+func (w *weavelet) computeStatus() *StatusReport {
+	initialized := w.initializedSet()
+
+	report := &StatusReport{
+		App:          w.info.App,
+		DeploymentID: w.info.DeploymentId,
+		WeaveletID:   w.info.Id,
+		Time:         time.Now(),
+		Draining:     w.draining.Load(),
+	}
+	for _, c := range w.componentsByName {
+		s := ComponentStatus{Name: c.info.Name, Routed: c.info.Routed}
+		for i := 0; i < c.info.Iface.NumMethod(); i++ {
+			s.Methods = append(s.Methods, c.info.Iface.Method(i).Name)
+		}
+
+		// A component is hosted by this weavelet if the weavelet initialized
+		// it, even if the deployer routes calls to it through the network
+		// rather than in-process. The placement of any other component is
+		// unknown until the deployer has sent its routing information.
+		local, placed := c.local.TryRead()
+		s.Local = local || initialized[c]
+		if placed && (!local || c.info.Routed || !w.info.SingleProcess) {
+			for _, e := range w.getClient(c).resolver.snapshot() {
+				s.Replicas = append(s.Replicas, e.Address())
+			}
+		}
+		if s.Local && len(s.Replicas) == 0 && w.dialAddr != "" {
+			s.Replicas = []string{w.dialAddr}
+		}
+		if s.Local {
+			s.Healthy = initialized[c]
+		} else {
+			s.Healthy = len(s.Replicas) > 0
+		}
+		report.Components = append(report.Components, s)
+	}
+	sort.Slice(report.Components, func(i, j int) bool {
+		return report.Components[i].Name < report.Components[j].Name
+	})
+	return report
+}
+
+// initializedSet returns the set of components initialized by the weavelet.
+func (w *weavelet) initializedSet() map[*component]bool {
+	w.initializedMu.Lock()
+	defer w.initializedMu.Unlock()
+	initialized := make(map[*component]bool, len(w.initialized))
+	for _, c := range w.initialized {
+		initialized[c] = true
+	}
+	return initialized
+}
+
+// clone returns a deep copy of the report, so that callers can't modify the
+// cached report.
+func (r *StatusReport) clone() *StatusReport {
+	c := *r
+	c.Components = make([]ComponentStatus, len(r.Components))
+	for i, s := range r.Components {
+		s.Methods = append([]string(nil), s.Methods...)
+		s.Replicas = append([]string(nil), s.Replicas...)
+		c.Components[i] = s
+	}
+	return &c
+}
+
+// RegisteredComponent describes a component registered in the binary. See
+// Components.
+type RegisteredComponent struct {
+	Name    string   // full component name, e.g., "example.com/pkg/Cache"
+	Impl    string   // implementation type, e.g., "pkg.cache"
+	Methods []string // names of the component's methods, in declaration order
+	Routed  bool     // is the component routed? see WithRouter
+
+	// Local and Remote report whether the component is hosted by the
+	// caller's process or by other processes. Both are false if the
+	// placement of the component is not known yet, e.g., because the
+	// component hasn't been used, or if no application is running.
+	Local  bool
+	Remote bool
+
+	// Listeners maps the name of every listener of the component to the
+	// address it listens on in the caller's process, or to the empty string
+	// if the listener hasn't been created in the caller's process.
+	Listeners map[string]string
+}
+
+// Components returns every component registered in the binary, sorted by
+// name, along with its placement and listeners as currently known by the
+// weavelet running the caller. Unlike Status, Components also works when no
+// application is running, in which case it only reports what the binary
+// registers. It can be called concurrently with the initialization of
+// components.
+func Components(ctx context.Context) []RegisteredComponent {
+	// w is nil if no application is running.
+	w, _ := weaveletFromContext(ctx)
+	var initialized map[*component]bool
+	var addrs map[string]string
+	if w != nil {
+		initialized = w.initializedSet()
+		addrs = w.listenerAddresses()
+	}
+
+	var components []RegisteredComponent
+	for _, reg := range codegen.Registered() {
+		rc := RegisteredComponent{
+			Name:      reg.Name,
+			Impl:      reg.Impl.String(),
+			Routed:    reg.Routed,
+			Listeners: make(map[string]string, len(reg.Listeners)),
+		}
+		for i := 0; i < reg.Iface.NumMethod(); i++ {
+			rc.Methods = append(rc.Methods, reg.Iface.Method(i).Name)
+		}
+		for _, name := range reg.Listeners {
+			rc.Listeners[name] = addrs[name]
+		}
+		if w != nil {
+			if c, ok := w.componentsByName[reg.Name]; ok {
+				local, placed := c.local.TryRead()
+				rc.Local = (placed && local) || initialized[c]
+				rc.Remote = placed && !rc.Local
+			}
+		}
+		components = append(components, rc)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].Name < components[j].Name
+	})
+	return components
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import "reflect"
+
+// A Stream is a sequence of values of type T that a component method returns
+// incrementally, rather than all at once. Calling a Stream with a yield
+// function calls yield on every value in the sequence, stopping early if
+// yield returns false. It returns an error if the sequence could not be
+// produced in full.
+//
+// A component method that returns a Stream must have results (Stream[T],
+// error), where T is serializable. For example:
+//
+//	type Logs interface {
+//	    Query(ctx context.Context, q string) (weaver.Stream[Entry], error)
+//	}
+//
+//	func (l *logs) Query(ctx context.Context, q string) (weaver.Stream[Entry], error) {
+//	    rows, err := l.db.QueryContext(ctx, q)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return func(yield func(Entry) bool) error {
+//	        defer rows.Close()
+//	        for rows.Next() {
+//	            var e Entry
+//	            if err := rows.Scan(&e.Time, &e.Msg); err != nil {
+//	                return err
+//	            }
+//	            if !yield(e) {
+//	                return nil
+//	            }
+//	        }
+//	        return rows.Err()
+//	    }, nil
+//	}
+//
+// And the caller iterates over the returned stream:
+//
+//	entries, err := logs.Query(ctx, q)
+//	if err != nil {
+//	    return err
+//	}
+//	err = entries(func(e Entry) bool {
+//	    fmt.Println(e.Msg)
+//	    return true
+//	})
+//
+// When the method is called remotely, its values are sent to the caller in
+// batches as they are produced, without buffering the entire sequence in
+// memory. The stream is flow controlled: if the caller consumes values slower
+// than the method produces them, the method blocks in yield until the caller
+// catches up. The method keeps running until the caller finishes iterating
+// over the stream, stops the iteration early, or cancels the context passed
+// to the method. A caller must therefore iterate over every Stream it
+// receives, and it may do so only once.
+//
+// If the stream fails midway, the caller receives the values produced before
+// the failure and the error is returned by the iteration. If the method
+// returns an error instead of a stream, the caller receives the error and no
+// stream.
+type Stream[T any] func(yield func(T) bool) error
+
+// isStream marks Stream types. See isStreamMethod.
+func (Stream[T]) isStream() {}
+
+// isStreamMethod returns whether the provided type of a component method is
+// the type of a method that returns a Stream.
+func isStreamMethod(t reflect.Type) bool {
+	type stream interface{ isStream() }
+	return t.NumOut() == 2 && t.Out(0).Implements(reflect.TypeOf((*stream)(nil)).Elem())
+}
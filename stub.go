@@ -27,8 +27,19 @@ type stub struct {
 	component string           // name of the remote component
 	conn      call.Connection  // connection to talk to the remote component
 	methods   []call.MethodKey // keys for the remote component methods
+	names     []string         // names of the remote component methods
 	balancer  call.Balancer    // if not nil, component load balancer
 	tracer    trace.Tracer     // component tracer
+	caller    string           // name of the calling component, if any
+
+	// If positive, arguments and results of at least this many bytes are
+	// compressed. See call.CallOptions.CompressMinBytes.
+	compressMinBytes int
+
+	// If keyed, key is used as the shard key of every call, instead of the
+	// shard key computed by the component's router, if any. See Ref.ForKey.
+	keyed bool
+	key   uint64
 }
 
 var _ codegen.Stub = &stub{}
@@ -40,9 +51,86 @@ func (s *stub) Tracer() trace.Tracer {
 
 // Run implements the codegen.Stub interface.
 func (s *stub) Run(ctx context.Context, method int, args []byte, shardKey uint64) ([]byte, error) {
+	return s.RunBuffers(ctx, method, [][]byte{args}, shardKey)
+}
+
+// RunBuffers implements the codegen.Stub interface.
+func (s *stub) RunBuffers(ctx context.Context, method int, args [][]byte, shardKey uint64) ([]byte, error) {
+	if s.keyed {
+		shardKey = s.key
+	}
+	ctx, err := injectContextValues(ctx)
+	if err != nil {
+		return nil, err
+	}
 	opts := call.CallOptions{
 		ShardKey: shardKey,
 		Balancer: s.balancer,
+		Caller:   s.caller,
+		Method:   s.names[method],
+
+		CompressMinBytes: s.compressMinBytes,
+	}
+	if h, ok := hedgingFromContext(ctx); ok {
+		return s.runHedged(ctx, h, method, args, opts)
+	}
+	return s.call(ctx, method, args, opts)
+}
+
+// call calls the provided method. A call rejected by a quiesced replica is
+// known not to have run, so it is retried, usually on another replica (see
+// Quiesce). If the component's calls are compressed, call records the sizes
+// of the argument and result of a successful call as sent over the network,
+// which the generated code can't see.
+func (s *stub) call(ctx context.Context, method int, args [][]byte, opts call.CallOptions) ([]byte, error) {
+	for i := 0; ; i++ {
+		result, err := s.callOnce(ctx, method, args, opts)
+		if i == quiescedRetries || !isQuiesced(err) {
+			return result, err
+		}
+	}
+}
+
+// callOnce calls the provided method once. See call.
+func (s *stub) callOnce(ctx context.Context, method int, args [][]byte, opts call.CallOptions) ([]byte, error) {
+	if opts.CompressMinBytes <= 0 {
+		return s.conn.CallBuffers(ctx, s.methods[method], args, opts)
+	}
+	var sizes call.WireSizes
+	opts.WireSizes = &sizes
+	result, err := s.conn.CallBuffers(ctx, s.methods[method], args, opts)
+	if err != nil {
+		return nil, err
+	}
+	labels := codegen.MethodLabels{
+		Caller:    s.caller,
+		Component: s.component,
+		Method:    s.names[method],
+		Remote:    true,
+	}
+	codegen.MethodWireBytesRequest.Get(labels).Put(float64(sizes.Request))
+	codegen.MethodWireBytesReply.Get(labels).Put(float64(sizes.Reply))
+	return result, nil
+}
+
+// RunStream implements the codegen.Stub interface.
+func (s *stub) RunStream(ctx context.Context, method int, args []byte, shardKey uint64) (codegen.StreamReader, error) {
+	if s.keyed {
+		shardKey = s.key
+	}
+	ctx, err := injectContextValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := call.CallOptions{
+		ShardKey: shardKey,
+		Balancer: s.balancer,
+		Caller:   s.caller,
+		Method:   s.names[method],
+	}
+	stream, err := s.conn.CallStream(ctx, s.methods[method], args, opts)
+	if err != nil {
+		return nil, err
 	}
-	return s.conn.Call(ctx, s.methods[method], args, opts)
+	return stream, nil
 }
@@ -15,6 +15,7 @@
 package weaver
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -37,6 +38,14 @@ func (c *localClient) Call(ctx context.Context, _ call.MethodKey, args []byte, _
 	return handleCall(ctx, reflect.ValueOf(c.fn), args)
 }
 
+func (c *localClient) CallBuffers(ctx context.Context, h call.MethodKey, args [][]byte, opts call.CallOptions) ([]byte, error) {
+	return c.Call(ctx, h, bytes.Join(args, nil), opts)
+}
+
+func (c *localClient) CallStream(context.Context, call.MethodKey, []byte, call.CallOptions) (*call.ClientStream, error) {
+	return nil, fmt.Errorf("streaming calls not supported")
+}
+
 func (c *localClient) Close() {}
 
 func TestCall(t *testing.T) {
@@ -134,6 +143,7 @@ func TestCall(t *testing.T) {
 			stub := stub{
 				conn:    &localClient{fn: test.fn},
 				methods: []call.MethodKey{call.MakeMethodKey("", "test")},
+				names:   []string{"test"},
 			}
 			out, err := stub.Run(context.Background(), 0, enc.Data(), 0)
 			if err != nil {
@@ -178,6 +188,7 @@ func TestErrorArgsNotEncoded(t *testing.T) {
 		stub := stub{
 			conn:    &localClient{fn: fn},
 			methods: []call.MethodKey{call.MakeMethodKey("", "test")},
+			names:   []string{"test"},
 		}
 		_, err := stub.Run(context.Background(), 0, nil, 0)
 		return err
@@ -213,6 +224,7 @@ func TestErrorResultsNotDecoded(t *testing.T) {
 	stub := stub{
 		conn:    &localClient{fn: fn},
 		methods: []call.MethodKey{call.MakeMethodKey("", "test")},
+		names:   []string{"test"},
 	}
 	if _, err := stub.Run(context.Background(), 0, enc.Data(), 0); err != nil {
 		t.Fatal(err)
@@ -0,0 +1,228 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/cron"
+	"github.com/ServiceWeaver/weaver/metrics"
+	"golang.org/x/exp/slog"
+)
+
+// A Task is a unit of background work that a component runs periodically,
+// e.g., compacting a table or flushing a buffer. See WithTasks.
+type Task struct {
+	// Name identifies the task in logs and metrics. Every task of a component
+	// must have a unique, non-empty name.
+	Name string
+
+	// Exactly one of Interval and Schedule must be set. If Interval is set,
+	// the task runs every Interval, starting Interval after the component is
+	// initialized. If Schedule is set, the task runs on the provided cron
+	// schedule (e.g., "*/5 * * * *" or "@daily"), in the local time zone.
+	// See the internal/cron package for the supported syntax.
+	Interval time.Duration
+	Schedule string
+
+	// If OneReplica is true, the task runs on only one of the replicas of the
+	// component, chosen by the deployer. Otherwise, the task runs on every
+	// replica. If the chosen replica fails, the multiprocess deployer picks
+	// another one. The SSH deployer always picks the first replica it starts
+	// and doesn't pick another one, so the task stops running if that replica
+	// fails. In a single process, the task runs in that process.
+	OneReplica bool
+
+	// By default, a run of the task that is due while a previous run is still
+	// executing is skipped. If AllowOverlap is true, the runs execute
+	// concurrently instead.
+	AllowOverlap bool
+
+	// Run performs the task. The provided ctx is canceled when the component
+	// shuts down. If Run returns an error or panics, the failure is logged
+	// and counted, and the task runs again at its next scheduled time.
+	Run func(context.Context) error
+}
+
+// WithTasks is implemented by a component implementation that runs
+// background tasks. After the component is initialized, the Service Weaver
+// runtime calls Tasks once and runs the returned tasks for the rest of the
+// component's lifetime. For example:
+//
+//	type store struct {
+//	    weaver.Implements[Store]
+//	}
+//
+//	func (s *store) Tasks() []weaver.Task {
+//	    return []weaver.Task{
+//	        {Name: "compact", Schedule: "@hourly", OneReplica: true, Run: s.compact},
+//	        {Name: "flush", Interval: 10 * time.Second, Run: s.flush},
+//	    }
+//	}
+//
+// The runtime records the following metrics for every task, labeled with the
+// component and the task name:
+//
+//   - serviceweaver_task_count: Number of runs of the task.
+//   - serviceweaver_task_error_count: Number of runs that failed.
+//   - serviceweaver_task_latency_micros: Duration of the runs.
+//   - serviceweaver_task_skipped_count: Number of runs skipped because the
+//     previous run was still executing.
+//
+// Tasks don't run in components that are replaced by fakes in weavertest.
+type WithTasks interface {
+	Tasks() []Task
+}
+
+type taskLabels struct {
+	Component string // full component name
+	Task      string // task name
+}
+
+var (
+	taskCounts = metrics.NewCounterMap[taskLabels](
+		"serviceweaver_task_count",
+		"Count of background task runs",
+	)
+	taskErrors = metrics.NewCounterMap[taskLabels](
+		"serviceweaver_task_error_count",
+		"Count of background task runs that failed",
+	)
+	taskLatencyMicros = metrics.NewHistogramMap[taskLabels](
+		"serviceweaver_task_latency_micros",
+		"Duration, in microseconds, of background task runs",
+		metrics.NonNegativeBuckets,
+	)
+	taskSkipped = metrics.NewCounterMap[taskLabels](
+		"serviceweaver_task_skipped_count",
+		"Count of background task runs skipped because the previous run was still executing",
+	)
+)
+
+// taskRunner runs a single background task.
+type taskRunner struct {
+	task      Task
+	component string                    // full component name
+	next      func(time.Time) time.Time // returns the time of the next run
+	isLeader  func() bool               // is this replica the leader?
+	logger    *slog.Logger              // the component's logger
+	running   atomic.Bool               // is a run executing?
+}
+
+// newTaskRunners validates the tasks of a component and returns a runner for
+// every one of them.
+func newTaskRunners(component string, tasks []Task, isLeader func() bool, logger *slog.Logger) ([]*taskRunner, error) {
+	names := map[string]bool{}
+	runners := make([]*taskRunner, 0, len(tasks))
+	for _, t := range tasks {
+		if t.Name == "" {
+			return nil, fmt.Errorf("component %q: task with empty name", component)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("component %q: duplicate task %q", component, t.Name)
+		}
+		names[t.Name] = true
+		if t.Run == nil {
+			return nil, fmt.Errorf("component %q: task %q: nil Run", component, t.Name)
+		}
+
+		r := &taskRunner{task: t, component: component, isLeader: isLeader, logger: logger}
+		switch {
+		case t.Interval != 0 && t.Schedule != "":
+			return nil, fmt.Errorf("component %q: task %q: both Interval and Schedule set", component, t.Name)
+		case t.Interval < 0:
+			return nil, fmt.Errorf("component %q: task %q: negative Interval %v", component, t.Name, t.Interval)
+		case t.Interval > 0:
+			interval := t.Interval
+			r.next = func(last time.Time) time.Time { return last.Add(interval) }
+		case t.Schedule != "":
+			schedule, err := cron.Parse(t.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("component %q: task %q: %w", component, t.Name, err)
+			}
+			if schedule.Next(time.Now()).IsZero() {
+				return nil, fmt.Errorf("component %q: task %q: schedule %q never runs", component, t.Name, t.Schedule)
+			}
+			r.next = schedule.Next
+		default:
+			return nil, fmt.Errorf("component %q: task %q: neither Interval nor Schedule set", component, t.Name)
+		}
+		runners = append(runners, r)
+	}
+	return runners, nil
+}
+
+// run runs the task until ctx is canceled.
+func (r *taskRunner) run(ctx context.Context) {
+	labels := taskLabels{Component: r.component, Task: r.task.Name}
+	at := r.next(time.Now())
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// Schedule the next run. If we fell behind (e.g., because the
+		// machine was suspended), skip the runs we missed.
+		now := time.Now()
+		at = r.next(at)
+		if at.Before(now) {
+			at = r.next(now)
+		}
+		if at.IsZero() {
+			return
+		}
+		timer.Reset(time.Until(at))
+
+		if r.task.OneReplica && !r.isLeader() {
+			continue
+		}
+		if !r.task.AllowOverlap && !r.running.CompareAndSwap(false, true) {
+			taskSkipped.Get(labels).Inc()
+			continue
+		}
+		go func() {
+			if !r.task.AllowOverlap {
+				defer r.running.Store(false)
+			}
+			r.runOnce(ctx, labels)
+		}()
+	}
+}
+
+// runOnce runs the task once and records its metrics.
+func (r *taskRunner) runOnce(ctx context.Context, labels taskLabels) {
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if x := recover(); x != nil {
+				err = fmt.Errorf("panic: %v", x)
+			}
+		}()
+		return r.task.Run(ctx)
+	}()
+	taskCounts.Get(labels).Inc()
+	taskLatencyMicros.Get(labels).Put(float64(time.Since(start).Microseconds()))
+	if err != nil && ctx.Err() == nil {
+		taskErrors.Get(labels).Inc()
+		r.logger.Error("Background task failed", "task", r.task.Name, "err", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"golang.org/x/exp/slog"
+)
+
+func TestNewTaskRunnersErrors(t *testing.T) {
+	run := func(context.Context) error { return nil }
+	for _, test := range []struct {
+		name  string
+		tasks []Task
+		want  string
+	}{
+		{"EmptyName", []Task{{Interval: time.Second, Run: run}}, "empty name"},
+		{"Duplicate", []Task{
+			{Name: "a", Interval: time.Second, Run: run},
+			{Name: "a", Interval: time.Second, Run: run},
+		}, "duplicate"},
+		{"NilRun", []Task{{Name: "a", Interval: time.Second}}, "nil Run"},
+		{"Both", []Task{{Name: "a", Interval: time.Second, Schedule: "@daily", Run: run}}, "both"},
+		{"Neither", []Task{{Name: "a", Run: run}}, "neither"},
+		{"NegativeInterval", []Task{{Name: "a", Interval: -time.Second, Run: run}}, "negative"},
+		{"BadSchedule", []Task{{Name: "a", Schedule: "* * *", Run: run}}, "fields"},
+		{"NeverRuns", []Task{{Name: "a", Schedule: "0 0 31 2 *", Run: run}}, "never runs"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newTaskRunners("c", test.tasks, func() bool { return true }, slog.Default())
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("newTaskRunners: got %v, want error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestTaskRunnerSkipsOverlappingRuns(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 100)
+	tasks := []Task{{
+		Name:     "slow",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		},
+	}}
+	runners, err := newTaskRunners("TestTaskRunnerSkipsOverlappingRuns", tasks, func() bool { return true }, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go runners[0].run(ctx)
+
+	<-started
+	skipped := func() float64 {
+		for _, m := range metrics.Snapshot() {
+			if m.Name == "serviceweaver_task_skipped_count" && m.Labels["component"] == "TestTaskRunnerSkipsOverlappingRuns" {
+				return m.Value
+			}
+		}
+		return 0
+	}
+	for skipped() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-started:
+		t.Fatal("task started while a previous run was executing")
+	default:
+	}
+	close(release)
+}
@@ -0,0 +1,270 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/ServiceWeaver/weaver/runtime/logging"
+	"github.com/ServiceWeaver/weaver/runtime/protos"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+// ComponentTestEnv runs a set of component implementations together in the
+// test process, without a weavelet, network connections, or code generated by
+// "weaver generate". It is meant for integration tests of a few components
+// whose dependencies can all be provided by the test. For example:
+//
+//	func TestCache(t *testing.T) {
+//	    var env weaver.ComponentTestEnv
+//	    env.Register(&cache{})
+//	    env.Register(&fakeStore{})
+//	    ctx := env.Start(t)
+//	    c, err := weaver.Get[Cache](ctx)
+//	    ...
+//	}
+//
+// Calls between the components are plain method calls, so arguments and
+// results are not serialized and interceptors, timeouts, and other call
+// options are not applied. Use weavertest to test components the way they
+// are deployed. The zero value of a ComponentTestEnv is ready to use.
+type ComponentTestEnv struct {
+	mu      sync.Mutex
+	impls   map[reflect.Type]any // registered implementations, by interface type
+	started bool
+}
+
+// testEnvKey is the context key under which Start stores the started
+// components, as a map from interface type to implementation.
+type testEnvKey struct{}
+
+// Register registers impl, a pointer to a struct that embeds
+// weaver.Implements[T], as the implementation of component T. The fields of
+// impl other than its weaver.Ref fields, e.g., its config, should be set
+// before Start is called. Register panics if impl is not a component
+// implementation, if another implementation of T was already registered, or
+// if Start was already called.
+func (e *ComponentTestEnv) Register(impl Instance) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.started {
+		panic(fmt.Errorf("ComponentTestEnv.Register(%T): environment already started", impl))
+	}
+	iface, err := testEnvInterface(impl)
+	if err != nil {
+		panic(err)
+	}
+	if _, ok := e.impls[iface]; ok {
+		panic(fmt.Errorf("ComponentTestEnv.Register(%T): component %v already registered", impl, iface))
+	}
+	if e.impls == nil {
+		e.impls = map[reflect.Type]any{}
+	}
+	e.impls[iface] = impl
+}
+
+// testEnvInterface returns the component interface type implemented by impl,
+// which should be a pointer to a struct that embeds weaver.Implements[T].
+func testEnvInterface(impl any) (reflect.Type, error) {
+	t := reflect.TypeOf(impl)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ComponentTestEnv.Register(%T): not a pointer to a component implementation struct", impl)
+	}
+	// See the definition of Implements.
+	f, ok := t.Elem().FieldByName("component_interface_type")
+	if !ok {
+		return nil, fmt.Errorf("ComponentTestEnv.Register(%T): type does not embed weaver.Implements", impl)
+	}
+	if !t.Implements(f.Type) {
+		return nil, fmt.Errorf("ComponentTestEnv.Register(%T): type does not implement %v", impl, f.Type)
+	}
+	return f.Type, nil
+}
+
+// Start fills the weaver.Ref fields of the registered components with the
+// registered implementations and calls their Init methods, with every
+// component initialized after the components it refers to. It returns a
+// context that can be passed to weaver.Get to get the components. When the
+// test finishes, the Shutdown methods of the components are called in the
+// reverse order, and the returned context is canceled.
+//
+// The weaver.ExternalRef fields of the components are filled the same way,
+// so a test can register a fake for a component of another application.
+//
+// Start fails the test if a component refers to a component that was not
+// registered, has a weaver.Listener field, or fails to initialize.
+func (e *ComponentTestEnv) Start(t testing.TB) context.Context {
+	t.Helper()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.started {
+		t.Fatal("ComponentTestEnv.Start: environment already started")
+	}
+	e.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, testEnvKey{}, e.impls)
+	var initialized []any
+	t.Cleanup(func() {
+		defer cancel()
+		var errs []error
+		for i := len(initialized) - 1; i >= 0; i-- {
+			if f, ok := initialized[i].(Finalizable); ok {
+				if err := f.Shutdown(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("component %T shutdown failed: %w", initialized[i], err))
+				}
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Sort the components so that they are wired up in a deterministic order.
+	ifaces := make([]reflect.Type, 0, len(e.impls))
+	for iface := range e.impls {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Slice(ifaces, func(i, j int) bool {
+		return testEnvName(ifaces[i]) < testEnvName(ifaces[j])
+	})
+
+	// Fill the Ref fields of the components, and record their dependencies.
+	w := &weavelet{info: &protos.EnvelopeInfo{
+		App:           "test",
+		DeploymentId:  uuid.New().String(),
+		Id:            uuid.New().String(),
+		SingleProcess: true,
+	}}
+	pp := logging.NewPrettyPrinter(false)
+	deps := map[reflect.Type][]reflect.Type{}
+	for _, iface := range ifaces {
+		impl := e.impls[iface]
+		name := testEnvName(iface)
+		c := &component{
+			wlet: w,
+			info: &codegen.Registration{Name: name, Iface: iface, Impl: reflect.TypeOf(impl).Elem()},
+		}
+		c.logger = slog.New(&logging.LogHandler{
+			Opts: logging.Options{
+				App:        w.info.App,
+				Deployment: w.info.DeploymentId,
+				Component:  name,
+				Weavelet:   w.info.Id,
+			},
+			Write: func(entry *protos.LogEntry) { t.Log(pp.Format(entry)) },
+		})
+		c.ctxLogger.Store(c.logger)
+		c.tracer = trace.NewNoopTracerProvider().Tracer("")
+		c.impl = &componentImpl{component: c, impl: impl}
+		impl.(interface{ setInstance(*componentImpl) }).setInstance(c.impl)
+
+		err := fillRefs(impl, func(refType reflect.Type, _ Balancer) (refTarget, error) {
+			sub, ok := e.impls[refType]
+			if !ok {
+				return refTarget{}, fmt.Errorf("component %v not registered", refType)
+			}
+			deps[iface] = append(deps[iface], refType)
+			return refTarget{
+				value: sub,
+				id:    testEnvName(refType),
+				keyed: func(any) any { return sub },
+			}, nil
+		})
+		if err != nil {
+			t.Fatalf("ComponentTestEnv.Start: component %q: %v", name, err)
+		}
+		err = fillExternalRefs(impl, func(t reflect.Type) (any, error) {
+			sub, ok := e.impls[t]
+			if !ok {
+				return nil, fmt.Errorf("external component %v not registered", t)
+			}
+			deps[iface] = append(deps[iface], t)
+			return sub, nil
+		})
+		if err != nil {
+			t.Fatalf("ComponentTestEnv.Start: component %q: %v", name, err)
+		}
+		err = fillListeners(impl, func(string, listenerOptions) (Listener, error) {
+			return Listener{}, fmt.Errorf("listeners are not supported by ComponentTestEnv")
+		})
+		if err != nil {
+			t.Fatalf("ComponentTestEnv.Start: component %q: %v", name, err)
+		}
+	}
+
+	// Initialize the components with their dependencies first, using a
+	// depth-first search. Cycles are broken arbitrarily.
+	visited := map[reflect.Type]bool{}
+	var visit func(reflect.Type)
+	visit = func(iface reflect.Type) {
+		if visited[iface] {
+			return
+		}
+		visited[iface] = true
+		for _, dep := range deps[iface] {
+			visit(dep)
+		}
+		impl := e.impls[iface]
+		if i, ok := impl.(Initializable); ok {
+			if err := i.Init(ctx); err != nil {
+				t.Fatalf("ComponentTestEnv.Start: component %q initialization failed: %v", testEnvName(iface), err)
+			}
+		}
+		initialized = append(initialized, impl)
+	}
+	for _, iface := range ifaces {
+		visit(iface)
+	}
+	return ctx
+}
+
+// testEnvName returns the full name of the component with the provided
+// interface type, e.g., "example.com/pkg/Cache".
+func testEnvName(iface reflect.Type) string {
+	return iface.PkgPath() + "/" + iface.Name()
+}
+
+// Get returns the component T started by a ComponentTestEnv, where ctx is the
+// context returned by ComponentTestEnv.Start or a context derived from it. T
+// can be a component interface type, e.g., Cache, or a pointer to the
+// implementation type, e.g., *cache.
+func Get[T any](ctx context.Context) (T, error) {
+	var zero T
+	t := reflection.Type[T]()
+	impls, ok := ctx.Value(testEnvKey{}).(map[reflect.Type]any)
+	if !ok {
+		return zero, fmt.Errorf("weaver.Get[%v]: context not returned by ComponentTestEnv.Start", t)
+	}
+	if impl, ok := impls[t]; ok {
+		return impl.(T), nil
+	}
+	for _, impl := range impls {
+		if reflect.TypeOf(impl) == t {
+			return impl.(T), nil
+		}
+	}
+	return zero, fmt.Errorf("weaver.Get[%v]: component not registered", t)
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testEnvStore interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type testEnvCache interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type testEnvStoreImpl struct {
+	Implements[testEnvStore]
+	events *[]string
+}
+
+func (s *testEnvStoreImpl) Init(context.Context) error {
+	*s.events = append(*s.events, "init store")
+	return nil
+}
+
+func (s *testEnvStoreImpl) Shutdown(context.Context) error {
+	*s.events = append(*s.events, "shutdown store")
+	return nil
+}
+
+func (s *testEnvStoreImpl) Get(_ context.Context, key string) (string, error) {
+	return "value of " + key, nil
+}
+
+type testEnvCacheImpl struct {
+	Implements[testEnvCache]
+	store  Ref[testEnvStore]
+	events *[]string
+}
+
+func (c *testEnvCacheImpl) Init(context.Context) error {
+	*c.events = append(*c.events, "init cache")
+	return nil
+}
+
+func (c *testEnvCacheImpl) Shutdown(context.Context) error {
+	*c.events = append(*c.events, "shutdown cache")
+	return nil
+}
+
+func (c *testEnvCacheImpl) Get(ctx context.Context, key string) (string, error) {
+	c.Logger().Debug("Get", "key", key)
+	v, err := c.store.Get().Get(ctx, key)
+	return fmt.Sprintf("cached %s", v), err
+}
+
+func TestComponentTestEnv(t *testing.T) {
+	var events []string
+	t.Run("env", func(t *testing.T) {
+		var env ComponentTestEnv
+		env.Register(&testEnvCacheImpl{events: &events})
+		env.Register(&testEnvStoreImpl{events: &events})
+		ctx := env.Start(t)
+
+		cache, err := Get[testEnvCache](ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := cache.Get(ctx, "k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "cached value of k"; got != want {
+			t.Errorf("Get: got %q, want %q", got, want)
+		}
+		impl, err := Get[*testEnvCacheImpl](ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := impl.Runtime().Component, "github.com/ServiceWeaver/weaver/testEnvCache"; got != want {
+			t.Errorf("Component: got %q, want %q", got, want)
+		}
+	})
+
+	want := []string{"init store", "init cache", "shutdown cache", "shutdown store"}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("events (-want +got):\n%s", diff)
+	}
+}
+
+func TestComponentTestEnvGetErrors(t *testing.T) {
+	if _, err := Get[testEnvCache](context.Background()); err == nil {
+		t.Error("Get with a background context: unexpected success")
+	}
+	var env ComponentTestEnv
+	env.Register(&testEnvStoreImpl{events: new([]string)})
+	ctx := env.Start(t)
+	if _, err := Get[testEnvCache](ctx); err == nil {
+		t.Error("Get of an unregistered component: unexpected success")
+	}
+}
+
+func TestComponentTestEnvRegisterTwice(t *testing.T) {
+	var env ComponentTestEnv
+	env.Register(&testEnvStoreImpl{})
+	defer func() {
+		if recover() == nil {
+			t.Error("second Register: unexpected success")
+		}
+	}()
+	env.Register(&testEnvStoreImpl{})
+}
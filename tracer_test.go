@@ -0,0 +1,94 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weaver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracerTest interface {
+	Work(context.Context) error
+}
+
+type tracerTestImpl struct {
+	Implements[tracerTest]
+}
+
+func (t *tracerTestImpl) Work(ctx context.Context) error {
+	_, span := t.Tracer().Start(ctx, "sub-operation")
+	span.End()
+	return nil
+}
+
+// tracerTestLocalStub mimics the local stubs generated by "weaver generate",
+// which create a span for every method call.
+type tracerTestLocalStub struct {
+	impl   tracerTest
+	tracer trace.Tracer
+}
+
+func (s tracerTestLocalStub) Work(ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "tracerTest.Work", trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+	return s.impl.Work(ctx)
+}
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	c := &component{
+		tracer: tracer,
+		info: &codegen.Registration{
+			Name: "pkg/tracerTest",
+			LocalStubFn: func(impl any, _ string, tracer trace.Tracer) any {
+				return tracerTestLocalStub{impl: impl.(tracerTest), tracer: tracer}
+			},
+		},
+	}
+	impl := &tracerTestImpl{}
+	c.impl = &componentImpl{component: c, impl: impl}
+	impl.setInstance(c.impl)
+
+	if got := impl.Tracer(); got != tracer {
+		t.Fatalf("Tracer: got %v, want the component's tracer %v", got, tracer)
+	}
+
+	// Call the method the way the weavelet does for local calls.
+	stub := c.info.LocalStubFn(c.impl.impl, "caller", c.impl.component.tracer).(tracerTest)
+	if err := stub.Work(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	sub, method := spans[0], spans[1]
+	if sub.Name() != "sub-operation" || method.Name() != "tracerTest.Work" {
+		t.Fatalf("got spans %q and %q, want %q and %q", sub.Name(), method.Name(), "sub-operation", "tracerTest.Work")
+	}
+	if got, want := sub.Parent().SpanID(), method.SpanContext().SpanID(); got != want {
+		t.Errorf("parent of sub-operation span: got %v, want method span %v", got, want)
+	}
+	if got, want := sub.InstrumentationScope(), method.InstrumentationScope(); got != want {
+		t.Errorf("instrumentation scope: got %v, want %v", got, want)
+	}
+}
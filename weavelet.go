@@ -25,10 +25,12 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ServiceWeaver/weaver/internal/config"
 	"github.com/ServiceWeaver/weaver/internal/envelope/conn"
+	"github.com/ServiceWeaver/weaver/internal/exports"
 	"github.com/ServiceWeaver/weaver/internal/net/call"
 	"github.com/ServiceWeaver/weaver/internal/private"
 	"github.com/ServiceWeaver/weaver/internal/reflection"
@@ -36,6 +38,7 @@ import (
 	"github.com/ServiceWeaver/weaver/runtime"
 	"github.com/ServiceWeaver/weaver/runtime/codegen"
 	"github.com/ServiceWeaver/weaver/runtime/logging"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
 	"github.com/ServiceWeaver/weaver/runtime/protos"
 	"github.com/ServiceWeaver/weaver/runtime/retry"
 	"go.opentelemetry.io/otel"
@@ -52,6 +55,10 @@ import (
 // readyMethodKey holds the key for a method used to check if a backend is ready.
 var readyMethodKey = call.MakeMethodKey("", "ready")
 
+// defaultDrainTimeout is how long a shutting down weavelet waits for its
+// in-flight remote calls, if the drain_timeout config field is not set.
+const defaultDrainTimeout = 5 * time.Second
+
 // A weavelet runs and manages components. As the name suggests, a weavelet is
 // analogous to a kubelet.
 type weavelet struct {
@@ -66,14 +73,61 @@ type weavelet struct {
 	componentsByName     map[string]*component       // component name -> component
 	componentsByType     map[reflect.Type]*component // component interface type -> component
 	componentsByImplType map[reflect.Type]*component // component impl type -> component
+	groups               map[string]string           // colocated component name -> group name
+
+	initializedMu sync.Mutex
+	initialized   []*component // initialized components, in initialization order
+
+	eagerMu sync.Mutex
+	eager   []*component // eager components hosted by the weavelet
 
 	listenersMu sync.Mutex
 	listeners   map[string]*listenerState
+
+	// The context of the weavelet's listeners, canceled by Shutdown to stop
+	// the servers running on them, which are counted by servers.
+	listenersCtx    context.Context
+	cancelListeners context.CancelFunc
+	servers         *activeServers
+
+	externalsMu sync.Mutex
+	externals   map[*component]*externalCheck // components of other applications
+
+	eventQueuesMu sync.Mutex
+	eventQueues   map[*component]*eventQueue // events published to subscribers
+
+	dialTimeout   time.Duration         // max time to wait for a remote component, or zero
+	drainTimeout  time.Duration         // max time to wait for in-flight calls on shutdown
+	drainer       call.Drainer          // drains the server of remote calls
+	draining      atomic.Bool           // is the weavelet shutting down?
+	stopServing   func()                // stops the server of remote calls, if any
+	metricsConfig runtime.MetricsConfig // configures runtime metrics
+	pprofConfig   runtime.ProfilingConfig
+	pprofAddr     string      // address serving the pprof handlers, if any
+	leader        atomic.Bool // runs one-replica tasks?
+
+	listenerTLS listenerTLSConfigs // TLS configs of listeners, keyed by name
+	listenerCfg listenerConfigs    // other options of listeners, keyed by name
+	externalCfg externalConfigs    // addresses of other applications' components
+	exportsDir  string             // where the deployer publishes exports, if it does
+	logConfig   logConfig          // log levels of components
+	certsMu     sync.Mutex
+	certs       []*certReloader // certificates of TLS listeners
+	sighupOnce  sync.Once       // starts reloading certificates on SIGHUP
+
+	statusMu     sync.Mutex
+	statusReport *StatusReport // cached report returned by Status
+
+	shutdownMu   sync.Mutex
+	shuttingDown chan struct{} // closed when Shutdown is first called
+	shutdownDone chan struct{} // closed when Shutdown finishes
+	shutdownErr  error         // the result of Shutdown, once shutdownDone is closed
 }
 
 type listenerState struct {
 	addr        string
 	initialized chan struct{} // Closed when addr has been filled
+	lis         net.Listener  // the listener, until closed by Shutdown
 }
 
 type transport struct {
@@ -84,6 +138,7 @@ type transport struct {
 type client struct {
 	resolver *routingResolver
 	balancer *routingBalancer
+	external bool // are the replicas found by a weaver.Resolver?
 }
 
 type server struct {
@@ -100,12 +155,17 @@ var _ private.App = &weavelet{}
 // newWeavelet returns a new weavelet.
 func newWeavelet(ctx context.Context, options private.AppOptions, componentInfos []*codegen.Registration) (*weavelet, error) {
 	w := &weavelet{
-		ctx:                  ctx,
 		overrides:            options.Fakes,
 		componentsByName:     make(map[string]*component, len(componentInfos)),
 		componentsByType:     make(map[reflect.Type]*component, len(componentInfos)),
 		componentsByImplType: make(map[reflect.Type]*component, len(componentInfos)),
 	}
+	w.ctx = withWeavelet(ctx, w)
+	w.listenersCtx, w.cancelListeners = context.WithCancel(w.ctx)
+	w.servers = newActiveServers()
+	w.shuttingDown = make(chan struct{})
+	w.shutdownDone = make(chan struct{})
+	trackWeavelet(w)
 
 	// TODO(mwhittaker): getEnv starts the WeaveletConn handler which calls
 	// methods of w, but w hasn't yet been fully constructed. This is a race.
@@ -120,6 +180,41 @@ func newWeavelet(ctx context.Context, options private.AppOptions, componentInfos
 		return nil, fmt.Errorf("unable to get weavelet information")
 	}
 	w.info = info
+	// A single process deployment has a single replica of every component.
+	w.leader.Store(info.SingleProcess)
+	if err := runtime.ParseConfigSection(listenerTLSKey, shortListenerTLSKey, info.Sections, &w.listenerTLS); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := runtime.ParseConfigSection(listenersKey, shortListenersKey, info.Sections, &w.listenerCfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := runtime.ParseConfigSection(externalsKey, shortExternalsKey, info.Sections, &w.externalCfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	w.exportsDir = os.Getenv(exports.DirKey)
+	if err := runtime.ParseConfigSection(logConfigKey, shortLogConfigKey, info.Sections, &w.logConfig); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	netConfig, err := runtime.ParseNetworkConfig(info.Sections)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	w.dialTimeout = netConfig.ComponentDialTimeout
+	w.drainTimeout = netConfig.DrainTimeout
+	if w.drainTimeout == 0 {
+		w.drainTimeout = defaultDrainTimeout
+	}
+	metricsConfig, err := runtime.ParseMetricsConfig(info.Sections)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if limit := metricsConfig.MetricCardinalityLimit; limit != 0 {
+		metrics.SetDefaultCardinalityLimit(limit)
+	}
+	w.metricsConfig = metricsConfig
+	if w.pprofConfig, err = runtime.ParseProfilingConfig(info.Sections); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
 
 	for _, info := range componentInfos {
 		c := &component{
@@ -129,11 +224,113 @@ func newWeavelet(ctx context.Context, options private.AppOptions, componentInfos
 			// Discard all log entries.
 			logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 1})),
 		}
+		if _, err := newBalancer(info); err != nil {
+			return nil, fmt.Errorf("component %s: %w", info.Name, err)
+		}
+		if _, err := newResolver(info); err != nil {
+			return nil, fmt.Errorf("component %s: %w", info.Name, err)
+		}
+		if c.methodTimeouts, err = parseMethodTimeouts(info, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.compressMinBytes, err = runtime.ParseCompressMinBytes(info.Name, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		window, err := runtime.ParseMetricsWindow(info.Name, w.info.Sections)
+		if err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if window > 0 {
+			codegen.SetMethodMetricsOptions(info.Name, codegen.MethodMetricsOptions{WindowDuration: window})
+		}
+		if c.rateLimiters, err = newRateLimiters(info, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.allowedCallers, err = newAllowedCallers(info, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.affinity, err = runtime.ParseAffinity(info.Name, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.affinity != nil && !info.Routed {
+			return nil, fmt.Errorf("parse config: section %q: %s is only supported for routed components", info.Name, runtime.AffinityKey)
+		}
+		c.routerHash = routerHash(info.Impl)
+		if c.prioritize, err = callPrioritizer(info.Impl); err != nil {
+			return nil, fmt.Errorf("component %s: %w", info.Name, err)
+		}
+		limit, err := runtime.ParseMaxConcurrentCalls(info.Name, w.info.Sections)
+		if err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		queue, err := runtime.ParseCallQueue(info.Name, w.info.Sections)
+		if err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if limit > 0 {
+			c.scheduler = newScheduler(info.Name, limit, queue)
+		}
+		c.singleton = isSingleton(info.Impl)
+		if c.eager, err = runtime.ParseEager(info.Name, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.crashOnPanic, err = runtime.ParseCrashOnPanic(info.Name, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.exported, err = runtime.ParseExported(info.Name, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+		if c.secrets, err = secretFields(info.Impl); err != nil {
+			return nil, fmt.Errorf("component %s: %w", info.Name, err)
+		}
+		if c.subscribes, err = subscribedEvent(info.Impl); err != nil {
+			return nil, fmt.Errorf("component %s: %w", info.Name, err)
+		}
+		if c.eventQueue, err = runtime.ParseEventQueue(info.Name, w.info.Sections); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
 		w.componentsByName[info.Name] = c
 		w.componentsByType[info.Iface] = c
 		w.componentsByImplType[info.Impl] = c
 	}
 
+	// Identify the colocation groups, which the allowed callers of remote
+	// calls are checked against.
+	colocate, err := runtime.ParseColocation(info.Sections)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	w.groups = map[string]string{}
+	for _, group := range colocate {
+		for _, component := range group {
+			w.groups[component] = group[0]
+		}
+	}
+	verified := info.SingleProcess || info.Mtls
+	for _, c := range w.componentsByName {
+		if err := checkAllowedCallers(c, w.componentsByName, verified); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+	}
+
+	if info.SingleProcess {
+		// Every component may be constructed in this process, so fail fast if
+		// any of them is missing a required secret, and keep the values for
+		// when the component is constructed. In other deployments, secrets
+		// are looked up when a component is constructed, since only the
+		// processes hosting a component need its secrets.
+		for _, c := range w.componentsByName {
+			if _, faked := w.overrides[c.info.Iface]; faked {
+				continue
+			}
+			secrets, err := lookupSecrets(ctx, c.secrets)
+			if err != nil {
+				return nil, fmt.Errorf("component %s: %w", c.info.Name, err)
+			}
+			c.secretValues = secrets
+		}
+	}
+
 	if info.Mtls {
 		// Initialize client side of the mTLS protocol.
 		for cname, c := range w.componentsByName {
@@ -175,12 +372,19 @@ func newWeavelet(ctx context.Context, options private.AppOptions, componentInfos
 		clientOpts: call.ClientOptions{
 			Logger:            env.SystemLogger(),
 			WriteFlattenLimit: 4 << 10,
+			KeepAlive:         netConfig.KeepAlive,
+			IdleTimeout:       netConfig.IdleTimeout,
 		},
 		serverOpts: call.ServerOptions{
 			Logger:                env.SystemLogger(),
 			Tracer:                tracer,
 			InlineHandlerDuration: 20 * time.Microsecond,
 			WriteFlattenLimit:     4 << 10,
+			Drainer:               &w.drainer,
+			// The handlers return the results of generated server stubs,
+			// which are encoder buffers that are not used after the stubs
+			// return.
+			ReleaseResult: codegen.ReleaseData,
 		},
 	}
 	w.tracer = tracer
@@ -235,13 +439,38 @@ func (w *weavelet) start() error {
 		}
 
 		server := &server{Listener: lis, wlet: w}
-		startWork(w.ctx, "handle calls", func() error {
-			return call.Serve(w.ctx, server, w.transport.serverOpts)
+		ctx, cancel := context.WithCancel(w.ctx)
+		w.stopServing = cancel
+		startWork(ctx, "handle calls", func() error {
+			return call.Serve(ctx, server, w.transport.serverOpts)
 		})
 	}
 
+	if !w.metricsConfig.DisableRuntimeMetrics {
+		interval := w.metricsConfig.RuntimeMetricsInterval
+		if interval == 0 {
+			interval = defaultRuntimeMetricsInterval
+		}
+		sampler := newRuntimeSampler(w.info.Id)
+		go sampler.run(w.ctx, interval)
+	}
+
+	if addr := w.pprofConfig.PprofAddress; addr != "" {
+		if err := w.servePprof(addr); err != nil {
+			return err
+		}
+	}
+
 	w.logRolodexCard()
 
+	if w.info.SingleProcess {
+		// Every component is hosted in this process. Other weavelets learn
+		// which components they host in UpdateComponents.
+		if err := w.startEager(maps.Values(w.componentsByName)); err != nil {
+			return err
+		}
+	}
+
 	// Make sure Main is initialized if local.
 	if _, err := w.getMainIfLocal(); err != nil {
 		return err
@@ -265,7 +494,7 @@ func (w *weavelet) Get(requester string, compType reflect.Type) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	result, _, err := w.getInstance(w.ctx, component, requester)
+	result, _, err := w.getInstance(w.ctx, component, requester, nil)
 	return result, err
 }
 
@@ -303,6 +532,9 @@ func (w *weavelet) logRolodexCard() {
 		fmt.Sprintf("   address    : %s", w.dialAddr),
 		fmt.Sprintf("   pid        : %v ", os.Getpid()),
 	}
+	if w.pprofAddr != "" {
+		lines = append(lines, fmt.Sprintf("   pprof      : http://%s/debug/pprof/ ", w.pprofAddr))
+	}
 
 	width := len(header)
 	for _, line := range lines {
@@ -323,8 +555,9 @@ func (w *weavelet) logRolodexCard() {
 // getInstance returns an instance of the provided component. If the component
 // is local, the results are a local stub used to invoke methods on the component
 // as well as the actual local object. Otherwise, the results are a network client
-// and nil.
-func (w *weavelet) getInstance(ctx context.Context, c *component, requester string) (any, any, error) {
+// and nil. If balancer is not nil, the network client uses it, rather than the
+// component's balancer, to pick the replicas its calls are sent to.
+func (w *weavelet) getInstance(ctx context.Context, c *component, requester string, balancer Balancer) (any, any, error) {
 	// Register the component.
 	c.registerInit.Do(func() {
 		w.env.SystemLogger().Debug("Activating component...", "component", c.info.Name)
@@ -347,83 +580,244 @@ func (w *weavelet) getInstance(ctx context.Context, c *component, requester stri
 		if err != nil {
 			return nil, nil, err
 		}
-		return c.info.LocalStubFn(impl.impl, requester, impl.component.tracer), impl.impl, nil
+		handle := withRateLimits(c, c.info.LocalStubFn(impl.impl, requester, impl.component.tracer), requester)
+		handle = withAllowedCallers(c, handle, requester)
+		return withMethodTimeouts(c, handle, requester, false), impl.impl, nil
 	}
 
-	stub, err := w.getStub(c)
+	stub, err := w.getStub(ctx, c)
 	if err != nil {
 		return nil, nil, err
 	}
-	return c.info.ClientStubFn(stub, requester), nil, nil
+	// Give every requester its own copy of the stub, so that the requester's
+	// name can be sent along with its calls.
+	s := *stub
+	s.caller = requester
+	if balancer != nil && !c.info.Routed {
+		s.balancer = &callBalancer{component: c.info.Name, balancer: balancer}
+	}
+	return withMethodTimeouts(c, c.info.ClientStubFn(&s, requester), requester, true), nil, nil
 }
 
 // getListener returns a network listener with the given name, along with its
-// proxy address.
-func (w *weavelet) getListener(name string) (net.Listener, string, error) {
+// proxy address and the options applied to its socket. reusePort is set by
+// the reuseport tag option of the listener.
+func (w *weavelet) getListener(name string, reusePort bool) (net.Listener, string, SocketOptions, error) {
 	if name == "" {
-		return nil, "", fmt.Errorf("getListener(%q): empty listener name", name)
+		return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): empty listener name", name)
 	}
 
 	// Get the address to listen on.
 	addr, err := w.env.GetListenerAddress(w.ctx, name)
 	if err != nil {
-		return nil, "", fmt.Errorf("getListener(%q): %w", name, err)
+		return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): %w", name, err)
 	}
 
 	// Listen on the address.
-	l, err := net.Listen("tcp", addr.Address)
+	sock, isUnix, err := parseUnixSocket(addr.Address)
+	if err != nil {
+		return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): %w", name, err)
+	}
+	cfg := w.listenerCfg[name]
+	var l net.Listener
+	var sockOpts SocketOptions
+	if isUnix {
+		if cfg.hasSocketOptions() {
+			return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): socket options in the [%s] config section only apply to TCP listeners, but the listener listens on %s", name, shortListenersKey, addr.Address)
+		}
+		l, err = sock.listen()
+	} else {
+		// The options were validated when the config was parsed.
+		sockOpts, err = cfg.socketOptions()
+		if err != nil {
+			return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): %w", name, err)
+		}
+		sockOpts.ReusePort = sockOpts.ReusePort || reusePort
+		l, sockOpts, err = w.listenTCP(name, addr.Address, sockOpts)
+	}
 	if err != nil {
-		return nil, "", fmt.Errorf("getListener(%q): %w", name, err)
+		return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): %w", name, err)
 	}
+	dialAddr := listenerAddress(l)
 
 	// Export the listener.
-	errMsg := fmt.Sprintf("getListener(%q): error exporting listener %v", name, l.Addr())
+	errMsg := fmt.Sprintf("getListener(%q): error exporting listener %v", name, dialAddr)
 	var reply *protos.ExportListenerReply
 	if err := w.repeatedly(errMsg, func() error {
 		var err error
-		reply, err = w.env.ExportListener(w.ctx, name, l.Addr().String())
+		reply, err = w.env.ExportListener(w.ctx, name, dialAddr)
 		return err
 	}); err != nil {
-		return nil, "", err
+		return nil, "", SocketOptions{}, err
 	}
 	if reply.Error != "" {
-		return nil, "", fmt.Errorf("getListener(%q): %s", name, reply.Error)
+		return nil, "", SocketOptions{}, fmt.Errorf("getListener(%q): %s", name, reply.Error)
 	}
 
 	w.listenersMu.Lock()
 	defer w.listenersMu.Unlock()
 	ls := w.getListenerState(name)
-	ls.addr = l.Addr().String()
+	ls.addr = dialAddr
+	ls.lis = l
 	close(ls.initialized) // Mark as initialized
 
-	return l, reply.ProxyAddress, nil
+	// A Unix domain socket is only reachable on this machine, so it is never
+	// fronted by a proxy.
+	if isUnix {
+		return l, "", sockOpts, nil
+	}
+	return l, reply.ProxyAddress, sockOpts, nil
 }
 
 // addHandlers registers a component's methods as handlers in the given map.
 // Specifically, for every method m in the component, we register a function f
 // that (1) creates the local component if it hasn't been created yet and (2)
-// calls m.
-func (w *weavelet) addHandlers(handlers *call.HandlerMap, c *component) {
+// calls m. peer is the verified identity of the weavelet issuing the calls, or
+// empty if unknown.
+func (w *weavelet) addHandlers(handlers *call.HandlerMap, c *component, peer string) {
 	for i, n := 0, c.info.Iface.NumMethod(); i < n; i++ {
 		mname := c.info.Iface.Method(i).Name
+		dm := &dispatchMetrics{component: c.info.Name, method: mname}
+		if isStreamMethod(c.info.Iface.Method(i).Type) {
+			handlers.SetStream(c.info.Name, mname, w.streamHandler(c, mname, peer, dm))
+			continue
+		}
 		handler := func(ctx context.Context, args []byte) (res []byte, err error) {
-			// This handler is supposed to invoke the method named mname on the
-			// local component. However, it is possible that the component has not
-			// yet been started (e.g., the start command was issued but hasn't
-			// yet taken effect). d.getImpl(c) will start the component if it
-			// hasn't already been started, or it will be a noop if the component
-			// has already been started.
-			impl, err := w.getImpl(w.ctx, c)
+			ctx, impl, done, err := w.beginRemoteCall(ctx, c, mname, peer, dm)
 			if err != nil {
 				return nil, err
 			}
+			defer done()
 			fn := impl.serverStub.GetStubFn(mname)
+			defer w.recoverPanic(c, mname, &err)
 			return fn(ctx, args)
 		}
 		handlers.Set(c.info.Name, mname, handler)
 	}
 }
 
+// streamHandler returns a handler for a method of a component that returns a
+// stream. See addHandlers.
+func (w *weavelet) streamHandler(c *component, mname string, peer string, dm *dispatchMetrics) call.StreamHandler {
+	return func(ctx context.Context, args []byte, send func([]byte) error) (res []byte, err error) {
+		ctx, impl, done, err := w.beginRemoteCall(ctx, c, mname, peer, dm)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		server, ok := impl.serverStub.(codegen.StreamServer)
+		if !ok {
+			return nil, fmt.Errorf("component %s: method %s returns a stream, but its server stub doesn't support streaming; re-run 'weaver generate'", c.info.Name, mname)
+		}
+		fn := server.GetStreamFn(mname)
+		defer w.recoverPanic(c, mname, &err)
+		return fn(ctx, args, send)
+	}
+}
+
+// beginRemoteCall runs the steps that precede the execution of every remote
+// call to the method mname of component c, issued by the weavelet with
+// verified identity peer: it authorizes and admits the call, starts the
+// component if needed, applies the method's rate limit, and waits for the
+// call's turn to run (see schedule), recording how long the call waited in
+// the dispatch metrics dm. It returns the context to run the method with,
+// the component's implementation, and a function to call when the method
+// returns.
+func (w *weavelet) beginRemoteCall(ctx context.Context, c *component, mname, peer string, dm *dispatchMetrics) (context.Context, *componentImpl, func(), error) {
+	received := receivedTime(ctx)
+	ctx, err := extractContextValues(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("component %s: method %s: %w", c.info.Name, mname, err)
+	}
+	if err := c.authorizeRemote(call.Caller(ctx), peer, mname); err != nil {
+		return nil, nil, nil, err
+	}
+	exit, err := c.admitRemoteCall(mname)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The handler is supposed to invoke the method named mname on the local
+	// component. However, it is possible that the component has not yet been
+	// started (e.g., the start command was issued but hasn't yet taken
+	// effect). w.getImpl(c) will start the component if it hasn't already
+	// been started, or it will be a noop if the component has already been
+	// started.
+	impl, err := w.getImpl(w.ctx, c)
+	if err != nil {
+		exit()
+		return nil, nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		// The caller's deadline expired while the component was starting.
+		// Don't run a method whose result will be dropped.
+		exit()
+		return nil, nil, nil, fmt.Errorf("component %s: method %s: caller gave up before the call started: %w", c.info.Name, mname, err)
+	}
+	if err := c.rateLimit(ctx, call.Caller(ctx), mname, true); err != nil {
+		exit()
+		return nil, nil, nil, err
+	}
+	ctx = withWeavelet(ctx, w)
+	ctx = codegen.WithCallerInfo(ctx, codegen.CallerInfo{
+		Component: call.Caller(ctx),
+		Identity:  peer,
+		Callee:    c.info.Name,
+	})
+	priority, release, err := c.schedule(ctx, mname)
+	if err != nil {
+		exit()
+		return nil, nil, nil, err
+	}
+	m := dm.get(call.Caller(ctx), priority)
+	m.EndDispatch(m.BeginDispatch(received))
+	return ctx, impl, func() { release(); exit() }, nil
+}
+
+// receivedTime returns the time at which the remote call with the provided
+// context was received.
+func receivedTime(ctx context.Context) time.Time {
+	if received := call.Received(ctx); !received.IsZero() {
+		return received
+	}
+	return time.Now()
+}
+
+// dispatchMetrics holds the metrics of a component method that are recorded
+// by the server handling remote calls to it, namely the queue wait of the
+// calls. The metrics are labeled with the calling component and the priority
+// of the calls (see WithPriority), so they are created lazily, on the first
+// call from every caller with every priority.
+type dispatchMetrics struct {
+	component string   // full component name
+	method    string   // method name
+	byCaller  sync.Map // dispatchKey -> *codegen.MethodMetrics
+}
+
+// dispatchKey identifies the metrics of a dispatchMetrics.
+type dispatchKey struct {
+	caller   string // caller name
+	priority int    // call priority
+}
+
+// get returns the metrics for calls with the provided priority issued by the
+// provided caller.
+func (d *dispatchMetrics) get(caller string, priority int) *codegen.MethodMetrics {
+	key := dispatchKey{caller, priority}
+	if m, ok := d.byCaller.Load(key); ok {
+		return m.(*codegen.MethodMetrics)
+	}
+	m := codegen.MethodMetricsFor(codegen.MethodLabels{
+		Caller:    caller,
+		Component: d.component,
+		Method:    d.method,
+		Remote:    true,
+		Priority:  priority,
+	})
+	actual, _ := d.byCaller.LoadOrStore(key, m)
+	return actual.(*codegen.MethodMetrics)
+}
+
 func (w *weavelet) ListenerAddress(name string) (string, error) {
 	w.listenersMu.Lock()
 	ls := w.getListenerState(name)
@@ -482,6 +876,7 @@ func (w *weavelet) UpdateComponents(req *protos.UpdateComponentsRequest) (*proto
 	components := slices.Clone(req.Components)
 	w.env.SystemLogger().Debug("UpdateComponents", "components", components)
 	go func() {
+		var cs []*component
 		for _, component := range components {
 			c, err := w.getComponent(component)
 			if err != nil {
@@ -489,9 +884,20 @@ func (w *weavelet) UpdateComponents(req *protos.UpdateComponentsRequest) (*proto
 				w.env.SystemLogger().Error("getComponent", "err", err, "component", component)
 				return
 			}
-			if _, err = w.getImpl(w.ctx, c); err != nil {
+			cs = append(cs, c)
+		}
+
+		// An eager component that can't be created fails the weavelet,
+		// rather than every call to the component.
+		if err := w.startEager(cs); err != nil && w.ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "start eager components: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, c := range cs {
+			if _, err := w.getImpl(w.ctx, c); err != nil {
 				// TODO(mwhittaker): Propagate errors.
-				w.env.SystemLogger().Error("getImpl", "err", err, "component", component)
+				w.env.SystemLogger().Error("getImpl", "err", err, "component", c.info.Name)
 				return
 			}
 		}
@@ -534,7 +940,9 @@ func (w *weavelet) UpdateRoutingInfo(req *protos.UpdateRoutingInfoRequest) (repl
 	if err != nil {
 		return nil, err
 	}
-	client.resolver.update(endpoints)
+	if !client.external {
+		client.resolver.update(endpoints)
+	}
 	client.balancer.update(req.RoutingInfo.Assignment)
 
 	// Update local.
@@ -542,6 +950,13 @@ func (w *weavelet) UpdateRoutingInfo(req *protos.UpdateRoutingInfoRequest) (repl
 	return &protos.UpdateRoutingInfoReply{}, nil
 }
 
+// UpdateLeader implements the conn.WeaverHandler interface.
+func (w *weavelet) UpdateLeader(req *protos.UpdateLeaderRequest) (*protos.UpdateLeaderReply, error) {
+	w.env.SystemLogger().Debug("UpdateLeader", "leader", req.Leader)
+	w.leader.Store(req.Leader)
+	return &protos.UpdateLeaderReply{}, nil
+}
+
 // getComponent returns the component with the given name.
 func (w *weavelet) getComponent(name string) (*component, error) {
 	// Note that we don't need to lock d.components because, while the components
@@ -580,6 +995,23 @@ func (w *weavelet) getComponentByImplType(t reflect.Type) (*component, error) {
 
 // getImpl returns a component's componentImpl, initializing it if necessary.
 func (w *weavelet) getImpl(ctx context.Context, c *component) (*componentImpl, error) {
+	// Constructing c fills its Ref fields, which may construct other local
+	// components. If c is already being constructed by this chain of calls,
+	// the components form a cycle, and waiting on c.implMu below would
+	// deadlock.
+	chain, _ := ctx.Value(constructingKey{}).([]*component)
+	for i, d := range chain {
+		if d == c {
+			names := make([]string, 0, len(chain)-i+1)
+			for _, d := range chain[i:] {
+				names = append(names, d.info.Name)
+			}
+			names = append(names, c.info.Name)
+			return nil, fmt.Errorf("weaver.Ref cycle: %s", strings.Join(names, " -> "))
+		}
+	}
+	ctx = context.WithValue(ctx, constructingKey{}, append(chain[:len(chain):len(chain)], c))
+
 	init := func(c *component) error {
 		// We have to initialize these fields before passing to c.info.fn
 		// because the user's constructor may use them.
@@ -596,11 +1028,24 @@ func (w *weavelet) getImpl(ctx context.Context, c *component) (*componentImpl, e
 				Weavelet:   w.info.Id,
 			},
 			Write: w.env.CreateLogSaver(),
+			Level: w.logConfig.level(c.info.Name),
 		})
+		c.ctxLogger.Store(c.logger)
 		c.tracer = w.tracer
 
 		w.env.SystemLogger().Debug("Constructing component", "component", c.info.Name)
+		_, faked := w.overrides[c.info.Iface]
+		singleton := c.singleton && !faked
+		if singleton {
+			if err := claimSingleton(w, c); err != nil {
+				w.env.SystemLogger().Error("Constructing component failed", "err", err, "component", c.info.Name)
+				return err
+			}
+		}
 		if err := w.createComponent(ctx, c); err != nil {
+			if singleton {
+				releaseSingleton(w, c)
+			}
 			w.env.SystemLogger().Error("Constructing component failed", "err", err, "component", c.info.Name)
 			return err
 		}
@@ -615,10 +1060,30 @@ func (w *weavelet) getImpl(ctx context.Context, c *component) (*componentImpl, e
 		})
 		return nil
 	}
-	c.implInit.Do(func() { c.implErr = init(c) })
+
+	c.implMu.Lock()
+	defer c.implMu.Unlock()
+	if c.implDone {
+		return c.impl, nil
+	}
+	if c.implErr != nil {
+		// A previous attempt to create the component failed, so this is a
+		// restart. Frequent restarts point to a flapping component.
+		labels := codegen.ComponentLabels{Component: c.info.Name}
+		codegen.ComponentRestarts.Get(labels).Inc()
+		codegen.ComponentLastRestart.Get(labels).Set(float64(time.Now().Unix()))
+		w.env.SystemLogger().Info("Restarting component", "component", c.info.Name, "err", c.implErr)
+	}
+	c.implErr = init(c)
+	c.implDone = c.implErr == nil
 	return c.impl, c.implErr
 }
 
+// constructingKey is the context key under which getImpl stores the
+// components, as a []*component, that are being constructed by the current
+// chain of calls, with each component referring to the next.
+type constructingKey struct{}
+
 func (w *weavelet) createComponent(ctx context.Context, c *component) error {
 	if obj, ok := w.overrides[c.info.Iface]; ok {
 		// Use supplied implementation (typically a weavertest fake).
@@ -633,11 +1098,21 @@ func (w *weavelet) createComponent(ctx context.Context, c *component) error {
 	// Fill config if necessary.
 	if cfg := config.Config(v); cfg != nil {
 		// Populate the *T.
-		if err := runtime.ParseConfigSection(c.info.Name, "", c.wlet.info.Sections, cfg); err != nil {
+		if err := runtime.ParseComponentConfig(c.info.Name, c.wlet.info.Sections, cfg); err != nil {
 			return err
 		}
 	}
 
+	// Fill secret fields.
+	secrets := c.secretValues
+	if secrets == nil {
+		var err error
+		if secrets, err = lookupSecrets(ctx, c.secrets); err != nil {
+			return fmt.Errorf("component %q: %w", c.info.Name, err)
+		}
+	}
+	fillSecrets(obj, c.secrets, secrets)
+
 	// Set obj.Implements.component to c.
 	if i, ok := obj.(interface{ setInstance(*componentImpl) }); !ok {
 		return fmt.Errorf("component %q: type %T is not a component implementation", c.info.Name, obj)
@@ -646,34 +1121,231 @@ func (w *weavelet) createComponent(ctx context.Context, c *component) error {
 	}
 
 	// Fill ref fields.
-	err := fillRefs(obj, func(refType reflect.Type) (any, error) {
+	err := fillRefs(obj, func(refType reflect.Type, balancer Balancer) (refTarget, error) {
 		sub, err := w.getComponentByType(refType)
 		if err != nil {
-			return nil, err
+			return refTarget{}, err
+		}
+		r, _, err := w.getInstance(ctx, sub, c.info.Name, balancer)
+		if err != nil {
+			return refTarget{}, err
+		}
+		target := refTarget{value: r, id: sub.info.Name}
+		target.keyed = func(key any) any {
+			return w.getKeyedInstance(sub, c.info.Name, key)
+		}
+		if !sub.local.Read() {
+			target.replicas = func(ctx context.Context) ([]replica, error) {
+				return w.getReplicas(ctx, sub, c.info.Name)
+			}
 		}
-		r, _, err := w.getInstance(ctx, sub, c.info.Name)
-		return r, err
+		return target, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Fill external ref fields.
+	err = fillExternalRefs(obj, func(t reflect.Type) (any, error) {
+		return w.getExternal(c, t)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Fill publisher fields.
+	err = fillPublishers(obj, func(t reflect.Type) (func(context.Context, []byte) error, error) {
+		return w.getPublisher(c.info.Name, t)
 	})
 	if err != nil {
 		return err
 	}
 
 	// Fill listener fields.
-	err = fillListeners(obj, w.getListener)
+	err = fillListeners(obj, func(name string, opts listenerOptions) (Listener, error) {
+		useTLS := opts.tls
+		cfg, hasCert := w.listenerTLS[name]
+		if useTLS && !hasCert {
+			return Listener{}, fmt.Errorf("listener %q requires TLS, but no certificate is configured in the [%s] config section", name, shortListenerTLSKey)
+		}
+		if !useTLS && hasCert {
+			return Listener{}, fmt.Errorf("listener %q has a certificate configured in the [%s] config section, but is not tagged with the tls option", name, shortListenerTLSKey)
+		}
+		var cert *certReloader
+		if useTLS {
+			var err error
+			if cert, err = newCertReloader(cfg.CertFile, cfg.KeyFile); err != nil {
+				return Listener{}, fmt.Errorf("listener %q: %w", name, err)
+			}
+		}
+		l, proxyAddr, sockOpts, err := w.getListener(name, opts.reusePort)
+		if err != nil {
+			return Listener{}, err
+		}
+		if useTLS {
+			l = newTLSListener(l, cert)
+			w.addCertificate(cert)
+		}
+		lis := Listener{Listener: l, proxyAddr: w.listenerCfg.proxyAddr(name, proxyAddr), tls: useTLS, sockOpts: sockOpts, ctx: w.listenersCtx, servers: w.servers, logger: c.logger, draining: w.draining.Load, component: c.info.Name, tracer: c.tracer, name: name}
+		if h, ok := obj.(interface{ HealthCheck(context.Context) error }); ok {
+			lis.health = h.HealthCheck
+		}
+		return lis, nil
+	})
 	if err != nil {
 		return err
 	}
 
 	// Call Init if available.
-	if i, ok := obj.(interface{ Init(context.Context) error }); ok {
+	if i, ok := obj.(Initializable); ok {
 		if err := i.Init(ctx); err != nil {
 			return fmt.Errorf("component %q initialization failed: %w", c.info.Name, err)
 		}
 	}
 	c.impl.impl = obj
+	w.initializedMu.Lock()
+	w.initialized = append(w.initialized, c)
+	w.initializedMu.Unlock()
+
+	// Start background tasks, if any.
+	if t, ok := obj.(WithTasks); ok {
+		runners, err := newTaskRunners(c.info.Name, t.Tasks(), w.leader.Load, c.logger)
+		if err != nil {
+			return err
+		}
+		for _, r := range runners {
+			go r.run(w.ctx)
+		}
+	}
 	return nil
 }
 
+// Shutdown shuts the weavelet down, at most once. Concurrent and later calls
+// wait for the first call to finish, or for their ctx to be done, and return
+// its result. See shutdown.
+func (w *weavelet) Shutdown(ctx context.Context) error {
+	w.shutdownMu.Lock()
+	select {
+	case <-w.shuttingDown:
+		w.shutdownMu.Unlock()
+		select {
+		case <-w.shutdownDone:
+			return w.shutdownErr
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for shutdown in progress: %w", ctx.Err())
+		}
+	default:
+	}
+	close(w.shuttingDown)
+	w.shutdownMu.Unlock()
+
+	w.shutdownErr = w.shutdown(ctx)
+	close(w.shutdownDone)
+	return w.shutdownErr
+}
+
+// shutdown quiesces the components hosted by the weavelet, drains the remote
+// calls it executes, stops its listeners, and waits for the events it
+// published to be delivered. It then calls the Shutdown method of every
+// initialized component that implements Finalizable. A component is shut
+// down before the components it depends on. It returns the errors of all
+// failed Shutdown calls, and ctx.Err() if ctx is done before the shutdown
+// finishes.
+func (w *weavelet) shutdown(ctx context.Context) error {
+	w.quiesceAll(ctx)
+	w.drain(ctx)
+	w.stopListeners(ctx)
+	w.flushEvents(ctx)
+
+	w.initializedMu.Lock()
+	components := w.initialized
+	w.initialized = nil // Shut down components at most once.
+	w.initializedMu.Unlock()
+	untrackWeavelet(w)
+
+	var errs []error
+	for _, c := range shutdownOrder(components) {
+		f, ok := c.impl.impl.(Finalizable)
+		if !ok {
+			continue
+		}
+		w.env.SystemLogger().Debug("Shutting down component", "component", c.info.Name)
+		if err := f.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("component %q shutdown failed: %w", c.info.Name, err))
+		}
+	}
+	releaseSingletons(w)
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown did not finish: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// drain stops the weavelet from executing new remote calls and waits, for at
+// most the drain timeout, for the remote calls it is executing to finish.
+// Callers retry the calls that the weavelet rejects on other replicas. drain
+// then closes the weavelet's connections, canceling the calls that are still
+// running.
+func (w *weavelet) drain(ctx context.Context) {
+	if !w.draining.CompareAndSwap(false, true) || w.stopServing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.drainTimeout)
+	defer cancel()
+	w.env.SystemLogger().Debug("Draining remote calls", "timeout", w.drainTimeout)
+	if err := w.drainer.Drain(ctx); err != nil {
+		w.env.SystemLogger().Error("Draining remote calls did not finish", "err", err)
+	}
+	w.stopServing()
+}
+
+// shutdownOrder returns the provided components, ordered such that every
+// component comes before the components it depends on. Note that components
+// are not necessarily initialized in dependency order, since a component
+// doesn't wait for its remote dependencies to be initialized.
+func shutdownOrder(components []*component) []*component {
+	order := dependencyOrder(components)
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// dependencyOrder returns the provided components, ordered such that every
+// component comes after the components it depends on.
+func dependencyOrder(components []*component) []*component {
+	deps := map[reflect.Type][]reflect.Type{}
+	for _, edge := range codegen.CallGraph() {
+		deps[edge.Caller] = append(deps[edge.Caller], edge.Callee)
+	}
+	byType := map[reflect.Type]*component{}
+	for _, c := range components {
+		byType[c.info.Iface] = c
+	}
+
+	// Order the components with dependencies first, using a depth-first
+	// search. Cycles are broken arbitrarily.
+	visited := map[*component]bool{}
+	var order []*component
+	var visit func(*component)
+	visit = func(c *component) {
+		if visited[c] {
+			return
+		}
+		visited[c] = true
+		for _, dep := range deps[c.info.Iface] {
+			if d, ok := byType[dep]; ok {
+				visit(d)
+			}
+		}
+		order = append(order, c)
+	}
+	for _, c := range components {
+		visit(c)
+	}
+	return order
+}
+
 func (w *weavelet) repeatedly(errMsg string, f func() error) error {
 	for r := retry.Begin(); r.Continue(w.ctx); {
 		if err := f(); err != nil {
@@ -688,58 +1360,180 @@ func (w *weavelet) repeatedly(errMsg string, f func() error) error {
 // getClient returns a component's network client, initializing it if necessary.
 func (w *weavelet) getClient(c *component) *client {
 	c.clientInit.Do(func() {
+		balancer := newRoutingBalancer(c.clientTLS, w.newCallBalancer(c))
+		if c.affinity != nil {
+			balancer.affinity = newAffinityCache(c.info.Name, c.affinity)
+		}
 		c.client = &client{
 			resolver: newRoutingResolver(),
-			balancer: newRoutingBalancer(c.clientTLS),
+			balancer: balancer,
+		}
+
+		// Note that newWeavelet already checked that newResolver succeeds.
+		if r, err := newResolver(c.info); err == nil && r != nil {
+			c.client.external = true
+			go watchResolver(w.ctx, w.env.SystemLogger(), c, r, c.client.resolver, resolverRefreshInterval)
 		}
 	})
 	return c.client
 }
 
+// newCallBalancer returns a new balancer for calls to the provided component.
+// The balancer is the one specified by the component's weaver.WithBalancer
+// embedding, if any, or a round-robin balancer otherwise.
+func (w *weavelet) newCallBalancer(c *component) call.Balancer {
+	// Note that newWeavelet already checked that newBalancer succeeds.
+	b, err := newBalancer(c.info)
+	if err != nil || b == nil {
+		return call.RoundRobin()
+	}
+	return &callBalancer{component: c.info.Name, balancer: b}
+}
+
 // getStub returns a component's componentStub, initializing it if necessary.
-func (w *weavelet) getStub(c *component) (*stub, error) {
-	init := func(c *component) error {
-		// Initialize the client.
-		w.env.SystemLogger().Debug("Creating a connection to a remote component...", "component", c.info.Name)
-		client := w.getClient(c)
+//
+// The stub is initialized in the background and is shared by all callers.
+// getStub waits for the initialization to finish or for ctx to be done,
+// whichever happens first, so a caller with a short deadline isn't held
+// hostage by a remote component that is slow to become available. The
+// initialization itself is bounded by the component_dial_timeout, if any, and
+// is not affected by a caller's ctx. If it fails, e.g., because no replica of
+// the component became available in time, it is retried with backoff by later
+// calls to getStub, so that the component can be reached once its replicas
+// come up.
+func (w *weavelet) getStub(ctx context.Context, c *component) (*stub, error) {
+	s, err := c.stub.get(ctx, func() (*stub, error) { return w.initStub(c) })
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return nil, fmt.Errorf("connect to component %q: %w", c.info.Name, err)
+	}
+	return s, err
+}
 
-		// Create the client connection.
-		opts := w.transport.clientOpts
-		conn, err := call.Connect(w.ctx, client.resolver, opts)
-		if err != nil {
-			w.env.SystemLogger().Error("Creating a connection to remote component failed", "err", err, "component", c.info.Name)
-			return err
-		}
-		if err := waitUntilReady(w.ctx, conn); err != nil {
-			w.env.SystemLogger().Error("Waiting for remote component failed", "err", err, "component", c.info.Name)
-			return err
-		}
+// initStub returns a new componentStub for a component.
+func (w *weavelet) initStub(c *component) (*stub, error) {
+	// Initialize the client.
+	w.env.SystemLogger().Debug("Creating a connection to a remote component...", "component", c.info.Name)
+	client := w.getClient(c)
 
-		w.env.SystemLogger().Debug("Creating connection to remote component succeeded", "component", c.info.Name)
+	// Create the client connection.
+	opts := w.transport.clientOpts
+	if !c.info.Routed {
+		// Routed components use the balancer passed with every call.
+		opts.Balancer = w.newCallBalancer(c)
+	}
+	conn, err := call.Connect(w.ctx, client.resolver, opts)
+	if err != nil {
+		w.env.SystemLogger().Error("Creating a connection to remote component failed", "err", err, "component", c.info.Name)
+		return nil, err
+	}
 
-		// Construct the keys for the methods.
-		n := c.info.Iface.NumMethod()
-		methods := make([]call.MethodKey, n)
-		for i := 0; i < n; i++ {
-			mname := c.info.Iface.Method(i).Name
-			methods[i] = call.MakeMethodKey(c.info.Name, mname)
+	// Wait for the component to become available. Note that the connection
+	// outlives the dial timeout, so only the wait is bounded by it.
+	ready := w.ctx
+	if w.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ready, cancel = context.WithTimeout(w.ctx, w.dialTimeout)
+		defer cancel()
+	}
+	if err := waitUntilReady(ready, conn); err != nil {
+		conn.Close()
+		if w.ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("component %q could not be reached within the component_dial_timeout of %v", c.info.Name, w.dialTimeout)
 		}
+		w.env.SystemLogger().Error("Waiting for remote component failed", "err", err, "component", c.info.Name)
+		return nil, err
+	}
+
+	w.env.SystemLogger().Debug("Creating connection to remote component succeeded", "component", c.info.Name)
+
+	// Construct the keys for the methods.
+	n := c.info.Iface.NumMethod()
+	methods := make([]call.MethodKey, n)
+	names := make([]string, n)
+	for i := 0; i < n; i++ {
+		mname := c.info.Iface.Method(i).Name
+		methods[i] = call.MakeMethodKey(c.info.Name, mname)
+		names[i] = mname
+	}
 
-		var balancer call.Balancer
-		if c.info.Routed {
-			balancer = client.balancer
+	var balancer call.Balancer
+	if c.info.Routed {
+		balancer = client.balancer
+	}
+	return &stub{
+		component: c.info.Name,
+		conn:      conn,
+		methods:   methods,
+		names:     names,
+		balancer:  balancer,
+		tracer:    w.tracer,
+
+		compressMinBytes: c.compressMinBytes,
+	}, nil
+}
+
+// getKeyedInstance returns a handle to the provided component whose calls are
+// routed by key. See Ref.ForKey.
+//
+// REQUIRES: getInstance(ctx, c, requester, ...) has succeeded.
+func (w *weavelet) getKeyedInstance(c *component, requester string, key any) any {
+	shardKey := c.shardKey(key)
+	local := c.local.Read()
+	var handle any
+	if local {
+		impl, err := w.getImpl(w.ctx, c)
+		if err != nil {
+			// getInstance succeeded, so getImpl has succeeded before.
+			panic(fmt.Errorf("component %q: %w", c.info.Name, err))
+		}
+		handle = withRateLimits(c, c.info.LocalStubFn(impl.impl, requester, impl.component.tracer), requester)
+		handle = withAllowedCallers(c, handle, requester)
+	} else {
+		// The stub was initialized by getInstance, so getStub doesn't block.
+		stub, err := w.getStub(w.ctx, c)
+		if err != nil {
+			panic(fmt.Errorf("component %q: %w", c.info.Name, err))
 		}
-		c.stub = &stub{
-			component: c.info.Name,
-			conn:      conn,
-			methods:   methods,
-			balancer:  balancer,
-			tracer:    w.tracer,
+		s := *stub
+		s.caller = requester
+		s.keyed = true
+		s.key = shardKey
+		if !c.info.Routed {
+			s.balancer = call.Sharded()
 		}
-		return nil
+		handle = c.info.ClientStubFn(&s, requester)
+	}
+	handle = withMethodTimeouts(c, handle, requester, !local)
+	if c.info.InterceptFn == nil {
+		return handle
 	}
-	c.stubInit.Do(func() { c.stubErr = init(c) })
-	return c.stub, c.stubErr
+	value := fmt.Sprint(key)
+	setKey := func(ctx context.Context, call codegen.Call, next codegen.Handler) ([]any, error) {
+		return next(SetMetadata(ctx, RoutingKeyMetadataKey, value), call.Args)
+	}
+	return c.info.InterceptFn(handle, setKey, codegen.Call{Caller: requester, Component: c.info.Name, Remote: !local})
+}
+
+// getReplicas returns a client stub for every replica of the provided remote
+// component. Every stub sends all of its calls to its replica.
+func (w *weavelet) getReplicas(ctx context.Context, c *component, requester string) ([]replica, error) {
+	stub, err := w.getStub(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, _, err := w.getClient(c).resolver.Resolve(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]replica, len(endpoints))
+	for i, endpoint := range endpoints {
+		s := *stub
+		s.caller = requester
+		s.balancer = &pinnedBalancer{addr: endpoint.Address()}
+		value := withMethodTimeouts(c, c.info.ClientStubFn(&s, requester), requester, true)
+		replicas[i] = replica{addr: endpoint.Address(), value: value}
+	}
+	return replicas, nil
 }
 
 func waitUntilReady(ctx context.Context, client call.Connection) error {
@@ -773,13 +1567,14 @@ func (s *server) Accept() (net.Conn, *call.HandlerMap, error) {
 
 	if !s.wlet.info.Mtls {
 		// No security: all components are accessible.
-		hm, err := s.handlers(maps.Keys(s.wlet.componentsByName))
+		hm, err := s.handlers(maps.Keys(s.wlet.componentsByName), "")
 		return conn, hm, err
 	}
 
 	// Establish a TLS connection with the client and get the list of
 	// components it can access.
 	var accessibleComponents []string
+	var peer string
 	tlsConfig := &tls.Config{
 		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
 			return s.wlet.getSelfCertificate()
@@ -788,6 +1583,10 @@ func (s *server) Accept() (net.Conn, *call.HandlerMap, error) {
 		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
 			var err error
 			accessibleComponents, err = s.wlet.env.VerifyClientCertificate(s.wlet.ctx, rawCerts)
+			if err != nil {
+				return err
+			}
+			peer, err = peerIdentity(rawCerts)
 			return err
 		},
 	}
@@ -797,12 +1596,34 @@ func (s *server) Accept() (net.Conn, *call.HandlerMap, error) {
 	}
 
 	// NOTE: VerifyPeerCertificate above has been called at this point.
-	hm, err := s.handlers(accessibleComponents)
+	hm, err := s.handlers(accessibleComponents, peer)
 	return tlsConn, hm, err
 }
 
-// handlers returns method handlers for the given components.
-func (s *server) handlers(components []string) (*call.HandlerMap, error) {
+// peerIdentity returns the identity of a peer, i.e., the only name stored in
+// its leaf certificate (see runtime.PeerIdentity). The certificate must have
+// already been verified. If the certificate doesn't hold a well-formed
+// identity, peerIdentity returns an error.
+func peerIdentity(rawCerts [][]byte) (string, error) {
+	if len(rawCerts) == 0 {
+		return "", fmt.Errorf("no peer certificate")
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return "", fmt.Errorf("peer certificate: %w", err)
+	}
+	if len(cert.DNSNames) != 1 {
+		return "", fmt.Errorf("peer certificate: want a single name, got %v", cert.DNSNames)
+	}
+	if _, _, err := runtime.ParsePeerIdentity(cert.DNSNames[0]); err != nil {
+		return "", fmt.Errorf("peer certificate: %w", err)
+	}
+	return cert.DNSNames[0], nil
+}
+
+// handlers returns method handlers for the given components. peer is the
+// identity of the weavelet that will issue calls to the handlers.
+func (s *server) handlers(components []string, peer string) (*call.HandlerMap, error) {
 	// Note that the components themselves may not be started, but we still
 	// register their handlers to avoid concurrency issues with on-demand
 	// handler additions.
@@ -812,13 +1633,29 @@ func (s *server) handlers(components []string) (*call.HandlerMap, error) {
 		if err != nil {
 			return nil, err
 		}
-		s.wlet.addHandlers(hm, c)
+		s.wlet.addHandlers(hm, c, peer)
 	}
 
-	// Add a dummy "ready" handler. Clients will repeatedly call this
-	// RPC until it responds successfully, ensuring the server is ready.
+	// Add the handlers used by the peer to deliver the events it publishes
+	// to subscriber components. A peer may deliver events to a subscriber
+	// without being allowed to call its methods.
+	for _, c := range s.wlet.componentsByName {
+		s.wlet.addEventHandler(hm, c, peer)
+	}
+
+	// Add a "ready" handler. Clients will repeatedly call this RPC until it
+	// responds successfully, ensuring the server is ready. The server isn't
+	// ready until its eager components have been created.
 	hm.Set("", "ready", func(context.Context, []byte) ([]byte, error) {
-		return nil, nil
+		return nil, s.wlet.waitEager()
 	})
+
+	// Add a "profile" handler, used by Profile to collect the profile of
+	// this weavelet on behalf of a peer.
+	hm.Set("", "profile", handleProfile)
+
+	// Add a "describe" handler, used by other applications to check that
+	// the components they reach through an ExternalRef are compatible.
+	hm.Set("", "describe", s.wlet.describe)
 	return hm, nil
 }
@@ -29,8 +29,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/ServiceWeaver/weaver/internal/net/call"
 	"github.com/ServiceWeaver/weaver/internal/private"
 	"github.com/ServiceWeaver/weaver/internal/reflection"
 	"github.com/ServiceWeaver/weaver/runtime/codegen"
@@ -67,6 +72,48 @@ var (
 	// retries, for example.
 	RemoteCallError = errors.New("Service Weaver remote call error")
 
+	// ErrUnavailable indicates that a remote component method call failed
+	// because no replica of the component was available, e.g., because the
+	// only replica of a singleton component failed and its replacement has not
+	// started yet. An error with an embedded ErrUnavailable also embeds
+	// RemoteCallError. Unlike other remote call errors though, ErrUnavailable
+	// guarantees that the method was not executed, so the call can be safely
+	// retried, ideally after a short backoff.
+	ErrUnavailable error = call.Unreachable
+
+	// ErrRateLimited indicates that a component method call was rejected
+	// because it exceeded the method's rate limit, set under rate_limits in
+	// the component's config. The method was not executed, so the call can be
+	// safely retried after a backoff.
+	ErrRateLimited = errors.New("Service Weaver rate limit exceeded")
+
+	// ErrPermissionDenied indicates that a component method call was
+	// rejected because the calling component is not listed among the
+	// method's allowed_callers in the callee's config. The method was not
+	// executed. Retrying the call won't help.
+	ErrPermissionDenied = errors.New("Service Weaver permission denied")
+
+	// ErrServerBusy indicates that a component method call was rejected
+	// because the replica that received it was already executing
+	// max_concurrent_calls calls, and its busy_policy or max_queued_calls
+	// kept the call from waiting. The method was not executed, so the call
+	// can be safely retried after a backoff.
+	ErrServerBusy = errors.New("Service Weaver component busy")
+
+	// ErrPanic indicates that a remote component method call failed because
+	// the method panicked, or because encoding its results did. The panic is
+	// contained to the call: the replica that executed it keeps serving other
+	// calls, unless crash_on_panic is set in the component's config. Unlike
+	// ErrServerBusy, the method may have been partially executed.
+	ErrPanic = errors.New("Service Weaver component method panicked")
+
+	// ErrIncompatibleVersion indicates that a call through an ExternalRef
+	// failed because the other application's version of the component has
+	// method signatures that differ from those of the caller's version. The
+	// error lists the mismatched methods. Retrying the call won't help; the
+	// two applications have to agree on the component's interface first.
+	ErrIncompatibleVersion = errors.New("Service Weaver component version mismatch")
+
 	// HealthzHandler is a health-check handler that returns an OK status for
 	// all incoming HTTP requests.
 	HealthzHandler = func(w http.ResponseWriter, _ *http.Request) {
@@ -100,6 +147,9 @@ type PointerToMain[T any] interface {
 	InstanceOf[Main]
 }
 
+// shutdownTimeout bounds the time Run spends shutting down components.
+const shutdownTimeout = 10 * time.Second
+
 // Run runs app as a Service Weaver application.
 //
 // The application is composed of a set of components that include
@@ -118,14 +168,19 @@ type PointerToMain[T any] interface {
 // call app and will return when app returns. If this process is
 // hosting other components, Run will start those components and never
 // return. Most callers of Run will not do anything (other than
-// possibly logging any returned error) after Run returns.
+// possibly logging any returned error) after Run returns. A process that
+// doesn't host weaver.Main returns from Run when it receives a SIGTERM, or when
+// Shutdown is called. Before
+// Run returns, it drains the remote calls executed by this process and calls
+// the Shutdown method of every component hosted by this process that
+// implements Finalizable.
 //
 //	func main() {
 //	    if err := weaver.Run(context.Background(), app); err != nil {
 //	        log.Fatal(err)
 //	    }
 //	}
-func Run[T any, _ PointerToMain[T]](ctx context.Context, app func(context.Context, *T) error) error {
+func Run[T any, _ PointerToMain[T]](ctx context.Context, app func(context.Context, *T) error) (err error) {
 	// Register HealthzHandler in the default ServerMux.
 	healthzInit.Do(func() {
 		http.HandleFunc(HealthzURL, HealthzHandler)
@@ -135,15 +190,37 @@ func Run[T any, _ PointerToMain[T]](ctx context.Context, app func(context.Contex
 	if err != nil {
 		return err
 	}
+	defer func() {
+		// Note that ctx may be done, so we use a fresh context.
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		// If app called weaver.Shutdown and returned its error, don't
+		// report it twice.
+		if shutdownErr := wlet.Shutdown(ctx); shutdownErr != nil && !errors.Is(err, shutdownErr) {
+			err = errors.Join(err, shutdownErr)
+		}
+	}()
 	if wlet.info.RunMain {
 		main, err := wlet.GetImpl("weaver.Run", reflection.Type[T]())
 		if err != nil {
 			return err
 		}
-		return app(ctx, main.(*T))
+		return app(withWeavelet(ctx, wlet), main.(*T))
+	}
+
+	// A deployer that replaces or removes this replica sends it a SIGTERM.
+	// Return, so that the deferred Shutdown drains in-flight remote calls.
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM)
+	defer signal.Stop(term)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-term:
+		return nil
+	case <-wlet.shuttingDown:
+		return nil
 	}
-	<-ctx.Done()
-	return ctx.Err()
 }
 
 func internalStart(ctx context.Context, opts private.AppOptions) (*weavelet, error) {
@@ -0,0 +1,112 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weavertest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ServiceWeaver/weaver/internal/config"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"golang.org/x/exp/slices"
+)
+
+// testConfig returns the contents of a config file that holds the settings in
+// base, overridden by the component configs in configs. It returns base
+// unchanged if configs is empty.
+func testConfig(base string, configs []ComponentConfig, fakes []FakeComponent) (string, error) {
+	if len(configs) == 0 {
+		return base, nil
+	}
+
+	sections := map[string]any{}
+	if _, err := toml.Decode(base, &sections); err != nil {
+		return "", fmt.Errorf("parse Runner.Config: %w", err)
+	}
+	for _, c := range configs {
+		reg, err := configuredComponent(c, fakes)
+		if err != nil {
+			return "", err
+		}
+		settings, err := configSettings(c.config)
+		if err != nil {
+			return "", fmt.Errorf("component %v: %w", c.intf, err)
+		}
+
+		// Settings in base that c.config sets too are overridden. Settings
+		// are matched to config fields by name case-insensitively, so they
+		// are overridden case-insensitively as well.
+		section, _ := sections[reg.Name].(map[string]any)
+		if section == nil {
+			section = map[string]any{}
+		}
+		for name := range section {
+			for override := range settings {
+				if strings.EqualFold(name, override) {
+					delete(section, name)
+				}
+			}
+		}
+		for name, value := range settings {
+			section[name] = value
+		}
+		sections[reg.Name] = section
+	}
+
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(sections); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// configuredComponent returns the registration of the component configured by
+// c, checking that c.config has the type of the component's config.
+func configuredComponent(c ComponentConfig, fakes []FakeComponent) (*codegen.Registration, error) {
+	regs := codegen.Registered()
+	i := slices.IndexFunc(regs, func(reg *codegen.Registration) bool { return reg.Iface == c.intf })
+	if i < 0 {
+		return nil, fmt.Errorf("component %v not found; maybe code generation wasn't run?", c.intf)
+	}
+	reg := regs[i]
+	if slices.ContainsFunc(fakes, func(f FakeComponent) bool { return f.intf == c.intf }) {
+		return nil, fmt.Errorf("component %v has both a fake and a config", c.intf)
+	}
+	want := config.Config(reflect.New(reg.Impl))
+	if want == nil {
+		return nil, fmt.Errorf("component %v has a config, but implementation %v doesn't embed weaver.WithConfig", c.intf, reg.Impl)
+	}
+	got := reflect.TypeOf(c.config)
+	if wantType := reflect.TypeOf(want); got != wantType && got != wantType.Elem() {
+		return nil, fmt.Errorf("component %v has a config of type %v, want %v", c.intf, got, wantType.Elem())
+	}
+	return reg, nil
+}
+
+// configSettings returns the settings of a config file section that sets the
+// fields of cfg.
+func configSettings(cfg any) (map[string]any, error) {
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(cfg); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	settings := map[string]any{}
+	if _, err := toml.Decode(b.String(), &settings); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	return settings, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weavertest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+const cacheSection = "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache"
+
+func TestTestConfig(t *testing.T) {
+	base := `
+["github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache"]
+size = 1
+eviction_policy = "lru"
+method_timeouts = {Config = "1m"}
+`
+	got, err := testConfig(base, []ComponentConfig{
+		ConfigFor[config.Cache](config.CacheConfig{Size: 10}),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sections map[string]map[string]any
+	if _, err := toml.Decode(got, &sections); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"Size":            int64(10),
+		"eviction_policy": "",
+		"TTL":             "0s",
+		"method_timeouts": map[string]any{"Config": "1m"},
+	}
+	if diff := cmp.Diff(want, sections[cacheSection]); diff != "" {
+		t.Fatalf("section (-want +got):\n%s", diff)
+	}
+}
+
+func TestTestConfigErrors(t *testing.T) {
+	type unregistered interface{}
+	for _, test := range []struct {
+		name    string
+		base    string
+		configs []ComponentConfig
+		fakes   []FakeComponent
+		want    string
+	}{
+		{
+			name:    "BadBase",
+			base:    "[",
+			configs: []ComponentConfig{ConfigFor[config.Cache](config.CacheConfig{})},
+			want:    "parse Runner.Config",
+		},
+		{
+			name:    "Unregistered",
+			configs: []ComponentConfig{ConfigFor[unregistered](config.CacheConfig{})},
+			want:    "not found",
+		},
+		{
+			name:    "NoConfig",
+			configs: []ComponentConfig{ConfigFor[config.Clock](config.CacheConfig{})},
+			want:    "doesn't embed weaver.WithConfig",
+		},
+		{
+			name:    "WrongType",
+			configs: []ComponentConfig{ConfigFor[config.Cache](struct{ Size int }{})},
+			want:    "want config.CacheConfig",
+		},
+		{
+			name:    "Fake",
+			configs: []ComponentConfig{ConfigFor[config.Cache](config.CacheConfig{})},
+			fakes:   []FakeComponent{Fake[config.Cache](fakeCache{})},
+			want:    "both a fake and a config",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testConfig(test.base, test.configs, test.fakes)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("testConfig: got %v, want error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+type fakeCache struct{ config.Cache }
@@ -57,6 +57,7 @@ type deployer struct {
 	wlet       *protos.EnvelopeInfo   // info for subprocesses
 	config     *protos.AppConfig      // application config
 	colocation map[string]string      // maps component to group
+	placement  *runtime.Placement     // placement hints
 	running    errgroup.Group         // collects errors from goroutines
 	local      map[string]bool        // Components that should run locally
 	log        func(*protos.LogEntry) // logs the passed in string
@@ -70,6 +71,7 @@ type deployer struct {
 type group struct {
 	name        string                  // group name
 	conns       []connection            // connections to the weavelet
+	leader      connection              // replica running one-replica tasks
 	components  map[string]bool         // started components
 	addresses   map[string]bool         // weavelet addresses
 	subscribers map[string][]connection // routing info subscribers, by component
@@ -105,7 +107,11 @@ var _ envelope.EnvelopeHandler = &handler{}
 // newDeployer returns a new weavertest multiprocess deployer. locals contains
 // components that should be co-located with the main component and not
 // replicated.
-func newDeployer(ctx context.Context, wlet *protos.EnvelopeInfo, config *protos.AppConfig, runner Runner, locals []reflect.Type, logWriter func(*protos.LogEntry)) *deployer {
+func newDeployer(ctx context.Context, wlet *protos.EnvelopeInfo, config *protos.AppConfig, runner Runner, locals []reflect.Type, logWriter func(*protos.LogEntry)) (*deployer, error) {
+	placement, err := runtime.ParsePlacement(config)
+	if err != nil {
+		return nil, err
+	}
 	colocation := map[string]string{}
 	for _, group := range config.Colocate {
 		for _, c := range group.Components {
@@ -120,6 +126,7 @@ func newDeployer(ctx context.Context, wlet *protos.EnvelopeInfo, config *protos.
 		wlet:       wlet,
 		config:     config,
 		colocation: colocation,
+		placement:  placement,
 		groups:     map[string]*group{},
 		local:      map[string]bool{},
 		log:        logWriter,
@@ -136,7 +143,7 @@ func newDeployer(ctx context.Context, wlet *protos.EnvelopeInfo, config *protos.
 		d.local[name] = true
 	}
 
-	return d
+	return d, nil
 }
 
 func (d *deployer) start() error {
@@ -198,6 +205,12 @@ func (d *deployer) start() error {
 		})
 		if err := d.registerReplica(g, e.WeaveletInfo()); err != nil {
 			d.stopLocked(fmt.Errorf(`cannot register the replica for "main": %w`, err))
+			return
+		}
+		if g.leader == (connection{}) {
+			if err := d.electLeader(g); err != nil {
+				d.stopLocked(fmt.Errorf(`cannot elect a leader for "main": %w`, err))
+			}
 		}
 	}()
 	return nil
@@ -343,7 +356,8 @@ func (h *handler) ActivateComponent(_ context.Context, req *protos.ActivateCompo
 }
 
 // startGroup starts the provided co-location group in a subprocess, if it
-// hasn't already been started.
+// hasn't already been started. A group that hosts a singleton component runs
+// a single replica; every other group runs DefaultReplication replicas.
 //
 // REQUIRES: d.mu is held.
 func (d *deployer) startGroup(g *group) error {
@@ -352,59 +366,151 @@ func (d *deployer) startGroup(g *group) error {
 		return nil
 	}
 
-	components := maps.Keys(g.components)
-	for r := 0; r < DefaultReplication; r++ {
-		// Start the weavelet.
-		wlet := &protos.EnvelopeInfo{
-			App:           d.wlet.App,
-			DeploymentId:  d.wlet.DeploymentId,
-			Id:            uuid.New().String(),
-			Sections:      d.wlet.Sections,
-			SingleProcess: d.wlet.SingleProcess,
-			SingleMachine: d.wlet.SingleMachine,
+	n := DefaultReplication
+	if d.isSingleton(g) {
+		n = 1
+	}
+	for r := 0; r < n; r++ {
+		if err := d.startReplica(g); err != nil {
+			return err
 		}
-		handler := &handler{
-			deployer:   d,
-			group:      g,
-			subscribed: map[string]bool{},
+	}
+	return nil
+}
+
+// startReplica starts a new replica of the provided co-location group in a
+// subprocess.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) startReplica(g *group) error {
+	// Start the weavelet.
+	wlet := &protos.EnvelopeInfo{
+		App:           d.wlet.App,
+		DeploymentId:  d.wlet.DeploymentId,
+		Id:            uuid.New().String(),
+		Sections:      d.wlet.Sections,
+		SingleProcess: d.wlet.SingleProcess,
+		SingleMachine: d.wlet.SingleMachine,
+	}
+	handler := &handler{
+		deployer:   d,
+		group:      g,
+		subscribed: map[string]bool{},
+	}
+	e, err := envelope.NewEnvelope(d.ctx, wlet, d.config)
+	if err != nil {
+		return err
+	}
+	c := connection{envelope: e}
+	handler.conn = c
+	d.running.Go(func() error {
+		err := e.Serve(handler)
+		if d.isSingleton(g) {
+			// Fail over to a new replica, unless the test is over.
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.ctx.Err() != nil {
+				return err
+			}
+			if err := d.replaceReplica(g, c); err != nil {
+				d.stopLocked(err)
+				return err
+			}
+			return nil
 		}
-		e, err := envelope.NewEnvelope(d.ctx, wlet, d.config)
-		if err != nil {
-			return err
+		d.stop(err)
+		return err
+	})
+	if err := d.registerReplica(g, e.WeaveletInfo()); err != nil {
+		return err
+	}
+	if err := e.UpdateComponents(maps.Keys(g.components)); err != nil {
+		return err
+	}
+	g.conns = append(g.conns, c)
+	if g.leader == (connection{}) {
+		return d.electLeader(g)
+	}
+	return nil
+}
+
+// electLeader makes the oldest replica of the provided co-location group its
+// leader.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) electLeader(g *group) error {
+	g.leader = connection{}
+	if len(g.conns) == 0 {
+		return nil
+	}
+	if err := g.conns[0].UpdateLeader(true); err != nil {
+		return err
+	}
+	g.leader = g.conns[0]
+	return nil
+}
+
+// replaceReplica replaces a failed replica of the provided co-location group
+// with a new one. Until the new replica is registered, the group has no
+// replicas, and calls to its components fail with weaver.ErrUnavailable.
+//
+// REQUIRES: d.mu is held.
+func (d *deployer) replaceReplica(g *group, failed connection) error {
+	// Forget the failed replica.
+	g.conns = without(g.conns, failed)
+	delete(g.addresses, failed.envelope.WeaveletInfo().DialAddr)
+	for _, other := range d.groups {
+		for component, subs := range other.subscribers {
+			other.subscribers[component] = without(subs, failed)
 		}
-		d.running.Go(func() error {
-			err := e.Serve(handler)
-			d.stop(err)
-			return err
-		})
-		if err := d.registerReplica(g, e.WeaveletInfo()); err != nil {
-			return err
+	}
+	for component := range g.components {
+		routing := g.routing(component)
+		for _, sub := range g.subscribers[component] {
+			if err := sub.UpdateRoutingInfo(routing); err != nil {
+				return err
+			}
 		}
-		if err := e.UpdateComponents(components); err != nil {
+	}
+
+	// Pick a new leader, if the failed replica was the leader.
+	if g.leader == failed {
+		if err := d.electLeader(g); err != nil {
 			return err
 		}
-		c := connection{envelope: e}
-		handler.conn = c
-		g.conns = append(g.conns, c)
 	}
-	return nil
+
+	// Start a new replica.
+	return d.startReplica(g)
+}
+
+// without returns conns without c.
+func without(conns []connection, c connection) []connection {
+	var result []connection
+	for _, other := range conns {
+		if other != c {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// isSingleton returns whether the provided co-location group hosts a
+// singleton component.
+func (d *deployer) isSingleton(g *group) bool {
+	for _, component := range d.placement.Singleton {
+		if d.groupName(component) == g.name {
+			return true
+		}
+	}
+	return false
 }
 
 // group returns the group that corresponds to the given component.
 //
 // REQUIRES: d.mu is held.
 func (d *deployer) group(component string) *group {
-	var name string
-	if !d.runner.multi {
-		name = "main" // Everything is in one group.
-	} else if d.local[component] {
-		name = "main" // Run locally
-	} else if x, ok := d.colocation[component]; ok {
-		name = x // Use specified group
-	} else {
-		name = component // A group of its own
-	}
-
+	name := d.groupName(component)
 	g, ok := d.groups[name]
 	if !ok {
 		g = &group{
@@ -418,6 +524,18 @@ func (d *deployer) group(component string) *group {
 	return g
 }
 
+// groupName returns the name of the group that hosts the given component.
+func (d *deployer) groupName(component string) string {
+	if !d.runner.multi {
+		return "main" // Everything is in one group.
+	} else if d.local[component] {
+		return "main" // Run locally
+	} else if x, ok := d.colocation[component]; ok {
+		return x // Use specified group
+	}
+	return component // A group of its own
+}
+
 // routing returns the RoutingInfo for the provided component.
 //
 // REQUIRES: d.mu is held.
@@ -449,3 +567,14 @@ func (c connection) UpdateComponents(components []string) error {
 	}
 	panic(fmt.Errorf("nil connection"))
 }
+
+// UpdateLeader is equivalent to Envelope.UpdateLeader.
+func (c connection) UpdateLeader(leader bool) error {
+	if c.envelope != nil {
+		return c.envelope.UpdateLeader(leader)
+	}
+	if c.conn != nil {
+		return c.conn.UpdateLeaderRPC(leader)
+	}
+	panic(fmt.Errorf("nil connection"))
+}
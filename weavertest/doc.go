@@ -55,4 +55,14 @@
 //	    // ...
 //	  })
 //	}
+//
+// Use [RunAll] to run a test with every runner:
+//
+//	func TestReverseAll(t *testing.T) {
+//	  weavertest.RunAll(t, func(t *testing.T, runner weavertest.Runner) {
+//	    runner.Test(t, func(t *testing.T, reverser Reverser) {
+//	      // ...
+//	    })
+//	  })
+//	}
 package weavertest
@@ -33,6 +33,7 @@ import (
 type Runner struct {
 	multi    bool // Use multiple processes
 	forceRPC bool // Use RPCs even for local calls
+	inline   bool // Run tests in the caller's test, not a sub-test
 
 	// Name is used as the name of the sub-test created by
 	// Runner.Test (or the sub-benchmark created by
@@ -51,6 +52,10 @@ type Runner struct {
 	// The typical use is to override some subset of the application
 	// code being tested with test-specific component implementations.
 	Fakes []FakeComponent
+
+	// Configs holds a list of component configs that should be used
+	// instead of the configs in Config, if any. See ConfigFor.
+	Configs []ComponentConfig
 }
 
 var (
@@ -72,6 +77,39 @@ var (
 // AllRunners returns a slice of all builtin weavertest runners.
 func AllRunners() []Runner { return []Runner{Local, RPC, Multi} }
 
+// RunAll runs body once for every runner returned by AllRunners, each time in
+// a sub-test of t named after the runner (e.g., "TestFoo/Multi"), so a failure
+// names the runner that hit it. body is passed the sub-test and the runner,
+// and typically calls runner.Test:
+//
+//	func TestFoo(t *testing.T) {
+//		weavertest.RunAll(t, func(t *testing.T, runner weavertest.Runner) {
+//			t.Parallel()
+//			runner.Test(t, func(t *testing.T, foo Foo) {
+//				// Test foo ...
+//			})
+//		})
+//	}
+//
+// The Test method of the runner passed to body runs directly in the sub-test
+// created for the runner, rather than in another sub-test.
+// body can call t.Parallel to run the runners in parallel, and can adjust
+// the runner before using it, e.g., to give every runner a config of its own
+// so that their listeners don't collide:
+//
+//	weavertest.RunAll(t, func(t *testing.T, runner weavertest.Runner) {
+//		runner.Config = configs[runner.Name]
+//		runner.Test(t, ...)
+//	})
+func RunAll(t *testing.T, body func(t *testing.T, runner Runner)) {
+	t.Helper()
+	for _, runner := range AllRunners() {
+		runner := runner
+		runner.inline = true
+		t.Run(runner.Name, func(t *testing.T) { body(t, runner) })
+	}
+}
+
 // FakeComponent records the implementation to use for a specific component type.
 type FakeComponent struct {
 	intf reflect.Type
@@ -89,6 +127,33 @@ func Fake[T any](impl any) FakeComponent {
 	return FakeComponent{intf: t, impl: impl}
 }
 
+// ComponentConfig records the config to use for a specific component type.
+type ComponentConfig struct {
+	intf   reflect.Type
+	config any
+}
+
+// ConfigFor arranges to use config as the config of the component type T,
+// i.e., as the value embedded by the weaver.WithConfig field of the
+// component implementation. The result is typically placed in
+// Runner.Configs. For example:
+//
+//	runner := weavertest.Local
+//	runner.Configs = []weavertest.ComponentConfig{
+//		weavertest.ConfigFor[Cache](cacheConfig{Size: 10}),
+//	}
+//	runner.Test(t, func(t *testing.T, cache Cache) {...})
+//
+// config is passed to the component the same way a config file would be, so
+// it is validated the same way too. The settings in config take precedence
+// over the settings for the same component in Runner.Config.
+//
+// REQUIRES: config must be a value of (or pointer to) the component's config
+// type.
+func ConfigFor[T any](config any) ComponentConfig {
+	return ComponentConfig{intf: reflection.Type[T](), config: config}
+}
+
 // Test runs a sub-test of t that tests the supplied Service Weaver
 // application code. It fails at runtime if body is not a function
 // whose signature looks like:
@@ -114,6 +179,10 @@ func Fake[T any](impl any) FakeComponent {
 // any registered weaver.Main component.
 func (r Runner) Test(t *testing.T, body any) {
 	t.Helper()
+	if r.inline {
+		r.sub(t, false, body)
+		return
+	}
 	t.Run(r.Name, func(t *testing.T) { r.sub(t, false, body) })
 }
 
@@ -187,6 +256,12 @@ func (r Runner) sub(t testing.TB, isBench bool, testBody any) {
 		}
 	}()
 
+	config, err := testConfig(r.Config, r.Configs, r.Fakes)
+	if err != nil {
+		t.Fatal(fmt.Errorf("weavertest.Runner.Configs: %w", err))
+	}
+	r.Config = config
+
 	if !r.multi && !r.forceRPC {
 		ctx = initSingleProcessLocal(ctx, r.Config)
 	} else {
@@ -298,7 +373,11 @@ func runWeaver(ctx context.Context, t testing.TB, runner Runner, body func(conte
 	if err != nil {
 		return err
 	}
-	return body(ctx, app)
+	if err := body(ctx, app); err != nil {
+		return err
+	}
+	// Note that components in other processes, if any, are not shut down.
+	return app.Shutdown(ctx)
 }
 
 // logStacks prints the stacks of live goroutines. This functionality
@@ -0,0 +1,69 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package balancedref contains components used to test weaver.BalancedRef.
+package balancedref
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that calls B through a weaver.BalancedRef.
+type A interface {
+	// Call calls B n times and returns the number of replicas picked by the
+	// balancer of A's reference to B so far.
+	Call(ctx context.Context, n int) (int64, error)
+}
+
+// B is a component with a trivial method.
+type B interface {
+	Ping(ctx context.Context) error
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.BalancedRef[B, countingBalancer]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+// picks is the number of replicas picked by countingBalancers in this process.
+var picks atomic.Int64
+
+// countingBalancer is a round-robin weaver.Balancer that counts its picks.
+type countingBalancer struct {
+	weaver.RoundRobin
+}
+
+// Pick implements the weaver.Balancer interface.
+func (c *countingBalancer) Pick(replicas []weaver.ReplicaInfo, call weaver.CallInfo) int {
+	picks.Add(1)
+	return c.RoundRobin.Pick(replicas, call)
+}
+
+func (a *a) Call(ctx context.Context, n int) (int64, error) {
+	for i := 0; i < n; i++ {
+		if err := a.b.Get().Ping(ctx); err != nil {
+			return 0, err
+		}
+	}
+	return picks.Load(), nil
+}
+
+func (b *b) Ping(context.Context) error { return nil }
@@ -0,0 +1,48 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancedref_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/balancedref"
+)
+
+func TestBalancedRef(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a balancedref.A) {
+			const n = 5
+			picks, err := a.Call(context.Background(), n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// B is local when running in a single process without RPCs, in
+			// which case calls aren't balanced. Otherwise, every call to B is
+			// balanced by the BalancedRef's balancer.
+			want := int64(n)
+			if runner.Name == weavertest.Local.Name {
+				want = 0
+			}
+			if picks < want {
+				t.Errorf("picks: got %d, want at least %d", picks, want)
+			}
+			if want == 0 && picks != 0 {
+				t.Errorf("picks: got %d, want 0", picks)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package broadcast contains components used to test weaver.Broadcast.
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Cache is a component whose replicas each hold their own key-value cache.
+type Cache interface {
+	Put(ctx context.Context, key, value string) error
+	Get(ctx context.Context, key string) (string, error)
+	Invalidate(ctx context.Context, key string) error
+}
+
+type cache struct {
+	weaver.Implements[Cache]
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (c *cache) Init(context.Context) error {
+	c.values = map[string]string{}
+	return nil
+}
+
+func (c *cache) Put(_ context.Context, key, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func (c *cache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key], nil
+}
+
+func (c *cache) Invalidate(_ context.Context, key string) error {
+	if key == "" {
+		return errors.New("empty key")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+// Store is a component that keeps every replica of Cache up to date.
+type Store interface {
+	// Put stores the provided value in every replica of Cache. It returns the
+	// number of replicas it reached.
+	Put(ctx context.Context, key, value string) (int, error)
+
+	// Get returns the value cached by some replica of Cache.
+	Get(ctx context.Context, key string) (string, error)
+
+	// Invalidate removes the provided key from every replica of Cache. It
+	// returns the number of replicas it reached.
+	Invalidate(ctx context.Context, key string) (int, error)
+}
+
+type store struct {
+	weaver.Implements[Store]
+	cache weaver.Ref[Cache]
+}
+
+func (s *store) Put(ctx context.Context, key, value string) (int, error) {
+	var n atomic.Int32
+	err := weaver.Broadcast(ctx, s.cache, func(c Cache) error {
+		n.Add(1)
+		return c.Put(ctx, key, value)
+	})
+	return int(n.Load()), err
+}
+
+func (s *store) Get(ctx context.Context, key string) (string, error) {
+	return s.cache.Get().Get(ctx, key)
+}
+
+func (s *store) Invalidate(ctx context.Context, key string) (int, error) {
+	var n atomic.Int32
+	err := weaver.Broadcast(ctx, s.cache, func(c Cache) error {
+		n.Add(1)
+		return c.Invalidate(ctx, key)
+	})
+	return int(n.Load()), err
+}
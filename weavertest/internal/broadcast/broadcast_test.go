@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadcast_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/broadcast"
+)
+
+func TestBroadcast(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		// Only the multiprocess runner runs more than one replica of Cache.
+		want := 1
+		if runner.Name == weavertest.Multi.Name {
+			want = weavertest.DefaultReplication
+		}
+		runner.Test(t, func(t *testing.T, store broadcast.Store) {
+			ctx := context.Background()
+			get := func(want string) {
+				t.Helper()
+				for i := 0; i < 10; i++ {
+					got, err := store.Get(ctx, "key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got != want {
+						t.Fatalf("Get: got %q, want %q", got, want)
+					}
+				}
+			}
+
+			n, err := store.Put(ctx, "key", "value")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != want {
+				t.Fatalf("Put: reached %d replicas, want %d", n, want)
+			}
+			get("value")
+
+			n, err = store.Invalidate(ctx, "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != want {
+				t.Fatalf("Invalidate: reached %d replicas, want %d", n, want)
+			}
+			get("")
+
+			// The error of every replica is reported.
+			_, err = store.Invalidate(ctx, "")
+			if err == nil || strings.Count(err.Error(), "empty key") != want {
+				t.Fatalf("Invalidate: got %v, want %d empty key errors", err, want)
+			}
+		})
+	}
+}
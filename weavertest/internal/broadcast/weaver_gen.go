@@ -0,0 +1,937 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache",
+		Iface: reflect.TypeOf((*Cache)(nil)).Elem(),
+		Impl:  reflect.TypeOf(cache{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return cache_intercept(cache_local_stub{impl: impl.(Cache), caller: caller, tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Method: "Get", Remote: false}), invalidateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Method: "Invalidate", Remote: false}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Method: "Put", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return cache_intercept(cache_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Method: "Get", Remote: true}), invalidateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Method: "Invalidate", Remote: true}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Method: "Put", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return cache_server_stub{impl: cache_intercept(impl.(Cache), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return cache_intercept(next.(Cache), interceptor, call)
+		},
+		RefData: "",
+	})
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store",
+		Iface: reflect.TypeOf((*Store)(nil)).Elem(),
+		Impl:  reflect.TypeOf(store{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return store_intercept(store_local_stub{impl: impl.(Store), caller: caller, tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Method: "Get", Remote: false}), invalidateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Method: "Invalidate", Remote: false}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Method: "Put", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return store_intercept(store_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Method: "Get", Remote: true}), invalidateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Method: "Invalidate", Remote: true}), putMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Method: "Put", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return store_server_stub{impl: store_intercept(impl.(Store), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return store_intercept(next.(Store), interceptor, call)
+		},
+		RefData: "⟦a3a3a86b:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store→github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache⟧\n",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Cache] = (*cache)(nil)
+var _ weaver.InstanceOf[Store] = (*store)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*cache)(nil)
+var _ weaver.Unrouted = (*store)(nil)
+
+// Local stub implementations.
+
+type cache_local_stub struct {
+	impl              Cache
+	caller            string
+	tracer            trace.Tracer
+	getMetrics        *codegen.MethodMetrics
+	invalidateMetrics *codegen.MethodMetrics
+	putMetrics        *codegen.MethodMetrics
+}
+
+// Check that cache_local_stub implements the Cache interface.
+var _ Cache = (*cache_local_stub)(nil)
+
+func (s cache_local_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	begin := s.getMetrics.Begin()
+	defer func() { s.getMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "broadcast.Cache.Get", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache")
+	return s.impl.Get(ctx, a0)
+}
+
+func (s cache_local_stub) Invalidate(ctx context.Context, a0 string) (err error) {
+	// Update metrics.
+	begin := s.invalidateMetrics.Begin()
+	defer func() { s.invalidateMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "broadcast.Cache.Invalidate", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache")
+	return s.impl.Invalidate(ctx, a0)
+}
+
+func (s cache_local_stub) Put(ctx context.Context, a0 string, a1 string) (err error) {
+	// Update metrics.
+	begin := s.putMetrics.Begin()
+	defer func() { s.putMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "broadcast.Cache.Put", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache")
+	return s.impl.Put(ctx, a0, a1)
+}
+
+type store_local_stub struct {
+	impl              Store
+	caller            string
+	tracer            trace.Tracer
+	getMetrics        *codegen.MethodMetrics
+	invalidateMetrics *codegen.MethodMetrics
+	putMetrics        *codegen.MethodMetrics
+}
+
+// Check that store_local_stub implements the Store interface.
+var _ Store = (*store_local_stub)(nil)
+
+func (s store_local_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	begin := s.getMetrics.Begin()
+	defer func() { s.getMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "broadcast.Store.Get", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store")
+	return s.impl.Get(ctx, a0)
+}
+
+func (s store_local_stub) Invalidate(ctx context.Context, a0 string) (r0 int, err error) {
+	// Update metrics.
+	begin := s.invalidateMetrics.Begin()
+	defer func() { s.invalidateMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "broadcast.Store.Invalidate", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store")
+	return s.impl.Invalidate(ctx, a0)
+}
+
+func (s store_local_stub) Put(ctx context.Context, a0 string, a1 string) (r0 int, err error) {
+	// Update metrics.
+	begin := s.putMetrics.Begin()
+	defer func() { s.putMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "broadcast.Store.Put", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store")
+	return s.impl.Put(ctx, a0, a1)
+}
+
+// Client stub implementations.
+
+type cache_client_stub struct {
+	stub              codegen.Stub
+	getMetrics        *codegen.MethodMetrics
+	invalidateMetrics *codegen.MethodMetrics
+	putMetrics        *codegen.MethodMetrics
+}
+
+// Check that cache_client_stub implements the Cache interface.
+var _ Cache = (*cache_client_stub)(nil)
+
+func (s cache_client_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.getMetrics.Begin()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "broadcast.Cache.Get", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+func (s cache_client_stub) Invalidate(ctx context.Context, a0 string) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.invalidateMetrics.Begin()
+	defer func() {
+		s.invalidateMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "broadcast.Cache.Invalidate", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s cache_client_stub) Put(ctx context.Context, a0 string, a1 string) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.putMetrics.Begin()
+	defer func() {
+		s.putMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "broadcast.Cache.Put", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	size += (4 + len(a1))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	enc.String(a1)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+type store_client_stub struct {
+	stub              codegen.Stub
+	getMetrics        *codegen.MethodMetrics
+	invalidateMetrics *codegen.MethodMetrics
+	putMetrics        *codegen.MethodMetrics
+}
+
+// Check that store_client_stub implements the Store interface.
+var _ Store = (*store_client_stub)(nil)
+
+func (s store_client_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.getMetrics.Begin()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "broadcast.Store.Get", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+func (s store_client_stub) Invalidate(ctx context.Context, a0 string) (r0 int, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.invalidateMetrics.Begin()
+	defer func() {
+		s.invalidateMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "broadcast.Store.Invalidate", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.Int()
+	err = dec.Error()
+	return
+}
+
+func (s store_client_stub) Put(ctx context.Context, a0 string, a1 string) (r0 int, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.putMetrics.Begin()
+	defer func() {
+		s.putMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "broadcast.Store.Put", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	size += (4 + len(a1))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	enc.String(a1)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.Int()
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type cache_server_stub struct {
+	impl       Cache
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that cache_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*cache_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s cache_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Get":
+		return s.get
+	case "Invalidate":
+		return s.invalidate
+	case "Put":
+		return s.put
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s cache_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s cache_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s cache_server_stub) invalidate(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", "Invalidate", func(ctx context.Context) error {
+		return s.impl.Invalidate(ctx, a0)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s cache_server_stub) put(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+	var a1 string
+	a1 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Cache", "Put", func(ctx context.Context) error {
+		return s.impl.Put(ctx, a0, a1)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type store_server_stub struct {
+	impl       Store
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that store_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*store_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s store_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Get":
+		return s.get
+	case "Invalidate":
+		return s.invalidate
+	case "Put":
+		return s.put
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s store_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s store_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s store_server_stub) invalidate(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", "Invalidate", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Invalidate(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Int(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s store_server_stub) put(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+	var a1 string
+	a1 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/broadcast/Store", "Put", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Put(ctx, a0, a1)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Int(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type cache_intercept_stub struct {
+	next        Cache
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that cache_intercept_stub implements the Cache interface.
+var _ Cache = (*cache_intercept_stub)(nil)
+
+// cache_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func cache_intercept(next Cache, interceptor codegen.Interceptor, call codegen.Call) Cache {
+	if interceptor == nil {
+		return next
+	}
+	return cache_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s cache_intercept_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s cache_intercept_stub) Invalidate(ctx context.Context, a0 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Invalidate", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Invalidate(ctx, codegen.Arg[string](args, 0))
+	})
+	return err
+}
+
+func (s cache_intercept_stub) Put(ctx context.Context, a0 string, a1 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Put", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Put(ctx, codegen.Arg[string](args, 0), codegen.Arg[string](args, 1))
+	})
+	return err
+}
+
+type store_intercept_stub struct {
+	next        Store
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that store_intercept_stub implements the Store interface.
+var _ Store = (*store_intercept_stub)(nil)
+
+// store_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func store_intercept(next Store, interceptor codegen.Interceptor, call codegen.Call) Store {
+	if interceptor == nil {
+		return next
+	}
+	return store_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s store_intercept_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s store_intercept_stub) Invalidate(ctx context.Context, a0 string) (r0 int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Invalidate", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Invalidate(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[int](results, 0), err
+}
+
+func (s store_intercept_stub) Put(ctx context.Context, a0 string, a1 string) (r0 int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Put", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Put(ctx, codegen.Arg[string](args, 0), codegen.Arg[string](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[int](results, 0), err
+}
@@ -18,6 +18,7 @@ package chain
 
 import (
 	"context"
+	"strconv"
 	"sync"
 
 	"github.com/ServiceWeaver/weaver"
@@ -25,6 +26,19 @@ import (
 
 //go:generate ../../../cmd/weaver/weaver generate
 
+// budgetKey is a context key of a budget that B decrements.
+var budgetKey = weaver.RegisterPropagator[int]("chain-budget", budgetPropagator{})
+
+type budgetPropagator struct{}
+
+func (budgetPropagator) Marshal(budget int) ([]byte, error) {
+	return []byte(strconv.Itoa(budget)), nil
+}
+
+func (budgetPropagator) Unmarshal(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
 type A interface {
 	Propagate(context.Context, int) error
 }
@@ -55,6 +69,10 @@ type c struct {
 	weaver.Implements[C]
 	mu  sync.Mutex
 	val int
+	md  map[string]string // metadata received by Propagate
+
+	budget    int  // budget received by Propagate
+	hasBudget bool // whether Propagate received a budget
 }
 
 func (a *a) Propagate(ctx context.Context, val int) error {
@@ -68,12 +86,20 @@ func (b *b) Propagate(ctx context.Context, val int) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.val = val
+	if _, ok := weaver.Metadata(ctx)["hop"]; ok {
+		ctx = weaver.SetMetadata(ctx, "hop", "b")
+	}
+	if budget, ok := budgetKey.Value(ctx); ok {
+		ctx = budgetKey.With(ctx, budget-1)
+	}
 	return b.c.Get().Propagate(ctx, val+1)
 }
 
-func (c *c) Propagate(_ context.Context, val int) error {
+func (c *c) Propagate(ctx context.Context, val int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.val = val
+	c.md = weaver.Metadata(ctx)
+	c.budget, c.hasBudget = budgetKey.Value(ctx)
 	return nil
 }
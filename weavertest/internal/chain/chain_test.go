@@ -18,7 +18,9 @@ import (
 	"context"
 	"testing"
 
+	"github.com/ServiceWeaver/weaver"
 	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestOneComponentImpl(t *testing.T) {
@@ -35,6 +37,45 @@ func TestOneComponentImpl(t *testing.T) {
 	}
 }
 
+func TestMetadataPropagation(t *testing.T) {
+	// Tests that metadata set by the caller of A reaches C, and that B can
+	// override it along the way.
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a A, c *c) {
+			ctx := weaver.SetMetadata(context.Background(), "tenant", "acme")
+			ctx = weaver.SetMetadata(ctx, "hop", "test")
+			if err := a.Propagate(ctx, 1); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{"tenant": "acme", "hop": "b"}
+			if diff := cmp.Diff(want, c.md); diff != "" {
+				t.Fatalf("metadata (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContextValuePropagation(t *testing.T) {
+	// Tests that a typed context value set by the caller of A reaches C, and
+	// that B can update it along the way.
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a A, c *c) {
+			ctx := weaver.SetMetadata(context.Background(), "tenant", "acme")
+			ctx = budgetKey.With(ctx, 10)
+			if err := a.Propagate(ctx, 1); err != nil {
+				t.Fatal(err)
+			}
+			if !c.hasBudget || c.budget != 9 {
+				t.Fatalf("budget: got (%d, %t), want (9, true)", c.budget, c.hasBudget)
+			}
+			want := map[string]string{"tenant": "acme"}
+			if diff := cmp.Diff(want, c.md); diff != "" {
+				t.Fatalf("metadata (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func BenchOneComponentImpl(b *testing.B) {
 	// Tests weaver.Bench with a component implementation pointer argument.
 	for _, runner := range weavertest.AllRunners() {
@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -39,13 +39,16 @@ func init() {
 		Iface: reflect.TypeOf((*A)(nil)).Elem(),
 		Impl:  reflect.TypeOf(a{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return a_local_stub{impl: impl.(A), tracer: tracer, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", Method: "Propagate", Remote: false})}
+			return a_intercept(a_local_stub{impl: impl.(A), caller: caller, tracer: tracer, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", Method: "Propagate", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return a_client_stub{stub: stub, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", Method: "Propagate", Remote: true})}
+			return a_intercept(a_client_stub{stub: stub, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", Method: "Propagate", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return a_server_stub{impl: impl.(A), addLoad: addLoad}
+			return a_server_stub{impl: a_intercept(impl.(A), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return a_intercept(next.(A), interceptor, call)
 		},
 		RefData: "⟦d3d93f6e:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/chain/A→github.com/ServiceWeaver/weaver/weavertest/internal/chain/B⟧\n",
 	})
@@ -54,13 +57,16 @@ func init() {
 		Iface: reflect.TypeOf((*B)(nil)).Elem(),
 		Impl:  reflect.TypeOf(b{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return b_local_stub{impl: impl.(B), tracer: tracer, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", Method: "Propagate", Remote: false})}
+			return b_intercept(b_local_stub{impl: impl.(B), caller: caller, tracer: tracer, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", Method: "Propagate", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return b_client_stub{stub: stub, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", Method: "Propagate", Remote: true})}
+			return b_intercept(b_client_stub{stub: stub, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", Method: "Propagate", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return b_server_stub{impl: impl.(B), addLoad: addLoad}
+			return b_server_stub{impl: b_intercept(impl.(B), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return b_intercept(next.(B), interceptor, call)
 		},
 		RefData: "⟦08d612ad:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/chain/B→github.com/ServiceWeaver/weaver/weavertest/internal/chain/C⟧\n",
 	})
@@ -69,13 +75,16 @@ func init() {
 		Iface: reflect.TypeOf((*C)(nil)).Elem(),
 		Impl:  reflect.TypeOf(c{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return c_local_stub{impl: impl.(C), tracer: tracer, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", Method: "Propagate", Remote: false})}
+			return c_intercept(c_local_stub{impl: impl.(C), caller: caller, tracer: tracer, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", Method: "Propagate", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return c_client_stub{stub: stub, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", Method: "Propagate", Remote: true})}
+			return c_intercept(c_client_stub{stub: stub, propagateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", Method: "Propagate", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return c_server_stub{impl: impl.(C), addLoad: addLoad}
+			return c_server_stub{impl: c_intercept(impl.(C), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return c_intercept(next.(C), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -95,6 +104,7 @@ var _ weaver.Unrouted = (*c)(nil)
 
 type a_local_stub struct {
 	impl             A
+	caller           string
 	tracer           trace.Tracer
 	propagateMetrics *codegen.MethodMetrics
 }
@@ -119,11 +129,13 @@ func (s a_local_stub) Propagate(ctx context.Context, a0 int) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A")
 	return s.impl.Propagate(ctx, a0)
 }
 
 type b_local_stub struct {
 	impl             B
+	caller           string
 	tracer           trace.Tracer
 	propagateMetrics *codegen.MethodMetrics
 }
@@ -148,11 +160,13 @@ func (s b_local_stub) Propagate(ctx context.Context, a0 int) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B")
 	return s.impl.Propagate(ctx, a0)
 }
 
 type c_local_stub struct {
 	impl             C
+	caller           string
 	tracer           trace.Tracer
 	propagateMetrics *codegen.MethodMetrics
 }
@@ -177,6 +191,7 @@ func (s c_local_stub) Propagate(ctx context.Context, a0 int) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C")
 	return s.impl.Propagate(ctx, a0)
 }
 
@@ -193,8 +208,11 @@ var _ A = (*a_client_stub)(nil)
 func (s a_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.propagateMetrics.Begin()
-	defer func() { s.propagateMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.propagateMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -207,6 +225,7 @@ func (s a_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -230,11 +249,12 @@ func (s a_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -256,8 +276,11 @@ var _ B = (*b_client_stub)(nil)
 func (s b_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.propagateMetrics.Begin()
-	defer func() { s.propagateMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.propagateMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -270,6 +293,7 @@ func (s b_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -293,11 +317,12 @@ func (s b_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -319,8 +344,11 @@ var _ C = (*c_client_stub)(nil)
 func (s c_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.propagateMetrics.Begin()
-	defer func() { s.propagateMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.propagateMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -333,6 +361,7 @@ func (s c_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -356,11 +385,12 @@ func (s c_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -374,8 +404,9 @@ func (s c_client_stub) Propagate(ctx context.Context, a0 int) (err error) {
 // Server stub implementations.
 
 type a_server_stub struct {
-	impl    A
-	addLoad func(key uint64, load float64)
+	impl       A
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that a_server_stub implements the codegen.Server interface.
@@ -391,6 +422,11 @@ func (s a_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s a_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s a_server_stub) propagate(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -404,10 +440,12 @@ func (s a_server_stub) propagate(ctx context.Context, args []byte) (res []byte,
 	var a0 int
 	a0 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Propagate(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/chain/A", "Propagate", func(ctx context.Context) error {
+		return s.impl.Propagate(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -416,8 +454,9 @@ func (s a_server_stub) propagate(ctx context.Context, args []byte) (res []byte,
 }
 
 type b_server_stub struct {
-	impl    B
-	addLoad func(key uint64, load float64)
+	impl       B
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that b_server_stub implements the codegen.Server interface.
@@ -433,6 +472,11 @@ func (s b_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s b_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s b_server_stub) propagate(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -446,10 +490,12 @@ func (s b_server_stub) propagate(ctx context.Context, args []byte) (res []byte,
 	var a0 int
 	a0 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Propagate(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/chain/B", "Propagate", func(ctx context.Context) error {
+		return s.impl.Propagate(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -458,8 +504,9 @@ func (s b_server_stub) propagate(ctx context.Context, args []byte) (res []byte,
 }
 
 type c_server_stub struct {
-	impl    C
-	addLoad func(key uint64, load float64)
+	impl       C
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that c_server_stub implements the codegen.Server interface.
@@ -475,6 +522,11 @@ func (s c_server_stub) GetStubFn(method string) func(ctx context.Context, args [
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s c_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s c_server_stub) propagate(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -488,13 +540,92 @@ func (s c_server_stub) propagate(ctx context.Context, args []byte) (res []byte,
 	var a0 int
 	a0 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Propagate(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/chain/C", "Propagate", func(ctx context.Context) error {
+		return s.impl.Propagate(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
 	enc.Error(appErr)
 	return enc.Data(), nil
 }
+
+// Intercept stub implementations.
+
+type a_intercept_stub struct {
+	next        A
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that a_intercept_stub implements the A interface.
+var _ A = (*a_intercept_stub)(nil)
+
+// a_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func a_intercept(next A, interceptor codegen.Interceptor, call codegen.Call) A {
+	if interceptor == nil {
+		return next
+	}
+	return a_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s a_intercept_stub) Propagate(ctx context.Context, a0 int) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Propagate", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Propagate(ctx, codegen.Arg[int](args, 0))
+	})
+	return err
+}
+
+type b_intercept_stub struct {
+	next        B
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that b_intercept_stub implements the B interface.
+var _ B = (*b_intercept_stub)(nil)
+
+// b_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func b_intercept(next B, interceptor codegen.Interceptor, call codegen.Call) B {
+	if interceptor == nil {
+		return next
+	}
+	return b_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s b_intercept_stub) Propagate(ctx context.Context, a0 int) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Propagate", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Propagate(ctx, codegen.Arg[int](args, 0))
+	})
+	return err
+}
+
+type c_intercept_stub struct {
+	next        C
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that c_intercept_stub implements the C interface.
+var _ C = (*c_intercept_stub)(nil)
+
+// c_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func c_intercept(next C, interceptor codegen.Interceptor, call codegen.Call) C {
+	if interceptor == nil {
+		return next
+	}
+	return c_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s c_intercept_stub) Propagate(ctx context.Context, a0 int) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Propagate", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Propagate(ctx, codegen.Arg[int](args, 0))
+	})
+	return err
+}
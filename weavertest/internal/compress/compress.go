@@ -0,0 +1,36 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compress contains components used to test the compression of
+// remote method calls.
+package compress
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Echo is a component that echoes its argument.
+type Echo interface {
+	Echo(ctx context.Context, s string) (string, error)
+}
+
+type echo struct {
+	weaver.Implements[Echo]
+}
+
+func (e *echo) Echo(_ context.Context, s string) (string, error) {
+	return s, nil
+}
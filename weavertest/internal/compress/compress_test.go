@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/compress"
+)
+
+const echoName = "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo"
+
+// wireBytes returns the sum of the sizes of the requests to Echo.Echo sent
+// over the network, and the number of requests.
+func wireBytes() (sum, count float64) {
+	for _, m := range metrics.Snapshot() {
+		if m.Name == "serviceweaver_method_wire_bytes_request" && m.Labels["component"] == echoName {
+			sum += m.Value
+			for _, c := range m.Counts {
+				count += float64(c)
+			}
+		}
+	}
+	return sum, count
+}
+
+func TestCompression(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = `["` + echoName + `"]` + "\ncompress_min_bytes = 1024\n"
+		runner.Test(t, func(t *testing.T, e compress.Echo) {
+			ctx := context.Background()
+			for _, size := range []int{10, 1 << 20} {
+				sumBefore, countBefore := wireBytes()
+				s := strings.Repeat("x", size)
+				got, err := e.Echo(ctx, s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != s {
+					t.Fatalf("Echo: got %d bytes, want %d", len(got), len(s))
+				}
+
+				sum, count := wireBytes()
+				if runner.Name == weavertest.Local.Name {
+					// Local calls are not sent over the network.
+					if count != countBefore {
+						t.Fatalf("local call recorded wire bytes")
+					}
+					continue
+				}
+				if count != countBefore+1 {
+					t.Fatalf("got %v requests, want %v", count-countBefore, 1)
+				}
+				sent := sum - sumBefore
+				if size >= 1024 && sent >= float64(size)/10 {
+					t.Errorf("%d byte request: %v bytes sent, want compressed", size, sent)
+				}
+				if size < 1024 && sent < float64(size) {
+					t.Errorf("%d byte request: %v bytes sent, want uncompressed", size, sent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package compress
+
+import (
+	"context"
+	"errors"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo",
+		Iface: reflect.TypeOf((*Echo)(nil)).Elem(),
+		Impl:  reflect.TypeOf(echo{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return echo_intercept(echo_local_stub{impl: impl.(Echo), caller: caller, tracer: tracer, echoMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo", Method: "Echo", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return echo_intercept(echo_client_stub{stub: stub, echoMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo", Method: "Echo", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return echo_server_stub{impl: echo_intercept(impl.(Echo), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return echo_intercept(next.(Echo), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Echo] = (*echo)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*echo)(nil)
+
+// Local stub implementations.
+
+type echo_local_stub struct {
+	impl        Echo
+	caller      string
+	tracer      trace.Tracer
+	echoMetrics *codegen.MethodMetrics
+}
+
+// Check that echo_local_stub implements the Echo interface.
+var _ Echo = (*echo_local_stub)(nil)
+
+func (s echo_local_stub) Echo(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	begin := s.echoMetrics.Begin()
+	defer func() { s.echoMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "compress.Echo.Echo", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo")
+	return s.impl.Echo(ctx, a0)
+}
+
+// Client stub implementations.
+
+type echo_client_stub struct {
+	stub        codegen.Stub
+	echoMetrics *codegen.MethodMetrics
+}
+
+// Check that echo_client_stub implements the Echo interface.
+var _ Echo = (*echo_client_stub)(nil)
+
+func (s echo_client_stub) Echo(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.echoMetrics.Begin()
+	defer func() {
+		s.echoMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "compress.Echo.Echo", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type echo_server_stub struct {
+	impl       Echo
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that echo_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*echo_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s echo_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Echo":
+		return s.echo
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s echo_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s echo_server_stub) echo(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/compress/Echo", "Echo", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Echo(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type echo_intercept_stub struct {
+	next        Echo
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that echo_intercept_stub implements the Echo interface.
+var _ Echo = (*echo_intercept_stub)(nil)
+
+// echo_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func echo_intercept(next Echo, interceptor codegen.Interceptor, call codegen.Call) Echo {
+	if interceptor == nil {
+		return next
+	}
+	return echo_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s echo_intercept_stub) Echo(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Echo", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Echo(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
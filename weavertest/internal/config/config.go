@@ -0,0 +1,70 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config contains components used to test per-test component
+// configs.
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Cache is a component with a config.
+type Cache interface {
+	// Config returns the config of the cache.
+	Config(context.Context) (CacheConfig, error)
+}
+
+// Clock is a component without a config.
+type Clock interface {
+	Now(context.Context) (time.Time, error)
+}
+
+// CacheConfig is the config of the Cache component.
+type CacheConfig struct {
+	weaver.AutoMarshal
+	Size     int
+	Policy   string `toml:"eviction_policy"`
+	TTL      time.Duration
+	Prefixes []string
+}
+
+// Validate implements the interface consulted when parsing configs.
+func (c *CacheConfig) Validate() error {
+	if c.Size < 0 {
+		return fmt.Errorf("negative size %d", c.Size)
+	}
+	return nil
+}
+
+type cache struct {
+	weaver.Implements[Cache]
+	weaver.WithConfig[CacheConfig]
+}
+
+func (c *cache) Config(context.Context) (CacheConfig, error) {
+	return *c.WithConfig.Config(), nil
+}
+
+type clock struct {
+	weaver.Implements[Clock]
+}
+
+func (*clock) Now(context.Context) (time.Time, error) {
+	return time.Now(), nil
+}
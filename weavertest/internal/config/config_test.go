@@ -0,0 +1,83 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+const tomlConfig = `
+["github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache"]
+size = 1
+eviction_policy = "lru"
+method_timeouts = {Config = "1m"}
+`
+
+func TestConfig(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		config  string
+		configs []weavertest.ComponentConfig
+		want    config.CacheConfig
+	}{
+		{
+			name:   "TOML",
+			config: tomlConfig,
+			want:   config.CacheConfig{Size: 1, Policy: "lru"},
+		},
+		{
+			name: "Typed",
+			configs: []weavertest.ComponentConfig{
+				weavertest.ConfigFor[config.Cache](config.CacheConfig{
+					Size:     10,
+					TTL:      time.Minute,
+					Prefixes: []string{"a", "b"},
+				}),
+			},
+			want: config.CacheConfig{Size: 10, TTL: time.Minute, Prefixes: []string{"a", "b"}},
+		},
+		{
+			// The typed config takes precedence.
+			name:   "Both",
+			config: tomlConfig,
+			configs: []weavertest.ComponentConfig{
+				weavertest.ConfigFor[config.Cache](&config.CacheConfig{Size: 10, Policy: "fifo"}),
+			},
+			want: config.CacheConfig{Size: 10, Policy: "fifo"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, runner := range weavertest.AllRunners() {
+				runner.Config = test.config
+				runner.Configs = test.configs
+				runner.Test(t, func(t *testing.T, cache config.Cache) {
+					got, err := cache.Config(context.Background())
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(test.want, got); diff != "" {
+						t.Fatalf("config (-want +got):\n%s", diff)
+					}
+				})
+			}
+		})
+	}
+}
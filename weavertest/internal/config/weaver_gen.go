@@ -0,0 +1,478 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+	"time"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache",
+		Iface: reflect.TypeOf((*Cache)(nil)).Elem(),
+		Impl:  reflect.TypeOf(cache{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return cache_intercept(cache_local_stub{impl: impl.(Cache), caller: caller, tracer: tracer, configMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache", Method: "Config", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return cache_intercept(cache_client_stub{stub: stub, configMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache", Method: "Config", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return cache_server_stub{impl: cache_intercept(impl.(Cache), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return cache_intercept(next.(Cache), interceptor, call)
+		},
+		RefData: "",
+	})
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock",
+		Iface: reflect.TypeOf((*Clock)(nil)).Elem(),
+		Impl:  reflect.TypeOf(clock{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return clock_intercept(clock_local_stub{impl: impl.(Clock), caller: caller, tracer: tracer, nowMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock", Method: "Now", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return clock_intercept(clock_client_stub{stub: stub, nowMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock", Method: "Now", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return clock_server_stub{impl: clock_intercept(impl.(Clock), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return clock_intercept(next.(Clock), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Cache] = (*cache)(nil)
+var _ weaver.InstanceOf[Clock] = (*clock)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*cache)(nil)
+var _ weaver.Unrouted = (*clock)(nil)
+
+// Local stub implementations.
+
+type cache_local_stub struct {
+	impl          Cache
+	caller        string
+	tracer        trace.Tracer
+	configMetrics *codegen.MethodMetrics
+}
+
+// Check that cache_local_stub implements the Cache interface.
+var _ Cache = (*cache_local_stub)(nil)
+
+func (s cache_local_stub) Config(ctx context.Context) (r0 CacheConfig, err error) {
+	// Update metrics.
+	begin := s.configMetrics.Begin()
+	defer func() { s.configMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "config.Cache.Config", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache")
+	return s.impl.Config(ctx)
+}
+
+type clock_local_stub struct {
+	impl       Clock
+	caller     string
+	tracer     trace.Tracer
+	nowMetrics *codegen.MethodMetrics
+}
+
+// Check that clock_local_stub implements the Clock interface.
+var _ Clock = (*clock_local_stub)(nil)
+
+func (s clock_local_stub) Now(ctx context.Context) (r0 time.Time, err error) {
+	// Update metrics.
+	begin := s.nowMetrics.Begin()
+	defer func() { s.nowMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "config.Clock.Now", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock")
+	return s.impl.Now(ctx)
+}
+
+// Client stub implementations.
+
+type cache_client_stub struct {
+	stub          codegen.Stub
+	configMetrics *codegen.MethodMetrics
+}
+
+// Check that cache_client_stub implements the Cache interface.
+var _ Cache = (*cache_client_stub)(nil)
+
+func (s cache_client_stub) Config(ctx context.Context) (r0 CacheConfig, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.configMetrics.Begin()
+	defer func() {
+		s.configMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "config.Cache.Config", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	(&r0).WeaverUnmarshal(dec)
+	err = dec.Error()
+	return
+}
+
+type clock_client_stub struct {
+	stub       codegen.Stub
+	nowMetrics *codegen.MethodMetrics
+}
+
+// Check that clock_client_stub implements the Clock interface.
+var _ Clock = (*clock_client_stub)(nil)
+
+func (s clock_client_stub) Now(ctx context.Context) (r0 time.Time, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.nowMetrics.Begin()
+	defer func() {
+		s.nowMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "config.Clock.Now", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	dec.DecodeBinaryUnmarshaler(&r0)
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type cache_server_stub struct {
+	impl       Cache
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that cache_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*cache_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s cache_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Config":
+		return s.config
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s cache_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s cache_server_stub) config(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 CacheConfig
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/config/Cache", "Config", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Config(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	(r0).WeaverMarshal(enc)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type clock_server_stub struct {
+	impl       Clock
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that clock_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*clock_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s clock_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Now":
+		return s.now
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s clock_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s clock_server_stub) now(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 time.Time
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/config/Clock", "Now", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Now(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.EncodeBinaryMarshaler(&r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type cache_intercept_stub struct {
+	next        Cache
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that cache_intercept_stub implements the Cache interface.
+var _ Cache = (*cache_intercept_stub)(nil)
+
+// cache_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func cache_intercept(next Cache, interceptor codegen.Interceptor, call codegen.Call) Cache {
+	if interceptor == nil {
+		return next
+	}
+	return cache_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s cache_intercept_stub) Config(ctx context.Context) (r0 CacheConfig, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Config", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Config(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[CacheConfig](results, 0), err
+}
+
+type clock_intercept_stub struct {
+	next        Clock
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that clock_intercept_stub implements the Clock interface.
+var _ Clock = (*clock_intercept_stub)(nil)
+
+// clock_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func clock_intercept(next Clock, interceptor codegen.Interceptor, call codegen.Call) Clock {
+	if interceptor == nil {
+		return next
+	}
+	return clock_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s clock_intercept_stub) Now(ctx context.Context) (r0 time.Time, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Now", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Now(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[time.Time](results, 0), err
+}
+
+// AutoMarshal implementations.
+
+var _ codegen.AutoMarshal = (*CacheConfig)(nil)
+
+type __is_CacheConfig[T ~struct {
+	weaver.AutoMarshal
+	Size     int
+	Policy   string "toml:\"eviction_policy\""
+	TTL      time.Duration
+	Prefixes []string
+}] struct{}
+
+var _ __is_CacheConfig[CacheConfig]
+
+func (x *CacheConfig) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("CacheConfig.WeaverMarshal: nil receiver"))
+	}
+	enc.Int(x.Size)
+	enc.String(x.Policy)
+	enc.Int64((int64)(x.TTL))
+	serviceweaver_enc_slice_string_4af10117(enc, x.Prefixes)
+}
+
+func (x *CacheConfig) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("CacheConfig.WeaverUnmarshal: nil receiver"))
+	}
+	x.Size = dec.Int()
+	x.Policy = dec.String()
+	*(*int64)(&x.TTL) = dec.Int64()
+	x.Prefixes = serviceweaver_dec_slice_string_4af10117(dec)
+}
+
+func serviceweaver_enc_slice_string_4af10117(enc *codegen.Encoder, arg []string) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		enc.String(arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_string_4af10117(dec *codegen.Decoder) []string {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]string, n)
+	for i := 0; i < n; i++ {
+		res[i] = dec.String()
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cycle contains components that refer to each other, used to test
+// that the cycle is reported rather than deadlocking. "weaver generate" rejects
+// such cycles by default, so the package is generated with -allow_ref_cycles.
+package cycle
+
+//go:generate ../../../cmd/weaver/weaver generate -allow_ref_cycles
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that depends on B.
+type A interface {
+	Ping(context.Context) error
+}
+
+// B is a component that depends on A.
+type B interface {
+	Ping(context.Context) error
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+}
+
+type b struct {
+	weaver.Implements[B]
+	a weaver.Ref[A]
+}
+
+func (*a) Ping(context.Context) error { return nil }
+func (*b) Ping(context.Context) error { return nil }
@@ -0,0 +1,50 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cycle_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/internal/private"
+	"github.com/ServiceWeaver/weaver/internal/reflection"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/cycle"
+)
+
+func TestRefCycle(t *testing.T) {
+	// The weavertest runners fail the test if a component can't be created,
+	// so we start the application directly.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = context.WithValue(ctx, runtime.BootstrapKey{}, runtime.Bootstrap{Quiet: !testing.Verbose()})
+	app, err := private.Start(ctx, private.AppOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(ctx) //nolint:errcheck // best effort
+
+	_, err = app.Get("test", reflection.Type[cycle.A]())
+	if err == nil {
+		t.Fatal("Get: unexpected success")
+	}
+	const pkg = "github.com/ServiceWeaver/weaver/weavertest/internal/cycle"
+	want := "weaver.Ref cycle: " + pkg + "/A -> " + pkg + "/B -> " + pkg + "/A"
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("Get: got error %q, want error containing %q", err, want)
+	}
+}
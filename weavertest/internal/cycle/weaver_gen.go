@@ -0,0 +1,409 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package cycle
+
+import (
+	"context"
+	"errors"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A",
+		Iface: reflect.TypeOf((*A)(nil)).Elem(),
+		Impl:  reflect.TypeOf(a{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return a_intercept(a_local_stub{impl: impl.(A), caller: caller, tracer: tracer, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A", Method: "Ping", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return a_intercept(a_client_stub{stub: stub, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A", Method: "Ping", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return a_server_stub{impl: a_intercept(impl.(A), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return a_intercept(next.(A), interceptor, call)
+		},
+		RefData: "⟦8d483b5f:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A→github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B⟧\n",
+	})
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B",
+		Iface: reflect.TypeOf((*B)(nil)).Elem(),
+		Impl:  reflect.TypeOf(b{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return b_intercept(b_local_stub{impl: impl.(B), caller: caller, tracer: tracer, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B", Method: "Ping", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return b_intercept(b_client_stub{stub: stub, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B", Method: "Ping", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return b_server_stub{impl: b_intercept(impl.(B), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return b_intercept(next.(B), interceptor, call)
+		},
+		RefData: "⟦5889daaf:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B→github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A⟧\n",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[A] = (*a)(nil)
+var _ weaver.InstanceOf[B] = (*b)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*a)(nil)
+var _ weaver.Unrouted = (*b)(nil)
+
+// Local stub implementations.
+
+type a_local_stub struct {
+	impl        A
+	caller      string
+	tracer      trace.Tracer
+	pingMetrics *codegen.MethodMetrics
+}
+
+// Check that a_local_stub implements the A interface.
+var _ A = (*a_local_stub)(nil)
+
+func (s a_local_stub) Ping(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.pingMetrics.Begin()
+	defer func() { s.pingMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "cycle.A.Ping", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A")
+	return s.impl.Ping(ctx)
+}
+
+type b_local_stub struct {
+	impl        B
+	caller      string
+	tracer      trace.Tracer
+	pingMetrics *codegen.MethodMetrics
+}
+
+// Check that b_local_stub implements the B interface.
+var _ B = (*b_local_stub)(nil)
+
+func (s b_local_stub) Ping(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.pingMetrics.Begin()
+	defer func() { s.pingMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "cycle.B.Ping", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B")
+	return s.impl.Ping(ctx)
+}
+
+// Client stub implementations.
+
+type a_client_stub struct {
+	stub        codegen.Stub
+	pingMetrics *codegen.MethodMetrics
+}
+
+// Check that a_client_stub implements the A interface.
+var _ A = (*a_client_stub)(nil)
+
+func (s a_client_stub) Ping(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.pingMetrics.Begin()
+	defer func() {
+		s.pingMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "cycle.A.Ping", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+type b_client_stub struct {
+	stub        codegen.Stub
+	pingMetrics *codegen.MethodMetrics
+}
+
+// Check that b_client_stub implements the B interface.
+var _ B = (*b_client_stub)(nil)
+
+func (s b_client_stub) Ping(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.pingMetrics.Begin()
+	defer func() {
+		s.pingMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "cycle.B.Ping", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type a_server_stub struct {
+	impl       A
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that a_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*a_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s a_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Ping":
+		return s.ping
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s a_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s a_server_stub) ping(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/A", "Ping", func(ctx context.Context) error {
+		return s.impl.Ping(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type b_server_stub struct {
+	impl       B
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that b_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*b_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s b_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Ping":
+		return s.ping
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s b_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s b_server_stub) ping(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/cycle/B", "Ping", func(ctx context.Context) error {
+		return s.impl.Ping(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type a_intercept_stub struct {
+	next        A
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that a_intercept_stub implements the A interface.
+var _ A = (*a_intercept_stub)(nil)
+
+// a_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func a_intercept(next A, interceptor codegen.Interceptor, call codegen.Call) A {
+	if interceptor == nil {
+		return next
+	}
+	return a_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s a_intercept_stub) Ping(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Ping", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Ping(ctx)
+	})
+	return err
+}
+
+type b_intercept_stub struct {
+	next        B
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that b_intercept_stub implements the B interface.
+var _ B = (*b_intercept_stub)(nil)
+
+// b_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func b_intercept(next B, interceptor codegen.Interceptor, call codegen.Call) B {
+	if interceptor == nil {
+		return next
+	}
+	return b_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s b_intercept_stub) Ping(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Ping", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Ping(ctx)
+	})
+	return err
+}
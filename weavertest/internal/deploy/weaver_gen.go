@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -39,13 +39,16 @@ func init() {
 		Iface: reflect.TypeOf((*Started)(nil)).Elem(),
 		Impl:  reflect.TypeOf(started{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return started_local_stub{impl: impl.(Started), tracer: tracer, markStartedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", Method: "MarkStarted", Remote: false})}
+			return started_intercept(started_local_stub{impl: impl.(Started), caller: caller, tracer: tracer, markStartedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", Method: "MarkStarted", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return started_client_stub{stub: stub, markStartedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", Method: "MarkStarted", Remote: true})}
+			return started_intercept(started_client_stub{stub: stub, markStartedMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", Method: "MarkStarted", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return started_server_stub{impl: impl.(Started), addLoad: addLoad}
+			return started_server_stub{impl: started_intercept(impl.(Started), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return started_intercept(next.(Started), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -54,13 +57,16 @@ func init() {
 		Iface: reflect.TypeOf((*Widget)(nil)).Elem(),
 		Impl:  reflect.TypeOf(widget{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return widget_local_stub{impl: impl.(Widget), tracer: tracer, useMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", Method: "Use", Remote: false})}
+			return widget_intercept(widget_local_stub{impl: impl.(Widget), caller: caller, tracer: tracer, useMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", Method: "Use", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return widget_client_stub{stub: stub, useMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", Method: "Use", Remote: true})}
+			return widget_intercept(widget_client_stub{stub: stub, useMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", Method: "Use", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return widget_server_stub{impl: impl.(Widget), addLoad: addLoad}
+			return widget_server_stub{impl: widget_intercept(impl.(Widget), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return widget_intercept(next.(Widget), interceptor, call)
 		},
 		RefData: "⟦f3fa3c18:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget→github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started⟧\n",
 	})
@@ -78,6 +84,7 @@ var _ weaver.Unrouted = (*widget)(nil)
 
 type started_local_stub struct {
 	impl               Started
+	caller             string
 	tracer             trace.Tracer
 	markStartedMetrics *codegen.MethodMetrics
 }
@@ -102,11 +109,13 @@ func (s started_local_stub) MarkStarted(ctx context.Context, a0 string) (err err
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started")
 	return s.impl.MarkStarted(ctx, a0)
 }
 
 type widget_local_stub struct {
 	impl       Widget
+	caller     string
 	tracer     trace.Tracer
 	useMetrics *codegen.MethodMetrics
 }
@@ -131,6 +140,7 @@ func (s widget_local_stub) Use(ctx context.Context, a0 string) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget")
 	return s.impl.Use(ctx, a0)
 }
 
@@ -147,8 +157,11 @@ var _ Started = (*started_client_stub)(nil)
 func (s started_client_stub) MarkStarted(ctx context.Context, a0 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.markStartedMetrics.Begin()
-	defer func() { s.markStartedMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.markStartedMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -161,6 +174,7 @@ func (s started_client_stub) MarkStarted(ctx context.Context, a0 string) (err er
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -184,11 +198,12 @@ func (s started_client_stub) MarkStarted(ctx context.Context, a0 string) (err er
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -210,8 +225,11 @@ var _ Widget = (*widget_client_stub)(nil)
 func (s widget_client_stub) Use(ctx context.Context, a0 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.useMetrics.Begin()
-	defer func() { s.useMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.useMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -224,6 +242,7 @@ func (s widget_client_stub) Use(ctx context.Context, a0 string) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -247,11 +266,12 @@ func (s widget_client_stub) Use(ctx context.Context, a0 string) (err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -265,8 +285,9 @@ func (s widget_client_stub) Use(ctx context.Context, a0 string) (err error) {
 // Server stub implementations.
 
 type started_server_stub struct {
-	impl    Started
-	addLoad func(key uint64, load float64)
+	impl       Started
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that started_server_stub implements the codegen.Server interface.
@@ -282,6 +303,11 @@ func (s started_server_stub) GetStubFn(method string) func(ctx context.Context,
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s started_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s started_server_stub) markStarted(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -295,10 +321,12 @@ func (s started_server_stub) markStarted(ctx context.Context, args []byte) (res
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.MarkStarted(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Started", "MarkStarted", func(ctx context.Context) error {
+		return s.impl.MarkStarted(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -307,8 +335,9 @@ func (s started_server_stub) markStarted(ctx context.Context, args []byte) (res
 }
 
 type widget_server_stub struct {
-	impl    Widget
-	addLoad func(key uint64, load float64)
+	impl       Widget
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that widget_server_stub implements the codegen.Server interface.
@@ -324,6 +353,11 @@ func (s widget_server_stub) GetStubFn(method string) func(ctx context.Context, a
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s widget_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s widget_server_stub) use(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -337,13 +371,67 @@ func (s widget_server_stub) use(ctx context.Context, args []byte) (res []byte, e
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Use(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/deploy/Widget", "Use", func(ctx context.Context) error {
+		return s.impl.Use(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
 	enc.Error(appErr)
 	return enc.Data(), nil
 }
+
+// Intercept stub implementations.
+
+type started_intercept_stub struct {
+	next        Started
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that started_intercept_stub implements the Started interface.
+var _ Started = (*started_intercept_stub)(nil)
+
+// started_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func started_intercept(next Started, interceptor codegen.Interceptor, call codegen.Call) Started {
+	if interceptor == nil {
+		return next
+	}
+	return started_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s started_intercept_stub) MarkStarted(ctx context.Context, a0 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "MarkStarted", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.MarkStarted(ctx, codegen.Arg[string](args, 0))
+	})
+	return err
+}
+
+type widget_intercept_stub struct {
+	next        Widget
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that widget_intercept_stub implements the Widget interface.
+var _ Widget = (*widget_intercept_stub)(nil)
+
+// widget_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func widget_intercept(next Widget, interceptor codegen.Interceptor, call codegen.Call) Widget {
+	if interceptor == nil {
+		return next
+	}
+	return widget_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s widget_intercept_stub) Use(ctx context.Context, a0 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Use", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Use(ctx, codegen.Arg[string](args, 0))
+	})
+	return err
+}
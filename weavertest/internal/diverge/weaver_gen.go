@@ -14,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*Errer)(nil)).Elem(),
 		Impl:  reflect.TypeOf(errer{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return errer_local_stub{impl: impl.(Errer), tracer: tracer, errMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", Method: "Err", Remote: false})}
+			return errer_intercept(errer_local_stub{impl: impl.(Errer), caller: caller, tracer: tracer, errMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", Method: "Err", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return errer_client_stub{stub: stub, errMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", Method: "Err", Remote: true})}
+			return errer_intercept(errer_client_stub{stub: stub, errMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", Method: "Err", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return errer_server_stub{impl: impl.(Errer), addLoad: addLoad}
+			return errer_server_stub{impl: errer_intercept(impl.(Errer), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return errer_intercept(next.(Errer), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -55,13 +58,16 @@ func init() {
 		Iface: reflect.TypeOf((*Pointer)(nil)).Elem(),
 		Impl:  reflect.TypeOf(pointer{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return pointer_local_stub{impl: impl.(Pointer), tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", Method: "Get", Remote: false})}
+			return pointer_intercept(pointer_local_stub{impl: impl.(Pointer), caller: caller, tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", Method: "Get", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return pointer_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", Method: "Get", Remote: true})}
+			return pointer_intercept(pointer_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", Method: "Get", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return pointer_server_stub{impl: impl.(Pointer), addLoad: addLoad}
+			return pointer_server_stub{impl: pointer_intercept(impl.(Pointer), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return pointer_intercept(next.(Pointer), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -79,6 +85,7 @@ var _ weaver.Unrouted = (*pointer)(nil)
 
 type errer_local_stub struct {
 	impl       Errer
+	caller     string
 	tracer     trace.Tracer
 	errMetrics *codegen.MethodMetrics
 }
@@ -103,11 +110,13 @@ func (s errer_local_stub) Err(ctx context.Context, a0 int) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer")
 	return s.impl.Err(ctx, a0)
 }
 
 type pointer_local_stub struct {
 	impl       Pointer
+	caller     string
 	tracer     trace.Tracer
 	getMetrics *codegen.MethodMetrics
 }
@@ -132,6 +141,7 @@ func (s pointer_local_stub) Get(ctx context.Context) (r0 Pair, err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer")
 	return s.impl.Get(ctx)
 }
 
@@ -148,8 +158,11 @@ var _ Errer = (*errer_client_stub)(nil)
 func (s errer_client_stub) Err(ctx context.Context, a0 int) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.errMetrics.Begin()
-	defer func() { s.errMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.errMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -162,6 +175,7 @@ func (s errer_client_stub) Err(ctx context.Context, a0 int) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -185,11 +199,12 @@ func (s errer_client_stub) Err(ctx context.Context, a0 int) (err error) {
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -211,8 +226,11 @@ var _ Pointer = (*pointer_client_stub)(nil)
 func (s pointer_client_stub) Get(ctx context.Context) (r0 Pair, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getMetrics.Begin()
-	defer func() { s.getMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -225,6 +243,7 @@ func (s pointer_client_stub) Get(ctx context.Context) (r0 Pair, err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -244,6 +263,7 @@ func (s pointer_client_stub) Get(ctx context.Context) (r0 Pair, err error) {
 	results, err = s.stub.Run(ctx, 0, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -258,8 +278,9 @@ func (s pointer_client_stub) Get(ctx context.Context) (r0 Pair, err error) {
 // Server stub implementations.
 
 type errer_server_stub struct {
-	impl    Errer
-	addLoad func(key uint64, load float64)
+	impl       Errer
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that errer_server_stub implements the codegen.Server interface.
@@ -275,6 +296,11 @@ func (s errer_server_stub) GetStubFn(method string) func(ctx context.Context, ar
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s errer_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s errer_server_stub) err(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -288,10 +314,12 @@ func (s errer_server_stub) err(ctx context.Context, args []byte) (res []byte, er
 	var a0 int
 	a0 = dec.Int()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Err(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Errer", "Err", func(ctx context.Context) error {
+		return s.impl.Err(ctx, a0)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -300,8 +328,9 @@ func (s errer_server_stub) err(ctx context.Context, args []byte) (res []byte, er
 }
 
 type pointer_server_stub struct {
-	impl    Pointer
-	addLoad func(key uint64, load float64)
+	impl       Pointer
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that pointer_server_stub implements the codegen.Server interface.
@@ -317,6 +346,11 @@ func (s pointer_server_stub) GetStubFn(method string) func(ctx context.Context,
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s pointer_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s pointer_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -325,10 +359,14 @@ func (s pointer_server_stub) get(ctx context.Context, args []byte) (res []byte,
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Get(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 Pair
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/diverge/Pointer", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -337,6 +375,59 @@ func (s pointer_server_stub) get(ctx context.Context, args []byte) (res []byte,
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type errer_intercept_stub struct {
+	next        Errer
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that errer_intercept_stub implements the Errer interface.
+var _ Errer = (*errer_intercept_stub)(nil)
+
+// errer_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func errer_intercept(next Errer, interceptor codegen.Interceptor, call codegen.Call) Errer {
+	if interceptor == nil {
+		return next
+	}
+	return errer_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s errer_intercept_stub) Err(ctx context.Context, a0 int) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Err", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Err(ctx, codegen.Arg[int](args, 0))
+	})
+	return err
+}
+
+type pointer_intercept_stub struct {
+	next        Pointer
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that pointer_intercept_stub implements the Pointer interface.
+var _ Pointer = (*pointer_intercept_stub)(nil)
+
+// pointer_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func pointer_intercept(next Pointer, interceptor codegen.Interceptor, call codegen.Call) Pointer {
+	if interceptor == nil {
+		return next
+	}
+	return pointer_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s pointer_intercept_stub) Get(ctx context.Context) (r0 Pair, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[Pair](results, 0), err
+}
+
 // AutoMarshal implementations.
 
 var _ codegen.AutoMarshal = (*Pair)(nil)
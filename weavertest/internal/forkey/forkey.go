@@ -0,0 +1,78 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package forkey contains components used to test weaver.Ref.ForKey.
+package forkey
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that calls B and R through keyed handles.
+type A interface {
+	// Keys calls B and R through handles keyed by key and returns the routing
+	// keys the calls carried.
+	Keys(ctx context.Context, key string) ([]string, error)
+}
+
+// B is an unrouted component.
+type B interface {
+	Key(ctx context.Context) (string, error)
+}
+
+// R is a routed component.
+type R interface {
+	Key(ctx context.Context) (string, error)
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+	r weaver.Ref[R]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+type router struct{}
+
+func (router) Key(context.Context) string { return "" }
+
+type r struct {
+	weaver.Implements[R]
+	weaver.WithRouter[router]
+}
+
+func (a *a) Keys(ctx context.Context, key string) ([]string, error) {
+	bKey, err := a.b.ForKey(key).Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rKey, err := a.r.ForKey(key).Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{bKey, rKey}, nil
+}
+
+func (b *b) Key(ctx context.Context) (string, error) {
+	return weaver.Metadata(ctx)[weaver.RoutingKeyMetadataKey], nil
+}
+
+func (r *r) Key(ctx context.Context) (string, error) {
+	return weaver.Metadata(ctx)[weaver.RoutingKeyMetadataKey], nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forkey_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/forkey"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestForKey(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a forkey.A) {
+			ctx := context.Background()
+			for _, tenant := range []string{"acme", "globex"} {
+				for i := 0; i < 3; i++ {
+					keys, err := a.Keys(ctx, tenant)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff([]string{tenant, tenant}, keys); diff != "" {
+						t.Errorf("keys (-want +got):\n%s", diff)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,669 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package forkey
+
+import (
+	"context"
+	"errors"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A",
+		Iface: reflect.TypeOf((*A)(nil)).Elem(),
+		Impl:  reflect.TypeOf(a{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return a_intercept(a_local_stub{impl: impl.(A), caller: caller, tracer: tracer, keysMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A", Method: "Keys", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return a_intercept(a_client_stub{stub: stub, keysMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A", Method: "Keys", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return a_server_stub{impl: a_intercept(impl.(A), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return a_intercept(next.(A), interceptor, call)
+		},
+		RefData: "⟦bb6b4c3b:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A→github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B⟧\n⟦1812993e:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A→github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R⟧\n",
+	})
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B",
+		Iface: reflect.TypeOf((*B)(nil)).Elem(),
+		Impl:  reflect.TypeOf(b{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return b_intercept(b_local_stub{impl: impl.(B), caller: caller, tracer: tracer, keyMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B", Method: "Key", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return b_intercept(b_client_stub{stub: stub, keyMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B", Method: "Key", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return b_server_stub{impl: b_intercept(impl.(B), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return b_intercept(next.(B), interceptor, call)
+		},
+		RefData: "",
+	})
+	codegen.Register(codegen.Registration{
+		Name:   "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R",
+		Iface:  reflect.TypeOf((*R)(nil)).Elem(),
+		Impl:   reflect.TypeOf(r{}),
+		Routed: true,
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return r_intercept(r_local_stub{impl: impl.(R), caller: caller, tracer: tracer, keyMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R", Method: "Key", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return r_intercept(r_client_stub{stub: stub, keyMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R", Method: "Key", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return r_server_stub{impl: r_intercept(impl.(R), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return r_intercept(next.(R), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[A] = (*a)(nil)
+var _ weaver.InstanceOf[B] = (*b)(nil)
+var _ weaver.InstanceOf[R] = (*r)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*a)(nil)
+var _ weaver.Unrouted = (*b)(nil)
+var _ weaver.RoutedBy[router] = (*r)(nil)
+
+// Component "r", router "router" checks.
+var _ func(context.Context) string = (&router{}).Key // routed
+
+// Local stub implementations.
+
+type a_local_stub struct {
+	impl        A
+	caller      string
+	tracer      trace.Tracer
+	keysMetrics *codegen.MethodMetrics
+}
+
+// Check that a_local_stub implements the A interface.
+var _ A = (*a_local_stub)(nil)
+
+func (s a_local_stub) Keys(ctx context.Context, a0 string) (r0 []string, err error) {
+	// Update metrics.
+	begin := s.keysMetrics.Begin()
+	defer func() { s.keysMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "forkey.A.Keys", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A")
+	return s.impl.Keys(ctx, a0)
+}
+
+type b_local_stub struct {
+	impl       B
+	caller     string
+	tracer     trace.Tracer
+	keyMetrics *codegen.MethodMetrics
+}
+
+// Check that b_local_stub implements the B interface.
+var _ B = (*b_local_stub)(nil)
+
+func (s b_local_stub) Key(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	begin := s.keyMetrics.Begin()
+	defer func() { s.keyMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "forkey.B.Key", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B")
+	return s.impl.Key(ctx)
+}
+
+type r_local_stub struct {
+	impl       R
+	caller     string
+	tracer     trace.Tracer
+	keyMetrics *codegen.MethodMetrics
+}
+
+// Check that r_local_stub implements the R interface.
+var _ R = (*r_local_stub)(nil)
+
+func (s r_local_stub) Key(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	begin := s.keyMetrics.Begin()
+	defer func() { s.keyMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "forkey.R.Key", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R")
+	return s.impl.Key(ctx)
+}
+
+// Client stub implementations.
+
+type a_client_stub struct {
+	stub        codegen.Stub
+	keysMetrics *codegen.MethodMetrics
+}
+
+// Check that a_client_stub implements the A interface.
+var _ A = (*a_client_stub)(nil)
+
+func (s a_client_stub) Keys(ctx context.Context, a0 string) (r0 []string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.keysMetrics.Begin()
+	defer func() {
+		s.keysMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "forkey.A.Keys", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = serviceweaver_dec_slice_string_4af10117(dec)
+	err = dec.Error()
+	return
+}
+
+type b_client_stub struct {
+	stub       codegen.Stub
+	keyMetrics *codegen.MethodMetrics
+}
+
+// Check that b_client_stub implements the B interface.
+var _ B = (*b_client_stub)(nil)
+
+func (s b_client_stub) Key(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.keyMetrics.Begin()
+	defer func() {
+		s.keyMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "forkey.B.Key", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+type r_client_stub struct {
+	stub       codegen.Stub
+	keyMetrics *codegen.MethodMetrics
+}
+
+// Check that r_client_stub implements the R interface.
+var _ R = (*r_client_stub)(nil)
+
+func (s r_client_stub) Key(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.keyMetrics.Begin()
+	defer func() {
+		s.keyMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "forkey.R.Key", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Set the shardKey.
+	var r router
+	shardKey := _hashR(r.Key(ctx))
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type a_server_stub struct {
+	impl       A
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that a_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*a_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s a_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Keys":
+		return s.keys
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s a_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s a_server_stub) keys(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/A", "Keys", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Keys(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	serviceweaver_enc_slice_string_4af10117(enc, r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type b_server_stub struct {
+	impl       B
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that b_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*b_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s b_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Key":
+		return s.key
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s b_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s b_server_stub) key(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/B", "Key", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Key(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type r_server_stub struct {
+	impl       R
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that r_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*r_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s r_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Key":
+		return s.key
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s r_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s r_server_stub) key(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+	var r router
+	loadKey := _hashR(r.Key(ctx))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/forkey/R", "Key", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Key(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type a_intercept_stub struct {
+	next        A
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that a_intercept_stub implements the A interface.
+var _ A = (*a_intercept_stub)(nil)
+
+// a_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func a_intercept(next A, interceptor codegen.Interceptor, call codegen.Call) A {
+	if interceptor == nil {
+		return next
+	}
+	return a_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s a_intercept_stub) Keys(ctx context.Context, a0 string) (r0 []string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Keys", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Keys(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]string](results, 0), err
+}
+
+type b_intercept_stub struct {
+	next        B
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that b_intercept_stub implements the B interface.
+var _ B = (*b_intercept_stub)(nil)
+
+// b_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func b_intercept(next B, interceptor codegen.Interceptor, call codegen.Call) B {
+	if interceptor == nil {
+		return next
+	}
+	return b_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s b_intercept_stub) Key(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Key", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Key(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+type r_intercept_stub struct {
+	next        R
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that r_intercept_stub implements the R interface.
+var _ R = (*r_intercept_stub)(nil)
+
+// r_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func r_intercept(next R, interceptor codegen.Interceptor, call codegen.Call) R {
+	if interceptor == nil {
+		return next
+	}
+	return r_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s r_intercept_stub) Key(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Key", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Key(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+// Router methods.
+
+// _hashR returns a 64 bit hash of the provided value.
+func _hashR(r string) uint64 {
+	var h codegen.Hasher
+	h.WriteString(string(r))
+	return h.Sum64()
+}
+
+// _orderedCodeR returns an order-preserving serialization of the provided value.
+func _orderedCodeR(r string) codegen.OrderedCode {
+	var enc codegen.OrderedEncoder
+	enc.WriteString(string(r))
+	return enc.Encode()
+}
+
+// Encoding/decoding implementations.
+
+func serviceweaver_enc_slice_string_4af10117(enc *codegen.Encoder, arg []string) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		enc.String(arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_string_4af10117(dec *codegen.Decoder) []string {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]string, n)
+	for i := 0; i < n; i++ {
+		res[i] = dec.String()
+	}
+	return res
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 
 	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/uuid"
 )
 
 //go:generate ../../../cmd/weaver/weaver generate
@@ -34,9 +36,26 @@ const (
 	noError
 )
 
+func init() {
+	// uuid.UUID is serializable on its own, being a byte array, but is
+	// encoded as its string form here to exercise registered codecs.
+	codegen.RegisterTypeCodec(
+		func(enc *codegen.Encoder, id uuid.UUID) { enc.String(id.String()) },
+		func(dec *codegen.Decoder) uuid.UUID { return uuid.MustParse(dec.String()) },
+	)
+}
+
+// item is a struct with a field whose type has a registered codec.
+type item struct {
+	weaver.AutoMarshal
+	ID   uuid.UUID
+	Name string
+}
+
 type testApp interface {
 	Get(_ context.Context, key string, behavior behaviorType) (int, error)
 	IncPointer(_ context.Context, arg *int) (*int, error)
+	Rename(_ context.Context, x item, name string) (item, error)
 }
 
 type impl struct {
@@ -65,3 +84,9 @@ func (p *impl) IncPointer(_ context.Context, arg *int) (*int, error) {
 	*res = *arg + 1
 	return res, nil
 }
+
+// Rename returns a copy of x with the provided name.
+func (p *impl) Rename(_ context.Context, x item, name string) (item, error) {
+	x.Name = name
+	return x, nil
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ServiceWeaver/weaver"
 	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/google/uuid"
 )
 
 // TODO(mwhittaker): Induce an error in the encoding, decoding, and RPC call.
@@ -83,3 +84,19 @@ func TestPointers(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisteredCodec(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		ctx := context.Background()
+		runner.Test(t, func(t *testing.T, client testApp) {
+			x := item{ID: uuid.New(), Name: "old"}
+			got, err := client.Rename(ctx, x, "new")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (item{ID: x.ID, Name: "new"}); got != want {
+				t.Fatalf("Rename: got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import "github.com/ServiceWeaver/weaver"
+
+// The following types contain slices and maps of primitives and of other
+// AutoMarshal structs.
+
+type lineItem struct {
+	weaver.AutoMarshal
+	Name  string
+	Price float64
+}
+
+type order struct {
+	weaver.AutoMarshal
+	ID       string
+	Tags     []string
+	Counts   map[string]int
+	Items    []lineItem
+	ByName   map[string]lineItem
+	Batches  [][]lineItem
+	Sections map[int]map[string][]lineItem
+	Owners   map[lineItem]bool
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestContainersRoundTrip(t *testing.T) {
+	a, b := lineItem{Name: "a", Price: 1}, lineItem{Name: "b", Price: 2}
+	opts := cmpopts.IgnoreUnexported(order{}, lineItem{})
+	for _, test := range []struct {
+		name string
+		o    order
+	}{
+		{"Zero", order{}},
+		{"Empty", order{
+			Tags:     []string{},
+			Counts:   map[string]int{},
+			Items:    []lineItem{},
+			ByName:   map[string]lineItem{},
+			Batches:  [][]lineItem{},
+			Sections: map[int]map[string][]lineItem{},
+			Owners:   map[lineItem]bool{},
+		}},
+		{"NilElements", order{
+			Batches:  [][]lineItem{nil, {}},
+			Sections: map[int]map[string][]lineItem{1: nil, 2: {"x": nil}},
+		}},
+		{"Full", order{
+			ID:       "id",
+			Tags:     []string{"x", "y"},
+			Counts:   map[string]int{"x": 1, "y": 2},
+			Items:    []lineItem{a, b},
+			ByName:   map[string]lineItem{"a": a, "b": b},
+			Batches:  [][]lineItem{{a}, {a, b}},
+			Sections: map[int]map[string][]lineItem{1: {"a": {a}}, 2: {"b": {b, a}}},
+			Owners:   map[lineItem]bool{a: true, b: false},
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got order
+			convert(t, &test.o, &got)
+			// Note that cmp.Diff distinguishes nil and empty slices and maps.
+			if diff := cmp.Diff(test.o, got, opts); diff != "" {
+				t.Fatalf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeterministicMapEncoding(t *testing.T) {
+	// Build equal maps with different insertion orders.
+	m1, m2 := map[string]lineItem{}, map[string]lineItem{}
+	for i := 0; i < 100; i++ {
+		k := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		m1[k] = lineItem{Name: k}
+	}
+	for i := 99; i >= 0; i-- {
+		k := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		m2[k] = lineItem{Name: k}
+	}
+
+	encode := func(m map[string]lineItem) []byte {
+		enc := codegen.NewEncoder()
+		o := order{ByName: m}
+		o.WeaverMarshal(enc)
+		return enc.Data()
+	}
+	want := encode(m1)
+	for i := 0; i < 10; i++ {
+		if got := encode(m2); !bytes.Equal(got, want) {
+			t.Fatalf("equal maps have different encodings")
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import "github.com/ServiceWeaver/weaver"
+
+// session has fields excluded from marshalling: a value derived from other
+// fields, and a cache that can't be marshaled.
+type session struct {
+	weaver.AutoMarshal
+	ID     string
+	Scores []int
+	total  int             `weaver:"-"` // sum of Scores
+	seen   map[string]bool `weaver:"-"`
+}
+
+// recordV4 renames the Name field of recordV3 in Go, and pins its wire name
+// so that it stays compatible with the previous versions.
+type recordV4 struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	Title              string `weaver:"Name"`
+	Count              int
+	summary            string `weaver:"-"`
+}
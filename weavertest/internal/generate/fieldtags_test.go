@@ -0,0 +1,65 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestExcludedFieldsRoundTrip(t *testing.T) {
+	src := session{ID: "a", Scores: []int{1, 2}, total: 3, seen: map[string]bool{"x": true}}
+	want := session{ID: "a", Scores: []int{1, 2}}
+
+	// Excluded fields are zero after decoding, even if they were set before.
+	for _, dst := range []*session{{}, {ID: "old", total: 42, seen: map[string]bool{"y": true}}} {
+		convert(t, &src, dst)
+		if diff := cmp.Diff(want, *dst, cmpopts.IgnoreUnexported(session{})); diff != "" {
+			t.Fatalf("(-want +got):\n%s", diff)
+		}
+		if dst.total != 0 || dst.seen != nil {
+			t.Fatalf("excluded fields: got total = %d, seen = %v, want zero", dst.total, dst.seen)
+		}
+	}
+}
+
+func TestWireNamesRoundTrip(t *testing.T) {
+	opts := cmpopts.IgnoreUnexported(recordV1{}, recordV4{})
+	for _, test := range []struct {
+		name string
+		src  codegen.AutoMarshal
+		dst  codegen.AutoMarshal
+		want any
+	}{
+		// The renamed field keeps its wire name, so it matches the field of
+		// the same name in older versions.
+		{"V4ToV1", &recordV4{Title: "a", Count: 1, summary: "x"}, &recordV1{}, &recordV1{Name: "a", Count: 1}},
+		{"V1ToV4", &recordV1{Name: "b", Count: 2}, &recordV4{summary: "stale"}, &recordV4{Title: "b", Count: 2}},
+		{"V4ToV4", &recordV4{Title: "c", Count: 3, summary: "x"}, &recordV4{}, &recordV4{Title: "c", Count: 3}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			convert(t, test.src, test.dst)
+			if diff := cmp.Diff(test.want, test.dst, opts); diff != "" {
+				t.Fatalf("(-want +got):\n%s", diff)
+			}
+			if dst, ok := test.dst.(*recordV4); ok && dst.summary != "" {
+				t.Fatalf("excluded field summary = %q, want empty", dst.summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// guarded is a component whose Private method is guarded by an observer.
+type guarded interface {
+	Public(_ context.Context, msg string) (string, error)
+	Private(_ context.Context, msg string) (string, error)
+}
+
+type guardedImpl struct {
+	weaver.Implements[guarded]
+	weaver.WithObserver[guard]
+}
+
+func (g *guardedImpl) Public(_ context.Context, msg string) (string, error) {
+	return msg, nil
+}
+
+func (g *guardedImpl) Private(_ context.Context, msg string) (string, error) {
+	return msg, nil
+}
+
+var errDenied = errors.New("permission denied")
+
+// guard rejects calls to Private that don't carry the "token" metadata.
+type guard struct{}
+
+func (*guard) Private(ctx context.Context, next guarded, msg string) (string, error) {
+	if weaver.Metadata(ctx)["token"] != "secret" {
+		return "", errDenied
+	}
+	return next.Private(ctx, msg)
+}
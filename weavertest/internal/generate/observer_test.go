@@ -0,0 +1,48 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/weavertest"
+)
+
+func TestObserver(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, g guarded) {
+			ctx := context.Background()
+
+			// Methods without an observer method are not intercepted.
+			if got, err := g.Public(ctx, "hello"); err != nil || got != "hello" {
+				t.Fatalf("Public: got (%q, %v), want (\"hello\", nil)", got, err)
+			}
+
+			// The observer short-circuits calls without a token.
+			if _, err := g.Private(ctx, "hello"); !errors.Is(err, errDenied) {
+				t.Fatalf("Private without token: got %v, want %v", err, errDenied)
+			}
+
+			// The observer forwards calls with a token.
+			authed := weaver.SetMetadata(ctx, "token", "secret")
+			if got, err := g.Private(authed, "hello"); err != nil || got != "hello" {
+				t.Fatalf("Private with token: got (%q, %v), want (\"hello\", nil)", got, err)
+			}
+		})
+	}
+}
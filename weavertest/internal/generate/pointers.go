@@ -0,0 +1,36 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import "github.com/ServiceWeaver/weaver"
+
+// The following types contain pointers, including chains of pointers, to
+// other AutoMarshal structs. A nil pointer is encoded as a single zero byte,
+// and a non-nil pointer as a one byte followed by the value it points to.
+
+type node struct {
+	weaver.AutoMarshal
+	Name string
+}
+
+type pointers struct {
+	weaver.AutoMarshal
+	Node   *node
+	Other  *node
+	Chain  **node
+	Deep   ********node
+	Nodes  []*node
+	ByName map[string]*node
+}
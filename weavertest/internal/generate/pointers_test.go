@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// deep returns a pointer chain of the type of pointers.Deep that leads to n.
+func deep(n *node) ********node {
+	p2 := &n
+	p3 := &p2
+	p4 := &p3
+	p5 := &p4
+	p6 := &p5
+	p7 := &p6
+	return &p7
+}
+
+func TestPointersRoundTrip(t *testing.T) {
+	a := &node{Name: "a"}
+	var nilNode *node
+	opts := cmpopts.IgnoreUnexported(pointers{}, node{})
+	for _, test := range []struct {
+		name string
+		p    pointers
+	}{
+		{"Nil", pointers{}},
+		{"NilInChain", pointers{Chain: &nilNode, Deep: deep(nil)}},
+		{"NilElements", pointers{
+			Nodes:  []*node{nil, a},
+			ByName: map[string]*node{"nil": nil},
+		}},
+		{"ZeroValue", pointers{Node: &node{}}},
+		{"Full", pointers{
+			Node:   a,
+			Other:  &node{Name: "b"},
+			Chain:  &a,
+			Deep:   deep(a),
+			Nodes:  []*node{a},
+			ByName: map[string]*node{"a": a},
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got pointers
+			convert(t, &test.p, &got)
+			// Note that cmp.Diff distinguishes nil pointers from pointers to
+			// zero values.
+			if diff := cmp.Diff(test.p, got, opts); diff != "" {
+				t.Fatalf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSharedPointers(t *testing.T) {
+	// Pointers that are shared before encoding are not shared after
+	// decoding; every pointer gets its own copy of the value.
+	shared := &node{Name: "shared"}
+	src := pointers{Node: shared, Other: shared, Nodes: []*node{shared, shared}}
+	var got pointers
+	convert(t, &src, &got)
+	if got.Node == got.Other || got.Nodes[0] == got.Nodes[1] {
+		t.Fatal("decoded pointers are shared")
+	}
+	got.Node.Name = "changed"
+	for _, n := range []*node{got.Other, got.Nodes[0], got.Nodes[1]} {
+		if n.Name != "shared" {
+			t.Errorf("got name %q, want %q", n.Name, "shared")
+		}
+	}
+}
+
+func TestNilPointerEncoding(t *testing.T) {
+	// Every nil pointer field is encoded as a single zero byte. The nil slice
+	// and map are each encoded as a length of -1.
+	enc := codegen.NewEncoder()
+	(&pointers{}).WeaverMarshal(enc)
+	want := []byte{
+		0, 0, 0, 0, // Node, Other, Chain, Deep
+		0xff, 0xff, 0xff, 0xff, // Nodes
+		0xff, 0xff, 0xff, 0xff, // ByName
+	}
+	if got := enc.Data(); !cmp.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
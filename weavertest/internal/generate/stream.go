@@ -0,0 +1,57 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// streamer is a component with methods that return streams.
+type streamer interface {
+	// Rows streams n rows. If fail is non-negative, the stream fails after
+	// fail rows. Rows fails without streaming if n is negative.
+	Rows(_ context.Context, n, fail int) (weaver.Stream[row], error)
+}
+
+type row struct {
+	weaver.AutoMarshal
+	Index   int
+	Payload string
+}
+
+type streamerImpl struct {
+	weaver.Implements[streamer]
+}
+
+func (s *streamerImpl) Rows(_ context.Context, n, fail int) (weaver.Stream[row], error) {
+	if n < 0 {
+		return nil, fmt.Errorf("negative number of rows %d", n)
+	}
+	return func(yield func(row) bool) error {
+		for i := 0; i < n; i++ {
+			if i == fail {
+				return fmt.Errorf("failed after %d rows", fail)
+			}
+			if !yield(row{Index: i, Payload: strings.Repeat("x", i%100)}) {
+				return nil
+			}
+		}
+		return nil
+	}, nil
+}
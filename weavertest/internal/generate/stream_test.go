@@ -0,0 +1,109 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/weavertest"
+)
+
+func TestStream(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		ctx := context.Background()
+		runner.Test(t, func(t *testing.T, s streamer) {
+			// Stream enough rows to span many chunks.
+			const n = 100000
+			rows, err := s.Rows(ctx, n, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i := 0
+			err = rows(func(r row) bool {
+				if r.Index != i || r.Payload != strings.Repeat("x", i%100) {
+					t.Fatalf("row %d: got %v", i, r)
+				}
+				i++
+				return true
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i != n {
+				t.Fatalf("got %d rows, want %d", i, n)
+			}
+		})
+	}
+}
+
+func TestStreamStop(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		ctx := context.Background()
+		runner.Test(t, func(t *testing.T, s streamer) {
+			// Stop iterating over an endless stream.
+			rows, err := s.Rows(ctx, 1<<62, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i := 0
+			err = rows(func(r row) bool {
+				i++
+				return i < 10
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i != 10 {
+				t.Fatalf("got %d rows, want 10", i)
+			}
+		})
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		ctx := context.Background()
+		runner.Test(t, func(t *testing.T, s streamer) {
+			// The method fails.
+			if _, err := s.Rows(ctx, -1, -1); err == nil || !strings.Contains(err.Error(), "negative") {
+				t.Fatalf("Rows: got %v, want negative number of rows error", err)
+			}
+
+			// The stream fails midway.
+			rows, err := s.Rows(ctx, 10, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i := 0
+			err = rows(func(r row) bool {
+				i++
+				return true
+			})
+			if err == nil || !strings.Contains(err.Error(), "failed after 5 rows") {
+				t.Fatalf("stream: got %v, want failed after 5 rows error", err)
+			}
+			if errors.Is(err, weaver.RemoteCallError) {
+				t.Fatalf("stream: application error %v is a RemoteCallError", err)
+			}
+			if i != 5 {
+				t.Fatalf("got %d rows, want 5", i)
+			}
+		})
+	}
+}
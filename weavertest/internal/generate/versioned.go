@@ -0,0 +1,50 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import "github.com/ServiceWeaver/weaver"
+
+// The following types are successive versions of the same struct, as it might
+// evolve across releases of an application. They use the versioned AutoMarshal
+// encoding, so values encoded by any version can be decoded by any other.
+
+type recordV1 struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	Name               string
+	Count              int
+}
+
+// recordV2 adds fields to recordV1.
+type recordV2 struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	Name               string
+	Count              int
+	Tags               []string
+	Inner              innerV2
+}
+
+// recordV3 reorders the fields of recordV2.
+type recordV3 struct {
+	weaver.AutoMarshal `weaver:"versioned"`
+	Inner              innerV2
+	Tags               []string
+	Count              int
+	Name               string
+}
+
+type innerV2 struct {
+	weaver.AutoMarshal
+	X, Y float64
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// convert encodes src and decodes the encoding into dst, as would happen if
+// an application encoded src and a different version of the application
+// decoded dst.
+func convert(t *testing.T, src, dst codegen.AutoMarshal) {
+	t.Helper()
+	enc := codegen.NewEncoder()
+	src.WeaverMarshal(enc)
+	dec := codegen.NewDecoder(enc.Data())
+	func() {
+		defer func() {
+			if err := codegen.CatchPanics(recover()); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		dst.WeaverUnmarshal(dec)
+	}()
+	if !dec.Empty() {
+		t.Fatalf("%T: trailing bytes after decoding", dst)
+	}
+}
+
+func TestVersionedAutoMarshal(t *testing.T) {
+	v1 := recordV1{Name: "a", Count: 1}
+	v2 := recordV2{Name: "b", Count: 2, Tags: []string{"x", "y"}, Inner: innerV2{X: 1, Y: 2}}
+	v3 := recordV3{Name: "c", Count: 3, Tags: []string{"z"}, Inner: innerV2{X: 3, Y: 4}}
+	opts := cmpopts.IgnoreUnexported(recordV1{}, recordV2{}, recordV3{}, innerV2{})
+
+	for _, test := range []struct {
+		name string
+		src  codegen.AutoMarshal
+		dst  codegen.AutoMarshal
+		want any
+	}{
+		// An old decoder ignores added fields.
+		{"V2ToV1", &v2, &recordV1{}, &recordV1{Name: "b", Count: 2}},
+		// A new decoder leaves missing fields zero.
+		{"V1ToV2", &v1, &recordV2{}, &recordV2{Name: "a", Count: 1}},
+		// Decoding overwrites existing fields, including missing ones.
+		{"V1ToNonEmptyV2", &v1, &recordV2{Tags: []string{"old"}}, &recordV2{Name: "a", Count: 1}},
+		// Reordered fields are matched by name.
+		{"V2ToV3", &v2, &recordV3{}, &recordV3{Name: "b", Count: 2, Tags: []string{"x", "y"}, Inner: innerV2{X: 1, Y: 2}}},
+		{"V3ToV2", &v3, &recordV2{}, &recordV2{Name: "c", Count: 3, Tags: []string{"z"}, Inner: innerV2{X: 3, Y: 4}}},
+		{"V3ToV1", &v3, &recordV1{}, &recordV1{Name: "c", Count: 3}},
+		{"V1ToV3", &v1, &recordV3{}, &recordV3{Name: "a", Count: 1}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			convert(t, test.src, test.dst)
+			if diff := cmp.Diff(test.want, test.dst, opts); diff != "" {
+				t.Fatalf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -6,17 +6,19 @@ package generate
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/ServiceWeaver/weaver"
 	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -34,36 +36,166 @@ please file an issue at https://github.com/ServiceWeaver/weaver/issues.
 `)
 
 func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded",
+		Iface: reflect.TypeOf((*guarded)(nil)).Elem(),
+		Impl:  reflect.TypeOf(guardedImpl{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return guarded_intercept(guarded_local_stub{impl: impl.(guarded), caller: caller, tracer: tracer, privateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", Method: "Private", Remote: false}), publicMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", Method: "Public", Remote: false}), observer: codegen.Observer[guard](impl)}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return guarded_intercept(guarded_client_stub{stub: stub, privateMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", Method: "Private", Remote: true}), publicMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", Method: "Public", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return guarded_server_stub{impl: guarded_intercept(impl.(guarded), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}, observer: codegen.Observer[guard](impl)}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return guarded_intercept(next.(guarded), interceptor, call)
+		},
+		RefData: "",
+	})
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer",
+		Iface: reflect.TypeOf((*streamer)(nil)).Elem(),
+		Impl:  reflect.TypeOf(streamerImpl{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return streamer_intercept(streamer_local_stub{impl: impl.(streamer), caller: caller, tracer: tracer, rowsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer", Method: "Rows", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return streamer_intercept(streamer_client_stub{stub: stub, rowsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer", Method: "Rows", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return streamer_server_stub{impl: streamer_intercept(impl.(streamer), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return streamer_intercept(next.(streamer), interceptor, call)
+		},
+		RefData: "",
+	})
 	codegen.Register(codegen.Registration{
 		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp",
 		Iface: reflect.TypeOf((*testApp)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return testApp_local_stub{impl: impl.(testApp), tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "Get", Remote: false}), incPointerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "IncPointer", Remote: false})}
+			return testApp_intercept(testApp_local_stub{impl: impl.(testApp), caller: caller, tracer: tracer, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "Get", Remote: false}), incPointerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "IncPointer", Remote: false}), renameMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "Rename", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return testApp_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "Get", Remote: true}), incPointerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "IncPointer", Remote: true})}
+			return testApp_intercept(testApp_client_stub{stub: stub, getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "Get", Remote: true}), incPointerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "IncPointer", Remote: true}), renameMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Method: "Rename", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return testApp_server_stub{impl: impl.(testApp), addLoad: addLoad}
+			return testApp_server_stub{impl: testApp_intercept(impl.(testApp), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return testApp_intercept(next.(testApp), interceptor, call)
 		},
 		RefData: "",
 	})
 }
 
 // weaver.Instance checks.
+var _ weaver.InstanceOf[guarded] = (*guardedImpl)(nil)
+var _ weaver.InstanceOf[streamer] = (*streamerImpl)(nil)
 var _ weaver.InstanceOf[testApp] = (*impl)(nil)
 
 // weaver.Router checks.
+var _ weaver.Unrouted = (*guardedImpl)(nil)
+var _ weaver.Unrouted = (*streamerImpl)(nil)
 var _ weaver.Unrouted = (*impl)(nil)
 
 // Local stub implementations.
 
+type guarded_local_stub struct {
+	impl           guarded
+	observer       *guard
+	caller         string
+	tracer         trace.Tracer
+	privateMetrics *codegen.MethodMetrics
+	publicMetrics  *codegen.MethodMetrics
+}
+
+// Check that guarded_local_stub implements the guarded interface.
+var _ guarded = (*guarded_local_stub)(nil)
+
+func (s guarded_local_stub) Private(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	begin := s.privateMetrics.Begin()
+	defer func() { s.privateMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "generate.guarded.Private", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded")
+	return s.observer.Private(ctx, s.impl, a0)
+}
+
+func (s guarded_local_stub) Public(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	begin := s.publicMetrics.Begin()
+	defer func() { s.publicMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "generate.guarded.Public", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded")
+	return s.impl.Public(ctx, a0)
+}
+
+type streamer_local_stub struct {
+	impl        streamer
+	caller      string
+	tracer      trace.Tracer
+	rowsMetrics *codegen.MethodMetrics
+}
+
+// Check that streamer_local_stub implements the streamer interface.
+var _ streamer = (*streamer_local_stub)(nil)
+
+func (s streamer_local_stub) Rows(ctx context.Context, a0 int, a1 int) (r0 weaver.Stream[row], err error) {
+	// Update metrics.
+	begin := s.rowsMetrics.Begin()
+	defer func() { s.rowsMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "generate.streamer.Rows", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer")
+	return s.impl.Rows(ctx, a0, a1)
+}
+
 type testApp_local_stub struct {
 	impl              testApp
+	caller            string
 	tracer            trace.Tracer
 	getMetrics        *codegen.MethodMetrics
 	incPointerMetrics *codegen.MethodMetrics
+	renameMetrics     *codegen.MethodMetrics
 }
 
 // Check that testApp_local_stub implements the testApp interface.
@@ -86,6 +218,7 @@ func (s testApp_local_stub) Get(ctx context.Context, a0 string, a1 behaviorType)
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp")
 	return s.impl.Get(ctx, a0, a1)
 }
 
@@ -106,30 +239,55 @@ func (s testApp_local_stub) IncPointer(ctx context.Context, a0 *int) (r0 *int, e
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp")
 	return s.impl.IncPointer(ctx, a0)
 }
 
+func (s testApp_local_stub) Rename(ctx context.Context, a0 item, a1 string) (r0 item, err error) {
+	// Update metrics.
+	begin := s.renameMetrics.Begin()
+	defer func() { s.renameMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "generate.testApp.Rename", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp")
+	return s.impl.Rename(ctx, a0, a1)
+}
+
 // Client stub implementations.
 
-type testApp_client_stub struct {
-	stub              codegen.Stub
-	getMetrics        *codegen.MethodMetrics
-	incPointerMetrics *codegen.MethodMetrics
+type guarded_client_stub struct {
+	stub           codegen.Stub
+	privateMetrics *codegen.MethodMetrics
+	publicMetrics  *codegen.MethodMetrics
 }
 
-// Check that testApp_client_stub implements the testApp interface.
-var _ testApp = (*testApp_client_stub)(nil)
+// Check that guarded_client_stub implements the guarded interface.
+var _ guarded = (*guarded_client_stub)(nil)
 
-func (s testApp_client_stub) Get(ctx context.Context, a0 string, a1 behaviorType) (r0 int, err error) {
+func (s guarded_client_stub) Private(ctx context.Context, a0 string) (r0 string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
-	begin := s.getMetrics.Begin()
-	defer func() { s.getMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	var transportErr bool
+	begin := s.privateMetrics.Begin()
+	defer func() {
+		s.privateMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
 		// Create a child span for this method.
-		ctx, span = s.stub.Tracer().Start(ctx, "generate.testApp.Get", trace.WithSpanKind(trace.SpanKindClient))
+		ctx, span = s.stub.Tracer().Start(ctx, "generate.guarded.Private", trace.WithSpanKind(trace.SpanKindClient))
 	}
 
 	defer func() {
@@ -137,6 +295,7 @@ func (s testApp_client_stub) Get(ctx context.Context, a0 string, a1 behaviorType
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -152,42 +311,44 @@ func (s testApp_client_stub) Get(ctx context.Context, a0 string, a1 behaviorType
 	// Preallocate a buffer of the right size.
 	size := 0
 	size += (4 + len(a0))
-	size += 8
 	enc := codegen.NewEncoder()
 	enc.Reset(size)
 
 	// Encode arguments.
 	enc.String(a0)
-	enc.Int((int)(a1))
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
 
 	// Decode the results.
 	dec := codegen.NewDecoder(results)
-	r0 = dec.Int()
+	r0 = dec.String()
 	err = dec.Error()
 	return
 }
 
-func (s testApp_client_stub) IncPointer(ctx context.Context, a0 *int) (r0 *int, err error) {
+func (s guarded_client_stub) Public(ctx context.Context, a0 string) (r0 string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
-	begin := s.incPointerMetrics.Begin()
-	defer func() { s.incPointerMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	var transportErr bool
+	begin := s.publicMetrics.Begin()
+	defer func() {
+		s.publicMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
 		// Create a child span for this method.
-		ctx, span = s.stub.Tracer().Start(ctx, "generate.testApp.IncPointer", trace.WithSpanKind(trace.SpanKindClient))
+		ctx, span = s.stub.Tracer().Start(ctx, "generate.guarded.Public", trace.WithSpanKind(trace.SpanKindClient))
 	}
 
 	defer func() {
@@ -195,6 +356,7 @@ func (s testApp_client_stub) IncPointer(ctx context.Context, a0 *int) (r0 *int,
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -209,103 +371,1559 @@ func (s testApp_client_stub) IncPointer(ctx context.Context, a0 *int) (r0 *int,
 
 	// Preallocate a buffer of the right size.
 	size := 0
-	size += serviceweaver_size_ptr_int_98a2a745(a0)
+	size += (4 + len(a0))
 	enc := codegen.NewEncoder()
 	enc.Reset(size)
 
 	// Encode arguments.
-	serviceweaver_enc_ptr_int_98a2a745(enc, a0)
+	enc.String(a0)
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
 
 	// Decode the results.
 	dec := codegen.NewDecoder(results)
-	r0 = serviceweaver_dec_ptr_int_98a2a745(dec)
+	r0 = dec.String()
 	err = dec.Error()
 	return
 }
 
-// Server stub implementations.
-
-type testApp_server_stub struct {
-	impl    testApp
-	addLoad func(key uint64, load float64)
+type streamer_client_stub struct {
+	stub        codegen.Stub
+	rowsMetrics *codegen.MethodMetrics
 }
 
-// Check that testApp_server_stub implements the codegen.Server interface.
-var _ codegen.Server = (*testApp_server_stub)(nil)
+// Check that streamer_client_stub implements the streamer interface.
+var _ streamer = (*streamer_client_stub)(nil)
 
-// GetStubFn implements the codegen.Server interface.
-func (s testApp_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
-	switch method {
-	case "Get":
-		return s.get
-	case "IncPointer":
-		return s.incPointer
-	default:
-		return nil
+func (s streamer_client_stub) Rows(ctx context.Context, a0 int, a1 int) (r0 weaver.Stream[row], err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.rowsMetrics.Begin()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "generate.streamer.Rows", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	// end records the outcome of the call.
+	end := func(err error) {
+		s.rowsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
 	}
-}
 
-func (s testApp_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
-	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
 		if err == nil {
 			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		// If the remote method started streaming, the call ends once the
+		// caller is done iterating over the stream.
+		if r0 == nil {
+			end(err)
 		}
 	}()
 
-	// Decode arguments.
-	dec := codegen.NewDecoder(args)
-	var a0 string
-	a0 = dec.String()
-	var a1 behaviorType
-	*(*int)(&a1) = dec.Int()
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += 8
+	size += 8
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.Int(a0)
+	enc.Int(a1)
+	var shardKey uint64
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Get(ctx, a0, a1)
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var stream codegen.StreamReader
+	stream, err = s.stub.RunStream(ctx, 0, enc.Data(), shardKey)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
 
-	// Encode the results.
-	enc := codegen.NewEncoder()
-	enc.Int(r0)
-	enc.Error(appErr)
-	return enc.Data(), nil
+	// Wait for the remote method to start streaming.
+	if _, ok := stream.Recv(); !ok {
+		// The remote method returned an error instead.
+		var results []byte
+		results, err = stream.Result()
+		replyBytes = len(results)
+		if err != nil {
+			transportErr = true
+			err = errors.Join(weaver.RemoteCallError, err)
+			return
+		}
+		dec := codegen.NewDecoder(results)
+		err = dec.Error()
+		return
+	}
+
+	// Decode the streamed values.
+	r0 = codegen.ReadStream(stream, func(dec *codegen.Decoder) (x row) {
+		(&x).WeaverUnmarshal(dec)
+		return
+	}, func(n int, results []byte, err error) error {
+		replyBytes += n
+		if err != nil {
+			transportErr = true
+			err = errors.Join(weaver.RemoteCallError, err)
+		} else if results != nil {
+			dec := codegen.NewDecoder(results)
+			err = dec.Error()
+		}
+		end(err)
+		return err
+	})
+	return
 }
 
-func (s testApp_server_stub) incPointer(ctx context.Context, args []byte) (res []byte, err error) {
-	// Catch and return any panics detected during encoding/decoding/rpc.
+type testApp_client_stub struct {
+	stub              codegen.Stub
+	getMetrics        *codegen.MethodMetrics
+	incPointerMetrics *codegen.MethodMetrics
+	renameMetrics     *codegen.MethodMetrics
+}
+
+// Check that testApp_client_stub implements the testApp interface.
+var _ testApp = (*testApp_client_stub)(nil)
+
+func (s testApp_client_stub) Get(ctx context.Context, a0 string, a1 behaviorType) (r0 int, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.getMetrics.Begin()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "generate.testApp.Get", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
 	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
 		if err == nil {
 			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
 		}
-	}()
 
-	// Decode arguments.
-	dec := codegen.NewDecoder(args)
-	var a0 *int
-	a0 = serviceweaver_dec_ptr_int_98a2a745(dec)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.IncPointer(ctx, a0)
+	}()
 
-	// Encode the results.
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	size += 8
 	enc := codegen.NewEncoder()
-	serviceweaver_enc_ptr_int_98a2a745(enc, r0)
-	enc.Error(appErr)
-	return enc.Data(), nil
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	enc.Int((int)(a1))
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.Int()
+	err = dec.Error()
+	return
+}
+
+func (s testApp_client_stub) IncPointer(ctx context.Context, a0 *int) (r0 *int, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.incPointerMetrics.Begin()
+	defer func() {
+		s.incPointerMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "generate.testApp.IncPointer", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += serviceweaver_size_ptr_int_98a2a745(a0)
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	serviceweaver_enc_ptr_int_98a2a745(enc, a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = serviceweaver_dec_ptr_int_98a2a745(dec)
+	err = dec.Error()
+	return
+}
+
+func (s testApp_client_stub) Rename(ctx context.Context, a0 item, a1 string) (r0 item, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.renameMetrics.Begin()
+	defer func() {
+		s.renameMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "generate.testApp.Rename", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Encode arguments.
+	enc := codegen.NewEncoder()
+	(a0).WeaverMarshal(enc)
+	enc.String(a1)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	(&r0).WeaverUnmarshal(dec)
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type guarded_server_stub struct {
+	impl       guarded
+	observer   *guard
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that guarded_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*guarded_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s guarded_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Private":
+		return s.private
+	case "Public":
+		return s.public
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s guarded_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s guarded_server_stub) private(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", "Private", func(ctx context.Context) (err error) {
+		r0, err = s.observer.Private(ctx, s.impl, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s guarded_server_stub) public(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/guarded", "Public", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Public(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type streamer_server_stub struct {
+	impl       streamer
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that streamer_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*streamer_server_stub)(nil)
+
+// Check that streamer_server_stub implements the codegen.StreamServer interface.
+var _ codegen.StreamServer = (*streamer_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s streamer_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	default:
+		return nil
+	}
+}
+
+// GetStreamFn implements the codegen.StreamServer interface.
+func (s streamer_server_stub) GetStreamFn(method string) func(ctx context.Context, args []byte, send func([]byte) error) ([]byte, error) {
+	switch method {
+	case "Rows":
+		return s.rows
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s streamer_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s streamer_server_stub) rows(ctx context.Context, args []byte, send func([]byte) error) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 int
+	a0 = dec.Int()
+	var a1 int
+	a1 = dec.Int()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 weaver.Stream[row]
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/streamer", "Rows", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Rows(ctx, a0, a1)
+		return err
+	})
+	if appErr == nil {
+		// Stream the results.
+		appErr = codegen.WriteStream[row](send, r0, func(enc *codegen.Encoder, x row) {
+			(x).WeaverMarshal(enc)
+		})
+	}
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type testApp_server_stub struct {
+	impl       testApp
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that testApp_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*testApp_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s testApp_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Get":
+		return s.get
+	case "IncPointer":
+		return s.incPointer
+	case "Rename":
+		return s.rename
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s testApp_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s testApp_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+	var a1 behaviorType
+	*(*int)(&a1) = dec.Int()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx, a0, a1)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Int(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s testApp_server_stub) incPointer(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 *int
+	a0 = serviceweaver_dec_ptr_int_98a2a745(dec)
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 *int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", "IncPointer", func(ctx context.Context) (err error) {
+		r0, err = s.impl.IncPointer(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	serviceweaver_enc_ptr_int_98a2a745(enc, r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s testApp_server_stub) rename(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 item
+	(&a0).WeaverUnmarshal(dec)
+	var a1 string
+	a1 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 item
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/generate/testApp", "Rename", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Rename(ctx, a0, a1)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	(r0).WeaverMarshal(enc)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type guarded_intercept_stub struct {
+	next        guarded
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that guarded_intercept_stub implements the guarded interface.
+var _ guarded = (*guarded_intercept_stub)(nil)
+
+// guarded_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func guarded_intercept(next guarded, interceptor codegen.Interceptor, call codegen.Call) guarded {
+	if interceptor == nil {
+		return next
+	}
+	return guarded_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s guarded_intercept_stub) Private(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Private", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Private(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s guarded_intercept_stub) Public(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Public", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Public(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+type streamer_intercept_stub struct {
+	next        streamer
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that streamer_intercept_stub implements the streamer interface.
+var _ streamer = (*streamer_intercept_stub)(nil)
+
+// streamer_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func streamer_intercept(next streamer, interceptor codegen.Interceptor, call codegen.Call) streamer {
+	if interceptor == nil {
+		return next
+	}
+	return streamer_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s streamer_intercept_stub) Rows(ctx context.Context, a0 int, a1 int) (r0 weaver.Stream[row], err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Rows", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Rows(ctx, codegen.Arg[int](args, 0), codegen.Arg[int](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[weaver.Stream[row]](results, 0), err
+}
+
+type testApp_intercept_stub struct {
+	next        testApp
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that testApp_intercept_stub implements the testApp interface.
+var _ testApp = (*testApp_intercept_stub)(nil)
+
+// testApp_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func testApp_intercept(next testApp, interceptor codegen.Interceptor, call codegen.Call) testApp {
+	if interceptor == nil {
+		return next
+	}
+	return testApp_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s testApp_intercept_stub) Get(ctx context.Context, a0 string, a1 behaviorType) (r0 int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0), codegen.Arg[behaviorType](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[int](results, 0), err
+}
+
+func (s testApp_intercept_stub) IncPointer(ctx context.Context, a0 *int) (r0 *int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "IncPointer", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.IncPointer(ctx, codegen.Arg[*int](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[*int](results, 0), err
+}
+
+func (s testApp_intercept_stub) Rename(ctx context.Context, a0 item, a1 string) (r0 item, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Rename", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Rename(ctx, codegen.Arg[item](args, 0), codegen.Arg[string](args, 1))
+		return []any{r0}, err
+	})
+	return codegen.Result[item](results, 0), err
+}
+
+// AutoMarshal implementations.
+
+var _ codegen.AutoMarshal = (*innerV2)(nil)
+
+type __is_innerV2[T ~struct {
+	weaver.AutoMarshal
+	X float64
+	Y float64
+}] struct{}
+
+var _ __is_innerV2[innerV2]
+
+func (x *innerV2) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("innerV2.WeaverMarshal: nil receiver"))
+	}
+	enc.Float64(x.X)
+	enc.Float64(x.Y)
+}
+
+func (x *innerV2) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("innerV2.WeaverUnmarshal: nil receiver"))
+	}
+	x.X = dec.Float64()
+	x.Y = dec.Float64()
+}
+
+var _ codegen.AutoMarshal = (*item)(nil)
+
+type __is_item[T ~struct {
+	weaver.AutoMarshal
+	ID   uuid.UUID
+	Name string
+}] struct{}
+
+var _ __is_item[item]
+
+func (x *item) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("item.WeaverMarshal: nil receiver"))
+	}
+	codegen.EncodeRegistered(enc, x.ID)
+	enc.String(x.Name)
+}
+
+func (x *item) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("item.WeaverUnmarshal: nil receiver"))
+	}
+	codegen.DecodeRegistered(dec, &x.ID)
+	x.Name = dec.String()
+}
+
+var _ codegen.AutoMarshal = (*lineItem)(nil)
+
+type __is_lineItem[T ~struct {
+	weaver.AutoMarshal
+	Name  string
+	Price float64
+}] struct{}
+
+var _ __is_lineItem[lineItem]
+
+func (x *lineItem) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("lineItem.WeaverMarshal: nil receiver"))
+	}
+	enc.String(x.Name)
+	enc.Float64(x.Price)
+}
+
+func (x *lineItem) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("lineItem.WeaverUnmarshal: nil receiver"))
+	}
+	x.Name = dec.String()
+	x.Price = dec.Float64()
+}
+
+var _ codegen.AutoMarshal = (*node)(nil)
+
+type __is_node[T ~struct {
+	weaver.AutoMarshal
+	Name string
+}] struct{}
+
+var _ __is_node[node]
+
+func (x *node) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("node.WeaverMarshal: nil receiver"))
+	}
+	enc.String(x.Name)
+}
+
+func (x *node) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("node.WeaverUnmarshal: nil receiver"))
+	}
+	x.Name = dec.String()
+}
+
+var _ codegen.AutoMarshal = (*order)(nil)
+
+type __is_order[T ~struct {
+	weaver.AutoMarshal
+	ID       string
+	Tags     []string
+	Counts   map[string]int
+	Items    []lineItem
+	ByName   map[string]lineItem
+	Batches  [][]lineItem
+	Sections map[int]map[string][]lineItem
+	Owners   map[lineItem]bool
+}] struct{}
+
+var _ __is_order[order]
+
+func (x *order) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("order.WeaverMarshal: nil receiver"))
+	}
+	enc.String(x.ID)
+	serviceweaver_enc_slice_string_4af10117(enc, x.Tags)
+	serviceweaver_enc_map_string_int_c20ee031(enc, x.Counts)
+	serviceweaver_enc_slice_lineItem_7d5b17af(enc, x.Items)
+	serviceweaver_enc_map_string_lineItem_425ba6d4(enc, x.ByName)
+	serviceweaver_enc_slice_slice_lineItem_248c1eff(enc, x.Batches)
+	serviceweaver_enc_map_int_map_string_slice_lineItem_7397c6fd(enc, x.Sections)
+	serviceweaver_enc_map_lineItem_bool_0bb16b55(enc, x.Owners)
+}
+
+func (x *order) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("order.WeaverUnmarshal: nil receiver"))
+	}
+	x.ID = dec.String()
+	x.Tags = serviceweaver_dec_slice_string_4af10117(dec)
+	x.Counts = serviceweaver_dec_map_string_int_c20ee031(dec)
+	x.Items = serviceweaver_dec_slice_lineItem_7d5b17af(dec)
+	x.ByName = serviceweaver_dec_map_string_lineItem_425ba6d4(dec)
+	x.Batches = serviceweaver_dec_slice_slice_lineItem_248c1eff(dec)
+	x.Sections = serviceweaver_dec_map_int_map_string_slice_lineItem_7397c6fd(dec)
+	x.Owners = serviceweaver_dec_map_lineItem_bool_0bb16b55(dec)
+}
+
+func serviceweaver_enc_slice_string_4af10117(enc *codegen.Encoder, arg []string) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		enc.String(arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_string_4af10117(dec *codegen.Decoder) []string {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]string, n)
+	for i := 0; i < n; i++ {
+		res[i] = dec.String()
+	}
+	return res
+}
+
+func serviceweaver_enc_map_string_int_c20ee031(enc *codegen.Encoder, arg map[string]int) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for _, k := range codegen.SortedKeys(arg) {
+		v := arg[k]
+		enc.String(k)
+		enc.Int(v)
+	}
+}
+
+func serviceweaver_dec_map_string_int_c20ee031(dec *codegen.Decoder) map[string]int {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[string]int, n)
+	var k string
+	var v int
+	for i := 0; i < n; i++ {
+		k = dec.String()
+		v = dec.Int()
+		res[k] = v
+	}
+	return res
+}
+
+func serviceweaver_enc_slice_lineItem_7d5b17af(enc *codegen.Encoder, arg []lineItem) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		(arg[i]).WeaverMarshal(enc)
+	}
+}
+
+func serviceweaver_dec_slice_lineItem_7d5b17af(dec *codegen.Decoder) []lineItem {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]lineItem, n)
+	for i := 0; i < n; i++ {
+		(&res[i]).WeaverUnmarshal(dec)
+	}
+	return res
+}
+
+func serviceweaver_enc_map_string_lineItem_425ba6d4(enc *codegen.Encoder, arg map[string]lineItem) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for _, k := range codegen.SortedKeys(arg) {
+		v := arg[k]
+		enc.String(k)
+		(v).WeaverMarshal(enc)
+	}
+}
+
+func serviceweaver_dec_map_string_lineItem_425ba6d4(dec *codegen.Decoder) map[string]lineItem {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[string]lineItem, n)
+	var k string
+	var v lineItem
+	for i := 0; i < n; i++ {
+		k = dec.String()
+		(&v).WeaverUnmarshal(dec)
+		res[k] = v
+	}
+	return res
+}
+
+func serviceweaver_enc_slice_slice_lineItem_248c1eff(enc *codegen.Encoder, arg [][]lineItem) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		serviceweaver_enc_slice_lineItem_7d5b17af(enc, arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_slice_lineItem_248c1eff(dec *codegen.Decoder) [][]lineItem {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([][]lineItem, n)
+	for i := 0; i < n; i++ {
+		res[i] = serviceweaver_dec_slice_lineItem_7d5b17af(dec)
+	}
+	return res
+}
+
+func serviceweaver_enc_map_string_slice_lineItem_f5f83d87(enc *codegen.Encoder, arg map[string][]lineItem) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for _, k := range codegen.SortedKeys(arg) {
+		v := arg[k]
+		enc.String(k)
+		serviceweaver_enc_slice_lineItem_7d5b17af(enc, v)
+	}
+}
+
+func serviceweaver_dec_map_string_slice_lineItem_f5f83d87(dec *codegen.Decoder) map[string][]lineItem {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[string][]lineItem, n)
+	var k string
+	var v []lineItem
+	for i := 0; i < n; i++ {
+		k = dec.String()
+		v = serviceweaver_dec_slice_lineItem_7d5b17af(dec)
+		res[k] = v
+	}
+	return res
+}
+
+func serviceweaver_enc_map_int_map_string_slice_lineItem_7397c6fd(enc *codegen.Encoder, arg map[int]map[string][]lineItem) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for _, k := range codegen.SortedKeys(arg) {
+		v := arg[k]
+		enc.Int(k)
+		serviceweaver_enc_map_string_slice_lineItem_f5f83d87(enc, v)
+	}
+}
+
+func serviceweaver_dec_map_int_map_string_slice_lineItem_7397c6fd(dec *codegen.Decoder) map[int]map[string][]lineItem {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[int]map[string][]lineItem, n)
+	var k int
+	var v map[string][]lineItem
+	for i := 0; i < n; i++ {
+		k = dec.Int()
+		v = serviceweaver_dec_map_string_slice_lineItem_f5f83d87(dec)
+		res[k] = v
+	}
+	return res
+}
+
+func serviceweaver_enc_map_lineItem_bool_0bb16b55(enc *codegen.Encoder, arg map[lineItem]bool) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for k, v := range arg {
+		(k).WeaverMarshal(enc)
+		enc.Bool(v)
+	}
+}
+
+func serviceweaver_dec_map_lineItem_bool_0bb16b55(dec *codegen.Decoder) map[lineItem]bool {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[lineItem]bool, n)
+	var k lineItem
+	var v bool
+	for i := 0; i < n; i++ {
+		(&k).WeaverUnmarshal(dec)
+		v = dec.Bool()
+		res[k] = v
+	}
+	return res
+}
+
+var _ codegen.AutoMarshal = (*pointers)(nil)
+
+type __is_pointers[T ~struct {
+	weaver.AutoMarshal
+	Node   *node
+	Other  *node
+	Chain  **node
+	Deep   ********node
+	Nodes  []*node
+	ByName map[string]*node
+}] struct{}
+
+var _ __is_pointers[pointers]
+
+func (x *pointers) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("pointers.WeaverMarshal: nil receiver"))
+	}
+	serviceweaver_enc_ptr_node_b5522f43(enc, x.Node)
+	serviceweaver_enc_ptr_node_b5522f43(enc, x.Other)
+	serviceweaver_enc_ptr_ptr_node_62ae24f6(enc, x.Chain)
+	serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_61abbf40(enc, x.Deep)
+	serviceweaver_enc_slice_ptr_node_ca92c72a(enc, x.Nodes)
+	serviceweaver_enc_map_string_ptr_node_14821142(enc, x.ByName)
+}
+
+func (x *pointers) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("pointers.WeaverUnmarshal: nil receiver"))
+	}
+	x.Node = serviceweaver_dec_ptr_node_b5522f43(dec)
+	x.Other = serviceweaver_dec_ptr_node_b5522f43(dec)
+	x.Chain = serviceweaver_dec_ptr_ptr_node_62ae24f6(dec)
+	x.Deep = serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_61abbf40(dec)
+	x.Nodes = serviceweaver_dec_slice_ptr_node_ca92c72a(dec)
+	x.ByName = serviceweaver_dec_map_string_ptr_node_14821142(dec)
+}
+
+func serviceweaver_enc_ptr_node_b5522f43(enc *codegen.Encoder, arg *node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		(*arg).WeaverMarshal(enc)
+	}
+}
+
+func serviceweaver_dec_ptr_node_b5522f43(dec *codegen.Decoder) *node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res node
+	(&res).WeaverUnmarshal(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_node_62ae24f6(enc *codegen.Encoder, arg **node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_node_b5522f43(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_node_62ae24f6(dec *codegen.Decoder) **node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res *node
+	res = serviceweaver_dec_ptr_node_b5522f43(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_ptr_node_8a5149fd(enc *codegen.Encoder, arg ***node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_ptr_node_62ae24f6(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_ptr_node_8a5149fd(dec *codegen.Decoder) ***node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res **node
+	res = serviceweaver_dec_ptr_ptr_node_62ae24f6(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_ptr_ptr_node_ecdcb174(enc *codegen.Encoder, arg ****node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_ptr_ptr_node_8a5149fd(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_ptr_ptr_node_ecdcb174(dec *codegen.Decoder) ****node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res ***node
+	res = serviceweaver_dec_ptr_ptr_ptr_node_8a5149fd(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_node_15698d10(enc *codegen.Encoder, arg *****node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_ptr_ptr_ptr_node_ecdcb174(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_node_15698d10(dec *codegen.Decoder) *****node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res ****node
+	res = serviceweaver_dec_ptr_ptr_ptr_ptr_node_ecdcb174(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_ptr_node_64dd8cac(enc *codegen.Encoder, arg ******node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_node_15698d10(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_ptr_node_64dd8cac(dec *codegen.Decoder) ******node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res *****node
+	res = serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_node_15698d10(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_2139afc7(enc *codegen.Encoder, arg *******node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_ptr_node_64dd8cac(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_2139afc7(dec *codegen.Decoder) *******node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res ******node
+	res = serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_ptr_node_64dd8cac(dec)
+	return &res
+}
+
+func serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_61abbf40(enc *codegen.Encoder, arg ********node) {
+	if arg == nil {
+		enc.Bool(false)
+	} else {
+		enc.Bool(true)
+		serviceweaver_enc_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_2139afc7(enc, *arg)
+	}
+}
+
+func serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_61abbf40(dec *codegen.Decoder) ********node {
+	if !dec.Bool() {
+		return nil
+	}
+	var res *******node
+	res = serviceweaver_dec_ptr_ptr_ptr_ptr_ptr_ptr_ptr_node_2139afc7(dec)
+	return &res
+}
+
+func serviceweaver_enc_slice_ptr_node_ca92c72a(enc *codegen.Encoder, arg []*node) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		serviceweaver_enc_ptr_node_b5522f43(enc, arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_ptr_node_ca92c72a(dec *codegen.Decoder) []*node {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]*node, n)
+	for i := 0; i < n; i++ {
+		res[i] = serviceweaver_dec_ptr_node_b5522f43(dec)
+	}
+	return res
+}
+
+func serviceweaver_enc_map_string_ptr_node_14821142(enc *codegen.Encoder, arg map[string]*node) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for _, k := range codegen.SortedKeys(arg) {
+		v := arg[k]
+		enc.String(k)
+		serviceweaver_enc_ptr_node_b5522f43(enc, v)
+	}
+}
+
+func serviceweaver_dec_map_string_ptr_node_14821142(dec *codegen.Decoder) map[string]*node {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[string]*node, n)
+	var k string
+	var v *node
+	for i := 0; i < n; i++ {
+		k = dec.String()
+		v = serviceweaver_dec_ptr_node_b5522f43(dec)
+		res[k] = v
+	}
+	return res
+}
+
+var _ codegen.AutoMarshal = (*recordV1)(nil)
+
+type __is_recordV1[T ~struct {
+	weaver.AutoMarshal "weaver:\"versioned\""
+	Name               string
+	Count              int
+}] struct{}
+
+var _ __is_recordV1[recordV1]
+
+func (x *recordV1) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV1.WeaverMarshal: nil receiver"))
+	}
+	enc.Len(2)
+	start := enc.BeginField("Name")
+	enc.String(x.Name)
+	enc.EndField(start)
+	start = enc.BeginField("Count")
+	enc.Int(x.Count)
+	enc.EndField(start)
+}
+
+func (x *recordV1) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV1.WeaverUnmarshal: nil receiver"))
+	}
+	*x = recordV1{}
+	for n := dec.Len(); n > 0; n-- {
+		name, fdec := dec.Field()
+		switch name {
+		case "Name":
+			x.Name = fdec.String()
+		case "Count":
+			x.Count = fdec.Int()
+		}
+	}
+}
+
+var _ codegen.AutoMarshal = (*recordV2)(nil)
+
+type __is_recordV2[T ~struct {
+	weaver.AutoMarshal "weaver:\"versioned\""
+	Name               string
+	Count              int
+	Tags               []string
+	Inner              innerV2
+}] struct{}
+
+var _ __is_recordV2[recordV2]
+
+func (x *recordV2) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV2.WeaverMarshal: nil receiver"))
+	}
+	enc.Len(4)
+	start := enc.BeginField("Name")
+	enc.String(x.Name)
+	enc.EndField(start)
+	start = enc.BeginField("Count")
+	enc.Int(x.Count)
+	enc.EndField(start)
+	start = enc.BeginField("Tags")
+	serviceweaver_enc_slice_string_4af10117(enc, x.Tags)
+	enc.EndField(start)
+	start = enc.BeginField("Inner")
+	(x.Inner).WeaverMarshal(enc)
+	enc.EndField(start)
+}
+
+func (x *recordV2) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV2.WeaverUnmarshal: nil receiver"))
+	}
+	*x = recordV2{}
+	for n := dec.Len(); n > 0; n-- {
+		name, fdec := dec.Field()
+		switch name {
+		case "Name":
+			x.Name = fdec.String()
+		case "Count":
+			x.Count = fdec.Int()
+		case "Tags":
+			x.Tags = serviceweaver_dec_slice_string_4af10117(fdec)
+		case "Inner":
+			(&x.Inner).WeaverUnmarshal(fdec)
+		}
+	}
+}
+
+var _ codegen.AutoMarshal = (*recordV3)(nil)
+
+type __is_recordV3[T ~struct {
+	weaver.AutoMarshal "weaver:\"versioned\""
+	Inner              innerV2
+	Tags               []string
+	Count              int
+	Name               string
+}] struct{}
+
+var _ __is_recordV3[recordV3]
+
+func (x *recordV3) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV3.WeaverMarshal: nil receiver"))
+	}
+	enc.Len(4)
+	start := enc.BeginField("Inner")
+	(x.Inner).WeaverMarshal(enc)
+	enc.EndField(start)
+	start = enc.BeginField("Tags")
+	serviceweaver_enc_slice_string_4af10117(enc, x.Tags)
+	enc.EndField(start)
+	start = enc.BeginField("Count")
+	enc.Int(x.Count)
+	enc.EndField(start)
+	start = enc.BeginField("Name")
+	enc.String(x.Name)
+	enc.EndField(start)
+}
+
+func (x *recordV3) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV3.WeaverUnmarshal: nil receiver"))
+	}
+	*x = recordV3{}
+	for n := dec.Len(); n > 0; n-- {
+		name, fdec := dec.Field()
+		switch name {
+		case "Inner":
+			(&x.Inner).WeaverUnmarshal(fdec)
+		case "Tags":
+			x.Tags = serviceweaver_dec_slice_string_4af10117(fdec)
+		case "Count":
+			x.Count = fdec.Int()
+		case "Name":
+			x.Name = fdec.String()
+		}
+	}
+}
+
+var _ codegen.AutoMarshal = (*recordV4)(nil)
+
+type __is_recordV4[T ~struct {
+	weaver.AutoMarshal "weaver:\"versioned\""
+	Title              string "weaver:\"Name\""
+	Count              int
+	summary            string "weaver:\"-\""
+}] struct{}
+
+var _ __is_recordV4[recordV4]
+
+func (x *recordV4) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV4.WeaverMarshal: nil receiver"))
+	}
+	enc.Len(2)
+	start := enc.BeginField("Name")
+	enc.String(x.Title)
+	enc.EndField(start)
+	start = enc.BeginField("Count")
+	enc.Int(x.Count)
+	enc.EndField(start)
+}
+
+func (x *recordV4) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("recordV4.WeaverUnmarshal: nil receiver"))
+	}
+	*x = recordV4{}
+	for n := dec.Len(); n > 0; n-- {
+		name, fdec := dec.Field()
+		switch name {
+		case "Name":
+			x.Title = fdec.String()
+		case "Count":
+			x.Count = fdec.Int()
+		}
+	}
+}
+
+var _ codegen.AutoMarshal = (*row)(nil)
+
+type __is_row[T ~struct {
+	weaver.AutoMarshal
+	Index   int
+	Payload string
+}] struct{}
+
+var _ __is_row[row]
+
+func (x *row) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("row.WeaverMarshal: nil receiver"))
+	}
+	enc.Int(x.Index)
+	enc.String(x.Payload)
+}
+
+func (x *row) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("row.WeaverUnmarshal: nil receiver"))
+	}
+	x.Index = dec.Int()
+	x.Payload = dec.String()
+}
+
+var _ codegen.AutoMarshal = (*session)(nil)
+
+type __is_session[T ~struct {
+	weaver.AutoMarshal
+	ID     string
+	Scores []int
+	total  int             "weaver:\"-\""
+	seen   map[string]bool "weaver:\"-\""
+}] struct{}
+
+var _ __is_session[session]
+
+func (x *session) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("session.WeaverMarshal: nil receiver"))
+	}
+	enc.String(x.ID)
+	serviceweaver_enc_slice_int_7c8c8866(enc, x.Scores)
+}
+
+func (x *session) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("session.WeaverUnmarshal: nil receiver"))
+	}
+	*x = session{}
+	x.ID = dec.String()
+	x.Scores = serviceweaver_dec_slice_int_7c8c8866(dec)
+}
+
+func serviceweaver_enc_slice_int_7c8c8866(enc *codegen.Encoder, arg []int) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		enc.Int(arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_int_7c8c8866(dec *codegen.Decoder) []int {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]int, n)
+	for i := 0; i < n; i++ {
+		res[i] = dec.Int()
+	}
+	return res
 }
 
 // Encoding/decoding implementations.
@@ -0,0 +1,65 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package intercept contains components used to test interceptors.
+package intercept
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that calls B.
+type A interface {
+	Greet(ctx context.Context, name string) (string, error)
+}
+
+// B is a component that intercepts the calls it receives. It requires every
+// call to carry a token in its metadata.
+type B interface {
+	Hello(ctx context.Context, name string) (string, error)
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+func (a *a) Greet(ctx context.Context, name string) (string, error) {
+	return a.b.Get().Hello(ctx, name)
+}
+
+func (b *b) Hello(ctx context.Context, name string) (string, error) {
+	if weaver.Metadata(ctx)["token"] == "" {
+		return "", fmt.Errorf("missing token")
+	}
+	return "hello " + name, nil
+}
+
+// Interceptors implements the weaver.Interceptable interface.
+func (b *b) Interceptors() []weaver.Interceptor {
+	return []weaver.Interceptor{upper}
+}
+
+// upper upper-cases the name passed to B.Hello.
+func upper(ctx context.Context, call weaver.Call, next weaver.Handler) ([]any, error) {
+	return next(ctx, []any{strings.ToUpper(call.Args[0].(string))})
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intercept_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/intercept"
+)
+
+const prefix = "github.com/ServiceWeaver/weaver/weavertest/internal/intercept/"
+
+var (
+	mu    sync.Mutex
+	calls []string // calls seen by the client interceptor in this process
+)
+
+func TestMain(m *testing.M) {
+	// Record every call and attach a token to it.
+	weaver.InterceptClient(func(ctx context.Context, call weaver.Call, next weaver.Handler) ([]any, error) {
+		mu.Lock()
+		calls = append(calls, call.Caller+" -> "+call.Component+"."+call.Method)
+		mu.Unlock()
+		return next(weaver.SetMetadata(ctx, "token", "secret"), call.Args)
+	})
+
+	// Answer calls to B.Hello for "mock" without calling B.
+	weaver.InterceptServer(func(ctx context.Context, call weaver.Call, next weaver.Handler) ([]any, error) {
+		if call.Component == prefix+"B" && call.Args[0] == "mock" {
+			return []any{"mocked"}, nil
+		}
+		return next(ctx, call.Args)
+	})
+	os.Exit(m.Run())
+}
+
+func TestInterceptors(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a intercept.A) {
+			mu.Lock()
+			calls = nil
+			mu.Unlock()
+
+			ctx := context.Background()
+			for _, test := range []struct{ name, want string }{
+				{"world", "hello WORLD"}, // modified by B's interceptor
+				{"mock", "mocked"},       // answered by the server interceptor
+			} {
+				got, err := a.Greet(ctx, test.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != test.want {
+					t.Errorf("Greet(%q): got %q, want %q", test.name, got, test.want)
+				}
+			}
+
+			// Components may run in other processes, in which case their
+			// calls are recorded there. Only check the calls that are
+			// recorded here.
+			mu.Lock()
+			defer mu.Unlock()
+			var greets int
+			for _, call := range calls {
+				switch call {
+				case t.Name() + " -> " + prefix + "A.Greet":
+					greets++
+				case prefix + "A -> " + prefix + "B.Hello":
+				default:
+					t.Errorf("unexpected call %q", call)
+				}
+			}
+			if greets != 2 {
+				t.Errorf("got %d calls to A.Greet, want 2: %v", greets, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lifecycle contains components used to test weaver.Initializable
+// and weaver.Finalizable.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+var (
+	mu     sync.Mutex
+	events []string
+)
+
+// record records a lifecycle event.
+func record(event string) {
+	mu.Lock()
+	defer mu.Unlock()
+	events = append(events, event)
+}
+
+// Events returns and clears the recorded lifecycle events.
+func Events() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	result := events
+	events = nil
+	return result
+}
+
+// A is a component that depends on B.
+type A interface {
+	Ping(context.Context) error
+}
+
+// B is a component.
+type B interface {
+	Ping(context.Context) error
+}
+
+// Flaky is a component whose first Init in every process fails.
+type Flaky interface {
+	Ping(context.Context) error
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+type flaky struct {
+	weaver.Implements[Flaky]
+}
+
+var (
+	_ weaver.Initializable = &a{}
+	_ weaver.Finalizable   = &a{}
+	_ weaver.Initializable = &b{}
+	_ weaver.Finalizable   = &b{}
+	_ weaver.Initializable = &flaky{}
+)
+
+// flakyInits is the number of times flaky.Init has been called.
+var flakyInits int
+
+func (a *a) Init(context.Context) error     { record("init A"); return nil }
+func (a *a) Shutdown(context.Context) error { record("shutdown A"); return nil }
+func (a *a) Ping(ctx context.Context) error { return a.b.Get().Ping(ctx) }
+func (b *b) Init(context.Context) error     { record("init B"); return nil }
+func (b *b) Shutdown(context.Context) error { record("shutdown B"); return nil }
+func (b *b) Ping(context.Context) error     { return nil }
+
+func (f *flaky) Init(context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	flakyInits++
+	if flakyInits == 1 {
+		return errors.New("flaky: first Init fails")
+	}
+	return nil
+}
+
+func (f *flaky) Ping(context.Context) error { return nil }
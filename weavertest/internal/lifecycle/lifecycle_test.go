@@ -0,0 +1,109 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/lifecycle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLifecycle(t *testing.T) {
+	// Only the runners that host every component in the test process shut
+	// down every component.
+	for _, runner := range []weavertest.Runner{weavertest.Local, weavertest.RPC} {
+		lifecycle.Events()
+		t.Run(runner.Name, func(t *testing.T) {
+			runner.Test(t, func(t *testing.T, a lifecycle.A) {
+				if err := a.Ping(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			})
+		})
+		// A depends on B, so A is shut down before B. Note that with the RPC
+		// runner, A's Init may run before B's.
+		events := lifecycle.Events()
+		if len(events) != 4 {
+			t.Fatalf("%s: got events %v, want 4 events", runner.Name, events)
+		}
+		inits := []string{events[0], events[1]}
+		sort.Strings(inits)
+		if diff := cmp.Diff([]string{"init A", "init B"}, inits); diff != "" {
+			t.Errorf("%s: init events (-want +got):\n%s", runner.Name, diff)
+		}
+		want := []string{"shutdown A", "shutdown B"}
+		if diff := cmp.Diff(want, events[2:]); diff != "" {
+			t.Errorf("%s: events (-want +got):\n%s", runner.Name, diff)
+		}
+	}
+}
+
+func TestRestart(t *testing.T) {
+	// With the RPC runner, the test calls Flaky remotely, so a failed Init
+	// fails the call, rather than the test.
+	weavertest.RPC.Test(t, func(t *testing.T, f lifecycle.Flaky) {
+		var err error
+		for i := 0; i < 3; i++ {
+			if err = f.Ping(context.Background()); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+
+		const name = "github.com/ServiceWeaver/weaver/weavertest/internal/lifecycle/Flaky"
+		var restarts, last float64
+		for _, m := range metrics.Snapshot() {
+			if m.Labels["component"] != name {
+				continue
+			}
+			switch m.Name {
+			case "serviceweaver_component_restart_total":
+				restarts = m.Value
+			case "serviceweaver_component_last_restart_unix":
+				last = m.Value
+			}
+		}
+		if restarts != 1 {
+			t.Errorf("got %v restarts, want 1", restarts)
+		}
+		if last <= 0 {
+			t.Errorf("got last restart %v, want a Unix time", last)
+		}
+	})
+}
+
+func TestEager(t *testing.T) {
+	// A is eager, so it is created before the test runs, even though the test
+	// never uses it, after B, which it depends on.
+	lifecycle.Events()
+	runner := weavertest.Local
+	runner.Config = `
+["github.com/ServiceWeaver/weaver/weavertest/internal/lifecycle/A"]
+eager = true
+`
+	runner.Test(t, func(t *testing.T, b lifecycle.B) {
+		want := []string{"init B", "init A"}
+		if diff := cmp.Diff(want, lifecycle.Events()); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mock contains components used to test the mocks generated by
+// 'weaver generate -mocks'.
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+//go:generate ../../../cmd/weaver/weaver generate -mocks
+
+// Cache is a key-value cache.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key, value string) error
+}
+
+// Frontend greets users by the names stored in a Cache.
+type Frontend interface {
+	Greet(ctx context.Context, user string) (string, error)
+}
+
+type cache struct {
+	weaver.Implements[Cache]
+}
+
+func (*cache) Get(context.Context, string) (string, error) { return "", nil }
+func (*cache) Put(context.Context, string, string) error   { return nil }
+
+type frontend struct {
+	weaver.Implements[Frontend]
+	cache weaver.Ref[Cache]
+}
+
+func (f *frontend) Greet(ctx context.Context, user string) (string, error) {
+	name, err := f.cache.Get().Get(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Hello, %s!", name), nil
+}
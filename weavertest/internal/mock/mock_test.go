@@ -0,0 +1,62 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGreetWithMockCache(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		// Frontend gets its Cache through a weaver.Ref[Cache]. Faking Cache
+		// makes the Ref dispatch to the mock.
+		cache := &mock.MockCache{
+			GetFunc: func(_ context.Context, key string) (string, error) {
+				if key == "alice" {
+					return "Alice", nil
+				}
+				return "", errors.New("not found")
+			},
+		}
+		runner.Fakes = append(runner.Fakes, weavertest.Fake[mock.Cache](cache))
+		runner.Test(t, func(t *testing.T, f mock.Frontend) {
+			ctx := context.Background()
+			got, err := f.Greet(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "Hello, Alice!"; got != want {
+				t.Errorf("Greet: got %q, want %q", got, want)
+			}
+			if _, err := f.Greet(ctx, "bob"); err == nil {
+				t.Error("Greet: unexpected success for unknown user")
+			}
+
+			want := []mock.MockCacheGetCall{{Key: "alice"}, {Key: "bob"}}
+			if diff := cmp.Diff(want, cache.GetCalls()); diff != "" {
+				t.Errorf("GetCalls (-want +got):\n%s", diff)
+			}
+			if n := len(cache.PutCalls()); n != 0 {
+				t.Errorf("got %d Put calls, want 0", n)
+			}
+		})
+	}
+}
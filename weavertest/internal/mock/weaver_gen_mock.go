@@ -0,0 +1,112 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package mock
+
+import (
+	"context"
+	"sync"
+)
+
+// MockCache is a mock implementation of the Cache component interface. Set the
+// <Method>Func field of a method to control its behavior. Calling a method
+// whose <Method>Func field is nil panics. A MockCache can be passed to
+// weavertest.Fake to replace the Cache component in tests.
+type MockCache struct {
+	GetFunc func(ctx context.Context, key string) (string, error)
+	PutFunc func(ctx context.Context, key string, value string) error
+
+	mu       sync.Mutex // guards the following fields
+	getCalls []MockCacheGetCall
+	putCalls []MockCachePutCall
+}
+
+// Check that MockCache implements the Cache interface.
+var _ Cache = (*MockCache)(nil)
+
+// MockCacheGetCall records the arguments of a call to MockCache.Get.
+type MockCacheGetCall struct {
+	Key string
+}
+
+// Get calls m.GetFunc and records the call.
+func (m *MockCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	m.getCalls = append(m.getCalls, MockCacheGetCall{Key: key})
+	m.mu.Unlock()
+	if m.GetFunc == nil {
+		panic("MockCache.Get called, but MockCache.GetFunc is nil; set GetFunc to mock Get")
+	}
+	return m.GetFunc(ctx, key)
+}
+
+// GetCalls returns the arguments of all calls to Get, in the order in which
+// they were made.
+func (m *MockCache) GetCalls() []MockCacheGetCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCacheGetCall(nil), m.getCalls...)
+}
+
+// MockCachePutCall records the arguments of a call to MockCache.Put.
+type MockCachePutCall struct {
+	Key   string
+	Value string
+}
+
+// Put calls m.PutFunc and records the call.
+func (m *MockCache) Put(ctx context.Context, key string, value string) error {
+	m.mu.Lock()
+	m.putCalls = append(m.putCalls, MockCachePutCall{Key: key, Value: value})
+	m.mu.Unlock()
+	if m.PutFunc == nil {
+		panic("MockCache.Put called, but MockCache.PutFunc is nil; set PutFunc to mock Put")
+	}
+	return m.PutFunc(ctx, key, value)
+}
+
+// PutCalls returns the arguments of all calls to Put, in the order in which
+// they were made.
+func (m *MockCache) PutCalls() []MockCachePutCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCachePutCall(nil), m.putCalls...)
+}
+
+// MockFrontend is a mock implementation of the Frontend component interface. Set the
+// <Method>Func field of a method to control its behavior. Calling a method
+// whose <Method>Func field is nil panics. A MockFrontend can be passed to
+// weavertest.Fake to replace the Frontend component in tests.
+type MockFrontend struct {
+	GreetFunc func(ctx context.Context, user string) (string, error)
+
+	mu         sync.Mutex // guards the following fields
+	greetCalls []MockFrontendGreetCall
+}
+
+// Check that MockFrontend implements the Frontend interface.
+var _ Frontend = (*MockFrontend)(nil)
+
+// MockFrontendGreetCall records the arguments of a call to MockFrontend.Greet.
+type MockFrontendGreetCall struct {
+	User string
+}
+
+// Greet calls m.GreetFunc and records the call.
+func (m *MockFrontend) Greet(ctx context.Context, user string) (string, error) {
+	m.mu.Lock()
+	m.greetCalls = append(m.greetCalls, MockFrontendGreetCall{User: user})
+	m.mu.Unlock()
+	if m.GreetFunc == nil {
+		panic("MockFrontend.Greet called, but MockFrontend.GreetFunc is nil; set GreetFunc to mock Greet")
+	}
+	return m.GreetFunc(ctx, user)
+}
+
+// GreetCalls returns the arguments of all calls to Greet, in the order in which
+// they were made.
+func (m *MockFrontend) GreetCalls() []MockFrontendGreetCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockFrontendGreetCall(nil), m.greetCalls...)
+}
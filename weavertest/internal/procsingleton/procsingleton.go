@@ -0,0 +1,81 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package procsingleton contains components used to test that there is at
+// most one instance of a weaver.Singleton component per process.
+package procsingleton
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+//go:generate ../../../cmd/weaver/weaver generate
+
+// live is the number of live device instances in this process.
+var live atomic.Int64
+
+// Device is a singleton component.
+type Device interface {
+	// Live returns the number of live Device instances in the process.
+	Live(context.Context) (int, error)
+}
+
+type device struct {
+	weaver.Implements[Device]
+	weaver.Singleton
+}
+
+func (d *device) Init(context.Context) error {
+	live.Add(1)
+	return nil
+}
+
+func (d *device) Shutdown(context.Context) error {
+	live.Add(-1)
+	return nil
+}
+
+func (d *device) Live(context.Context) (int, error) {
+	return int(live.Load()), nil
+}
+
+// A and B are components that share Device.
+type A interface {
+	Live(context.Context) (int, error)
+}
+
+type B interface {
+	Live(context.Context) (int, error)
+}
+
+type a struct {
+	weaver.Implements[A]
+	device weaver.Ref[Device]
+}
+
+func (a *a) Live(ctx context.Context) (int, error) {
+	return a.device.Get().Live(ctx)
+}
+
+type b struct {
+	weaver.Implements[B]
+	device weaver.Ref[Device]
+}
+
+func (b *b) Live(ctx context.Context) (int, error) {
+	return b.device.Get().Live(ctx)
+}
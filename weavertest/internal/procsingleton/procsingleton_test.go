@@ -0,0 +1,76 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package procsingleton_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/private"
+	"github.com/ServiceWeaver/weaver/runtime"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/procsingleton"
+)
+
+func TestSingleton(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a procsingleton.A, b procsingleton.B) {
+			ctx := context.Background()
+			for name, c := range map[string]interface {
+				Live(context.Context) (int, error)
+			}{"A": a, "B": b} {
+				n, err := c.Live(ctx)
+				if err != nil {
+					t.Fatalf("%s.Live: %v", name, err)
+				}
+				if n != 1 {
+					t.Errorf("%s.Live: got %d instances, want 1", name, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSecondInstance(t *testing.T) {
+	ctx := context.WithValue(context.Background(), runtime.BootstrapKey{}, runtime.Bootstrap{Quiet: true})
+	device := reflect.TypeOf((*procsingleton.Device)(nil)).Elem()
+	weavertest.Local.Test(t, func(t *testing.T, d procsingleton.Device) {
+		// A second application in the same process can't create Device.
+		app, err := private.Start(ctx, private.AppOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer app.Shutdown(ctx)
+		_, err = app.Get("test", device)
+		if err == nil || !strings.Contains(err.Error(), "weaver.Singleton") {
+			t.Fatalf("Get: got %v, want weaver.Singleton error", err)
+		}
+		if n, err := d.Live(ctx); err != nil || n != 1 {
+			t.Fatalf("Live: got %d, %v, want 1, nil", n, err)
+		}
+	})
+
+	// Once the first application shuts down, Device can be created again.
+	app, err := private.Start(ctx, private.AppOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(ctx)
+	if _, err := app.Get("test", device); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+}
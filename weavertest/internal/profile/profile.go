@@ -0,0 +1,76 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profile contains components used to test weaver.Profile.
+package profile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that profiles itself and B.
+type A interface {
+	// Profile returns the merged profile of every replica of the component
+	// with the provided name.
+	Profile(ctx context.Context, component string, opts Options) ([]byte, error)
+
+	// PprofAddresses returns the addresses on which A and B serve the pprof
+	// handlers.
+	PprofAddresses(context.Context) (string, string, error)
+}
+
+// B is a component.
+type B interface {
+	PprofAddress(context.Context) (string, error)
+}
+
+// Options is a serializable copy of weaver.ProfileOptions.
+type Options struct {
+	weaver.AutoMarshal
+	Type       string
+	DurationMs int
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+func (a *a) Profile(ctx context.Context, component string, opts Options) ([]byte, error) {
+	// Make sure that B has a replica before profiling it.
+	if _, err := a.b.Get().PprofAddress(ctx); err != nil {
+		return nil, fmt.Errorf("reach B: %w", err)
+	}
+	return weaver.Profile(ctx, component, weaver.ProfileOptions{
+		Type:     opts.Type,
+		Duration: time.Duration(opts.DurationMs) * time.Millisecond,
+	})
+}
+
+func (a *a) PprofAddresses(ctx context.Context) (string, string, error) {
+	addr, err := a.b.Get().PprofAddress(ctx)
+	return a.Runtime().PprofAddress, addr, err
+}
+
+func (b *b) PprofAddress(context.Context) (string, error) {
+	return b.Runtime().PprofAddress, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/profile"
+	pprof "github.com/google/pprof/profile"
+)
+
+const prefix = "github.com/ServiceWeaver/weaver/weavertest/internal/profile/"
+
+func TestProfile(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a profile.A) {
+			ctx := context.Background()
+			for _, component := range []string{"A", "B"} {
+				for _, opts := range []profile.Options{
+					{Type: "cpu", DurationMs: 100},
+					{Type: "heap"},
+					{Type: "goroutine"},
+					{Type: "mutex"},
+				} {
+					t.Run(fmt.Sprintf("%s/%s", component, opts.Type), func(t *testing.T) {
+						data, err := a.Profile(ctx, prefix+component, opts)
+						if err != nil {
+							t.Fatal(err)
+						}
+						prof, err := pprof.ParseData(data)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if opts.Type == "goroutine" && len(prof.Sample) == 0 {
+							t.Error("empty goroutine profile")
+						}
+					})
+				}
+			}
+
+			// Invalid options are rejected.
+			if _, err := a.Profile(ctx, prefix+"B", profile.Options{Type: "cpu"}); err == nil {
+				t.Error("CPU profile without a duration: unexpected success")
+			}
+			if _, err := a.Profile(ctx, prefix+"B", profile.Options{Type: "threads"}); err == nil {
+				t.Error("unknown profile type: unexpected success")
+			}
+		})
+	}
+}
+
+func TestPprofAddress(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = "[serviceweaver]\npprof_address = 'localhost:0'\n"
+		runner.Test(t, func(t *testing.T, a profile.A) {
+			addrA, addrB, err := a.PprofAddresses(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, addr := range []string{addrA, addrB} {
+				if addr == "" {
+					t.Fatal("pprof handlers not served")
+				}
+				resp, err := http.Get(fmt.Sprintf("http://%s/debug/pprof/goroutine?debug=1", addr))
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("GET %s: %s: %s", addr, resp.Status, body)
+				}
+			}
+		})
+	}
+}
+
+func TestPprofDisabledByDefault(t *testing.T) {
+	weavertest.Local.Test(t, func(t *testing.T, a profile.A) {
+		addrA, addrB, err := a.PprofAddresses(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addrA != "" || addrB != "" {
+			t.Errorf("pprof addresses: got %q, %q, want none", addrA, addrB)
+		}
+	})
+}
@@ -16,8 +16,10 @@ package protos
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
 	"github.com/ServiceWeaver/weaver/weavertest"
 )
 
@@ -33,3 +35,83 @@ func TestPingPong(t *testing.T) {
 		}
 	})
 }
+
+func TestPingBatch(t *testing.T) {
+	ctx := context.Background()
+	weavertest.Multi.Test(t, func(t *testing.T, pingponger PingPonger) {
+		// Nil messages should round-trip as nil, both as fields and as slice
+		// and map elements.
+		batch := Batch{
+			Pings:  []*Ping{{Id: 1}, nil, {Id: 2}},
+			ByName: map[string]*Ping{"a": {Id: 3}, "b": nil},
+		}
+		pongs, err := pingponger.PingBatch(ctx, batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []any
+		for _, pong := range pongs {
+			if pong == nil {
+				got = append(got, nil)
+			} else {
+				got = append(got, pong.Id)
+			}
+		}
+		want := []any{nil, int64(1), nil, int64(2), int64(3), nil}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("PingBatch: got %v, want %v", got, want)
+		}
+	})
+}
+
+// pingStruct is a hand-written Go struct equivalent to the Ping proto, as
+// used by applications that convert protos to and from AutoMarshal structs.
+type pingStruct struct {
+	Id int64
+}
+
+// BenchmarkEncodePings compares serializing a batch of protos using their
+// proto encoding with converting them to hand-written structs and serializing
+// those instead.
+func BenchmarkEncodePings(b *testing.B) {
+	pings := make([]*Ping, 100)
+	for i := range pings {
+		pings[i] = &Ping{Id: int64(i) << 20}
+	}
+
+	b.Run("Proto", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			enc := codegen.NewEncoder()
+			enc.Len(len(pings))
+			for _, ping := range pings {
+				enc.EncodeProto(ping)
+			}
+			dec := codegen.NewDecoder(enc.Data())
+			out := make([]*Ping, dec.Len())
+			for j := range out {
+				out[j] = &Ping{}
+				dec.DecodeProto(out[j])
+			}
+		}
+	})
+
+	b.Run("ManualConversion", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			structs := make([]pingStruct, len(pings))
+			for j, ping := range pings {
+				structs[j] = pingStruct{Id: ping.Id}
+			}
+			enc := codegen.NewEncoder()
+			enc.Len(len(structs))
+			for _, s := range structs {
+				enc.Int64(s.Id)
+			}
+			dec := codegen.NewDecoder(enc.Data())
+			out := make([]*Ping, dec.Len())
+			for j := range out {
+				s := pingStruct{Id: dec.Int64()}
+				out[j] = &Ping{Id: s.Id}
+			}
+		}
+	})
+}
@@ -25,6 +25,16 @@ import (
 
 type PingPonger interface {
 	Ping(context.Context, *Ping) (*Pong, error)
+	PingBatch(context.Context, Batch) ([]*Pong, error)
+}
+
+// Batch is a batch of pings. Its proto fields are serialized using their
+// proto encoding.
+type Batch struct {
+	weaver.AutoMarshal
+	First  *Ping
+	Pings  []*Ping
+	ByName map[string]*Ping
 }
 
 type impl struct{ weaver.Implements[PingPonger] }
@@ -32,3 +42,21 @@ type impl struct{ weaver.Implements[PingPonger] }
 func (*impl) Ping(_ context.Context, ping *Ping) (*Pong, error) {
 	return &Pong{Id: ping.Id}, nil
 }
+
+func (*impl) PingBatch(_ context.Context, batch Batch) ([]*Pong, error) {
+	// Nil pings are answered with nil pongs.
+	pong := func(ping *Ping) *Pong {
+		if ping == nil {
+			return nil
+		}
+		return &Pong{Id: ping.Id}
+	}
+	pongs := []*Pong{pong(batch.First)}
+	for _, ping := range batch.Pings {
+		pongs = append(pongs, pong(ping))
+	}
+	for _, name := range []string{"a", "b"} {
+		pongs = append(pongs, pong(batch.ByName[name]))
+	}
+	return pongs, nil
+}
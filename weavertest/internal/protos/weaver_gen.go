@@ -6,6 +6,7 @@ package protos
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/ServiceWeaver/weaver"
 	"github.com/ServiceWeaver/weaver/runtime/codegen"
 	"go.opentelemetry.io/otel/codes"
@@ -13,10 +14,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -39,13 +40,16 @@ func init() {
 		Iface: reflect.TypeOf((*PingPonger)(nil)).Elem(),
 		Impl:  reflect.TypeOf(impl{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return pingPonger_local_stub{impl: impl.(PingPonger), tracer: tracer, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Method: "Ping", Remote: false})}
+			return pingPonger_intercept(pingPonger_local_stub{impl: impl.(PingPonger), caller: caller, tracer: tracer, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Method: "Ping", Remote: false}), pingBatchMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Method: "PingBatch", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return pingPonger_client_stub{stub: stub, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Method: "Ping", Remote: true})}
+			return pingPonger_intercept(pingPonger_client_stub{stub: stub, pingMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Method: "Ping", Remote: true}), pingBatchMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Method: "PingBatch", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return pingPonger_server_stub{impl: impl.(PingPonger), addLoad: addLoad}
+			return pingPonger_server_stub{impl: pingPonger_intercept(impl.(PingPonger), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return pingPonger_intercept(next.(PingPonger), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -60,9 +64,11 @@ var _ weaver.Unrouted = (*impl)(nil)
 // Local stub implementations.
 
 type pingPonger_local_stub struct {
-	impl        PingPonger
-	tracer      trace.Tracer
-	pingMetrics *codegen.MethodMetrics
+	impl             PingPonger
+	caller           string
+	tracer           trace.Tracer
+	pingMetrics      *codegen.MethodMetrics
+	pingBatchMetrics *codegen.MethodMetrics
 }
 
 // Check that pingPonger_local_stub implements the PingPonger interface.
@@ -85,14 +91,37 @@ func (s pingPonger_local_stub) Ping(ctx context.Context, a0 *Ping) (r0 *Pong, er
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger")
 	return s.impl.Ping(ctx, a0)
 }
 
+func (s pingPonger_local_stub) PingBatch(ctx context.Context, a0 Batch) (r0 []*Pong, err error) {
+	// Update metrics.
+	begin := s.pingBatchMetrics.Begin()
+	defer func() { s.pingBatchMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "protos.PingPonger.PingBatch", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger")
+	return s.impl.PingBatch(ctx, a0)
+}
+
 // Client stub implementations.
 
 type pingPonger_client_stub struct {
-	stub        codegen.Stub
-	pingMetrics *codegen.MethodMetrics
+	stub             codegen.Stub
+	pingMetrics      *codegen.MethodMetrics
+	pingBatchMetrics *codegen.MethodMetrics
 }
 
 // Check that pingPonger_client_stub implements the PingPonger interface.
@@ -101,8 +130,11 @@ var _ PingPonger = (*pingPonger_client_stub)(nil)
 func (s pingPonger_client_stub) Ping(ctx context.Context, a0 *Ping) (r0 *Pong, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.pingMetrics.Begin()
-	defer func() { s.pingMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.pingMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -115,6 +147,7 @@ func (s pingPonger_client_stub) Ping(ctx context.Context, a0 *Ping) (r0 *Pong, e
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -133,11 +166,12 @@ func (s pingPonger_client_stub) Ping(ctx context.Context, a0 *Ping) (r0 *Pong, e
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -149,11 +183,68 @@ func (s pingPonger_client_stub) Ping(ctx context.Context, a0 *Ping) (r0 *Pong, e
 	return
 }
 
+func (s pingPonger_client_stub) PingBatch(ctx context.Context, a0 Batch) (r0 []*Pong, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.pingBatchMetrics.Begin()
+	defer func() {
+		s.pingBatchMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "protos.PingPonger.PingBatch", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Encode arguments.
+	enc := codegen.NewEncoder()
+	(a0).WeaverMarshal(enc)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = serviceweaver_dec_slice_ptr_Pong_aff25185(dec)
+	err = dec.Error()
+	return
+}
+
 // Server stub implementations.
 
 type pingPonger_server_stub struct {
-	impl    PingPonger
-	addLoad func(key uint64, load float64)
+	impl       PingPonger
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that pingPonger_server_stub implements the codegen.Server interface.
@@ -164,11 +255,18 @@ func (s pingPonger_server_stub) GetStubFn(method string) func(ctx context.Contex
 	switch method {
 	case "Ping":
 		return s.ping
+	case "PingBatch":
+		return s.pingBatch
 	default:
 		return nil
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s pingPonger_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s pingPonger_server_stub) ping(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -182,10 +280,14 @@ func (s pingPonger_server_stub) ping(ctx context.Context, args []byte) (res []by
 	var a0 *Ping
 	a0 = serviceweaver_dec_ptr_Ping_53efca65(dec)
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Ping(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 *Pong
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", "Ping", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Ping(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -194,7 +296,101 @@ func (s pingPonger_server_stub) ping(ctx context.Context, args []byte) (res []by
 	return enc.Data(), nil
 }
 
-// Encoding/decoding implementations.
+func (s pingPonger_server_stub) pingBatch(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 Batch
+	(&a0).WeaverUnmarshal(dec)
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []*Pong
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/protos/PingPonger", "PingBatch", func(ctx context.Context) (err error) {
+		r0, err = s.impl.PingBatch(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	serviceweaver_enc_slice_ptr_Pong_aff25185(enc, r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type pingPonger_intercept_stub struct {
+	next        PingPonger
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that pingPonger_intercept_stub implements the PingPonger interface.
+var _ PingPonger = (*pingPonger_intercept_stub)(nil)
+
+// pingPonger_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func pingPonger_intercept(next PingPonger, interceptor codegen.Interceptor, call codegen.Call) PingPonger {
+	if interceptor == nil {
+		return next
+	}
+	return pingPonger_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s pingPonger_intercept_stub) Ping(ctx context.Context, a0 *Ping) (r0 *Pong, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Ping", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Ping(ctx, codegen.Arg[*Ping](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[*Pong](results, 0), err
+}
+
+func (s pingPonger_intercept_stub) PingBatch(ctx context.Context, a0 Batch) (r0 []*Pong, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "PingBatch", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.PingBatch(ctx, codegen.Arg[Batch](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]*Pong](results, 0), err
+}
+
+// AutoMarshal implementations.
+
+var _ codegen.AutoMarshal = (*Batch)(nil)
+
+type __is_Batch[T ~struct {
+	weaver.AutoMarshal
+	First  *Ping
+	Pings  []*Ping
+	ByName map[string]*Ping
+}] struct{}
+
+var _ __is_Batch[Batch]
+
+func (x *Batch) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("Batch.WeaverMarshal: nil receiver"))
+	}
+	serviceweaver_enc_ptr_Ping_53efca65(enc, x.First)
+	serviceweaver_enc_slice_ptr_Ping_0eafc096(enc, x.Pings)
+	serviceweaver_enc_map_string_ptr_Ping_4e98e6ca(enc, x.ByName)
+}
+
+func (x *Batch) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("Batch.WeaverUnmarshal: nil receiver"))
+	}
+	x.First = serviceweaver_dec_ptr_Ping_53efca65(dec)
+	x.Pings = serviceweaver_dec_slice_ptr_Ping_0eafc096(dec)
+	x.ByName = serviceweaver_dec_map_string_ptr_Ping_4e98e6ca(dec)
+}
 
 func serviceweaver_enc_ptr_Ping_53efca65(enc *codegen.Encoder, arg *Ping) {
 	if arg == nil {
@@ -214,6 +410,60 @@ func serviceweaver_dec_ptr_Ping_53efca65(dec *codegen.Decoder) *Ping {
 	return &res
 }
 
+func serviceweaver_enc_slice_ptr_Ping_0eafc096(enc *codegen.Encoder, arg []*Ping) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		serviceweaver_enc_ptr_Ping_53efca65(enc, arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_ptr_Ping_0eafc096(dec *codegen.Decoder) []*Ping {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]*Ping, n)
+	for i := 0; i < n; i++ {
+		res[i] = serviceweaver_dec_ptr_Ping_53efca65(dec)
+	}
+	return res
+}
+
+func serviceweaver_enc_map_string_ptr_Ping_4e98e6ca(enc *codegen.Encoder, arg map[string]*Ping) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for _, k := range codegen.SortedKeys(arg) {
+		v := arg[k]
+		enc.String(k)
+		serviceweaver_enc_ptr_Ping_53efca65(enc, v)
+	}
+}
+
+func serviceweaver_dec_map_string_ptr_Ping_4e98e6ca(dec *codegen.Decoder) map[string]*Ping {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make(map[string]*Ping, n)
+	var k string
+	var v *Ping
+	for i := 0; i < n; i++ {
+		k = dec.String()
+		v = serviceweaver_dec_ptr_Ping_53efca65(dec)
+		res[k] = v
+	}
+	return res
+}
+
+// Encoding/decoding implementations.
+
 func serviceweaver_enc_ptr_Pong_10ae1a4e(enc *codegen.Encoder, arg *Pong) {
 	if arg == nil {
 		enc.Bool(false)
@@ -231,3 +481,26 @@ func serviceweaver_dec_ptr_Pong_10ae1a4e(dec *codegen.Decoder) *Pong {
 	dec.DecodeProto(&res)
 	return &res
 }
+
+func serviceweaver_enc_slice_ptr_Pong_aff25185(enc *codegen.Encoder, arg []*Pong) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		serviceweaver_enc_ptr_Pong_10ae1a4e(enc, arg[i])
+	}
+}
+
+func serviceweaver_dec_slice_ptr_Pong_aff25185(dec *codegen.Decoder) []*Pong {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]*Pong, n)
+	for i := 0; i < n; i++ {
+		res[i] = serviceweaver_dec_ptr_Pong_10ae1a4e(dec)
+	}
+	return res
+}
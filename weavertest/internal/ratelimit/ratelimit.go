@@ -0,0 +1,39 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit contains a component used to test method rate limits.
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Mailer is a component whose methods are rate limited in tests.
+type Mailer interface {
+	Send(context.Context) error
+	Queue(context.Context) error
+	Batch(context.Context) error
+	Flush(context.Context) error
+}
+
+type mailer struct {
+	weaver.Implements[Mailer]
+}
+
+func (*mailer) Send(context.Context) error  { return nil }
+func (*mailer) Queue(context.Context) error { return nil }
+func (*mailer) Batch(context.Context) error { return nil }
+func (*mailer) Flush(context.Context) error { return nil }
@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit"
+)
+
+// Every replica of Mailer admits one Send and one Batch call, and then
+// rejects Send calls right away and makes Batch calls wait. Queue calls wait
+// for at most 50ms each, and Flush calls are not limited.
+const config = `
+["github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer"]
+rate_limits = { Send = { qps = 0.001 }, Queue = { qps = 20, mode = "wait" }, Batch = { qps = 0.001, mode = "wait" }, Flush = { qps = 0 } }
+`
+
+func TestRateLimitReject(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = config
+		runner.Test(t, func(t *testing.T, m ratelimit.Mailer) {
+			ctx := context.Background()
+			var admitted, rejected int
+			for i := 0; i < 5; i++ {
+				switch err := m.Send(ctx); {
+				case err == nil:
+					admitted++
+				case errors.Is(err, weaver.ErrRateLimited):
+					rejected++
+				default:
+					t.Fatalf("Send: %v", err)
+				}
+			}
+			// Every replica admits one call.
+			if admitted == 0 || admitted > weavertest.DefaultReplication || rejected == 0 {
+				t.Fatalf("Send: %d calls admitted and %d rejected", admitted, rejected)
+			}
+		})
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = config
+		runner.Test(t, func(t *testing.T, m ratelimit.Mailer) {
+			ctx := context.Background()
+			for i := 0; i < 4; i++ {
+				if err := m.Queue(ctx); err != nil {
+					t.Fatalf("Queue: %v", err)
+				}
+				if err := m.Flush(ctx); err != nil {
+					t.Fatalf("Flush: %v", err)
+				}
+			}
+
+			// Calls that can't be admitted before their deadline are rejected
+			// without waiting for it.
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			start := time.Now()
+			var rejected int
+			for i := 0; i < 5; i++ {
+				switch err := m.Batch(ctx); {
+				case err == nil:
+				case errors.Is(err, weaver.ErrRateLimited):
+					rejected++
+				default:
+					t.Fatalf("Batch: %v", err)
+				}
+			}
+			if rejected == 0 {
+				t.Fatal("Batch: no calls rejected")
+			}
+			if d := time.Since(start); d > 30*time.Second {
+				t.Errorf("Batch: rejected after %v", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer",
+		Iface: reflect.TypeOf((*Mailer)(nil)).Elem(),
+		Impl:  reflect.TypeOf(mailer{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return mailer_intercept(mailer_local_stub{impl: impl.(Mailer), caller: caller, tracer: tracer, batchMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Batch", Remote: false}), flushMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Flush", Remote: false}), queueMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Queue", Remote: false}), sendMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Send", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return mailer_intercept(mailer_client_stub{stub: stub, batchMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Batch", Remote: true}), flushMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Flush", Remote: true}), queueMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Queue", Remote: true}), sendMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Method: "Send", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return mailer_server_stub{impl: mailer_intercept(impl.(Mailer), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return mailer_intercept(next.(Mailer), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Mailer] = (*mailer)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*mailer)(nil)
+
+// Local stub implementations.
+
+type mailer_local_stub struct {
+	impl         Mailer
+	caller       string
+	tracer       trace.Tracer
+	batchMetrics *codegen.MethodMetrics
+	flushMetrics *codegen.MethodMetrics
+	queueMetrics *codegen.MethodMetrics
+	sendMetrics  *codegen.MethodMetrics
+}
+
+// Check that mailer_local_stub implements the Mailer interface.
+var _ Mailer = (*mailer_local_stub)(nil)
+
+func (s mailer_local_stub) Batch(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.batchMetrics.Begin()
+	defer func() { s.batchMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "ratelimit.Mailer.Batch", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer")
+	return s.impl.Batch(ctx)
+}
+
+func (s mailer_local_stub) Flush(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.flushMetrics.Begin()
+	defer func() { s.flushMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "ratelimit.Mailer.Flush", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer")
+	return s.impl.Flush(ctx)
+}
+
+func (s mailer_local_stub) Queue(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.queueMetrics.Begin()
+	defer func() { s.queueMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "ratelimit.Mailer.Queue", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer")
+	return s.impl.Queue(ctx)
+}
+
+func (s mailer_local_stub) Send(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.sendMetrics.Begin()
+	defer func() { s.sendMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "ratelimit.Mailer.Send", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer")
+	return s.impl.Send(ctx)
+}
+
+// Client stub implementations.
+
+type mailer_client_stub struct {
+	stub         codegen.Stub
+	batchMetrics *codegen.MethodMetrics
+	flushMetrics *codegen.MethodMetrics
+	queueMetrics *codegen.MethodMetrics
+	sendMetrics  *codegen.MethodMetrics
+}
+
+// Check that mailer_client_stub implements the Mailer interface.
+var _ Mailer = (*mailer_client_stub)(nil)
+
+func (s mailer_client_stub) Batch(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.batchMetrics.Begin()
+	defer func() {
+		s.batchMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "ratelimit.Mailer.Batch", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s mailer_client_stub) Flush(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.flushMetrics.Begin()
+	defer func() {
+		s.flushMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "ratelimit.Mailer.Flush", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 1, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s mailer_client_stub) Queue(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.queueMetrics.Begin()
+	defer func() {
+		s.queueMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "ratelimit.Mailer.Queue", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 2, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s mailer_client_stub) Send(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.sendMetrics.Begin()
+	defer func() {
+		s.sendMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "ratelimit.Mailer.Send", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 3, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type mailer_server_stub struct {
+	impl       Mailer
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that mailer_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*mailer_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s mailer_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Batch":
+		return s.batch
+	case "Flush":
+		return s.flush
+	case "Queue":
+		return s.queue
+	case "Send":
+		return s.send
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s mailer_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s mailer_server_stub) batch(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", "Batch", func(ctx context.Context) error {
+		return s.impl.Batch(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s mailer_server_stub) flush(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", "Flush", func(ctx context.Context) error {
+		return s.impl.Flush(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s mailer_server_stub) queue(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", "Queue", func(ctx context.Context) error {
+		return s.impl.Queue(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s mailer_server_stub) send(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/ratelimit/Mailer", "Send", func(ctx context.Context) error {
+		return s.impl.Send(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type mailer_intercept_stub struct {
+	next        Mailer
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that mailer_intercept_stub implements the Mailer interface.
+var _ Mailer = (*mailer_intercept_stub)(nil)
+
+// mailer_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func mailer_intercept(next Mailer, interceptor codegen.Interceptor, call codegen.Call) Mailer {
+	if interceptor == nil {
+		return next
+	}
+	return mailer_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s mailer_intercept_stub) Batch(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Batch", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Batch(ctx)
+	})
+	return err
+}
+
+func (s mailer_intercept_stub) Flush(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Flush", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Flush(ctx)
+	})
+	return err
+}
+
+func (s mailer_intercept_stub) Queue(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Queue", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Queue(ctx)
+	})
+	return err
+}
+
+func (s mailer_intercept_stub) Send(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Send", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Send(ctx)
+	})
+	return err
+}
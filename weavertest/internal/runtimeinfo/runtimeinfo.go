@@ -0,0 +1,104 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package runtimeinfo contains components used to test Instance.Runtime and
+// weaver.ComponentInfo.
+package runtimeinfo
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Info is a serializable copy of weaver.RuntimeInfo.
+type Info struct {
+	weaver.AutoMarshal
+	App          string
+	DeploymentID string
+	WeaveletID   string
+	Component    string
+	Listeners    map[string]string
+}
+
+func infoOf(r weaver.RuntimeInfo) Info {
+	return Info{
+		App:          r.App,
+		DeploymentID: r.DeploymentID,
+		WeaveletID:   r.WeaveletID,
+		Component:    r.Component,
+		Listeners:    r.Listeners,
+	}
+}
+
+// Metadata is a serializable copy of weaver.ComponentMetadata.
+type Metadata struct {
+	weaver.AutoMarshal
+	ComponentName string
+	ReplicaCount  int
+	Version       string
+}
+
+func metadataOf(ctx context.Context) Metadata {
+	md := weaver.ComponentInfo(ctx)
+	return Metadata{
+		ComponentName: md.ComponentName,
+		ReplicaCount:  md.ReplicaCount,
+		Version:       md.Version,
+	}
+}
+
+// A is a component that has a listener and calls B.
+type A interface {
+	// Infos returns the runtime info of A and B.
+	Infos(context.Context) (Info, Info, error)
+
+	// Metadata returns the component metadata of A and B.
+	Metadata(context.Context) (Metadata, Metadata, error)
+}
+
+// B is a component.
+type B interface {
+	Info(context.Context) (Info, error)
+	Metadata(context.Context) (Metadata, error)
+}
+
+type a struct {
+	weaver.Implements[A]
+	b   weaver.Ref[B]
+	lis weaver.Listener
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+func (a *a) Infos(ctx context.Context) (Info, Info, error) {
+	info, err := a.b.Get().Info(ctx)
+	return infoOf(a.Runtime()), info, err
+}
+
+func (b *b) Info(context.Context) (Info, error) {
+	return infoOf(b.Runtime()), nil
+}
+
+func (a *a) Metadata(ctx context.Context) (Metadata, Metadata, error) {
+	md, err := a.b.Get().Metadata(ctx)
+	// A's metadata is unaffected by the call to B.
+	return metadataOf(ctx), md, err
+}
+
+func (b *b) Metadata(ctx context.Context) (Metadata, error) {
+	return metadataOf(ctx), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtimeinfo_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/runtimeinfo"
+)
+
+func TestRuntimeInfo(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a runtimeinfo.A) {
+			ctx := context.Background()
+			infoA, infoB, err := a.Infos(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			const prefix = "github.com/ServiceWeaver/weaver/weavertest/internal/runtimeinfo/"
+			if got, want := infoA.Component, prefix+"A"; got != want {
+				t.Errorf("A component: got %q, want %q", got, want)
+			}
+			if got, want := infoB.Component, prefix+"B"; got != want {
+				t.Errorf("B component: got %q, want %q", got, want)
+			}
+			for _, info := range []runtimeinfo.Info{infoA, infoB} {
+				if info.App == "" || info.DeploymentID == "" || info.WeaveletID == "" {
+					t.Errorf("%s: missing ids: %+v", info.Component, info)
+				}
+			}
+			if infoA.App != infoB.App || infoA.DeploymentID != infoB.DeploymentID {
+				t.Errorf("A and B report different deployments: %+v, %+v", infoA, infoB)
+			}
+			if _, ok := infoA.Listeners["lis"]; !ok {
+				t.Errorf("A listeners: got %v, want lis", infoA.Listeners)
+			}
+
+			// The info is stable for the duration of the test.
+			againA, againB, err := a.Infos(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if againA.DeploymentID != infoA.DeploymentID || againB.DeploymentID != infoB.DeploymentID {
+				t.Errorf("deployment ids changed: %q, %q", againA.DeploymentID, againB.DeploymentID)
+			}
+		})
+	}
+}
+
+func TestComponentInfo(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a runtimeinfo.A) {
+			ctx := context.Background()
+			info, _, err := a.Infos(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mdA, mdB, err := a.Metadata(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			const prefix = "github.com/ServiceWeaver/weaver/weavertest/internal/runtimeinfo/"
+			if got, want := mdA.ComponentName, prefix+"A"; got != want {
+				t.Errorf("A component: got %q, want %q", got, want)
+			}
+			if got, want := mdB.ComponentName, prefix+"B"; got != want {
+				t.Errorf("B component: got %q, want %q", got, want)
+			}
+			for _, md := range []runtimeinfo.Metadata{mdA, mdB} {
+				if md.Version != info.DeploymentID {
+					t.Errorf("%s version: got %q, want %q", md.ComponentName, md.Version, info.DeploymentID)
+				}
+				if md.ReplicaCount < 1 {
+					t.Errorf("%s replicas: got %d, want at least 1", md.ComponentName, md.ReplicaCount)
+				}
+			}
+
+			// Outside of a component method, only the name is known.
+			want := weaver.ComponentMetadata{ComponentName: filepath.Base(os.Args[0])}
+			if got := weaver.ComponentInfo(ctx); got != want {
+				t.Errorf("ComponentInfo outside a component: got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
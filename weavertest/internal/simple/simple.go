@@ -34,6 +34,7 @@ import (
 
 type Source interface {
 	Emit(ctx context.Context, file, msg string) error
+	DestinationCaller(ctx context.Context) (string, error)
 }
 
 type source struct {
@@ -45,11 +46,18 @@ func (s *source) Emit(ctx context.Context, file, msg string) error {
 	return s.dst.Get().Record(ctx, file, msg)
 }
 
+// DestinationCaller returns the caller observed by Destination when called
+// from Source.
+func (s *source) DestinationCaller(ctx context.Context) (string, error) {
+	return s.dst.Get().Caller(ctx)
+}
+
 type Destination interface {
 	Getpid(_ context.Context) (int, error)
 	Record(_ context.Context, file, msg string) error
 	GetAll(_ context.Context, file string) ([]string, error)
 	RoutedRecord(_ context.Context, file, msg string) error
+	Caller(_ context.Context) (string, error)
 }
 
 type destRouter struct{}
@@ -88,6 +96,15 @@ func (d *destination) RoutedRecord(ctx context.Context, file, msg string) error
 	return d.Record(ctx, file, "routed: "+msg)
 }
 
+// Caller returns the name of the component calling Caller.
+func (d *destination) Caller(ctx context.Context) (string, error) {
+	caller, ok := weaver.CallerIdentity(ctx)
+	if !ok {
+		return "", fmt.Errorf("no caller identity")
+	}
+	return caller.Component, nil
+}
+
 // GetAll returns all added messages.
 func (d *destination) GetAll(_ context.Context, file string) ([]string, error) {
 	d.mu.Lock()
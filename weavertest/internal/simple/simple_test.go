@@ -16,6 +16,7 @@ package simple_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,7 +27,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ServiceWeaver/weaver"
 	"github.com/ServiceWeaver/weaver/internal/traceio"
+	"github.com/ServiceWeaver/weaver/runtime/metrics"
 	"github.com/ServiceWeaver/weaver/weavertest"
 	"github.com/ServiceWeaver/weaver/weavertest/internal/simple"
 	"github.com/google/uuid"
@@ -53,11 +56,33 @@ func TestOneComponent(t *testing.T) {
 	}
 }
 
+func TestRunAll(t *testing.T) {
+	weavertest.RunAll(t, func(t *testing.T, runner weavertest.Runner) {
+		t.Parallel()
+		if want := "TestRunAll/" + runner.Name; t.Name() != want {
+			t.Errorf("sub-test name: got %q, want %q", t.Name(), want)
+		}
+		runner.Test(t, func(t *testing.T, dst simple.Destination) {
+			if want := "TestRunAll/" + runner.Name; t.Name() != want {
+				t.Errorf("test name: got %q, want %q", t.Name(), want)
+			}
+			dstPid, err := dst.Getpid(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := dstPid == os.Getpid(), runner.Name != weavertest.Multi.Name; got != want {
+				t.Errorf("same process: got %t, want %t", got, want)
+			}
+		})
+	})
+}
+
 type fakeDest struct{ file, msg string }
 
 func (f *fakeDest) Getpid(context.Context) (int, error)                { return 100, nil }
 func (f *fakeDest) GetAll(context.Context, string) ([]string, error)   { return nil, nil }
 func (f *fakeDest) RoutedRecord(context.Context, string, string) error { return nil }
+func (f *fakeDest) Caller(context.Context) (string, error)             { return "", nil }
 func (f *fakeDest) Record(ctx context.Context, file, msg string) error {
 	f.file = file
 	f.msg = msg
@@ -94,7 +119,25 @@ func TestTwoComponents(t *testing.T) {
 		  ]
 	]`
 
-	for _, runner := range append(weavertest.AllRunners(), colocate) {
+	// Runner with Source and Destination colocated using placement hints.
+	placement := weavertest.Multi
+	placement.Name = "Placement"
+	placement.Config = `
+		[placement]
+		colocate = [
+		  [
+		    "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source",
+		    "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination",
+		  ]
+		]
+		separate = [
+		  [
+		    "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source",
+		    "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server",
+		  ]
+		]`
+
+	for _, runner := range append(weavertest.AllRunners(), colocate, placement) {
 		runner.Test(t, func(t *testing.T, src simple.Source, dst simple.Destination) {
 			file := filepath.Join(t.TempDir(), fmt.Sprintf("simple_%s", uuid.New().String()))
 			want := []string{"a", "b", "c", "d", "e"}
@@ -115,6 +158,21 @@ func TestTwoComponents(t *testing.T) {
 	}
 }
 
+func TestCallerIdentity(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, src simple.Source) {
+			got, err := src.DestinationCaller(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			const want = "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source"
+			if got != want {
+				t.Fatalf("DestinationCaller() = %q; expecting %q", got, want)
+			}
+		})
+	}
+}
+
 func TestServer(t *testing.T) {
 	for _, runner := range weavertest.AllRunners() {
 		runner.Test(t, func(t *testing.T, srv simple.Server) {
@@ -221,3 +279,50 @@ func BenchmarkTracedCall(b *testing.B) {
 		}
 	})
 }
+
+func TestQuiesce(t *testing.T) {
+	// With the RPC runner, the test calls Destination remotely, so the calls
+	// go through the quiesce gate.
+	weavertest.RPC.Test(t, func(t *testing.T, dst simple.Destination) {
+		ctx := context.Background()
+		if _, err := dst.Getpid(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		const name = "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination"
+		quiesced := func() float64 {
+			for _, m := range metrics.Snapshot() {
+				if m.Name == "serviceweaver_component_quiesced" && m.Labels["component"] == name {
+					return m.Value
+				}
+			}
+			return -1
+		}
+
+		if err := weaver.Quiesce(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		if got := quiesced(); got != 1 {
+			t.Errorf("quiesced gauge: got %v, want 1", got)
+		}
+		// The only replica of Destination is quiesced, so the call can't be
+		// rerouted.
+		if _, err := dst.Getpid(ctx); !errors.Is(err, weaver.ErrUnavailable) {
+			t.Fatalf("Getpid while quiesced: got %v, want ErrUnavailable", err)
+		}
+
+		if err := weaver.Resume(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		if got := quiesced(); got != 0 {
+			t.Errorf("quiesced gauge: got %v, want 0", got)
+		}
+		if _, err := dst.Getpid(ctx); err != nil {
+			t.Fatalf("Getpid after Resume: %v", err)
+		}
+
+		if err := weaver.Quiesce(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Unknown"); err == nil {
+			t.Error("Quiesce of an unknown component: unexpected success")
+		}
+	})
+}
@@ -13,10 +13,10 @@ import (
 	"reflect"
 )
 
-var _ codegen.LatestVersion = codegen.Version[[0][17]struct{}](`
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
 
 ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
-version v0.17.0). The generated code is incompatible with the version of the
+version v0.18.0). The generated code is incompatible with the version of the
 github.com/ServiceWeaver/weaver module that you're using. The weaver module
 version can be found in your go.mod file or by running the following command.
 
@@ -40,13 +40,16 @@ func init() {
 		Impl:   reflect.TypeOf(destination{}),
 		Routed: true,
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return destination_local_stub{impl: impl.(Destination), tracer: tracer, getAllMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "GetAll", Remote: false}), getpidMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Getpid", Remote: false}), recordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Record", Remote: false}), routedRecordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "RoutedRecord", Remote: false})}
+			return destination_intercept(destination_local_stub{impl: impl.(Destination), caller: caller, tracer: tracer, callerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Caller", Remote: false}), getAllMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "GetAll", Remote: false}), getpidMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Getpid", Remote: false}), recordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Record", Remote: false}), routedRecordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "RoutedRecord", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return destination_client_stub{stub: stub, getAllMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "GetAll", Remote: true}), getpidMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Getpid", Remote: true}), recordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Record", Remote: true}), routedRecordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "RoutedRecord", Remote: true})}
+			return destination_intercept(destination_client_stub{stub: stub, callerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Caller", Remote: true}), getAllMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "GetAll", Remote: true}), getpidMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Getpid", Remote: true}), recordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "Record", Remote: true}), routedRecordMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Method: "RoutedRecord", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return destination_server_stub{impl: impl.(Destination), addLoad: addLoad}
+			return destination_server_stub{impl: destination_intercept(impl.(Destination), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return destination_intercept(next.(Destination), interceptor, call)
 		},
 		RefData: "",
 	})
@@ -56,13 +59,16 @@ func init() {
 		Impl:      reflect.TypeOf(server{}),
 		Listeners: []string{"hello"},
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return server_local_stub{impl: impl.(Server), tracer: tracer, addressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Address", Remote: false}), proxyAddressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "ProxyAddress", Remote: false}), shutdownMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Shutdown", Remote: false})}
+			return server_intercept(server_local_stub{impl: impl.(Server), caller: caller, tracer: tracer, addressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Address", Remote: false}), proxyAddressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "ProxyAddress", Remote: false}), shutdownMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Shutdown", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return server_client_stub{stub: stub, addressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Address", Remote: true}), proxyAddressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "ProxyAddress", Remote: true}), shutdownMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Shutdown", Remote: true})}
+			return server_intercept(server_client_stub{stub: stub, addressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Address", Remote: true}), proxyAddressMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "ProxyAddress", Remote: true}), shutdownMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Method: "Shutdown", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return server_server_stub{impl: impl.(Server), addLoad: addLoad}
+			return server_server_stub{impl: server_intercept(impl.(Server), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return server_intercept(next.(Server), interceptor, call)
 		},
 		RefData: "⟦1e2dce71:wEaVeRlIsTeNeRs:github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server→hello⟧\n",
 	})
@@ -71,13 +77,16 @@ func init() {
 		Iface: reflect.TypeOf((*Source)(nil)).Elem(),
 		Impl:  reflect.TypeOf(source{}),
 		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
-			return source_local_stub{impl: impl.(Source), tracer: tracer, emitMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Method: "Emit", Remote: false})}
+			return source_intercept(source_local_stub{impl: impl.(Source), caller: caller, tracer: tracer, destinationCallerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Method: "DestinationCaller", Remote: false}), emitMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Method: "Emit", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source"})
 		},
 		ClientStubFn: func(stub codegen.Stub, caller string) any {
-			return source_client_stub{stub: stub, emitMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Method: "Emit", Remote: true})}
+			return source_intercept(source_client_stub{stub: stub, destinationCallerMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Method: "DestinationCaller", Remote: true}), emitMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Method: "Emit", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Remote: true})
 		},
 		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
-			return source_server_stub{impl: impl.(Source), addLoad: addLoad}
+			return source_server_stub{impl: source_intercept(impl.(Source), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return source_intercept(next.(Source), interceptor, call)
 		},
 		RefData: "⟦bf914175:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source→github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination⟧\n",
 	})
@@ -101,11 +110,13 @@ type __destination_destRouter_if_youre_seeing_this_you_probably_forgot_to_run_we
 
 type __destination_destRouter_embedding struct{}
 
+func (__destination_destRouter_embedding) Caller() {}
 func (__destination_destRouter_embedding) GetAll() {}
 func (__destination_destRouter_embedding) Getpid() {}
 func (__destination_destRouter_embedding) Record() {}
 
 var _ func(_ context.Context, file string, msg string) string = (&destRouter{}).RoutedRecord                 // routed
+var _ = (&__destination_destRouter_if_youre_seeing_this_you_probably_forgot_to_run_weaver_generate{}).Caller // unrouted
 var _ = (&__destination_destRouter_if_youre_seeing_this_you_probably_forgot_to_run_weaver_generate{}).GetAll // unrouted
 var _ = (&__destination_destRouter_if_youre_seeing_this_you_probably_forgot_to_run_weaver_generate{}).Getpid // unrouted
 var _ = (&__destination_destRouter_if_youre_seeing_this_you_probably_forgot_to_run_weaver_generate{}).Record // unrouted
@@ -114,7 +125,9 @@ var _ = (&__destination_destRouter_if_youre_seeing_this_you_probably_forgot_to_r
 
 type destination_local_stub struct {
 	impl                Destination
+	caller              string
 	tracer              trace.Tracer
+	callerMetrics       *codegen.MethodMetrics
 	getAllMetrics       *codegen.MethodMetrics
 	getpidMetrics       *codegen.MethodMetrics
 	recordMetrics       *codegen.MethodMetrics
@@ -124,6 +137,27 @@ type destination_local_stub struct {
 // Check that destination_local_stub implements the Destination interface.
 var _ Destination = (*destination_local_stub)(nil)
 
+func (s destination_local_stub) Caller(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	begin := s.callerMetrics.Begin()
+	defer func() { s.callerMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "simple.Destination.Caller", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination")
+	return s.impl.Caller(ctx)
+}
+
 func (s destination_local_stub) GetAll(ctx context.Context, a0 string) (r0 []string, err error) {
 	// Update metrics.
 	begin := s.getAllMetrics.Begin()
@@ -141,6 +175,7 @@ func (s destination_local_stub) GetAll(ctx context.Context, a0 string) (r0 []str
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination")
 	return s.impl.GetAll(ctx, a0)
 }
 
@@ -161,6 +196,7 @@ func (s destination_local_stub) Getpid(ctx context.Context) (r0 int, err error)
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination")
 	return s.impl.Getpid(ctx)
 }
 
@@ -181,6 +217,7 @@ func (s destination_local_stub) Record(ctx context.Context, a0 string, a1 string
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination")
 	return s.impl.Record(ctx, a0, a1)
 }
 
@@ -201,11 +238,13 @@ func (s destination_local_stub) RoutedRecord(ctx context.Context, a0 string, a1
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination")
 	return s.impl.RoutedRecord(ctx, a0, a1)
 }
 
 type server_local_stub struct {
 	impl                Server
+	caller              string
 	tracer              trace.Tracer
 	addressMetrics      *codegen.MethodMetrics
 	proxyAddressMetrics *codegen.MethodMetrics
@@ -232,6 +271,7 @@ func (s server_local_stub) Address(ctx context.Context) (r0 string, err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server")
 	return s.impl.Address(ctx)
 }
 
@@ -252,6 +292,7 @@ func (s server_local_stub) ProxyAddress(ctx context.Context) (r0 string, err err
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server")
 	return s.impl.ProxyAddress(ctx)
 }
 
@@ -272,18 +313,42 @@ func (s server_local_stub) Shutdown(ctx context.Context) (err error) {
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server")
 	return s.impl.Shutdown(ctx)
 }
 
 type source_local_stub struct {
-	impl        Source
-	tracer      trace.Tracer
-	emitMetrics *codegen.MethodMetrics
+	impl                     Source
+	caller                   string
+	tracer                   trace.Tracer
+	destinationCallerMetrics *codegen.MethodMetrics
+	emitMetrics              *codegen.MethodMetrics
 }
 
 // Check that source_local_stub implements the Source interface.
 var _ Source = (*source_local_stub)(nil)
 
+func (s source_local_stub) DestinationCaller(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	begin := s.destinationCallerMetrics.Begin()
+	defer func() { s.destinationCallerMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "simple.Source.DestinationCaller", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source")
+	return s.impl.DestinationCaller(ctx)
+}
+
 func (s source_local_stub) Emit(ctx context.Context, a0 string, a1 string) (err error) {
 	// Update metrics.
 	begin := s.emitMetrics.Begin()
@@ -301,6 +366,7 @@ func (s source_local_stub) Emit(ctx context.Context, a0 string, a1 string) (err
 		}()
 	}
 
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source")
 	return s.impl.Emit(ctx, a0, a1)
 }
 
@@ -308,6 +374,7 @@ func (s source_local_stub) Emit(ctx context.Context, a0 string, a1 string) (err
 
 type destination_client_stub struct {
 	stub                codegen.Stub
+	callerMetrics       *codegen.MethodMetrics
 	getAllMetrics       *codegen.MethodMetrics
 	getpidMetrics       *codegen.MethodMetrics
 	recordMetrics       *codegen.MethodMetrics
@@ -317,11 +384,66 @@ type destination_client_stub struct {
 // Check that destination_client_stub implements the Destination interface.
 var _ Destination = (*destination_client_stub)(nil)
 
+func (s destination_client_stub) Caller(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.callerMetrics.Begin()
+	defer func() {
+		s.callerMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "simple.Destination.Caller", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
 func (s destination_client_stub) GetAll(ctx context.Context, a0 string) (r0 []string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getAllMetrics.Begin()
-	defer func() { s.getAllMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getAllMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -334,6 +456,7 @@ func (s destination_client_stub) GetAll(ctx context.Context, a0 string) (r0 []st
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -357,11 +480,12 @@ func (s destination_client_stub) GetAll(ctx context.Context, a0 string) (r0 []st
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -376,8 +500,11 @@ func (s destination_client_stub) GetAll(ctx context.Context, a0 string) (r0 []st
 func (s destination_client_stub) Getpid(ctx context.Context) (r0 int, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.getpidMetrics.Begin()
-	defer func() { s.getpidMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.getpidMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -390,6 +517,7 @@ func (s destination_client_stub) Getpid(ctx context.Context) (r0 int, err error)
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -406,9 +534,10 @@ func (s destination_client_stub) Getpid(ctx context.Context) (r0 int, err error)
 
 	// Call the remote method.
 	var results []byte
-	results, err = s.stub.Run(ctx, 1, nil, shardKey)
+	results, err = s.stub.Run(ctx, 2, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -423,8 +552,11 @@ func (s destination_client_stub) Getpid(ctx context.Context) (r0 int, err error)
 func (s destination_client_stub) Record(ctx context.Context, a0 string, a1 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.recordMetrics.Begin()
-	defer func() { s.recordMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.recordMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -437,6 +569,7 @@ func (s destination_client_stub) Record(ctx context.Context, a0 string, a1 strin
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -462,11 +595,12 @@ func (s destination_client_stub) Record(ctx context.Context, a0 string, a1 strin
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 2, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 3, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -480,8 +614,11 @@ func (s destination_client_stub) Record(ctx context.Context, a0 string, a1 strin
 func (s destination_client_stub) RoutedRecord(ctx context.Context, a0 string, a1 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.routedRecordMetrics.Begin()
-	defer func() { s.routedRecordMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.routedRecordMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -494,6 +631,7 @@ func (s destination_client_stub) RoutedRecord(ctx context.Context, a0 string, a1
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -522,11 +660,12 @@ func (s destination_client_stub) RoutedRecord(ctx context.Context, a0 string, a1
 	shardKey := _hashDestination(r.RoutedRecord(ctx, a0, a1))
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 3, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 4, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -550,8 +689,11 @@ var _ Server = (*server_client_stub)(nil)
 func (s server_client_stub) Address(ctx context.Context) (r0 string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.addressMetrics.Begin()
-	defer func() { s.addressMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.addressMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -564,6 +706,7 @@ func (s server_client_stub) Address(ctx context.Context) (r0 string, err error)
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -583,6 +726,7 @@ func (s server_client_stub) Address(ctx context.Context) (r0 string, err error)
 	results, err = s.stub.Run(ctx, 0, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -597,8 +741,11 @@ func (s server_client_stub) Address(ctx context.Context) (r0 string, err error)
 func (s server_client_stub) ProxyAddress(ctx context.Context) (r0 string, err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.proxyAddressMetrics.Begin()
-	defer func() { s.proxyAddressMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.proxyAddressMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -611,6 +758,7 @@ func (s server_client_stub) ProxyAddress(ctx context.Context) (r0 string, err er
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -630,6 +778,7 @@ func (s server_client_stub) ProxyAddress(ctx context.Context) (r0 string, err er
 	results, err = s.stub.Run(ctx, 1, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -644,8 +793,11 @@ func (s server_client_stub) ProxyAddress(ctx context.Context) (r0 string, err er
 func (s server_client_stub) Shutdown(ctx context.Context) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.shutdownMetrics.Begin()
-	defer func() { s.shutdownMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.shutdownMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -658,6 +810,7 @@ func (s server_client_stub) Shutdown(ctx context.Context) (err error) {
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -677,6 +830,7 @@ func (s server_client_stub) Shutdown(ctx context.Context) (err error) {
 	results, err = s.stub.Run(ctx, 2, nil, shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -688,18 +842,74 @@ func (s server_client_stub) Shutdown(ctx context.Context) (err error) {
 }
 
 type source_client_stub struct {
-	stub        codegen.Stub
-	emitMetrics *codegen.MethodMetrics
+	stub                     codegen.Stub
+	destinationCallerMetrics *codegen.MethodMetrics
+	emitMetrics              *codegen.MethodMetrics
 }
 
 // Check that source_client_stub implements the Source interface.
 var _ Source = (*source_client_stub)(nil)
 
+func (s source_client_stub) DestinationCaller(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.destinationCallerMetrics.Begin()
+	defer func() {
+		s.destinationCallerMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "simple.Source.DestinationCaller", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
 func (s source_client_stub) Emit(ctx context.Context, a0 string, a1 string) (err error) {
 	// Update metrics.
 	var requestBytes, replyBytes int
+	var transportErr bool
 	begin := s.emitMetrics.Begin()
-	defer func() { s.emitMetrics.End(begin, err != nil, requestBytes, replyBytes) }()
+	defer func() {
+		s.emitMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
 
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
@@ -712,6 +922,7 @@ func (s source_client_stub) Emit(ctx context.Context, a0 string, a1 string) (err
 		if err == nil {
 			err = codegen.CatchPanics(recover())
 			if err != nil {
+				transportErr = true
 				err = errors.Join(weaver.RemoteCallError, err)
 			}
 		}
@@ -737,11 +948,12 @@ func (s source_client_stub) Emit(ctx context.Context, a0 string, a1 string) (err
 	var shardKey uint64
 
 	// Call the remote method.
-	requestBytes = len(enc.Data())
+	requestBytes = enc.Size()
 	var results []byte
-	results, err = s.stub.Run(ctx, 0, enc.Data(), shardKey)
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
 	replyBytes = len(results)
 	if err != nil {
+		transportErr = true
 		err = errors.Join(weaver.RemoteCallError, err)
 		return
 	}
@@ -755,8 +967,9 @@ func (s source_client_stub) Emit(ctx context.Context, a0 string, a1 string) (err
 // Server stub implementations.
 
 type destination_server_stub struct {
-	impl    Destination
-	addLoad func(key uint64, load float64)
+	impl       Destination
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that destination_server_stub implements the codegen.Server interface.
@@ -765,6 +978,8 @@ var _ codegen.Server = (*destination_server_stub)(nil)
 // GetStubFn implements the codegen.Server interface.
 func (s destination_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
 	switch method {
+	case "Caller":
+		return s.caller
 	case "GetAll":
 		return s.getAll
 	case "Getpid":
@@ -778,6 +993,35 @@ func (s destination_server_stub) GetStubFn(method string) func(ctx context.Conte
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s destination_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s destination_server_stub) caller(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", "Caller", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Caller(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
 func (s destination_server_stub) getAll(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -791,10 +1035,14 @@ func (s destination_server_stub) getAll(ctx context.Context, args []byte) (res [
 	var a0 string
 	a0 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.GetAll(ctx, a0)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", "GetAll", func(ctx context.Context) (err error) {
+		r0, err = s.impl.GetAll(ctx, a0)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -811,10 +1059,14 @@ func (s destination_server_stub) getpid(ctx context.Context, args []byte) (res [
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Getpid(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 int
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", "Getpid", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Getpid(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -838,10 +1090,12 @@ func (s destination_server_stub) record(ctx context.Context, args []byte) (res [
 	var a1 string
 	a1 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Record(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", "Record", func(ctx context.Context) error {
+		return s.impl.Record(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -864,12 +1118,16 @@ func (s destination_server_stub) routedRecord(ctx context.Context, args []byte)
 	var a1 string
 	a1 = dec.String()
 	var r destRouter
-	s.addLoad(_hashDestination(r.RoutedRecord(ctx, a0, a1)), 1.0)
-
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.RoutedRecord(ctx, a0, a1)
+	loadKey := _hashDestination(r.RoutedRecord(ctx, a0, a1))
+	ctx, load := codegen.WithCallLoad(ctx)
+	defer func() { s.addLoad(loadKey, load.Load()) }()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination", "RoutedRecord", func(ctx context.Context) error {
+		return s.impl.RoutedRecord(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -878,8 +1136,9 @@ func (s destination_server_stub) routedRecord(ctx context.Context, args []byte)
 }
 
 type server_server_stub struct {
-	impl    Server
-	addLoad func(key uint64, load float64)
+	impl       Server
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that server_server_stub implements the codegen.Server interface.
@@ -899,6 +1158,11 @@ func (s server_server_stub) GetStubFn(method string) func(ctx context.Context, a
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s server_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
 func (s server_server_stub) address(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -907,10 +1171,14 @@ func (s server_server_stub) address(ctx context.Context, args []byte) (res []byt
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.Address(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", "Address", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Address(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -927,10 +1195,14 @@ func (s server_server_stub) proxyAddress(ctx context.Context, args []byte) (res
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	r0, appErr := s.impl.ProxyAddress(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", "ProxyAddress", func(ctx context.Context) (err error) {
+		r0, err = s.impl.ProxyAddress(ctx)
+		return err
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -947,10 +1219,12 @@ func (s server_server_stub) shutdown(ctx context.Context, args []byte) (res []by
 		}
 	}()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Shutdown(ctx)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Server", "Shutdown", func(ctx context.Context) error {
+		return s.impl.Shutdown(ctx)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -959,8 +1233,9 @@ func (s server_server_stub) shutdown(ctx context.Context, args []byte) (res []by
 }
 
 type source_server_stub struct {
-	impl    Source
-	addLoad func(key uint64, load float64)
+	impl       Source
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
 }
 
 // Check that source_server_stub implements the codegen.Server interface.
@@ -969,6 +1244,8 @@ var _ codegen.Server = (*source_server_stub)(nil)
 // GetStubFn implements the codegen.Server interface.
 func (s source_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
 	switch method {
+	case "DestinationCaller":
+		return s.destinationCaller
 	case "Emit":
 		return s.emit
 	default:
@@ -976,6 +1253,35 @@ func (s source_server_stub) GetStubFn(method string) func(ctx context.Context, a
 	}
 }
 
+// Use implements the codegen.Server interface.
+func (s source_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s source_server_stub) destinationCaller(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", "DestinationCaller", func(ctx context.Context) (err error) {
+		r0, err = s.impl.DestinationCaller(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
 func (s source_server_stub) emit(ctx context.Context, args []byte) (res []byte, err error) {
 	// Catch and return any panics detected during encoding/decoding/rpc.
 	defer func() {
@@ -991,10 +1297,12 @@ func (s source_server_stub) emit(ctx context.Context, args []byte) (res []byte,
 	var a1 string
 	a1 = dec.String()
 
-	// TODO(rgrandl): The deferred function above will recover from panics in the
-	// user code: fix this.
-	// Call the local method.
-	appErr := s.impl.Emit(ctx, a0, a1)
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source", "Emit", func(ctx context.Context) error {
+		return s.impl.Emit(ctx, a0, a1)
+	})
 
 	// Encode the results.
 	enc := codegen.NewEncoder()
@@ -1002,6 +1310,138 @@ func (s source_server_stub) emit(ctx context.Context, args []byte) (res []byte,
 	return enc.Data(), nil
 }
 
+// Intercept stub implementations.
+
+type destination_intercept_stub struct {
+	next        Destination
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that destination_intercept_stub implements the Destination interface.
+var _ Destination = (*destination_intercept_stub)(nil)
+
+// destination_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func destination_intercept(next Destination, interceptor codegen.Interceptor, call codegen.Call) Destination {
+	if interceptor == nil {
+		return next
+	}
+	return destination_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s destination_intercept_stub) Caller(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Caller", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Caller(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s destination_intercept_stub) GetAll(ctx context.Context, a0 string) (r0 []string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "GetAll", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.GetAll(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[[]string](results, 0), err
+}
+
+func (s destination_intercept_stub) Getpid(ctx context.Context) (r0 int, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Getpid", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Getpid(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[int](results, 0), err
+}
+
+func (s destination_intercept_stub) Record(ctx context.Context, a0 string, a1 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Record", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Record(ctx, codegen.Arg[string](args, 0), codegen.Arg[string](args, 1))
+	})
+	return err
+}
+
+func (s destination_intercept_stub) RoutedRecord(ctx context.Context, a0 string, a1 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "RoutedRecord", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.RoutedRecord(ctx, codegen.Arg[string](args, 0), codegen.Arg[string](args, 1))
+	})
+	return err
+}
+
+type server_intercept_stub struct {
+	next        Server
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that server_intercept_stub implements the Server interface.
+var _ Server = (*server_intercept_stub)(nil)
+
+// server_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func server_intercept(next Server, interceptor codegen.Interceptor, call codegen.Call) Server {
+	if interceptor == nil {
+		return next
+	}
+	return server_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s server_intercept_stub) Address(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Address", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Address(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s server_intercept_stub) ProxyAddress(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "ProxyAddress", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.ProxyAddress(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s server_intercept_stub) Shutdown(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Shutdown", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Shutdown(ctx)
+	})
+	return err
+}
+
+type source_intercept_stub struct {
+	next        Source
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that source_intercept_stub implements the Source interface.
+var _ Source = (*source_intercept_stub)(nil)
+
+// source_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func source_intercept(next Source, interceptor codegen.Interceptor, call codegen.Call) Source {
+	if interceptor == nil {
+		return next
+	}
+	return source_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s source_intercept_stub) DestinationCaller(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "DestinationCaller", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.DestinationCaller(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+func (s source_intercept_stub) Emit(ctx context.Context, a0 string, a1 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Emit", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Emit(ctx, codegen.Arg[string](args, 0), codegen.Arg[string](args, 1))
+	})
+	return err
+}
+
 // Router methods.
 
 // _hashDestination returns a 64 bit hash of the provided value.
@@ -0,0 +1,53 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package singleton contains a singleton component used to test that
+// deployers run a single replica of singleton components.
+package singleton
+
+import (
+	"context"
+	"os"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/google/uuid"
+)
+
+// Leader is a component that is configured to be a singleton.
+type Leader interface {
+	// Instance returns a unique identifier of the replica that executes it.
+	Instance(context.Context) (string, error)
+
+	// Crash terminates the process hosting the replica that executes it.
+	Crash(context.Context) error
+}
+
+type leader struct {
+	weaver.Implements[Leader]
+	id string
+}
+
+func (l *leader) Init(context.Context) error {
+	l.id = uuid.New().String()
+	return nil
+}
+
+func (l *leader) Instance(context.Context) (string, error) {
+	return l.id, nil
+}
+
+func (l *leader) Crash(context.Context) error {
+	os.Exit(1)
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package singleton_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/singleton"
+)
+
+// runner returns a multiprocess runner that makes Leader a singleton.
+func runner() weavertest.Runner {
+	runner := weavertest.Multi
+	runner.Name = "Singleton"
+	runner.Config = `
+		[placement]
+		singleton = ["github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader"]`
+	return runner
+}
+
+func TestSingleton(t *testing.T) {
+	runner().Test(t, func(t *testing.T, l singleton.Leader) {
+		// Concurrent callers should all be served by the same replica.
+		ctx := context.Background()
+		const n, m = 10, 20
+		var mu sync.Mutex
+		instances := map[string]bool{}
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < m; j++ {
+					id, err := l.Instance(ctx)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					instances[id] = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if len(instances) != 1 {
+			t.Fatalf("got %d instances, want 1", len(instances))
+		}
+	})
+}
+
+func TestSingletonFailover(t *testing.T) {
+	runner().Test(t, func(t *testing.T, l singleton.Leader) {
+		ctx := context.Background()
+		before, err := l.Instance(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Crash the replica and wait for its replacement.
+		if err := l.Crash(ctx); !errors.Is(err, weaver.RemoteCallError) {
+			t.Fatalf("Crash: got %v, want a weaver.RemoteCallError", err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		for {
+			after, err := l.Instance(ctx)
+			switch {
+			case ctx.Err() != nil:
+				t.Fatal("timed out waiting for a new replica")
+			case err != nil && !errors.Is(err, weaver.RemoteCallError):
+				t.Fatalf("Instance: unexpected error %v", err)
+			case err != nil:
+				// The replacement has not started yet.
+				time.Sleep(10 * time.Millisecond)
+				continue
+			case after == before:
+				t.Fatalf("Instance: got the crashed replica %q", before)
+			}
+			return
+		}
+	})
+}
@@ -0,0 +1,338 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package singleton
+
+import (
+	"context"
+	"errors"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader",
+		Iface: reflect.TypeOf((*Leader)(nil)).Elem(),
+		Impl:  reflect.TypeOf(leader{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return leader_intercept(leader_local_stub{impl: impl.(Leader), caller: caller, tracer: tracer, crashMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", Method: "Crash", Remote: false}), instanceMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", Method: "Instance", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return leader_intercept(leader_client_stub{stub: stub, crashMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", Method: "Crash", Remote: true}), instanceMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", Method: "Instance", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return leader_server_stub{impl: leader_intercept(impl.(Leader), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return leader_intercept(next.(Leader), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Leader] = (*leader)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*leader)(nil)
+
+// Local stub implementations.
+
+type leader_local_stub struct {
+	impl            Leader
+	caller          string
+	tracer          trace.Tracer
+	crashMetrics    *codegen.MethodMetrics
+	instanceMetrics *codegen.MethodMetrics
+}
+
+// Check that leader_local_stub implements the Leader interface.
+var _ Leader = (*leader_local_stub)(nil)
+
+func (s leader_local_stub) Crash(ctx context.Context) (err error) {
+	// Update metrics.
+	begin := s.crashMetrics.Begin()
+	defer func() { s.crashMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "singleton.Leader.Crash", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader")
+	return s.impl.Crash(ctx)
+}
+
+func (s leader_local_stub) Instance(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	begin := s.instanceMetrics.Begin()
+	defer func() { s.instanceMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "singleton.Leader.Instance", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader")
+	return s.impl.Instance(ctx)
+}
+
+// Client stub implementations.
+
+type leader_client_stub struct {
+	stub            codegen.Stub
+	crashMetrics    *codegen.MethodMetrics
+	instanceMetrics *codegen.MethodMetrics
+}
+
+// Check that leader_client_stub implements the Leader interface.
+var _ Leader = (*leader_client_stub)(nil)
+
+func (s leader_client_stub) Crash(ctx context.Context) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.crashMetrics.Begin()
+	defer func() {
+		s.crashMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "singleton.Leader.Crash", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s leader_client_stub) Instance(ctx context.Context) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.instanceMetrics.Begin()
+	defer func() {
+		s.instanceMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "singleton.Leader.Instance", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 1, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type leader_server_stub struct {
+	impl       Leader
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that leader_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*leader_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s leader_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Crash":
+		return s.crash
+	case "Instance":
+		return s.instance
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s leader_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s leader_server_stub) crash(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", "Crash", func(ctx context.Context) error {
+		return s.impl.Crash(ctx)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s leader_server_stub) instance(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/singleton/Leader", "Instance", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Instance(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type leader_intercept_stub struct {
+	next        Leader
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that leader_intercept_stub implements the Leader interface.
+var _ Leader = (*leader_intercept_stub)(nil)
+
+// leader_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func leader_intercept(next Leader, interceptor codegen.Interceptor, call codegen.Call) Leader {
+	if interceptor == nil {
+		return next
+	}
+	return leader_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s leader_intercept_stub) Crash(ctx context.Context) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Crash", nil, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Crash(ctx)
+	})
+	return err
+}
+
+func (s leader_intercept_stub) Instance(ctx context.Context) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Instance", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Instance(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
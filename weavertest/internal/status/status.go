@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package status contains components used to test weaver.Status and
+// weaver.Components.
+package status
+
+import (
+	"context"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that reports the application status after calling B.
+type A interface {
+	Check(ctx context.Context) ([]Component, error)
+
+	// Components returns the components registered in the binary.
+	Components(ctx context.Context) ([]Registered, error)
+}
+
+// B is a component with two methods.
+type B interface {
+	Ping(ctx context.Context) error
+	Pong(ctx context.Context) error
+}
+
+// Component is a serializable subset of weaver.ComponentStatus.
+type Component struct {
+	weaver.AutoMarshal
+	Name    string
+	Local   bool
+	Methods []string
+	Healthy bool
+}
+
+// Registered is a serializable subset of weaver.RegisteredComponent.
+type Registered struct {
+	weaver.AutoMarshal
+	Name      string
+	Methods   []string
+	Local     bool
+	Listeners map[string]string
+}
+
+type a struct {
+	weaver.Implements[A]
+	b   weaver.Ref[B]
+	lis weaver.Listener
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+func (a *a) Check(ctx context.Context) ([]Component, error) {
+	if err := a.b.Get().Ping(ctx); err != nil {
+		return nil, err
+	}
+	report, err := weaver.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var components []Component
+	for _, c := range report.Components {
+		components = append(components, Component{
+			Name:    c.Name,
+			Local:   c.Local,
+			Methods: c.Methods,
+			Healthy: c.Healthy,
+		})
+	}
+	return components, nil
+}
+
+func (a *a) Components(ctx context.Context) ([]Registered, error) {
+	var components []Registered
+	for _, c := range weaver.Components(ctx) {
+		components = append(components, Registered{
+			Name:      c.Name,
+			Methods:   c.Methods,
+			Local:     c.Local,
+			Listeners: c.Listeners,
+		})
+	}
+	return components, nil
+}
+
+func (b *b) Ping(context.Context) error { return nil }
+func (b *b) Pong(context.Context) error { return nil }
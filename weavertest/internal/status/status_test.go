@@ -0,0 +1,113 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/status"
+	"github.com/google/go-cmp/cmp"
+)
+
+const prefix = "github.com/ServiceWeaver/weaver/weavertest/internal/status/"
+
+func TestStatus(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a status.A) {
+			ctx := context.Background()
+			components, err := a.Check(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A reports itself as local. B was just called, so it's healthy
+			// wherever it is placed.
+			got := map[string]status.Component{}
+			for _, c := range components {
+				got[c.Name] = c
+			}
+			if c := got[prefix+"A"]; !c.Local || !c.Healthy {
+				t.Errorf("A: got %+v, want local and healthy", c)
+			}
+			if c := got[prefix+"B"]; !c.Healthy {
+				t.Errorf("B: got %+v, want healthy", c)
+			}
+			if diff := cmp.Diff([]string{"Ping", "Pong"}, got[prefix+"B"].Methods); diff != "" {
+				t.Errorf("B methods (-want +got):\n%s", diff)
+			}
+
+			// Status can also be called outside of a component.
+			report, err := weaver.Status(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.App == "" || report.DeploymentID == "" {
+				t.Errorf("missing ids: %+v", report)
+			}
+			if c := report.Component(prefix + "A"); c == nil || !c.Healthy {
+				t.Errorf("A: got %+v, want healthy", c)
+			}
+			if report.Component(prefix+"C") != nil {
+				t.Errorf("unexpected component C")
+			}
+		})
+	}
+}
+
+func TestComponents(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, a status.A) {
+			components, err := a.Components(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]status.Registered{}
+			for _, c := range components {
+				got[c.Name] = c
+			}
+
+			// A is local and its listener has been created.
+			c, ok := got[prefix+"A"]
+			if !ok {
+				t.Fatalf("A not registered in %v", components)
+			}
+			if !c.Local {
+				t.Errorf("A: got %+v, want local", c)
+			}
+			if addr, ok := c.Listeners["lis"]; !ok || addr == "" {
+				t.Errorf("A listeners: got %v, want an address for lis", c.Listeners)
+			}
+			if diff := cmp.Diff([]string{"Ping", "Pong"}, got[prefix+"B"].Methods); diff != "" {
+				t.Errorf("B methods (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Outside of an application, only the registry is reported.
+	for _, c := range weaver.Components(context.Background()) {
+		if c.Name != prefix+"A" {
+			continue
+		}
+		if c.Local || c.Remote {
+			t.Errorf("A outside of an application: got %+v, want unknown placement", c)
+		}
+		if diff := cmp.Diff(map[string]string{"lis": ""}, c.Listeners); diff != "" {
+			t.Errorf("A listeners (-want +got):\n%s", diff)
+		}
+	}
+}
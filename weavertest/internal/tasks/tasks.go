@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tasks contains components used to test weaver.WithTasks.
+package tasks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// Stats describes the task runs of a single replica of Worker.
+type Stats struct {
+	weaver.AutoMarshal
+	Runs       int // runs of the task that runs on every replica
+	LeaderRuns int // runs of the task that runs on one replica
+	MaxOverlap int // max number of concurrent runs of the slow task
+}
+
+// Worker is a component that runs background tasks.
+type Worker interface {
+	Stats(context.Context) (Stats, error)
+}
+
+type worker struct {
+	weaver.Implements[Worker]
+	mu      sync.Mutex
+	stats   Stats
+	running int // number of running runs of the slow task
+}
+
+var _ weaver.WithTasks = &worker{}
+
+func (w *worker) Tasks() []weaver.Task {
+	return []weaver.Task{
+		{
+			Name:     "every",
+			Interval: 10 * time.Millisecond,
+			Run: func(context.Context) error {
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				w.stats.Runs++
+				return nil
+			},
+		},
+		{
+			Name:       "leader",
+			Interval:   10 * time.Millisecond,
+			OneReplica: true,
+			Run: func(context.Context) error {
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				w.stats.LeaderRuns++
+				return nil
+			},
+		},
+		{
+			Name:     "slow",
+			Interval: 5 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				w.mu.Lock()
+				w.running++
+				if w.running > w.stats.MaxOverlap {
+					w.stats.MaxOverlap = w.running
+				}
+				w.mu.Unlock()
+
+				select {
+				case <-time.After(30 * time.Millisecond):
+				case <-ctx.Done():
+				}
+
+				w.mu.Lock()
+				w.running--
+				w.mu.Unlock()
+				return nil
+			},
+		},
+	}
+}
+
+func (w *worker) Stats(context.Context) (Stats, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats, nil
+}
+
+// Driver is a component that collects the stats of every replica of Worker.
+type Driver interface {
+	Stats(context.Context) ([]Stats, error)
+}
+
+type driver struct {
+	weaver.Implements[Driver]
+	worker weaver.Ref[Worker]
+}
+
+func (d *driver) Stats(ctx context.Context) ([]Stats, error) {
+	var mu sync.Mutex
+	var all []Stats
+	err := weaver.Broadcast(ctx, d.worker, func(w Worker) error {
+		stats, err := w.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		all = append(all, stats)
+		return nil
+	})
+	return all, err
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/tasks"
+)
+
+func TestTasks(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, driver tasks.Driver) {
+			ctx := context.Background()
+
+			// Wait for the tasks to run a few times on every replica.
+			var stats []tasks.Stats
+			for start := time.Now(); ; {
+				var err error
+				stats, err = driver.Stats(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				done := len(stats) > 0
+				for _, s := range stats {
+					done = done && s.Runs >= 5
+				}
+				if done {
+					break
+				}
+				if time.Since(start) > 10*time.Second {
+					t.Fatalf("tasks didn't run: %+v", stats)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			leaders := 0
+			for _, s := range stats {
+				if s.LeaderRuns > 0 {
+					leaders++
+				}
+				if s.MaxOverlap > 1 {
+					t.Errorf("slow task overlapped %d times", s.MaxOverlap)
+				}
+			}
+			if leaders != 1 {
+				t.Errorf("one-replica task ran on %d replicas, want 1: %+v", leaders, stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver",
+		Iface: reflect.TypeOf((*Driver)(nil)).Elem(),
+		Impl:  reflect.TypeOf(driver{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return driver_intercept(driver_local_stub{impl: impl.(Driver), caller: caller, tracer: tracer, statsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver", Method: "Stats", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return driver_intercept(driver_client_stub{stub: stub, statsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver", Method: "Stats", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return driver_server_stub{impl: driver_intercept(impl.(Driver), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return driver_intercept(next.(Driver), interceptor, call)
+		},
+		RefData: "⟦123aec46:wEaVeReDgE:github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver→github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker⟧\n",
+	})
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker",
+		Iface: reflect.TypeOf((*Worker)(nil)).Elem(),
+		Impl:  reflect.TypeOf(worker{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return worker_intercept(worker_local_stub{impl: impl.(Worker), caller: caller, tracer: tracer, statsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker", Method: "Stats", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return worker_intercept(worker_client_stub{stub: stub, statsMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker", Method: "Stats", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return worker_server_stub{impl: worker_intercept(impl.(Worker), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return worker_intercept(next.(Worker), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Driver] = (*driver)(nil)
+var _ weaver.InstanceOf[Worker] = (*worker)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*driver)(nil)
+var _ weaver.Unrouted = (*worker)(nil)
+
+// Local stub implementations.
+
+type driver_local_stub struct {
+	impl         Driver
+	caller       string
+	tracer       trace.Tracer
+	statsMetrics *codegen.MethodMetrics
+}
+
+// Check that driver_local_stub implements the Driver interface.
+var _ Driver = (*driver_local_stub)(nil)
+
+func (s driver_local_stub) Stats(ctx context.Context) (r0 []Stats, err error) {
+	// Update metrics.
+	begin := s.statsMetrics.Begin()
+	defer func() { s.statsMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "tasks.Driver.Stats", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver")
+	return s.impl.Stats(ctx)
+}
+
+type worker_local_stub struct {
+	impl         Worker
+	caller       string
+	tracer       trace.Tracer
+	statsMetrics *codegen.MethodMetrics
+}
+
+// Check that worker_local_stub implements the Worker interface.
+var _ Worker = (*worker_local_stub)(nil)
+
+func (s worker_local_stub) Stats(ctx context.Context) (r0 Stats, err error) {
+	// Update metrics.
+	begin := s.statsMetrics.Begin()
+	defer func() { s.statsMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "tasks.Worker.Stats", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker")
+	return s.impl.Stats(ctx)
+}
+
+// Client stub implementations.
+
+type driver_client_stub struct {
+	stub         codegen.Stub
+	statsMetrics *codegen.MethodMetrics
+}
+
+// Check that driver_client_stub implements the Driver interface.
+var _ Driver = (*driver_client_stub)(nil)
+
+func (s driver_client_stub) Stats(ctx context.Context) (r0 []Stats, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.statsMetrics.Begin()
+	defer func() {
+		s.statsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "tasks.Driver.Stats", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = serviceweaver_dec_slice_Stats_3d881308(dec)
+	err = dec.Error()
+	return
+}
+
+type worker_client_stub struct {
+	stub         codegen.Stub
+	statsMetrics *codegen.MethodMetrics
+}
+
+// Check that worker_client_stub implements the Worker interface.
+var _ Worker = (*worker_client_stub)(nil)
+
+func (s worker_client_stub) Stats(ctx context.Context) (r0 Stats, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.statsMetrics.Begin()
+	defer func() {
+		s.statsMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "tasks.Worker.Stats", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	var shardKey uint64
+
+	// Call the remote method.
+	var results []byte
+	results, err = s.stub.Run(ctx, 0, nil, shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	(&r0).WeaverUnmarshal(dec)
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type driver_server_stub struct {
+	impl       Driver
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that driver_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*driver_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s driver_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Stats":
+		return s.stats
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s driver_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s driver_server_stub) stats(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 []Stats
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Driver", "Stats", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Stats(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	serviceweaver_enc_slice_Stats_3d881308(enc, r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+type worker_server_stub struct {
+	impl       Worker
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that worker_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*worker_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s worker_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Stats":
+		return s.stats
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s worker_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s worker_server_stub) stats(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 Stats
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/tasks/Worker", "Stats", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Stats(ctx)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	(r0).WeaverMarshal(enc)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type driver_intercept_stub struct {
+	next        Driver
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that driver_intercept_stub implements the Driver interface.
+var _ Driver = (*driver_intercept_stub)(nil)
+
+// driver_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func driver_intercept(next Driver, interceptor codegen.Interceptor, call codegen.Call) Driver {
+	if interceptor == nil {
+		return next
+	}
+	return driver_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s driver_intercept_stub) Stats(ctx context.Context) (r0 []Stats, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Stats", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Stats(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[[]Stats](results, 0), err
+}
+
+type worker_intercept_stub struct {
+	next        Worker
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that worker_intercept_stub implements the Worker interface.
+var _ Worker = (*worker_intercept_stub)(nil)
+
+// worker_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func worker_intercept(next Worker, interceptor codegen.Interceptor, call codegen.Call) Worker {
+	if interceptor == nil {
+		return next
+	}
+	return worker_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s worker_intercept_stub) Stats(ctx context.Context) (r0 Stats, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Stats", nil, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Stats(ctx)
+		return []any{r0}, err
+	})
+	return codegen.Result[Stats](results, 0), err
+}
+
+// AutoMarshal implementations.
+
+var _ codegen.AutoMarshal = (*Stats)(nil)
+
+type __is_Stats[T ~struct {
+	weaver.AutoMarshal
+	Runs       int
+	LeaderRuns int
+	MaxOverlap int
+}] struct{}
+
+var _ __is_Stats[Stats]
+
+func (x *Stats) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("Stats.WeaverMarshal: nil receiver"))
+	}
+	enc.Int(x.Runs)
+	enc.Int(x.LeaderRuns)
+	enc.Int(x.MaxOverlap)
+}
+
+func (x *Stats) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("Stats.WeaverUnmarshal: nil receiver"))
+	}
+	x.Runs = dec.Int()
+	x.LeaderRuns = dec.Int()
+	x.MaxOverlap = dec.Int()
+}
+
+// Encoding/decoding implementations.
+
+func serviceweaver_enc_slice_Stats_3d881308(enc *codegen.Encoder, arg []Stats) {
+	if arg == nil {
+		enc.Len(-1)
+		return
+	}
+	enc.Len(len(arg))
+	for i := 0; i < len(arg); i++ {
+		(arg[i]).WeaverMarshal(enc)
+	}
+}
+
+func serviceweaver_dec_slice_Stats_3d881308(dec *codegen.Decoder) []Stats {
+	n := dec.Len()
+	if n == -1 {
+		return nil
+	}
+	res := make([]Stats, n)
+	for i := 0; i < n; i++ {
+		(&res[i]).WeaverUnmarshal(dec)
+	}
+	return res
+}
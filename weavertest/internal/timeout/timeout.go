@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeout contains components used to test method timeouts.
+package timeout
+
+import (
+	"context"
+	"time"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+// A is a component that calls B.
+type A interface {
+	// SleepB calls B.Sleep with the provided duration.
+	SleepB(ctx context.Context, d time.Duration) error
+}
+
+// B is a component whose methods sleep.
+type B interface {
+	// Sleep sleeps for the provided duration, or until ctx is done.
+	Sleep(ctx context.Context, d time.Duration) error
+
+	// Nap is like Sleep.
+	Nap(ctx context.Context, d time.Duration) error
+
+	// Deadline returns the time remaining until the deadline of ctx, and
+	// whether ctx has a deadline at all.
+	Deadline(ctx context.Context) (time.Duration, bool, error)
+}
+
+type a struct {
+	weaver.Implements[A]
+	b weaver.Ref[B]
+}
+
+type b struct {
+	weaver.Implements[B]
+}
+
+func (a *a) SleepB(ctx context.Context, d time.Duration) error {
+	return a.b.Get().Sleep(ctx, d)
+}
+
+func (b *b) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, d)
+}
+
+func (b *b) Nap(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, d)
+}
+
+func (b *b) Deadline(ctx context.Context) (time.Duration, bool, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false, nil
+	}
+	return time.Until(deadline), true, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
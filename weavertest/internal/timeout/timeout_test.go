@@ -0,0 +1,111 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeout_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/timeout"
+)
+
+const config = `
+["github.com/ServiceWeaver/weaver/weavertest/internal/timeout/B"]
+method_timeouts = { Sleep = "100ms", Deadline = "5s" }
+`
+
+func TestMethodTimeout(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = config
+		runner.Test(t, func(t *testing.T, b timeout.B) {
+			ctx := context.Background()
+
+			// A call that finishes in time succeeds.
+			if err := b.Sleep(ctx, time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+
+			// A call that runs too long fails with DeadlineExceeded.
+			start := time.Now()
+			err := b.Sleep(ctx, time.Minute)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Sleep: got %v, want %v", err, context.DeadlineExceeded)
+			}
+			if !strings.Contains(err.Error(), "timeout/B.Sleep") {
+				t.Errorf("Sleep: error %q doesn't name the method", err)
+			}
+			if d := time.Since(start); d > 30*time.Second {
+				t.Errorf("Sleep: returned after %v", d)
+			}
+
+			// An earlier deadline set by the caller is kept.
+			short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			if err := b.Sleep(short, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Sleep: got %v, want %v", err, context.DeadlineExceeded)
+			}
+
+			// Methods without a timeout are not affected.
+			if err := b.Nap(ctx, 200*time.Millisecond); err != nil {
+				t.Fatalf("Nap: %v", err)
+			}
+		})
+	}
+}
+
+func TestMethodTimeoutFromComponent(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = config
+		runner.Test(t, func(t *testing.T, a timeout.A) {
+			err := a.SleepB(context.Background(), time.Minute)
+			if err == nil || !strings.Contains(err.Error(), "timeout/B.Sleep") {
+				t.Fatalf("SleepB: got %v, want B.Sleep timeout", err)
+			}
+		})
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Config = config
+		runner.Test(t, func(t *testing.T, b timeout.B) {
+			// The callee sees the caller's deadline when it is the earliest.
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			left, ok, err := b.Deadline(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || left <= 0 || left > 2*time.Second {
+				t.Errorf("Deadline: got (%v, %t), want a deadline in (0, 2s]", left, ok)
+			}
+
+			// Otherwise, the callee sees its configured timeout.
+			ctx, cancel = context.WithTimeout(context.Background(), time.Hour)
+			defer cancel()
+			left, ok, err = b.Deadline(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || left <= 0 || left > 5*time.Second {
+				t.Errorf("Deadline: got (%v, %t), want a deadline in (0, 5s]", left, ok)
+			}
+		})
+	}
+}
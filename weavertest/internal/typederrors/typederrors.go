@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typederrors contains components used to test that the types of
+// errors returned by remote calls are preserved.
+package typederrors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ServiceWeaver/weaver"
+)
+
+//go:generate ../../../cmd/weaver/weaver generate
+
+// NotFoundError is returned for a missing key.
+type NotFoundError struct {
+	weaver.AutoMarshal
+	Key string
+}
+
+func (e NotFoundError) Error() string { return fmt.Sprintf("key %q not found", e.Key) }
+
+// ConflictError is returned for a stale version.
+type ConflictError struct {
+	weaver.AutoMarshal
+	Want, Got int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict: want %d, got %d", e.Want, e.Got)
+}
+
+// PlainError is an error type that isn't registered, as it isn't an
+// AutoMarshal struct.
+type PlainError struct{ msg string }
+
+func (e PlainError) Error() string { return e.msg }
+
+// Catalog is a read-only versioned key-value store.
+type Catalog interface {
+	// Get returns the value of the provided key.
+	Get(ctx context.Context, key string) (string, error)
+
+	// Check returns an error if the key is not at the provided version.
+	Check(ctx context.Context, key string, version int) error
+
+	// Fail returns an error with the provided message.
+	Fail(ctx context.Context, msg string) error
+}
+
+type entry struct {
+	value   string
+	version int
+}
+
+type catalog struct {
+	weaver.Implements[Catalog]
+	entries map[string]entry
+}
+
+func (c *catalog) Init(context.Context) error {
+	c.entries = map[string]entry{"a": {value: "x", version: 1}}
+	return nil
+}
+
+func (c *catalog) Get(_ context.Context, key string) (string, error) {
+	e, ok := c.entries[key]
+	if !ok {
+		return "", fmt.Errorf("get: %w", NotFoundError{Key: key})
+	}
+	return e.value, nil
+}
+
+func (c *catalog) Check(_ context.Context, key string, version int) error {
+	e, ok := c.entries[key]
+	if !ok {
+		return fmt.Errorf("check: %w", NotFoundError{Key: key})
+	}
+	if version != e.version {
+		return &ConflictError{Want: e.version, Got: version}
+	}
+	return nil
+}
+
+func (c *catalog) Fail(_ context.Context, msg string) error {
+	return PlainError{msg}
+}
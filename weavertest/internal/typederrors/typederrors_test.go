@@ -0,0 +1,71 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typederrors_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/weavertest"
+	"github.com/ServiceWeaver/weaver/weavertest/internal/typederrors"
+)
+
+func TestTypedErrors(t *testing.T) {
+	for _, runner := range weavertest.AllRunners() {
+		runner.Test(t, func(t *testing.T, c typederrors.Catalog) {
+			ctx := context.Background()
+
+			// A wrapped value error.
+			_, err := c.Get(ctx, "b")
+			var notFound typederrors.NotFoundError
+			if !errors.As(err, &notFound) {
+				t.Fatalf("Get: errors.As(%v, *NotFoundError) = false", err)
+			}
+			if notFound.Key != "b" {
+				t.Errorf("Get: got key %q, want %q", notFound.Key, "b")
+			}
+			if !errors.Is(err, typederrors.NotFoundError{Key: "b"}) {
+				t.Errorf("Get: errors.Is(%v, NotFoundError{b}) = false", err)
+			}
+			if errors.Is(err, typederrors.NotFoundError{Key: "c"}) {
+				t.Errorf("Get: errors.Is(%v, NotFoundError{c}) = true", err)
+			}
+
+			// A pointer error.
+			if err := c.Check(ctx, "a", 1); err != nil {
+				t.Fatal(err)
+			}
+			err = c.Check(ctx, "a", 0)
+			var conflict *typederrors.ConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("Check: errors.As(%v, **ConflictError) = false", err)
+			}
+			if conflict.Want != 1 || conflict.Got != 0 {
+				t.Errorf("Check: got %+v, want {Want:1 Got:0}", *conflict)
+			}
+
+			// An unregistered error keeps its message only.
+			err = c.Fail(ctx, "boom")
+			if err == nil || err.Error() != "boom" {
+				t.Fatalf("Fail: got %v, want boom", err)
+			}
+			var plain typederrors.PlainError
+			if want, got := runner.Name == weavertest.Local.Name, errors.As(err, &plain); want != got {
+				t.Errorf("Fail: errors.As(%v, *PlainError) = %t, want %t", err, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,544 @@
+// Code generated by "weaver generate". DO NOT EDIT.
+//go:build !ignoreWeaverGen
+
+package typederrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ServiceWeaver/weaver"
+	"github.com/ServiceWeaver/weaver/runtime/codegen"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+)
+
+var _ codegen.LatestVersion = codegen.Version[[0][18]struct{}](`
+
+ERROR: You generated this file with 'weaver generate' v0.17.0 (codegen
+version v0.18.0). The generated code is incompatible with the version of the
+github.com/ServiceWeaver/weaver module that you're using. The weaver module
+version can be found in your go.mod file or by running the following command.
+
+    go list -m github.com/ServiceWeaver/weaver
+
+We recommend updating the weaver module and the 'weaver generate' command by
+running the following.
+
+    go get github.com/ServiceWeaver/weaver@latest
+    go install github.com/ServiceWeaver/weaver/cmd/weaver@latest
+
+Then, re-run 'weaver generate' and re-build your code. If the problem persists,
+please file an issue at https://github.com/ServiceWeaver/weaver/issues.
+
+`)
+
+func init() {
+	codegen.Register(codegen.Registration{
+		Name:  "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog",
+		Iface: reflect.TypeOf((*Catalog)(nil)).Elem(),
+		Impl:  reflect.TypeOf(catalog{}),
+		LocalStubFn: func(impl any, caller string, tracer trace.Tracer) any {
+			return catalog_intercept(catalog_local_stub{impl: impl.(Catalog), caller: caller, tracer: tracer, checkMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Method: "Check", Remote: false}), failMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Method: "Fail", Remote: false}), getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Method: "Get", Remote: false})}, codegen.LocalInterceptor(impl), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog"})
+		},
+		ClientStubFn: func(stub codegen.Stub, caller string) any {
+			return catalog_intercept(catalog_client_stub{stub: stub, checkMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Method: "Check", Remote: true}), failMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Method: "Fail", Remote: true}), getMetrics: codegen.MethodMetricsFor(codegen.MethodLabels{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Method: "Get", Remote: true})}, codegen.ClientInterceptor(), codegen.Call{Caller: caller, Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Remote: true})
+		},
+		ServerStubFn: func(impl any, addLoad func(uint64, float64)) codegen.Server {
+			return catalog_server_stub{impl: catalog_intercept(impl.(Catalog), codegen.ServerInterceptor(impl), codegen.Call{Component: "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", Remote: true}), addLoad: addLoad, middleware: &codegen.ServerMiddlewares{}}
+		},
+		InterceptFn: func(next any, interceptor codegen.Interceptor, call codegen.Call) any {
+			return catalog_intercept(next.(Catalog), interceptor, call)
+		},
+		RefData: "",
+	})
+}
+
+// weaver.Instance checks.
+var _ weaver.InstanceOf[Catalog] = (*catalog)(nil)
+
+// weaver.Router checks.
+var _ weaver.Unrouted = (*catalog)(nil)
+
+// Local stub implementations.
+
+type catalog_local_stub struct {
+	impl         Catalog
+	caller       string
+	tracer       trace.Tracer
+	checkMetrics *codegen.MethodMetrics
+	failMetrics  *codegen.MethodMetrics
+	getMetrics   *codegen.MethodMetrics
+}
+
+// Check that catalog_local_stub implements the Catalog interface.
+var _ Catalog = (*catalog_local_stub)(nil)
+
+func (s catalog_local_stub) Check(ctx context.Context, a0 string, a1 int) (err error) {
+	// Update metrics.
+	begin := s.checkMetrics.Begin()
+	defer func() { s.checkMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "typederrors.Catalog.Check", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog")
+	return s.impl.Check(ctx, a0, a1)
+}
+
+func (s catalog_local_stub) Fail(ctx context.Context, a0 string) (err error) {
+	// Update metrics.
+	begin := s.failMetrics.Begin()
+	defer func() { s.failMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "typederrors.Catalog.Fail", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog")
+	return s.impl.Fail(ctx, a0)
+}
+
+func (s catalog_local_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	begin := s.getMetrics.Begin()
+	defer func() { s.getMetrics.End(begin, err != nil, 0, 0) }()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.tracer.Start(ctx, "typederrors.Catalog.Get", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	ctx = codegen.WithLocalCall(ctx, s.caller, "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog")
+	return s.impl.Get(ctx, a0)
+}
+
+// Client stub implementations.
+
+type catalog_client_stub struct {
+	stub         codegen.Stub
+	checkMetrics *codegen.MethodMetrics
+	failMetrics  *codegen.MethodMetrics
+	getMetrics   *codegen.MethodMetrics
+}
+
+// Check that catalog_client_stub implements the Catalog interface.
+var _ Catalog = (*catalog_client_stub)(nil)
+
+func (s catalog_client_stub) Check(ctx context.Context, a0 string, a1 int) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.checkMetrics.Begin()
+	defer func() {
+		s.checkMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "typederrors.Catalog.Check", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	size += 8
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	enc.Int(a1)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 0, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s catalog_client_stub) Fail(ctx context.Context, a0 string) (err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.failMetrics.Begin()
+	defer func() {
+		s.failMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "typederrors.Catalog.Fail", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 1, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	err = dec.Error()
+	return
+}
+
+func (s catalog_client_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	// Update metrics.
+	var requestBytes, replyBytes int
+	var transportErr bool
+	begin := s.getMetrics.Begin()
+	defer func() {
+		s.getMetrics.EndWithKind(begin, codegen.ErrorKindOf(err, transportErr), requestBytes, replyBytes)
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		// Create a child span for this method.
+		ctx, span = s.stub.Tracer().Start(ctx, "typederrors.Catalog.Get", trace.WithSpanKind(trace.SpanKindClient))
+	}
+
+	defer func() {
+		// Catch and return any panics detected during encoding/decoding/rpc.
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+			if err != nil {
+				transportErr = true
+				err = errors.Join(weaver.RemoteCallError, err)
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+	}()
+
+	// Preallocate a buffer of the right size.
+	size := 0
+	size += (4 + len(a0))
+	enc := codegen.NewEncoder()
+	enc.Reset(size)
+
+	// Encode arguments.
+	enc.String(a0)
+	var shardKey uint64
+
+	// Call the remote method.
+	requestBytes = enc.Size()
+	var results []byte
+	results, err = s.stub.RunBuffers(ctx, 2, enc.Buffers(), shardKey)
+	replyBytes = len(results)
+	if err != nil {
+		transportErr = true
+		err = errors.Join(weaver.RemoteCallError, err)
+		return
+	}
+
+	// Decode the results.
+	dec := codegen.NewDecoder(results)
+	r0 = dec.String()
+	err = dec.Error()
+	return
+}
+
+// Server stub implementations.
+
+type catalog_server_stub struct {
+	impl       Catalog
+	addLoad    func(key uint64, load float64)
+	middleware *codegen.ServerMiddlewares
+}
+
+// Check that catalog_server_stub implements the codegen.Server interface.
+var _ codegen.Server = (*catalog_server_stub)(nil)
+
+// GetStubFn implements the codegen.Server interface.
+func (s catalog_server_stub) GetStubFn(method string) func(ctx context.Context, args []byte) ([]byte, error) {
+	switch method {
+	case "Check":
+		return s.check
+	case "Fail":
+		return s.fail
+	case "Get":
+		return s.get
+	default:
+		return nil
+	}
+}
+
+// Use implements the codegen.Server interface.
+func (s catalog_server_stub) Use(mw codegen.ServerMiddleware) {
+	s.middleware.Use(mw)
+}
+
+func (s catalog_server_stub) check(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+	var a1 int
+	a1 = dec.Int()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", "Check", func(ctx context.Context) error {
+		return s.impl.Check(ctx, a0, a1)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s catalog_server_stub) fail(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", "Fail", func(ctx context.Context) error {
+		return s.impl.Fail(ctx, a0)
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+func (s catalog_server_stub) get(ctx context.Context, args []byte) (res []byte, err error) {
+	// Catch and return any panics detected during encoding/decoding/rpc.
+	defer func() {
+		if err == nil {
+			err = codegen.CatchPanics(recover())
+		}
+	}()
+
+	// Decode arguments.
+	dec := codegen.NewDecoder(args)
+	var a0 string
+	a0 = dec.String()
+
+	// Call the local method. The deferred function above only catches
+	// encoding and decoding panics. Other panics are contained by the
+	// runtime (see weaver.ErrPanic).
+	var r0 string
+	appErr := s.middleware.Run(ctx, "github.com/ServiceWeaver/weaver/weavertest/internal/typederrors/Catalog", "Get", func(ctx context.Context) (err error) {
+		r0, err = s.impl.Get(ctx, a0)
+		return err
+	})
+
+	// Encode the results.
+	enc := codegen.NewEncoder()
+	enc.String(r0)
+	enc.Error(appErr)
+	return enc.Data(), nil
+}
+
+// Intercept stub implementations.
+
+type catalog_intercept_stub struct {
+	next        Catalog
+	interceptor codegen.Interceptor
+	call        codegen.Call
+}
+
+// Check that catalog_intercept_stub implements the Catalog interface.
+var _ Catalog = (*catalog_intercept_stub)(nil)
+
+// catalog_intercept returns next, wrapped in an intercept stub if interceptor
+// is not nil.
+func catalog_intercept(next Catalog, interceptor codegen.Interceptor, call codegen.Call) Catalog {
+	if interceptor == nil {
+		return next
+	}
+	return catalog_intercept_stub{next: next, interceptor: interceptor, call: call}
+}
+
+func (s catalog_intercept_stub) Check(ctx context.Context, a0 string, a1 int) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Check", []any{a0, a1}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Check(ctx, codegen.Arg[string](args, 0), codegen.Arg[int](args, 1))
+	})
+	return err
+}
+
+func (s catalog_intercept_stub) Fail(ctx context.Context, a0 string) (err error) {
+	_, err = codegen.Intercept(ctx, s.interceptor, s.call, "Fail", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		return nil, s.next.Fail(ctx, codegen.Arg[string](args, 0))
+	})
+	return err
+}
+
+func (s catalog_intercept_stub) Get(ctx context.Context, a0 string) (r0 string, err error) {
+	results, err := codegen.Intercept(ctx, s.interceptor, s.call, "Get", []any{a0}, func(ctx context.Context, args []any) ([]any, error) {
+		r0, err := s.next.Get(ctx, codegen.Arg[string](args, 0))
+		return []any{r0}, err
+	})
+	return codegen.Result[string](results, 0), err
+}
+
+// AutoMarshal implementations.
+
+var _ codegen.AutoMarshal = (*ConflictError)(nil)
+
+type __is_ConflictError[T ~struct {
+	weaver.AutoMarshal
+	Want int
+	Got  int
+}] struct{}
+
+var _ __is_ConflictError[ConflictError]
+
+func init() { codegen.RegisterError[ConflictError]() }
+
+func (x *ConflictError) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("ConflictError.WeaverMarshal: nil receiver"))
+	}
+	enc.Int(x.Want)
+	enc.Int(x.Got)
+}
+
+func (x *ConflictError) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("ConflictError.WeaverUnmarshal: nil receiver"))
+	}
+	x.Want = dec.Int()
+	x.Got = dec.Int()
+}
+
+var _ codegen.AutoMarshal = (*NotFoundError)(nil)
+
+type __is_NotFoundError[T ~struct {
+	weaver.AutoMarshal
+	Key string
+}] struct{}
+
+var _ __is_NotFoundError[NotFoundError]
+
+func init() { codegen.RegisterError[NotFoundError]() }
+
+func (x *NotFoundError) WeaverMarshal(enc *codegen.Encoder) {
+	if x == nil {
+		panic(fmt.Errorf("NotFoundError.WeaverMarshal: nil receiver"))
+	}
+	enc.String(x.Key)
+}
+
+func (x *NotFoundError) WeaverUnmarshal(dec *codegen.Decoder) {
+	if x == nil {
+		panic(fmt.Errorf("NotFoundError.WeaverUnmarshal: nil receiver"))
+	}
+	x.Key = dec.String()
+}
@@ -101,7 +101,10 @@ func initMultiProcess(ctx context.Context, t testing.TB, isBench bool, runner Ru
 	}
 
 	// Launch the deployer.
-	d := newDeployer(ctx, wlet, appConfig, runner, locals, logWriter)
+	d, err := newDeployer(ctx, wlet, appConfig, runner, locals, logWriter)
+	if err != nil {
+		return nil, nil, err
+	}
 	if err := d.start(); err != nil {
 		return nil, nil, err
 	}
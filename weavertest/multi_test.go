@@ -0,0 +1,53 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package weavertest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ServiceWeaver/weaver/internal/private"
+	"github.com/ServiceWeaver/weaver/runtime/logging"
+	_ "github.com/ServiceWeaver/weaver/weavertest/internal/simple"
+)
+
+func TestAllowedCallersWithoutMTLS(t *testing.T) {
+	// The multiprocess runner doesn't use mTLS, so its weavelets can't verify
+	// the callers of remote calls, and must refuse to enforce allowed callers.
+	runner := Multi
+	runner.Config = `
+["github.com/ServiceWeaver/weaver/weavertest/internal/simple/Destination"]
+allowed_callers = {Record = ["github.com/ServiceWeaver/weaver/weavertest/internal/simple/Source"]}
+`
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := logging.NewTestLogger(t, testing.Verbose())
+	ctx, cleanup, err := initMultiProcess(ctx, t, false, runner, nil, logger.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cancel()
+		if err := cleanup(); err != nil {
+			t.Log("cleanup", err)
+		}
+	}()
+
+	err = runWeaver(ctx, t, runner, func(context.Context, private.App) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "mTLS") {
+		t.Fatalf("runWeaver: got %v, want an error about mTLS", err)
+	}
+}